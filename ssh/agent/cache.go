@@ -0,0 +1,300 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package agent
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// maxCachedSignatures is how many signatures a CachingAgent remembers.
+const maxCachedSignatures = 64
+
+// CachingAgent is an agent that passes requests on to an upstream agent,
+// typically one reached over a slow connection such as a forwarded one. It
+// remembers the keys that the upstream agent lists and the signatures it
+// makes for a while, and signs concurrently, which pipelines the requests
+// to agents returned by NewClient.
+//
+// Adding, removing or locking keys through the CachingAgent clears what it
+// remembers, and nothing is remembered while the upstream agent is locked
+// through it. Upstream agents in the same process, such as the keyring, are
+// checked for being locked otherwise. Other changes made to the upstream
+// agent are seen once the remembered keys expire.
+//
+// Signatures made with keys that were added through the CachingAgent with
+// constraints, such as ConfirmBeforeUse, are never remembered.
+type CachingAgent struct {
+	agent Agent
+	ttl   time.Duration
+
+	mu          sync.Mutex
+	keys        []*Key
+	expires     time.Time
+	gen         uint64 // incremented by Invalidate
+	sigs        map[[sha256.Size]byte]*cachedSignature
+	locked      bool
+	constrained map[string]bool // marshaled keys added with constraints
+}
+
+// A cachedSignature is a signature that is being or has been made.
+type cachedSignature struct {
+	done    chan struct{}
+	sig     *ssh.Signature
+	err     error
+	expires time.Time
+}
+
+// NewCachingAgent returns a CachingAgent that remembers the keys and
+// signatures of agent for ttl.
+func NewCachingAgent(agent Agent, ttl time.Duration) *CachingAgent {
+	return &CachingAgent{
+		agent:       agent,
+		ttl:         ttl,
+		sigs:        make(map[[sha256.Size]byte]*cachedSignature),
+		constrained: make(map[string]bool),
+	}
+}
+
+// isLockedAgent is implemented by agents that can tell whether they are
+// locked.
+type isLockedAgent interface {
+	isLocked() bool
+}
+
+// upstreamLockedLocked reports whether the upstream agent is known to be
+// locked. The caller must hold a.mu.
+func (a *CachingAgent) upstreamLockedLocked() bool {
+	if a.locked {
+		return true
+	}
+	l, ok := a.agent.(isLockedAgent)
+	return ok && l.isLocked()
+}
+
+// Invalidate makes the agent forget the keys and signatures it remembers.
+func (a *CachingAgent) Invalidate() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.keys = nil
+	a.gen++
+	a.sigs = make(map[[sha256.Size]byte]*cachedSignature)
+}
+
+// List returns the keys of the upstream agent, as listed at most the TTL ago.
+func (a *CachingAgent) List() ([]*Key, error) {
+	a.mu.Lock()
+	if a.upstreamLockedLocked() {
+		a.mu.Unlock()
+		return a.agent.List()
+	}
+	if a.keys != nil && time.Now().Before(a.expires) {
+		keys := append([]*Key(nil), a.keys...)
+		a.mu.Unlock()
+		return keys, nil
+	}
+	gen := a.gen
+	a.mu.Unlock()
+
+	keys, err := a.agent.List()
+	if err != nil {
+		return nil, err
+	}
+	a.mu.Lock()
+	// Keys listed before an Invalidate may be out of date, and locked
+	// agents list no keys.
+	if gen == a.gen && len(keys) > 0 {
+		a.keys = append(make([]*Key, 0, len(keys)), keys...)
+		a.expires = time.Now().Add(a.ttl)
+	}
+	a.mu.Unlock()
+	return keys, nil
+}
+
+// Sign signs data with key.
+func (a *CachingAgent) Sign(key ssh.PublicKey, data []byte) (*ssh.Signature, error) {
+	return a.SignWithFlags(key, data, 0)
+}
+
+// SignWithFlags signs data with key, returning the signature made earlier
+// for the same request if it is remembered. Concurrent identical requests
+// are sent once. Flags are only supported if the upstream agent is an
+// ExtendedAgent.
+func (a *CachingAgent) SignWithFlags(key ssh.PublicKey, data []byte, flags SignatureFlags) (*ssh.Signature, error) {
+	blob := key.Marshal()
+	a.mu.Lock()
+	bypass := a.constrained[string(blob)] || a.upstreamLockedLocked()
+	a.mu.Unlock()
+	if bypass {
+		return a.sign(key, data, flags)
+	}
+
+	h := sha256.New()
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], uint32(len(blob)))
+	h.Write(buf[:])
+	h.Write(blob)
+	binary.BigEndian.PutUint32(buf[:], uint32(flags))
+	h.Write(buf[:])
+	h.Write(data)
+	var id [sha256.Size]byte
+	h.Sum(id[:0])
+
+	a.mu.Lock()
+	if c, ok := a.sigs[id]; ok && (c.expires.IsZero() || time.Now().Before(c.expires)) {
+		a.mu.Unlock()
+		<-c.done
+		return c.sig, c.err
+	}
+	a.evictSignaturesLocked()
+	c := &cachedSignature{done: make(chan struct{})}
+	a.sigs[id] = c
+	gen := a.gen
+	a.mu.Unlock()
+
+	c.sig, c.err = a.sign(key, data, flags)
+	a.mu.Lock()
+	if c.err != nil || gen != a.gen {
+		// Don't remember failures, which may be temporary, or signatures
+		// requested before an Invalidate.
+		if a.sigs[id] == c {
+			delete(a.sigs, id)
+		}
+	} else {
+		c.expires = time.Now().Add(a.ttl)
+	}
+	a.mu.Unlock()
+	close(c.done)
+	return c.sig, c.err
+}
+
+// evictSignaturesLocked makes room for a signature in a.sigs.
+func (a *CachingAgent) evictSignaturesLocked() {
+	if len(a.sigs) < maxCachedSignatures {
+		return
+	}
+	now := time.Now()
+	for id, c := range a.sigs {
+		if !c.expires.IsZero() && !now.Before(c.expires) {
+			delete(a.sigs, id)
+		}
+	}
+	// Otherwise drop any finished signature.
+	for id, c := range a.sigs {
+		if len(a.sigs) < maxCachedSignatures {
+			break
+		}
+		if !c.expires.IsZero() {
+			delete(a.sigs, id)
+		}
+	}
+}
+
+func (a *CachingAgent) sign(key ssh.PublicKey, data []byte, flags SignatureFlags) (*ssh.Signature, error) {
+	if ext, ok := a.agent.(ExtendedAgent); ok {
+		return ext.SignWithFlags(key, data, flags)
+	}
+	if flags != 0 {
+		return nil, errors.New("agent: signature flags not supported by upstream agent")
+	}
+	return a.agent.Sign(key, data)
+}
+
+// Signers returns signers for the listed keys, which sign through a.
+func (a *CachingAgent) Signers() ([]ssh.Signer, error) {
+	keys, err := a.List()
+	if err != nil {
+		return nil, err
+	}
+	var signers []ssh.Signer
+	for _, k := range keys {
+		signers = append(signers, &agentKeyringSigner{a, k})
+	}
+	return signers, nil
+}
+
+// Add adds a key to the upstream agent.
+func (a *CachingAgent) Add(key AddedKey) error {
+	if err := checkDestinationSupport(a, key); err != nil {
+		return err
+	}
+	defer a.Invalidate()
+	if key.LifetimeSecs != 0 || key.ConfirmBeforeUse || len(key.ConstraintExtensions) > 0 {
+		// Mark the key before it can be used.
+		signer, err := ssh.NewSignerFromKey(key.PrivateKey)
+		if err != nil {
+			return err
+		}
+		blob := signer.PublicKey().Marshal()
+		if key.Certificate != nil {
+			blob = key.Certificate.Marshal()
+		}
+		a.mu.Lock()
+		a.constrained[string(blob)] = true
+		a.mu.Unlock()
+	}
+	return a.agent.Add(key)
+}
+
+// Remove removes a key from the upstream agent.
+func (a *CachingAgent) Remove(key ssh.PublicKey) error {
+	defer a.Invalidate()
+	if err := a.agent.Remove(key); err != nil {
+		return err
+	}
+	a.mu.Lock()
+	delete(a.constrained, string(key.Marshal()))
+	a.mu.Unlock()
+	return nil
+}
+
+// RemoveAll removes all keys from the upstream agent.
+func (a *CachingAgent) RemoveAll() error {
+	defer a.Invalidate()
+	if err := a.agent.RemoveAll(); err != nil {
+		return err
+	}
+	a.mu.Lock()
+	a.constrained = make(map[string]bool)
+	a.mu.Unlock()
+	return nil
+}
+
+// Lock locks the upstream agent.
+func (a *CachingAgent) Lock(passphrase []byte) error {
+	defer a.Invalidate()
+	if err := a.agent.Lock(passphrase); err != nil {
+		return err
+	}
+	a.mu.Lock()
+	a.locked = true
+	a.mu.Unlock()
+	return nil
+}
+
+// Unlock unlocks the upstream agent.
+func (a *CachingAgent) Unlock(passphrase []byte) error {
+	defer a.Invalidate()
+	if err := a.agent.Unlock(passphrase); err != nil {
+		return err
+	}
+	a.mu.Lock()
+	a.locked = false
+	a.mu.Unlock()
+	return nil
+}
+
+// Extension passes extension requests on to the upstream agent.
+func (a *CachingAgent) Extension(extensionType string, contents []byte) ([]byte, error) {
+	if ext, ok := a.agent.(ExtendedAgent); ok {
+		return ext.Extension(extensionType, contents)
+	}
+	return nil, ErrExtensionUnsupported
+}
@@ -0,0 +1,223 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package agent
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// countingAgent counts the List and Sign requests to an agent.
+type countingAgent struct {
+	ExtendedAgent
+	lists, signs atomic.Int32
+}
+
+func (a *countingAgent) List() ([]*Key, error) {
+	a.lists.Add(1)
+	return a.ExtendedAgent.List()
+}
+
+func (a *countingAgent) Sign(key ssh.PublicKey, data []byte) (*ssh.Signature, error) {
+	return a.SignWithFlags(key, data, 0)
+}
+
+func (a *countingAgent) SignWithFlags(key ssh.PublicKey, data []byte, flags SignatureFlags) (*ssh.Signature, error) {
+	a.signs.Add(1)
+	return a.ExtendedAgent.SignWithFlags(key, data, flags)
+}
+
+func TestCachingAgentList(t *testing.T) {
+	upstream := &countingAgent{ExtendedAgent: filterTestKeyring(t).(ExtendedAgent)}
+	agent := NewCachingAgent(upstream, time.Hour)
+
+	for i := 0; i < 3; i++ {
+		if keys, err := agent.List(); err != nil || len(keys) != 3 {
+			t.Fatalf("List = %d keys, %v, want 3", len(keys), err)
+		}
+	}
+	if n := upstream.lists.Load(); n != 1 {
+		t.Errorf("upstream listed %d times, want 1", n)
+	}
+
+	if err := agent.Remove(testPublicKeys["rsa"]); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if keys, err := agent.List(); err != nil || len(keys) != 2 {
+		t.Errorf("List after Remove = %d keys, %v, want 2", len(keys), err)
+	}
+	if n := upstream.lists.Load(); n != 2 {
+		t.Errorf("upstream listed %d times, want 2", n)
+	}
+
+	agent.ttl = 0
+	agent.Invalidate()
+	agent.List()
+	agent.List()
+	if n := upstream.lists.Load(); n != 4 {
+		t.Errorf("upstream listed %d times with expired keys, want 4", n)
+	}
+}
+
+func TestCachingAgentSign(t *testing.T) {
+	upstream := &countingAgent{ExtendedAgent: filterTestKeyring(t).(ExtendedAgent)}
+	agent := NewCachingAgent(upstream, time.Hour)
+	key := testPublicKeys["ed25519"]
+
+	for i := 0; i < 2; i++ {
+		sig, err := agent.Sign(key, []byte("data"))
+		if err != nil {
+			t.Fatalf("Sign: %v", err)
+		}
+		if err := key.Verify([]byte("data"), sig); err != nil {
+			t.Fatalf("Verify: %v", err)
+		}
+	}
+	if n := upstream.signs.Load(); n != 1 {
+		t.Errorf("upstream signed %d times, want 1", n)
+	}
+	if _, err := agent.Sign(key, []byte("other")); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if _, err := agent.SignWithFlags(testPublicKeys["rsa"], []byte("data"), SignatureFlagRsaSha256); err != nil {
+		t.Fatalf("SignWithFlags: %v", err)
+	}
+	if n := upstream.signs.Load(); n != 3 {
+		t.Errorf("upstream signed %d times, want 3", n)
+	}
+
+	signers, err := agent.Signers()
+	if err != nil || len(signers) != 3 {
+		t.Fatalf("Signers = %d signers, %v, want 3", len(signers), err)
+	}
+}
+
+func TestCachingAgentConcurrentSign(t *testing.T) {
+	keyring := filterTestKeyring(t)
+	client, cleanup := startAgent(t, keyring)
+	defer cleanup()
+	agent := NewCachingAgent(client, time.Hour)
+	key := testPublicKeys["ecdsa"]
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Half of the requests are identical.
+			data := []byte(fmt.Sprint(i % (cap(errs) / 2)))
+			sig, err := agent.Sign(key, data)
+			if err == nil {
+				err = key.Verify(data, sig)
+			}
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestCachingAgentConstrainedKey(t *testing.T) {
+	upstream := &countingAgent{ExtendedAgent: NewKeyring().(ExtendedAgent)}
+	agent := NewCachingAgent(upstream, time.Hour)
+	if err := agent.Add(AddedKey{PrivateKey: testPrivateKeys["ed25519"], LifetimeSecs: 3600}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := agent.Sign(testPublicKeys["ed25519"], []byte("data")); err != nil {
+			t.Fatalf("Sign: %v", err)
+		}
+	}
+	if n := upstream.signs.Load(); n != 2 {
+		t.Errorf("upstream signed %d times with a constrained key, want 2", n)
+	}
+}
+
+func TestCachingAgentLocked(t *testing.T) {
+	keyring := filterTestKeyring(t)
+	agent := NewCachingAgent(keyring, time.Hour)
+	key := testPublicKeys["ed25519"]
+	if _, err := agent.Sign(key, []byte("data")); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if keys, err := agent.List(); err != nil || len(keys) != 3 {
+		t.Fatalf("List = %d keys, %v, want 3", len(keys), err)
+	}
+
+	// Locking the keyring behind the agent's back is noticed.
+	if err := keyring.Lock([]byte("secret")); err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+	if keys, err := agent.List(); err != nil || len(keys) != 0 {
+		t.Errorf("List of a locked keyring = %d keys, %v, want none", len(keys), err)
+	}
+	if _, err := agent.Sign(key, []byte("data")); err == nil {
+		t.Error("remembered signature returned for a locked keyring")
+	}
+	if err := keyring.Unlock([]byte("secret")); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+
+	// So is locking an agent of another process through the agent.
+	client, cleanup := startAgent(t, keyring)
+	defer cleanup()
+	agent = NewCachingAgent(client, time.Hour)
+	if _, err := agent.Sign(key, []byte("data")); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if err := agent.Lock([]byte("secret")); err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+	if keys, err := agent.List(); err != nil || len(keys) != 0 {
+		t.Errorf("List of a locked agent = %d keys, %v, want none", len(keys), err)
+	}
+	if _, err := agent.Sign(key, []byte("data")); err == nil {
+		t.Error("signed with a locked agent")
+	}
+	if err := agent.Unlock([]byte("secret")); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	if keys, err := agent.List(); err != nil || len(keys) != 3 {
+		t.Errorf("List after Unlock = %d keys, %v, want 3", len(keys), err)
+	}
+}
+
+// invalidatingAgent invalidates a CachingAgent while listing keys.
+type invalidatingAgent struct {
+	*countingAgent
+	cache *CachingAgent
+}
+
+func (a *invalidatingAgent) List() ([]*Key, error) {
+	keys, err := a.countingAgent.List()
+	a.cache.Invalidate()
+	return keys, err
+}
+
+func TestCachingAgentListInvalidated(t *testing.T) {
+	upstream := &invalidatingAgent{countingAgent: &countingAgent{ExtendedAgent: filterTestKeyring(t).(ExtendedAgent)}}
+	agent := NewCachingAgent(upstream, time.Hour)
+	upstream.cache = agent
+
+	for i := 0; i < 2; i++ {
+		if keys, err := agent.List(); err != nil || len(keys) != 3 {
+			t.Fatalf("List = %d keys, %v, want 3", len(keys), err)
+		}
+	}
+	if n := upstream.lists.Load(); n != 2 {
+		t.Errorf("upstream listed %d times, want 2", n)
+	}
+}
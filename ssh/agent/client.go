@@ -311,14 +311,25 @@ func parseKey(in []byte) (out *Key, rest []byte, err error) {
 type client struct {
 	// conn is typically a *net.UnixConn
 	conn io.ReadWriter
-	// mu is used to prevent concurrent access to the agent
-	mu sync.Mutex
+
+	// mu serializes writing requests, and numbers them in next.
+	mu   sync.Mutex
+	next uint64
+
+	// turn is the number of the request whose reply is read next. Replies
+	// come in the order of the requests.
+	turnMu   sync.Mutex
+	turnCond *sync.Cond
+	turn     uint64
 }
 
 // NewClient returns an Agent that talks to an ssh-agent process over
-// the given connection.
+// the given connection. Concurrent calls are pipelined: a request is sent
+// without waiting for the replies to earlier ones.
 func NewClient(rw io.ReadWriter) ExtendedAgent {
-	return &client{conn: rw}
+	c := &client{conn: rw}
+	c.turnCond = sync.NewCond(&c.turnMu)
+	return c
 }
 
 // call sends an RPC to the agent. On success, the reply is
@@ -340,13 +351,28 @@ func (c *client) call(req []byte) (reply interface{}, err error) {
 // bytes of the response are returned; no unmarshalling is
 // performed on the response.
 func (c *client) callRaw(req []byte) (reply []byte, err error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
 	msg := make([]byte, 4+len(req))
 	binary.BigEndian.PutUint32(msg, uint32(len(req)))
 	copy(msg[4:], req)
-	if _, err = c.conn.Write(msg); err != nil {
+
+	c.mu.Lock()
+	_, err = c.conn.Write(msg)
+	n := c.next
+	c.next++
+	c.mu.Unlock()
+
+	c.turnMu.Lock()
+	for c.turn != n {
+		c.turnCond.Wait()
+	}
+	c.turnMu.Unlock()
+	defer func() {
+		c.turnMu.Lock()
+		c.turn++
+		c.turnCond.Broadcast()
+		c.turnMu.Unlock()
+	}()
+	if err != nil {
 		return nil, clientErr(err)
 	}
 
@@ -651,21 +677,34 @@ type ed25519CertMsg struct {
 // Add adds a private key to the agent. If a certificate is given,
 // that certificate is added instead as public key.
 func (c *client) Add(key AddedKey) error {
+	constraints := marshalConstraints(key.LifetimeSecs, key.ConfirmBeforeUse, key.ConstraintExtensions)
+
+	cert := key.Certificate
+	if cert == nil {
+		return c.insertKey(key.PrivateKey, key.Comment, constraints)
+	}
+	return c.insertCert(key.PrivateKey, cert, key.Comment, constraints)
+}
+
+// marshalConstraints is the inverse of parseConstraints.
+func marshalConstraints(lifetimeSecs uint32, confirmBeforeUse bool, extensions []ConstraintExtension) []byte {
 	var constraints []byte
 
-	if secs := key.LifetimeSecs; secs != 0 {
-		constraints = append(constraints, ssh.Marshal(constrainLifetimeAgentMsg{secs})...)
+	if lifetimeSecs != 0 {
+		constraints = append(constraints, ssh.Marshal(constrainLifetimeAgentMsg{lifetimeSecs})...)
 	}
 
-	if key.ConfirmBeforeUse {
+	if confirmBeforeUse {
 		constraints = append(constraints, agentConstrainConfirm)
 	}
 
-	cert := key.Certificate
-	if cert == nil {
-		return c.insertKey(key.PrivateKey, key.Comment, constraints)
+	for _, ext := range extensions {
+		constraints = append(constraints, ssh.Marshal(constrainExtensionAgentMsg{
+			ExtensionName:    ext.ExtensionName,
+			ExtensionDetails: ext.ExtensionDetails,
+		})...)
 	}
-	return c.insertCert(key.PrivateKey, cert, key.Comment, constraints)
+	return constraints
 }
 
 func (c *client) insertCert(s interface{}, cert *ssh.Certificate, comment string, constraints []byte) error {
@@ -765,7 +804,7 @@ func (c *client) Signers() ([]ssh.Signer, error) {
 }
 
 type agentKeyringSigner struct {
-	agent *client
+	agent ExtendedAgent
 	pub   ssh.PublicKey
 }
 
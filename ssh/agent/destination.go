@@ -0,0 +1,355 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package agent
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// RestrictDestinationExtension is the constraint extension that restricts
+// the hosts that a key may be used to authenticate to, and through which
+// hosts the agent may be forwarded for that, as with ssh-add -h. It relies
+// on connections being bound to sessions with SessionBindExtension. See
+// section 2 of PROTOCOL.agent in OpenSSH.
+const RestrictDestinationExtension = "restrict-destination-v00@openssh.com"
+
+// A HostKeySpec identifies the hosts of a ConstraintHop by a host key, or
+// by the key of a certificate authority that signed their host certificates.
+type HostKeySpec struct {
+	Key  ssh.PublicKey
+	IsCA bool
+}
+
+// A ConstraintHop is one end of a DestinationConstraint.
+type ConstraintHop struct {
+	// User is the pattern of the users that may be authenticated as on
+	// the host. It is only allowed for the To hop, and if empty, any user
+	// may be.
+	User string
+
+	// Hostname is the name of the host, against which the principals of
+	// its host certificate are checked. It is empty for a From hop that is
+	// the origin, the host running the agent.
+	Hostname string
+
+	// HostKeys identify the host. They are empty for the origin.
+	HostKeys []HostKeySpec
+}
+
+// A DestinationConstraint permits a key to be used for authentication from
+// one host to another. A key restricted with RestrictDestination may only
+// be used through connections whose chain of bound sessions follows its
+// constraints, from the origin to the host being authenticated to.
+type DestinationConstraint struct {
+	From, To ConstraintHop
+}
+
+// RestrictDestination returns the constraint extension that restricts a key
+// added with AddedKey.ConstraintExtensions to the given constraints. The
+// restrictions are enforced by ServeAgent when it serves the keyring
+// returned by NewKeyring or a FileKeyring; agents that pass requests on to
+// another, such as a FilterAgent, refuse restricted keys, and must not
+// serve a keyring that already holds some.
+func RestrictDestination(constraints ...DestinationConstraint) ConstraintExtension {
+	var details []byte
+	for _, c := range constraints {
+		details = append(details, ssh.Marshal(struct{ Constraint []byte }{ssh.Marshal(struct {
+			From, To, Reserved []byte
+		}{marshalHop(c.From), marshalHop(c.To), nil})})...)
+	}
+	return ConstraintExtension{ExtensionName: RestrictDestinationExtension, ExtensionDetails: details}
+}
+
+func marshalHop(h ConstraintHop) []byte {
+	b := ssh.Marshal(struct {
+		User, Hostname string
+		Reserved       []byte
+	}{h.User, h.Hostname, nil})
+	for _, k := range h.HostKeys {
+		b = append(b, ssh.Marshal(struct {
+			Key  []byte
+			IsCA bool
+		}{k.Key.Marshal(), k.IsCA})...)
+	}
+	return b
+}
+
+// ParseRestrictDestination parses the ExtensionDetails of a
+// RestrictDestinationExtension constraint.
+func ParseRestrictDestination(details []byte) ([]DestinationConstraint, error) {
+	var constraints []DestinationConstraint
+	for len(details) > 0 {
+		var msg struct {
+			Constraint []byte
+			Rest       []byte `ssh:"rest"`
+		}
+		if err := ssh.Unmarshal(details, &msg); err != nil {
+			return nil, fmt.Errorf("agent: malformed destination constraint: %v", err)
+		}
+		details = msg.Rest
+		var hops struct {
+			From, To, Reserved []byte
+		}
+		if err := ssh.Unmarshal(msg.Constraint, &hops); err != nil {
+			return nil, fmt.Errorf("agent: malformed destination constraint: %v", err)
+		}
+		var c DestinationConstraint
+		var err error
+		if c.From, err = parseHop(hops.From); err != nil {
+			return nil, err
+		}
+		if c.To, err = parseHop(hops.To); err != nil {
+			return nil, err
+		}
+		switch {
+		case c.From.User != "":
+			return nil, errors.New("agent: destination constraint with a user for the from hop")
+		case c.From.Hostname == "" && len(c.From.HostKeys) > 0:
+			return nil, errors.New("agent: destination constraint with host keys for the origin")
+		case c.From.Hostname != "" && len(c.From.HostKeys) == 0:
+			return nil, errors.New("agent: destination constraint without host keys for the from hop")
+		case c.To.Hostname == "" || len(c.To.HostKeys) == 0:
+			return nil, errors.New("agent: destination constraint without a host name and keys for the to hop")
+		}
+		constraints = append(constraints, c)
+	}
+	return constraints, nil
+}
+
+func parseHop(b []byte) (ConstraintHop, error) {
+	var h ConstraintHop
+	var msg struct {
+		User, Hostname string
+		Reserved       []byte
+		Rest           []byte `ssh:"rest"`
+	}
+	if err := ssh.Unmarshal(b, &msg); err != nil {
+		return h, fmt.Errorf("agent: malformed destination constraint hop: %v", err)
+	}
+	h.User, h.Hostname = msg.User, msg.Hostname
+	for rest := msg.Rest; len(rest) > 0; {
+		var key struct {
+			Key  []byte
+			IsCA bool
+			Rest []byte `ssh:"rest"`
+		}
+		if err := ssh.Unmarshal(rest, &key); err != nil {
+			return h, fmt.Errorf("agent: malformed destination constraint key: %v", err)
+		}
+		pub, err := ssh.ParsePublicKey(key.Key)
+		if err != nil {
+			return h, fmt.Errorf("agent: destination constraint key: %v", err)
+		}
+		h.HostKeys = append(h.HostKeys, HostKeySpec{Key: pub, IsCA: key.IsCA})
+		rest = key.Rest
+	}
+	return h, nil
+}
+
+// destinationConstrainer is implemented by agents, such as the keyring,
+// that hold keys restricted with RestrictDestinationExtension, so that
+// ServeAgent enforces the restrictions. Other agents refuse such keys, see
+// checkDestinationSupport.
+type destinationConstrainer interface {
+	// destinationConstraints returns the constraints of key, or nil if it
+	// is not restricted.
+	destinationConstraints(key ssh.PublicKey) []DestinationConstraint
+}
+
+// checkDestinationSupport returns an error if key is restricted with
+// RestrictDestinationExtension and is added to agent, which can't report
+// the restriction to ServeAgent. Agents that pass requests on to an
+// upstream one check the keys they are asked to add with themselves as
+// agent, so that the restriction isn't held where it can't be enforced.
+func checkDestinationSupport(agent Agent, key AddedKey) error {
+	if _, ok := agent.(destinationConstrainer); ok {
+		return nil
+	}
+	for _, ext := range key.ConstraintExtensions {
+		if ext.ExtensionName == RestrictDestinationExtension {
+			return errors.New("agent: destination constraints not supported by this agent")
+		}
+	}
+	return nil
+}
+
+func (s *server) destinationConstraints(key ssh.PublicKey) []DestinationConstraint {
+	if d, ok := s.agent.(destinationConstrainer); ok {
+		return d.destinationConstraints(key)
+	}
+	return nil
+}
+
+// permittedKeys removes the keys that the connection may not use from keys.
+func (s *server) permittedKeys(keys []*Key) []*Key {
+	if len(s.bindings) == 0 {
+		return keys
+	}
+	permitted := keys[:0:0]
+	for _, k := range keys {
+		if c := s.destinationConstraints(k); c == nil || s.permitted(c, "", false) {
+			permitted = append(permitted, k)
+		}
+	}
+	return permitted
+}
+
+// checkDestination reports whether key may sign data, a user
+// authentication request, through the sessions that the connection is
+// bound to.
+func (s *server) checkDestination(key ssh.PublicKey, data []byte) error {
+	constraints := s.destinationConstraints(key)
+	if constraints == nil {
+		return nil
+	}
+	if len(s.bindings) == 0 {
+		return errors.New("agent: refusing to use a destination-constrained key on an unbound connection")
+	}
+	user, sessionID, hostKey, err := parseUserAuthRequest(data, key)
+	if err != nil {
+		return fmt.Errorf("agent: refusing to use a destination-constrained key: %v", err)
+	}
+	if !s.permitted(constraints, user, true) {
+		return errors.New("agent: destination-constrained key not permitted for this destination")
+	}
+	last := s.bindings[len(s.bindings)-1]
+	if !bytes.Equal(sessionID, last.sessionID) {
+		return errors.New("agent: refusing to use a destination-constrained key for a session the connection is not bound to")
+	}
+	// The destination's host key is only optional for the first hop.
+	if len(s.bindings) > 1 && hostKey == nil {
+		return errors.New("agent: refusing to use a destination-constrained key on a forwarded connection without a host key in the request")
+	}
+	if hostKey != nil && !bytes.Equal(hostKey.Marshal(), last.hostKey.Marshal()) {
+		return errors.New("agent: host key in the request does not match the bound session")
+	}
+	return nil
+}
+
+// permitted reports whether constraints allow a key to be used through the
+// sessions that the connection is bound to, and to authenticate as user if
+// signing.
+func (s *server) permitted(constraints []DestinationConstraint, user string, signing bool) bool {
+	if len(s.bindings) == 0 {
+		// Local use.
+		return true
+	}
+	var from ssh.PublicKey
+	for i, b := range s.bindings {
+		testUser, last := "", i == len(s.bindings)-1
+		if last {
+			// The user can only be checked at the final hop, which must
+			// not be a forwarding one when signing.
+			testUser = user
+			if b.forwarding && signing {
+				return false
+			}
+		} else if !b.forwarding {
+			return false
+		}
+		if !permittedHop(constraints, from, b.hostKey, testUser, signing && last) {
+			return false
+		}
+		from = b.hostKey
+	}
+	// Keys that may be used on a host that the agent is forwarded to, but
+	// not beyond it, are hidden there.
+	if last := s.bindings[len(s.bindings)-1]; last.forwarding && !signing {
+		return permittedHop(constraints, last.hostKey, nil, "", false)
+	}
+	return true
+}
+
+// permittedHop reports whether a constraint allows a hop from the host of
+// from, or the origin if nil, to the host of to, or any if nil.
+func permittedHop(constraints []DestinationConstraint, from, to ssh.PublicKey, user string, checkUser bool) bool {
+	for _, c := range constraints {
+		if from == nil {
+			if c.From.Hostname != "" || len(c.From.HostKeys) > 0 {
+				continue
+			}
+		} else if !matchHop(from, c.From) {
+			continue
+		}
+		if to != nil && !matchHop(to, c.To) {
+			continue
+		}
+		if checkUser && c.To.User != "" && !ssh.MatchPrincipalPattern(user, []string{c.To.User}) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// matchHop reports whether key is a host key of hop.
+func matchHop(key ssh.PublicKey, hop ConstraintHop) bool {
+	for _, spec := range hop.HostKeys {
+		if !spec.IsCA {
+			if bytes.Equal(key.Marshal(), spec.Key.Marshal()) {
+				return true
+			}
+			continue
+		}
+		cert, ok := key.(*ssh.Certificate)
+		if !ok || cert.CertType != ssh.HostCert || len(cert.ValidPrincipals) == 0 ||
+			!bytes.Equal(cert.SignatureKey.Marshal(), spec.Key.Marshal()) {
+			continue
+		}
+		if err := (&ssh.CertChecker{}).CheckCert(hop.Hostname, cert); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// parseUserAuthRequest parses data signed by key for public key user
+// authentication. hostKey is the host key of the server for the
+// publickey-hostbound-v00@openssh.com method, and nil otherwise.
+func parseUserAuthRequest(data []byte, key ssh.PublicKey) (user string, sessionID []byte, hostKey ssh.PublicKey, err error) {
+	const msgUserAuthRequest = 50
+	var msg struct {
+		SessionID []byte
+		Type      byte
+		User      string
+		Service   string
+		Method    string
+		HasSig    bool
+		Algo      string
+		PubKey    []byte
+		Rest      []byte `ssh:"rest"`
+	}
+	if err := ssh.Unmarshal(data, &msg); err != nil || msg.Type != msgUserAuthRequest {
+		return "", nil, nil, errors.New("not a user authentication request")
+	}
+	if msg.Service != "ssh-connection" || !msg.HasSig || !bytes.Equal(msg.PubKey, key.Marshal()) {
+		return "", nil, nil, errors.New("malformed user authentication request")
+	}
+	switch msg.Method {
+	case "publickey":
+	case "publickey-hostbound-v00@openssh.com":
+		var hb struct {
+			HostKey []byte
+			Rest    []byte `ssh:"rest"`
+		}
+		if err := ssh.Unmarshal(msg.Rest, &hb); err != nil {
+			return "", nil, nil, errors.New("malformed user authentication request")
+		}
+		if hostKey, err = ssh.ParsePublicKey(hb.HostKey); err != nil {
+			return "", nil, nil, err
+		}
+		msg.Rest = hb.Rest
+	default:
+		return "", nil, nil, fmt.Errorf("unexpected user authentication method %q", msg.Method)
+	}
+	if len(msg.Rest) > 0 {
+		return "", nil, nil, errors.New("trailing data in user authentication request")
+	}
+	return msg.User, msg.SessionID, hostKey, nil
+}
@@ -0,0 +1,226 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package agent
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestRestrictDestinationRoundTrip(t *testing.T) {
+	constraints := []DestinationConstraint{
+		{To: ConstraintHop{User: "alice", Hostname: "a.example.com", HostKeys: []HostKeySpec{{Key: testPublicKeys["ecdsa"]}}}},
+		{
+			From: ConstraintHop{Hostname: "a.example.com", HostKeys: []HostKeySpec{{Key: testPublicKeys["ecdsa"]}}},
+			To:   ConstraintHop{Hostname: "*.internal", HostKeys: []HostKeySpec{{Key: testPublicKeys["rsa"], IsCA: true}}},
+		},
+	}
+	ext := RestrictDestination(constraints...)
+	if ext.ExtensionName != RestrictDestinationExtension {
+		t.Errorf("got extension %q", ext.ExtensionName)
+	}
+	got, err := ParseRestrictDestination(ext.ExtensionDetails)
+	if err != nil {
+		t.Fatalf("ParseRestrictDestination: %v", err)
+	}
+	if !reflect.DeepEqual(got, constraints) {
+		t.Errorf("got %+v, want %+v", got, constraints)
+	}
+
+	for _, c := range []DestinationConstraint{
+		{From: ConstraintHop{User: "u"}, To: constraints[0].To},
+		{From: ConstraintHop{HostKeys: constraints[0].To.HostKeys}, To: constraints[0].To},
+		{From: ConstraintHop{Hostname: "a"}, To: constraints[0].To},
+		{To: ConstraintHop{Hostname: "a"}},
+	} {
+		if _, err := ParseRestrictDestination(RestrictDestination(c).ExtensionDetails); err == nil {
+			t.Errorf("invalid constraint %+v accepted", c)
+		}
+	}
+}
+
+func TestRestrictDestinationOpenSSHAgent(t *testing.T) {
+	agent, _, cleanup := startOpenSSHAgent(t)
+	defer cleanup()
+	err := agent.Add(AddedKey{
+		PrivateKey: testPrivateKeys["ed25519"],
+		ConstraintExtensions: []ConstraintExtension{RestrictDestination(DestinationConstraint{
+			To: ConstraintHop{Hostname: "server", HostKeys: []HostKeySpec{{Key: testPublicKeys["ecdsa"]}}},
+		})},
+	})
+	if err != nil {
+		t.Fatalf("Add with a destination constraint: %v", err)
+	}
+	client, err := dialWithAgent(t, agent)
+	if err != nil {
+		t.Fatalf("dial to a permitted destination: %v", err)
+	}
+	client.Close()
+}
+
+// userAuthData returns the data signed by key for user authentication in a
+// session, as publickey-hostbound-v00@openssh.com if hostKey is not nil.
+func userAuthData(sessionID []byte, user string, key, hostKey ssh.PublicKey) []byte {
+	method := "publickey"
+	if hostKey != nil {
+		method = "publickey-hostbound-v00@openssh.com"
+	}
+	data := ssh.Marshal(struct {
+		SessionID []byte
+		Type      byte
+		User      string
+		Service   string
+		Method    string
+		HasSig    bool
+		Algo      string
+		PubKey    []byte
+	}{sessionID, 50, user, "ssh-connection", method, true, key.Type(), key.Marshal()})
+	if hostKey != nil {
+		data = append(data, ssh.Marshal(struct{ HostKey []byte }{hostKey.Marshal()})...)
+	}
+	return data
+}
+
+func TestRestrictDestination(t *testing.T) {
+	hostA, hostB := testSigners["ecdsa"], testSigners["rsa"]
+	keyring := NewKeyring()
+	err := keyring.Add(AddedKey{
+		PrivateKey: testPrivateKeys["ed25519"],
+		ConstraintExtensions: []ConstraintExtension{RestrictDestination(
+			DestinationConstraint{
+				To: ConstraintHop{Hostname: "a", HostKeys: []HostKeySpec{{Key: hostA.PublicKey()}}},
+			},
+			DestinationConstraint{
+				From: ConstraintHop{Hostname: "a", HostKeys: []HostKeySpec{{Key: hostA.PublicKey()}}},
+				To:   ConstraintHop{User: "bob", Hostname: "b", HostKeys: []HostKeySpec{{Key: hostB.PublicKey()}}},
+			},
+		)},
+	})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	key := testPublicKeys["ed25519"]
+
+	bind := func(agent ExtendedAgent, host ssh.Signer, id string, forwarding bool) {
+		t.Helper()
+		sig, err := host.Sign(nil, []byte(id))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := BindSession(agent, host.PublicKey(), []byte(id), ssh.Marshal(sig), forwarding); err != nil {
+			t.Fatalf("BindSession: %v", err)
+		}
+	}
+	listed := func(agent ExtendedAgent) bool {
+		t.Helper()
+		keys, err := agent.List()
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		for _, k := range keys {
+			if bytes.Equal(k.Marshal(), key.Marshal()) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Without bindings, the key can't be used for authentication.
+	agent, cleanup := startAgent(t, keyring)
+	if _, err := agent.Sign(key, userAuthData([]byte("s"), "alice", key, nil)); err == nil {
+		t.Error("constrained key signed on an unbound connection")
+	}
+	cleanup()
+
+	// Directly from the origin to a.
+	agent, cleanup = startAgent(t, keyring)
+	bind(agent, hostA, "origin-a", false)
+	if _, err := agent.Sign(key, userAuthData([]byte("origin-a"), "alice", key, nil)); err != nil {
+		t.Errorf("signing for a: %v", err)
+	}
+	if _, err := agent.Sign(key, []byte("data")); err == nil {
+		t.Error("constrained key signed data other than user authentication")
+	}
+	cleanup()
+
+	// Directly to b isn't permitted.
+	agent, cleanup = startAgent(t, keyring)
+	bind(agent, hostB, "origin-b", false)
+	if listed(agent) {
+		t.Error("key listed on a connection bound to b")
+	}
+	if _, err := agent.Sign(key, userAuthData([]byte("origin-b"), "bob", key, nil)); err == nil {
+		t.Error("constrained key signed for b from the origin")
+	}
+	cleanup()
+
+	// Through a to b, as bob only, with the host key of b in the request.
+	agent, cleanup = startAgent(t, keyring)
+	defer cleanup()
+	bind(agent, hostA, "origin-a", true)
+	if !listed(agent) {
+		t.Error("key not listed on a connection forwarded to a")
+	}
+	bind(agent, hostB, "a-b", false)
+	if _, err := agent.Sign(key, userAuthData([]byte("a-b"), "alice", key, hostB.PublicKey())); err == nil {
+		t.Error("constrained key signed for a user other than bob")
+	}
+	if _, err := agent.Sign(key, userAuthData([]byte("a-b"), "bob", key, nil)); err == nil {
+		t.Error("constrained key signed on a forwarded connection without the host key")
+	}
+	if _, err := agent.Sign(key, userAuthData([]byte("a-b"), "bob", key, hostA.PublicKey())); err == nil {
+		t.Error("constrained key signed with the host key of another session")
+	}
+	if _, err := agent.Sign(key, userAuthData([]byte("a-b"), "bob", key, hostB.PublicKey())); err != nil {
+		t.Errorf("signing for bob on b through a: %v", err)
+	}
+}
+
+func TestRestrictDestinationUnsupported(t *testing.T) {
+	restricted := AddedKey{
+		PrivateKey: testPrivateKeys["ed25519"],
+		ConstraintExtensions: []ConstraintExtension{RestrictDestination(DestinationConstraint{
+			To: ConstraintHop{Hostname: "a", HostKeys: []HostKeySpec{{Key: testPublicKeys["ecdsa"]}}},
+		})},
+	}
+	// Agents that pass requests on can't report the restrictions of the
+	// keys they hold to ServeAgent, so they must not take restricted keys.
+	for name, agent := range map[string]Agent{
+		"ExtensionAgent": NewExtensionAgent(NewKeyring()),
+		"FilterAgent":    &FilterAgent{Agent: NewKeyring(), AllowAdd: true},
+		"CachingAgent":   NewCachingAgent(NewKeyring(), time.Minute),
+		"IdleLockAgent":  NewIdleLockAgent(NewKeyring(), IdleLockConfig{Timeout: time.Hour}),
+	} {
+		if err := agent.Add(restricted); err == nil {
+			t.Errorf("%s.Add accepted a destination-constrained key", name)
+		}
+		client, cleanup := startAgent(t, agent)
+		if err := client.Add(restricted); err == nil {
+			t.Errorf("ServeAgent(%s) accepted a destination-constrained key", name)
+		}
+		if err := client.Add(AddedKey{PrivateKey: testPrivateKeys["ed25519"]}); err != nil {
+			t.Errorf("ServeAgent(%s): Add: %v", name, err)
+		}
+		cleanup()
+	}
+
+	f, err := openFileKeyring(t, filepath.Join(t.TempDir(), "keyring"), "secret", nil)
+	if err != nil {
+		t.Fatalf("NewFileKeyring: %v", err)
+	}
+	client, cleanup := startAgent(t, f)
+	defer cleanup()
+	if err := client.Add(restricted); err != nil {
+		t.Fatalf("ServeAgent(FileKeyring): Add: %v", err)
+	}
+	if _, err := client.Sign(testPublicKeys["ed25519"], userAuthData([]byte("s"), "user", testPublicKeys["ed25519"], nil)); err == nil {
+		t.Error("FileKeyring signed with a destination-constrained key on an unbound connection")
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+)
+
+// A DiscoveryMethod is a way for Discover to find an agent.
+type DiscoveryMethod string
+
+const (
+	// DiscoverAuthSock connects to the agent that the SSH_AUTH_SOCK
+	// environment variable points to, a Unix socket or, on Windows, a
+	// named pipe.
+	DiscoverAuthSock DiscoveryMethod = "SSH_AUTH_SOCK"
+
+	// DiscoverOpenSSHPipe connects to the agent of OpenSSH for Windows,
+	// on OpenSSHPipe. It is only supported on Windows.
+	DiscoverOpenSSHPipe DiscoveryMethod = "OpenSSH pipe"
+
+	// DiscoverPageant connects to Pageant, the agent of PuTTY. It is only
+	// supported on Windows.
+	DiscoverPageant DiscoveryMethod = "Pageant"
+)
+
+// DefaultDiscoveryOrder is the order in which Discover tries the methods
+// when none are given.
+var DefaultDiscoveryOrder = []DiscoveryMethod{DiscoverAuthSock, DiscoverOpenSSHPipe, DiscoverPageant}
+
+var errDiscoveryUnsupported = errors.New("not supported on this platform")
+
+// A Discovery describes the agent that Discover connected to.
+type Discovery struct {
+	// Method is the method that found the agent.
+	Method DiscoveryMethod
+
+	// Address is the path of the Unix socket or the name of the pipe on
+	// which the agent listens, or empty for Pageant.
+	Address string
+
+	conn io.Closer
+}
+
+func (d *Discovery) String() string {
+	if d.Address == "" {
+		return string(d.Method)
+	}
+	return fmt.Sprintf("%s (%s)", d.Method, d.Address)
+}
+
+// Close closes the connection to the agent.
+func (d *Discovery) Close() error {
+	return d.conn.Close()
+}
+
+// Discover connects to the first agent found by methods, tried in order,
+// or by DefaultDiscoveryOrder if none are given. It returns a client for
+// the agent and a description of it, whose Close method must be called
+// once the client is no longer needed.
+func Discover(methods ...DiscoveryMethod) (ExtendedAgent, *Discovery, error) {
+	if len(methods) == 0 {
+		methods = DefaultDiscoveryOrder
+	}
+	var errs []error
+	for _, m := range methods {
+		d := &Discovery{Method: m}
+		var client ExtendedAgent
+		var err error
+		switch m {
+		case DiscoverAuthSock:
+			d.Address = os.Getenv("SSH_AUTH_SOCK")
+			client, d.conn, err = dialAuthSock(d.Address)
+		case DiscoverOpenSSHPipe:
+			d.Address = openSSHPipe
+			client, d.conn, err = discoverPipe(d.Address)
+		case DiscoverPageant:
+			client, d.conn, err = discoverPageant()
+		default:
+			err = errors.New("unknown discovery method")
+		}
+		if err == nil {
+			return client, d, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", m, err))
+	}
+	return nil, nil, fmt.Errorf("agent: no agent found: %w", errors.Join(errs...))
+}
+
+func dialAuthSock(addr string) (ExtendedAgent, io.Closer, error) {
+	if addr == "" {
+		return nil, nil, errors.New("SSH_AUTH_SOCK is not set")
+	}
+	if strings.HasPrefix(addr, `\\.\pipe\`) {
+		return discoverPipe(addr)
+	}
+	conn, err := net.Dial("unix", addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	return NewClient(conn), conn, nil
+}
@@ -0,0 +1,62 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package agent
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestDiscoverAuthSock(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skipping on windows, which may not support Unix sockets")
+	}
+	dir, err := os.MkdirTemp("", "agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "agent.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	keyring := NewKeyring()
+	if err := keyring.Add(AddedKey{PrivateKey: testPrivateKeys["ed25519"], Comment: "found"}); err != nil {
+		t.Fatal(err)
+	}
+	go Serve(l, keyring)
+
+	t.Setenv("SSH_AUTH_SOCK", socket)
+	client, d, err := Discover()
+	if err != nil {
+		t.Fatalf("Discover: %v", err)
+	}
+	defer d.Close()
+	if d.Method != DiscoverAuthSock || d.Address != socket {
+		t.Errorf("discovered %v, want SSH_AUTH_SOCK at %s", d, socket)
+	}
+	if got := listComments(t, client); !got["found"] {
+		t.Errorf("listed %v from the discovered agent", got)
+	}
+}
+
+func TestDiscoverNotFound(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+	_, _, err := Discover(DiscoverAuthSock, "bogus")
+	if err == nil {
+		t.Fatal("Discover succeeded without an agent")
+	}
+	for _, want := range []string{"SSH_AUTH_SOCK is not set", "bogus: unknown discovery method"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
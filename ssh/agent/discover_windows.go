@@ -0,0 +1,19 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build windows
+
+package agent
+
+import "io"
+
+const openSSHPipe = OpenSSHPipe
+
+func discoverPipe(name string) (ExtendedAgent, io.Closer, error) {
+	return DialPipe(name)
+}
+
+func discoverPageant() (ExtendedAgent, io.Closer, error) {
+	return DialPageant()
+}
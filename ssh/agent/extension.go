@@ -0,0 +1,163 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// agentExtensionResponse is the reply to some extension requests, such as
+// the query extension. See [PROTOCOL.agent], section 3.8.
+const agentExtensionResponse = 29
+
+// QueryExtension is the extension with which clients ask an agent which
+// extensions it supports.
+const QueryExtension = "query"
+
+// An ExtensionHandler handles the contents of an extension request. It
+// returns the complete response message, including its type byte, or nil
+// for an SSH_AGENT_SUCCESS message. If it returns ErrExtensionUnsupported,
+// an SSH_AGENT_FAILURE message is sent, and for any other error an
+// SSH_AGENT_EXTENSION_FAILURE message.
+type ExtensionHandler func(contents []byte) ([]byte, error)
+
+// ExtensionAgent adds extension handlers to an Agent. It is typically
+// served with ServeAgent. It answers the query extension with the
+// extensions that are registered.
+type ExtensionAgent struct {
+	Agent
+
+	mu       sync.RWMutex
+	handlers map[string]ExtensionHandler
+}
+
+// NewExtensionAgent returns an ExtensionAgent without handlers that passes
+// requests on to agent. Extension requests without a handler are passed on
+// too if agent is an ExtendedAgent.
+func NewExtensionAgent(agent Agent) *ExtensionAgent {
+	return &ExtensionAgent{Agent: agent, handlers: make(map[string]ExtensionHandler)}
+}
+
+// HandleExtension registers the handler for extensionType, replacing any
+// previous one. A nil handler removes it.
+func (a *ExtensionAgent) HandleExtension(extensionType string, handler ExtensionHandler) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if handler == nil {
+		delete(a.handlers, extensionType)
+	} else {
+		a.handlers[extensionType] = handler
+	}
+}
+
+// Add adds a key to the upstream agent.
+func (a *ExtensionAgent) Add(key AddedKey) error {
+	if err := checkDestinationSupport(a, key); err != nil {
+		return err
+	}
+	return a.Agent.Add(key)
+}
+
+// SignWithFlags implements ExtendedAgent. Without flags, it falls back to
+// the Sign method of agents that are not ExtendedAgents.
+func (a *ExtensionAgent) SignWithFlags(key ssh.PublicKey, data []byte, flags SignatureFlags) (*ssh.Signature, error) {
+	if ext, ok := a.Agent.(ExtendedAgent); ok {
+		return ext.SignWithFlags(key, data, flags)
+	}
+	if flags != 0 {
+		return nil, fmt.Errorf("agent: signature flags %d not supported", flags)
+	}
+	return a.Agent.Sign(key, data)
+}
+
+// Extension implements ExtendedAgent.
+func (a *ExtensionAgent) Extension(extensionType string, contents []byte) ([]byte, error) {
+	a.mu.RLock()
+	handler, ok := a.handlers[extensionType]
+	a.mu.RUnlock()
+	switch {
+	case ok:
+		return handler(contents)
+	case extensionType == QueryExtension:
+		return a.query(), nil
+	}
+	if ext, ok := a.Agent.(ExtendedAgent); ok {
+		return ext.Extension(extensionType, contents)
+	}
+	return nil, ErrExtensionUnsupported
+}
+
+type queryResponse struct {
+	ExtensionType string `sshtype:"29"`
+	Rest          []byte `ssh:"rest"`
+}
+
+func (a *ExtensionAgent) query() []byte {
+	a.mu.RLock()
+	names := []string{QueryExtension}
+	for name := range a.handlers {
+		if name != QueryExtension {
+			names = append(names, name)
+		}
+	}
+	a.mu.RUnlock()
+	sort.Strings(names[1:])
+
+	var list []byte
+	for _, name := range names {
+		list = append(list, ssh.Marshal(struct{ Name string }{name})...)
+	}
+	return ssh.Marshal(queryResponse{ExtensionType: QueryExtension, Rest: list})
+}
+
+// QueryExtensions asks agent which extensions it supports, with the query
+// extension. It returns ErrExtensionUnsupported if the agent doesn't support
+// the query extension.
+func QueryExtensions(agent ExtendedAgent) ([]string, error) {
+	resp, err := agent.Extension(QueryExtension, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp) == 0 {
+		return nil, errors.New("agent: empty response to query extension")
+	}
+	var rest []byte
+	switch resp[0] {
+	case agentExtensionResponse:
+		var msg queryResponse
+		if err := ssh.Unmarshal(resp, &msg); err != nil {
+			return nil, err
+		}
+		if msg.ExtensionType != QueryExtension {
+			return nil, fmt.Errorf("agent: unexpected response to query extension: %q", msg.ExtensionType)
+		}
+		rest = msg.Rest
+	case agentSuccess:
+		// Older versions of [PROTOCOL.agent] list the extensions right
+		// after SSH_AGENT_SUCCESS.
+		rest = resp[1:]
+	default:
+		return nil, fmt.Errorf("agent: unexpected response to query extension: type %d", resp[0])
+	}
+
+	var names []string
+	for len(rest) > 0 {
+		var name struct {
+			Name string
+			Rest []byte `ssh:"rest"`
+		}
+		if err := ssh.Unmarshal(rest, &name); err != nil {
+			return nil, errors.New("agent: malformed response to query extension")
+		}
+		names = append(names, name.Name)
+		rest = name.Rest
+	}
+	return names, nil
+}
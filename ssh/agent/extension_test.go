@@ -0,0 +1,66 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package agent
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestExtensionAgent(t *testing.T) {
+	ext := NewExtensionAgent(NewKeyring())
+	ext.HandleExtension("echo@example.com", func(contents []byte) ([]byte, error) {
+		return append([]byte{agentSuccess}, contents...), nil
+	})
+	ext.HandleExtension("fail@example.com", func(contents []byte) ([]byte, error) {
+		return nil, errors.New("failed")
+	})
+	ext.HandleExtension("ack@example.com", func(contents []byte) ([]byte, error) {
+		return nil, nil
+	})
+	client, cleanup := startAgent(t, ext)
+	defer cleanup()
+
+	if resp, err := client.Extension("echo@example.com", []byte{1, 2, 3}); err != nil || !bytes.Equal(resp, []byte{agentSuccess, 1, 2, 3}) {
+		t.Errorf("echo extension = %v, %v", resp, err)
+	}
+	if resp, err := client.Extension("ack@example.com", nil); err != nil || !bytes.Equal(resp, []byte{agentSuccess}) {
+		t.Errorf("ack extension = %v, %v", resp, err)
+	}
+	if _, err := client.Extension("fail@example.com", nil); err == nil || err == ErrExtensionUnsupported {
+		t.Errorf("failing extension = %v, want a generic failure", err)
+	}
+	if _, err := client.Extension("other@example.com", nil); err != ErrExtensionUnsupported {
+		t.Errorf("unregistered extension = %v, want ErrExtensionUnsupported", err)
+	}
+
+	names, err := QueryExtensions(client)
+	if want := []string{"query", "ack@example.com", "echo@example.com", "fail@example.com"}; err != nil || !reflect.DeepEqual(names, want) {
+		t.Errorf("QueryExtensions = %q, %v, want %q", names, err, want)
+	}
+
+	ext.HandleExtension("fail@example.com", nil)
+	if _, err := client.Extension("fail@example.com", nil); err != ErrExtensionUnsupported {
+		t.Errorf("removed extension = %v, want ErrExtensionUnsupported", err)
+	}
+
+	// Other requests go to the wrapped agent.
+	if err := client.Add(AddedKey{PrivateKey: testPrivateKeys["ed25519"]}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if _, err := client.SignWithFlags(testPublicKeys["ed25519"], []byte("data"), 0); err != nil {
+		t.Errorf("SignWithFlags: %v", err)
+	}
+}
+
+func TestQueryExtensionsUnsupported(t *testing.T) {
+	client, cleanup := startKeyringAgent(t)
+	defer cleanup()
+	if _, err := QueryExtensions(client); err != ErrExtensionUnsupported {
+		t.Errorf("QueryExtensions = %v, want ErrExtensionUnsupported", err)
+	}
+}
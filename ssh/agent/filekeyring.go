@@ -0,0 +1,478 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package agent
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	fileKeyringMagic = "golang.org/x/crypto/ssh/agent keyring v1"
+	fileKeyringKDF   = "scrypt"
+
+	// The scrypt parameters of new keyring files.
+	fileKeyringLogN = 15
+	fileKeyringR    = 8
+	fileKeyringP    = 1
+)
+
+// FileKeyringConfig configures a FileKeyring.
+type FileKeyringConfig struct {
+	// Path is the file that the keys are stored in. It is created if it
+	// does not exist.
+	Path string
+
+	// Passphrase returns the secret that the file is encrypted with. It is
+	// called once, when the keyring is opened, and may prompt the user or
+	// fetch the secret from an OS keychain.
+	Passphrase func() ([]byte, error)
+
+	// Confirm is called before a key added with ConfirmBeforeUse is used
+	// to sign, and reports whether the use is allowed, typically after
+	// asking the user. If nil, such keys can't be used.
+	Confirm func(key *Key) bool
+}
+
+// FileKeyring is an agent that holds keys like NewKeyring, but also stores
+// them, with their constraints, in a file encrypted with a passphrase, so
+// that they survive restarts. It honors the lifetime and ConfirmBeforeUse
+// constraints, and it is safe for concurrent use by multiple goroutines.
+//
+// Served with Serve on a Unix socket, it can replace ssh-agent for clients
+// that use SSH_AUTH_SOCK.
+type FileKeyring struct {
+	path    string
+	confirm func(key *Key) bool
+	*keyring
+
+	// mu guards the fields below and writes to the file.
+	mu      sync.Mutex
+	header  fileKeyringHeader
+	aead    []byte
+	entries []storedKey
+}
+
+// fileKeyringHeader is the unencrypted start of a keyring file.
+type fileKeyringHeader struct {
+	Magic      string
+	KDF        string
+	Salt       []byte
+	LogN, R, P uint32
+}
+
+// fileKeyringFile is the contents of a keyring file. Header is the
+// marshaled fileKeyringHeader, and Data is a random XChaCha20-Poly1305 nonce
+// followed by the sealed entries, each marshaled as a string.
+type fileKeyringFile struct {
+	Header []byte
+	Data   []byte
+}
+
+// fileKeyringEntry is a key stored in a keyring file.
+type fileKeyringEntry struct {
+	// PrivateKey is the key in the unencrypted OpenSSH PEM format.
+	PrivateKey  []byte
+	Certificate []byte
+	Comment     string
+	// Expire is the Unix time at which the key expires, or 0.
+	Expire  uint64
+	Confirm bool
+	// Constraints are the marshaled ConstraintExtensions of the key.
+	Constraints []byte
+}
+
+// storedKey is a key of a FileKeyring.
+type storedKey struct {
+	entry fileKeyringEntry
+	// blob is the public key, or certificate, of the key.
+	blob []byte
+}
+
+// NewFileKeyring opens the keyring file of config, creating it if needed.
+func NewFileKeyring(config FileKeyringConfig) (*FileKeyring, error) {
+	if config.Passphrase == nil {
+		return nil, errors.New("agent: FileKeyringConfig.Passphrase is nil")
+	}
+	passphrase, err := config.Passphrase()
+	if err != nil {
+		return nil, err
+	}
+	f := &FileKeyring{path: config.Path, confirm: config.Confirm, keyring: &keyring{}}
+
+	data, err := os.ReadFile(config.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		f.header = fileKeyringHeader{
+			Magic: fileKeyringMagic,
+			KDF:   fileKeyringKDF,
+			Salt:  make([]byte, 16),
+			LogN:  fileKeyringLogN,
+			R:     fileKeyringR,
+			P:     fileKeyringP,
+		}
+		if _, err := rand.Read(f.header.Salt); err != nil {
+			return nil, err
+		}
+		if f.aead, err = f.header.deriveKey(passphrase); err != nil {
+			return nil, err
+		}
+		if err := f.saveLocked(); err != nil {
+			return nil, err
+		}
+		return f, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var file fileKeyringFile
+	if err := ssh.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("agent: %s is not a keyring file", config.Path)
+	}
+	if err := ssh.Unmarshal(file.Header, &f.header); err != nil || f.header.Magic != fileKeyringMagic {
+		return nil, fmt.Errorf("agent: %s is not a keyring file", config.Path)
+	}
+	if f.header.KDF != fileKeyringKDF {
+		return nil, fmt.Errorf("agent: unsupported keyring file KDF %q", f.header.KDF)
+	}
+	if f.aead, err = f.header.deriveKey(passphrase); err != nil {
+		return nil, err
+	}
+	plaintext, err := f.open(file.Header, file.Data)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.load(plaintext); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (h *fileKeyringHeader) deriveKey(passphrase []byte) ([]byte, error) {
+	if h.LogN == 0 || h.LogN > 30 {
+		return nil, fmt.Errorf("agent: invalid keyring file scrypt cost %d", h.LogN)
+	}
+	key, err := scrypt.Key(passphrase, h.Salt, 1<<h.LogN, int(h.R), int(h.P), chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, fmt.Errorf("agent: invalid keyring file scrypt parameters: %v", err)
+	}
+	return key, nil
+}
+
+func (f *FileKeyring) open(header, data []byte) ([]byte, error) {
+	aead, err := chacha20poly1305.NewX(f.aead)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("agent: truncated keyring file")
+	}
+	plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], header)
+	if err != nil {
+		return nil, errors.New("agent: incorrect passphrase for keyring file")
+	}
+	return plaintext, nil
+}
+
+// load adds the unexpired keys of plaintext to the keyring.
+func (f *FileKeyring) load(plaintext []byte) error {
+	for len(plaintext) > 0 {
+		var msg struct {
+			Entry []byte
+			Rest  []byte `ssh:"rest"`
+		}
+		var e fileKeyringEntry
+		if err := ssh.Unmarshal(plaintext, &msg); err != nil {
+			return fmt.Errorf("agent: malformed keyring file entry: %v", err)
+		}
+		if err := ssh.Unmarshal(msg.Entry, &e); err != nil {
+			return fmt.Errorf("agent: malformed keyring file entry: %v", err)
+		}
+		plaintext = msg.Rest
+
+		key := AddedKey{Comment: e.Comment}
+		var err error
+		if key.PrivateKey, err = ssh.ParseRawPrivateKey(e.PrivateKey); err != nil {
+			return fmt.Errorf("agent: keyring file entry: %v", err)
+		}
+		if len(e.Certificate) > 0 {
+			pub, err := ssh.ParsePublicKey(e.Certificate)
+			if err != nil {
+				return fmt.Errorf("agent: keyring file entry: %v", err)
+			}
+			cert, ok := pub.(*ssh.Certificate)
+			if !ok {
+				return errors.New("agent: keyring file entry with a certificate that is not one")
+			}
+			key.Certificate = cert
+		}
+		if e.Expire != 0 {
+			left := time.Until(time.Unix(int64(e.Expire), 0))
+			if left <= 0 {
+				continue
+			}
+			key.LifetimeSecs = uint32((left + time.Second - 1) / time.Second)
+		}
+		if key.ConstraintExtensions, err = parseConstraintExtensions(e.Constraints); err != nil {
+			return err
+		}
+		if err := f.keyring.Add(key); err != nil {
+			return err
+		}
+		blob, err := addedKeyBlob(key)
+		if err != nil {
+			return err
+		}
+		f.entries = append(f.entries, storedKey{e, blob})
+	}
+	return nil
+}
+
+func parseConstraintExtensions(b []byte) ([]ConstraintExtension, error) {
+	var exts []ConstraintExtension
+	for len(b) > 0 {
+		var msg struct {
+			ExtensionName    string
+			ExtensionDetails []byte
+			Rest             []byte `ssh:"rest"`
+		}
+		if err := ssh.Unmarshal(b, &msg); err != nil {
+			return nil, fmt.Errorf("agent: malformed keyring file constraint: %v", err)
+		}
+		exts = append(exts, ConstraintExtension{msg.ExtensionName, msg.ExtensionDetails})
+		b = msg.Rest
+	}
+	return exts, nil
+}
+
+// addedKeyBlob returns the public key, or certificate, of key.
+func addedKeyBlob(key AddedKey) ([]byte, error) {
+	if key.Certificate != nil {
+		return key.Certificate.Marshal(), nil
+	}
+	signer, err := ssh.NewSignerFromKey(key.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	return signer.PublicKey().Marshal(), nil
+}
+
+// saveLocked writes the unexpired keys to the file, replacing it
+// atomically. The caller must hold f.mu.
+func (f *FileKeyring) saveLocked() error {
+	now := uint64(time.Now().Unix())
+	entries := f.entries[:0]
+	var plaintext []byte
+	for _, k := range f.entries {
+		if k.entry.Expire != 0 && k.entry.Expire <= now {
+			continue
+		}
+		entries = append(entries, k)
+		plaintext = append(plaintext, ssh.Marshal(struct{ Entry []byte }{ssh.Marshal(k.entry)})...)
+	}
+	f.entries = entries
+
+	aead, err := chacha20poly1305.NewX(f.aead)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	header := ssh.Marshal(f.header)
+	data := aead.Seal(nonce, nonce, plaintext, header)
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(ssh.Marshal(fileKeyringFile{header, data})); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
+
+// removeEntriesLocked removes the entries for blob. The caller must hold
+// f.mu.
+func (f *FileKeyring) removeEntriesLocked(blob []byte) {
+	entries := f.entries[:0]
+	for _, k := range f.entries {
+		if !bytes.Equal(k.blob, blob) {
+			entries = append(entries, k)
+		}
+	}
+	f.entries = entries
+}
+
+// List returns the identities known to the agent.
+func (f *FileKeyring) List() ([]*Key, error) {
+	return f.keyring.List()
+}
+
+// Add adds a key to the keyring and stores it, replacing the key if it is
+// already stored. Only keys that can be marshaled in the OpenSSH format
+// can be added.
+func (f *FileKeyring) Add(key AddedKey) error {
+	block, err := ssh.MarshalPrivateKey(key.PrivateKey, "")
+	if err != nil {
+		return err
+	}
+	e := fileKeyringEntry{
+		PrivateKey: pem.EncodeToMemory(block),
+		Comment:    key.Comment,
+		Confirm:    key.ConfirmBeforeUse,
+	}
+	if key.Certificate != nil {
+		e.Certificate = key.Certificate.Marshal()
+	}
+	if key.LifetimeSecs > 0 {
+		e.Expire = uint64(time.Now().Add(time.Duration(key.LifetimeSecs) * time.Second).Unix())
+	}
+	for _, ext := range key.ConstraintExtensions {
+		e.Constraints = append(e.Constraints, ssh.Marshal(ext)...)
+	}
+	blob, err := addedKeyBlob(key)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r := f.keyring
+	r.mu.Lock()
+	if r.locked {
+		r.mu.Unlock()
+		return errLocked
+	}
+	// Replace any older copy of the key, so that the new constraints apply.
+	r.removeLocked(blob)
+	err = r.addLocked(key)
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	f.removeEntriesLocked(blob)
+	f.entries = append(f.entries, storedKey{e, blob})
+	return f.saveLocked()
+}
+
+// Remove removes all identities with the given public key.
+func (f *FileKeyring) Remove(key ssh.PublicKey) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.keyring.Remove(key); err != nil {
+		return err
+	}
+	f.removeEntriesLocked(key.Marshal())
+	return f.saveLocked()
+}
+
+// RemoveAll removes all identities.
+func (f *FileKeyring) RemoveAll() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.keyring.RemoveAll(); err != nil {
+		return err
+	}
+	f.entries = nil
+	return f.saveLocked()
+}
+
+// Lock locks the agent. The keys stay stored, and the agent is unlocked
+// again when it is restarted.
+func (f *FileKeyring) Lock(passphrase []byte) error {
+	return f.keyring.Lock(passphrase)
+}
+
+// Unlock undoes the effect of Lock.
+func (f *FileKeyring) Unlock(passphrase []byte) error {
+	return f.keyring.Unlock(passphrase)
+}
+
+// Sign returns a signature for the data.
+func (f *FileKeyring) Sign(key ssh.PublicKey, data []byte) (*ssh.Signature, error) {
+	return f.SignWithFlags(key, data, 0)
+}
+
+// SignWithFlags returns a signature for the data, once confirmed if the key
+// was added with ConfirmBeforeUse.
+func (f *FileKeyring) SignWithFlags(key ssh.PublicKey, data []byte, flags SignatureFlags) (*ssh.Signature, error) {
+	if err := f.checkConfirm(key); err != nil {
+		return nil, err
+	}
+	return f.keyring.SignWithFlags(key, data, flags)
+}
+
+// checkConfirm asks for the use of key to be confirmed, if its constraints
+// require it.
+func (f *FileKeyring) checkConfirm(key ssh.PublicKey) error {
+	f.keyring.mu.Lock()
+	locked := f.keyring.locked
+	f.keyring.mu.Unlock()
+	if locked {
+		return errLocked
+	}
+
+	wanted := key.Marshal()
+	now := uint64(time.Now().Unix())
+	f.mu.Lock()
+	var k *Key
+	for _, s := range f.entries {
+		if bytes.Equal(s.blob, wanted) && (s.entry.Expire == 0 || s.entry.Expire > now) {
+			if s.entry.Confirm {
+				k = &Key{Format: key.Type(), Blob: wanted, Comment: s.entry.Comment}
+			}
+			break
+		}
+	}
+	f.mu.Unlock()
+	if k == nil {
+		return nil
+	}
+
+	if f.confirm == nil || !f.confirm(k) {
+		return errors.New("agent: use of key not confirmed")
+	}
+	return nil
+}
+
+// Signers returns signers for all the known keys, which sign through the
+// agent so that ConfirmBeforeUse is honored.
+func (f *FileKeyring) Signers() ([]ssh.Signer, error) {
+	signers, err := f.keyring.Signers()
+	if err != nil {
+		return nil, err
+	}
+	for i, s := range signers {
+		signers[i] = &agentKeyringSigner{f, s.PublicKey()}
+	}
+	return signers, nil
+}
+
+// The FileKeyring does not support any extensions.
+func (f *FileKeyring) Extension(extensionType string, contents []byte) ([]byte, error) {
+	return nil, ErrExtensionUnsupported
+}
@@ -0,0 +1,141 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package agent
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func openFileKeyring(t *testing.T, path, passphrase string, confirm func(*Key) bool) (*FileKeyring, error) {
+	t.Helper()
+	return NewFileKeyring(FileKeyringConfig{
+		Path:       path,
+		Passphrase: func() ([]byte, error) { return []byte(passphrase), nil },
+		Confirm:    confirm,
+	})
+}
+
+func TestFileKeyring(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keyring")
+	f, err := openFileKeyring(t, path, "secret", nil)
+	if err != nil {
+		t.Fatalf("NewFileKeyring: %v", err)
+	}
+	for name, key := range map[string]AddedKey{
+		"rsa":     {PrivateKey: testPrivateKeys["rsa"], Comment: "rsa"},
+		"ecdsa":   {PrivateKey: testPrivateKeys["ecdsa"], Comment: "gone", LifetimeSecs: 3600},
+		"ed25519": {PrivateKey: testPrivateKeys["ed25519"], Comment: "confirm", ConfirmBeforeUse: true},
+	} {
+		if err := f.Add(key); err != nil {
+			t.Fatalf("Add(%s): %v", name, err)
+		}
+	}
+	if err := f.Add(AddedKey{PrivateKey: testPrivateKeys["rsa"], Comment: "rsa", LifetimeSecs: 3600}); err != nil {
+		t.Fatalf("Add again: %v", err)
+	}
+	if _, err := f.Sign(testPublicKeys["ed25519"], []byte("data")); err == nil {
+		t.Error("signed with a key that requires confirmation without Confirm")
+	}
+	if info, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	} else if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("keyring file mode %v, want 0600", info.Mode().Perm())
+	}
+
+	// Expire the ecdsa key in the file.
+	f.mu.Lock()
+	for i := range f.entries {
+		if f.entries[i].entry.Comment == "gone" {
+			f.entries[i].entry.Expire = uint64(time.Now().Add(-time.Second).Unix())
+		}
+	}
+	f.saveLocked()
+	f.mu.Unlock()
+
+	if _, err := openFileKeyring(t, path, "wrong", nil); err == nil {
+		t.Error("opened a keyring with the wrong passphrase")
+	}
+
+	var confirmed []string
+	f, err = openFileKeyring(t, path, "secret", func(k *Key) bool {
+		confirmed = append(confirmed, k.Comment)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("reopening: %v", err)
+	}
+	if got := listComments(t, f); len(got) != 2 || !got["rsa"] || !got["confirm"] {
+		t.Errorf("listed %v after reopening, want rsa and confirm", got)
+	}
+	if f.entries[0].entry.Expire == 0 && f.entries[1].entry.Expire == 0 {
+		t.Error("lifetime of the key added again was not stored")
+	}
+	for _, name := range []string{"rsa", "ed25519"} {
+		sig, err := f.Sign(testPublicKeys[name], []byte("data"))
+		if err != nil {
+			t.Fatalf("Sign(%s): %v", name, err)
+		}
+		if err := testPublicKeys[name].Verify([]byte("data"), sig); err != nil {
+			t.Errorf("Verify(%s): %v", name, err)
+		}
+	}
+	if len(confirmed) != 1 || confirmed[0] != "confirm" {
+		t.Errorf("confirmed %v, want only the confirm key", confirmed)
+	}
+
+	if err := f.Lock([]byte("lock")); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Add(AddedKey{PrivateKey: testPrivateKeys["ecdsa"]}); err != errLocked {
+		t.Errorf("Add to a locked keyring = %v, want errLocked", err)
+	}
+	if err := f.Unlock([]byte("lock")); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Remove(testPublicKeys["rsa"]); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	f, err = openFileKeyring(t, path, "secret", nil)
+	if err != nil {
+		t.Fatalf("reopening: %v", err)
+	}
+	if got := listComments(t, f); len(got) != 1 || !got["confirm"] {
+		t.Errorf("listed %v after Remove, want confirm", got)
+	}
+}
+
+func TestServe(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skipping on windows, which may not support Unix sockets")
+	}
+	dir, err := os.MkdirTemp("", "agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "agent.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	f, err := openFileKeyring(t, filepath.Join(dir, "keyring"), "secret", nil)
+	if err != nil {
+		t.Fatalf("NewFileKeyring: %v", err)
+	}
+	go Serve(l, f)
+
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	testAgentInterface(t, NewClient(conn), testPrivateKeys["ed25519"], nil, 0)
+}
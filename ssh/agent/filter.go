@@ -0,0 +1,221 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package agent
+
+import (
+	"bytes"
+	"errors"
+
+	"golang.org/x/crypto/ssh"
+)
+
+var errNotPermitted = errors.New("agent: operation not permitted by filter")
+
+// FilterAgent is an agent that passes requests on to an upstream agent,
+// but only exposes some of its keys and denies the operations that change
+// it, unless allowed. It is typically offered to remote hosts with
+// ForwardToAgent, so that they can't see or use every key of the local
+// agent.
+type FilterAgent struct {
+	// Agent is the upstream agent.
+	Agent Agent
+
+	// Filter reports whether a key of the upstream agent is exposed. If
+	// nil, all keys are. FingerprintFilter and CommentFilter return
+	// common filters.
+	Filter func(key *Key) bool
+
+	// AllowAdd allows keys to be added to the upstream agent. Added keys
+	// are only exposed if they pass Filter.
+	AllowAdd bool
+
+	// AllowRemove allows exposed keys to be removed from the upstream
+	// agent. RemoveAll only removes the exposed keys.
+	AllowRemove bool
+
+	// AllowLock allows the upstream agent to be locked and unlocked.
+	AllowLock bool
+
+	// AllowExtensions are the extension requests that are passed on to
+	// the upstream agent, if it is an ExtendedAgent, besides
+	// SessionBindExtension. Other extension requests fail with
+	// ErrExtensionUnsupported.
+	AllowExtensions []string
+}
+
+// FingerprintFilter returns a filter for FilterAgent.Filter that exposes the
+// keys with the given fingerprints.
+func FingerprintFilter(fingerprints ...ssh.Fingerprint) func(key *Key) bool {
+	return func(key *Key) bool {
+		for _, fp := range fingerprints {
+			if fp.Matches(key) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// CommentFilter returns a filter for FilterAgent.Filter that exposes the keys
+// whose comment matches patterns, a comma-separated list of wildcard
+// patterns, which may be negated with '!', as for ssh.MatchPrincipalPattern.
+func CommentFilter(patterns string) func(key *Key) bool {
+	return func(key *Key) bool {
+		return ssh.MatchPrincipalPattern(key.Comment, []string{patterns})
+	}
+}
+
+// List returns the exposed keys of the upstream agent.
+func (a *FilterAgent) List() ([]*Key, error) {
+	keys, err := a.Agent.List()
+	if err != nil {
+		return nil, err
+	}
+	if a.Filter == nil {
+		return keys, nil
+	}
+	var exposed []*Key
+	for _, k := range keys {
+		if a.Filter(k) {
+			exposed = append(exposed, k)
+		}
+	}
+	return exposed, nil
+}
+
+// exposed reports whether key is an exposed key of the upstream agent.
+func (a *FilterAgent) exposed(key ssh.PublicKey) error {
+	keys, err := a.List()
+	if err != nil {
+		return err
+	}
+	wanted := key.Marshal()
+	for _, k := range keys {
+		if bytes.Equal(k.Blob, wanted) {
+			return nil
+		}
+	}
+	return errNotPermitted
+}
+
+// Sign signs data with an exposed key.
+func (a *FilterAgent) Sign(key ssh.PublicKey, data []byte) (*ssh.Signature, error) {
+	return a.SignWithFlags(key, data, 0)
+}
+
+// SignWithFlags signs data with an exposed key. Flags are only supported if
+// the upstream agent is an ExtendedAgent.
+func (a *FilterAgent) SignWithFlags(key ssh.PublicKey, data []byte, flags SignatureFlags) (*ssh.Signature, error) {
+	if err := a.exposed(key); err != nil {
+		return nil, err
+	}
+	if ext, ok := a.Agent.(ExtendedAgent); ok {
+		return ext.SignWithFlags(key, data, flags)
+	}
+	if flags != 0 {
+		return nil, errors.New("agent: signature flags not supported by upstream agent")
+	}
+	return a.Agent.Sign(key, data)
+}
+
+// Signers returns the signers of the exposed keys.
+func (a *FilterAgent) Signers() ([]ssh.Signer, error) {
+	signers, err := a.Agent.Signers()
+	if err != nil || a.Filter == nil {
+		return signers, err
+	}
+	keys, err := a.List()
+	if err != nil {
+		return nil, err
+	}
+	var exposed []ssh.Signer
+	for _, s := range signers {
+		blob := s.PublicKey().Marshal()
+		for _, k := range keys {
+			if bytes.Equal(k.Blob, blob) {
+				exposed = append(exposed, s)
+				break
+			}
+		}
+	}
+	return exposed, nil
+}
+
+// Add adds a key to the upstream agent, if allowed.
+func (a *FilterAgent) Add(key AddedKey) error {
+	if !a.AllowAdd {
+		return errNotPermitted
+	}
+	if err := checkDestinationSupport(a, key); err != nil {
+		return err
+	}
+	return a.Agent.Add(key)
+}
+
+// Remove removes an exposed key from the upstream agent, if allowed.
+func (a *FilterAgent) Remove(key ssh.PublicKey) error {
+	if !a.AllowRemove {
+		return errNotPermitted
+	}
+	if err := a.exposed(key); err != nil {
+		return err
+	}
+	return a.Agent.Remove(key)
+}
+
+// RemoveAll removes the exposed keys from the upstream agent, if allowed.
+func (a *FilterAgent) RemoveAll() error {
+	if !a.AllowRemove {
+		return errNotPermitted
+	}
+	if a.Filter == nil {
+		return a.Agent.RemoveAll()
+	}
+	keys, err := a.List()
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if err := a.Agent.Remove(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Lock locks the upstream agent, if allowed.
+func (a *FilterAgent) Lock(passphrase []byte) error {
+	if !a.AllowLock {
+		return errNotPermitted
+	}
+	return a.Agent.Lock(passphrase)
+}
+
+// Unlock unlocks the upstream agent, if allowed.
+func (a *FilterAgent) Unlock(passphrase []byte) error {
+	if !a.AllowLock {
+		return errNotPermitted
+	}
+	return a.Agent.Unlock(passphrase)
+}
+
+// Extension passes allowed extension requests on to the upstream agent.
+// SessionBindExtension requests are always passed on, so that the upstream
+// agent can enforce its restrictions.
+func (a *FilterAgent) Extension(extensionType string, contents []byte) ([]byte, error) {
+	ext, ok := a.Agent.(ExtendedAgent)
+	if !ok {
+		return nil, ErrExtensionUnsupported
+	}
+	if extensionType == SessionBindExtension {
+		return ext.Extension(extensionType, contents)
+	}
+	for _, allowed := range a.AllowExtensions {
+		if allowed == extensionType {
+			return ext.Extension(extensionType, contents)
+		}
+	}
+	return nil, ErrExtensionUnsupported
+}
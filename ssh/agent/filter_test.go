@@ -0,0 +1,96 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package agent
+
+import (
+	"crypto"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func filterTestKeyring(t *testing.T) Agent {
+	keyring := NewKeyring()
+	for name, comment := range map[string]string{
+		"rsa":     "work-rsa",
+		"ecdsa":   "work-ecdsa",
+		"ed25519": "personal",
+	} {
+		if err := keyring.Add(AddedKey{PrivateKey: testPrivateKeys[name], Comment: comment}); err != nil {
+			t.Fatalf("Add(%s): %v", name, err)
+		}
+	}
+	return keyring
+}
+
+func listComments(t *testing.T, agent Agent) map[string]bool {
+	t.Helper()
+	keys, err := agent.List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	comments := make(map[string]bool)
+	for _, k := range keys {
+		comments[k.Comment] = true
+	}
+	return comments
+}
+
+func TestFilterAgent(t *testing.T) {
+	upstream := filterTestKeyring(t)
+	filter := &FilterAgent{Agent: upstream, Filter: CommentFilter("work-*,!work-ecdsa")}
+	agent, cleanup := startAgent(t, filter)
+	defer cleanup()
+
+	if got := listComments(t, agent); len(got) != 1 || !got["work-rsa"] {
+		t.Errorf("listed %v, want only work-rsa", got)
+	}
+	if _, err := agent.Sign(testPublicKeys["rsa"], []byte("data")); err != nil {
+		t.Errorf("Sign with an exposed key: %v", err)
+	}
+	if _, err := agent.Sign(testPublicKeys["ed25519"], []byte("data")); err == nil {
+		t.Error("signed with a hidden key")
+	}
+	signers, err := filter.Signers()
+	if err != nil || len(signers) != 1 {
+		t.Errorf("Signers = %d signers, %v, want one", len(signers), err)
+	}
+
+	if err := agent.Add(AddedKey{PrivateKey: testPrivateKeys["dsa"]}); err == nil {
+		t.Error("Add succeeded without AllowAdd")
+	}
+	if err := agent.Remove(testPublicKeys["rsa"]); err == nil {
+		t.Error("Remove succeeded without AllowRemove")
+	}
+	if err := agent.Lock([]byte("pass")); err == nil {
+		t.Error("Lock succeeded without AllowLock")
+	}
+	if _, err := agent.Extension("query", nil); err != ErrExtensionUnsupported {
+		t.Errorf("Extension = %v, want ErrExtensionUnsupported", err)
+	}
+
+	// Only the exposed keys are removed.
+	filter.AllowRemove = true
+	if err := agent.Remove(testPublicKeys["ed25519"]); err == nil {
+		t.Error("removed a hidden key")
+	}
+	if err := agent.RemoveAll(); err != nil {
+		t.Fatalf("RemoveAll: %v", err)
+	}
+	if got := listComments(t, upstream); len(got) != 2 || got["work-rsa"] {
+		t.Errorf("upstream keys after RemoveAll: %v", got)
+	}
+}
+
+func TestFingerprintFilter(t *testing.T) {
+	fp, err := ssh.NewFingerprint(testPublicKeys["ed25519"], crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	agent := &FilterAgent{Agent: filterTestKeyring(t), Filter: FingerprintFilter(fp)}
+	if got := listComments(t, agent); len(got) != 1 || !got["personal"] {
+		t.Errorf("listed %v, want only personal", got)
+	}
+}
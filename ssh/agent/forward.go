@@ -6,8 +6,10 @@ package agent
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"net"
+	"os"
 	"sync"
 
 	"golang.org/x/crypto/ssh"
@@ -17,7 +19,7 @@ import (
 // ForwardToAgent or ForwardToRemote should be called to route
 // the authentication requests.
 func RequestAgentForwarding(session *ssh.Session) error {
-	ok, err := session.SendRequest("auth-agent-req@openssh.com", true, nil)
+	ok, err := session.SendRequest(agentRequestType, true, nil)
 	if err != nil {
 		return err
 	}
@@ -28,11 +30,18 @@ func RequestAgentForwarding(session *ssh.Session) error {
 }
 
 // ForwardToAgent routes authentication requests to the given keyring.
+// Each forwarded connection is bound to the session of client for
+// forwarding, as with SessionBindExtension, and so are extended agents
+// such as a client of ssh-agent. Since such an agent is shared by all the
+// forwarded connections, the bindings that they make are all applied to
+// it; ForwardToRemote gives each connection its own connection to
+// ssh-agent instead.
 func ForwardToAgent(client *ssh.Client, keyring Agent) error {
 	channels := client.HandleChannelOpen(channelType)
 	if channels == nil {
 		return errors.New("agent: already have handler for " + channelType)
 	}
+	bindMsg := clientSessionBindMsg(client)
 
 	go func() {
 		for ch := range channels {
@@ -42,8 +51,14 @@ func ForwardToAgent(client *ssh.Client, keyring Agent) error {
 			}
 			go ssh.DiscardRequests(reqs)
 			go func() {
-				ServeAgent(keyring, channel)
-				channel.Close()
+				defer channel.Close()
+				s := &server{agent: keyring}
+				if bindMsg != nil {
+					if err := s.bindSession(bindMsg); err != nil {
+						return
+					}
+				}
+				s.serve(channel)
 			}()
 		}
 	}()
@@ -52,8 +67,171 @@ func ForwardToAgent(client *ssh.Client, keyring Agent) error {
 
 const channelType = "auth-agent@openssh.com"
 
+// agentRequestType is the session request with which clients ask for agent
+// forwarding.
+const agentRequestType = "auth-agent-req@openssh.com"
+
+// ForwardedAgent is the agent of a client that asked for agent forwarding on
+// a session, reached by opening auth-agent@openssh.com channels to the
+// client.
+type ForwardedAgent struct {
+	conn ssh.Conn
+}
+
+// AcceptAgentForwarding accepts an auth-agent-req@openssh.com request
+// received on a session of conn, typically a *ssh.ServerConn, and returns
+// the agent that the client forwards.
+func AcceptAgentForwarding(conn ssh.Conn, req *ssh.Request) (*ForwardedAgent, error) {
+	if req.Type != agentRequestType {
+		return nil, fmt.Errorf("agent: request type %q is not %s", req.Type, agentRequestType)
+	}
+	if req.WantReply {
+		if err := req.Reply(true, nil); err != nil {
+			return nil, err
+		}
+	}
+	return &ForwardedAgent{conn: conn}, nil
+}
+
+// Dial opens a new connection to the forwarded agent.
+func (f *ForwardedAgent) Dial() (net.Conn, error) {
+	ch, reqs, err := f.conn.OpenChannel(channelType, nil)
+	if err != nil {
+		return nil, err
+	}
+	go ssh.DiscardRequests(reqs)
+	return ssh.NewChannelConn(ch, nil, nil), nil
+}
+
+// Listen creates a Unix socket at path and forwards the connections made to
+// it to the agent in the background, until the returned listener is closed.
+// The path is typically passed in the SSH_AUTH_SOCK environment variable of
+// the commands run by the session.
+//
+// Only the owner may connect to the socket. As others could connect before
+// its permissions are set, path should be in a directory that only the
+// owner can access, such as one made by os.MkdirTemp.
+func (f *ForwardedAgent) Listen(path string) (net.Listener, error) {
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		l.Close()
+		return nil, err
+	}
+	go f.Serve(l)
+	return l, nil
+}
+
+// Serve forwards the connections accepted from l to the agent, until
+// l.Accept fails. It returns the error of Accept.
+func (f *ForwardedAgent) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			remote, err := f.Dial()
+			if err != nil {
+				return
+			}
+			defer remote.Close()
+			proxyConn(conn, remote)
+		}()
+	}
+}
+
+// Agent returns an agent that opens a new connection to the forwarded agent
+// for each operation.
+func (f *ForwardedAgent) Agent() ExtendedAgent {
+	return &forwardedAgent{f}
+}
+
+type forwardedAgent struct {
+	f *ForwardedAgent
+}
+
+// call runs op on a new connection to the forwarded agent.
+func (a *forwardedAgent) call(op func(ExtendedAgent) error) error {
+	conn, err := a.f.Dial()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return op(NewClient(conn))
+}
+
+func (a *forwardedAgent) List() (keys []*Key, err error) {
+	err = a.call(func(c ExtendedAgent) error {
+		keys, err = c.List()
+		return err
+	})
+	return keys, err
+}
+
+func (a *forwardedAgent) Sign(key ssh.PublicKey, data []byte) (sig *ssh.Signature, err error) {
+	err = a.call(func(c ExtendedAgent) error {
+		sig, err = c.Sign(key, data)
+		return err
+	})
+	return sig, err
+}
+
+func (a *forwardedAgent) SignWithFlags(key ssh.PublicKey, data []byte, flags SignatureFlags) (sig *ssh.Signature, err error) {
+	err = a.call(func(c ExtendedAgent) error {
+		sig, err = c.SignWithFlags(key, data, flags)
+		return err
+	})
+	return sig, err
+}
+
+func (a *forwardedAgent) Add(key AddedKey) error {
+	return a.call(func(c ExtendedAgent) error { return c.Add(key) })
+}
+
+func (a *forwardedAgent) Remove(key ssh.PublicKey) error {
+	return a.call(func(c ExtendedAgent) error { return c.Remove(key) })
+}
+
+func (a *forwardedAgent) RemoveAll() error {
+	return a.call(func(c ExtendedAgent) error { return c.RemoveAll() })
+}
+
+func (a *forwardedAgent) Lock(passphrase []byte) error {
+	return a.call(func(c ExtendedAgent) error { return c.Lock(passphrase) })
+}
+
+func (a *forwardedAgent) Unlock(passphrase []byte) error {
+	return a.call(func(c ExtendedAgent) error { return c.Unlock(passphrase) })
+}
+
+func (a *forwardedAgent) Signers() ([]ssh.Signer, error) {
+	keys, err := a.List()
+	if err != nil {
+		return nil, err
+	}
+	var result []ssh.Signer
+	for _, k := range keys {
+		result = append(result, &agentKeyringSigner{a, k})
+	}
+	return result, nil
+}
+
+func (a *forwardedAgent) Extension(extensionType string, contents []byte) (res []byte, err error) {
+	err = a.call(func(c ExtendedAgent) error {
+		res, err = c.Extension(extensionType, contents)
+		return err
+	})
+	return res, err
+}
+
 // ForwardToRemote routes authentication requests to the ssh-agent
-// process serving on the given unix socket.
+// process serving on the given unix socket. As in ssh, each connection to
+// the socket is first bound to the session of client for forwarding, as
+// with BindSession.
 func ForwardToRemote(client *ssh.Client, addr string) error {
 	channels := client.HandleChannelOpen(channelType)
 	if channels == nil {
@@ -72,32 +250,43 @@ func ForwardToRemote(client *ssh.Client, addr string) error {
 				continue
 			}
 			go ssh.DiscardRequests(reqs)
-			go forwardUnixSocket(channel, addr)
+			go forwardUnixSocket(client, channel, addr)
 		}
 	}()
 	return nil
 }
 
-func forwardUnixSocket(channel ssh.Channel, addr string) {
+func forwardUnixSocket(client *ssh.Client, channel ssh.Channel, addr string) {
 	conn, err := net.Dial("unix", addr)
 	if err != nil {
 		return
 	}
+	if hostKey, signature := client.SessionHostKey(); hostKey != nil {
+		// Agents that reject the binding are still used, as by ssh.
+		BindSession(NewClient(conn), hostKey, client.SessionID(), signature, true)
+	}
 
+	proxyConn(conn, ssh.NewChannelConn(channel, nil, nil))
+	conn.Close()
+	channel.Close()
+}
+
+// proxyConn copies data between a and b until both directions are done,
+// closing each direction for writing once its source is done.
+func proxyConn(a, b net.Conn) {
+	type closeWriter interface {
+		CloseWrite() error
+	}
 	var wg sync.WaitGroup
 	wg.Add(2)
-	go func() {
-		io.Copy(conn, channel)
-		conn.(*net.UnixConn).CloseWrite()
-		wg.Done()
-	}()
-	go func() {
-		io.Copy(channel, conn)
-		channel.CloseWrite()
+	copyHalf := func(dst, src net.Conn) {
+		io.Copy(dst, src)
+		if cw, ok := dst.(closeWriter); ok {
+			cw.CloseWrite()
+		}
 		wg.Done()
-	}()
-
+	}
+	go copyHalf(a, b)
+	go copyHalf(b, a)
 	wg.Wait()
-	conn.Close()
-	channel.Close()
 }
@@ -0,0 +1,198 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package agent
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// IdleLockConfig configures an IdleLockAgent.
+type IdleLockConfig struct {
+	// Timeout is how long the agent may go without signing before it is
+	// locked.
+	Timeout time.Duration
+
+	// Passphrase is the passphrase that the agent is locked with, and
+	// that unlocks it.
+	Passphrase []byte
+
+	// OnLock, if not nil, is called when the agent has been locked because
+	// it was idle.
+	OnLock func()
+}
+
+// IdleLockAgent is an agent that passes requests on to an upstream agent,
+// such as the keyring, and locks it once no key has been used to sign for
+// a while. It is typically served with ServeAgent to enforce security
+// policies on shared or portable machines.
+//
+// The timeout starts again when the agent is unlocked or signs. Close
+// stops it.
+type IdleLockAgent struct {
+	agent  Agent
+	config IdleLockConfig
+
+	mu     sync.Mutex
+	timer  *time.Timer
+	gen    uint64 // incremented whenever the timer is stopped or reset
+	closed bool
+}
+
+// NewIdleLockAgent returns an IdleLockAgent for agent, which must not be
+// locked, and starts its timeout.
+func NewIdleLockAgent(agent Agent, config IdleLockConfig) *IdleLockAgent {
+	config.Passphrase = append([]byte(nil), config.Passphrase...)
+	a := &IdleLockAgent{agent: agent, config: config}
+	a.mu.Lock()
+	a.resetLocked()
+	a.mu.Unlock()
+	return a
+}
+
+// resetLocked starts the timeout again. The caller must hold a.mu.
+func (a *IdleLockAgent) resetLocked() {
+	a.stopLocked()
+	if a.closed {
+		return
+	}
+	gen := a.gen
+	a.timer = time.AfterFunc(a.config.Timeout, func() { a.idle(gen) })
+}
+
+// stopLocked stops the timeout. The caller must hold a.mu.
+func (a *IdleLockAgent) stopLocked() {
+	a.gen++
+	if a.timer != nil {
+		a.timer.Stop()
+		a.timer = nil
+	}
+}
+
+func (a *IdleLockAgent) idle(gen uint64) {
+	a.mu.Lock()
+	if gen != a.gen {
+		// The timeout was reset or stopped since.
+		a.mu.Unlock()
+		return
+	}
+	a.timer = nil
+	err := a.agent.Lock(a.config.Passphrase)
+	if err != nil {
+		// The upstream agent may have been locked other than through a,
+		// or failed for a while. Try again later rather than never.
+		a.resetLocked()
+	}
+	a.mu.Unlock()
+	if err == nil && a.config.OnLock != nil {
+		a.config.OnLock()
+	}
+}
+
+// Close stops the timeout. It does not lock or close the upstream agent.
+func (a *IdleLockAgent) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.closed = true
+	a.stopLocked()
+	return nil
+}
+
+// List returns the identities known to the upstream agent.
+func (a *IdleLockAgent) List() ([]*Key, error) {
+	return a.agent.List()
+}
+
+// Sign signs data with key and starts the timeout again.
+func (a *IdleLockAgent) Sign(key ssh.PublicKey, data []byte) (*ssh.Signature, error) {
+	return a.SignWithFlags(key, data, 0)
+}
+
+// SignWithFlags signs data with key and starts the timeout again. Flags are
+// only supported if the upstream agent is an ExtendedAgent.
+func (a *IdleLockAgent) SignWithFlags(key ssh.PublicKey, data []byte, flags SignatureFlags) (*ssh.Signature, error) {
+	var sig *ssh.Signature
+	var err error
+	if ext, ok := a.agent.(ExtendedAgent); ok {
+		sig, err = ext.SignWithFlags(key, data, flags)
+	} else if flags != 0 {
+		return nil, errors.New("agent: signature flags not supported by upstream agent")
+	} else {
+		sig, err = a.agent.Sign(key, data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// The upstream agent signed, so it is unlocked, even if the timeout
+	// stopped because it was locked.
+	a.mu.Lock()
+	a.resetLocked()
+	a.mu.Unlock()
+	return sig, nil
+}
+
+// Signers returns signers for all the known keys, which sign through the
+// agent so that they start the timeout again.
+func (a *IdleLockAgent) Signers() ([]ssh.Signer, error) {
+	signers, err := a.agent.Signers()
+	if err != nil {
+		return nil, err
+	}
+	for i, s := range signers {
+		signers[i] = &agentKeyringSigner{a, s.PublicKey()}
+	}
+	return signers, nil
+}
+
+// Add adds a key to the upstream agent.
+func (a *IdleLockAgent) Add(key AddedKey) error {
+	if err := checkDestinationSupport(a, key); err != nil {
+		return err
+	}
+	return a.agent.Add(key)
+}
+
+// Remove removes a key from the upstream agent.
+func (a *IdleLockAgent) Remove(key ssh.PublicKey) error {
+	return a.agent.Remove(key)
+}
+
+// RemoveAll removes all keys from the upstream agent.
+func (a *IdleLockAgent) RemoveAll() error {
+	return a.agent.RemoveAll()
+}
+
+// Lock locks the upstream agent and stops the timeout.
+func (a *IdleLockAgent) Lock(passphrase []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.agent.Lock(passphrase); err != nil {
+		return err
+	}
+	a.stopLocked()
+	return nil
+}
+
+// Unlock unlocks the upstream agent and starts the timeout again.
+func (a *IdleLockAgent) Unlock(passphrase []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.agent.Unlock(passphrase); err != nil {
+		return err
+	}
+	a.resetLocked()
+	return nil
+}
+
+// Extension passes extension requests on to the upstream agent.
+func (a *IdleLockAgent) Extension(extensionType string, contents []byte) ([]byte, error) {
+	if ext, ok := a.agent.(ExtendedAgent); ok {
+		return ext.Extension(extensionType, contents)
+	}
+	return nil, ErrExtensionUnsupported
+}
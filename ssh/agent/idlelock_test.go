@@ -0,0 +1,119 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package agent
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIdleLockAgent(t *testing.T) {
+	keyring := NewKeyring()
+	if err := keyring.Add(AddedKey{PrivateKey: testPrivateKeys["ed25519"]}); err != nil {
+		t.Fatal(err)
+	}
+	locked := make(chan struct{}, 1)
+	idle := NewIdleLockAgent(keyring, IdleLockConfig{
+		Timeout:    200 * time.Millisecond,
+		Passphrase: []byte("secret"),
+		OnLock:     func() { locked <- struct{}{} },
+	})
+	defer idle.Close()
+	agent, cleanup := startAgent(t, idle)
+	defer cleanup()
+
+	// Signing keeps the agent unlocked.
+	for i := 0; i < 4; i++ {
+		time.Sleep(100 * time.Millisecond)
+		if _, err := agent.Sign(testPublicKeys["ed25519"], []byte("data")); err != nil {
+			t.Fatalf("Sign after %d signatures: %v", i, err)
+		}
+	}
+	select {
+	case <-locked:
+		t.Fatal("agent locked while in use")
+	default:
+	}
+
+	select {
+	case <-locked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("agent not locked when idle")
+	}
+	if _, err := agent.Sign(testPublicKeys["ed25519"], []byte("data")); err == nil {
+		t.Error("signed after locking")
+	}
+
+	// Unlocking starts the timeout again.
+	if err := agent.Unlock([]byte("secret")); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	if _, err := agent.Sign(testPublicKeys["ed25519"], []byte("data")); err != nil {
+		t.Fatalf("Sign after unlocking: %v", err)
+	}
+	select {
+	case <-locked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("agent not locked when idle after unlocking")
+	}
+
+	// The agent isn't locked again while locked by the user.
+	if err := agent.Unlock([]byte("secret")); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	if err := agent.Lock([]byte("mine")); err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+	select {
+	case <-locked:
+		t.Error("agent locked by timeout while locked")
+	case <-time.After(400 * time.Millisecond):
+	}
+	if err := agent.Unlock([]byte("mine")); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+}
+
+func TestIdleLockAgentUpstreamLocked(t *testing.T) {
+	keyring := NewKeyring()
+	if err := keyring.Add(AddedKey{PrivateKey: testPrivateKeys["ed25519"]}); err != nil {
+		t.Fatal(err)
+	}
+	locked := make(chan struct{}, 1)
+	idle := NewIdleLockAgent(keyring, IdleLockConfig{
+		Timeout:    100 * time.Millisecond,
+		Passphrase: []byte("secret"),
+		OnLock:     func() { locked <- struct{}{} },
+	})
+	defer idle.Close()
+
+	// The timeout keeps running while the upstream agent is locked other
+	// than through idle.
+	if err := keyring.Lock([]byte("other")); err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+	time.Sleep(300 * time.Millisecond)
+	if err := keyring.Unlock([]byte("other")); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	select {
+	case <-locked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("agent not locked after a failed lock")
+	}
+
+	// Signing once unlocked upstream starts the timeout again.
+	if err := keyring.Unlock([]byte("secret")); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	if _, err := idle.Sign(testPublicKeys["ed25519"], []byte("data")); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	select {
+	case <-locked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("agent not locked after signing")
+	}
+}
@@ -20,6 +20,10 @@ type privKey struct {
 	signer  ssh.Signer
 	comment string
 	expire  *time.Time
+
+	// destinations are the constraints of RestrictDestinationExtension,
+	// enforced by ServeAgent.
+	destinations []DestinationConstraint
 }
 
 type keyring struct {
@@ -111,6 +115,13 @@ func (r *keyring) Unlock(passphrase []byte) error {
 	return nil
 }
 
+// isLocked reports whether the agent is locked.
+func (r *keyring) isLocked() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.locked
+}
+
 // expireKeysLocked removes expired keys from the keyring. If a key was added
 // with a lifetimesecs contraint and seconds >= lifetimesecs seconds have
 // elapsed, it is removed. The caller *must* be holding the keyring mutex.
@@ -145,13 +156,20 @@ func (r *keyring) List() ([]*Key, error) {
 
 // Insert adds a private key to the keyring. If a certificate
 // is given, that certificate is added as public key. Note that
-// any constraints given are ignored.
+// constraints other than the lifetime and RestrictDestinationExtension
+// are ignored.
 func (r *keyring) Add(key AddedKey) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	if r.locked {
 		return errLocked
 	}
+	return r.addLocked(key)
+}
+
+// addLocked does the actual key addition. The caller must already be holding
+// the keyring mutex.
+func (r *keyring) addLocked(key AddedKey) error {
 	signer, err := ssh.NewSignerFromKey(key.PrivateKey)
 
 	if err != nil {
@@ -175,6 +193,17 @@ func (r *keyring) Add(key AddedKey) error {
 		p.expire = &t
 	}
 
+	for _, ext := range key.ConstraintExtensions {
+		if ext.ExtensionName != RestrictDestinationExtension {
+			continue
+		}
+		constraints, err := ParseRestrictDestination(ext.ExtensionDetails)
+		if err != nil {
+			return err
+		}
+		p.destinations = append(p.destinations, constraints...)
+	}
+
 	r.keys = append(r.keys, p)
 
 	return nil
@@ -219,6 +248,18 @@ func (r *keyring) SignWithFlags(key ssh.PublicKey, data []byte, flags SignatureF
 	return nil, errors.New("not found")
 }
 
+func (r *keyring) destinationConstraints(key ssh.PublicKey) []DestinationConstraint {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	wanted := key.Marshal()
+	for _, k := range r.keys {
+		if bytes.Equal(k.signer.PublicKey().Marshal(), wanted) {
+			return k.destinations
+		}
+	}
+	return nil
+}
+
 // Signers returns signers for all the known keys.
 func (r *keyring) Signers() ([]ssh.Signer, error) {
 	r.mu.Lock()
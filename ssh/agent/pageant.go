@@ -0,0 +1,70 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package agent
+
+import (
+	"encoding/binary"
+	"io"
+	"os"
+	"sync"
+)
+
+// A queryConn is a connection to an agent, such as Pageant, that is not a
+// stream but answers each request message at once. Requests written to it
+// are passed to query once complete, and the responses are read back.
+type queryConn struct {
+	query func(msg []byte) ([]byte, error)
+
+	mu     sync.Mutex
+	req    []byte
+	resp   []byte
+	closed bool
+}
+
+func (c *queryConn) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return 0, os.ErrClosed
+	}
+	c.req = append(c.req, p...)
+	for len(c.req) >= 4 {
+		n := 4 + int(binary.BigEndian.Uint32(c.req))
+		if len(c.req) < n {
+			break
+		}
+		resp, err := c.query(c.req[:n])
+		c.req = c.req[n:]
+		if err != nil {
+			return 0, err
+		}
+		c.resp = append(c.resp, resp...)
+	}
+	return len(p), nil
+}
+
+func (c *queryConn) Read(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return 0, os.ErrClosed
+	}
+	if len(c.resp) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, c.resp)
+	c.resp = c.resp[n:]
+	return n, nil
+}
+
+func (c *queryConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return os.ErrClosed
+	}
+	c.closed = true
+	return nil
+}
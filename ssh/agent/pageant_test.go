@@ -0,0 +1,53 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package agent
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestQueryConn(t *testing.T) {
+	keyring := NewKeyring()
+	queries := 0
+	conn := &queryConn{query: func(msg []byte) ([]byte, error) {
+		queries++
+		// Answer the single request like an agent reading it from a
+		// stream.
+		var resp bytes.Buffer
+		ServeAgent(keyring, struct {
+			io.Reader
+			io.Writer
+		}{bytes.NewReader(msg), &resp})
+		return resp.Bytes(), nil
+	}}
+	client := NewClient(conn)
+
+	if err := client.Add(AddedKey{PrivateKey: testPrivateKeys["ed25519"]}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	data := []byte("hello")
+	sig, err := client.Sign(testPublicKeys["ed25519"], data)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if err := testPublicKeys["ed25519"].Verify(data, sig); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+	if queries != 2 {
+		t.Errorf("got %d queries, want 2", queries)
+	}
+
+	conn.Close()
+	if _, err := client.List(); err == nil {
+		t.Error("List succeeded after Close")
+	}
+	if _, err := conn.Write(nil); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("Write after Close = %v, want os.ErrClosed", err)
+	}
+}
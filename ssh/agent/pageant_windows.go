@@ -0,0 +1,156 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build windows
+
+package agent
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sync/atomic"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	user32           = windows.NewLazySystemDLL("user32.dll")
+	procFindWindowW  = user32.NewProc("FindWindowW")
+	procSendMessageW = user32.NewProc("SendMessageW")
+)
+
+const (
+	// pageantMaxMsgLen is the size of the shared memory of a request,
+	// which holds both the request and the response.
+	pageantMaxMsgLen = 8192
+
+	// pageantCopyDataID identifies agent requests sent to Pageant.
+	pageantCopyDataID = 0x804e50ba
+
+	wmCopyData = 0x004a
+)
+
+var errPageantNotRunning = errors.New("agent: Pageant is not running")
+
+// pageantRequests numbers the shared memory of requests, so that
+// concurrent requests don't share it.
+var pageantRequests atomic.Uint32
+
+// copyData is a COPYDATASTRUCT.
+type copyData struct {
+	data uintptr
+	size uint32
+	ptr  unsafe.Pointer
+}
+
+// DialPageant returns a client for the running Pageant, the agent of PuTTY,
+// with which it communicates through a window message and shared memory.
+// Closing the returned io.Closer makes later calls fail.
+func DialPageant() (ExtendedAgent, io.Closer, error) {
+	if _, err := pageantWindow(); err != nil {
+		return nil, nil, err
+	}
+	conn := &queryConn{query: pageantQuery}
+	return NewClient(conn), conn, nil
+}
+
+// DialWindowsAgent connects to the agent of OpenSSH for Windows, on
+// OpenSSHPipe, or if that fails, to Pageant.
+func DialWindowsAgent() (ExtendedAgent, io.Closer, error) {
+	client, conn, err := DialPipe(OpenSSHPipe)
+	if err == nil {
+		return client, conn, nil
+	}
+	client, conn, pageantErr := DialPageant()
+	if pageantErr != nil {
+		return nil, nil, errors.Join(err, pageantErr)
+	}
+	return client, conn, nil
+}
+
+func pageantWindow() (uintptr, error) {
+	name, err := windows.UTF16PtrFromString("Pageant")
+	if err != nil {
+		return 0, err
+	}
+	hwnd, _, _ := procFindWindowW.Call(uintptr(unsafe.Pointer(name)), uintptr(unsafe.Pointer(name)))
+	if hwnd == 0 {
+		return 0, errPageantNotRunning
+	}
+	return hwnd, nil
+}
+
+// pageantQuery sends a request message, with its length prefix, to Pageant
+// and returns its response, likewise prefixed.
+func pageantQuery(msg []byte) ([]byte, error) {
+	if len(msg) > pageantMaxMsgLen {
+		return nil, errors.New("agent: request too large for Pageant")
+	}
+	hwnd, err := pageantWindow()
+	if err != nil {
+		return nil, err
+	}
+
+	sa, err := pageantSecurityAttributes()
+	if err != nil {
+		return nil, err
+	}
+	name := fmt.Sprintf("PageantRequest%08x%08x", windows.GetCurrentProcessId(), pageantRequests.Add(1))
+	name16, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return nil, err
+	}
+	m, err := windows.CreateFileMapping(windows.InvalidHandle, sa, windows.PAGE_READWRITE, 0, pageantMaxMsgLen, name16)
+	if m != 0 {
+		defer windows.CloseHandle(m)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("agent: creating Pageant request: %w", err)
+	}
+	addr, err := windows.MapViewOfFile(m, windows.FILE_MAP_WRITE, 0, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("agent: mapping Pageant request: %w", err)
+	}
+	defer windows.UnmapViewOfFile(addr)
+	buf := unsafe.Slice((*byte)(*(*unsafe.Pointer)(unsafe.Pointer(&addr))), pageantMaxMsgLen)
+	copy(buf, msg)
+
+	// Pageant expects the name of the shared memory as a C string.
+	cname := append([]byte(name), 0)
+	cds := copyData{data: pageantCopyDataID, size: uint32(len(cname)), ptr: unsafe.Pointer(&cname[0])}
+	ret, _, _ := procSendMessageW.Call(hwnd, wmCopyData, 0, uintptr(unsafe.Pointer(&cds)))
+	runtime.KeepAlive(cname)
+	if ret == 0 {
+		return nil, errors.New("agent: Pageant failed to answer the request")
+	}
+
+	n := binary.BigEndian.Uint32(buf)
+	if n > pageantMaxMsgLen-4 {
+		return nil, errors.New("agent: Pageant response too large")
+	}
+	return append([]byte(nil), buf[:4+n]...), nil
+}
+
+// pageantSecurityAttributes restricts the shared memory of requests to the
+// current user. Pageant only answers requests whose memory is owned by the
+// user it runs as.
+func pageantSecurityAttributes() (*windows.SecurityAttributes, error) {
+	user, err := windows.GetCurrentProcessToken().GetTokenUser()
+	if err != nil {
+		return nil, err
+	}
+	sid := user.User.Sid.String()
+	sd, err := windows.SecurityDescriptorFromString("O:" + sid + "D:P(A;;GA;;;" + sid + ")")
+	if err != nil {
+		return nil, err
+	}
+	return &windows.SecurityAttributes{
+		Length:             uint32(unsafe.Sizeof(windows.SecurityAttributes{})),
+		SecurityDescriptor: sd,
+	}, nil
+}
@@ -0,0 +1,160 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build windows
+
+package agent
+
+import (
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+// OpenSSHPipe is the named pipe on which the agent of OpenSSH for Windows
+// listens.
+const OpenSSHPipe = `\\.\pipe\openssh-ssh-agent`
+
+// pipeBusyTimeout is how long DialPipe waits for an instance of a busy pipe
+// to become available.
+const pipeBusyTimeout = 5 * time.Second
+
+// DialPipe connects to the agent listening on the named pipe name, which
+// is typically OpenSSHPipe, and returns a client for it. The connection is
+// closed by closing the returned io.Closer.
+//
+// The pipe is opened for overlapped I/O, so that closing the connection
+// interrupts pending calls.
+func DialPipe(name string) (ExtendedAgent, io.Closer, error) {
+	conn, err := dialPipe(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return NewClient(conn), conn, nil
+}
+
+// pipeConn is a connection to a named pipe opened for overlapped I/O.
+type pipeConn struct {
+	h windows.Handle
+
+	// closeMu is held for reading during I/O, and for writing while
+	// closing h, which must not happen with I/O pending.
+	closeMu sync.RWMutex
+	closing atomic.Bool
+
+	rd, wr pipeOp
+}
+
+// A pipeOp serializes reads or writes. ov must not move while I/O is
+// pending, which is why it lives in the heap allocated pipeConn.
+type pipeOp struct {
+	mu sync.Mutex
+	ov windows.Overlapped
+}
+
+func dialPipe(name string) (*pipeConn, error) {
+	path, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return nil, err
+	}
+	// Only let the server identify us, rather than impersonate us, in
+	// case someone else created the pipe.
+	const flags = windows.FILE_FLAG_OVERLAPPED | windows.SECURITY_SQOS_PRESENT | windows.SECURITY_IDENTIFICATION
+	deadline := time.Now().Add(pipeBusyTimeout)
+	var h windows.Handle
+	for {
+		h, err = windows.CreateFile(path, windows.GENERIC_READ|windows.GENERIC_WRITE, 0, nil, windows.OPEN_EXISTING, flags, 0)
+		if err != windows.ERROR_PIPE_BUSY || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	c := &pipeConn{h: h}
+	for _, op := range []*pipeOp{&c.rd, &c.wr} {
+		op.ov.HEvent, err = windows.CreateEvent(nil, 1, 0, nil)
+		if err != nil {
+			c.closeHandles()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (c *pipeConn) Read(p []byte) (int, error) {
+	n, err := c.do(&c.rd, windows.ReadFile, p)
+	if err == windows.ERROR_BROKEN_PIPE {
+		return 0, io.EOF
+	}
+	return n, err
+}
+
+func (c *pipeConn) Write(p []byte) (int, error) {
+	written := 0
+	for written < len(p) {
+		n, err := c.do(&c.wr, windows.WriteFile, p[written:])
+		written += n
+		if err != nil {
+			return written, err
+		}
+		if n == 0 {
+			return written, io.ErrShortWrite
+		}
+	}
+	return written, nil
+}
+
+// do runs a ReadFile or WriteFile operation and waits for it to complete.
+func (c *pipeConn) do(op *pipeOp, f func(windows.Handle, []byte, *uint32, *windows.Overlapped) error, p []byte) (int, error) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	c.closeMu.RLock()
+	defer c.closeMu.RUnlock()
+	if c.closing.Load() {
+		return 0, os.ErrClosed
+	}
+
+	op.ov = windows.Overlapped{HEvent: op.ov.HEvent}
+	var n uint32
+	err := f(c.h, p, &n, &op.ov)
+	if err == nil || err == windows.ERROR_IO_PENDING {
+		// Close may have been called after the check above, and then
+		// cancelled nothing.
+		if c.closing.Load() {
+			windows.CancelIoEx(c.h, &op.ov)
+		}
+		err = windows.GetOverlappedResult(c.h, &op.ov, &n, true)
+	}
+	if err == windows.ERROR_OPERATION_ABORTED && c.closing.Load() {
+		err = os.ErrClosed
+	}
+	return int(n), err
+}
+
+// Close closes the connection, interrupting pending reads and writes.
+func (c *pipeConn) Close() error {
+	if c.closing.Swap(true) {
+		return os.ErrClosed
+	}
+	windows.CancelIoEx(c.h, nil)
+	c.closeMu.Lock()
+	defer c.closeMu.Unlock()
+	return c.closeHandles()
+}
+
+func (c *pipeConn) closeHandles() error {
+	for _, op := range []*pipeOp{&c.rd, &c.wr} {
+		if op.ov.HEvent != 0 {
+			windows.CloseHandle(op.ov.HEvent)
+		}
+	}
+	return windows.CloseHandle(c.h)
+}
@@ -0,0 +1,103 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build windows
+
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+// listenPipe creates a named pipe and passes its server end to serve once
+// a client connects. It returns the name of the pipe.
+func listenPipe(t *testing.T, serve func(*os.File)) string {
+	t.Helper()
+	name := fmt.Sprintf(`\\.\pipe\go-agent-test-%d-%d`, os.Getpid(), time.Now().UnixNano())
+	path, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := windows.CreateNamedPipe(path, windows.PIPE_ACCESS_DUPLEX, windows.PIPE_TYPE_BYTE|windows.PIPE_WAIT, 1, 4096, 4096, 0, nil)
+	if err != nil {
+		t.Fatalf("CreateNamedPipe: %v", err)
+	}
+	f := os.NewFile(uintptr(h), name)
+	t.Cleanup(func() { f.Close() })
+	go func() {
+		if err := windows.ConnectNamedPipe(h, nil); err != nil && err != windows.ERROR_PIPE_CONNECTED {
+			return
+		}
+		serve(f)
+	}()
+	return name
+}
+
+func TestDialPipe(t *testing.T) {
+	keyring := NewKeyring()
+	name := listenPipe(t, func(f *os.File) { ServeAgent(keyring, f) })
+
+	client, conn, err := DialPipe(name)
+	if err != nil {
+		t.Fatalf("DialPipe: %v", err)
+	}
+	defer conn.Close()
+	if err := client.Add(AddedKey{PrivateKey: testPrivateKeys["ed25519"]}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	keys, err := client.List()
+	if err != nil || len(keys) != 1 {
+		t.Fatalf("List = %v, %v, want one key", keys, err)
+	}
+	data := []byte("hello")
+	sig, err := client.Sign(testPublicKeys["ed25519"], data)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if err := testPublicKeys["ed25519"].Verify(data, sig); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+}
+
+func TestDialPipeClose(t *testing.T) {
+	// The server never answers, so the call is interrupted by Close.
+	name := listenPipe(t, func(f *os.File) {})
+
+	client, conn, err := DialPipe(name)
+	if err != nil {
+		t.Fatalf("DialPipe: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.List()
+		done <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	if err := conn.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("List succeeded after Close")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not interrupt the pending call")
+	}
+	if err := conn.Close(); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("second Close = %v, want os.ErrClosed", err)
+	}
+}
+
+func TestDialPipeMissing(t *testing.T) {
+	if _, _, err := DialPipe(`\\.\pipe\go-agent-test-missing`); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("DialPipe of a missing pipe = %v, want os.ErrNotExist", err)
+	}
+}
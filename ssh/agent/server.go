@@ -16,6 +16,7 @@ import (
 	"io"
 	"log"
 	"math/big"
+	"net"
 
 	"golang.org/x/crypto/ssh"
 )
@@ -24,6 +25,14 @@ import (
 // the SSH-agent, wire protocol.
 type server struct {
 	agent Agent
+
+	// bindings are the sessions that the connection is bound to with the
+	// session-bind@openssh.com extension, oldest first.
+	bindings []sessionBinding
+
+	// bindAttempted is set once a session-bind@openssh.com request has
+	// been received, even if it failed.
+	bindAttempted bool
 }
 
 func (s *server) processRequestBytes(reqData []byte) []byte {
@@ -127,6 +136,12 @@ func (s *server) processRequest(data []byte) (interface{}, error) {
 			Format: wk.Format,
 			Blob:   req.KeyBlob,
 		}
+		if err := s.checkSessionBinding(req.Data); err != nil {
+			return nil, err
+		}
+		if err := s.checkDestination(k, req.Data); err != nil {
+			return nil, err
+		}
 
 		var sig *ssh.Signature
 		var err error
@@ -146,6 +161,7 @@ func (s *server) processRequest(data []byte) (interface{}, error) {
 		if err != nil {
 			return nil, err
 		}
+		keys = s.permittedKeys(keys)
 
 		rep := identitiesAnswerAgentMsg{
 			NumKeys: uint32(len(keys)),
@@ -158,7 +174,20 @@ func (s *server) processRequest(data []byte) (interface{}, error) {
 	case agentAddIDConstrained, agentAddIdentity:
 		return nil, s.insertIdentity(data)
 
+	case agentAddSmartcardKey, agentAddSmartcardKeyConstrained, agentRemoveSmartcardKey:
+		return nil, s.handleSmartcardKey(data)
+
 	case agentExtension:
+		var req extensionAgentMsg
+		if err := ssh.Unmarshal(data, &req); err != nil {
+			return nil, err
+		}
+		if req.ExtensionType == SessionBindExtension {
+			// Like ssh-agent, answer failed bindings with a standard
+			// SSH_AGENT_FAILURE message.
+			return nil, s.bindSession(req.Contents)
+		}
+
 		// Return a stub object where the whole contents of the response gets marshaled.
 		var responseStub struct {
 			Rest []byte `ssh:"rest"`
@@ -169,10 +198,6 @@ func (s *server) processRequest(data []byte) (interface{}, error) {
 			// requires that we return a standard SSH_AGENT_FAILURE message.
 			responseStub.Rest = []byte{agentFailure}
 		} else {
-			var req extensionAgentMsg
-			if err := ssh.Unmarshal(data, &req); err != nil {
-				return nil, err
-			}
 			res, err := extendedAgent.Extension(req.ExtensionType, req.Contents)
 			if err != nil {
 				// If agent extensions are unsupported, return a standard SSH_AGENT_FAILURE
@@ -527,14 +552,36 @@ func (s *server) insertIdentity(req []byte) error {
 	if err != nil {
 		return err
 	}
+	if err := checkDestinationSupport(s.agent, *addedKey); err != nil {
+		return err
+	}
 	return s.agent.Add(*addedKey)
 }
 
 // ServeAgent serves the agent protocol on the given connection. It
 // returns when an I/O error occurs.
 func ServeAgent(agent Agent, c io.ReadWriter) error {
-	s := &server{agent}
+	return (&server{agent: agent}).serve(c)
+}
+
+// Serve accepts connections on l and serves the agent protocol on each of
+// them, until Accept fails. Listening on a Unix socket that SSH_AUTH_SOCK
+// points to, agent can replace ssh-agent; the socket should only be
+// accessible to its owner.
+func Serve(l net.Listener, agent Agent) error {
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer c.Close()
+			ServeAgent(agent, c)
+		}()
+	}
+}
 
+func (s *server) serve(c io.ReadWriter) error {
 	var length [4]byte
 	for {
 		if _, err := io.ReadFull(c, length[:]); err != nil {
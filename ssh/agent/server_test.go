@@ -9,7 +9,11 @@ import (
 	"crypto/rand"
 	"fmt"
 	pseudorand "math/rand"
+	"net"
+	"os"
+	"path/filepath"
 	"reflect"
+	"runtime"
 	"strings"
 	"testing"
 
@@ -87,6 +91,113 @@ func TestSetupForwardAgent(t *testing.T) {
 	conn.Close()
 }
 
+func TestAcceptAgentForwarding(t *testing.T) {
+	a, b, err := netPipe()
+	if err != nil {
+		t.Fatalf("netPipe: %v", err)
+	}
+	defer a.Close()
+	defer b.Close()
+
+	serverConf := ssh.ServerConfig{
+		NoClientAuth: true,
+	}
+	serverConf.AddHostKey(testSigners["rsa"])
+	forwarded := make(chan *ForwardedAgent, 1)
+	go func() {
+		conn, chans, reqs, err := ssh.NewServerConn(a, &serverConf)
+		if err != nil {
+			t.Errorf("NewServerConn error: %v", err)
+			close(forwarded)
+			return
+		}
+		go ssh.DiscardRequests(reqs)
+		for newCh := range chans {
+			ch, reqs, err := newCh.Accept()
+			if err != nil {
+				return
+			}
+			defer ch.Close()
+			go func() {
+				for req := range reqs {
+					if req.Type != agentRequestType {
+						req.Reply(false, nil)
+						continue
+					}
+					f, err := AcceptAgentForwarding(conn, req)
+					if err != nil {
+						t.Errorf("AcceptAgentForwarding: %v", err)
+					}
+					forwarded <- f
+				}
+			}()
+		}
+	}()
+
+	conf := ssh.ClientConfig{
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	}
+	conn, chans, reqs, err := ssh.NewClientConn(b, "", &conf)
+	if err != nil {
+		t.Fatalf("NewClientConn: %v", err)
+	}
+	client := ssh.NewClient(conn, chans, reqs)
+	defer client.Close()
+
+	keyring := NewKeyring()
+	if err := keyring.Add(AddedKey{PrivateKey: testPrivateKeys["ed25519"]}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := ForwardToAgent(client, keyring); err != nil {
+		t.Fatalf("ForwardToAgent: %v", err)
+	}
+	session, err := client.NewSession()
+	if err != nil {
+		t.Fatalf("NewSession: %v", err)
+	}
+	defer session.Close()
+	if err := RequestAgentForwarding(session); err != nil {
+		t.Fatalf("RequestAgentForwarding: %v", err)
+	}
+	f := <-forwarded
+	if f == nil {
+		t.Fatal("no forwarded agent")
+	}
+
+	signers, err := f.Agent().Signers()
+	if err != nil || len(signers) != 1 {
+		t.Fatalf("Signers: %v, %v; want 1 signer", signers, err)
+	}
+	data := []byte("data")
+	sig, err := signers[0].Sign(rand.Reader, data)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if err := signers[0].PublicKey().Verify(data, sig); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+
+	l, err := f.Listen(filepath.Join(t.TempDir(), "agent.sock"))
+	if err != nil {
+		t.Skipf("Unix sockets are not supported: %v", err)
+	}
+	defer l.Close()
+	if fi, err := os.Stat(l.Addr().String()); err != nil {
+		t.Fatal(err)
+	} else if runtime.GOOS != "windows" && fi.Mode().Perm() != 0600 {
+		t.Errorf("socket mode %v, want 0600", fi.Mode().Perm())
+	}
+	sock, err := net.Dial("unix", l.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer sock.Close()
+	keys, err := NewClient(sock).List()
+	if err != nil || len(keys) != 1 {
+		t.Errorf("List over the socket: %v, %v; want 1 key", keys, err)
+	}
+}
+
 func TestV1ProtocolMessages(t *testing.T) {
 	c1, c2, err := netPipe()
 	if err != nil {
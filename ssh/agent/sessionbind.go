@@ -0,0 +1,175 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package agent
+
+import (
+	"bytes"
+	"errors"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// SessionBindExtension is the extension with which a client binds its
+// connection to the agent to an SSH session, by proving which server the
+// session is with. An agent then only signs user authentication requests
+// for the session that the connection was last bound to, unless the
+// binding was made for agent forwarding. This keeps a server that a
+// client forwards its agent to from relaying user authentication requests
+// for other sessions, and keeps agents from being used on connections whose
+// binding failed. See section 1 of PROTOCOL.agent in OpenSSH.
+const SessionBindExtension = "session-bind@openssh.com"
+
+// maxSessionBindings is how many sessions a connection may be bound to, as
+// in ssh-agent.
+const maxSessionBindings = 16
+
+type sessionBindMsg struct {
+	HostKey    []byte
+	SessionID  []byte
+	Signature  []byte
+	Forwarding bool
+}
+
+type sessionBinding struct {
+	hostKey    ssh.PublicKey
+	sessionID  []byte
+	forwarding bool
+}
+
+// BindSession binds the connection of agent to the session with the given
+// ID, which the server authenticated with hostKey and signature, as
+// returned by ssh.Client.SessionHostKey. forwarding is set if the
+// connection serves a forwarded agent, rather than authentication of the
+// session itself.
+//
+// Agents reply to bindings they reject as to unsupported extensions, so
+// both make BindSession return ErrExtensionUnsupported.
+func BindSession(agent ExtendedAgent, hostKey ssh.PublicKey, sessionID, signature []byte, forwarding bool) error {
+	_, err := agent.Extension(SessionBindExtension, ssh.Marshal(sessionBindMsg{
+		HostKey:    hostKey.Marshal(),
+		SessionID:  sessionID,
+		Signature:  signature,
+		Forwarding: forwarding,
+	}))
+	return err
+}
+
+// SessionBindCallback returns an ssh.SessionBindCallback that binds the
+// connection of agent to the session before the agent is used for user
+// authentication. As in ssh, agents that don't support the extension are
+// used without binding. Once bound, the connection to the agent can't be
+// used to authenticate other sessions.
+func SessionBindCallback(agent ExtendedAgent) ssh.SessionBindCallback {
+	return func(hostKey ssh.PublicKey, sessionID, signature []byte) error {
+		err := BindSession(agent, hostKey, sessionID, signature, false)
+		if err == ErrExtensionUnsupported {
+			return nil
+		}
+		return err
+	}
+}
+
+// clientSessionBindMsg returns the contents of a forwarding
+// session-bind@openssh.com request for the session of client, or nil if it
+// can't be bound.
+func clientSessionBindMsg(client *ssh.Client) []byte {
+	hostKey, signature := client.SessionHostKey()
+	if hostKey == nil {
+		return nil
+	}
+	return ssh.Marshal(sessionBindMsg{
+		HostKey:    hostKey.Marshal(),
+		SessionID:  client.SessionID(),
+		Signature:  signature,
+		Forwarding: true,
+	})
+}
+
+// bindSession handles a session-bind@openssh.com request, following the
+// rules of ssh-agent. The binding is also passed on to the agent, so that
+// an agent that enforces bindings itself, such as ssh-agent behind a
+// client returned by NewClient, applies its restrictions too.
+func (s *server) bindSession(contents []byte) error {
+	s.bindAttempted = true
+	var msg sessionBindMsg
+	if err := ssh.Unmarshal(contents, &msg); err != nil {
+		return err
+	}
+	hostKey, err := ssh.ParsePublicKey(msg.HostKey)
+	if err != nil {
+		return err
+	}
+	var sig ssh.Signature
+	if err := ssh.Unmarshal(msg.Signature, &sig); err != nil {
+		return err
+	}
+	if err := hostKey.Verify(msg.SessionID, &sig); err != nil {
+		return errors.New("agent: invalid session binding signature")
+	}
+	n := len(s.bindings)
+	if err := s.addSessionBinding(sessionBinding{hostKey: hostKey, sessionID: msg.SessionID, forwarding: msg.Forwarding}); err != nil {
+		return err
+	}
+	if extendedAgent, ok := s.agent.(ExtendedAgent); ok {
+		// Agents that don't support bindings are left to the checks of
+		// the server. Agents that do also refuse to sign once a binding
+		// failed, which they answer as unsupported.
+		if _, err := extendedAgent.Extension(SessionBindExtension, contents); err != nil && err != ErrExtensionUnsupported {
+			s.bindings = s.bindings[:n]
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *server) addSessionBinding(b sessionBinding) error {
+	for _, prev := range s.bindings {
+		if bytes.Equal(prev.sessionID, b.sessionID) {
+			if !bytes.Equal(prev.hostKey.Marshal(), b.hostKey.Marshal()) {
+				return errors.New("agent: session already bound to another host key")
+			}
+			return nil
+		}
+	}
+	if n := len(s.bindings); n > 0 && !s.bindings[n-1].forwarding {
+		return errors.New("agent: connection already bound for authentication")
+	}
+	if len(s.bindings) >= maxSessionBindings {
+		return errors.New("agent: too many session bindings")
+	}
+	s.bindings = append(s.bindings, b)
+	return nil
+}
+
+// checkSessionBinding reports whether the connection may be used to sign
+// data.
+func (s *server) checkSessionBinding(data []byte) error {
+	if s.bindAttempted && len(s.bindings) == 0 {
+		return errors.New("agent: refusing to sign after a failed session binding")
+	}
+	if len(s.bindings) == 0 {
+		return nil
+	}
+	last := s.bindings[len(s.bindings)-1]
+	if sessionID, ok := userAuthSessionID(data); ok && !last.forwarding && !bytes.Equal(sessionID, last.sessionID) {
+		return errors.New("agent: refusing to sign user authentication for a session the connection is not bound to")
+	}
+	return nil
+}
+
+// userAuthSessionID returns the session ID of data, if it is signed for
+// public key user authentication. See RFC 4252, section 7.
+func userAuthSessionID(data []byte) ([]byte, bool) {
+	var msg struct {
+		SessionID []byte
+		Type      byte
+		Rest      []byte `ssh:"rest"`
+	}
+	const msgUserAuthRequest = 50
+	if err := ssh.Unmarshal(data, &msg); err != nil || msg.Type != msgUserAuthRequest {
+		return nil, false
+	}
+	return msg.SessionID, true
+}
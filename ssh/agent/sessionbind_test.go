@@ -0,0 +1,260 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package agent
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// dialWithAgent connects to a server that accepts the ed25519 test key,
+// authenticating with agent after binding the session to it.
+func dialWithAgent(t *testing.T, agent ExtendedAgent) (*ssh.Client, error) {
+	a, b, err := netPipe()
+	if err != nil {
+		t.Fatalf("netPipe: %v", err)
+	}
+	t.Cleanup(func() {
+		a.Close()
+		b.Close()
+	})
+
+	serverConf := &ssh.ServerConfig{
+		PublicKeyCallback: func(c ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if bytes.Equal(key.Marshal(), testPublicKeys["ed25519"].Marshal()) {
+				return nil, nil
+			}
+			return nil, errors.New("pubkey rejected")
+		},
+	}
+	serverConf.AddHostKey(testSigners["ecdsa"])
+	go func() {
+		conn, chans, reqs, err := ssh.NewServerConn(a, serverConf)
+		if err != nil {
+			return
+		}
+		go ssh.DiscardRequests(reqs)
+		go func() {
+			for range chans {
+			}
+		}()
+		conn.Wait()
+	}()
+
+	conf := &ssh.ClientConfig{
+		User:                "user",
+		Auth:                []ssh.AuthMethod{ssh.PublicKeysCallback(agent.Signers)},
+		HostKeyCallback:     ssh.InsecureIgnoreHostKey(),
+		SessionBindCallback: SessionBindCallback(agent),
+	}
+	conn, chans, reqs, err := ssh.NewClientConn(b, "server:22", conf)
+	if err != nil {
+		return nil, err
+	}
+	return ssh.NewClient(conn, chans, reqs), nil
+}
+
+func TestSessionBind(t *testing.T) {
+	agent, cleanup := startKeyringAgent(t)
+	defer cleanup()
+	if err := agent.Add(AddedKey{PrivateKey: testPrivateKeys["ed25519"]}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	client, err := dialWithAgent(t, agent)
+	if err != nil {
+		t.Fatalf("dial with a bound agent: %v", err)
+	}
+	defer client.Close()
+
+	// The agent connection is bound to the first session, so it must not
+	// sign for another one.
+	if _, err := dialWithAgent(t, agent); err == nil {
+		t.Error("agent bound to another session signed user authentication")
+	}
+	// Other data can still be signed.
+	if _, err := agent.Sign(testPublicKeys["ed25519"], []byte("data")); err != nil {
+		t.Errorf("Sign: %v", err)
+	}
+}
+
+func TestSessionBindOpenSSHAgent(t *testing.T) {
+	agent, _, cleanup := startOpenSSHAgent(t)
+	defer cleanup()
+	if err := agent.Add(AddedKey{PrivateKey: testPrivateKeys["ed25519"]}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	client, err := dialWithAgent(t, agent)
+	if err != nil {
+		t.Fatalf("dial with a bound agent: %v", err)
+	}
+	client.Close()
+}
+
+func TestSessionBindRejected(t *testing.T) {
+	agent, cleanup := startKeyringAgent(t)
+	defer cleanup()
+	if err := agent.Add(AddedKey{PrivateKey: testPrivateKeys["ed25519"]}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	sessionID := []byte("session")
+	sig, err := testSigners["rsa"].Sign(nil, []byte("another session"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := BindSession(agent, testPublicKeys["rsa"], sessionID, ssh.Marshal(sig), false); err != ErrExtensionUnsupported {
+		t.Errorf("BindSession with a bad signature = %v, want ErrExtensionUnsupported", err)
+	}
+	if _, err := agent.Sign(testPublicKeys["ed25519"], []byte("data")); err == nil {
+		t.Error("agent signed after a failed session binding")
+	}
+}
+
+func TestSessionBindForwarding(t *testing.T) {
+	agent, cleanup := startKeyringAgent(t)
+	defer cleanup()
+
+	bind := func(id string, forwarding bool) error {
+		sig, err := testSigners["ecdsa"].Sign(nil, []byte(id))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return BindSession(agent, testPublicKeys["ecdsa"], []byte(id), ssh.Marshal(sig), forwarding)
+	}
+	if err := bind("first", true); err != nil {
+		t.Fatalf("forwarding binding: %v", err)
+	}
+	if err := bind("first", true); err != nil {
+		t.Errorf("repeated binding: %v", err)
+	}
+	if err := bind("second", false); err != nil {
+		t.Fatalf("binding after a forwarding binding: %v", err)
+	}
+	if err := bind("third", true); err == nil {
+		t.Error("binding after an authentication binding succeeded")
+	}
+}
+
+// forwardAgent returns a client for upstream, forwarded with ForwardToAgent
+// over an SSH connection to a server with the ecdsa host key.
+func forwardAgent(t *testing.T, upstream Agent) ExtendedAgent {
+	a, b, err := netPipe()
+	if err != nil {
+		t.Fatalf("netPipe: %v", err)
+	}
+	t.Cleanup(func() {
+		a.Close()
+		b.Close()
+	})
+	serverConf := &ssh.ServerConfig{NoClientAuth: true}
+	serverConf.AddHostKey(testSigners["ecdsa"])
+	incoming := make(chan *ssh.ServerConn, 1)
+	go func() {
+		conn, chans, reqs, err := ssh.NewServerConn(a, serverConf)
+		incoming <- conn
+		if err != nil {
+			return
+		}
+		go ssh.DiscardRequests(reqs)
+		go func() {
+			for range chans {
+			}
+		}()
+	}()
+
+	conf := &ssh.ClientConfig{HostKeyCallback: ssh.InsecureIgnoreHostKey()}
+	conn, chans, reqs, err := ssh.NewClientConn(b, "server:22", conf)
+	if err != nil {
+		t.Fatalf("NewClientConn: %v", err)
+	}
+	client := ssh.NewClient(conn, chans, reqs)
+	if err := ForwardToAgent(client, upstream); err != nil {
+		t.Fatalf("ForwardToAgent: %v", err)
+	}
+	server := <-incoming
+	if server == nil {
+		t.Fatal("NewServerConn failed")
+	}
+	ch, chReqs, err := server.OpenChannel(channelType, nil)
+	if err != nil {
+		t.Fatalf("OpenChannel(%q): %v", channelType, err)
+	}
+	go ssh.DiscardRequests(chReqs)
+	t.Cleanup(func() { ch.Close() })
+	return NewClient(ch)
+}
+
+func TestSessionBindRelayed(t *testing.T) {
+	upstream := NewExtensionAgent(NewKeyring())
+	var bindings []sessionBindMsg
+	upstream.HandleExtension(SessionBindExtension, func(contents []byte) ([]byte, error) {
+		var msg sessionBindMsg
+		if err := ssh.Unmarshal(contents, &msg); err != nil {
+			return nil, err
+		}
+		bindings = append(bindings, msg)
+		return nil, nil
+	})
+
+	// ForwardToAgent binds the upstream agent for forwarding, then the
+	// bindings made over the forwarded connection are relayed.
+	agent := forwardAgent(t, upstream)
+	sig, err := testSigners["rsa"].Sign(nil, []byte("onward"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := BindSession(agent, testPublicKeys["rsa"], []byte("onward"), ssh.Marshal(sig), false); err != nil {
+		t.Fatalf("BindSession: %v", err)
+	}
+	if len(bindings) != 2 || !bindings[0].Forwarding || bindings[1].Forwarding || string(bindings[1].SessionID) != "onward" {
+		t.Fatalf("upstream bindings %+v, want a forwarding one, then onward", bindings)
+	}
+	if !bytes.Equal(bindings[0].HostKey, testPublicKeys["ecdsa"].Marshal()) {
+		t.Error("upstream not bound to the session of the forwarding client")
+	}
+}
+
+func TestSessionBindRelayedOpenSSHAgent(t *testing.T) {
+	upstream, _, cleanup := startOpenSSHAgent(t)
+	defer cleanup()
+	key := testPublicKeys["ed25519"]
+	err := upstream.Add(AddedKey{
+		PrivateKey: testPrivateKeys["ed25519"],
+		ConstraintExtensions: []ConstraintExtension{RestrictDestination(DestinationConstraint{
+			To: ConstraintHop{Hostname: "server", HostKeys: []HostKeySpec{{Key: testPublicKeys["ecdsa"]}}},
+		})},
+	})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	// The client can't enforce the constraint, so ssh-agent must see the
+	// binding to a host that the key isn't permitted for.
+	agent, cleanup := startAgent(t, upstream)
+	defer cleanup()
+	sig, err := testSigners["rsa"].Sign(nil, []byte("elsewhere"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := BindSession(agent, testPublicKeys["rsa"], []byte("elsewhere"), ssh.Marshal(sig), false); err != nil {
+		t.Fatalf("BindSession: %v", err)
+	}
+	keys, err := agent.List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	for _, k := range keys {
+		if bytes.Equal(k.Marshal(), key.Marshal()) {
+			t.Error("ssh-agent listed a key that is not permitted for the bound host")
+		}
+	}
+	if _, err := agent.Sign(key, userAuthData([]byte("elsewhere"), "user", key, nil)); err == nil {
+		t.Error("ssh-agent signed for a host that the key is not permitted for")
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package agent
+
+import (
+	"errors"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// SmartcardKey identifies the keys of a smartcard, or of another PKCS#11
+// token, to add to an agent, as with ssh-add -s.
+type SmartcardKey struct {
+	// ReaderID identifies the token. For ssh-agent, it is the path of the
+	// PKCS#11 provider library.
+	ReaderID string
+
+	// PIN unlocks the token.
+	PIN []byte
+
+	// LifetimeSecs, if not zero, is the number of seconds that the keys
+	// will be held by the agent.
+	LifetimeSecs uint32
+
+	// ConfirmBeforeUse, if true, requests that the agent confirm with the
+	// user before each use of the keys.
+	ConfirmBeforeUse bool
+
+	// ConstraintExtensions are the experimental or private-use constraints
+	// defined by users.
+	ConstraintExtensions []ConstraintExtension
+}
+
+// SmartcardAgent is implemented by agents that can hold the keys of
+// smartcards. The agents returned by NewClient implement it, and ServeAgent
+// passes the smartcard requests on to agents that do, unless the connection
+// was bound with SessionBindExtension, as it is by ForwardToAgent.
+type SmartcardAgent interface {
+	Agent
+
+	// AddSmartcardKey adds the keys of a smartcard to the agent.
+	AddSmartcardKey(key SmartcardKey) error
+
+	// RemoveSmartcardKey removes the keys of a smartcard from the agent.
+	RemoveSmartcardKey(readerID string, pin []byte) error
+}
+
+// See [PROTOCOL.agent], section 3.3.
+type addSmartcardKeyAgentMsg struct {
+	ReaderID    string `sshtype:"20|26"`
+	PIN         []byte
+	Constraints []byte `ssh:"rest"`
+}
+
+type removeSmartcardKeyAgentMsg struct {
+	ReaderID string `sshtype:"21"`
+	PIN      []byte
+}
+
+func (c *client) AddSmartcardKey(key SmartcardKey) error {
+	req := ssh.Marshal(addSmartcardKeyAgentMsg{
+		ReaderID:    key.ReaderID,
+		PIN:         key.PIN,
+		Constraints: marshalConstraints(key.LifetimeSecs, key.ConfirmBeforeUse, key.ConstraintExtensions),
+	})
+	// if constraints are present then the message type needs to be changed.
+	if key.LifetimeSecs != 0 || key.ConfirmBeforeUse || len(key.ConstraintExtensions) != 0 {
+		req[0] = agentAddSmartcardKeyConstrained
+	}
+	return c.simpleCall(req)
+}
+
+func (c *client) RemoveSmartcardKey(readerID string, pin []byte) error {
+	req := ssh.Marshal(removeSmartcardKeyAgentMsg{
+		ReaderID: readerID,
+		PIN:      pin,
+	})
+	return c.simpleCall(req)
+}
+
+var _ SmartcardAgent = &client{}
+
+func (s *server) handleSmartcardKey(data []byte) error {
+	agent, ok := s.agent.(SmartcardAgent)
+	if !ok {
+		return errors.New("agent: smartcard keys not supported")
+	}
+	// As in ssh-agent, remote hosts that the agent is forwarded to must
+	// not make it load providers of their choosing.
+	if s.bindAttempted {
+		return errors.New("agent: refusing smartcard key request on a bound connection")
+	}
+
+	if data[0] == agentRemoveSmartcardKey {
+		var req removeSmartcardKeyAgentMsg
+		if err := ssh.Unmarshal(data, &req); err != nil {
+			return err
+		}
+		return agent.RemoveSmartcardKey(req.ReaderID, req.PIN)
+	}
+
+	var req addSmartcardKeyAgentMsg
+	if err := ssh.Unmarshal(data, &req); err != nil {
+		return err
+	}
+	if data[0] == agentAddSmartcardKey && len(req.Constraints) != 0 {
+		return errors.New("agent: constraints in unconstrained smartcard key request")
+	}
+	key := SmartcardKey{ReaderID: req.ReaderID, PIN: req.PIN}
+	var err error
+	key.LifetimeSecs, key.ConfirmBeforeUse, key.ConstraintExtensions, err = parseConstraints(req.Constraints)
+	if err != nil {
+		return err
+	}
+	return agent.AddSmartcardKey(key)
+}
@@ -0,0 +1,88 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package agent
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// smartcardAgent records the smartcard requests to a keyring.
+type smartcardAgent struct {
+	Agent
+	added   []SmartcardKey
+	removed []string
+}
+
+func (a *smartcardAgent) AddSmartcardKey(key SmartcardKey) error {
+	if string(key.PIN) != "1234" {
+		return errors.New("bad PIN")
+	}
+	a.added = append(a.added, key)
+	return nil
+}
+
+func (a *smartcardAgent) RemoveSmartcardKey(readerID string, pin []byte) error {
+	a.removed = append(a.removed, readerID)
+	return nil
+}
+
+func TestSmartcardKey(t *testing.T) {
+	upstream := &smartcardAgent{Agent: NewKeyring()}
+	client, cleanup := startAgent(t, upstream)
+	defer cleanup()
+	agent := client.(SmartcardAgent)
+
+	keys := []SmartcardKey{
+		{ReaderID: "/usr/lib/opensc-pkcs11.so", PIN: []byte("1234")},
+		{
+			ReaderID:             "/usr/lib/other.so",
+			PIN:                  []byte("1234"),
+			LifetimeSecs:         60,
+			ConfirmBeforeUse:     true,
+			ConstraintExtensions: []ConstraintExtension{{ExtensionName: "foo@example.com", ExtensionDetails: []byte("bar")}},
+		},
+	}
+	for _, key := range keys {
+		if err := agent.AddSmartcardKey(key); err != nil {
+			t.Fatalf("AddSmartcardKey(%q): %v", key.ReaderID, err)
+		}
+	}
+	if !reflect.DeepEqual(upstream.added, keys) {
+		t.Errorf("added %+v, want %+v", upstream.added, keys)
+	}
+	if err := agent.AddSmartcardKey(SmartcardKey{ReaderID: "x", PIN: []byte("0000")}); err == nil {
+		t.Error("AddSmartcardKey succeeded with a rejected PIN")
+	}
+	if err := agent.RemoveSmartcardKey(keys[0].ReaderID, nil); err != nil {
+		t.Fatalf("RemoveSmartcardKey: %v", err)
+	}
+	if !reflect.DeepEqual(upstream.removed, []string{keys[0].ReaderID}) {
+		t.Errorf("removed %q", upstream.removed)
+	}
+}
+
+func TestSmartcardKeyUnsupported(t *testing.T) {
+	client, cleanup := startKeyringAgent(t)
+	defer cleanup()
+	if err := client.(SmartcardAgent).AddSmartcardKey(SmartcardKey{ReaderID: "x"}); err == nil {
+		t.Error("AddSmartcardKey succeeded on a keyring")
+	}
+}
+
+func TestSmartcardKeyForwarded(t *testing.T) {
+	upstream := &smartcardAgent{Agent: NewKeyring()}
+	agent := forwardAgent(t, upstream).(SmartcardAgent)
+	if err := agent.AddSmartcardKey(SmartcardKey{ReaderID: "/tmp/evil.so", PIN: []byte("1234")}); err == nil {
+		t.Error("AddSmartcardKey succeeded on a forwarded connection")
+	}
+	if err := agent.RemoveSmartcardKey("/usr/lib/opensc-pkcs11.so", nil); err == nil {
+		t.Error("RemoveSmartcardKey succeeded on a forwarded connection")
+	}
+	if len(upstream.added) != 0 || len(upstream.removed) != 0 {
+		t.Errorf("upstream got smartcard requests: %+v, %q", upstream.added, upstream.removed)
+	}
+}
@@ -0,0 +1,250 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// AuthorizedKeyOptions are the options of an authorized_keys line, as
+// described in the AUTHORIZED_KEYS FILE FORMAT section of sshd(8).
+type AuthorizedKeyOptions struct {
+	// CertAuthority means the key is trusted to sign user certificates.
+	CertAuthority bool
+
+	// Command is the command forced by command=, or empty.
+	Command string
+
+	// Environment are the NAME=value pairs of environment= options.
+	Environment []string
+
+	// ExpiryTime is when the key stops being accepted, from
+	// expiry-time=, or zero.
+	ExpiryTime time.Time
+
+	// From are the patterns of from=, which the client's address must
+	// match, see AllowsSource.
+	From []string
+
+	// PermitOpen and PermitListen are the host:port pairs of permitopen=
+	// and permitlisten= options, restricting port forwarding.
+	PermitOpen   []string
+	PermitListen []string
+
+	// Principals are the names of principals=, for cert-authority keys.
+	Principals []string
+
+	// Tunnel is the device of tunnel=, or empty.
+	Tunnel string
+
+	// AgentForwarding, PortForwarding, PTY, UserRC and X11Forwarding are
+	// true unless disabled by restrict or the corresponding no- option,
+	// and can be enabled again after restrict.
+	AgentForwarding bool
+	PortForwarding  bool
+	PTY             bool
+	UserRC          bool
+	X11Forwarding   bool
+
+	// NoTouchRequired and VerifyRequired relax and tighten the checks of
+	// the user presence and verification flags of security key
+	// signatures.
+	NoTouchRequired bool
+	VerifyRequired  bool
+}
+
+// ParseAuthorizedKeyOptions decodes the options returned by
+// ParseAuthorizedKey. Option names are case-insensitive, as in sshd;
+// unknown options and malformed values are errors.
+func ParseAuthorizedKeyOptions(options []string) (*AuthorizedKeyOptions, error) {
+	o := &AuthorizedKeyOptions{
+		AgentForwarding: true,
+		PortForwarding:  true,
+		PTY:             true,
+		UserRC:          true,
+		X11Forwarding:   true,
+	}
+	for _, opt := range options {
+		name, value, hasValue := strings.Cut(opt, "=")
+		name = strings.ToLower(name)
+		if hasValue {
+			value, err := dequoteOption(value)
+			if err != nil {
+				return nil, fmt.Errorf("ssh: authorized_keys option %s: %v", name, err)
+			}
+			if err := o.setValue(name, value); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		enable := !strings.HasPrefix(name, "no-")
+		switch name {
+		case "cert-authority":
+			o.CertAuthority = true
+		case "restrict":
+			o.AgentForwarding, o.PortForwarding, o.PTY, o.UserRC, o.X11Forwarding = false, false, false, false, false
+		case "agent-forwarding", "no-agent-forwarding":
+			o.AgentForwarding = enable
+		case "port-forwarding", "no-port-forwarding":
+			o.PortForwarding = enable
+		case "pty", "no-pty":
+			o.PTY = enable
+		case "user-rc", "no-user-rc":
+			o.UserRC = enable
+		case "x11-forwarding", "no-x11-forwarding":
+			o.X11Forwarding = enable
+		case "no-touch-required":
+			o.NoTouchRequired = true
+		case "verify-required":
+			o.VerifyRequired = true
+		default:
+			return nil, fmt.Errorf("ssh: unknown authorized_keys option %q", name)
+		}
+	}
+	return o, nil
+}
+
+func (o *AuthorizedKeyOptions) setValue(name, value string) error {
+	switch name {
+	case "command":
+		if o.Command != "" {
+			return fmt.Errorf("ssh: duplicate authorized_keys option %s", name)
+		}
+		o.Command = value
+	case "environment":
+		if strings.IndexByte(value, '=') <= 0 {
+			return fmt.Errorf("ssh: invalid authorized_keys environment %q", value)
+		}
+		o.Environment = append(o.Environment, value)
+	case "expiry-time":
+		t, err := parseExpiryTime(value)
+		if err != nil {
+			return err
+		}
+		// Like sshd, the earliest of several expiry times wins.
+		if o.ExpiryTime.IsZero() || t.Before(o.ExpiryTime) {
+			o.ExpiryTime = t
+		}
+	case "from":
+		o.From = append(o.From, strings.Split(value, ",")...)
+	case "permitopen":
+		if !strings.Contains(value, ":") {
+			return fmt.Errorf("ssh: invalid authorized_keys permitopen %q", value)
+		}
+		o.PermitOpen = append(o.PermitOpen, value)
+	case "permitlisten":
+		o.PermitListen = append(o.PermitListen, value)
+	case "principals":
+		o.Principals = append(o.Principals, strings.Split(value, ",")...)
+	case "tunnel":
+		o.Tunnel = value
+	default:
+		return fmt.Errorf("ssh: unknown authorized_keys option %q", name)
+	}
+	return nil
+}
+
+// dequoteOption removes the quotes around an option value, unescaping
+// quotes inside it.
+func dequoteOption(v string) (string, error) {
+	if len(v) < 2 || v[0] != '"' || v[len(v)-1] != '"' {
+		return "", fmt.Errorf("value %q is not quoted", v)
+	}
+	return strings.ReplaceAll(v[1:len(v)-1], `\"`, `"`), nil
+}
+
+// parseExpiryTime parses a time in the YYYYMMDD[HHMM[SS]] format of sshd,
+// in local time or, with a Z suffix, UTC.
+func parseExpiryTime(v string) (time.Time, error) {
+	loc := time.Local
+	if s, ok := strings.CutSuffix(v, "Z"); ok {
+		v, loc = s, time.UTC
+	}
+	var layout string
+	switch len(v) {
+	case 8:
+		layout = "20060102"
+	case 12:
+		layout = "200601021504"
+	case 14:
+		layout = "20060102150405"
+	default:
+		return time.Time{}, fmt.Errorf("ssh: invalid authorized_keys expiry-time %q", v)
+	}
+	t, err := time.ParseInLocation(layout, v, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("ssh: invalid authorized_keys expiry-time %q", v)
+	}
+	return t, nil
+}
+
+// Expired reports whether the key has an expiry time that is not after
+// now.
+func (o *AuthorizedKeyOptions) Expired(now time.Time) bool {
+	return !o.ExpiryTime.IsZero() && !now.Before(o.ExpiryTime)
+}
+
+// AllowsSource reports whether a client connecting from addr is allowed by
+// the from= patterns. Patterns may use '*' and '?' wildcards, CIDR
+// notation, and '!' to deny addresses that would otherwise be allowed. Only
+// the IP address of addr is matched: patterns naming hosts, which sshd
+// matches after a reverse DNS lookup, never match. A key without from=
+// allows any address.
+func (o *AuthorizedKeyOptions) AllowsSource(addr net.Addr) bool {
+	if len(o.From) == 0 {
+		return true
+	}
+	var ip net.IP
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		ip = a.IP
+	case *net.UDPAddr:
+		ip = a.IP
+	case *net.IPAddr:
+		ip = a.IP
+	default:
+		if addr == nil {
+			return false
+		}
+		host, _, err := net.SplitHostPort(addr.String())
+		if err != nil {
+			host = addr.String()
+		}
+		if ip = net.ParseIP(host); ip == nil {
+			return false
+		}
+	}
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+
+	allowed := false
+	for _, pattern := range o.From {
+		negated := strings.HasPrefix(pattern, "!")
+		if negated {
+			pattern = pattern[1:]
+		}
+		if !matchAddress(pattern, ip) {
+			continue
+		}
+		if negated {
+			return false
+		}
+		allowed = true
+	}
+	return allowed
+}
+
+func matchAddress(pattern string, ip net.IP) bool {
+	if strings.Contains(pattern, "/") {
+		_, ipNet, err := net.ParseCIDR(pattern)
+		return err == nil && ipNet.Contains(ip)
+	}
+	return matchPattern(pattern, ip.String())
+}
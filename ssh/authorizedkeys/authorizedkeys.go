@@ -0,0 +1,202 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package authorizedkeys writes OpenSSH authorized_keys files, the format
+// read by ssh.ParseAuthorizedKey and described in the AUTHORIZED_KEYS FILE
+// FORMAT section of sshd(8).
+//
+// Files are updated by writing a temporary file next to them and renaming
+// it into place, so that sshd never reads a partially written file.
+// Concurrent updates of the same file are not coordinated: the last rename
+// wins.
+package authorizedkeys
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// An Entry is a line of an authorized_keys file.
+type Entry struct {
+	// Options are the options of the key, in the form returned by
+	// ssh.ParseAuthorizedKey: either a flag such as "no-pty" or a name
+	// and quoted value made with Option.
+	Options []string
+
+	Key ssh.PublicKey
+
+	// Comment follows the key; it must not contain newlines.
+	Comment string
+}
+
+// Option returns an option with a value, such as command="uptime", quoting
+// the value and escaping the quotes inside it.
+func Option(name, value string) string {
+	return name + `="` + strings.ReplaceAll(value, `"`, `\"`) + `"`
+}
+
+// Line returns the entry as a line of an authorized_keys file, ending with
+// a newline. It returns an error if the entry would not read back as the
+// same options, key and comment, for example because an option value
+// contains a newline or ends with a backslash.
+func (e *Entry) Line() ([]byte, error) {
+	if e.Key == nil {
+		return nil, errors.New("authorizedkeys: entry has no key")
+	}
+	var b bytes.Buffer
+	if len(e.Options) > 0 {
+		b.WriteString(strings.Join(e.Options, ","))
+		b.WriteByte(' ')
+	}
+	key := ssh.MarshalAuthorizedKey(e.Key)
+	b.Write(key[:len(key)-1])
+	if e.Comment != "" {
+		b.WriteByte(' ')
+		b.WriteString(e.Comment)
+	}
+	b.WriteByte('\n')
+
+	pub, comment, options, rest, err := ssh.ParseAuthorizedKey(b.Bytes())
+	if err != nil || len(rest) > 0 || comment != e.Comment ||
+		!bytes.Equal(pub.Marshal(), e.Key.Marshal()) ||
+		!(len(options) == 0 && len(e.Options) == 0 || reflect.DeepEqual(options, e.Options)) {
+		return nil, fmt.Errorf("authorizedkeys: entry for %s cannot be written as a single line", ssh.FingerprintSHA256(e.Key))
+	}
+	return b.Bytes(), nil
+}
+
+// Append adds entries to the end of the file at path, creating it with
+// permissions 0600 if it doesn't exist.
+func Append(path string, entries ...Entry) error {
+	var lines []byte
+	for i := range entries {
+		line, err := entries[i].Line()
+		if err != nil {
+			return err
+		}
+		lines = append(lines, line...)
+	}
+
+	data, perm, err := readFile(path)
+	if err != nil {
+		return err
+	}
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		data = append(data, '\n')
+	}
+	return writeFile(path, append(data, lines...), perm)
+}
+
+// Remove rewrites the file at path without the entries for which remove
+// returns true, and returns how many were removed. Comments, blank lines
+// and lines that can't be parsed are kept as they are. If no entries are
+// removed, the file is left untouched.
+func Remove(path string, remove func(e *Entry) bool) (int, error) {
+	data, perm, err := readFile(path)
+	if err != nil {
+		return 0, err
+	}
+	var out []byte
+	removed := 0
+	s := bufio.NewScanner(bytes.NewReader(data))
+	s.Split(scanLines)
+	for s.Scan() {
+		line := s.Bytes()
+		pub, comment, options, _, err := ssh.ParseAuthorizedKey(line)
+		if err == nil && remove(&Entry{Options: options, Key: pub, Comment: comment}) {
+			removed++
+			continue
+		}
+		out = append(out, line...)
+	}
+	if err := s.Err(); err != nil {
+		return 0, err
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, writeFile(path, out, perm)
+}
+
+// RemoveKey removes the entries for key from the file at path, and returns
+// how many were removed.
+func RemoveKey(path string, key ssh.PublicKey) (int, error) {
+	want := key.Marshal()
+	return Remove(path, func(e *Entry) bool {
+		return bytes.Equal(e.Key.Marshal(), want)
+	})
+}
+
+// scanLines is like bufio.ScanLines, but keeps the line endings so that
+// lines are written back unchanged.
+func scanLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return i + 1, data[:i+1], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// readFile returns the contents and permissions of the file at path, or
+// nothing and 0600 if it doesn't exist.
+func readFile(path string) ([]byte, fs.FileMode, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, 0600, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, fi.Mode().Perm(), nil
+}
+
+// writeFile replaces the file at path with data by renaming a temporary
+// file over it.
+func writeFile(path string, data []byte, perm fs.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	if err := writeAndClose(f, data, perm); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+func writeAndClose(f *os.File, data []byte, perm fs.FileMode) error {
+	if err := f.Chmod(perm); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
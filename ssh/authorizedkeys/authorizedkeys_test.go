@@ -0,0 +1,151 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package authorizedkeys
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/testdata"
+)
+
+func publicKey(t *testing.T, name string) ssh.PublicKey {
+	t.Helper()
+	s, err := ssh.ParsePrivateKey(testdata.PEMBytes[name])
+	if err != nil {
+		t.Fatalf("ParsePrivateKey(%q): %v", name, err)
+	}
+	return s.PublicKey()
+}
+
+func TestLineRoundTrip(t *testing.T) {
+	e := Entry{
+		Options: []string{"restrict", Option("command", `echo "a, b"`), Option("from", "10.0.0.0/8,!10.1.2.3")},
+		Key:     publicKey(t, "ed25519"),
+		Comment: "alice@example.com laptop",
+	}
+	line, err := e.Line()
+	if err != nil {
+		t.Fatalf("Line: %v", err)
+	}
+	if !strings.HasPrefix(string(line), `restrict,command="echo \"a, b\"",from="10.0.0.0/8,!10.1.2.3" ssh-ed25519 `) {
+		t.Errorf("got line %q", line)
+	}
+
+	pub, comment, options, _, err := ssh.ParseAuthorizedKey(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if comment != e.Comment || !reflect.DeepEqual(options, e.Options) || string(pub.Marshal()) != string(e.Key.Marshal()) {
+		t.Errorf("got %q, %q", options, comment)
+	}
+	o, err := ssh.ParseAuthorizedKeyOptions(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.Command != `echo "a, b"` || o.PTY {
+		t.Errorf("got options %+v", o)
+	}
+
+	if line, err := (&Entry{Key: e.Key}).Line(); err != nil || strings.Count(string(line), " ") != 1 {
+		t.Errorf("entry without options and comment: %q, %v", line, err)
+	}
+}
+
+func TestLineInvalid(t *testing.T) {
+	key := publicKey(t, "ed25519")
+	for _, e := range []Entry{
+		{},
+		{Key: key, Comment: "two\nlines"},
+		{Key: key, Options: []string{Option("command", "ends with \\")}},
+		{Key: key, Options: []string{Option("command", "new\nline")}},
+		{Key: key, Options: []string{"no pty"}},
+	} {
+		if line, err := e.Line(); err == nil {
+			t.Errorf("Line(%+v) = %q, want error", e, line)
+		}
+	}
+}
+
+func TestAppendAndRemove(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "authorized_keys")
+	ed, ec, rsa := publicKey(t, "ed25519"), publicKey(t, "ecdsa"), publicKey(t, "rsa")
+
+	if err := Append(path, Entry{Key: ed, Comment: "first"}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := fi.Mode().Perm(); perm != 0600 {
+		t.Errorf("created file with permissions %v, want 0600", perm)
+	}
+
+	// Add a comment and a line without a trailing newline by hand.
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("# managed by hand\r\n")
+	f.Write(ssh.MarshalAuthorizedKey(ec)[:len(ssh.MarshalAuthorizedKey(ec))-1])
+	f.Close()
+	if err := os.Chmod(path, 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Append(path, Entry{Key: rsa, Options: []string{"no-pty"}}, Entry{Key: ed, Comment: "again"}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	keys := readKeys(t, path)
+	if want := []string{"first", "", "", "again"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("got comments %q, want %q", keys, want)
+	}
+
+	n, err := RemoveKey(path, ed)
+	if err != nil || n != 2 {
+		t.Fatalf("RemoveKey = %d, %v; want 2 removed", n, err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "# managed by hand\r\necdsa-sha2-nistp256 ") || strings.Contains(string(data), "ssh-ed25519") {
+		t.Errorf("got file %q", data)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0640 {
+		t.Errorf("permissions not kept: %v, %v", fi.Mode(), err)
+	}
+
+	if n, err := RemoveKey(path, ed); err != nil || n != 0 {
+		t.Errorf("second RemoveKey = %d, %v", n, err)
+	}
+	matches, _ := filepath.Glob(filepath.Join(filepath.Dir(path), ".*tmp*"))
+	if len(matches) > 0 {
+		t.Errorf("temporary files left behind: %v", matches)
+	}
+}
+
+// readKeys returns the comments of the keys in the file at path.
+func readKeys(t *testing.T, path string) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var comments []string
+	for len(data) > 0 {
+		_, comment, _, rest, err := ssh.ParseAuthorizedKey(data)
+		if err != nil {
+			break
+		}
+		comments = append(comments, comment)
+		data = rest
+	}
+	return comments
+}
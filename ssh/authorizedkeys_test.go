@@ -0,0 +1,99 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseAuthorizedKeyOptions(t *testing.T) {
+	line := `restrict,pty,command="echo \"hi\", bye",environment="LANG=C",expiry-time="20300102Z",` +
+		`from="10.0.0.0/8,!10.1.2.3,192.168.1.*",permitopen="localhost:80",permitlisten="8080",` +
+		`principals="alice,bob",Cert-Authority,no-touch-required ` +
+		string(MarshalAuthorizedKey(testPublicKeys["ed25519"]))
+	_, _, options, _, err := ParseAuthorizedKey([]byte(line))
+	if err != nil {
+		t.Fatalf("ParseAuthorizedKey: %v", err)
+	}
+	o, err := ParseAuthorizedKeyOptions(options)
+	if err != nil {
+		t.Fatalf("ParseAuthorizedKeyOptions: %v", err)
+	}
+	want := &AuthorizedKeyOptions{
+		CertAuthority:   true,
+		Command:         `echo "hi", bye`,
+		Environment:     []string{"LANG=C"},
+		ExpiryTime:      time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC),
+		From:            []string{"10.0.0.0/8", "!10.1.2.3", "192.168.1.*"},
+		PermitOpen:      []string{"localhost:80"},
+		PermitListen:    []string{"8080"},
+		Principals:      []string{"alice", "bob"},
+		PTY:             true,
+		NoTouchRequired: true,
+	}
+	if !reflect.DeepEqual(o, want) {
+		t.Errorf("got  %+v\nwant %+v", o, want)
+	}
+
+	for ip, allowed := range map[string]bool{
+		"10.9.9.9":     true,
+		"10.1.2.3":     false,
+		"192.168.1.20": true,
+		"192.168.2.20": false,
+		"2001:db8::1":  false,
+	} {
+		addr := &net.TCPAddr{IP: net.ParseIP(ip), Port: 1234}
+		if got := o.AllowsSource(addr); got != allowed {
+			t.Errorf("AllowsSource(%s) = %v, want %v", ip, got, allowed)
+		}
+	}
+	if !o.Expired(time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)) || o.Expired(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Error("wrong expiry")
+	}
+}
+
+func TestParseAuthorizedKeyOptionsDefaults(t *testing.T) {
+	o, err := ParseAuthorizedKeyOptions(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !o.AgentForwarding || !o.PortForwarding || !o.PTY || !o.UserRC || !o.X11Forwarding {
+		t.Errorf("features disabled by default: %+v", o)
+	}
+	if !o.AllowsSource(&net.TCPAddr{IP: net.IPv4(1, 2, 3, 4)}) || o.Expired(time.Now()) {
+		t.Error("key without options is restricted")
+	}
+
+	o, err = ParseAuthorizedKeyOptions([]string{"no-agent-forwarding", "no-X11-forwarding", `expiry-time="202001020304"`, `expiry-time="201901020304"`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.AgentForwarding || o.X11Forwarding || !o.PTY {
+		t.Errorf("got %+v", o)
+	}
+	if want := time.Date(2019, 1, 2, 3, 4, 0, 0, time.Local); !o.ExpiryTime.Equal(want) {
+		t.Errorf("got expiry %v, want the earliest, %v", o.ExpiryTime, want)
+	}
+}
+
+func TestParseAuthorizedKeyOptionsErrors(t *testing.T) {
+	for _, options := range [][]string{
+		{"no-such-option"},
+		{`no-such-option="x"`},
+		{"command=unquoted"},
+		{`command="a"`, `command="b"`},
+		{`environment="NOEQUALS"`},
+		{`expiry-time="2020"`},
+		{`expiry-time="20201399"`},
+		{`permitopen="noport"`},
+	} {
+		if _, err := ParseAuthorizedKeyOptions(options); err == nil {
+			t.Errorf("ParseAuthorizedKeyOptions(%q) succeeded", options)
+		}
+	}
+}
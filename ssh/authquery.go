@@ -0,0 +1,75 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// ServerAuthInfo describes how a server lets a user authenticate, as
+// returned by QueryAuthMethods.
+type ServerAuthInfo struct {
+	// Methods are the authentication methods that the server lists as
+	// able to continue, such as "publickey", "password" or
+	// "keyboard-interactive". It is empty if NoneAccepted is true.
+	Methods []string
+
+	// Banner is the concatenation of the banner messages that the server
+	// sent, if any.
+	Banner string
+
+	// NoneAccepted reports whether the server accepted the "none"
+	// method, that is, lets the user in without credentials.
+	NoneAccepted bool
+}
+
+// QueryAuthMethods performs the key exchange with the server on c and sends
+// a "none" authentication request for config.User, returning the
+// authentication methods and banner that the server answers with, so that
+// tools can find out how a user may log in before asking them for
+// credentials. The server is verified with config.HostKeyCallback as for
+// NewClientConn, config.Auth is ignored and c is closed before returning.
+func QueryAuthMethods(c net.Conn, addr string, config *ClientConfig) (*ServerAuthInfo, error) {
+	defer c.Close()
+
+	fullConf := *config
+	fullConf.SetDefaults()
+	if fullConf.GSSAPIKeyExchange != nil {
+		fullConf.KeyExchanges = withGSSKexAlgos(fullConf.KeyExchanges)
+	}
+	if fullConf.HostKeyCallback == nil {
+		return nil, errors.New("ssh: must specify HostKeyCallback")
+	}
+	info := &ServerAuthInfo{}
+	bannerCallback := fullConf.BannerCallback
+	fullConf.BannerCallback = func(message string) error {
+		info.Banner += message
+		if bannerCallback != nil {
+			return bannerCallback(message)
+		}
+		return nil
+	}
+
+	conn := &connection{
+		sshConn: sshConn{conn: c, user: fullConf.User},
+	}
+	if err := conn.clientKeyExchange(addr, &fullConf); err != nil {
+		return nil, fmt.Errorf("ssh: handshake failed: %w", clientHandshakeError(err, ""))
+	}
+	defer conn.transport.Close()
+	if err := conn.requestUserAuth(); err != nil {
+		return nil, clientHandshakeError(err, info.Banner)
+	}
+	result, methods, err := new(noneAuth).auth(context.Background(), conn.sessionID, fullConf.User, conn.transport, fullConf.Rand, conn.transport.peerExtInfo)
+	if err != nil {
+		return nil, clientHandshakeError(err, info.Banner)
+	}
+	info.NoneAccepted = result == authSuccess
+	info.Methods = methods
+	return info, nil
+}
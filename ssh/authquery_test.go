@@ -0,0 +1,89 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestQueryAuthMethods(t *testing.T) {
+	for _, tt := range []struct {
+		name         string
+		serverConfig *ServerConfig
+		want         ServerAuthInfo
+	}{
+		{
+			name: "methods",
+			serverConfig: &ServerConfig{
+				PasswordCallback: func(conn ConnMetadata, pass []byte) (*Permissions, error) {
+					return nil, errors.New("password auth failed")
+				},
+				PublicKeyCallback: func(conn ConnMetadata, key PublicKey) (*Permissions, error) {
+					return nil, errors.New("publickey auth failed")
+				},
+				BannerCallback: func(conn ConnMetadata) string {
+					return "welcome " + conn.User()
+				},
+			},
+			want: ServerAuthInfo{
+				Methods: []string{"password", "publickey"},
+				Banner:  "welcome testuser",
+			},
+		},
+		{
+			name:         "none",
+			serverConfig: &ServerConfig{NoClientAuth: true},
+			want:         ServerAuthInfo{NoneAccepted: true},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			c1, c2, err := netPipe()
+			if err != nil {
+				t.Fatalf("netPipe: %v", err)
+			}
+			defer c1.Close()
+			defer c2.Close()
+
+			tt.serverConfig.AddHostKey(testSigners["rsa"])
+			go newServer(c1, tt.serverConfig)
+
+			var banners []string
+			config := &ClientConfig{
+				User:            "testuser",
+				Auth:            []AuthMethod{Password("unused")},
+				HostKeyCallback: InsecureIgnoreHostKey(),
+				BannerCallback: func(message string) error {
+					banners = append(banners, message)
+					return nil
+				},
+			}
+			info, err := QueryAuthMethods(c2, "", config)
+			if err != nil {
+				t.Fatalf("QueryAuthMethods: %v", err)
+			}
+			if !reflect.DeepEqual(*info, tt.want) {
+				t.Errorf("got %+v, want %+v", *info, tt.want)
+			}
+			if tt.want.Banner != "" && (len(banners) != 1 || banners[0] != tt.want.Banner) {
+				t.Errorf("BannerCallback got %q, want %q", banners, tt.want.Banner)
+			}
+		})
+	}
+}
+
+func TestQueryAuthMethodsNoHostKeyCallback(t *testing.T) {
+	c1, c2, err := netPipe()
+	if err != nil {
+		t.Fatalf("netPipe: %v", err)
+	}
+	defer c1.Close()
+	defer c2.Close()
+
+	if _, err := QueryAuthMethods(c2, "", &ClientConfig{User: "testuser"}); err == nil {
+		t.Fatal("QueryAuthMethods succeeded without HostKeyCallback")
+	}
+}
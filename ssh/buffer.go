@@ -14,6 +14,8 @@ import (
 // buffer provides a linked list buffer for data exchange
 // between producer and consumer. Theoretically the buffer is
 // of unlimited capacity as it does no allocation of its own.
+// The buffers of channels are bounded by the flow-control
+// window, see Config.ChannelBufferSize.
 type buffer struct {
 	// protects concurrent access to head, tail and closed
 	*sync.Cond
@@ -96,6 +98,35 @@ func (b *buffer) deadline() {
 	b.Cond.L.Unlock()
 }
 
+// readMessage returns the data passed to the next call of write in one
+// piece, or what is left of it after a partial Read. Like Read, it blocks
+// until data is available or the buffer is closed.
+func (b *buffer) readMessage() ([]byte, error) {
+	b.Cond.L.Lock()
+	defer b.Cond.L.Unlock()
+
+	for {
+		if b.deadlineReached {
+			return nil, os.ErrDeadlineExceeded
+		}
+		if len(b.head.buf) == 0 && b.head != b.tail {
+			b.head = b.head.next
+		}
+		if len(b.head.buf) > 0 {
+			buf := b.head.buf
+			b.head.buf = nil
+			return buf, nil
+		}
+		if b.head != b.tail {
+			continue
+		}
+		if b.closed {
+			return nil, io.EOF
+		}
+		b.Cond.Wait()
+	}
+}
+
 // Read reads data from the internal buffer in buf.  Reads will block
 // if no data is available, or until the buffer is closed.
 func (b *buffer) Read(buf []byte) (n int, err error) {
@@ -0,0 +1,232 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// forceCommandCriticalOption restricts a user certificate to one command.
+const forceCommandCriticalOption = "force-command"
+
+// defaultUserCertExtensions are the extensions ssh-keygen grants to user
+// certificates unless told otherwise.
+var defaultUserCertExtensions = []string{
+	"permit-X11-forwarding",
+	"permit-agent-forwarding",
+	"permit-port-forwarding",
+	"permit-pty",
+	"permit-user-rc",
+}
+
+// A CertificateBuilder assembles and signs a Certificate, checking that its
+// fields are consistent. Its setters return the builder, so that calls can
+// be chained:
+//
+//	cert, err := NewCertificateBuilder(userKey, UserCert).
+//		KeyID("alice").
+//		Principals("alice").
+//		ValidBetween(now, now.Add(8*time.Hour)).
+//		SignWith(ca, KeyAlgoED25519)
+//
+// Like ssh-keygen, certificates are valid forever unless a validity period
+// is set, and user certificates have the permit-X11-forwarding,
+// permit-agent-forwarding, permit-port-forwarding, permit-pty and
+// permit-user-rc extensions unless ClearExtensions is called.
+type CertificateBuilder struct {
+	cert         Certificate
+	anyPrincipal bool
+}
+
+// NewCertificateBuilder returns a builder for a certificate of certType,
+// UserCert or HostCert, for key.
+func NewCertificateBuilder(key PublicKey, certType uint32) *CertificateBuilder {
+	b := &CertificateBuilder{cert: Certificate{
+		Key:         key,
+		CertType:    certType,
+		ValidBefore: CertTimeInfinity,
+		Permissions: Permissions{
+			CriticalOptions: make(map[string]string),
+			Extensions:      make(map[string]string),
+		},
+	}}
+	if certType == UserCert {
+		for _, ext := range defaultUserCertExtensions {
+			b.cert.Extensions[ext] = ""
+		}
+	}
+	return b
+}
+
+// Serial sets the serial number, which can be used to revoke the
+// certificate. It defaults to zero.
+func (b *CertificateBuilder) Serial(serial uint64) *CertificateBuilder {
+	b.cert.Serial = serial
+	return b
+}
+
+// KeyID sets the key identifier, which servers log when the certificate is
+// used.
+func (b *CertificateBuilder) KeyID(id string) *CertificateBuilder {
+	b.cert.KeyId = id
+	return b
+}
+
+// Principals adds user names, for user certificates, or host names, for
+// host certificates, that the certificate is valid for.
+func (b *CertificateBuilder) Principals(principals ...string) *CertificateBuilder {
+	b.cert.ValidPrincipals = append(b.cert.ValidPrincipals, principals...)
+	return b
+}
+
+// AllowAnyPrincipal allows a certificate without principals, which is valid
+// for any user or host name. Without it, Validate requires principals.
+func (b *CertificateBuilder) AllowAnyPrincipal() *CertificateBuilder {
+	b.anyPrincipal = true
+	return b
+}
+
+// ValidBetween sets the validity period of the certificate. A zero after
+// leaves it valid from the beginning of time, and a zero before valid
+// forever.
+func (b *CertificateBuilder) ValidBetween(after, before time.Time) *CertificateBuilder {
+	b.cert.ValidAfter = 0
+	if !after.IsZero() {
+		b.cert.ValidAfter = uint64(after.Unix())
+		if after.Unix() < 0 {
+			b.cert.ValidAfter = 0
+		}
+	}
+	b.cert.ValidBefore = CertTimeInfinity
+	if !before.IsZero() {
+		b.cert.ValidBefore = uint64(before.Unix())
+		if before.Unix() < 0 {
+			b.cert.ValidBefore = 0
+		}
+	}
+	return b
+}
+
+// CriticalOption sets a critical option, which servers must understand to
+// accept the certificate.
+func (b *CertificateBuilder) CriticalOption(name, value string) *CertificateBuilder {
+	b.cert.CriticalOptions[name] = value
+	return b
+}
+
+// ForceCommand sets the force-command critical option, restricting a user
+// certificate to running command.
+func (b *CertificateBuilder) ForceCommand(command string) *CertificateBuilder {
+	return b.CriticalOption(forceCommandCriticalOption, command)
+}
+
+// SourceAddress sets the source-address critical option, restricting the
+// use of a user certificate to clients at the given addresses, IP
+// addresses or CIDR ranges.
+func (b *CertificateBuilder) SourceAddress(addrs ...string) *CertificateBuilder {
+	return b.CriticalOption(sourceAddressCriticalOption, strings.Join(addrs, ","))
+}
+
+// Extension sets an extension, which servers that don't understand it
+// ignore.
+func (b *CertificateBuilder) Extension(name, value string) *CertificateBuilder {
+	b.cert.Extensions[name] = value
+	return b
+}
+
+// ClearExtensions removes all extensions, including the defaults of user
+// certificates.
+func (b *CertificateBuilder) ClearExtensions() *CertificateBuilder {
+	b.cert.Extensions = make(map[string]string)
+	return b
+}
+
+// Validate reports whether the certificate is consistent.
+func (b *CertificateBuilder) Validate() error {
+	c := &b.cert
+	if c.Key == nil {
+		return errors.New("ssh: certificate has no key")
+	}
+	if _, ok := c.Key.(*Certificate); ok {
+		return errors.New("ssh: certificate key is itself a certificate")
+	}
+	if c.CertType != UserCert && c.CertType != HostCert {
+		return fmt.Errorf("ssh: invalid certificate type %d", c.CertType)
+	}
+	if len(c.ValidPrincipals) == 0 && !b.anyPrincipal {
+		return errors.New("ssh: certificate has no principals; call AllowAnyPrincipal to make it valid for all")
+	}
+	for _, p := range c.ValidPrincipals {
+		if p == "" {
+			return errors.New("ssh: certificate has an empty principal")
+		}
+	}
+	if c.ValidAfter >= c.ValidBefore {
+		return errors.New("ssh: certificate validity period is empty")
+	}
+	if c.CertType == HostCert && len(c.CriticalOptions) > 0 {
+		return errors.New("ssh: host certificates have no critical options")
+	}
+	if addrs, ok := c.CriticalOptions[sourceAddressCriticalOption]; ok {
+		for _, addr := range strings.Split(addrs, ",") {
+			if net.ParseIP(addr) != nil {
+				continue
+			}
+			if _, _, err := net.ParseCIDR(addr); err != nil {
+				return fmt.Errorf("ssh: invalid source-address %q", addr)
+			}
+		}
+	}
+	return nil
+}
+
+// Build validates the certificate and returns it unsigned.
+func (b *CertificateBuilder) Build() (*Certificate, error) {
+	if err := b.Validate(); err != nil {
+		return nil, err
+	}
+	c := b.cert
+	c.ValidPrincipals = append([]string(nil), c.ValidPrincipals...)
+	c.CriticalOptions = copyTuples(c.CriticalOptions)
+	c.Extensions = copyTuples(c.Extensions)
+	return &c, nil
+}
+
+func copyTuples(m map[string]string) map[string]string {
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// SignWith validates the certificate and signs it with the certificate
+// authority ca, using a random nonce. algorithm is the signature algorithm,
+// such as KeyAlgoRSASHA256; if empty, it is chosen like SignCert does. The
+// algorithm must be supported by ca.
+func (b *CertificateBuilder) SignWith(ca Signer, algorithm string) (*Certificate, error) {
+	cert, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+	if algorithm == "" {
+		err = cert.SignCert(rand.Reader, ca)
+	} else if as, ok := ca.(AlgorithmSigner); ok {
+		err = cert.SignCertWithAlgorithm(rand.Reader, as, algorithm)
+	} else if algorithm != ca.PublicKey().Type() {
+		err = fmt.Errorf("ssh: certificate authority does not support algorithm %q", algorithm)
+	} else {
+		err = cert.SignCert(rand.Reader, ca)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return cert, nil
+}
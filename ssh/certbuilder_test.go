@@ -0,0 +1,127 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCertificateBuilder(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	cert, err := NewCertificateBuilder(testPublicKeys["ecdsa"], UserCert).
+		Serial(42).
+		KeyID("alice@example.com").
+		Principals("alice", "root").
+		ValidBetween(now, now.Add(time.Hour)).
+		SourceAddress("192.0.2.1", "198.51.100.0/24").
+		Extension("permit-pty", "").
+		SignWith(testSigners["rsa"], KeyAlgoRSASHA256)
+	if err != nil {
+		t.Fatalf("SignWith: %v", err)
+	}
+
+	if cert.Serial != 42 || cert.KeyId != "alice@example.com" || cert.CertType != UserCert {
+		t.Errorf("got serial %d, key ID %q, type %d", cert.Serial, cert.KeyId, cert.CertType)
+	}
+	if cert.ValidAfter != uint64(now.Unix()) || cert.ValidBefore != uint64(now.Unix()+3600) {
+		t.Errorf("got validity [%d, %d)", cert.ValidAfter, cert.ValidBefore)
+	}
+	if got := cert.CriticalOptions[sourceAddressCriticalOption]; got != "192.0.2.1,198.51.100.0/24" {
+		t.Errorf("got source-address %q", got)
+	}
+	for _, ext := range defaultUserCertExtensions {
+		if _, ok := cert.Extensions[ext]; !ok {
+			t.Errorf("missing default extension %q", ext)
+		}
+	}
+	if cert.Signature.Format != KeyAlgoRSASHA256 {
+		t.Errorf("got signature algorithm %q, want %q", cert.Signature.Format, KeyAlgoRSASHA256)
+	}
+
+	checker := CertChecker{
+		IsUserAuthority: func(auth PublicKey) bool {
+			return bytes.Equal(auth.Marshal(), testPublicKeys["rsa"].Marshal())
+		},
+		Clock: func() time.Time { return now.Add(time.Minute) },
+	}
+	if err := checker.CheckCert("alice", cert); err != nil {
+		t.Errorf("CheckCert: %v", err)
+	}
+}
+
+func TestCertificateBuilderDefaults(t *testing.T) {
+	cert, err := NewCertificateBuilder(testPublicKeys["ed25519"], HostCert).
+		Principals("host.example.com").
+		SignWith(testSigners["ed25519"], "")
+	if err != nil {
+		t.Fatalf("SignWith: %v", err)
+	}
+	if cert.ValidAfter != 0 || cert.ValidBefore != CertTimeInfinity {
+		t.Errorf("got validity [%d, %d), want forever", cert.ValidAfter, cert.ValidBefore)
+	}
+	if len(cert.Extensions) != 0 || len(cert.CriticalOptions) != 0 {
+		t.Errorf("host certificate has permissions %v", cert.Permissions)
+	}
+	if len(cert.Nonce) == 0 {
+		t.Error("certificate has no nonce")
+	}
+}
+
+func TestCertificateBuilderBuildCopies(t *testing.T) {
+	b := NewCertificateBuilder(testPublicKeys["ed25519"], UserCert).Principals("alice")
+	cert, err := b.Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	b.Principals("bob").Extension("permit-user-rc", "x").ClearExtensions()
+	if len(cert.ValidPrincipals) != 1 || len(cert.Extensions) != len(defaultUserCertExtensions) {
+		t.Errorf("changing the builder changed the built certificate: %v", cert)
+	}
+	if cert.Signature != nil {
+		t.Error("Build returned a signed certificate")
+	}
+}
+
+func TestCertificateBuilderValidate(t *testing.T) {
+	now := time.Now()
+	key := testPublicKeys["ed25519"]
+	tests := []struct {
+		name    string
+		builder *CertificateBuilder
+		wantErr string
+	}{
+		{"no key", NewCertificateBuilder(nil, UserCert).Principals("a"), "no key"},
+		{"bad type", NewCertificateBuilder(key, 3).Principals("a"), "type"},
+		{"no principals", NewCertificateBuilder(key, UserCert), "principals"},
+		{"empty principal", NewCertificateBuilder(key, UserCert).Principals(""), "empty principal"},
+		{"backwards", NewCertificateBuilder(key, UserCert).Principals("a").ValidBetween(now, now.Add(-time.Hour)), "validity"},
+		{"empty period", NewCertificateBuilder(key, UserCert).Principals("a").ValidBetween(now, now), "validity"},
+		{"host critical option", NewCertificateBuilder(key, HostCert).Principals("h").ForceCommand("ls"), "critical"},
+		{"bad source", NewCertificateBuilder(key, UserCert).Principals("a").SourceAddress("example.com"), "source-address"},
+	}
+	for _, tt := range tests {
+		err := tt.builder.Validate()
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: got error %v, want one containing %q", tt.name, err, tt.wantErr)
+		}
+		if _, err := tt.builder.SignWith(testSigners["ed25519"], ""); err == nil {
+			t.Errorf("%s: SignWith succeeded", tt.name)
+		}
+	}
+
+	if err := NewCertificateBuilder(key, UserCert).AllowAnyPrincipal().Validate(); err != nil {
+		t.Errorf("AllowAnyPrincipal: %v", err)
+	}
+}
+
+func TestCertificateBuilderSignWithUnsupportedAlgorithm(t *testing.T) {
+	b := NewCertificateBuilder(testPublicKeys["ed25519"], UserCert).Principals("alice")
+	if _, err := b.SignWith(testSigners["ecdsa"], KeyAlgoRSASHA512); err == nil {
+		t.Error("SignWith succeeded with an algorithm the authority doesn't support")
+	}
+}
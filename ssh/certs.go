@@ -6,6 +6,7 @@ package ssh
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -279,12 +280,22 @@ func (s *openSSHCertSigner) PublicKey() PublicKey {
 	return s.pub
 }
 
+func (s *openSSHCertSigner) SignWithContext(ctx context.Context, rand io.Reader, data []byte, algorithm string) (*Signature, error) {
+	return signWithContext(ctx, s.signer, rand, data, algorithm)
+}
+
 func (s *algorithmOpenSSHCertSigner) SignWithAlgorithm(rand io.Reader, data []byte, algorithm string) (*Signature, error) {
 	return s.algorithmSigner.SignWithAlgorithm(rand, data, algorithm)
 }
 
 const sourceAddressCriticalOption = "source-address"
 
+// Security key options, see checkSKSignature.
+const (
+	noTouchRequiredExtension     = "no-touch-required"
+	verifyRequiredCriticalOption = "verify-required"
+)
+
 // CertChecker does the work of verifying a certificate. Its methods
 // can be plugged into ClientConfig.HostKeyCallback and
 // ServerConfig.PublicKeyCallback. For the CertChecker to work,
@@ -312,6 +323,11 @@ type CertChecker struct {
 	// is used.
 	Clock func() time.Time
 
+	// ClockSkew is how far the clock may differ from the one of the
+	// certificate authority. Certificates are accepted from ClockSkew
+	// before their ValidAfter until ClockSkew after their ValidBefore.
+	ClockSkew time.Duration
+
 	// UserKeyFallback is called when CertChecker.Authenticate encounters a
 	// public key that is not a certificate. It must implement validation
 	// of user keys or else, if nil, all such keys are rejected.
@@ -327,6 +343,14 @@ type CertChecker struct {
 	// is revoked and false otherwise. If nil, no certificates are
 	// considered to have been revoked.
 	IsRevoked func(cert *Certificate) bool
+
+	// MatchHostPrincipal reports whether the host name is allowed by the
+	// ValidPrincipals of a host certificate. Set it to
+	// MatchPrincipalPattern to accept OpenSSH style patterns such as
+	// "*.internal.example.com". If nil, MatchPrincipalExact is used. The
+	// user names of user certificates are always matched exactly, since
+	// a principal like "*" would let the certificate log in as anyone.
+	MatchHostPrincipal PrincipalMatcher
 }
 
 // CheckHostKey checks a host key certificate. This method can be
@@ -408,14 +432,11 @@ func (c *CertChecker) CheckCert(principal string, cert *Certificate) error {
 
 	if len(cert.ValidPrincipals) > 0 {
 		// By default, certs are valid for all users/hosts.
-		found := false
-		for _, p := range cert.ValidPrincipals {
-			if p == principal {
-				found = true
-				break
-			}
+		match := MatchPrincipalExact
+		if cert.CertType == HostCert && c.MatchHostPrincipal != nil {
+			match = c.MatchHostPrincipal
 		}
-		if !found {
+		if !match(principal, cert.ValidPrincipals) {
 			return fmt.Errorf("ssh: principal %q not in the set of valid principals for given certificate: %q", principal, cert.ValidPrincipals)
 		}
 	}
@@ -425,10 +446,12 @@ func (c *CertChecker) CheckCert(principal string, cert *Certificate) error {
 		clock = time.Now
 	}
 
-	unixNow := clock().Unix()
+	now := clock()
+	unixNow := now.Add(c.ClockSkew).Unix()
 	if after := int64(cert.ValidAfter); after < 0 || unixNow < int64(cert.ValidAfter) {
 		return fmt.Errorf("ssh: cert is not yet valid")
 	}
+	unixNow = now.Add(-c.ClockSkew).Unix()
 	if before := int64(cert.ValidBefore); cert.ValidBefore != uint64(CertTimeInfinity) && (unixNow >= before || before < 0) {
 		return fmt.Errorf("ssh: cert has expired")
 	}
@@ -442,37 +465,57 @@ func (c *CertChecker) CheckCert(principal string, cert *Certificate) error {
 // SignCert signs the certificate with an authority, setting the Nonce,
 // SignatureKey, and Signature fields. If the authority implements the
 // MultiAlgorithmSigner interface the first algorithm in the list is used. This
-// is useful if you want to sign with a specific algorithm.
+// is useful if you want to sign with a specific algorithm. Otherwise RSA
+// authorities sign with rsa-sha2-512; see SignCertWithAlgorithm to choose
+// another algorithm.
 func (c *Certificate) SignCert(rand io.Reader, authority Signer) error {
-	c.Nonce = make([]byte, 32)
-	if _, err := io.ReadFull(rand, c.Nonce); err != nil {
-		return err
-	}
-	c.SignatureKey = authority.PublicKey()
+	return c.SignCertContext(context.Background(), rand, authority)
+}
 
+// SignCertContext is like SignCert, but passes ctx to the authority if it
+// implements SignerWithContext, so that a remote signature can be abandoned.
+func (c *Certificate) SignCertContext(ctx context.Context, rand io.Reader, authority Signer) error {
+	var algorithm string
 	if v, ok := authority.(MultiAlgorithmSigner); ok {
 		if len(v.Algorithms()) == 0 {
 			return errors.New("the provided authority has no signature algorithm")
 		}
 		// Use the first algorithm in the list.
-		sig, err := v.SignWithAlgorithm(rand, c.bytesForSigning(), v.Algorithms()[0])
-		if err != nil {
-			return err
-		}
-		c.Signature = sig
-		return nil
+		algorithm = v.Algorithms()[0]
 	} else if v, ok := authority.(AlgorithmSigner); ok && v.PublicKey().Type() == KeyAlgoRSA {
 		// Default to KeyAlgoRSASHA512 for ssh-rsa signers.
 		// TODO: consider using KeyAlgoRSASHA256 as default.
-		sig, err := v.SignWithAlgorithm(rand, c.bytesForSigning(), KeyAlgoRSASHA512)
-		if err != nil {
-			return err
-		}
-		c.Signature = sig
-		return nil
+		algorithm = KeyAlgoRSASHA512
+	}
+
+	return c.signCert(ctx, rand, authority, algorithm)
+}
+
+// SignCertWithAlgorithm is like SignCert, but signs with algorithm, such as
+// KeyAlgoRSASHA512, instead of the authority's default. It returns an error
+// if the algorithm is not supported by the authority's key, or, for a
+// MultiAlgorithmSigner, not one of its algorithms.
+func (c *Certificate) SignCertWithAlgorithm(rand io.Reader, authority AlgorithmSigner, algorithm string) error {
+	keyType := authority.PublicKey().Type()
+	if !contains(algorithmsForKeyFormat(underlyingAlgo(keyType)), algorithm) {
+		return fmt.Errorf("ssh: algorithm %q is not supported for key type %q", algorithm, keyType)
 	}
+	if v, ok := authority.(MultiAlgorithmSigner); ok && !contains(v.Algorithms(), algorithm) {
+		return fmt.Errorf("ssh: algorithm %q is restricted for the provided authority", algorithm)
+	}
+	return c.signCert(context.Background(), rand, authority, algorithm)
+}
+
+// signCert sets the Nonce and SignatureKey fields and signs the certificate
+// with algorithm, or the authority's default if empty.
+func (c *Certificate) signCert(ctx context.Context, rand io.Reader, authority Signer, algorithm string) error {
+	c.Nonce = make([]byte, 32)
+	if _, err := io.ReadFull(rand, c.Nonce); err != nil {
+		return err
+	}
+	c.SignatureKey = authority.PublicKey()
 
-	sig, err := authority.Sign(rand, c.bytesForSigning())
+	sig, err := signWithContext(ctx, authority, rand, c.bytesForSigning(), algorithm)
 	if err != nil {
 		return err
 	}
@@ -406,3 +406,34 @@ func TestCertSignWithMultiAlgorithmSigner(t *testing.T) {
 		})
 	}
 }
+
+func TestCertSignWithAlgorithm(t *testing.T) {
+	cert := &Certificate{
+		Key:         testPublicKeys["ecdsa"],
+		ValidBefore: CertTimeInfinity,
+		CertType:    UserCert,
+	}
+	rsaSigner := testSigners["rsa"].(AlgorithmSigner)
+	for _, algo := range []string{KeyAlgoRSA, KeyAlgoRSASHA256, KeyAlgoRSASHA512} {
+		if err := cert.SignCertWithAlgorithm(rand.Reader, rsaSigner, algo); err != nil {
+			t.Fatalf("SignCertWithAlgorithm(%q): %v", algo, err)
+		}
+		if cert.Signature.Format != algo {
+			t.Errorf("got signature format %q, want %q", cert.Signature.Format, algo)
+		}
+		if err := cert.SignatureKey.Verify(cert.bytesForSigning(), cert.Signature); err != nil {
+			t.Errorf("signature with %q does not verify: %v", algo, err)
+		}
+	}
+
+	if err := cert.SignCertWithAlgorithm(rand.Reader, rsaSigner, KeyAlgoED25519); err == nil {
+		t.Error("signed an RSA certificate with ssh-ed25519")
+	}
+	restricted, err := NewSignerWithAlgorithms(rsaSigner, []string{KeyAlgoRSASHA256})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cert.SignCertWithAlgorithm(rand.Reader, restricted, KeyAlgoRSASHA512); err == nil {
+		t.Error("signed with an algorithm the authority is restricted from")
+	}
+}
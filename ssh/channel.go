@@ -5,12 +5,14 @@
 package ssh
 
 import (
+	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
 	"log"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -99,6 +101,73 @@ type ChannelWithDeadlines interface {
 	SetWriteDeadline(deadline time.Time) error
 }
 
+// ChannelWithCloseRead is a channel that can tell the peer to stop sending
+// data, using the eow@openssh.com channel request of OpenSSH. All channels
+// created by this package implement it.
+type ChannelWithCloseRead interface {
+	Channel
+
+	// CloseRead signals that no more in-band data will be read from the
+	// channel, the counterpart of CloseWrite, so that proxies can forward a
+	// half-close of a TCP connection in either direction. The peer is
+	// expected to stop writing and to send EOF, but data it already sent
+	// may still be read. After the peer sends the request, Write and the
+	// writers returned by Stderr and Extended return io.EOF.
+	CloseRead() error
+}
+
+// ChannelWithRequestContext is a channel whose requests can be abandoned
+// if the peer is slow to reply. All channels created by this package
+// implement it.
+type ChannelWithRequestContext interface {
+	Channel
+
+	// SendRequestContext is like SendRequest, but stops waiting for the
+	// reply once ctx is done, returning ErrReplyTimeout if its deadline
+	// passed and ctx.Err() otherwise. The reply of an abandoned request is
+	// discarded when it arrives, before the next request can receive its
+	// own.
+	SendRequestContext(ctx context.Context, name string, wantReply bool, payload []byte) (bool, error)
+}
+
+// wrappedChannel is embedded by Channels that wrap another one, to support
+// deadlines and CloseRead if the wrapped Channel does.
+type wrappedChannel struct {
+	Channel
+}
+
+func (c wrappedChannel) CloseRead() error {
+	if ch, ok := c.Channel.(ChannelWithCloseRead); ok {
+		return ch.CloseRead()
+	}
+	return errors.New("ssh: CloseRead not supported")
+}
+
+func (c wrappedChannel) SetDeadline(deadline time.Time) error {
+	if ch, ok := c.Channel.(ChannelWithDeadlines); ok {
+		return ch.SetDeadline(deadline)
+	}
+	return errors.New("ssh: deadline not supported")
+}
+
+func (c wrappedChannel) SetReadDeadline(deadline time.Time) error {
+	if ch, ok := c.Channel.(ChannelWithDeadlines); ok {
+		return ch.SetReadDeadline(deadline)
+	}
+	return errors.New("ssh: deadline not supported")
+}
+
+func (c wrappedChannel) SetWriteDeadline(deadline time.Time) error {
+	if ch, ok := c.Channel.(ChannelWithDeadlines); ok {
+		return ch.SetWriteDeadline(deadline)
+	}
+	return errors.New("ssh: deadline not supported")
+}
+
+// eowRequest is the channel request with which OpenSSH signals that a
+// channel will no longer accept data, see [PROTOCOL], section 2.1.
+const eowRequest = "eow@openssh.com"
+
 // Request is a request sent outside of the normal stream of
 // data. Requests can either be specific to an SSH channel, or they
 // can be global.
@@ -107,20 +176,34 @@ type Request struct {
 	WantReply bool
 	Payload   []byte
 
-	ch  *channel
-	mux *mux
+	ch    *channel
+	mux   *mux
+	reply *globalReply // of global requests received by mux
 }
 
 // Reply sends a response to a request. It must be called for all requests
 // where WantReply is true and is a no-op otherwise. The payload argument is
 // ignored for replies to channel-specific requests.
+//
+// Replies to global requests are sent in the order of the requests, as
+// the peer expects, so the reply to a request is held back until the
+// earlier requests are answered.
 func (r *Request) Reply(ok bool, payload []byte) error {
 	if !r.WantReply {
 		return nil
 	}
 
 	if r.ch == nil {
-		return r.mux.ackRequest(ok, payload)
+		if r.reply == nil {
+			return r.mux.ackRequest(ok, payload)
+		}
+		select {
+		case <-r.mux.done:
+			return io.EOF
+		default:
+		}
+		r.reply.reply(ok, payload)
+		return nil
 	}
 
 	return r.ch.ackRequest(ok)
@@ -195,19 +278,29 @@ type channel struct {
 	msg chan interface{}
 
 	// Since requests have no ID, there can be only one request
-	// with WantReply=true outstanding.  This lock is held by a
-	// goroutine that has such an outgoing request pending.
-	sentRequestMu sync.Mutex
+	// with WantReply=true outstanding.  This semaphore is held by a
+	// goroutine that has such an outgoing request pending, or that
+	// discards the reply of an abandoned one.
+	sentRequest chan struct{}
 
 	incomingRequests chan *Request
 
 	sentEOF bool
 
+	// peerClosedRead is set once the peer sent an eow@openssh.com
+	// request, after which writes fail.
+	peerClosedRead atomic.Bool
+
 	// thread-safe data
 	remoteWin  window
 	pending    *buffer
 	extPending *buffer
 
+	// windowSize is the initial and maximum value of myWindow. Since the
+	// window is only increased once data is consumed, it bounds the amount
+	// of buffered incoming data.
+	windowSize uint32
+
 	// windowMu protects myWindow, the flow-control window, and myConsumed,
 	// the number of bytes consumed since we last increased myWindow
 	windowMu   sync.Mutex
@@ -224,6 +317,10 @@ type channel struct {
 	// packetPool has a buffer for each extended channel ID to
 	// save allocations during writes.
 	packetPool map[uint32][]byte
+
+	// countedOpen is set while the channel is counted as open in the
+	// connection's Metrics.
+	countedOpen atomic.Bool
 }
 
 func (ch *channel) SetDeadline(deadline time.Time) error {
@@ -271,7 +368,7 @@ func (ch *channel) sendMessage(msg interface{}) error {
 // WriteExtended writes data to a specific extended stream. These streams are
 // used, for example, for stderr.
 func (ch *channel) WriteExtended(data []byte, extendedCode uint32) (n int, err error) {
-	if ch.sentEOF {
+	if ch.sentEOF || ch.peerClosedRead.Load() {
 		return 0, io.EOF
 	}
 	// 1 byte message type, 4 bytes remoteId, 4 bytes data length
@@ -315,6 +412,8 @@ func (ch *channel) WriteExtended(data []byte, extendedCode uint32) (n int, err e
 
 		n += len(todo)
 		data = data[len(todo):]
+		ch.mux.metrics.ChannelBytesSent(ch.chanType, len(todo))
+		ch.mux.markActive()
 	}
 
 	ch.writeMu.Lock()
@@ -324,6 +423,48 @@ func (ch *channel) WriteExtended(data []byte, extendedCode uint32) (n int, err e
 	return n, err
 }
 
+// writeDatagram sends data in a single SSH_MSG_CHANNEL_DATA message, for
+// channel types that carry one datagram per message, such as
+// tun@openssh.com.
+func (ch *channel) writeDatagram(data []byte) error {
+	if ch.sentEOF || ch.peerClosedRead.Load() {
+		return io.EOF
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	if uint32(len(data)) > ch.maxRemotePayload {
+		return errors.New("ssh: datagram exceeds maximum packet size")
+	}
+	if err := ch.remoteWin.reserveAll(uint32(len(data))); err != nil {
+		return err
+	}
+	packet := make([]byte, 9+len(data))
+	packet[0] = msgChannelData
+	binary.BigEndian.PutUint32(packet[1:], ch.remoteId)
+	binary.BigEndian.PutUint32(packet[5:], uint32(len(data)))
+	copy(packet[9:], data)
+	if err := ch.writePacket(packet); err != nil {
+		return err
+	}
+	ch.mux.metrics.ChannelBytesSent(ch.chanType, len(data))
+	ch.mux.markActive()
+	return nil
+}
+
+// readDatagram returns the data of the next SSH_MSG_CHANNEL_DATA message.
+// It must not be mixed with Read.
+func (ch *channel) readDatagram() ([]byte, error) {
+	data, err := ch.pending.readMessage()
+	if err != nil {
+		return nil, err
+	}
+	if err := ch.adjustWindow(uint32(len(data))); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return data, nil
+}
+
 func (ch *channel) handleData(packet []byte) error {
 	headerLen := 9
 	isExtendedData := packet[0] == msgChannelExtendedData
@@ -363,6 +504,9 @@ func (ch *channel) handleData(packet []byte) error {
 	ch.myWindow -= length
 	ch.windowMu.Unlock()
 
+	ch.mux.metrics.ChannelBytesReceived(ch.chanType, len(data))
+	ch.mux.markActive()
+
 	if extended == 1 {
 		ch.extPending.write(data)
 	} else if extended > 0 {
@@ -379,8 +523,8 @@ func (c *channel) adjustWindow(adj uint32) error {
 	// exceed the initial window setting, we don't worry about overflow.
 	c.myConsumed += adj
 	var sendAdj uint32
-	if (channelWindowSize-c.myWindow > 3*c.maxIncomingPayload) ||
-		(c.myWindow < channelWindowSize/2) {
+	if (c.windowSize-c.myWindow > 3*c.maxIncomingPayload) ||
+		(c.myWindow < c.windowSize/2) {
 		sendAdj = c.myConsumed
 		c.myConsumed = 0
 		c.myWindow += sendAdj
@@ -454,6 +598,9 @@ func (ch *channel) handlePacket(packet []byte) error {
 		ch.sendMessage(channelCloseMsg{PeersID: ch.remoteId})
 		ch.mux.chanList.remove(ch.localId)
 		ch.close()
+		ch.countClosed()
+		ch.mux.markActive()
+		ch.mux.logger.Debug("ssh: channel closed", "type", ch.chanType, "id", ch.localId)
 		return nil
 	case msgChannelEOF:
 		// RFC 4254 is mute on how EOF affects dataExt messages but
@@ -491,6 +638,19 @@ func (ch *channel) handlePacket(packet []byte) error {
 			return fmt.Errorf("ssh: invalid window update for %d bytes", msg.AdditionalBytes)
 		}
 	case *channelRequestMsg:
+		if msg.Request != keepaliveRequest {
+			ch.mux.markActive()
+		}
+		if msg.Request == eowRequest {
+			ch.peerClosedRead.Store(true)
+			// Unblock writers waiting for window space the peer will
+			// no longer grant.
+			ch.remoteWin.close()
+			if msg.WantReply {
+				return ch.ackRequest(true)
+			}
+			return nil
+		}
 		req := Request{
 			Type:      msg.Request,
 			WantReply: msg.WantReply,
@@ -508,18 +668,21 @@ func (ch *channel) handlePacket(packet []byte) error {
 func (m *mux) newChannel(chanType string, direction channelDirection, extraData []byte) *channel {
 	ch := &channel{
 		remoteWin:        window{Cond: newCond()},
-		myWindow:         channelWindowSize,
+		windowSize:       m.windowSize,
+		myWindow:         m.windowSize,
 		pending:          newBuffer(),
 		extPending:       newBuffer(),
 		direction:        direction,
 		incomingRequests: make(chan *Request, chanSize),
 		msg:              make(chan interface{}, chanSize),
+		sentRequest:      make(chan struct{}, 1),
 		chanType:         chanType,
 		extraData:        extraData,
 		mux:              m,
 		packetPool:       make(map[uint32][]byte),
 	}
 	ch.localId = m.chanList.add(ch)
+	m.markActive()
 	return ch
 }
 
@@ -566,6 +729,8 @@ func (ch *channel) Accept() (Channel, <-chan *Request, error) {
 	if err := ch.sendMessage(confirm); err != nil {
 		return nil, nil, err
 	}
+	ch.countOpened()
+	ch.mux.logger.Debug("ssh: channel opened", "type", ch.chanType, "id", ch.localId, "inbound", true)
 
 	return ch, ch.incomingRequests, nil
 }
@@ -581,6 +746,7 @@ func (ch *channel) Reject(reason RejectionReason, message string) error {
 		Language: "en",
 	}
 	ch.decided = true
+	ch.mux.logger.Debug("ssh: channel open rejected", "type", ch.chanType, "reason", reason, "message", message)
 	return ch.sendMessage(reject)
 }
 
@@ -607,6 +773,11 @@ func (ch *channel) CloseWrite() error {
 		PeersID: ch.remoteId})
 }
 
+func (ch *channel) CloseRead() error {
+	_, err := ch.SendRequest(eowRequest, false, nil)
+	return err
+}
+
 func (ch *channel) Close() error {
 	if !ch.decided {
 		return errUndecided
@@ -630,15 +801,28 @@ func (ch *channel) Stderr() io.ReadWriter {
 }
 
 func (ch *channel) SendRequest(name string, wantReply bool, payload []byte) (bool, error) {
+	return ch.SendRequestContext(context.Background(), name, wantReply, payload)
+}
+
+func (ch *channel) SendRequestContext(ctx context.Context, name string, wantReply bool, payload []byte) (bool, error) {
 	if !ch.decided {
 		return false, errUndecided
 	}
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
 
 	if wantReply {
-		ch.sentRequestMu.Lock()
-		defer ch.sentRequestMu.Unlock()
+		select {
+		case ch.sentRequest <- struct{}{}:
+		case <-ctx.Done():
+			return false, replyWaitError(ctx)
+		}
 	}
 
+	if name != keepaliveRequest {
+		ch.mux.markActive()
+	}
 	msg := channelRequestMsg{
 		PeersID:             ch.remoteId,
 		Request:             name,
@@ -647,11 +831,25 @@ func (ch *channel) SendRequest(name string, wantReply bool, payload []byte) (boo
 	}
 
 	if err := ch.sendMessage(msg); err != nil {
+		if wantReply {
+			<-ch.sentRequest
+		}
 		return false, err
 	}
 
 	if wantReply {
-		m, ok := (<-ch.msg)
+		var m interface{}
+		var ok bool
+		select {
+		case m, ok = <-ch.msg:
+			<-ch.sentRequest
+		case <-ctx.Done():
+			go func() {
+				<-ch.msg
+				<-ch.sentRequest
+			}()
+			return false, replyWaitError(ctx)
+		}
 		if !ok {
 			return false, io.EOF
 		}
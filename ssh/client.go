@@ -6,11 +6,13 @@ package ssh
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"net"
 	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -24,6 +26,131 @@ type Client struct {
 	forwards        forwardList // forwarded tcpip connections from the remote side
 	mu              sync.Mutex
 	channelHandlers map[string]chan NewChannel
+
+	noMoreSessions atomic.Bool // set by NoMoreSessions
+
+	x11Mu sync.Mutex
+	x11   *x11Forwarder // handles x11 channels once RequestX11 is used
+}
+
+// ForceRekey starts a new key exchange, unless one is already in progress.
+// It does not wait for the key exchange to complete.
+func (c *Client) ForceRekey() error {
+	if r, ok := c.Conn.(rekeyer); ok {
+		return r.ForceRekey()
+	}
+	return errNoRekey
+}
+
+// SendIgnore sends a SSH_MSG_IGNORE message with data to the server, which
+// discards it. It can be used to pad the traffic or to keep intermediate
+// devices from timing out the connection without a round trip.
+func (c *Client) SendIgnore(data []byte) error {
+	if d, ok := c.Conn.(diagnosticSender); ok {
+		return d.SendIgnore(data)
+	}
+	return errNoDiagnostics
+}
+
+// SendDebug sends a SSH_MSG_DEBUG message to the server, which may log or
+// display it. See RFC 4253, section 11.3.
+func (c *Client) SendDebug(alwaysDisplay bool, message, language string) error {
+	if d, ok := c.Conn.(diagnosticSender); ok {
+		return d.SendDebug(alwaysDisplay, message, language)
+	}
+	return errNoDiagnostics
+}
+
+// Ping sends a ping@openssh.com message to the server and waits for its
+// reply, returning the round-trip time. It returns an error if the server
+// did not advertise support for the extension, see [PROTOCOL], section 1.9.
+func (c *Client) Ping(ctx context.Context) (time.Duration, error) {
+	conn, ok := c.Conn.(*connection)
+	if !ok {
+		return 0, errors.New("ssh: connection does not support ping@openssh.com")
+	}
+	if _, ok := conn.PeerExtInfo()[pingExtension]; !ok {
+		return 0, errors.New("ssh: server does not support ping@openssh.com")
+	}
+	return conn.ping(ctx)
+}
+
+// RTT returns the round-trip times measured on the connection so far, by
+// Ping, MeasureRTT and keepalives. It returns the zero value if the
+// underlying Conn does not measure them.
+func (c *Client) RTT() RTTStats {
+	if r, ok := c.Conn.(rttConn); ok {
+		return r.RTT()
+	}
+	return RTTStats{}
+}
+
+// MeasureRTT measures one round trip to the server and adds it to the
+// statistics returned by RTT. Unlike Ping, it falls back to a keepalive
+// request if the server does not support ping@openssh.com.
+func (c *Client) MeasureRTT(ctx context.Context) (time.Duration, error) {
+	if r, ok := c.Conn.(rttConn); ok {
+		return r.MeasureRTT(ctx)
+	}
+	return 0, errNoRTT
+}
+
+// NoMoreSessions sends a no-more-sessions@openssh.com request, telling the
+// server to refuse any further session channels on this connection, as
+// OpenSSH clients do once they have opened the last session they need. This
+// keeps an attacker who gains control of the client, for example through a
+// forwarded agent or a multiplexed connection, from surreptitiously opening
+// sessions of their own; see [PROTOCOL], section 2.2. Afterwards NewSession
+// fails without contacting the server.
+func (c *Client) NoMoreSessions() error {
+	c.noMoreSessions.Store(true)
+	_, _, err := c.SendRequest(noMoreSessionsRequest, false, nil)
+	return err
+}
+
+// SendRequestContext is like SendRequest, but stops waiting if ctx is done
+// before the reply arrives, returning ErrReplyTimeout if its deadline passed
+// and ctx.Err() otherwise. If the underlying Conn does not support
+// abandoning requests, ctx is only checked before sending.
+func (c *Client) SendRequestContext(ctx context.Context, name string, wantReply bool, payload []byte) (bool, []byte, error) {
+	return sendRequestContext(ctx, c.Conn, name, wantReply, payload)
+}
+
+// OpenChannelContext is like OpenChannel, but returns ctx.Err() if ctx is
+// done before the peer accepts or rejects the channel, in which case a
+// channel accepted later is closed. If the underlying Conn does not support
+// abandoning channel opens, ctx is only checked before sending.
+func (c *Client) OpenChannelContext(ctx context.Context, name string, data []byte) (Channel, <-chan *Request, error) {
+	return openChannelContext(ctx, c.Conn, name, data)
+}
+
+// OpenChannelAsync sends the request to open a channel like OpenChannel,
+// but returns without waiting for the peer to accept or reject it, so that
+// many channels can be opened concurrently without waiting for each
+// confirmation in turn. ctx bounds the wait, as with OpenChannelContext.
+func (c *Client) OpenChannelAsync(ctx context.Context, name string, data []byte) *PendingChannel {
+	return openChannelAsync(ctx, c.Conn, name, data)
+}
+
+// Algorithms returns the algorithms negotiated in the most recent key
+// exchange. It returns the zero value if the underlying Conn does not
+// implement AlgorithmsConnMetadata.
+func (c *Client) Algorithms() NegotiatedAlgorithms {
+	if a, ok := c.Conn.(AlgorithmsConnMetadata); ok {
+		return a.Algorithms()
+	}
+	return NegotiatedAlgorithms{}
+}
+
+// Banner returns the banner messages that the server sent before the user
+// was authenticated, concatenated, or "" if there were none. They are also
+// passed to ClientConfig.BannerCallback as they arrive. The version string
+// of the server is returned by ServerVersion.
+func (c *Client) Banner() string {
+	if conn, ok := c.Conn.(*connection); ok {
+		return conn.banner
+	}
+	return ""
 }
 
 // HandleChannelOpen returns a channel on which NewChannel requests
@@ -69,28 +196,100 @@ func NewClient(c Conn, chans <-chan NewChannel, reqs <-chan *Request) *Client {
 // as the underlying transport.  The Request and NewChannel channels
 // must be serviced or the connection will hang.
 func NewClientConn(c net.Conn, addr string, config *ClientConfig) (Conn, <-chan NewChannel, <-chan *Request, error) {
+	return newClientConn(context.Background(), c, addr, config)
+}
+
+// newClientConn implements NewClientConn, passing ctx to the signers used
+// for user authentication.
+func newClientConn(ctx context.Context, c net.Conn, addr string, config *ClientConfig) (Conn, <-chan NewChannel, <-chan *Request, error) {
 	fullConf := *config
 	fullConf.SetDefaults()
+	if fullConf.GSSAPIKeyExchange != nil {
+		fullConf.KeyExchanges = withGSSKexAlgos(fullConf.KeyExchanges)
+	}
 	if fullConf.HostKeyCallback == nil {
 		c.Close()
 		return nil, nil, nil, errors.New("ssh: must specify HostKeyCallback")
 	}
 
 	conn := &connection{
-		sshConn: sshConn{conn: c, user: fullConf.User},
+		sshConn:          sshConn{conn: c, user: fullConf.User},
+		hostKeysCallback: fullConf.HostKeysCallback,
+		hostKeysHandler:  fullConf.HostKeysHandler,
 	}
 
-	if err := conn.clientHandshake(addr, &fullConf); err != nil {
+	if err := conn.clientHandshake(ctx, addr, &fullConf); err != nil {
 		c.Close()
 		return nil, nil, nil, fmt.Errorf("ssh: handshake failed: %w", err)
 	}
-	conn.mux = newMux(conn.transport)
+	conn.mux = newMuxConfig(conn.transport, &fullConf.Config)
+	if fullConf.KeepaliveInterval > 0 {
+		go conn.mux.keepalive(fullConf.KeepaliveInterval, fullConf.KeepaliveMaxMissed)
+	}
+	if fullConf.IdleTimeout > 0 {
+		go conn.mux.idleTimeout(fullConf.IdleTimeout)
+	}
 	return conn, conn.mux.incomingChannels, conn.mux.incomingRequests, nil
 }
 
+// NewClientConnContext is like NewClientConn but aborts the key exchange and
+// user authentication if ctx is done before they complete. In that case the
+// underlying connection is closed and the context's error is returned. Signers
+// that implement SignerWithContext are passed ctx. Once the connection is
+// established, expiration of the context has no effect.
+func NewClientConnContext(ctx context.Context, c net.Conn, addr string, config *ClientConfig) (Conn, <-chan NewChannel, <-chan *Request, error) {
+	if err := ctx.Err(); err != nil {
+		c.Close()
+		return nil, nil, nil, err
+	}
+
+	done := make(chan struct{})
+	interrupted := make(chan bool, 1)
+	go func() {
+		select {
+		case <-ctx.Done():
+			// Closing the connection unblocks any pending read or write
+			// in the handshake.
+			c.Close()
+			interrupted <- true
+		case <-done:
+			interrupted <- false
+		}
+	}()
+
+	conn, chans, reqs, err := newClientConn(ctx, c, addr, config)
+	close(done)
+	if <-interrupted {
+		if conn != nil {
+			conn.Close()
+		}
+		return nil, nil, nil, ctx.Err()
+	}
+	return conn, chans, reqs, err
+}
+
 // clientHandshake performs the client side key exchange. See RFC 4253 Section
 // 7.
-func (c *connection) clientHandshake(dialAddress string, config *ClientConfig) error {
+func (c *connection) clientHandshake(ctx context.Context, dialAddress string, config *ClientConfig) error {
+	if err := c.clientKeyExchange(dialAddress, config); err != nil {
+		return clientHandshakeError(err, "")
+	}
+	if config.SessionBindCallback != nil && c.sessionHostKey != nil {
+		if err := config.SessionBindCallback(c.sessionHostKey, c.SessionID(), dup(c.sessionSignature)); err != nil {
+			return clientHandshakeError(err, "")
+		}
+	}
+	err := c.clientAuthenticate(ctx, config)
+	c.banner = c.transport.banner
+	if err != nil {
+		return clientHandshakeError(err, c.banner)
+	}
+	return nil
+}
+
+// clientKeyExchange performs the version exchange and the first key
+// exchange, after which the client can authenticate.
+func (c *connection) clientKeyExchange(dialAddress string, config *ClientConfig) error {
 	if config.ClientVersion != "" {
 		c.clientVersion = []byte(config.ClientVersion)
 	} else {
@@ -99,18 +298,28 @@ func (c *connection) clientHandshake(dialAddress string, config *ClientConfig) e
 	var err error
 	c.serverVersion, err = exchangeVersions(c.sshConn.conn, c.clientVersion)
 	if err != nil {
+		config.Logger.Info("ssh: version exchange failed", "error", err)
 		return err
 	}
+	config.Logger.Info("ssh: version exchanged", "local", string(c.clientVersion), "remote", string(c.serverVersion))
 
-	c.transport = newClientTransport(
-		newTransport(c.sshConn.conn, config.Rand, true /* is client */),
-		c.clientVersion, c.serverVersion, config, dialAddress, c.sshConn.RemoteAddr())
+	tr := newTransport(c.sshConn.conn, config.Rand, true /* is client */)
+	tr.debugCallback = config.DebugCallback
+	c.transport = newClientTransport(tr, c.clientVersion, c.serverVersion, config, dialAddress, c.sshConn.RemoteAddr())
 	if err := c.transport.waitSession(); err != nil {
 		return err
 	}
 
 	c.sessionID = c.transport.getSessionID()
-	return c.clientAuthenticate(config)
+	c.peerExtInfo = c.transport.peerExtInfo
+	if c.transport.sessionHostKey != nil {
+		key, err := ParsePublicKey(c.transport.sessionHostKey)
+		if err != nil {
+			return err
+		}
+		c.sessionHostKey, c.sessionSignature = key, c.transport.sessionSignature
+	}
+	return nil
 }
 
 // verifyHostKeySignature verifies the host key obtained in the key exchange.
@@ -131,15 +340,26 @@ func verifyHostKeySignature(hostKey PublicKey, algo string, result *kexResult) e
 // NewSession opens a new Session for this client. (A session is a remote
 // execution of a program.)
 func (c *Client) NewSession() (*Session, error) {
+	if c.noMoreSessions.Load() {
+		return nil, errors.New("ssh: no more sessions")
+	}
 	ch, in, err := c.OpenChannel("session", nil)
 	if err != nil {
 		return nil, err
 	}
-	return newSession(ch, in)
+	s, err := newSession(ch, in)
+	if err != nil {
+		return nil, err
+	}
+	s.client = c
+	return s, nil
 }
 
 func (c *Client) handleGlobalRequests(incoming <-chan *Request) {
 	for r := range incoming {
+		if r.Type == hostKeysRequest {
+			c.handleHostKeys(r)
+		}
 		// This handles keepalive messages and matches
 		// the behaviour of OpenSSH.
 		r.Reply(false, nil)
@@ -174,7 +394,7 @@ func (c *Client) handleChannelOpens(in <-chan NewChannel) {
 // to incoming channels and requests, use net.Dial with NewClientConn
 // instead.
 func Dial(network, addr string, config *ClientConfig) (*Client, error) {
-	conn, err := net.DialTimeout(network, addr, config.Timeout)
+	conn, err := dialContext(context.Background(), network, addr, config)
 	if err != nil {
 		return nil, err
 	}
@@ -185,6 +405,23 @@ func Dial(network, addr string, config *ClientConfig) (*Client, error) {
 	return NewClient(c, chans, reqs), nil
 }
 
+// DialContext starts a client connection to the given SSH server. It is
+// like Dial, but the provided Context bounds the TCP connection, the key
+// exchange and the user authentication. If the context expires before the
+// connection is established, an error is returned. Once successfully
+// connected, any expiration of the context will not affect the connection.
+func DialContext(ctx context.Context, network, addr string, config *ClientConfig) (*Client, error) {
+	conn, err := dialContext(ctx, network, addr, config)
+	if err != nil {
+		return nil, err
+	}
+	c, chans, reqs, err := NewClientConnContext(ctx, conn, addr, config)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(c, chans, reqs), nil
+}
+
 // HostKeyCallback is the function type used for verifying server
 // keys.  A HostKeyCallback must return nil if the host key is OK, or
 // an error to reject it. It receives the hostname as passed to Dial
@@ -224,6 +461,29 @@ type ClientConfig struct {
 	// simplistic display on Stderr.
 	BannerCallback BannerCallback
 
+	// HostKeysCallback, if not nil, is called by Client with the host keys
+	// the server announces after authentication. See HostKeysCallback.
+	HostKeysCallback HostKeysCallback
+
+	// HostKeysHandler, if not nil, is called by Client with the host keys
+	// the server announces after authentication, like HostKeysCallback.
+	// See HostKeysHandler.
+	HostKeysHandler HostKeysHandler
+
+	// SessionBindCallback, if not nil, is called after the first key
+	// exchange, before authentication. See SessionBindCallback.
+	SessionBindCallback SessionBindCallback
+
+	// GSSAPIKeyExchange, if not nil, enables the GSSAPI key exchange
+	// methods gss-nistp256-sha256-* and gss-group14-sha256-* for the
+	// Kerberos V5 mechanism (RFC 4462, section 2 and RFC 8732), which are
+	// preferred over the methods of Config.KeyExchanges unless some of
+	// them are listed there, and the null host key algorithm of RFC 4462,
+	// section 5, which is accepted last. If one of them is negotiated, the
+	// server is authenticated by the GSSAPI security context, and
+	// HostKeyCallback is not called.
+	GSSAPIKeyExchange *GSSAPIKexConfig
+
 	// ClientVersion contains the version identification string that will
 	// be used for the connection. If empty, a reasonable default is used.
 	ClientVersion string
@@ -239,6 +499,13 @@ type ClientConfig struct {
 	//
 	// A Timeout of zero means no timeout.
 	Timeout time.Duration
+
+	// ProxyDialer, if not nil, is used by Dial and DialContext to connect
+	// to the server, for example a SOCKS5 dialer of golang.org/x/net/proxy
+	// or an HTTPConnectProxy. Timeout then bounds the connection through
+	// the proxy if the dialer has a DialContext method, like
+	// golang.org/x/net/proxy.ContextDialer, and is ignored otherwise.
+	ProxyDialer ProxyDialer
 }
 
 // InsecureIgnoreHostKey returns a function that can be used for
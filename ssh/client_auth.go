@@ -6,10 +6,12 @@ package ssh
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"strings"
+	"sync"
 )
 
 type authResult int
@@ -21,44 +23,8 @@ const (
 )
 
 // clientAuthenticate authenticates with the remote server. See RFC 4252.
-func (c *connection) clientAuthenticate(config *ClientConfig) error {
-	// initiate user auth session
-	if err := c.transport.writePacket(Marshal(&serviceRequestMsg{serviceUserAuth})); err != nil {
-		return err
-	}
-	packet, err := c.transport.readPacket()
-	if err != nil {
-		return err
-	}
-	// The server may choose to send a SSH_MSG_EXT_INFO at this point (if we
-	// advertised willingness to receive one, which we always do) or not. See
-	// RFC 8308, Section 2.4.
-	extensions := make(map[string][]byte)
-	if len(packet) > 0 && packet[0] == msgExtInfo {
-		var extInfo extInfoMsg
-		if err := Unmarshal(packet, &extInfo); err != nil {
-			return err
-		}
-		payload := extInfo.Payload
-		for i := uint32(0); i < extInfo.NumExtensions; i++ {
-			name, rest, ok := parseString(payload)
-			if !ok {
-				return parseError(msgExtInfo)
-			}
-			value, rest, ok := parseString(rest)
-			if !ok {
-				return parseError(msgExtInfo)
-			}
-			extensions[string(name)] = value
-			payload = rest
-		}
-		packet, err = c.transport.readPacket()
-		if err != nil {
-			return err
-		}
-	}
-	var serviceAccept serviceAcceptMsg
-	if err := Unmarshal(packet, &serviceAccept); err != nil {
+func (c *connection) clientAuthenticate(ctx context.Context, config *ClientConfig) error {
+	if err := c.requestUserAuth(); err != nil {
 		return err
 	}
 
@@ -69,7 +35,8 @@ func (c *connection) clientAuthenticate(config *ClientConfig) error {
 
 	sessionID := c.transport.getSessionID()
 	for auth := AuthMethod(new(noneAuth)); auth != nil; {
-		ok, methods, err := auth.auth(sessionID, config.User, c.transport, config.Rand, extensions)
+		ok, methods, err := auth.auth(ctx, sessionID, config.User, c.transport, config.Rand, c.transport.peerExtInfo)
+		config.Logger.Info("ssh: authentication attempt", "user", config.User, "method", auth.method(), "result", ok.String(), "error", err)
 		if err != nil {
 			// On disconnect, return error immediately
 			if _, ok := err.(*disconnectMsg); ok {
@@ -111,10 +78,36 @@ func (c *connection) clientAuthenticate(config *ClientConfig) error {
 		if auth == nil && err != nil {
 			// We have an error and there are no other authentication methods to
 			// try, so we return it.
+			return &ClientAuthError{Tried: tried, Remaining: methods, Err: err}
+		}
+	}
+	return &ClientAuthError{Tried: tried, Remaining: lastMethods}
+}
+
+// requestUserAuth requests the ssh-userauth service, which starts the
+// authentication phase.
+func (c *connection) requestUserAuth() error {
+	if err := c.transport.writePacket(Marshal(&serviceRequestMsg{serviceUserAuth})); err != nil {
+		return err
+	}
+	packet, err := c.transport.readPacket()
+	if err != nil {
+		return err
+	}
+	// The server may choose to send a SSH_MSG_EXT_INFO at this point (if we
+	// advertised willingness to receive one, which we always do) or not. See
+	// RFC 8308, Section 2.4.
+	if len(packet) > 0 && packet[0] == msgExtInfo {
+		if err := c.transport.recordExtInfo(packet); err != nil {
+			return err
+		}
+		packet, err = c.transport.readPacket()
+		if err != nil {
 			return err
 		}
 	}
-	return fmt.Errorf("ssh: unable to authenticate, attempted methods %v, no supported methods remain", tried)
+	var serviceAccept serviceAcceptMsg
+	return Unmarshal(packet, &serviceAccept)
 }
 
 func contains(list []string, e string) bool {
@@ -133,7 +126,7 @@ type AuthMethod interface {
 	// If authentication is not successful, a []string of alternative
 	// method names is returned. If the slice is nil, it will be ignored
 	// and the previous set of possible methods will be reused.
-	auth(session []byte, user string, p packetConn, rand io.Reader, extensions map[string][]byte) (authResult, []string, error)
+	auth(ctx context.Context, session []byte, user string, p packetConn, rand io.Reader, extensions map[string][]byte) (authResult, []string, error)
 
 	// method returns the RFC 4252 method name.
 	method() string
@@ -142,7 +135,7 @@ type AuthMethod interface {
 // "none" authentication, RFC 4252 section 5.2.
 type noneAuth int
 
-func (n *noneAuth) auth(session []byte, user string, c packetConn, rand io.Reader, _ map[string][]byte) (authResult, []string, error) {
+func (n *noneAuth) auth(ctx context.Context, session []byte, user string, c packetConn, rand io.Reader, _ map[string][]byte) (authResult, []string, error) {
 	if err := c.writePacket(Marshal(&userAuthRequestMsg{
 		User:    user,
 		Service: serviceSSH,
@@ -162,16 +155,37 @@ func (n *noneAuth) method() string {
 // a function call, e.g. by prompting the user.
 type passwordCallback func() (password string, err error)
 
-func (cb passwordCallback) auth(session []byte, user string, c packetConn, rand io.Reader, _ map[string][]byte) (authResult, []string, error) {
-	type passwordAuthMsg struct {
-		User     string `sshtype:"50"`
-		Service  string
-		Method   string
-		Reply    bool
-		Password string
-	}
+type passwordAuthMsg struct {
+	User     string `sshtype:"50"`
+	Service  string
+	Method   string
+	Reply    bool
+	Password string
+}
+
+// See RFC 4252, section 8.
+type passwordChangeAuthMsg struct {
+	User        string `sshtype:"50"`
+	Service     string
+	Method      string
+	Change      bool
+	OldPassword string
+	NewPassword string
+}
+
+func (cb passwordCallback) auth(ctx context.Context, session []byte, user string, c packetConn, rand io.Reader, _ map[string][]byte) (authResult, []string, error) {
+	return passwordAuth(user, c, cb, nil)
+}
 
-	pw, err := cb()
+func (cb passwordCallback) method() string {
+	return "password"
+}
+
+// passwordAuth authenticates user with the password returned by prompt. If
+// the server asks for the password to be changed, the new password is
+// obtained from change, or the attempt fails if change is nil.
+func passwordAuth(user string, c packetConn, prompt passwordCallback, change PasswordChangeCallback) (authResult, []string, error) {
+	pw, err := prompt()
 	// REVIEW NOTE: is there a need to support skipping a password attempt?
 	// The program may only find out that the user doesn't have a password
 	// when prompting.
@@ -182,18 +196,61 @@ func (cb passwordCallback) auth(session []byte, user string, c packetConn, rand
 	if err := c.writePacket(Marshal(&passwordAuthMsg{
 		User:     user,
 		Service:  serviceSSH,
-		Method:   cb.method(),
+		Method:   prompt.method(),
 		Reply:    false,
 		Password: pw,
 	})); err != nil {
 		return authFailure, nil, err
 	}
 
-	return handleAuthResponse(c)
+	for {
+		ok, methods, packet, err := readAuthResponse(c, msgUserAuthPasswdChangeReq)
+		if packet == nil {
+			return ok, methods, err
+		}
+		var req userAuthPasswdChangeReqMsg
+		if err := Unmarshal(packet, &req); err != nil {
+			return authFailure, nil, err
+		}
+		if change == nil {
+			return authFailure, nil, errors.New("ssh: server requested a password change")
+		}
+		newPassword, err := change(req.Prompt, req.Language)
+		if err != nil {
+			return authFailure, nil, err
+		}
+		if err := c.writePacket(Marshal(&passwordChangeAuthMsg{
+			User:        user,
+			Service:     serviceSSH,
+			Method:      prompt.method(),
+			Change:      true,
+			OldPassword: pw,
+			NewPassword: newPassword,
+		})); err != nil {
+			return authFailure, nil, err
+		}
+	}
 }
 
-func (cb passwordCallback) method() string {
-	return "password"
+// PasswordChangeCallback is the function type used to answer a request of
+// the server to change an expired or otherwise unacceptable password. It
+// receives the prompt of the server, in the language tagged language, and
+// returns the new password.
+type PasswordChangeCallback func(prompt, language string) (newPassword string, err error)
+
+// passwordChangeAuth is a password AuthMethod that supports changing the
+// password when the server asks for it.
+type passwordChangeAuth struct {
+	prompt passwordCallback
+	change PasswordChangeCallback
+}
+
+func (p *passwordChangeAuth) auth(ctx context.Context, session []byte, user string, c packetConn, rand io.Reader, _ map[string][]byte) (authResult, []string, error) {
+	return passwordAuth(user, c, p.prompt, p.change)
+}
+
+func (p *passwordChangeAuth) method() string {
+	return p.prompt.method()
 }
 
 // Password returns an AuthMethod using the given password.
@@ -207,6 +264,14 @@ func PasswordCallback(prompt func() (secret string, err error)) AuthMethod {
 	return passwordCallback(prompt)
 }
 
+// PasswordCallbackWithChange returns an AuthMethod like PasswordCallback,
+// which calls change for a new password if the server asks for the password
+// to be changed, for example because it has expired. change is called again
+// if the server rejects the new password.
+func PasswordCallbackWithChange(prompt func() (secret string, err error), change PasswordChangeCallback) AuthMethod {
+	return &passwordChangeAuth{prompt: prompt, change: change}
+}
+
 type publickeyAuthMsg struct {
 	User    string `sshtype:"50"`
 	Service string
@@ -299,7 +364,7 @@ func pickSignatureAlgorithm(signer Signer, extensions map[string][]byte) (MultiA
 	return as, algo, nil
 }
 
-func (cb publicKeyCallback) auth(session []byte, user string, c packetConn, rand io.Reader, extensions map[string][]byte) (authResult, []string, error) {
+func (cb publicKeyCallback) auth(ctx context.Context, session []byte, user string, c packetConn, rand io.Reader, extensions map[string][]byte) (authResult, []string, error) {
 	// Authentication is performed by sending an enquiry to test if a key is
 	// acceptable to the remote. If the key is acceptable, the client will
 	// attempt to authenticate with the valid key.  If not the client will repeat
@@ -353,7 +418,7 @@ func (cb publicKeyCallback) auth(session []byte, user string, c packetConn, rand
 			Service: serviceSSH,
 			Method:  cb.method(),
 		}, algo, pubKey)
-		sign, err := as.SignWithAlgorithm(rand, data, underlyingAlgo(algo))
+		sign, err := signWithContext(ctx, as, rand, data, underlyingAlgo(algo))
 		if err != nil {
 			return authFailure, nil, err
 		}
@@ -461,41 +526,125 @@ func PublicKeysCallback(getSigners func() (signers []Signer, err error)) AuthMet
 	return publicKeyCallback(getSigners)
 }
 
+// hostBasedAuthMsg is the "hostbased" user authentication request of RFC
+// 4252, section 9.
+type hostBasedAuthMsg struct {
+	User       string `sshtype:"50"`
+	Service    string
+	Method     string
+	Algoname   string
+	PubKey     []byte
+	ClientHost string
+	ClientUser string
+	// Sig is tagged with "rest" so that the signed data can be built by
+	// marshaling the message without it.
+	Sig []byte `ssh:"rest"`
+}
+
+// hostBasedSignedData returns the data signed by the client host key in a
+// hostbased authentication request.
+func hostBasedSignedData(sessionID []byte, msg hostBasedAuthMsg) []byte {
+	msg.Sig = nil
+	return append(appendString(nil, string(sessionID)), Marshal(&msg)...)
+}
+
+// hostBasedAuth is an AuthMethod that authenticates the user on the client
+// host with the host key of the client.
+type hostBasedAuth struct {
+	signer     Signer
+	clientHost string
+	clientUser string
+}
+
+func (h *hostBasedAuth) method() string {
+	return "hostbased"
+}
+
+func (h *hostBasedAuth) auth(ctx context.Context, session []byte, user string, c packetConn, rand io.Reader, extensions map[string][]byte) (authResult, []string, error) {
+	as, algo, err := pickSignatureAlgorithm(h.signer, extensions)
+	if err != nil {
+		return authFailure, nil, err
+	}
+	msg := hostBasedAuthMsg{
+		User:       user,
+		Service:    serviceSSH,
+		Method:     h.method(),
+		Algoname:   algo,
+		PubKey:     h.signer.PublicKey().Marshal(),
+		ClientHost: h.clientHost,
+		ClientUser: h.clientUser,
+	}
+	sign, err := signWithContext(ctx, as, rand, hostBasedSignedData(session, msg), underlyingAlgo(algo))
+	if err != nil {
+		return authFailure, nil, err
+	}
+	msg.Sig = appendString(nil, string(Marshal(sign)))
+	if err := c.writePacket(Marshal(&msg)); err != nil {
+		return authFailure, nil, err
+	}
+	return handleAuthResponse(c)
+}
+
+// HostBased returns an AuthMethod that uses "hostbased" authentication, see
+// RFC 4252, section 9: the server trusts that clientUser on the host named
+// clientHost is who they claim to be, as vouched for by signer, the private
+// host key of the client host. OpenSSH servers expect clientHost to be the
+// fully qualified name of the host, and accept it with a trailing dot.
+// Access to the host key is normally restricted, as in ssh-keysign.
+func HostBased(clientHost, clientUser string, signer Signer) AuthMethod {
+	return &hostBasedAuth{signer: signer, clientHost: clientHost, clientUser: clientUser}
+}
+
 // handleAuthResponse returns whether the preceding authentication request succeeded
 // along with a list of remaining authentication methods to try next and
 // an error if an unexpected response was received.
 func handleAuthResponse(c packetConn) (authResult, []string, error) {
+	ok, methods, _, err := readAuthResponse(c, 0)
+	return ok, methods, err
+}
+
+// readAuthResponse is like handleAuthResponse, but if the server answers
+// with a message of type other, it returns that packet instead. other is
+// ignored if it is 0.
+func readAuthResponse(c packetConn, other byte) (authResult, []string, []byte, error) {
 	gotMsgExtInfo := false
 	for {
 		packet, err := c.readPacket()
 		if err != nil {
-			return authFailure, nil, err
+			return authFailure, nil, nil, err
+		}
+
+		if other != 0 && packet[0] == other {
+			return authFailure, nil, packet, nil
 		}
 
 		switch packet[0] {
 		case msgUserAuthBanner:
 			if err := handleBannerResponse(c, packet); err != nil {
-				return authFailure, nil, err
+				return authFailure, nil, nil, err
 			}
 		case msgExtInfo:
-			// Ignore post-authentication RFC 8308 extensions, once.
+			// Record post-authentication RFC 8308 extensions, once.
 			if gotMsgExtInfo {
-				return authFailure, nil, unexpectedMessageError(msgUserAuthSuccess, packet[0])
+				return authFailure, nil, nil, unexpectedMessageError(msgUserAuthSuccess, packet[0])
 			}
 			gotMsgExtInfo = true
+			if err := recordExtInfo(c, packet); err != nil {
+				return authFailure, nil, nil, err
+			}
 		case msgUserAuthFailure:
 			var msg userAuthFailureMsg
 			if err := Unmarshal(packet, &msg); err != nil {
-				return authFailure, nil, err
+				return authFailure, nil, nil, err
 			}
 			if msg.PartialSuccess {
-				return authPartialSuccess, msg.Methods, nil
+				return authPartialSuccess, msg.Methods, nil, nil
 			}
-			return authFailure, msg.Methods, nil
+			return authFailure, msg.Methods, nil, nil
 		case msgUserAuthSuccess:
-			return authSuccess, nil, nil
+			return authSuccess, nil, nil, nil
 		default:
-			return authFailure, nil, unexpectedMessageError(msgUserAuthSuccess, packet[0])
+			return authFailure, nil, nil, unexpectedMessageError(msgUserAuthSuccess, packet[0])
 		}
 	}
 }
@@ -511,6 +660,7 @@ func handleBannerResponse(c packetConn, packet []byte) error {
 		return nil
 	}
 
+	transport.banner += msg.Message
 	if transport.bannerCallback != nil {
 		return transport.bannerCallback(msg.Message)
 	}
@@ -518,6 +668,17 @@ func handleBannerResponse(c packetConn, packet []byte) error {
 	return nil
 }
 
+// recordExtInfo records the extensions of a SSH_MSG_EXT_INFO message
+// received during authentication.
+func recordExtInfo(c packetConn, packet []byte) error {
+	transport, ok := c.(*handshakeTransport)
+	if !ok {
+		_, err := parseExtInfo(packet)
+		return err
+	}
+	return transport.recordExtInfo(packet)
+}
+
 // KeyboardInteractiveChallenge should print questions, optionally
 // disabling echoing (e.g. for passwords), and return all the answers.
 // Challenge may be called multiple times in a single session. After
@@ -537,7 +698,7 @@ func (cb KeyboardInteractiveChallenge) method() string {
 	return "keyboard-interactive"
 }
 
-func (cb KeyboardInteractiveChallenge) auth(session []byte, user string, c packetConn, rand io.Reader, _ map[string][]byte) (authResult, []string, error) {
+func (cb KeyboardInteractiveChallenge) auth(ctx context.Context, session []byte, user string, c packetConn, rand io.Reader, _ map[string][]byte) (authResult, []string, error) {
 	type initiateMsg struct {
 		User       string `sshtype:"50"`
 		Service    string
@@ -574,6 +735,9 @@ func (cb KeyboardInteractiveChallenge) auth(session []byte, user string, c packe
 				return authFailure, nil, unexpectedMessageError(msgUserAuthInfoRequest, packet[0])
 			}
 			gotMsgExtInfo = true
+			if err := recordExtInfo(c, packet); err != nil {
+				return authFailure, nil, err
+			}
 			continue
 		case msgUserAuthInfoRequest:
 			// OK
@@ -647,9 +811,9 @@ type retryableAuthMethod struct {
 	maxTries   int
 }
 
-func (r *retryableAuthMethod) auth(session []byte, user string, c packetConn, rand io.Reader, extensions map[string][]byte) (ok authResult, methods []string, err error) {
+func (r *retryableAuthMethod) auth(ctx context.Context, session []byte, user string, c packetConn, rand io.Reader, extensions map[string][]byte) (ok authResult, methods []string, err error) {
 	for i := 0; r.maxTries <= 0 || i < r.maxTries; i++ {
-		ok, methods, err = r.authMethod.auth(session, user, c, rand, extensions)
+		ok, methods, err = r.authMethod.auth(ctx, session, user, c, rand, extensions)
 		if ok != authFailure || err != nil { // either success, partial success or error terminate
 			return ok, methods, err
 		}
@@ -676,6 +840,60 @@ func RetryableAuthMethod(auth AuthMethod, maxTries int) AuthMethod {
 	return &retryableAuthMethod{authMethod: auth, maxTries: maxTries}
 }
 
+// AuthSequence is an AuthMethod that authenticates with a fixed sequence of
+// methods, for servers that require several of them, such as "publickey"
+// followed by "password". Each method after the first is only tried once
+// the previous one partially succeeded, and if the server lists it as able
+// to continue. The sequence is named after its first method, which must not
+// be listed separately in ClientConfig.Auth.
+type AuthSequence struct {
+	steps []AuthMethod
+
+	mu        sync.Mutex
+	succeeded []string
+}
+
+// NewAuthSequence returns an AuthSequence of steps, which must not be
+// empty.
+func NewAuthSequence(steps ...AuthMethod) *AuthSequence {
+	return &AuthSequence{steps: steps}
+}
+
+// Succeeded returns the names of the methods of the sequence that succeeded,
+// fully or partially, in the last authentication attempt.
+func (s *AuthSequence) Succeeded() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.succeeded...)
+}
+
+func (s *AuthSequence) method() string {
+	return s.steps[0].method()
+}
+
+func (s *AuthSequence) auth(ctx context.Context, session []byte, user string, c packetConn, rand io.Reader, extensions map[string][]byte) (authResult, []string, error) {
+	s.mu.Lock()
+	s.succeeded = nil
+	s.mu.Unlock()
+
+	for i, step := range s.steps {
+		ok, methods, err := step.auth(ctx, session, user, c, rand, extensions)
+		if ok == authFailure || err != nil {
+			return ok, methods, err
+		}
+		s.mu.Lock()
+		s.succeeded = append(s.succeeded, step.method())
+		s.mu.Unlock()
+		// Stop if the server needs no more methods or does not accept the
+		// next one, in which case the other methods of ClientConfig.Auth
+		// may be tried.
+		if ok == authSuccess || i == len(s.steps)-1 || !contains(methods, s.steps[i+1].method()) {
+			return ok, methods, nil
+		}
+	}
+	return authFailure, nil, errors.New("ssh: empty authentication sequence")
+}
+
 // GSSAPIWithMICAuthMethod is an AuthMethod with "gssapi-with-mic" authentication.
 // See RFC 4462 section 3
 // gssAPIClient is implementation of the GSSAPIClient interface, see the definition of the interface for details.
@@ -692,7 +910,7 @@ type gssAPIWithMICCallback struct {
 	target       string
 }
 
-func (g *gssAPIWithMICCallback) auth(session []byte, user string, c packetConn, rand io.Reader, _ map[string][]byte) (authResult, []string, error) {
+func (g *gssAPIWithMICCallback) auth(ctx context.Context, session []byte, user string, c packetConn, rand io.Reader, _ map[string][]byte) (authResult, []string, error) {
 	m := &userAuthRequestMsg{
 		User:    user,
 		Service: serviceSSH,
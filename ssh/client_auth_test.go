@@ -6,6 +6,7 @@ package ssh
 
 import (
 	"bytes"
+	"context"
 	"crypto/rand"
 	"errors"
 	"fmt"
@@ -16,6 +17,7 @@ import (
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 )
 
 type keyboardInteractive map[string]string
@@ -221,6 +223,93 @@ func TestAuthMethodWrongPassword(t *testing.T) {
 	}
 }
 
+func TestAuthMethodPasswordChange(t *testing.T) {
+	serverConfig := &ServerConfig{
+		PasswordCallback: func(conn ConnMetadata, pass []byte) (*Permissions, error) {
+			if string(pass) == "expired" {
+				return nil, &PasswordChangeRequiredError{Prompt: "password expired"}
+			}
+			return nil, errors.New("password auth failed")
+		},
+		PasswordChangeCallback: func(conn ConnMetadata, oldPass, newPass []byte) (*Permissions, error) {
+			if string(oldPass) != "expired" {
+				return nil, errors.New("password auth failed")
+			}
+			if string(newPass) == "weak" {
+				return nil, &PasswordChangeRequiredError{Prompt: "password too weak"}
+			}
+			return nil, nil
+		},
+	}
+	serverConfig.AddHostKey(testSigners["rsa"])
+
+	for _, tt := range []struct {
+		name    string
+		change  PasswordChangeCallback
+		wantErr bool
+	}{
+		{
+			name: "change",
+			change: func() PasswordChangeCallback {
+				passwords := []string{"weak", "strong"}
+				return func(prompt, language string) (string, error) {
+					want := "password expired"
+					if len(passwords) == 1 {
+						want = "password too weak"
+					}
+					if prompt != want {
+						t.Errorf("got prompt %q, want %q", prompt, want)
+					}
+					p := passwords[0]
+					passwords = passwords[1:]
+					return p, nil
+				}
+			}(),
+		},
+		{
+			name: "rejected",
+			change: func(prompt, language string) (string, error) {
+				return "", errors.New("no new password")
+			},
+			wantErr: true,
+		},
+		{
+			// Each change request counts as an attempt, so the server
+			// disconnects after MaxAuthTries.
+			name: "always weak",
+			change: func(prompt, language string) (string, error) {
+				return "weak", nil
+			},
+			wantErr: true,
+		},
+		{name: "no callback", wantErr: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			c1, c2, err := netPipe()
+			if err != nil {
+				t.Fatalf("netPipe: %v", err)
+			}
+			defer c1.Close()
+			defer c2.Close()
+			go newServer(c1, serverConfig)
+
+			auth := PasswordCallback(func() (string, error) { return "expired", nil })
+			if tt.change != nil {
+				auth = PasswordCallbackWithChange(func() (string, error) { return "expired", nil }, tt.change)
+			}
+			config := &ClientConfig{
+				User:            "testuser",
+				Auth:            []AuthMethod{auth},
+				HostKeyCallback: InsecureIgnoreHostKey(),
+			}
+			_, _, _, err = NewClientConn(c2, "", config)
+			if gotErr := err != nil; gotErr != tt.wantErr {
+				t.Fatalf("NewClientConn: %v, want error: %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestAuthMethodKeyboardInteractive(t *testing.T) {
 	answers := keyboardInteractive(map[string]string{
 		"question1": "answer1",
@@ -1157,7 +1246,7 @@ func (cb configurablePublicKeyCallback) method() string {
 	return "publickey"
 }
 
-func (cb configurablePublicKeyCallback) auth(session []byte, user string, c packetConn, rand io.Reader, extensions map[string][]byte) (authResult, []string, error) {
+func (cb configurablePublicKeyCallback) auth(ctx context.Context, session []byte, user string, c packetConn, rand io.Reader, extensions map[string][]byte) (authResult, []string, error) {
 	pub := cb.signer.PublicKey()
 
 	ok, err := validateKey(pub, cb.signatureAlgo, user, c)
@@ -1282,3 +1371,205 @@ func TestCertAuthOpenSSHCompat(t *testing.T) {
 		t.Fatalf("unable to dial remote side: %s", err)
 	}
 }
+
+func TestHostBasedAuthRequest(t *testing.T) {
+	a, b := memPipe()
+	defer a.Close()
+	defer b.Close()
+
+	sessionID := []byte("session")
+	signer := testSigners["ecdsa"]
+	type result struct {
+		ok  authResult
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		ok, _, err := HostBased("client.example.com.", "alice", signer).auth(context.Background(), sessionID, "bob", a, rand.Reader, nil)
+		done <- result{ok, err}
+	}()
+
+	packet, err := b.readPacket()
+	if err != nil {
+		t.Fatalf("readPacket: %v", err)
+	}
+	var msg hostBasedAuthMsg
+	if err := Unmarshal(packet, &msg); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if msg.User != "bob" || msg.Method != "hostbased" || msg.ClientHost != "client.example.com." || msg.ClientUser != "alice" ||
+		!bytes.Equal(msg.PubKey, signer.PublicKey().Marshal()) {
+		t.Errorf("got request %+v", msg)
+	}
+	blob, rest, ok := parseString(msg.Sig)
+	if !ok || len(rest) > 0 {
+		t.Fatalf("malformed signature %x", msg.Sig)
+	}
+	sig, rest, ok := parseSignatureBody(blob)
+	if !ok || len(rest) > 0 {
+		t.Fatalf("malformed signature %x", blob)
+	}
+	if err := signer.PublicKey().Verify(hostBasedSignedData(sessionID, msg), sig); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+
+	if err := b.writePacket([]byte{msgUserAuthSuccess}); err != nil {
+		t.Fatalf("writePacket: %v", err)
+	}
+	if r := <-done; r.ok != authSuccess || r.err != nil {
+		t.Errorf("auth = %v, %v; want success", r.ok, r.err)
+	}
+}
+
+func TestHostBasedAuth(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		signer  Signer
+		host    string
+		wantErr bool
+	}{
+		{"ecdsa", testSigners["ecdsa"], "client.example.com.", false},
+		{"rsa", testSigners["rsa"], "client.example.com.", false},
+		{"unknown host", testSigners["ecdsa"], "other.example.com.", true},
+		{"wrong key", testSigners["ed25519"], "client.example.com.", true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			c1, c2, err := netPipe()
+			if err != nil {
+				t.Fatalf("netPipe: %v", err)
+			}
+			defer c1.Close()
+			defer c2.Close()
+
+			serverConfig := &ServerConfig{
+				HostBasedCallback: func(conn ConnMetadata, key PublicKey, clientHost, clientUser string) (*Permissions, error) {
+					if conn.User() != "testuser" || clientUser != "alice" {
+						return nil, fmt.Errorf("user %q on %q is not trusted", clientUser, clientHost)
+					}
+					if clientHost != "client.example.com." ||
+						(!bytes.Equal(key.Marshal(), testPublicKeys["ecdsa"].Marshal()) && !bytes.Equal(key.Marshal(), testPublicKeys["rsa"].Marshal())) {
+						return nil, fmt.Errorf("unknown host %q", clientHost)
+					}
+					return &Permissions{Extensions: map[string]string{"client-host": clientHost}}, nil
+				},
+			}
+			serverConfig.AddHostKey(testSigners["rsa"])
+			perms := make(chan *Permissions, 1)
+			go func() {
+				conn, _, _, err := NewServerConn(c1, serverConfig)
+				if err != nil {
+					perms <- nil
+					return
+				}
+				perms <- conn.Permissions
+			}()
+
+			clientConfig := &ClientConfig{
+				User:            "testuser",
+				Auth:            []AuthMethod{HostBased(tt.host, "alice", tt.signer)},
+				HostKeyCallback: InsecureIgnoreHostKey(),
+			}
+			_, _, _, err = NewClientConn(c2, "", clientConfig)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewClientConn: %v, want error %v", err, tt.wantErr)
+			}
+			if p := <-perms; !tt.wantErr && (p == nil || p.Extensions["client-host"] != tt.host) {
+				t.Errorf("got permissions %+v", p)
+			}
+		})
+	}
+}
+
+type ctxKey struct{}
+
+// contextSigner is a SignerWithContext that records the contexts it is
+// passed, and waits for them to be done if block is set.
+type contextSigner struct {
+	AlgorithmSigner
+	block bool
+	got   []context.Context
+}
+
+func (s *contextSigner) SignWithContext(ctx context.Context, rand io.Reader, data []byte, algorithm string) (*Signature, error) {
+	s.got = append(s.got, ctx)
+	if s.block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return s.SignWithAlgorithm(rand, data, algorithm)
+}
+
+func TestSignerWithContext(t *testing.T) {
+	run := func(ctx context.Context, signer Signer) error {
+		c1, c2, err := netPipe()
+		if err != nil {
+			t.Fatalf("netPipe: %v", err)
+		}
+		defer c1.Close()
+		defer c2.Close()
+
+		serverConfig := &ServerConfig{
+			PublicKeyCallback: func(conn ConnMetadata, key PublicKey) (*Permissions, error) {
+				if bytes.Equal(key.Marshal(), testPublicKeys["rsa"].Marshal()) {
+					return nil, nil
+				}
+				return nil, errors.New("unknown key")
+			},
+		}
+		serverConfig.AddHostKey(testSigners["ecdsa"])
+		go NewServerConn(c1, serverConfig)
+
+		_, _, _, err = NewClientConnContext(ctx, c2, "", &ClientConfig{
+			User:            "testuser",
+			Auth:            []AuthMethod{PublicKeys(signer)},
+			HostKeyCallback: InsecureIgnoreHostKey(),
+		})
+		return err
+	}
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+	cs := &contextSigner{AlgorithmSigner: testSigners["rsa"].(AlgorithmSigner)}
+	// The context is passed through the wrapper of NewSignerWithAlgorithms.
+	signer, err := NewSignerWithAlgorithms(cs, []string{KeyAlgoRSASHA256})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := run(ctx, signer); err != nil {
+		t.Fatalf("handshake: %v", err)
+	}
+	if len(cs.got) == 0 || cs.got[0].Value(ctxKey{}) != "value" {
+		t.Errorf("SignWithContext was not passed the handshake context")
+	}
+
+	cs = &contextSigner{AlgorithmSigner: testSigners["rsa"].(AlgorithmSigner), block: true}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := run(ctx, cs); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("handshake with a blocked signer: got %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestSignCertContext(t *testing.T) {
+	cert := &Certificate{
+		Key:         testPublicKeys["rsa"],
+		CertType:    UserCert,
+		ValidBefore: CertTimeInfinity,
+	}
+	cs := &contextSigner{AlgorithmSigner: testSigners["ed25519"].(AlgorithmSigner)}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+	if err := cert.SignCertContext(ctx, rand.Reader, cs); err != nil {
+		t.Fatal(err)
+	}
+	if len(cs.got) != 1 || cs.got[0].Value(ctxKey{}) != "value" {
+		t.Errorf("SignWithContext was not passed the context")
+	}
+	if err := cert.SignatureKey.Verify(cert.bytesForSigning(), cert.Signature); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := cert.SignCertContext(ctx, rand.Reader, testSigners["ed25519"]); err != context.Canceled {
+		t.Errorf("SignCertContext with a done context: got %v, want %v", err, context.Canceled)
+	}
+}
@@ -6,12 +6,14 @@ package ssh
 
 import (
 	"bytes"
+	"context"
 	"crypto/rand"
 	"errors"
 	"fmt"
 	"net"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestClientVersion(t *testing.T) {
@@ -209,6 +211,53 @@ func TestBannerCallback(t *testing.T) {
 	}
 }
 
+func TestClientBanner(t *testing.T) {
+	c1, c2, err := netPipe()
+	if err != nil {
+		t.Fatalf("netPipe: %v", err)
+	}
+	defer c1.Close()
+	defer c2.Close()
+
+	serverConf := &ServerConfig{
+		PasswordCallback: func(conn ConnMetadata, password []byte) (*Permissions, error) {
+			return nil, &BannerError{Err: errors.New("wrong password"), Message: "Try again\n"}
+		},
+		KeyboardInteractiveCallback: func(conn ConnMetadata, challenge KeyboardInteractiveChallenge) (*Permissions, error) {
+			return &Permissions{}, nil
+		},
+		BannerCallback: func(conn ConnMetadata) string {
+			return "Hello World\n"
+		},
+	}
+	serverConf.AddHostKey(testSigners["rsa"])
+	go NewServerConn(c1, serverConf)
+
+	clientConf := ClientConfig{
+		Auth: []AuthMethod{
+			Password("123"),
+			KeyboardInteractive(func(name, instruction string, questions []string, echos []bool) ([]string, error) {
+				return nil, nil
+			}),
+		},
+		User:            "user",
+		HostKeyCallback: InsecureIgnoreHostKey(),
+	}
+	conn, chans, reqs, err := NewClientConn(c2, "", &clientConf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewClient(conn, chans, reqs)
+	defer client.Close()
+
+	if got, want := client.Banner(), "Hello World\nTry again\n"; got != want {
+		t.Errorf("got banner %q, want %q", got, want)
+	}
+	if got := string(client.ServerVersion()); got != packageVersion {
+		t.Errorf("got server version %q, want %q", got, packageVersion)
+	}
+}
+
 func TestNewClientConn(t *testing.T) {
 	errHostKeyMismatch := errors.New("host key mismatch")
 
@@ -276,6 +325,81 @@ func TestNewClientConn(t *testing.T) {
 	}
 }
 
+func TestNewClientConnContextCancel(t *testing.T) {
+	c1, c2, err := netPipe()
+	if err != nil {
+		t.Fatalf("netPipe: %v", err)
+	}
+	defer c1.Close()
+	defer c2.Close()
+
+	// The server side never answers, so the client blocks in the version
+	// exchange until the context expires.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, _, _, err = NewClientConnContext(ctx, c2, "", &ClientConfig{
+		HostKeyCallback: InsecureIgnoreHostKey(),
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got error %v; want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestDialContext(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	go func() {
+		for {
+			c, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			serverConf := &ServerConfig{NoClientAuth: true}
+			serverConf.AddHostKey(testSigners["rsa"])
+			go func() {
+				conn, chans, reqs, err := NewServerConn(c, serverConf)
+				if err != nil {
+					return
+				}
+				go DiscardRequests(reqs)
+				go func() {
+					for ch := range chans {
+						ch.Reject(Prohibited, "")
+					}
+				}()
+				conn.Wait()
+			}()
+		}
+	}()
+
+	clientConf := &ClientConfig{
+		User:            "testuser",
+		HostKeyCallback: InsecureIgnoreHostKey(),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	client, err := DialContext(ctx, "tcp", listener.Addr().String(), clientConf)
+	if err != nil {
+		t.Fatalf("DialContext: %v", err)
+	}
+	// Cancelling the context after the connection is established must not
+	// affect it.
+	cancel()
+	if _, _, err := client.SendRequest("test", true, nil); err != nil {
+		t.Errorf("SendRequest after cancel: %v", err)
+	}
+	client.Close()
+
+	if _, err := DialContext(ctx, "tcp", listener.Addr().String(), clientConf); !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v; want %v", err, context.Canceled)
+	}
+}
+
 func TestUnsupportedAlgorithm(t *testing.T) {
 	for _, tt := range []struct {
 		name      string
@@ -365,3 +489,146 @@ func TestUnsupportedAlgorithm(t *testing.T) {
 		})
 	}
 }
+
+func TestClientPing(t *testing.T) {
+	c1, c2, err := netPipe()
+	if err != nil {
+		t.Fatalf("netPipe: %v", err)
+	}
+	defer c1.Close()
+	defer c2.Close()
+
+	serverConf := &ServerConfig{NoClientAuth: true}
+	serverConf.AddHostKey(testSigners["ecdsa"])
+	go func() {
+		conn, chans, reqs, err := NewServerConn(c2, serverConf)
+		if err != nil {
+			return
+		}
+		go DiscardRequests(reqs)
+		for range chans {
+		}
+		conn.Close()
+	}()
+
+	clientConf := &ClientConfig{
+		User:            "user",
+		HostKeyCallback: InsecureIgnoreHostKey(),
+	}
+	conn, chans, reqs, err := NewClientConn(c1, "", clientConf)
+	if err != nil {
+		t.Fatalf("NewClientConn: %v", err)
+	}
+	client := NewClient(conn, chans, reqs)
+	defer client.Close()
+
+	for i := 0; i < 3; i++ {
+		if rtt, err := client.Ping(context.Background()); err != nil || rtt <= 0 {
+			t.Fatalf("Ping: %v, %v", rtt, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.Ping(ctx); err != context.Canceled {
+		t.Errorf("Ping with canceled context: got %v, want %v", err, context.Canceled)
+	}
+
+	// A late pong for an earlier ping must be skipped.
+	conn.(*connection).mux.pongs <- "stale"
+	if _, err := client.Ping(context.Background()); err != nil {
+		t.Errorf("Ping: %v", err)
+	}
+
+	delete(conn.(*connection).peerExtInfo, pingExtension)
+	if _, err := client.Ping(context.Background()); err == nil {
+		t.Error("Ping succeeded without server support")
+	}
+}
+
+func TestSendDebug(t *testing.T) {
+	c1, c2, err := netPipe()
+	if err != nil {
+		t.Fatalf("netPipe: %v", err)
+	}
+	defer c1.Close()
+	defer c2.Close()
+
+	type debug struct {
+		alwaysDisplay     bool
+		message, language string
+	}
+	serverDebug := make(chan debug, 1)
+	clientDebug := make(chan debug, 1)
+
+	serverConf := &ServerConfig{
+		NoClientAuth: true,
+		Config: Config{
+			DebugCallback: func(alwaysDisplay bool, message, language string) {
+				serverDebug <- debug{alwaysDisplay, message, language}
+			},
+		},
+	}
+	serverConf.AddHostKey(testSigners["rsa"])
+	serverDone := make(chan *ServerConn, 1)
+	go func() {
+		conn, chans, reqs, err := NewServerConn(c1, serverConf)
+		if err != nil {
+			t.Errorf("NewServerConn: %v", err)
+			serverDone <- nil
+			return
+		}
+		go DiscardRequests(reqs)
+		go func() {
+			for newCh := range chans {
+				newCh.Reject(Prohibited, "")
+			}
+		}()
+		serverDone <- conn
+	}()
+
+	conn, chans, reqs, err := NewClientConn(c2, "", &ClientConfig{
+		User:            "user",
+		HostKeyCallback: InsecureIgnoreHostKey(),
+		Config: Config{
+			DebugCallback: func(alwaysDisplay bool, message, language string) {
+				clientDebug <- debug{alwaysDisplay, message, language}
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewClient(conn, chans, reqs)
+	defer client.Close()
+	server := <-serverDone
+	if server == nil {
+		t.FailNow()
+	}
+	defer server.Close()
+
+	if err := client.SendIgnore([]byte("padding")); err != nil {
+		t.Fatalf("SendIgnore: %v", err)
+	}
+	if err := client.SendDebug(true, "hello server", "en"); err != nil {
+		t.Fatalf("SendDebug: %v", err)
+	}
+	if got, want := <-serverDebug, (debug{true, "hello server", "en"}); got != want {
+		t.Errorf("server got %+v, want %+v", got, want)
+	}
+
+	if err := server.SendIgnore(nil); err != nil {
+		t.Fatalf("SendIgnore: %v", err)
+	}
+	if err := server.SendDebug(false, "hello client", ""); err != nil {
+		t.Fatalf("SendDebug: %v", err)
+	}
+	if got, want := <-clientDebug, (debug{false, "hello client", ""}); got != want {
+		t.Errorf("client got %+v, want %+v", got, want)
+	}
+
+	// The connection is still usable after the skipped messages.
+	if _, _, err := client.SendRequest("test", true, nil); err != nil {
+		t.Errorf("SendRequest: %v", err)
+	}
+}
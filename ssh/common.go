@@ -29,7 +29,7 @@ const (
 // supportedCiphers lists ciphers we support but might not recommend.
 var supportedCiphers = []string{
 	"aes128-ctr", "aes192-ctr", "aes256-ctr",
-	"aes128-gcm@openssh.com", gcm256CipherID,
+	gcm128CipherID, gcm256CipherID,
 	chacha20Poly1305ID,
 	"arcfour256", "arcfour128", "arcfour",
 	aes128cbcID,
@@ -38,7 +38,7 @@ var supportedCiphers = []string{
 
 // preferredCiphers specifies the default preference for ciphers.
 var preferredCiphers = []string{
-	"aes128-gcm@openssh.com", gcm256CipherID,
+	gcm128CipherID, gcm256CipherID,
 	chacha20Poly1305ID,
 	"aes128-ctr", "aes192-ctr", "aes256-ctr",
 }
@@ -46,11 +46,14 @@ var preferredCiphers = []string{
 // supportedKexAlgos specifies the supported key-exchange algorithms in
 // preference order.
 var supportedKexAlgos = []string{
+	kexAlgoSNTRUP761SHA512, kexAlgoSNTRUP761SHA512OpenSSH,
 	kexAlgoCurve25519SHA256, kexAlgoCurve25519SHA256LibSSH,
-	// P384 and P521 are not constant-time yet, but since we don't
+	// curve448, P384 and P521 are not constant-time yet, but since we don't
 	// reuse ephemeral keys, using them for ECDH should be OK.
+	kexAlgoCurve448SHA512,
 	kexAlgoECDH256, kexAlgoECDH384, kexAlgoECDH521,
-	kexAlgoDH14SHA256, kexAlgoDH16SHA512, kexAlgoDH14SHA1,
+	kexAlgoDH14SHA256, kexAlgoDH15SHA512, kexAlgoDH16SHA512,
+	kexAlgoDH17SHA512, kexAlgoDH18SHA512, kexAlgoDH14SHA1,
 	kexAlgoDH1SHA1,
 }
 
@@ -62,16 +65,21 @@ var serverForbiddenKexAlgos = map[string]struct{}{
 }
 
 // preferredKexAlgos specifies the default preference for key-exchange
-// algorithms in preference order. The diffie-hellman-group16-sha512 algorithm
-// is disabled by default because it is a bit slower than the others.
+// algorithms in preference order. The curve448-sha512 and
+// diffie-hellman-group15-sha512 to diffie-hellman-group18-sha512 algorithms
+// are disabled by default because they are slower than the others, the
+// larger groups considerably so. The sntrup761x25519-sha512 algorithms are
+// disabled by default until their implementation has been checked against
+// the NTRU Prime known-answer tests.
 var preferredKexAlgos = []string{
 	kexAlgoCurve25519SHA256, kexAlgoCurve25519SHA256LibSSH,
 	kexAlgoECDH256, kexAlgoECDH384, kexAlgoECDH521,
 	kexAlgoDH14SHA256, kexAlgoDH14SHA1,
 }
 
-// supportedHostKeyAlgos specifies the supported host-key algorithms (i.e. methods
-// of authenticating servers) in preference order.
+// supportedHostKeyAlgos specifies the default host-key algorithms (i.e. methods
+// of authenticating servers) in preference order. KeyAlgoED448 is supported
+// if listed in ClientConfig.HostKeyAlgorithms, but not by default.
 var supportedHostKeyAlgos = []string{
 	CertAlgoRSASHA256v01, CertAlgoRSASHA512v01,
 	CertAlgoRSAv01, CertAlgoDSAv01, CertAlgoECDSA256v01,
@@ -91,7 +99,9 @@ var supportedMACs = []string{
 	"hmac-sha2-256-etm@openssh.com", "hmac-sha2-512-etm@openssh.com", "hmac-sha2-256", "hmac-sha2-512", "hmac-sha1", "hmac-sha1-96",
 }
 
-var supportedCompressions = []string{compressionNone}
+// supportedCompressions lists the supported compression algorithms in
+// preference order.
+var supportedCompressions = []string{compressionNone, compressionZlibOpenSSH, compressionZlib}
 
 // hashFuncs keeps the mapping of supported signature algorithms to their
 // respective hashes needed for signing and verification.
@@ -103,7 +113,7 @@ var hashFuncs = map[string]crypto.Hash{
 	KeyAlgoECDSA256:  crypto.SHA256,
 	KeyAlgoECDSA384:  crypto.SHA384,
 	KeyAlgoECDSA521:  crypto.SHA512,
-	// KeyAlgoED25519 doesn't pre-hash.
+	// KeyAlgoED25519 and KeyAlgoED448 don't pre-hash.
 	KeyAlgoSKECDSA256: crypto.SHA256,
 	KeyAlgoSKED25519:  crypto.SHA256,
 }
@@ -141,7 +151,12 @@ func isRSACert(algo string) bool {
 // authentication algorithms. Note that this doesn't include certificate types
 // since those use the underlying algorithm. This list is sent to the client if
 // it supports the server-sig-algs extension. Order is irrelevant.
-var supportedPubKeyAuthAlgos = []string{
+var supportedPubKeyAuthAlgos = append([]string{KeyAlgoED448}, preferredPubKeyAuthAlgos...)
+
+// preferredPubKeyAuthAlgos specifies the client public key authentication
+// algorithms that servers accept by default. KeyAlgoED448 is only accepted
+// if listed in ServerConfig.PublicKeyAuthAlgorithms.
+var preferredPubKeyAuthAlgos = []string{
 	KeyAlgoED25519,
 	KeyAlgoSKED25519, KeyAlgoSKECDSA256,
 	KeyAlgoECDSA256, KeyAlgoECDSA384, KeyAlgoECDSA521,
@@ -168,7 +183,7 @@ func findCommon(what string, client []string, server []string) (common string, e
 			}
 		}
 	}
-	return "", fmt.Errorf("ssh: no common algorithm for %s; client offered: %v, server offered: %v", what, client, server)
+	return "", &AlgorithmNegotiationError{What: what, Client: client, Server: server}
 }
 
 // directionAlgorithms records algorithm choices in one direction (either read or write)
@@ -206,6 +221,15 @@ type algorithms struct {
 	r       directionAlgorithms
 }
 
+func (a *algorithms) negotiated() NegotiatedAlgorithms {
+	return NegotiatedAlgorithms{
+		KeyExchange: a.kex,
+		HostKey:     a.hostKey,
+		Read:        DirectionAlgorithms(a.r),
+		Write:       DirectionAlgorithms(a.w),
+	}
+}
+
 func findAgreedAlgorithms(isClient bool, clientKexInit, serverKexInit *kexInitMsg) (algs *algorithms, err error) {
 	result := &algorithms{}
 
@@ -265,6 +289,13 @@ func findAgreedAlgorithms(isClient bool, clientKexInit, serverKexInit *kexInitMs
 // stuff.
 const minRekeyThreshold uint64 = 256
 
+// DebugCallback is the function type used to receive SSH_MSG_DEBUG messages
+// from the peer, see RFC 4253, section 11.3. alwaysDisplay is set if the
+// peer asks for message to be displayed even when debugging is not enabled.
+// The callback is called from the goroutine reading the connection, so it
+// must not block.
+type DebugCallback func(alwaysDisplay bool, message, language string)
+
 // Config contains configuration data common to both ServerConfig and
 // ClientConfig.
 type Config struct {
@@ -278,6 +309,11 @@ type Config struct {
 	// unspecified, a size suitable for the chosen cipher is used.
 	RekeyThreshold uint64
 
+	// RekeyAfterDuration, if positive, is the time after which a new key
+	// is negotiated, counted from the end of the previous key exchange.
+	// It applies in addition to RekeyThreshold.
+	RekeyAfterDuration time.Duration
+
 	// The allowed key exchanges algorithms. If unspecified then a default set
 	// of algorithms is used. Unsupported values are silently ignored.
 	KeyExchanges []string
@@ -289,6 +325,78 @@ type Config struct {
 	// The allowed MAC algorithms. If unspecified then a sensible default is
 	// used. Unsupported values are silently ignored.
 	MACs []string
+
+	// The allowed compression algorithms, in order of preference. If
+	// unspecified, compression is disabled. The supported algorithms are
+	// "none", "zlib@openssh.com", which only starts compressing once the
+	// user is authenticated, and "zlib". Unsupported values are silently
+	// ignored.
+	Compressions []string
+
+	// ChannelBufferSize is the maximum number of bytes of incoming data
+	// buffered for each channel, counting the data and extended data
+	// streams together. It is used as the flow-control window advertised to
+	// the peer, and the window is only extended as the data is consumed, so
+	// the peer stops sending, and its writes block, until the buffer is
+	// drained. Values below 32KiB are raised to 32KiB. If zero, 2MiB is used.
+	//
+	// There is no mode in which a full buffer blocks the connection
+	// instead: the connection reads the data of all its channels, so
+	// waiting for one slow reader would stall every other channel. A peer
+	// that sends more than the window allows is disconnected.
+	ChannelBufferSize uint32
+
+	// ExtInfo holds additional extensions, keyed by name, to advertise to
+	// the peer in the SSH_MSG_EXT_INFO message sent after the first key
+	// exchange. See RFC 8308. Servers always advertise the
+	// "server-sig-algs" and "ping@openssh.com" extensions, and entries
+	// with those names are ignored. Clients only send the message if
+	// ExtInfo is not empty and the server indicated it accepts one.
+	ExtInfo map[string][]byte
+
+	// PacketTracer, if not nil, is called with every packet sent and
+	// received, which is useful to debug interoperability problems. The
+	// payloads of user authentication requests, keyboard-interactive
+	// responses and channel data are passed as nil, unless
+	// TraceSensitivePayloads is set, as they may contain passwords or user
+	// data.
+	PacketTracer PacketTracer
+
+	// Logger, if not nil, receives messages about the version exchange, key
+	// exchanges, authentication attempts, channels being opened and closed,
+	// and the end of the connection.
+	Logger Logger
+
+	// DebugCallback, if not nil, is called with the SSH_MSG_DEBUG messages
+	// that the peer sends.
+	DebugCallback DebugCallback
+
+	// Metrics, if not nil, receives counters about the packets, channels,
+	// key exchanges and authentication failures of the connection.
+	Metrics Metrics
+
+	// TraceSensitivePayloads makes PacketTracer receive the payloads of all
+	// packets.
+	TraceSensitivePayloads bool
+
+	// KeepaliveInterval, if positive, is the interval at which a
+	// "keepalive@openssh.com" global request is sent to the peer once the
+	// connection is established. If KeepaliveMaxMissed consecutive
+	// intervals pass without a reply, the peer is considered dead, the
+	// connection is closed and Wait returns ErrKeepaliveTimeout.
+	KeepaliveInterval time.Duration
+
+	// KeepaliveMaxMissed is the number of keepalive intervals without a
+	// reply after which the peer is considered dead. If zero, 3 is used,
+	// matching the OpenSSH ServerAliveCountMax default.
+	KeepaliveMaxMissed int
+
+	// IdleTimeout, if positive, is the duration after which an established
+	// connection on which no channel data, channel opens or closes, or
+	// requests other than keepalives have flowed, is closed. A
+	// SSH_MSG_DISCONNECT message is sent to the peer, and Wait returns
+	// ErrIdleTimeout.
+	IdleTimeout time.Duration
 }
 
 // SetDefaults sets sensible values for unset fields in config. This is
@@ -334,6 +442,36 @@ func (c *Config) SetDefaults() {
 	}
 	c.MACs = macs
 
+	if c.Compressions == nil {
+		c.Compressions = []string{compressionNone}
+	}
+	var compressions []string
+	for _, a := range c.Compressions {
+		if contains(supportedCompressions, a) {
+			// Ignore the compression if we have no implementation.
+			compressions = append(compressions, a)
+		}
+	}
+	c.Compressions = compressions
+
+	if c.ChannelBufferSize == 0 {
+		c.ChannelBufferSize = channelWindowSize
+	} else if c.ChannelBufferSize < channelMaxPacket {
+		c.ChannelBufferSize = channelMaxPacket
+	}
+
+	if c.Logger == nil {
+		c.Logger = discardLogger{}
+	}
+
+	if c.Metrics == nil {
+		c.Metrics = discardMetrics{}
+	}
+
+	if c.KeepaliveMaxMissed <= 0 {
+		c.KeepaliveMaxMissed = 3
+	}
+
 	if c.RekeyThreshold == 0 {
 		// cipher specific default
 	} else if c.RekeyThreshold < minRekeyThreshold {
@@ -510,6 +648,31 @@ func (w *window) reserve(win uint32) (uint32, error) {
 	return win, err
 }
 
+// reserveAll is like reserve, but blocks until all of win can be reserved
+// at once.
+func (w *window) reserveAll(win uint32) error {
+	w.L.Lock()
+	defer w.L.Unlock()
+
+	if w.deadlineReached {
+		return os.ErrDeadlineExceeded
+	}
+	w.writeWaiters++
+	w.Broadcast()
+	for w.win < win && !w.closed && !w.deadlineReached {
+		w.Wait()
+	}
+	w.writeWaiters--
+	if w.closed {
+		return io.EOF
+	}
+	if w.deadlineReached {
+		return os.ErrDeadlineExceeded
+	}
+	w.win -= win
+	return nil
+}
+
 // waitWriterBlocked waits until some goroutine is blocked for further
 // writes. It is used in tests only.
 func (w *window) waitWriterBlocked() {
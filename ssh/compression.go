@@ -0,0 +1,476 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+)
+
+// Compression algorithms, as defined in RFC 4253, section 6.2, and the
+// OpenSSH PROTOCOL file, section 1.6.
+const (
+	// compressionZlib compresses all packets after the first msgNewKeys.
+	compressionZlib = "zlib"
+	// compressionZlibOpenSSH delays compression until the user has been
+	// authenticated, so that unauthenticated peers cannot reach the
+	// decompressor.
+	compressionZlibOpenSSH = "zlib@openssh.com"
+)
+
+// compressionActive reports whether packets in the direction of s must be
+// compressed. authDone reports whether user authentication has succeeded.
+func (s *connectionState) compressionActive(authDone bool) bool {
+	switch s.compression {
+	case compressionZlib:
+		return true
+	case compressionZlibOpenSSH:
+		return authDone
+	}
+	return false
+}
+
+// zlibCompressor compresses the payloads of the packets sent in one
+// direction. The packets form a single zlib stream, and each packet is
+// terminated with a sync flush so that the peer can decompress it without
+// waiting for more data.
+type zlibCompressor struct {
+	buf bytes.Buffer
+	w   *zlib.Writer
+}
+
+func newZlibCompressor() *zlibCompressor {
+	c := &zlibCompressor{}
+	c.w = zlib.NewWriter(&c.buf)
+	return c
+}
+
+// compress returns the compressed form of payload. The result is only valid
+// until the next call.
+func (c *zlibCompressor) compress(payload []byte) ([]byte, error) {
+	c.buf.Reset()
+	if _, err := c.w.Write(payload); err != nil {
+		return nil, err
+	}
+	if err := c.w.Flush(); err != nil {
+		return nil, err
+	}
+	return c.buf.Bytes(), nil
+}
+
+// maxHistory is the size of the deflate window.
+const maxHistory = 1 << 15
+
+var errShortInput = errors.New("ssh: short zlib input")
+
+const (
+	inflateHeader = iota
+	inflateStored
+	inflateHuffman
+)
+
+// zlibDecompressor decompresses the payloads of the packets received in one
+// direction. Unlike compress/zlib, it keeps its state when a packet ends in
+// the middle of a block, which is what OpenSSH's Z_PARTIAL_FLUSH produces.
+type zlibDecompressor struct {
+	// in holds the compressed input that has not been consumed yet, and
+	// pos is the offset in bits of the next unread bit in in.
+	in  []byte
+	pos int
+
+	headerDone bool
+	state      int
+	final      bool
+	stored     int
+	lit, dist  *huffman
+
+	// hist contains the decompressed data, of which at least the last
+	// maxHistory bytes are kept across packets for back-references.
+	hist []byte
+}
+
+func newZlibDecompressor() *zlibDecompressor {
+	return &zlibDecompressor{}
+}
+
+// decompress returns the data decompressed from payload. It consumes as
+// much of payload as possible, and keeps any incomplete trailing symbol for
+// the next call.
+func (d *zlibDecompressor) decompress(payload []byte) ([]byte, error) {
+	d.in = append(d.in[d.pos/8:], payload...)
+	d.pos %= 8
+
+	start := len(d.hist)
+	for {
+		saved := d.pos
+		err := d.step()
+		if err == errShortInput {
+			d.pos = saved
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(d.hist)-start > maxPacket {
+			return nil, errors.New("ssh: decompressed packet too large")
+		}
+	}
+
+	out := make([]byte, len(d.hist)-start)
+	copy(out, d.hist[start:])
+
+	if n := len(d.hist) - maxHistory; n > 0 {
+		d.hist = append(d.hist[:0], d.hist[n:]...)
+	}
+	return out, nil
+}
+
+// step decodes a single unit of input: the stream header, a block header,
+// a run of stored bytes or a Huffman-coded symbol. It returns errShortInput
+// if the input ends before the unit is complete.
+func (d *zlibDecompressor) step() error {
+	if !d.headerDone {
+		cmf, err := d.bits(8)
+		if err != nil {
+			return err
+		}
+		flg, err := d.bits(8)
+		if err != nil {
+			return err
+		}
+		if cmf&0x0f != 8 || (cmf<<8|flg)%31 != 0 || flg&0x20 != 0 {
+			return errors.New("ssh: invalid zlib header")
+		}
+		d.headerDone = true
+		return nil
+	}
+
+	switch d.state {
+	case inflateHeader:
+		if d.final {
+			// The stream is never finished in SSH.
+			if d.pos < 8*len(d.in) {
+				return errors.New("ssh: data after end of zlib stream")
+			}
+			return errShortInput
+		}
+		return d.blockHeader()
+
+	case inflateStored:
+		if d.stored == 0 {
+			d.state = inflateHeader
+			return nil
+		}
+		avail := len(d.in) - d.pos/8
+		if avail == 0 {
+			return errShortInput
+		}
+		if avail > d.stored {
+			avail = d.stored
+		}
+		off := d.pos / 8
+		d.hist = append(d.hist, d.in[off:off+avail]...)
+		d.pos += 8 * avail
+		d.stored -= avail
+		return nil
+
+	case inflateHuffman:
+		return d.symbol()
+	}
+	panic("unreachable")
+}
+
+func (d *zlibDecompressor) blockHeader() error {
+	final, err := d.bits(1)
+	if err != nil {
+		return err
+	}
+	typ, err := d.bits(2)
+	if err != nil {
+		return err
+	}
+
+	switch typ {
+	case 0:
+		// Skip to the byte boundary.
+		if rem := d.pos % 8; rem != 0 {
+			d.pos += 8 - rem
+		}
+		n, err := d.bits(16)
+		if err != nil {
+			return err
+		}
+		nn, err := d.bits(16)
+		if err != nil {
+			return err
+		}
+		if n != ^nn&0xffff {
+			return errors.New("ssh: invalid zlib stored block length")
+		}
+		d.stored = n
+		d.state = inflateStored
+	case 1:
+		d.lit, d.dist = fixedLiteralCodes, fixedDistanceCodes
+		d.state = inflateHuffman
+	case 2:
+		if err := d.dynamicCodes(); err != nil {
+			return err
+		}
+		d.state = inflateHuffman
+	default:
+		return errors.New("ssh: invalid zlib block type")
+	}
+	d.final = final == 1
+	return nil
+}
+
+// codeLengthOrder is the order of the code length code lengths in a dynamic
+// block header, as specified in RFC 1951, section 3.2.7.
+var codeLengthOrder = [19]int{16, 17, 18, 0, 8, 7, 9, 6, 10, 5, 11, 4, 12, 3, 13, 2, 14, 1, 15}
+
+func (d *zlibDecompressor) dynamicCodes() error {
+	nlen, err := d.bits(5)
+	if err != nil {
+		return err
+	}
+	ndist, err := d.bits(5)
+	if err != nil {
+		return err
+	}
+	ncode, err := d.bits(4)
+	if err != nil {
+		return err
+	}
+	nlen += 257
+	ndist++
+	ncode += 4
+	if nlen > 286 || ndist > 30 {
+		return errors.New("ssh: invalid zlib dynamic block header")
+	}
+
+	var lengths [286 + 30]uint8
+	for i := 0; i < ncode; i++ {
+		l, err := d.bits(3)
+		if err != nil {
+			return err
+		}
+		lengths[codeLengthOrder[i]] = uint8(l)
+	}
+	lencode, err := newHuffman(lengths[:19])
+	if err != nil {
+		return err
+	}
+
+	lengths = [286 + 30]uint8{}
+	for i := 0; i < nlen+ndist; {
+		sym, err := d.decode(lencode)
+		if err != nil {
+			return err
+		}
+		if sym < 16 {
+			lengths[i] = uint8(sym)
+			i++
+			continue
+		}
+
+		var prev uint8
+		var rep int
+		switch sym {
+		case 16:
+			if i == 0 {
+				return errors.New("ssh: invalid zlib code length repeat")
+			}
+			prev = lengths[i-1]
+			rep, err = d.bits(2)
+			rep += 3
+		case 17:
+			rep, err = d.bits(3)
+			rep += 3
+		default:
+			rep, err = d.bits(7)
+			rep += 11
+		}
+		if err != nil {
+			return err
+		}
+		if i+rep > nlen+ndist {
+			return errors.New("ssh: invalid zlib code length repeat")
+		}
+		for ; rep > 0; rep-- {
+			lengths[i] = prev
+			i++
+		}
+	}
+
+	if lengths[256] == 0 {
+		return errors.New("ssh: zlib block has no end code")
+	}
+	if d.lit, err = newHuffman(lengths[:nlen]); err != nil {
+		return err
+	}
+	d.dist, err = newHuffman(lengths[nlen : nlen+ndist])
+	return err
+}
+
+// Base values and extra bits of the length and distance codes, as specified
+// in RFC 1951, section 3.2.5.
+var (
+	lengthBase  = [29]int{3, 4, 5, 6, 7, 8, 9, 10, 11, 13, 15, 17, 19, 23, 27, 31, 35, 43, 51, 59, 67, 83, 99, 115, 131, 163, 195, 227, 258}
+	lengthExtra = [29]uint{0, 0, 0, 0, 0, 0, 0, 0, 1, 1, 1, 1, 2, 2, 2, 2, 3, 3, 3, 3, 4, 4, 4, 4, 5, 5, 5, 5, 0}
+	distBase    = [30]int{1, 2, 3, 4, 5, 7, 9, 13, 17, 25, 33, 49, 65, 97, 129, 193, 257, 385, 513, 769, 1025, 1537, 2049, 3073, 4097, 6145, 8193, 12289, 16385, 24577}
+	distExtra   = [30]uint{0, 0, 0, 0, 1, 1, 2, 2, 3, 3, 4, 4, 5, 5, 6, 6, 7, 7, 8, 8, 9, 9, 10, 10, 11, 11, 12, 12, 13, 13}
+)
+
+func (d *zlibDecompressor) symbol() error {
+	sym, err := d.decode(d.lit)
+	if err != nil {
+		return err
+	}
+	switch {
+	case sym < 256:
+		d.hist = append(d.hist, byte(sym))
+		return nil
+	case sym == 256:
+		d.state = inflateHeader
+		return nil
+	case sym > 285:
+		return errors.New("ssh: invalid zlib length code")
+	}
+
+	sym -= 257
+	extra, err := d.bits(lengthExtra[sym])
+	if err != nil {
+		return err
+	}
+	length := lengthBase[sym] + extra
+
+	dsym, err := d.decode(d.dist)
+	if err != nil {
+		return err
+	}
+	if dsym > 29 {
+		return errors.New("ssh: invalid zlib distance code")
+	}
+	extra, err = d.bits(distExtra[dsym])
+	if err != nil {
+		return err
+	}
+	dist := distBase[dsym] + extra
+	if dist > len(d.hist) {
+		return errors.New("ssh: invalid zlib distance")
+	}
+
+	// The source and destination may overlap, so copy byte by byte.
+	from := len(d.hist) - dist
+	for i := 0; i < length; i++ {
+		d.hist = append(d.hist, d.hist[from+i])
+	}
+	return nil
+}
+
+// bits reads n bits, least significant bit first.
+func (d *zlibDecompressor) bits(n uint) (int, error) {
+	if d.pos+int(n) > 8*len(d.in) {
+		return 0, errShortInput
+	}
+	v := 0
+	for i := 0; i < int(n); i++ {
+		p := d.pos + i
+		v |= int(d.in[p/8]>>(p%8)&1) << i
+	}
+	d.pos += int(n)
+	return v, nil
+}
+
+// huffman is a canonical Huffman code, represented by the number of codes
+// of each length and the symbols ordered by code.
+type huffman struct {
+	count  [16]int
+	symbol []int
+}
+
+func newHuffman(lengths []uint8) (*huffman, error) {
+	h := &huffman{}
+	for _, l := range lengths {
+		h.count[l]++
+	}
+	if h.count[0] == len(lengths) {
+		// No codes at all. This is fine as long as they are not used.
+		return h, nil
+	}
+
+	// Reject over-subscribed codes. Incomplete codes are allowed, and
+	// reading an unassigned code is an error.
+	left := 1
+	for l := 1; l < 16; l++ {
+		left <<= 1
+		left -= h.count[l]
+		if left < 0 {
+			return nil, errors.New("ssh: invalid zlib Huffman code")
+		}
+	}
+
+	var offs [16]int
+	for l := 1; l < 15; l++ {
+		offs[l+1] = offs[l] + h.count[l]
+	}
+	h.symbol = make([]int, offs[15]+h.count[15])
+	for sym, l := range lengths {
+		if l != 0 {
+			h.symbol[offs[l]] = sym
+			offs[l]++
+		}
+	}
+	return h, nil
+}
+
+// decode reads one symbol coded with h.
+func (d *zlibDecompressor) decode(h *huffman) (int, error) {
+	code, first, index := 0, 0, 0
+	for l := 1; l < 16; l++ {
+		b, err := d.bits(1)
+		if err != nil {
+			return 0, err
+		}
+		code |= b
+		count := h.count[l]
+		if code-first < count {
+			return h.symbol[index+code-first], nil
+		}
+		index += count
+		first += count
+		first <<= 1
+		code <<= 1
+	}
+	return 0, errors.New("ssh: invalid zlib code")
+}
+
+var fixedLiteralCodes, fixedDistanceCodes = fixedCodes()
+
+// fixedCodes returns the fixed Huffman codes of RFC 1951, section 3.2.6.
+func fixedCodes() (lit, dist *huffman) {
+	var lengths [288]uint8
+	for i := range lengths {
+		switch {
+		case i < 144:
+			lengths[i] = 8
+		case i < 256:
+			lengths[i] = 9
+		case i < 280:
+			lengths[i] = 7
+		default:
+			lengths[i] = 8
+		}
+	}
+	lit, _ = newHuffman(lengths[:])
+
+	var dlengths [30]uint8
+	for i := range dlengths {
+		dlengths[i] = 5
+	}
+	dist, _ = newHuffman(dlengths[:])
+	return lit, dist
+}
@@ -0,0 +1,283 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"encoding/hex"
+	"errors"
+	"io"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+)
+
+func compressionTestData(n int) []byte {
+	r := rand.New(rand.NewSource(1))
+	words := []string{"ssh", "channel", "window", "packet", "zlib", "\n", " "}
+	var b bytes.Buffer
+	for b.Len() < n {
+		if r.Intn(10) == 0 {
+			b.WriteByte(byte(r.Intn(256)))
+		} else {
+			b.WriteString(words[r.Intn(len(words))])
+		}
+	}
+	return b.Bytes()[:n]
+}
+
+func TestZlibRoundTrip(t *testing.T) {
+	c := newZlibCompressor()
+	d := newZlibDecompressor()
+	data := compressionTestData(1 << 20)
+	for i, n := 0, 1; i < len(data); i, n = i+n, n*3%40000+1 {
+		if i+n > len(data) {
+			n = len(data) - i
+		}
+		want := data[i : i+n]
+		compressed, err := c.compress(want)
+		if err != nil {
+			t.Fatalf("compress: %v", err)
+		}
+		got, err := d.decompress(compressed)
+		if err != nil {
+			t.Fatalf("decompress: %v", err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("packet at offset %d: got %d bytes, want %d", i, len(got), len(want))
+		}
+	}
+}
+
+// TestZlibDecompressSplit checks that the decompressor picks up where it
+// left off when the input ends in the middle of a block, as it does with
+// OpenSSH's partial flushes.
+func TestZlibDecompressSplit(t *testing.T) {
+	data := compressionTestData(200000)
+	for _, level := range []int{zlib.NoCompression, zlib.BestSpeed, zlib.DefaultCompression, zlib.HuffmanOnly} {
+		var b bytes.Buffer
+		w, err := zlib.NewWriterLevel(&b, level)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(data)
+		w.Flush()
+
+		d := newZlibDecompressor()
+		var got []byte
+		compressed := b.Bytes()
+		for len(compressed) > 0 {
+			n := 7
+			if n > len(compressed) {
+				n = len(compressed)
+			}
+			out, err := d.decompress(compressed[:n])
+			compressed = compressed[n:]
+			if err != nil {
+				t.Fatalf("level %d: decompress: %v", level, err)
+			}
+			got = append(got, out...)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("level %d: got %d bytes, want %d", level, len(got), len(data))
+		}
+	}
+}
+
+// TestZlibDecompressOpenSSH decompresses packets sent by OpenSSH.
+func TestZlibDecompressOpenSSH(t *testing.T) {
+	data, err := os.ReadFile("testdata/openssh-zlib.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := newZlibDecompressor()
+	var in []byte
+	n := 0
+	for _, line := range strings.Split(string(data), "\n") {
+		name, value, ok := strings.Cut(line, "=")
+		if !ok || strings.HasPrefix(line, "#") {
+			continue
+		}
+		b, err := hex.DecodeString(value)
+		if err != nil {
+			t.Fatalf("bad line %q: %v", line, err)
+		}
+		switch name {
+		case "in":
+			in = b
+		case "out":
+			got, err := d.decompress(in)
+			if err != nil {
+				t.Fatalf("packet %d: decompress: %v", n, err)
+			}
+			if !bytes.Equal(got, b) {
+				t.Errorf("packet %d: got %x, want %x", n, got, b)
+			}
+			n++
+		}
+	}
+	if n == 0 {
+		t.Fatal("no packets in the test data")
+	}
+}
+
+// FuzzInflate checks that the decompressor inflates what compress/flate
+// deflates, at any level and however the input is split into packets.
+func FuzzInflate(f *testing.F) {
+	f.Add(compressionTestData(1000), 6, 100, 7)
+	f.Add([]byte("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"), 9, 3, 1)
+	f.Add([]byte{}, 0, 0, 1)
+	f.Fuzz(func(t *testing.T, data []byte, level, flushAt, chunk int) {
+		level = level%(flate.BestCompression-flate.HuffmanOnly+1) + flate.HuffmanOnly
+		if level < flate.HuffmanOnly {
+			level += flate.BestCompression - flate.HuffmanOnly + 1
+		}
+		if flushAt < 0 || flushAt > len(data) {
+			flushAt = len(data)
+		}
+		if chunk <= 0 {
+			chunk = 1
+		}
+		var b bytes.Buffer
+		w, err := zlib.NewWriterLevel(&b, level)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(data[:flushAt])
+		w.Flush()
+		w.Write(data[flushAt:])
+		w.Flush()
+
+		d := newZlibDecompressor()
+		var got []byte
+		for compressed := b.Bytes(); len(compressed) > 0; {
+			n := chunk
+			if n > len(compressed) {
+				n = len(compressed)
+			}
+			out, err := d.decompress(compressed[:n])
+			if err != nil {
+				t.Fatalf("level %d: decompress: %v", level, err)
+			}
+			got = append(got, out...)
+			compressed = compressed[n:]
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatalf("level %d: got %d bytes, want %d", level, len(got), len(data))
+		}
+
+		// Arbitrary input must not make the decompressor panic.
+		newZlibDecompressor().decompress(data)
+	})
+}
+
+func TestZlibDecompressTooLarge(t *testing.T) {
+	c := newZlibCompressor()
+	compressed, err := c.compress(make([]byte, maxPacket+1))
+	if err != nil {
+		t.Fatalf("compress: %v", err)
+	}
+	_, err = newZlibDecompressor().decompress(compressed)
+	if err == nil || !strings.Contains(err.Error(), "large") {
+		t.Errorf("got %v, want error about a large packet", err)
+	}
+}
+
+func TestZlibDecompressInvalid(t *testing.T) {
+	for _, in := range [][]byte{
+		{0x78, 0x00},                   // bad header checksum
+		{0x78, 0x9c, 0x07},             // reserved block type
+		{0x78, 0x9c, 0x00, 0, 0, 0, 0}, // stored block with bad length
+	} {
+		d := newZlibDecompressor()
+		if _, err := d.decompress(in); err == nil {
+			t.Errorf("decompress(%x) succeeded", in)
+		}
+	}
+}
+
+func TestCompressionHandshake(t *testing.T) {
+	for _, algo := range []string{compressionZlib, compressionZlibOpenSSH} {
+		t.Run(algo, func(t *testing.T) {
+			c1, c2, err := netPipe()
+			if err != nil {
+				t.Fatalf("netPipe: %v", err)
+			}
+			defer c1.Close()
+			defer c2.Close()
+
+			serverConf := &ServerConfig{
+				Config:       Config{Compressions: []string{algo}},
+				NoClientAuth: true,
+			}
+			serverConf.AddHostKey(testSigners["ecdsa"])
+			done := make(chan error, 1)
+			go func() {
+				_, chans, reqs, err := NewServerConn(c2, serverConf)
+				if err != nil {
+					done <- err
+					return
+				}
+				go DiscardRequests(reqs)
+				newCh, ok := <-chans
+				if !ok {
+					done <- errors.New("no incoming channel")
+					return
+				}
+				ch, reqs, err := newCh.Accept()
+				if err != nil {
+					done <- err
+					return
+				}
+				go DiscardRequests(reqs)
+				_, err = io.Copy(ch, ch)
+				ch.Close()
+				done <- err
+			}()
+
+			clientConf := &ClientConfig{
+				Config:          Config{Compressions: []string{compressionNone, algo}},
+				User:            "user",
+				HostKeyCallback: InsecureIgnoreHostKey(),
+			}
+			conn, _, reqs, err := NewClientConn(c1, "", clientConf)
+			if err != nil {
+				t.Fatalf("NewClientConn: %v", err)
+			}
+			go DiscardRequests(reqs)
+			defer conn.Close()
+
+			ch, reqs, err := conn.OpenChannel("echo", nil)
+			if err != nil {
+				t.Fatalf("OpenChannel: %v", err)
+			}
+			go DiscardRequests(reqs)
+
+			data := compressionTestData(1 << 20)
+			go func() {
+				ch.Write(data)
+				ch.CloseWrite()
+			}()
+			got, err := io.ReadAll(ch)
+			if err != nil {
+				t.Fatalf("ReadAll: %v", err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("echoed %d bytes, want %d bytes", len(got), len(data))
+			}
+			if err := <-done; err != nil {
+				t.Errorf("server: %v", err)
+			}
+
+			tr := conn.(*connection).transport.conn.(*transport)
+			if tr.writer.compression != algo || tr.writer.compressor == nil {
+				t.Errorf("client writer uses compression %q, started: %t; want %q, started", tr.writer.compression, tr.writer.compressor != nil, algo)
+			}
+		})
+	}
+}
@@ -5,6 +5,8 @@
 package ssh
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net"
 )
@@ -43,6 +45,124 @@ type ConnMetadata interface {
 	LocalAddr() net.Addr
 }
 
+// ExtInfoConnMetadata is a ConnMetadata that also reports the RFC 8308
+// extensions advertised by the peer. The ConnMetadata passed to server
+// callbacks, and the Conn returned by NewClientConn and NewServerConn,
+// implement it.
+type ExtInfoConnMetadata interface {
+	ConnMetadata
+
+	// PeerExtInfo returns the extensions, keyed by name, that the peer
+	// advertised in SSH_MSG_EXT_INFO messages so far. Servers see the
+	// extensions sent by clients before authentication starts, and clients
+	// see the extensions sent by servers once authentication completes.
+	PeerExtInfo() map[string][]byte
+}
+
+// DirectionAlgorithms holds the algorithms negotiated for one direction of
+// a connection.
+type DirectionAlgorithms struct {
+	Cipher string
+
+	// MAC is empty if the cipher authenticates the packets itself, as the
+	// AES-GCM and ChaCha20-Poly1305 ciphers do.
+	MAC string
+
+	Compression string
+}
+
+// NegotiatedAlgorithms holds the algorithms negotiated in the most recent
+// key exchange of a connection. Read and Write are the algorithms for
+// packets received and sent by the local side, respectively.
+type NegotiatedAlgorithms struct {
+	KeyExchange string
+	HostKey     string
+	Read        DirectionAlgorithms
+	Write       DirectionAlgorithms
+}
+
+// AlgorithmsConnMetadata is a ConnMetadata that also reports the
+// negotiated algorithms. The ConnMetadata passed to server callbacks, and
+// the Conn returned by NewClientConn and NewServerConn, implement it.
+type AlgorithmsConnMetadata interface {
+	ConnMetadata
+
+	// Algorithms returns the algorithms negotiated in the most recent key
+	// exchange.
+	Algorithms() NegotiatedAlgorithms
+}
+
+// diagnosticSender is implemented by connections that support SendIgnore
+// and SendDebug.
+type diagnosticSender interface {
+	SendIgnore(data []byte) error
+	SendDebug(alwaysDisplay bool, message, language string) error
+}
+
+// errNoDiagnostics is returned by SendIgnore and SendDebug if the
+// underlying Conn does not support sending transport messages.
+var errNoDiagnostics = errors.New("ssh: connection does not support sending transport messages")
+
+// rekeyer is implemented by connections that support ForceRekey.
+type rekeyer interface {
+	ForceRekey() error
+}
+
+// contextConn is implemented by connections that support
+// SendRequestContext and OpenChannelContext.
+type contextConn interface {
+	SendRequestContext(ctx context.Context, name string, wantReply bool, payload []byte) (bool, []byte, error)
+	OpenChannelContext(ctx context.Context, name string, data []byte) (Channel, <-chan *Request, error)
+}
+
+// sendRequestContext calls conn.SendRequestContext if conn implements
+// contextConn, and otherwise conn.SendRequest after checking ctx.
+func sendRequestContext(ctx context.Context, conn Conn, name string, wantReply bool, payload []byte) (bool, []byte, error) {
+	if c, ok := conn.(contextConn); ok {
+		return c.SendRequestContext(ctx, name, wantReply, payload)
+	}
+	if err := ctx.Err(); err != nil {
+		return false, nil, err
+	}
+	return conn.SendRequest(name, wantReply, payload)
+}
+
+// openChannelContext calls conn.OpenChannelContext if conn implements
+// contextConn, and otherwise conn.OpenChannel after checking ctx.
+func openChannelContext(ctx context.Context, conn Conn, name string, data []byte) (Channel, <-chan *Request, error) {
+	if c, ok := conn.(contextConn); ok {
+		return c.OpenChannelContext(ctx, name, data)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	return conn.OpenChannel(name, data)
+}
+
+// asyncOpener is implemented by connections that support
+// OpenChannelAsync.
+type asyncOpener interface {
+	OpenChannelAsync(ctx context.Context, name string, data []byte) *PendingChannel
+}
+
+// openChannelAsync calls conn.OpenChannelAsync if conn implements
+// asyncOpener, and otherwise openChannelContext in a new goroutine.
+func openChannelAsync(ctx context.Context, conn Conn, name string, data []byte) *PendingChannel {
+	if c, ok := conn.(asyncOpener); ok {
+		return c.OpenChannelAsync(ctx, name, data)
+	}
+	p := &PendingChannel{done: make(chan struct{})}
+	go func() {
+		p.ch, p.reqs, p.err = openChannelContext(ctx, conn, name, data)
+		close(p.done)
+	}()
+	return p
+}
+
+// errNoRekey is returned by ForceRekey if the underlying Conn does not
+// support starting a key exchange.
+var errNoRekey = errors.New("ssh: connection does not support rekeying")
+
 // Conn represents an SSH connection for both server and client roles.
 // Conn is the basis for implementing an application layer, such
 // as ClientConn, which implements the traditional shell access for
@@ -91,12 +211,53 @@ type connection struct {
 
 	// The connection protocol.
 	*mux
+
+	// hostKeysCallback is set on clients from ClientConfig.HostKeysCallback.
+	hostKeysCallback HostKeysCallback
+
+	// hostKeysHandler is set on clients from ClientConfig.HostKeysHandler.
+	hostKeysHandler HostKeysHandler
+
+	// sessionHostKey and sessionSignature are the host key and its
+	// signature of the session ID from the first key exchange, on clients.
+	sessionHostKey   PublicKey
+	sessionSignature []byte
+
+	// banner is the concatenation of the banners that the server sent
+	// during authentication, on clients.
+	banner string
 }
 
 func (c *connection) Close() error {
 	return c.sshConn.conn.Close()
 }
 
+func (c *connection) Algorithms() NegotiatedAlgorithms {
+	return c.transport.getAlgorithms()
+}
+
+func (c *connection) SendIgnore(data []byte) error {
+	return c.transport.writePacket(Marshal(&ignoreMsg{Data: data}))
+}
+
+func (c *connection) SendDebug(alwaysDisplay bool, message, language string) error {
+	return c.transport.writePacket(Marshal(&debugMsg{
+		AlwaysDisplay: alwaysDisplay,
+		Message:       message,
+		Language:      language,
+	}))
+}
+
+// ForceRekey starts a new key exchange, unless one is already in progress.
+// It does not wait for the key exchange to complete.
+func (c *connection) ForceRekey() error {
+	if err := c.transport.getWriteError(); err != nil {
+		return err
+	}
+	c.transport.requestKeyExchange()
+	return nil
+}
+
 // sshConn provides net.Conn metadata, but disallows direct reads and
 // writes.
 type sshConn struct {
@@ -106,6 +267,10 @@ type sshConn struct {
 	sessionID     []byte
 	clientVersion []byte
 	serverVersion []byte
+
+	// peerExtInfo is shared with the handshakeTransport, which records
+	// the extensions advertised by the peer.
+	peerExtInfo map[string][]byte
 }
 
 func dup(src []byte) []byte {
@@ -134,6 +299,14 @@ func (c *sshConn) SessionID() []byte {
 	return dup(c.sessionID)
 }
 
+func (c *sshConn) PeerExtInfo() map[string][]byte {
+	extensions := make(map[string][]byte, len(c.peerExtInfo))
+	for name, value := range c.peerExtInfo {
+		extensions[name] = dup(value)
+	}
+	return extensions
+}
+
 func (c *sshConn) ClientVersion() []byte {
 	return dup(c.clientVersion)
 }
@@ -0,0 +1,160 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+)
+
+// defaultFallbackDelay is the delay between connection attempts recommended
+// by RFC 8305, section 5.
+const defaultFallbackDelay = 250 * time.Millisecond
+
+// Dialer connects to an SSH server that has several addresses, such as the
+// IPv4 and IPv6 addresses of a dual-stack host, racing connection attempts
+// to them as described in RFC 8305 ("Happy Eyeballs"), and performing the
+// SSH handshake on the first connection established.
+type Dialer struct {
+	// FallbackDelay is how long to wait for a connection attempt before
+	// starting the next one in parallel. An attempt that fails also
+	// starts the next one immediately. If zero, 250ms is used. If
+	// negative, the addresses are tried one after the other.
+	FallbackDelay time.Duration
+}
+
+// Dial is like DialContext with context.Background.
+func (d *Dialer) Dial(network, hostname string, addrs []string, config *ClientConfig) (*Client, error) {
+	return d.DialContext(context.Background(), network, hostname, addrs, config)
+}
+
+// DialContext connects to the SSH server at addrs, which are tried
+// alternating between IPv6 and IPv4, starting with the family of the first
+// one. Each TCP connection is made like with DialContext, honoring
+// config.Timeout and config.ProxyDialer. hostname, typically the host and
+// port that addrs were resolved from, is passed to config.HostKeyCallback;
+// if empty, the address connected to is passed instead. ctx bounds the
+// connection attempts and the handshake.
+func (d *Dialer) DialContext(ctx context.Context, network, hostname string, addrs []string, config *ClientConfig) (*Client, error) {
+	if len(addrs) == 0 {
+		return nil, errors.New("ssh: no address to dial")
+	}
+	conn, addr, err := d.race(ctx, network, interleaveAddrs(addrs), config)
+	if err != nil {
+		return nil, err
+	}
+	if hostname == "" {
+		hostname = addr
+	}
+	c, chans, reqs, err := NewClientConnContext(ctx, conn, hostname, config)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(c, chans, reqs), nil
+}
+
+type raceResult struct {
+	conn net.Conn
+	addr string
+	err  error
+}
+
+// race connects to addrs in order, starting an attempt whenever the
+// previous one failed or has not finished within the fallback delay, and
+// returns the first connection established. The other attempts are
+// canceled, and the connections that they establish anyway closed. If all
+// attempts fail, the error of the first one is returned.
+func (d *Dialer) race(ctx context.Context, network string, addrs []string, config *ClientConfig) (net.Conn, string, error) {
+	delay := d.FallbackDelay
+	if delay == 0 {
+		delay = defaultFallbackDelay
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan raceResult)
+	next, pending := 0, 0
+	var fallback <-chan time.Time
+	start := func() {
+		addr := addrs[next]
+		next++
+		pending++
+		go func() {
+			conn, err := dialContext(ctx, network, addr, config)
+			results <- raceResult{conn, addr, err}
+		}()
+		fallback = nil
+		if delay > 0 && next < len(addrs) {
+			fallback = time.After(delay)
+		}
+	}
+
+	start()
+	var firstErr error
+	for pending > 0 {
+		select {
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				go func(pending int) {
+					for ; pending > 0; pending-- {
+						if r := <-results; r.conn != nil {
+							r.conn.Close()
+						}
+					}
+				}(pending)
+				return r.conn, r.addr, nil
+			}
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			if next < len(addrs) {
+				start()
+			}
+		case <-fallback:
+			start()
+		}
+	}
+	return nil, "", firstErr
+}
+
+// interleaveAddrs orders addrs alternating between IPv6 and other
+// addresses, starting with the family of the first one, and otherwise
+// keeping their order, see RFC 8305, section 4.
+func interleaveAddrs(addrs []string) []string {
+	var v6, other []string
+	for _, addr := range addrs {
+		if isIPv6Addr(addr) {
+			v6 = append(v6, addr)
+		} else {
+			other = append(other, addr)
+		}
+	}
+	first, second := other, v6
+	if isIPv6Addr(addrs[0]) {
+		first, second = v6, other
+	}
+	result := make([]string, 0, len(addrs))
+	for i := 0; i < len(first) || i < len(second); i++ {
+		if i < len(first) {
+			result = append(result, first[i])
+		}
+		if i < len(second) {
+			result = append(result, second[i])
+		}
+	}
+	return result
+}
+
+func isIPv6Addr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.To4() == nil
+}
@@ -0,0 +1,125 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInterleaveAddrs(t *testing.T) {
+	for _, tt := range []struct {
+		in, want []string
+	}{
+		{
+			in:   []string{"[::1]:22", "[::2]:22", "1.1.1.1:22", "2.2.2.2:22"},
+			want: []string{"[::1]:22", "1.1.1.1:22", "[::2]:22", "2.2.2.2:22"},
+		},
+		{
+			in:   []string{"1.1.1.1:22", "2.2.2.2:22", "[::1]:22"},
+			want: []string{"1.1.1.1:22", "[::1]:22", "2.2.2.2:22"},
+		},
+		{
+			in:   []string{"host:22", "[::1]:22"},
+			want: []string{"host:22", "[::1]:22"},
+		},
+	} {
+		if got := interleaveAddrs(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("interleaveAddrs(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// raceDialer connects to the addresses of a real listener, hangs on those
+// starting with "hang" until canceled, and fails on the others.
+type raceDialer struct {
+	canceled chan string
+}
+
+func (d *raceDialer) Dial(network, addr string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, addr)
+}
+
+func (d *raceDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	switch {
+	case strings.HasPrefix(addr, "127.0.0.1:"):
+		var nd net.Dialer
+		return nd.DialContext(ctx, network, addr)
+	case strings.HasPrefix(addr, "hang"):
+		<-ctx.Done()
+		d.canceled <- addr
+		return nil, ctx.Err()
+	}
+	return nil, errors.New("unreachable " + addr)
+}
+
+func TestDialerRace(t *testing.T) {
+	server := listenSSH(t)
+
+	for _, tt := range []struct {
+		name          string
+		fallbackDelay time.Duration
+		addrs         []string
+		wantCanceled  int
+		wantErr       string
+	}{
+		{
+			name:          "hanging first",
+			fallbackDelay: 10 * time.Millisecond,
+			addrs:         []string{"hang:22", server.Addr().String()},
+			wantCanceled:  1,
+		},
+		{
+			name:          "failing first",
+			fallbackDelay: time.Hour,
+			addrs:         []string{"fail:22", server.Addr().String()},
+		},
+		{
+			name:    "all failing",
+			addrs:   []string{"fail1:22", "fail2:22"},
+			wantErr: "unreachable fail1:22",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			pd := &raceDialer{canceled: make(chan string, len(tt.addrs))}
+			var verified string
+			config := &ClientConfig{
+				User: "user",
+				HostKeyCallback: func(hostname string, remote net.Addr, key PublicKey) error {
+					verified = hostname
+					return nil
+				},
+				ProxyDialer: pd,
+			}
+			d := &Dialer{FallbackDelay: tt.fallbackDelay}
+			client, err := d.Dial("tcp", "", tt.addrs, config)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Dial: %v", err)
+			}
+			defer client.Close()
+			if verified != server.Addr().String() {
+				t.Errorf("got hostname %q, want %q", verified, server.Addr())
+			}
+			for i := 0; i < tt.wantCanceled; i++ {
+				select {
+				case <-pd.canceled:
+				case <-time.After(10 * time.Second):
+					t.Fatal("hanging attempt was not canceled")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+)
+
+// The errors in this file are returned, wrapped, by NewClientConn and Dial
+// so that callers can tell why a connection could not be established with
+// errors.As.
+
+// HostKeyError is returned if HostKeyCallback rejected the host key of the
+// server. Err is the error of the callback, such as a *knownhosts.KeyError.
+type HostKeyError struct {
+	Hostname string
+	Remote   net.Addr
+	Key      PublicKey
+	Err      error
+}
+
+func (e *HostKeyError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *HostKeyError) Unwrap() error {
+	return e.Err
+}
+
+// AlgorithmNegotiationError is returned if the client and the server have
+// no algorithm in common for some purpose during the key exchange.
+type AlgorithmNegotiationError struct {
+	// What is the purpose of the algorithm, such as "key exchange", "host
+	// key" or "client to server cipher".
+	What string
+
+	Client []string
+	Server []string
+}
+
+func (e *AlgorithmNegotiationError) Error() string {
+	return fmt.Sprintf("ssh: no common algorithm for %s; client offered: %v, server offered: %v", e.What, e.Client, e.Server)
+}
+
+// DisconnectError is returned if the server disconnected before the
+// connection was established, typically during authentication after sending
+// a banner that explains why.
+type DisconnectError struct {
+	// Reason is the disconnect reason code, see RFC 4253, section 11.1.
+	Reason  uint32
+	Message string
+
+	// Banner is the concatenation of the banner messages that the server
+	// sent during authentication, if any.
+	Banner string
+}
+
+func (e *DisconnectError) Error() string {
+	return fmt.Sprintf("ssh: disconnect, reason %d: %s", e.Reason, e.Message)
+}
+
+// ClientAuthError is returned if the client could not authenticate.
+type ClientAuthError struct {
+	// Tried lists the authentication methods that failed.
+	Tried []string
+
+	// Remaining lists the methods that the server last listed as able to
+	// continue, none of which is in ClientConfig.Auth or left to try.
+	Remaining []string
+
+	// Err is the error of the last method tried, if it failed with one
+	// rather than being refused by the server.
+	Err error
+}
+
+func (e *ClientAuthError) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("ssh: unable to authenticate, attempted methods %v, no supported methods remain", e.Tried)
+}
+
+func (e *ClientAuthError) Unwrap() error {
+	return e.Err
+}
+
+// NetworkError is returned if the underlying connection failed or was
+// closed by the server. Err is the error of the connection, such as io.EOF
+// or a *net.OpError.
+type NetworkError struct {
+	Err error
+}
+
+func (e *NetworkError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *NetworkError) Unwrap() error {
+	return e.Err
+}
+
+// clientHandshakeError returns err, a failure of the client handshake, as
+// one of the error types above where possible. banner holds the banners
+// received from the server.
+func clientHandshakeError(err error, banner string) error {
+	var disconnect *disconnectMsg
+	if errors.As(err, &disconnect) {
+		return &DisconnectError{
+			Reason:  disconnect.Reason,
+			Message: disconnect.Message,
+			Banner:  banner,
+		}
+	}
+	var netErr net.Error
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, net.ErrClosed) || errors.As(err, &netErr) {
+		return &NetworkError{Err: err}
+	}
+	return err
+}
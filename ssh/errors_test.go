@@ -0,0 +1,128 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"errors"
+	"io"
+	"net"
+	"testing"
+)
+
+func clientConnError(t *testing.T, serverConfig *ServerConfig, clientConfig *ClientConfig) error {
+	c1, c2, err := netPipe()
+	if err != nil {
+		t.Fatalf("netPipe: %v", err)
+	}
+	defer c1.Close()
+	defer c2.Close()
+
+	if serverConfig == nil {
+		c1.Close()
+	} else {
+		serverConfig.AddHostKey(testSigners["rsa"])
+		go newServer(c1, serverConfig)
+	}
+	conn, _, _, err := NewClientConn(c2, "", clientConfig)
+	if err == nil {
+		conn.Close()
+		t.Fatal("NewClientConn succeeded")
+	}
+	return err
+}
+
+func passwordServerConfig() *ServerConfig {
+	return &ServerConfig{
+		PasswordCallback: func(conn ConnMetadata, pass []byte) (*Permissions, error) {
+			return nil, errors.New("password auth failed")
+		},
+	}
+}
+
+func TestHostKeyError(t *testing.T) {
+	errRejected := errors.New("rejected")
+	err := clientConnError(t, passwordServerConfig(), &ClientConfig{
+		HostKeyCallback: func(hostname string, remote net.Addr, key PublicKey) error {
+			return errRejected
+		},
+	})
+	var hostKeyErr *HostKeyError
+	if !errors.As(err, &hostKeyErr) {
+		t.Fatalf("got %v, want a HostKeyError", err)
+	}
+	if hostKeyErr.Key.Type() != KeyAlgoRSA {
+		t.Errorf("got host key of type %q, want %q", hostKeyErr.Key.Type(), KeyAlgoRSA)
+	}
+	if !errors.Is(err, errRejected) {
+		t.Errorf("got %v, want it to wrap the callback error", err)
+	}
+}
+
+func TestAlgorithmNegotiationError(t *testing.T) {
+	serverConfig := passwordServerConfig()
+	serverConfig.Ciphers = []string{"aes128-ctr"}
+	err := clientConnError(t, serverConfig, &ClientConfig{
+		HostKeyCallback: InsecureIgnoreHostKey(),
+		Config:          Config{Ciphers: []string{"aes256-ctr"}},
+	})
+	var algErr *AlgorithmNegotiationError
+	if !errors.As(err, &algErr) {
+		t.Fatalf("got %v, want an AlgorithmNegotiationError", err)
+	}
+	if algErr.What != "client to server cipher" || len(algErr.Client) != 1 || algErr.Client[0] != "aes256-ctr" {
+		t.Errorf("got %+v", algErr)
+	}
+}
+
+func TestDisconnectError(t *testing.T) {
+	serverConfig := passwordServerConfig()
+	serverConfig.MaxAuthTries = 1
+	serverConfig.BannerCallback = func(conn ConnMetadata) string {
+		return "account locked"
+	}
+	err := clientConnError(t, serverConfig, &ClientConfig{
+		User:            "testuser",
+		Auth:            []AuthMethod{Password("wrong")},
+		HostKeyCallback: InsecureIgnoreHostKey(),
+	})
+	var discErr *DisconnectError
+	if !errors.As(err, &discErr) {
+		t.Fatalf("got %v, want a DisconnectError", err)
+	}
+	if discErr.Banner != "account locked" {
+		t.Errorf("got banner %q, want %q", discErr.Banner, "account locked")
+	}
+}
+
+func TestClientAuthError(t *testing.T) {
+	err := clientConnError(t, passwordServerConfig(), &ClientConfig{
+		User:            "testuser",
+		Auth:            []AuthMethod{Password("wrong")},
+		HostKeyCallback: InsecureIgnoreHostKey(),
+	})
+	var authErr *ClientAuthError
+	if !errors.As(err, &authErr) {
+		t.Fatalf("got %v, want a ClientAuthError", err)
+	}
+	if !contains(authErr.Tried, "password") {
+		t.Errorf("got tried methods %q, want password", authErr.Tried)
+	}
+	if len(authErr.Remaining) != 1 || authErr.Remaining[0] != "password" {
+		t.Errorf("got remaining methods %q, want [password]", authErr.Remaining)
+	}
+}
+
+func TestNetworkError(t *testing.T) {
+	err := clientConnError(t, nil, &ClientConfig{
+		HostKeyCallback: InsecureIgnoreHostKey(),
+	})
+	var netErr *NetworkError
+	if !errors.As(err, &netErr) {
+		t.Fatalf("got %v, want a NetworkError", err)
+	}
+	if !errors.Is(err, io.EOF) {
+		t.Errorf("got %v, want it to wrap io.EOF", err)
+	}
+}
@@ -7,8 +7,10 @@ package ssh_test
 import (
 	"bufio"
 	"bytes"
+	"crypto/ed25519"
 	"crypto/rand"
 	"crypto/rsa"
+	"encoding/pem"
 	"fmt"
 	"log"
 	"net"
@@ -398,3 +400,25 @@ func ExampleCertificate_SignCert() {
 	// ssh-keygen -L -f <path to the file>
 	fmt.Println(string(ssh.MarshalAuthorizedKey(&certificate)))
 }
+
+func ExampleMarshalPrivateKey() {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		log.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(priv, "user@example.com")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile("id_ed25519", pem.EncodeToMemory(block), 0600); err != nil {
+		log.Fatal(err)
+	}
+
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile("id_ed25519.pub", ssh.MarshalAuthorizedKey(sshPub), 0644); err != nil {
+		log.Fatal(err)
+	}
+}
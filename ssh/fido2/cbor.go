@@ -0,0 +1,222 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fido2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// This file implements the subset of CBOR (RFC 8949) that CTAP2 messages
+// use, with the canonical encoding of CTAP2, section 8.
+
+const (
+	cborUint   = 0
+	cborNegInt = 1
+	cborBytes  = 2
+	cborText   = 3
+	cborArray  = 4
+	cborMap    = 5
+	cborSimple = 7
+)
+
+// cborPair is an entry of a cborPairs map.
+type cborPair struct {
+	key, value interface{}
+}
+
+// cborPairs is a map to encode, whose keys are sorted into canonical order.
+type cborPairs []cborPair
+
+// appendHead appends the initial byte and argument of a data item.
+func appendHead(b []byte, major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return append(b, major<<5|byte(n))
+	case n <= 0xff:
+		return append(b, major<<5|24, byte(n))
+	case n <= 0xffff:
+		return binary.BigEndian.AppendUint16(append(b, major<<5|25), uint16(n))
+	case n <= 0xffffffff:
+		return binary.BigEndian.AppendUint32(append(b, major<<5|26), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, major<<5|27), n)
+	}
+}
+
+// cborEncode returns the canonical encoding of v, which is made of ints,
+// strings, byte slices, bools, slices of values and cborPairs.
+func cborEncode(v interface{}) ([]byte, error) {
+	return appendCBOR(nil, v)
+}
+
+func appendCBOR(b []byte, v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case int:
+		if v < 0 {
+			return appendHead(b, cborNegInt, uint64(-1-v)), nil
+		}
+		return appendHead(b, cborUint, uint64(v)), nil
+	case uint64:
+		return appendHead(b, cborUint, v), nil
+	case []byte:
+		return append(appendHead(b, cborBytes, uint64(len(v))), v...), nil
+	case string:
+		return append(appendHead(b, cborText, uint64(len(v))), v...), nil
+	case bool:
+		if v {
+			return append(b, cborSimple<<5|21), nil
+		}
+		return append(b, cborSimple<<5|20), nil
+	case []interface{}:
+		b = appendHead(b, cborArray, uint64(len(v)))
+		for _, e := range v {
+			var err error
+			if b, err = appendCBOR(b, e); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case cborPairs:
+		type entry struct{ key, value []byte }
+		entries := make([]entry, len(v))
+		for i, p := range v {
+			k, err := cborEncode(p.key)
+			if err != nil {
+				return nil, err
+			}
+			val, err := cborEncode(p.value)
+			if err != nil {
+				return nil, err
+			}
+			entries[i] = entry{k, val}
+		}
+		// Keys are sorted by length first, then bytewise.
+		sort.Slice(entries, func(i, j int) bool {
+			ki, kj := entries[i].key, entries[j].key
+			if len(ki) != len(kj) {
+				return len(ki) < len(kj)
+			}
+			return bytes.Compare(ki, kj) < 0
+		})
+		b = appendHead(b, cborMap, uint64(len(entries)))
+		for _, e := range entries {
+			b = append(append(b, e.key...), e.value...)
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf("fido2: cannot encode %T as CBOR", v)
+	}
+}
+
+var errCBORTruncated = errors.New("fido2: truncated CBOR data")
+
+// maxCBORDepth bounds the nesting of decoded values.
+const maxCBORDepth = 16
+
+// cborDecode decodes the data item at the start of b, returning it and the
+// bytes that follow. Unsigned integers decode to uint64, negative ones to
+// int64, byte and text strings to []byte and string, arrays to
+// []interface{}, maps to map[interface{}]interface{}, and the simple
+// values to bool or nil. Floats and tags are not supported.
+func cborDecode(b []byte) (v interface{}, rest []byte, err error) {
+	return decodeCBOR(b, 0)
+}
+
+func decodeCBOR(b []byte, depth int) (interface{}, []byte, error) {
+	if depth > maxCBORDepth {
+		return nil, nil, errors.New("fido2: CBOR data nested too deeply")
+	}
+	if len(b) == 0 {
+		return nil, nil, errCBORTruncated
+	}
+	major, info := b[0]>>5, b[0]&0x1f
+	b = b[1:]
+
+	var n uint64
+	switch {
+	case info < 24:
+		n = uint64(info)
+	case info <= 27:
+		size := 1 << (info - 24)
+		if len(b) < size {
+			return nil, nil, errCBORTruncated
+		}
+		for _, c := range b[:size] {
+			n = n<<8 | uint64(c)
+		}
+		b = b[size:]
+	default:
+		return nil, nil, fmt.Errorf("fido2: unsupported CBOR item %#x", major<<5|info)
+	}
+
+	switch major {
+	case cborUint:
+		return n, b, nil
+	case cborNegInt:
+		if n > 1<<63-1 {
+			return nil, nil, errors.New("fido2: CBOR integer out of range")
+		}
+		return -1 - int64(n), b, nil
+	case cborBytes, cborText:
+		if uint64(len(b)) < n {
+			return nil, nil, errCBORTruncated
+		}
+		s := b[:n]
+		if major == cborText {
+			return string(s), b[n:], nil
+		}
+		return append([]byte(nil), s...), b[n:], nil
+	case cborArray:
+		// Each element takes at least one byte.
+		if uint64(len(b)) < n {
+			return nil, nil, errCBORTruncated
+		}
+		a := make([]interface{}, n)
+		for i := range a {
+			var err error
+			if a[i], b, err = decodeCBOR(b, depth+1); err != nil {
+				return nil, nil, err
+			}
+		}
+		return a, b, nil
+	case cborMap:
+		if uint64(len(b)) < 2*n {
+			return nil, nil, errCBORTruncated
+		}
+		m := make(map[interface{}]interface{}, n)
+		for i := uint64(0); i < n; i++ {
+			k, rest, err := decodeCBOR(b, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			switch k.(type) {
+			case uint64, int64, string:
+			default:
+				return nil, nil, fmt.Errorf("fido2: unsupported CBOR map key %T", k)
+			}
+			v, rest, err := decodeCBOR(rest, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			m[k] = v
+			b = rest
+		}
+		return m, b, nil
+	case cborSimple:
+		switch info {
+		case 20:
+			return false, b, nil
+		case 21:
+			return true, b, nil
+		case 22:
+			return nil, b, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("fido2: unsupported CBOR item %#x", major<<5|info)
+}
@@ -0,0 +1,97 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fido2
+
+import (
+	"bytes"
+	"encoding/hex"
+	"reflect"
+	"testing"
+)
+
+func TestCBOREncode(t *testing.T) {
+	for _, tt := range []struct {
+		v    interface{}
+		want string
+	}{
+		// From appendix A of RFC 8949.
+		{0, "00"},
+		{23, "17"},
+		{24, "1818"},
+		{1000, "1903e8"},
+		{1000000, "1a000f4240"},
+		{uint64(1000000000000), "1b000000e8d4a51000"},
+		{-1, "20"},
+		{-1000, "3903e7"},
+		{[]byte{1, 2, 3, 4}, "4401020304"},
+		{"IETF", "6449455446"},
+		{false, "f4"},
+		{true, "f5"},
+		{[]interface{}{1, []interface{}{2, 3}}, "8201820203"},
+		// CTAP2 canonical order: shorter keys first, then bytewise.
+		{cborPairs{{"up", true}, {"a", 1}, {10, 2}, {1, 3}}, "a40103 0a02 6161 01 627570f5"},
+	} {
+		got, err := cborEncode(tt.v)
+		if err != nil {
+			t.Errorf("cborEncode(%#v): %v", tt.v, err)
+			continue
+		}
+		want, _ := hex.DecodeString(string(bytes.ReplaceAll([]byte(tt.want), []byte(" "), nil)))
+		if !bytes.Equal(got, want) {
+			t.Errorf("cborEncode(%#v) = %x, want %x", tt.v, got, want)
+		}
+	}
+
+	if _, err := cborEncode(1.5); err == nil {
+		t.Error("cborEncode of a float succeeded")
+	}
+}
+
+func TestCBORDecode(t *testing.T) {
+	b, err := cborEncode(cborPairs{
+		{1, "example.com"},
+		{2, []byte{1, 2, 3}},
+		{-3, []interface{}{true, false, 1000000}},
+		{"k", cborPairs{{"x", 1}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b = append(b, 0xff)
+	got, rest, err := cborDecode(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[interface{}]interface{}{
+		uint64(1): "example.com",
+		uint64(2): []byte{1, 2, 3},
+		int64(-3): []interface{}{true, false, uint64(1000000)},
+		"k":       map[interface{}]interface{}{"x": uint64(1)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("cborDecode = %#v, want %#v", got, want)
+	}
+	if !bytes.Equal(rest, []byte{0xff}) {
+		t.Errorf("cborDecode left %x, want ff", rest)
+	}
+
+	for _, bad := range []string{
+		"",
+		"19 03",               // truncated argument
+		"44 0102",             // truncated byte string
+		"a1 40 01",            // byte string map key
+		"82 01",               // truncated array
+		"1f",                  // indefinite length
+		"f9 3c00",             // float
+		"c1 1a514b67b0",       // tag
+		"9b ffffffffffffffff", // huge array
+		"818181818181818181818181818181818181 00", // too deep
+	} {
+		b, _ := hex.DecodeString(string(bytes.ReplaceAll([]byte(bad), []byte(" "), nil)))
+		if _, _, err := cborDecode(b); err == nil {
+			t.Errorf("cborDecode(%s) succeeded", bad)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fido2
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// This file implements the CTAPHID transport of CTAP2, section 11.2, which
+// carries messages in HID reports of 64 bytes.
+
+const (
+	reportSize = 64
+
+	// Sizes of the data in initialization and continuation packets.
+	initDataSize = reportSize - 7
+	contDataSize = reportSize - 5
+
+	// maxMessageSize is the largest message that fits in an
+	// initialization packet and 128 continuation packets.
+	maxMessageSize = initDataSize + 128*contDataSize
+
+	broadcastCID = 0xffffffff
+
+	cmdInit      = 0x86
+	cmdCBOR      = 0x90
+	cmdKeepalive = 0xbb
+	cmdError     = 0xbf
+)
+
+// hidConn is a CTAPHID channel to a device.
+type hidConn struct {
+	rw  io.ReadWriter
+	cid uint32
+}
+
+// newHIDConn allocates a channel on the device behind rw.
+func newHIDConn(rw io.ReadWriter) (*hidConn, error) {
+	c := &hidConn{rw: rw, cid: broadcastCID}
+	nonce := make([]byte, 8)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	if err := c.send(cmdInit, nonce); err != nil {
+		return nil, err
+	}
+	for {
+		resp, err := c.recv(cmdInit)
+		if err != nil {
+			return nil, err
+		}
+		// Responses to the INIT requests of other programs can be seen
+		// on the broadcast channel.
+		if len(resp) >= 17 && bytes.Equal(resp[:8], nonce) {
+			c.cid = binary.BigEndian.Uint32(resp[8:12])
+			return c, nil
+		}
+	}
+}
+
+// send sends a message in as many packets as needed.
+func (c *hidConn) send(cmd byte, data []byte) error {
+	if len(data) > maxMessageSize {
+		return errors.New("fido2: message too long")
+	}
+	packet := make([]byte, reportSize)
+	binary.BigEndian.PutUint32(packet, c.cid)
+	packet[4] = cmd
+	binary.BigEndian.PutUint16(packet[5:], uint16(len(data)))
+	n := copy(packet[7:], data)
+	if _, err := c.rw.Write(packet); err != nil {
+		return err
+	}
+	data = data[n:]
+	for seq := byte(0); len(data) > 0; seq++ {
+		packet := make([]byte, reportSize)
+		binary.BigEndian.PutUint32(packet, c.cid)
+		packet[4] = seq
+		n := copy(packet[5:], data)
+		if _, err := c.rw.Write(packet); err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+// readPacket reads the next packet on the channel of c.
+func (c *hidConn) readPacket() ([]byte, error) {
+	for {
+		packet := make([]byte, reportSize)
+		n, err := c.rw.Read(packet)
+		if err != nil {
+			return nil, err
+		}
+		if n < 7 {
+			return nil, errors.New("fido2: short HID report")
+		}
+		if binary.BigEndian.Uint32(packet) == c.cid {
+			return packet[:n], nil
+		}
+	}
+}
+
+// recv reads the response to a cmd request, skipping keepalives sent while
+// the device waits for the user.
+func (c *hidConn) recv(cmd byte) ([]byte, error) {
+	for {
+		packet, err := c.readPacket()
+		if err != nil {
+			return nil, err
+		}
+		got := packet[4]
+		if got == cmdKeepalive {
+			continue
+		}
+		if got&0x80 == 0 {
+			return nil, errors.New("fido2: unexpected continuation packet")
+		}
+
+		size := int(binary.BigEndian.Uint16(packet[5:]))
+		if size > maxMessageSize {
+			return nil, errors.New("fido2: response too long")
+		}
+		data := make([]byte, 0, size)
+		data = append(data, packet[7:min(7+size, len(packet))]...)
+		for seq := byte(0); len(data) < size; seq++ {
+			packet, err := c.readPacket()
+			if err != nil {
+				return nil, err
+			}
+			if packet[4] != seq {
+				return nil, fmt.Errorf("fido2: got packet %#x, want continuation %d", packet[4], seq)
+			}
+			data = append(data, packet[5:min(5+size-len(data), len(packet))]...)
+		}
+
+		switch got {
+		case cmd:
+			return data, nil
+		case cmdError:
+			if len(data) > 0 {
+				return nil, fmt.Errorf("fido2: CTAPHID error %#x", data[0])
+			}
+			return nil, errors.New("fido2: CTAPHID error")
+		default:
+			return nil, fmt.Errorf("fido2: got command %#x, want %#x", got, cmd)
+		}
+	}
+}
+
+// transact sends a CTAP2 command with its CBOR encoded parameters and
+// returns the status and the CBOR encoded response.
+func (c *hidConn) transact(command byte, params []byte) (status byte, resp []byte, err error) {
+	if err := c.send(cmdCBOR, append([]byte{command}, params...)); err != nil {
+		return 0, nil, err
+	}
+	data, err := c.recv(cmdCBOR)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(data) == 0 {
+		return 0, nil, errors.New("fido2: empty CTAP2 response")
+	}
+	return data[0], data[1:], nil
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
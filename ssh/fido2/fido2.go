@@ -0,0 +1,215 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package fido2 implements an ssh.SKProvider for FIDO2 security keys
+// attached over USB HID, speaking the CTAP2 protocol of the FIDO Alliance
+// "Client to Authenticator Protocol". It plays the part of the built-in
+// security key middleware of OpenSSH.
+//
+// Only signing is supported: keys are enrolled with ssh-keygen, and the
+// resulting private key files are loaded with ssh.ParseSKPrivateKey.
+// Devices that require a PIN are not supported.
+package fido2
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// CTAP2 commands and status codes, see CTAP2, sections 6 and 8.2.
+const (
+	ctapGetAssertion = 0x02
+
+	statusOK            = 0x00
+	statusNoCredentials = 0x2e
+	statusPINRequired   = 0x36
+)
+
+// ErrNoCredentials is returned when no security key holds the credential
+// of a request.
+var ErrNoCredentials = errors.New("fido2: no security key holds the credential")
+
+// StatusError is returned when a security key fails a CTAP2 command.
+type StatusError struct {
+	Status byte
+}
+
+func (e *StatusError) Error() string {
+	if e.Status == statusPINRequired {
+		return "fido2: security key requires a PIN, which is not supported"
+	}
+	return fmt.Sprintf("fido2: security key returned CTAP2 status %#x", e.Status)
+}
+
+// A Device is a FIDO2 security key.
+type Device struct {
+	mu   sync.Mutex
+	conn *hidConn
+}
+
+// NewDevice returns a Device that exchanges 64-byte HID reports with a
+// security key through rw, allocating a CTAPHID channel. Each Read of rw
+// must return one input report, and each Write must send one output report.
+// On Linux, Open returns a suitable rw for a hidraw device.
+func NewDevice(rw io.ReadWriter) (*Device, error) {
+	conn, err := newHIDConn(rw)
+	if err != nil {
+		return nil, err
+	}
+	return &Device{conn: conn}, nil
+}
+
+// Close closes the underlying connection, if it is an io.Closer.
+func (d *Device) Close() error {
+	if c, ok := d.conn.rw.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// assertion is the part of an authenticatorGetAssertion response that
+// makes up an SSH signature.
+type assertion struct {
+	flags     byte
+	counter   uint32
+	signature []byte
+}
+
+// getAssertion asks d to sign clientDataHash with the credential credID of
+// the relying party rpID. If up is false, the device doesn't wait for the
+// user, which is used to probe for the credential.
+func (d *Device) getAssertion(rpID string, clientDataHash, credID []byte, up, uv bool) (*assertion, error) {
+	options := cborPairs{{"up", up}}
+	if uv {
+		options = append(options, cborPair{"uv", true})
+	}
+	params, err := cborEncode(cborPairs{
+		{1, rpID},
+		{2, clientDataHash},
+		{3, []interface{}{cborPairs{{"id", credID}, {"type", "public-key"}}}},
+		{5, options},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	status, resp, err := d.conn.transact(ctapGetAssertion, params)
+	d.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if status != statusOK {
+		return nil, &StatusError{Status: status}
+	}
+
+	v, _, err := cborDecode(resp)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return nil, errors.New("fido2: malformed authenticatorGetAssertion response")
+	}
+	authData, ok1 := m[uint64(2)].([]byte)
+	signature, ok2 := m[uint64(3)].([]byte)
+	if !ok1 || !ok2 || len(authData) < 37 {
+		return nil, errors.New("fido2: malformed authenticatorGetAssertion response")
+	}
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	if !bytes.Equal(authData[:32], rpIDHash[:]) {
+		return nil, errors.New("fido2: security key signed for another relying party")
+	}
+	return &assertion{
+		flags:     authData[32],
+		counter:   binary.BigEndian.Uint32(authData[33:37]),
+		signature: signature,
+	}, nil
+}
+
+// Sign implements ssh.SKProvider for a single device.
+func (d *Device) Sign(req *ssh.SKSignRequest) (*ssh.SKSignResponse, error) {
+	switch req.Algorithm {
+	case ssh.KeyAlgoSKECDSA256, ssh.KeyAlgoSKED25519:
+	default:
+		return nil, fmt.Errorf("fido2: unsupported key type %s", req.Algorithm)
+	}
+	a, err := d.getAssertion(req.Application, req.Digest, req.KeyHandle,
+		req.Flags&ssh.SKFlagUserPresent != 0, req.Flags&ssh.SKFlagUserVerified != 0)
+	if err != nil {
+		if se, ok := err.(*StatusError); ok && se.Status == statusNoCredentials {
+			return nil, ErrNoCredentials
+		}
+		return nil, err
+	}
+	return &ssh.SKSignResponse{Flags: a.flags, Counter: a.counter, Signature: a.signature}, nil
+}
+
+// Provider is an ssh.SKProvider that signs with whichever of several
+// security keys holds the credential.
+type Provider struct {
+	// Devices returns the security keys to try. The Provider closes
+	// them after each signature. If nil, the devices returned by
+	// DevicePaths are opened.
+	Devices func() ([]*Device, error)
+}
+
+func (p *Provider) devices() ([]*Device, error) {
+	if p.Devices != nil {
+		return p.Devices()
+	}
+	paths, err := DevicePaths()
+	if err != nil {
+		return nil, err
+	}
+	var devs []*Device
+	for _, path := range paths {
+		rw, err := Open(path)
+		if err != nil {
+			continue
+		}
+		dev, err := NewDevice(rw)
+		if err != nil {
+			rw.Close()
+			continue
+		}
+		devs = append(devs, dev)
+	}
+	return devs, nil
+}
+
+// Sign implements ssh.SKProvider. If there are several devices, they are
+// first asked without user presence whether they hold the credential, so
+// that only the right one waits for a touch.
+func (p *Provider) Sign(req *ssh.SKSignRequest) (*ssh.SKSignResponse, error) {
+	devs, err := p.devices()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		for _, d := range devs {
+			d.Close()
+		}
+	}()
+
+	if len(devs) == 1 {
+		return devs[0].Sign(req)
+	}
+	for _, d := range devs {
+		// Probing signs a dummy hash, and fails if d doesn't hold the
+		// credential.
+		if _, err := d.getAssertion(req.Application, make([]byte, sha256.Size), req.KeyHandle, false, false); err != nil {
+			continue
+		}
+		return d.Sign(req)
+	}
+	return nil, ErrNoCredentials
+}
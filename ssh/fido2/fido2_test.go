@@ -0,0 +1,290 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fido2
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// packetQueue holds HID reports.
+type packetQueue [][]byte
+
+func (q *packetQueue) Write(p []byte) (int, error) {
+	*q = append(*q, append([]byte(nil), p...))
+	return len(p), nil
+}
+
+func (q *packetQueue) Read(p []byte) (int, error) {
+	if len(*q) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, (*q)[0])
+	*q = (*q)[1:]
+	return n, nil
+}
+
+// fakeKey is a security key that answers authenticatorGetAssertion for its
+// credentials.
+type fakeKey struct {
+	t           *testing.T
+	credentials map[string]crypto.Signer
+	status      byte
+	counter     uint32
+
+	in      packetQueue
+	out     packetQueue
+	touched int
+}
+
+const fakeCID = 0x01020304
+
+func (k *fakeKey) Read(p []byte) (int, error) {
+	return k.out.Read(p)
+}
+
+func (k *fakeKey) Write(p []byte) (int, error) {
+	if len(p) != reportSize {
+		k.t.Fatalf("got report of %d bytes, want %d", len(p), reportSize)
+	}
+	k.in.Write(p)
+	size := int(binary.BigEndian.Uint16(k.in[0][5:]))
+	if size > initDataSize+(len(k.in)-1)*contDataSize {
+		return len(p), nil
+	}
+	// The message is complete, read it back with the code under test.
+	conn := &hidConn{rw: &k.in, cid: binary.BigEndian.Uint32(k.in[0])}
+	cmd := k.in[0][4]
+	msg, err := conn.recv(cmd)
+	if err != nil {
+		k.t.Fatalf("fake key: %v", err)
+	}
+	k.handle(conn.cid, cmd, msg)
+	return len(p), nil
+}
+
+func (k *fakeKey) handle(cid uint32, cmd byte, msg []byte) {
+	// Traffic for other channels must be ignored.
+	other := &hidConn{rw: &k.out, cid: 0x0badcafe}
+	other.send(cmdCBOR, []byte{statusOK})
+
+	out := &hidConn{rw: &k.out, cid: cid}
+	switch cmd {
+	case cmdInit:
+		if cid != broadcastCID {
+			k.t.Fatalf("INIT on channel %#x", cid)
+		}
+		resp := append(msg, 0, 0, 0, 0, 2, 1, 0, 0, 4)
+		binary.BigEndian.PutUint32(resp[8:], fakeCID)
+		out.send(cmdInit, resp)
+	case cmdCBOR:
+		if cid != fakeCID {
+			k.t.Fatalf("CBOR message on channel %#x", cid)
+		}
+		out.send(cmdKeepalive, []byte{2})
+		out.send(cmdCBOR, k.getAssertion(msg))
+	default:
+		out.send(cmdError, []byte{1})
+	}
+}
+
+func (k *fakeKey) getAssertion(msg []byte) []byte {
+	if k.status != statusOK {
+		return []byte{k.status}
+	}
+	if msg[0] != ctapGetAssertion {
+		k.t.Fatalf("got CTAP2 command %#x", msg[0])
+	}
+	v, rest, err := cborDecode(msg[1:])
+	if err != nil || len(rest) > 0 {
+		k.t.Fatalf("bad authenticatorGetAssertion parameters: %v", err)
+	}
+	params := v.(map[interface{}]interface{})
+	rpID := params[uint64(1)].(string)
+	clientDataHash := params[uint64(2)].([]byte)
+	cred := params[uint64(3)].([]interface{})[0].(map[interface{}]interface{})
+	options := params[uint64(5)].(map[interface{}]interface{})
+
+	signer, ok := k.credentials[rpID+"/"+string(cred["id"].([]byte))]
+	if !ok {
+		return []byte{statusNoCredentials}
+	}
+	var flags byte
+	if options["up"] == true {
+		k.touched++
+		flags |= ssh.SKFlagUserPresent
+	}
+	if options["uv"] == true {
+		flags |= ssh.SKFlagUserVerified
+	}
+	k.counter++
+
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	authData := append(rpIDHash[:], flags, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(authData[33:], k.counter)
+	signed := append(append([]byte(nil), authData...), clientDataHash...)
+	var sig []byte
+	if _, ok := signer.(ed25519.PrivateKey); ok {
+		sig, err = signer.Sign(rand.Reader, signed, crypto.Hash(0))
+	} else {
+		h := sha256.Sum256(signed)
+		sig, err = signer.Sign(rand.Reader, h[:], crypto.SHA256)
+	}
+	if err != nil {
+		k.t.Fatal(err)
+	}
+	resp, err := cborEncode(cborPairs{{2, authData}, {3, sig}})
+	if err != nil {
+		k.t.Fatal(err)
+	}
+	return append([]byte{statusOK}, resp...)
+}
+
+// skPublicKey returns the security key public key for key.
+func skPublicKey(t *testing.T, key crypto.Signer, application string) ssh.PublicKey {
+	var b []byte
+	switch pub := key.Public().(type) {
+	case ed25519.PublicKey:
+		b = ssh.Marshal(struct{ Name, Key, App string }{ssh.KeyAlgoSKED25519, string(pub), application})
+	case *ecdsa.PublicKey:
+		point := elliptic.Marshal(pub.Curve, pub.X, pub.Y)
+		b = ssh.Marshal(struct{ Name, Curve, Key, App string }{ssh.KeyAlgoSKECDSA256, "nistp256", string(point), application})
+	}
+	pub, err := ssh.ParsePublicKey(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pub
+}
+
+func TestDeviceSign(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A long key handle makes the request span several packets.
+	longHandle := bytes.Repeat([]byte("h"), 200)
+	fake := &fakeKey{t: t, credentials: map[string]crypto.Signer{
+		"ssh:/ed":                    edKey,
+		"ssh:/" + string(longHandle): ecKey,
+	}}
+	dev, err := NewDevice(fake)
+	if err != nil {
+		t.Fatalf("NewDevice: %v", err)
+	}
+
+	for _, tt := range []struct {
+		key    crypto.Signer
+		handle []byte
+		flags  byte
+	}{
+		{edKey, []byte("ed"), ssh.SKFlagUserPresent},
+		{ecKey, longHandle, ssh.SKFlagUserPresent},
+		{ecKey, longHandle, ssh.SKFlagUserPresent | ssh.SKFlagUserVerified},
+		{edKey, []byte("ed"), 0},
+	} {
+		pub := skPublicKey(t, tt.key, "ssh:")
+		signer, err := ssh.NewSKSigner(pub, tt.handle, tt.flags, dev)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data := []byte("session data")
+		sig, err := signer.Sign(rand.Reader, data)
+		if err != nil {
+			t.Fatalf("%s: Sign: %v", pub.Type(), err)
+		}
+		if err := pub.Verify(data, sig); err != nil {
+			t.Errorf("%s: Verify: %v", pub.Type(), err)
+		}
+		sk, err := ssh.ParseSKSignature(sig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sk.Flags != tt.flags {
+			t.Errorf("%s: got flags %#x, want %#x", pub.Type(), sk.Flags, tt.flags)
+		}
+		if sk.Counter != fake.counter {
+			t.Errorf("%s: got counter %d, want %d", pub.Type(), sk.Counter, fake.counter)
+		}
+	}
+
+	pub := skPublicKey(t, edKey, "ssh:")
+	signer, _ := ssh.NewSKSigner(pub, []byte("unknown"), ssh.SKFlagUserPresent, dev)
+	if _, err := signer.Sign(rand.Reader, []byte("data")); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("signing with an unknown credential: got %v, want %v", err, ErrNoCredentials)
+	}
+
+	fake.status = statusPINRequired
+	signer, _ = ssh.NewSKSigner(pub, []byte("ed"), ssh.SKFlagUserPresent, dev)
+	var se *StatusError
+	if _, err := signer.Sign(rand.Reader, []byte("data")); !errors.As(err, &se) || se.Status != statusPINRequired {
+		t.Errorf("signing with a PIN protected key: got %v, want a StatusError", err)
+	}
+}
+
+func TestProviderSelectsDevice(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fakes := []*fakeKey{
+		{t: t, credentials: map[string]crypto.Signer{}},
+		{t: t, credentials: map[string]crypto.Signer{"ssh:/ed": edKey}},
+		{t: t, credentials: map[string]crypto.Signer{}},
+	}
+	p := &Provider{Devices: func() ([]*Device, error) {
+		var devs []*Device
+		for _, f := range fakes {
+			dev, err := NewDevice(f)
+			if err != nil {
+				return nil, err
+			}
+			devs = append(devs, dev)
+		}
+		return devs, nil
+	}}
+
+	pub := skPublicKey(t, edKey, "ssh:")
+	signer, err := ssh.NewSKSigner(pub, []byte("ed"), ssh.SKFlagUserPresent, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := signer.Sign(rand.Reader, []byte("data"))
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if err := pub.Verify([]byte("data"), sig); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+	for i, f := range fakes {
+		want := 0
+		if i == 1 {
+			want = 1
+		}
+		if f.touched != want {
+			t.Errorf("device %d waited for %d touches, want %d", i, f.touched, want)
+		}
+	}
+
+	signer, _ = ssh.NewSKSigner(pub, []byte("unknown"), ssh.SKFlagUserPresent, p)
+	if _, err := signer.Sign(rand.Reader, []byte("data")); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("signing with an unknown credential: got %v, want %v", err, ErrNoCredentials)
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fido2
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// fidoUsagePage is the start of a HID report descriptor item declaring the
+// FIDO Alliance usage page 0xf1d0.
+var fidoUsagePage = []byte{0x06, 0xd0, 0xf1}
+
+// DevicePaths returns the hidraw device nodes of the attached FIDO security
+// keys.
+func DevicePaths() ([]string, error) {
+	descriptors, err := filepath.Glob("/sys/class/hidraw/*/device/report_descriptor")
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, d := range descriptors {
+		desc, err := os.ReadFile(d)
+		if err != nil || !bytes.Contains(desc, fidoUsagePage) {
+			continue
+		}
+		name := filepath.Base(filepath.Dir(filepath.Dir(d)))
+		paths = append(paths, filepath.Join("/dev", name))
+	}
+	return paths, nil
+}
+
+// hidraw adds the report number that hidraw expects in front of output
+// reports.
+type hidraw struct {
+	*os.File
+}
+
+func (h hidraw) Write(report []byte) (int, error) {
+	if _, err := h.File.Write(append([]byte{0}, report...)); err != nil {
+		return 0, err
+	}
+	return len(report), nil
+}
+
+// Open opens a hidraw device node, such as one returned by DevicePaths, for
+// use with NewDevice.
+func Open(path string) (io.ReadWriteCloser, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	return hidraw{f}, nil
+}
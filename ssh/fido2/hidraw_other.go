@@ -0,0 +1,26 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+
+package fido2
+
+import (
+	"errors"
+	"io"
+)
+
+var errUnsupported = errors.New("fido2: HID devices are only supported on Linux")
+
+// DevicePaths returns the hidraw device nodes of the attached FIDO security
+// keys. It is only implemented on Linux.
+func DevicePaths() ([]string, error) {
+	return nil, errUnsupported
+}
+
+// Open opens a hidraw device node, such as one returned by DevicePaths, for
+// use with NewDevice. It is only implemented on Linux.
+func Open(path string) (io.ReadWriteCloser, error) {
+	return nil, errUnsupported
+}
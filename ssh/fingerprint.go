@@ -0,0 +1,213 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/dsa"
+	"crypto/md5"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// A Fingerprint is the hash of a public key, as displayed to users to
+// identify the key.
+type Fingerprint struct {
+	// Hash is crypto.SHA256 or crypto.MD5.
+	Hash crypto.Hash
+
+	// Sum is the hash of the wire encoding of the key.
+	Sum []byte
+}
+
+// NewFingerprint returns the fingerprint of pub using hash, which must be
+// crypto.SHA256 or crypto.MD5.
+func NewFingerprint(pub PublicKey, hash crypto.Hash) (Fingerprint, error) {
+	switch hash {
+	case crypto.SHA256:
+		sum := sha256.Sum256(pub.Marshal())
+		return Fingerprint{Hash: hash, Sum: sum[:]}, nil
+	case crypto.MD5:
+		sum := md5.Sum(pub.Marshal())
+		return Fingerprint{Hash: hash, Sum: sum[:]}, nil
+	}
+	return Fingerprint{}, fmt.Errorf("ssh: unsupported fingerprint hash %v", hash)
+}
+
+// ParseFingerprint parses a fingerprint in the format of FingerprintSHA256,
+// "SHA256:" followed by base64, or of FingerprintLegacyMD5, colon separated
+// hex optionally prefixed with "MD5:".
+func ParseFingerprint(s string) (Fingerprint, error) {
+	if b64, ok := strings.CutPrefix(s, "SHA256:"); ok {
+		sum, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(b64, "="))
+		if err != nil || len(sum) != sha256.Size {
+			return Fingerprint{}, errors.New("ssh: invalid SHA256 fingerprint")
+		}
+		return Fingerprint{Hash: crypto.SHA256, Sum: sum}, nil
+	}
+
+	hexPairs := strings.Split(strings.TrimPrefix(s, "MD5:"), ":")
+	if len(hexPairs) != md5.Size {
+		return Fingerprint{}, errors.New("ssh: invalid fingerprint")
+	}
+	sum := make([]byte, 0, md5.Size)
+	for _, p := range hexPairs {
+		b, err := hex.DecodeString(p)
+		if err != nil || len(b) != 1 {
+			return Fingerprint{}, errors.New("ssh: invalid MD5 fingerprint")
+		}
+		sum = append(sum, b[0])
+	}
+	return Fingerprint{Hash: crypto.MD5, Sum: sum}, nil
+}
+
+// String returns the fingerprint in the format of FingerprintSHA256 or
+// FingerprintLegacyMD5.
+func (f Fingerprint) String() string {
+	if f.Hash == crypto.MD5 {
+		hexPairs := make([]string, len(f.Sum))
+		for i, c := range f.Sum {
+			hexPairs[i] = hex.EncodeToString([]byte{c})
+		}
+		return strings.Join(hexPairs, ":")
+	}
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(f.Sum)
+}
+
+// Equal reports whether f and other are the same fingerprint.
+func (f Fingerprint) Equal(other Fingerprint) bool {
+	return f.Hash == other.Hash && bytes.Equal(f.Sum, other.Sum)
+}
+
+// Matches reports whether f is the fingerprint of pub.
+func (f Fingerprint) Matches(pub PublicKey) bool {
+	g, err := NewFingerprint(pub, f.Hash)
+	return err == nil && f.Equal(g)
+}
+
+// Field size of the randomart, see OpenSSH's sshkey.c.
+const (
+	randomartBase = 8
+	randomartRows = randomartBase + 1
+	randomartCols = randomartBase*2 + 1
+)
+
+// randomartSymbols are the symbols for how often the bishop visited a cell,
+// followed by the start and end markers.
+const randomartSymbols = " .o+=*BOX@%&#/^SE"
+
+// Randomart returns the visualization of the SHA-256 fingerprint of pub
+// printed by ssh-keygen -lv and by ssh with VisualHostKey, using the "drunken
+// bishop" algorithm of OpenSSH.
+func Randomart(pub PublicKey) string {
+	sum := sha256.Sum256(pub.Marshal())
+	return randomart(randomartTitle(pub), "SHA256", sum[:])
+}
+
+func randomart(title, hashName string, sum []byte) string {
+	var field [randomartCols][randomartRows]int
+	end := len(randomartSymbols) - 1
+	x, y := randomartCols/2, randomartRows/2
+
+	// Each byte moves the bishop four times, two bits per move.
+	for _, b := range sum {
+		for i := 0; i < 4; i++ {
+			if b&1 != 0 {
+				x++
+			} else {
+				x--
+			}
+			if b&2 != 0 {
+				y++
+			} else {
+				y--
+			}
+			x = clamp(x, randomartCols-1)
+			y = clamp(y, randomartRows-1)
+			if field[x][y] < end-2 {
+				field[x][y]++
+			}
+			b >>= 2
+		}
+	}
+	field[randomartCols/2][randomartRows/2] = end - 1
+	field[x][y] = end
+
+	var b strings.Builder
+	border := func(label string) {
+		b.WriteByte('+')
+		pad := (randomartCols - len(label)) / 2
+		b.WriteString(strings.Repeat("-", pad))
+		b.WriteString(label)
+		b.WriteString(strings.Repeat("-", randomartCols-pad-len(label)))
+		b.WriteByte('+')
+	}
+	border(title)
+	b.WriteByte('\n')
+	for y := 0; y < randomartRows; y++ {
+		b.WriteByte('|')
+		for x := 0; x < randomartCols; x++ {
+			b.WriteByte(randomartSymbols[field[x][y]])
+		}
+		b.WriteString("|\n")
+	}
+	border("[" + hashName + "]")
+	return b.String()
+}
+
+// clamp limits v to the range [0, hi].
+func clamp(v, hi int) int {
+	if v < 0 {
+		return 0
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+// randomartTitle returns the key type and size shown at the top of the
+// randomart, such as "[RSA 3072]".
+func randomartTitle(pub PublicKey) string {
+	name, bits, suffix := "UNKNOWN", 0, ""
+	if cert, ok := pub.(*Certificate); ok {
+		pub, suffix = cert.Key, "-CERT"
+	}
+	switch k := pub.(type) {
+	case *rsaPublicKey:
+		name, bits = "RSA", (*rsa.PublicKey)(k).N.BitLen()
+	case *dsaPublicKey:
+		name, bits = "DSA", (*dsa.PublicKey)(k).P.BitLen()
+	case *ecdsaPublicKey:
+		name, bits = "ECDSA", k.Curve.Params().BitSize
+	case *skECDSAPublicKey:
+		name, bits = "ECDSA-SK", k.Curve.Params().BitSize
+	case ed25519PublicKey:
+		name, bits = "ED25519", 256
+	case *skEd25519PublicKey:
+		name, bits = "ED25519-SK", 256
+	case ed448PublicKey:
+		name, bits = "ED448", 456
+	}
+	name += suffix
+
+	// Like OpenSSH, drop the size from titles longer than the 16
+	// characters that fit in its buffer, and cut off what still doesn't.
+	const maxTitle = randomartCols - 1
+	title := fmt.Sprintf("[%s %d]", name, bits)
+	if len(title) > maxTitle {
+		title = "[" + name + "]"
+	}
+	if len(title) > maxTitle {
+		title = title[:maxTitle]
+	}
+	return title
+}
@@ -0,0 +1,107 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"crypto"
+	"crypto/rand"
+	"strings"
+	"testing"
+)
+
+func TestRandomart(t *testing.T) {
+	// Output of ssh-keygen -lv.
+	const authorizedKey = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAID9k8DwftjMlddHBVahDNN3rXyEV/YebN4Q2mt9MLy34 test"
+	const want = `+--[ED25519 256]--+
+|Eo =+o.oo.       |
+| . ++ ...        |
+|. +  = o.        |
+|oo  o.+..        |
+|.o. o.. S        |
+|oo.+.  o         |
+|=o=.+ o          |
+|**.= . .         |
+|O@= . .          |
++----[SHA256]-----+`
+	pub, _, _, _, err := ParseAuthorizedKey([]byte(authorizedKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := Randomart(pub); got != want {
+		t.Errorf("Randomart() =\n%s\nwant\n%s", got, want)
+	}
+
+	cert := func(key PublicKey) *Certificate {
+		c := &Certificate{Key: key, CertType: UserCert}
+		if err := c.SignCert(rand.Reader, testSigners["ecdsa"]); err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	for _, tt := range []struct {
+		pub  PublicKey
+		want string
+	}{
+		{testPublicKeys["rsa"], "+---[RSA 2048]----+"},
+		{testPublicKeys["ecdsa"], "+---[ECDSA 256]---+"},
+		// Titles of more than 16 characters lose their size, as in
+		// OpenSSH.
+		{cert(testPublicKeys["ed25519"]), "+-[ED25519-CERT]--+"},
+		{cert(testPublicKeys["ecdsap384"]), "+[ECDSA-CERT 384]-+"},
+	} {
+		art := Randomart(tt.pub)
+		if first := art[:strings.IndexByte(art, '\n')]; first != tt.want {
+			t.Errorf("%s: got title line %q, want %q", tt.pub.Type(), first, tt.want)
+		}
+	}
+}
+
+func TestParseFingerprint(t *testing.T) {
+	pub := testPublicKeys["ed25519"]
+	for _, s := range []string{
+		FingerprintSHA256(pub),
+		FingerprintSHA256(pub) + "=",
+		FingerprintLegacyMD5(pub),
+		"MD5:" + FingerprintLegacyMD5(pub),
+	} {
+		f, err := ParseFingerprint(s)
+		if err != nil {
+			t.Errorf("ParseFingerprint(%q): %v", s, err)
+			continue
+		}
+		if !f.Matches(pub) {
+			t.Errorf("ParseFingerprint(%q) does not match its key", s)
+		}
+		if f.Matches(testPublicKeys["rsa"]) {
+			t.Errorf("ParseFingerprint(%q) matches another key", s)
+		}
+		if got := f.String(); got != strings.TrimPrefix(strings.TrimSuffix(s, "="), "MD5:") {
+			t.Errorf("String() = %q, want %q", got, s)
+		}
+	}
+
+	sha, _ := NewFingerprint(pub, crypto.SHA256)
+	md, _ := NewFingerprint(pub, crypto.MD5)
+	if sha.Equal(md) {
+		t.Error("SHA256 and MD5 fingerprints are equal")
+	}
+	if _, err := NewFingerprint(pub, crypto.SHA1); err == nil {
+		t.Error("NewFingerprint with SHA1 succeeded")
+	}
+
+	for _, s := range []string{
+		"",
+		"SHA256:",
+		"SHA256:!!!!",
+		"SHA256:" + strings.Repeat("A", 40),
+		"MD5:00:11",
+		strings.Repeat("zz:", 15) + "zz",
+		strings.Repeat("000:", 15) + "00",
+	} {
+		if _, err := ParseFingerprint(s); err == nil {
+			t.Errorf("ParseFingerprint(%q) succeeded", s)
+		}
+	}
+}
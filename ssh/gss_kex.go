@@ -0,0 +1,362 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/asn1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// GSSAPIKexClient is a GSSAPIClient that can also verify MICs from the
+// server, as needed for GSSAPI key exchange.
+type GSSAPIKexClient interface {
+	GSSAPIClient
+
+	// VerifyMIC verifies that micToken is a MIC of micField from the
+	// server, using the security context that was just established. See
+	// RFC 2743 section 2.3.2 and RFC 4462 section 2.1.
+	VerifyMIC(micField []byte, micToken []byte) error
+}
+
+// GSSAPIKexServer is a GSSAPIServer that can also generate MICs, as needed
+// for GSSAPI key exchange.
+type GSSAPIKexServer interface {
+	GSSAPIServer
+
+	// GetMIC generates a MIC of micField for the client, using the
+	// security context that was just established. See RFC 2743 section
+	// 2.3.1 and RFC 4462 section 2.1.
+	GetMIC(micField []byte) ([]byte, error)
+}
+
+// GSSAPIKexConfig configures GSSAPI key exchange on the client.
+type GSSAPIKexConfig struct {
+	// Client establishes the security context with the server.
+	Client GSSAPIKexClient
+
+	// Target is the host name of the server, whose "host" service
+	// principal the security context is established with.
+	Target string
+}
+
+// GSSAPI key exchange methods, see RFC 4462, section 2 and RFC 8732. The
+// names end with the encoding of the hash of the mechanism, which is always
+// Kerberos V5, as for gssapi-with-mic authentication.
+var (
+	kexAlgoGSSGroup14SHA256 = "gss-group14-sha256-" + gssMechSuffix(krb5Mesh)
+	kexAlgoGSSNISTP256      = "gss-nistp256-sha256-" + gssMechSuffix(krb5Mesh)
+)
+
+// gssKexAlgos are the GSSAPI key exchange methods, in order of preference,
+// offered when GSSAPI key exchange is configured.
+var gssKexAlgos = []string{kexAlgoGSSNISTP256, kexAlgoGSSGroup14SHA256}
+
+// gssMechSuffix returns the base64 encoding of the MD5 hash of the DER
+// encoding of mech, see RFC 4462, section 2.
+func gssMechSuffix(mech asn1.ObjectIdentifier) string {
+	der, _ := asn1.Marshal(mech)
+	sum := md5.Sum(der)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// withGSSKexAlgos returns kexAlgos with the GSSAPI key exchange methods
+// prepended, unless some of them are already listed.
+func withGSSKexAlgos(kexAlgos []string) []string {
+	for _, algo := range gssKexAlgos {
+		if contains(kexAlgos, algo) {
+			return kexAlgos
+		}
+	}
+	return append(append([]string(nil), gssKexAlgos...), kexAlgos...)
+}
+
+// hostKeyAlgoNull is the host key algorithm of servers that are only
+// authenticated by GSSAPI key exchange, see RFC 4462, section 5. Clients
+// and servers that enable GSSAPI key exchange offer it last.
+const hostKeyAlgoNull = "null"
+
+var errGSSKexNotConfigured = errors.New("ssh: GSSAPI key exchange negotiated but not configured")
+
+// gssKex is a GSSAPI key exchange method. The ephemeral key agreement is
+// either Diffie-Hellman in a group or ECDH on a curve, and the server is
+// authenticated by a MIC of the exchange hash instead of a host key
+// signature.
+type gssKex struct {
+	dh    *dhGroup
+	curve elliptic.Curve
+}
+
+// Client and Server implement kexAlgorithm; the handshake calls client and
+// server instead, with the GSSAPI security context provider.
+func (k *gssKex) Client(c packetConn, rand io.Reader, magics *handshakeMagics) (*kexResult, error) {
+	return nil, errGSSKexNotConfigured
+}
+
+func (k *gssKex) Server(c packetConn, rand io.Reader, magics *handshakeMagics, priv AlgorithmSigner, algo string) (*kexResult, error) {
+	return nil, errGSSKexNotConfigured
+}
+
+func (k *gssKex) hash() crypto.Hash {
+	if k.dh != nil {
+		return k.dh.hashFunc
+	}
+	return ecHash(k.curve)
+}
+
+// keyPair generates an ephemeral key pair, and returns the public key
+// encoded as it is sent and hashed: an mpint for Diffie-Hellman, and a
+// string for ECDH.
+func (k *gssKex) keyPair(randSource io.Reader) (priv *big.Int, pub []byte, err error) {
+	if k.curve != nil {
+		key, err := ecdsa.GenerateKey(k.curve, randSource)
+		if err != nil {
+			return nil, nil, err
+		}
+		return key.D, appendString(nil, string(elliptic.Marshal(k.curve, key.X, key.Y))), nil
+	}
+	for {
+		if priv, err = rand.Int(randSource, k.dh.pMinus1); err != nil {
+			return nil, nil, err
+		}
+		if priv.Sign() > 0 {
+			break
+		}
+	}
+	X := new(big.Int).Exp(k.dh.g, priv, k.dh.p)
+	pub = make([]byte, intLength(X))
+	marshalInt(pub, X)
+	return priv, pub, nil
+}
+
+// sharedSecret parses the peer's public key from the start of in, and
+// returns it in its encoded form, the shared secret encoded as an mpint, and
+// the rest of in.
+func (k *gssKex) sharedSecret(priv *big.Int, in []byte) (peer, secret, rest []byte, err error) {
+	var ki *big.Int
+	if k.curve != nil {
+		point, r, ok := parseString(in)
+		if !ok {
+			return nil, nil, nil, errors.New("ssh: malformed GSSAPI key exchange public key")
+		}
+		x, y, err := unmarshalECKey(k.curve, point)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		ki, _ = k.curve.ScalarMult(x, y, priv.Bytes())
+		rest = r
+	} else {
+		y, r, ok := parseInt(in)
+		if !ok {
+			return nil, nil, nil, errors.New("ssh: malformed GSSAPI key exchange public key")
+		}
+		if ki, err = k.dh.diffieHellman(y, priv); err != nil {
+			return nil, nil, nil, err
+		}
+		rest = r
+	}
+	secret = make([]byte, intLength(ki))
+	marshalInt(secret, ki)
+	return in[:len(in)-len(rest)], secret, rest, nil
+}
+
+// exchangeHash computes H, see RFC 4462, section 2.1. hostKey is empty if
+// the server did not send one.
+func (k *gssKex) exchangeHash(magics *handshakeMagics, hostKey, clientPub, serverPub, secret []byte) []byte {
+	h := k.hash().New()
+	magics.write(h)
+	writeString(h, hostKey)
+	h.Write(clientPub)
+	h.Write(serverPub)
+	h.Write(secret)
+	return h.Sum(nil)
+}
+
+// client runs the client side of the key exchange. The server is
+// authenticated by the security context, so its host key, if sent, is only
+// hashed into H and is neither verified nor passed to the HostKeyCallback.
+func (k *gssKex) client(c packetConn, randSource io.Reader, magics *handshakeMagics, config *GSSAPIKexConfig) (*kexResult, error) {
+	gss := config.Client
+	defer gss.DeleteSecContext()
+	target := "host@" + config.Target
+
+	priv, pub, err := k.keyPair(randSource)
+	if err != nil {
+		return nil, err
+	}
+	token, needContinue, err := gss.InitSecContext(target, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.writePacket(Marshal(&kexGSSInitMsg{Token: token, PubKey: pub})); err != nil {
+		return nil, err
+	}
+
+	var hostKey []byte
+	for {
+		packet, err := c.readPacket()
+		if err != nil {
+			return nil, err
+		}
+		switch packet[0] {
+		case msgKexGSSHostKey:
+			var msg kexGSSHostKeyMsg
+			if err := Unmarshal(packet, &msg); err != nil {
+				return nil, err
+			}
+			hostKey = msg.HostKey
+		case msgKexGSSContinue:
+			var msg kexGSSContinueMsg
+			if err := Unmarshal(packet, &msg); err != nil {
+				return nil, err
+			}
+			if !needContinue {
+				return nil, errors.New("ssh: unexpected GSSAPI token from server")
+			}
+			if token, needContinue, err = gss.InitSecContext(target, msg.Token, false); err != nil {
+				return nil, err
+			}
+			if len(token) > 0 {
+				if err := c.writePacket(Marshal(&kexGSSContinueMsg{Token: token})); err != nil {
+					return nil, err
+				}
+			}
+		case msgKexGSSComplete:
+			serverPub, secret, rest, err := k.sharedSecret(priv, packet[1:])
+			if err != nil {
+				return nil, err
+			}
+			mic, rest, ok := parseString(rest)
+			if !ok || len(rest) < 1 {
+				return nil, parseError(msgKexGSSComplete)
+			}
+			if hasToken := rest[0] != 0; hasToken {
+				final, r, ok := parseString(rest[1:])
+				if !ok || len(r) > 0 {
+					return nil, parseError(msgKexGSSComplete)
+				}
+				if token, needContinue, err = gss.InitSecContext(target, final, false); err != nil {
+					return nil, err
+				}
+				if len(token) > 0 {
+					// The server does not expect more tokens.
+					needContinue = true
+				}
+			} else if len(rest) > 1 {
+				return nil, parseError(msgKexGSSComplete)
+			}
+			if needContinue {
+				return nil, errors.New("ssh: GSSAPI security context not established")
+			}
+			H := k.exchangeHash(magics, hostKey, pub, serverPub, secret)
+			if err := gss.VerifyMIC(H, mic); err != nil {
+				return nil, fmt.Errorf("ssh: GSSAPI key exchange MIC verification failed: %w", err)
+			}
+			return &kexResult{
+				H:       H,
+				K:       secret,
+				HostKey: hostKey,
+				Hash:    k.hash(),
+			}, nil
+		case msgKexGSSError:
+			var msg kexGSSErrorMsg
+			if err := Unmarshal(packet, &msg); err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("ssh: GSSAPI key exchange failed on the server: %s", msg.Message)
+		default:
+			return nil, unexpectedMessageError(msgKexGSSComplete, packet[0])
+		}
+	}
+}
+
+// server runs the server side of the key exchange, sending hostKey, if not
+// nil, to the client so that it is hashed into H.
+func (k *gssKex) server(c packetConn, randSource io.Reader, magics *handshakeMagics, gss GSSAPIKexServer, hostKey PublicKey) (*kexResult, error) {
+	defer gss.DeleteSecContext()
+
+	packet, err := c.readPacket()
+	if err != nil {
+		return nil, err
+	}
+	var init kexGSSInitMsg
+	if err := Unmarshal(packet, &init); err != nil {
+		return nil, err
+	}
+	priv, pub, err := k.keyPair(randSource)
+	if err != nil {
+		return nil, err
+	}
+	clientPub, secret, rest, err := k.sharedSecret(priv, init.PubKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, parseError(msgKexGSSInit)
+	}
+
+	var hostKeyBytes []byte
+	if hostKey != nil {
+		hostKeyBytes = hostKey.Marshal()
+		if err := c.writePacket(Marshal(&kexGSSHostKeyMsg{HostKey: hostKeyBytes})); err != nil {
+			return nil, err
+		}
+	}
+
+	token := init.Token
+	var out []byte
+	for {
+		var needContinue bool
+		out, _, needContinue, err = gss.AcceptSecContext(token)
+		if err != nil {
+			c.writePacket(Marshal(&kexGSSErrorMsg{Message: err.Error()}))
+			return nil, err
+		}
+		if !needContinue {
+			break
+		}
+		if err := c.writePacket(Marshal(&kexGSSContinueMsg{Token: out})); err != nil {
+			return nil, err
+		}
+		packet, err := c.readPacket()
+		if err != nil {
+			return nil, err
+		}
+		var msg kexGSSContinueMsg
+		if err := Unmarshal(packet, &msg); err != nil {
+			return nil, err
+		}
+		token = msg.Token
+	}
+
+	H := k.exchangeHash(magics, hostKeyBytes, clientPub, pub, secret)
+	mic, err := gss.GetMIC(H)
+	if err != nil {
+		return nil, err
+	}
+	complete := append([]byte{msgKexGSSComplete}, pub...)
+	complete = appendString(complete, string(mic))
+	complete = appendBool(complete, len(out) > 0)
+	if len(out) > 0 {
+		complete = appendString(complete, string(out))
+	}
+	if err := c.writePacket(complete); err != nil {
+		return nil, err
+	}
+	return &kexResult{
+		H:       H,
+		K:       secret,
+		HostKey: hostKeyBytes,
+		Hash:    k.hash(),
+	}, nil
+}
@@ -0,0 +1,158 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+)
+
+// fakeGSSKexMech is a GSSAPI mechanism whose contexts are established by
+// exchanging fixed tokens, and whose MICs are HMACs with a shared key.
+type fakeGSSKexMech struct {
+	key   []byte
+	round int
+}
+
+func (f *fakeGSSKexMech) mic(data []byte) []byte {
+	m := hmac.New(sha256.New, f.key)
+	m.Write(data)
+	return m.Sum(nil)
+}
+
+func (f *fakeGSSKexMech) GetMIC(micField []byte) ([]byte, error) {
+	return f.mic(micField), nil
+}
+
+func (f *fakeGSSKexMech) VerifyMIC(micField []byte, micToken []byte) error {
+	if !hmac.Equal(micToken, f.mic(micField)) {
+		return errors.New("bad MIC")
+	}
+	return nil
+}
+
+func (f *fakeGSSKexMech) DeleteSecContext() error {
+	f.round = 0
+	return nil
+}
+
+type fakeGSSKexClient struct {
+	fakeGSSKexMech
+	targets []string
+}
+
+func (f *fakeGSSKexClient) InitSecContext(target string, token []byte, isGSSDelegCreds bool) ([]byte, bool, error) {
+	f.targets = append(f.targets, target)
+	f.round++
+	switch {
+	case f.round == 1 && token == nil:
+		return []byte("tok1"), true, nil
+	case f.round == 2 && string(token) == "tok2":
+		return []byte("tok3"), false, nil
+	}
+	return nil, false, fmt.Errorf("unexpected token %q in round %d", token, f.round)
+}
+
+type fakeGSSKexServer struct {
+	fakeGSSKexMech
+}
+
+func (f *fakeGSSKexServer) AcceptSecContext(token []byte) ([]byte, string, bool, error) {
+	f.round++
+	switch {
+	case f.round == 1 && string(token) == "tok1":
+		return []byte("tok2"), "user@EXAMPLE.COM", true, nil
+	case f.round == 2 && string(token) == "tok3":
+		return nil, "user@EXAMPLE.COM", false, nil
+	}
+	return nil, "", false, fmt.Errorf("unexpected token %q in round %d", token, f.round)
+}
+
+func TestGSSAPIKeyExchange(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		kex       []string
+		serverKey string
+		hostKey   string
+		wantErr   bool
+	}{
+		{"nistp256", nil, "secret", "ecdsa", false},
+		{"group14", []string{kexAlgoGSSGroup14SHA256}, "secret", "ecdsa", false},
+		{"null host key", nil, "secret", "", false},
+		{"bad MIC", nil, "other secret", "ecdsa", true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			c1, c2, err := netPipe()
+			if err != nil {
+				t.Fatalf("netPipe: %v", err)
+			}
+			defer c1.Close()
+			defer c2.Close()
+
+			serverConf := &ServerConfig{
+				NoClientAuth:      true,
+				GSSAPIKeyExchange: &fakeGSSKexServer{fakeGSSKexMech{key: []byte(tt.serverKey)}},
+			}
+			if tt.hostKey != "" {
+				serverConf.AddHostKey(testSigners[tt.hostKey])
+			}
+			go func() {
+				conn, _, _, err := NewServerConn(c1, serverConf)
+				if err == nil {
+					conn.Close()
+				}
+			}()
+
+			gss := &fakeGSSKexClient{fakeGSSKexMech: fakeGSSKexMech{key: []byte("secret")}}
+			clientConf := &ClientConfig{
+				User: "user",
+				HostKeyCallback: func(hostname string, remote net.Addr, key PublicKey) error {
+					return errors.New("HostKeyCallback called")
+				},
+				GSSAPIKeyExchange: &GSSAPIKexConfig{Client: gss, Target: "server.example.com"},
+			}
+			clientConf.KeyExchanges = tt.kex
+			conn, _, _, err := NewClientConn(c2, "", clientConf)
+			if tt.wantErr {
+				if err == nil {
+					conn.Close()
+					t.Fatal("NewClientConn succeeded with a bad MIC")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewClientConn: %v", err)
+			}
+			defer conn.Close()
+
+			want := tt.kex
+			if want == nil {
+				want = []string{kexAlgoGSSNISTP256}
+			}
+			algs := conn.(AlgorithmsConnMetadata).Algorithms()
+			if algs.KeyExchange != want[0] {
+				t.Errorf("negotiated key exchange %q, want %q", algs.KeyExchange, want[0])
+			}
+			if (algs.HostKey == hostKeyAlgoNull) != (tt.hostKey == "") {
+				t.Errorf("negotiated host key algorithm %q with host key %q", algs.HostKey, tt.hostKey)
+			}
+			if len(gss.targets) == 0 || gss.targets[0] != "host@server.example.com" {
+				t.Errorf("security context established with %q, want host@server.example.com", gss.targets)
+			}
+		})
+	}
+}
+
+func TestGSSAPIKexNames(t *testing.T) {
+	// See RFC 4462, section 2.
+	const suffix = "toWM5Slw5Ew8Mqkay+al2g=="
+	if kexAlgoGSSGroup14SHA256 != "gss-group14-sha256-"+suffix || kexAlgoGSSNISTP256 != "gss-nistp256-sha256-"+suffix {
+		t.Errorf("got %q and %q, want names ending in %q", kexAlgoGSSGroup14SHA256, kexAlgoGSSNISTP256, suffix)
+	}
+}
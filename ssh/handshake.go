@@ -5,14 +5,17 @@
 package ssh
 
 import (
+	"context"
 	"crypto/rand"
 	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 )
 
 // debugHandshake, if set, prints messages sent and received.  Key
@@ -56,9 +59,12 @@ type handshakeTransport struct {
 	serverVersion []byte
 	clientVersion []byte
 
-	// hostKeys is non-empty if we are the server. In that case,
-	// it contains all host keys that can be used to sign the
-	// connection.
+	// isServer is set if we are the server.
+	isServer bool
+
+	// hostKeys contains all host keys that can be used to sign the
+	// connection, if we are the server. It may be empty if only GSSAPI
+	// key exchange is used.
 	hostKeys []Signer
 
 	// publicKeyAuthAlgorithms is non-empty if we are the server. In that case,
@@ -69,6 +75,11 @@ type handshakeTransport struct {
 	// we accept these key types from the server as host key.
 	hostKeyAlgorithms []string
 
+	// peerExtInfo holds the extensions the peer advertised in
+	// SSH_MSG_EXT_INFO messages. It is only accessed by the goroutine
+	// driving authentication.
+	peerExtInfo map[string][]byte
+
 	// On read error, incoming is closed, and readError is set.
 	incoming  chan []byte
 	readError error
@@ -80,6 +91,7 @@ type handshakeTransport struct {
 	pendingPackets   [][]byte // Used when a key exchange is in progress.
 	writePacketsLeft uint32
 	writeBytesLeft   int64
+	negotiated       NegotiatedAlgorithms // set when a key exchange completes
 
 	// If the read loop wants to schedule a kex, it pings this
 	// channel, and the write loop will send out a kex
@@ -101,9 +113,22 @@ type handshakeTransport struct {
 	// dance to handle a custom server's message.
 	bannerCallback BannerCallback
 
+	// banner is the concatenation of the banners received during
+	// authentication, if we are the client.
+	banner string
+
+	// gssKexConfig and gssKexServer provide the GSSAPI security contexts
+	// of GSSAPI key exchanges, on the client and the server respectively.
+	gssKexConfig *GSSAPIKexConfig
+	gssKexServer GSSAPIKexServer
+
 	// Algorithms agreed in the last key exchange.
 	algorithms *algorithms
 
+	// rekeyTimer requests a key exchange once Config.RekeyAfterDuration
+	// has passed since the last one. It is only accessed by kexLoop.
+	rekeyTimer *time.Timer
+
 	// Counters exclusively owned by readLoop.
 	readPacketsLeft uint32
 	readBytesLeft   int64
@@ -111,6 +136,11 @@ type handshakeTransport struct {
 	// The session ID or nil if first kex did not complete yet.
 	sessionID []byte
 
+	// sessionHostKey and sessionSignature are the host key and the
+	// signature of the session ID of the first kex, on clients.
+	sessionHostKey   []byte
+	sessionSignature []byte
+
 	// strictMode indicates if the other side of the handshake indicated
 	// that we should be following the strict KEX protocol restrictions.
 	strictMode bool
@@ -123,13 +153,14 @@ type pendingKex struct {
 
 func newHandshakeTransport(conn keyingTransport, config *Config, clientVersion, serverVersion []byte) *handshakeTransport {
 	t := &handshakeTransport{
-		conn:          conn,
+		conn:          newTracingTransport(newMetricsTransport(conn, config), config),
 		serverVersion: serverVersion,
 		clientVersion: clientVersion,
 		incoming:      make(chan []byte, chanSize),
 		requestKex:    make(chan struct{}, 1),
 		startKex:      make(chan *pendingKex),
 		kexLoopDone:   make(chan struct{}),
+		peerExtInfo:   make(map[string][]byte),
 
 		config: config,
 	}
@@ -147,11 +178,15 @@ func newClientTransport(conn keyingTransport, clientVersion, serverVersion []byt
 	t.remoteAddr = addr
 	t.hostKeyCallback = config.HostKeyCallback
 	t.bannerCallback = config.BannerCallback
+	t.gssKexConfig = config.GSSAPIKeyExchange
 	if config.HostKeyAlgorithms != nil {
 		t.hostKeyAlgorithms = config.HostKeyAlgorithms
 	} else {
 		t.hostKeyAlgorithms = supportedHostKeyAlgos
 	}
+	if t.gssKexConfig != nil && !contains(t.hostKeyAlgorithms, hostKeyAlgoNull) {
+		t.hostKeyAlgorithms = append(cloneStrings(t.hostKeyAlgorithms), hostKeyAlgoNull)
+	}
 	go t.readLoop()
 	go t.kexLoop()
 	return t
@@ -159,8 +194,10 @@ func newClientTransport(conn keyingTransport, clientVersion, serverVersion []byt
 
 func newServerTransport(conn keyingTransport, clientVersion, serverVersion []byte, config *ServerConfig) *handshakeTransport {
 	t := newHandshakeTransport(conn, &config.Config, clientVersion, serverVersion)
+	t.isServer = true
 	t.hostKeys = config.hostKeys
 	t.publicKeyAuthAlgorithms = config.PublicKeyAuthAlgorithms
+	t.gssKexServer = config.GSSAPIKeyExchange
 	go t.readLoop()
 	go t.kexLoop()
 	return t
@@ -170,6 +207,14 @@ func (t *handshakeTransport) getSessionID() []byte {
 	return t.sessionID
 }
 
+// getAlgorithms returns the algorithms negotiated in the last completed key
+// exchange.
+func (t *handshakeTransport) getAlgorithms() NegotiatedAlgorithms {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.negotiated
+}
+
 // waitSession waits for the session to be established. This should be
 // the first thing to call after instantiating handshakeTransport.
 func (t *handshakeTransport) waitSession() error {
@@ -185,7 +230,7 @@ func (t *handshakeTransport) waitSession() error {
 }
 
 func (t *handshakeTransport) id() string {
-	if len(t.hostKeys) > 0 {
+	if t.isServer {
 		return "server"
 	}
 	return "client"
@@ -281,6 +326,20 @@ func (t *handshakeTransport) resetWriteThresholds() {
 	}
 }
 
+// resetRekeyTimer schedules the next key exchange if
+// Config.RekeyAfterDuration is set.
+func (t *handshakeTransport) resetRekeyTimer() {
+	d := t.config.RekeyAfterDuration
+	if d <= 0 {
+		return
+	}
+	if t.rekeyTimer == nil {
+		t.rekeyTimer = time.AfterFunc(d, t.requestKeyExchange)
+	} else {
+		t.rekeyTimer.Reset(d)
+	}
+}
+
 func (t *handshakeTransport) kexLoop() {
 
 write:
@@ -324,13 +383,25 @@ write:
 		// channel on the pendingKex request.
 
 		err := t.enterKeyExchange(request.otherInit)
+		if err != nil {
+			t.config.Logger.Info("ssh: key exchange failed", "error", err)
+		} else {
+			t.config.Metrics.KeyExchange()
+			t.config.Logger.Debug("ssh: key exchange finished",
+				"kex", t.algorithms.kex, "host_key", t.algorithms.hostKey,
+				"read_cipher", t.algorithms.r.Cipher, "write_cipher", t.algorithms.w.Cipher)
+		}
 
 		t.mu.Lock()
 		t.writeError = err
 		t.sentInitPacket = nil
 		t.sentInitMsg = nil
+		if err == nil {
+			t.negotiated = t.algorithms.negotiated()
+		}
 
 		t.resetWriteThresholds()
+		t.resetRekeyTimer()
 
 		// we have completed the key exchange. Since the
 		// reader is still blocked, it is safe to clear out
@@ -365,6 +436,10 @@ write:
 		t.mu.Unlock()
 	}
 
+	if t.rekeyTimer != nil {
+		t.rekeyTimer.Stop()
+	}
+
 	// Unblock reader.
 	t.conn.Close()
 
@@ -480,8 +555,8 @@ func (t *handshakeTransport) sendKexInit() error {
 		CiphersServerClient:     t.config.Ciphers,
 		MACsClientServer:        t.config.MACs,
 		MACsServerClient:        t.config.MACs,
-		CompressionClientServer: supportedCompressions,
-		CompressionServerClient: supportedCompressions,
+		CompressionClientServer: t.config.Compressions,
+		CompressionServerClient: t.config.Compressions,
 	}
 	io.ReadFull(rand.Reader, msg.Cookie[:])
 
@@ -492,8 +567,7 @@ func (t *handshakeTransport) sendKexInit() error {
 	msg.KexAlgos = make([]string, 0, len(t.config.KeyExchanges)+2) // room for kex-strict and ext-info
 	msg.KexAlgos = append(msg.KexAlgos, t.config.KeyExchanges...)
 
-	isServer := len(t.hostKeys) > 0
-	if isServer {
+	if t.isServer {
 		for _, k := range t.hostKeys {
 			// If k is a MultiAlgorithmSigner, we restrict the signature
 			// algorithms. If k is a AlgorithmSigner, presume it supports all
@@ -516,8 +590,14 @@ func (t *handshakeTransport) sendKexInit() error {
 				msg.ServerHostKeyAlgos = append(msg.ServerHostKeyAlgos, keyFormat)
 			}
 		}
+		if t.gssKexServer != nil {
+			msg.ServerHostKeyAlgos = append(msg.ServerHostKeyAlgos, hostKeyAlgoNull)
+		}
 
+		// As a server we accept a SSH_MSG_EXT_INFO from the client, see RFC
+		// 8308, Section 2.1, and opt into the strict KEX mode.
 		if t.sessionID == nil {
+			msg.KexAlgos = append(msg.KexAlgos, "ext-info-s")
 			msg.KexAlgos = append(msg.KexAlgos, kexStrictServer)
 		}
 	} else {
@@ -610,6 +690,7 @@ func (t *handshakeTransport) enterKeyExchange(otherInitPacket []byte) error {
 	if debugHandshake {
 		log.Printf("%s entered key exchange", t.id())
 	}
+	t.config.Logger.Debug("ssh: key exchange started", "first", t.sessionID == nil)
 
 	otherInit := &kexInitMsg{}
 	if err := Unmarshal(otherInitPacket, otherInit); err != nil {
@@ -625,7 +706,7 @@ func (t *handshakeTransport) enterKeyExchange(otherInitPacket []byte) error {
 
 	clientInit := otherInit
 	serverInit := t.sentInitMsg
-	isClient := len(t.hostKeys) == 0
+	isClient := !t.isServer
 	if isClient {
 		clientInit, serverInit = serverInit, clientInit
 
@@ -668,9 +749,12 @@ func (t *handshakeTransport) enterKeyExchange(otherInitPacket []byte) error {
 	if !ok {
 		return fmt.Errorf("ssh: unexpected key exchange algorithm %v", t.algorithms.kex)
 	}
+	if _, ok := kex.(*gssKex); !ok && t.algorithms.hostKey == hostKeyAlgoNull {
+		return fmt.Errorf("ssh: key exchange %s requires a host key", t.algorithms.kex)
+	}
 
 	var result *kexResult
-	if len(t.hostKeys) > 0 {
+	if t.isServer {
 		result, err = t.server(kex, &magics)
 	} else {
 		result, err = t.client(kex, &magics)
@@ -683,6 +767,10 @@ func (t *handshakeTransport) enterKeyExchange(otherInitPacket []byte) error {
 	firstKeyExchange := t.sessionID == nil
 	if firstKeyExchange {
 		t.sessionID = result.H
+		if isClient && len(result.Signature) > 0 {
+			t.sessionHostKey = result.HostKey
+			t.sessionSignature = result.Signature
+		}
 	}
 	result.SessionID = t.sessionID
 
@@ -693,25 +781,14 @@ func (t *handshakeTransport) enterKeyExchange(otherInitPacket []byte) error {
 		return err
 	}
 
-	// On the server side, after the first SSH_MSG_NEWKEYS, send a SSH_MSG_EXT_INFO
-	// message with the server-sig-algs extension if the client supports it. See
-	// RFC 8308, Sections 2.4 and 3.1, and [PROTOCOL], Section 1.9.
-	if !isClient && firstKeyExchange && contains(clientInit.KexAlgos, "ext-info-c") {
-		supportedPubKeyAuthAlgosList := strings.Join(t.publicKeyAuthAlgorithms, ",")
-		extInfo := &extInfoMsg{
-			NumExtensions: 2,
-			Payload:       make([]byte, 0, 4+15+4+len(supportedPubKeyAuthAlgosList)+4+16+4+1),
-		}
-		extInfo.Payload = appendInt(extInfo.Payload, len("server-sig-algs"))
-		extInfo.Payload = append(extInfo.Payload, "server-sig-algs"...)
-		extInfo.Payload = appendInt(extInfo.Payload, len(supportedPubKeyAuthAlgosList))
-		extInfo.Payload = append(extInfo.Payload, supportedPubKeyAuthAlgosList...)
-		extInfo.Payload = appendInt(extInfo.Payload, len("ping@openssh.com"))
-		extInfo.Payload = append(extInfo.Payload, "ping@openssh.com"...)
-		extInfo.Payload = appendInt(extInfo.Payload, 1)
-		extInfo.Payload = append(extInfo.Payload, "0"...)
-		if err := t.conn.writePacket(Marshal(extInfo)); err != nil {
-			return err
+	// After the first SSH_MSG_NEWKEYS, send a SSH_MSG_EXT_INFO message if the
+	// peer supports it. See RFC 8308, Sections 2.4 and 3.1, and [PROTOCOL],
+	// Section 1.9.
+	if firstKeyExchange {
+		if extInfo := t.extInfo(isClient, clientInit, serverInit); extInfo != nil {
+			if err := t.conn.writePacket(Marshal(extInfo)); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -730,6 +807,84 @@ func (t *handshakeTransport) enterKeyExchange(otherInitPacket []byte) error {
 	return nil
 }
 
+// extInfo returns the SSH_MSG_EXT_INFO message to send after the first
+// key exchange, or nil if there is nothing to send or the peer does not
+// accept it.
+func (t *handshakeTransport) extInfo(isClient bool, clientInit, serverInit *kexInitMsg) *extInfoMsg {
+	var names []string
+	values := make(map[string][]byte)
+	if isClient {
+		if !contains(serverInit.KexAlgos, "ext-info-s") {
+			return nil
+		}
+	} else {
+		if !contains(clientInit.KexAlgos, "ext-info-c") {
+			return nil
+		}
+		names = append(names, "server-sig-algs", pingExtension)
+		values["server-sig-algs"] = []byte(strings.Join(t.publicKeyAuthAlgorithms, ","))
+		values[pingExtension] = []byte("0")
+	}
+
+	var extra []string
+	for name, value := range t.config.ExtInfo {
+		if _, ok := values[name]; ok {
+			continue
+		}
+		extra = append(extra, name)
+		values[name] = value
+	}
+	sort.Strings(extra)
+	names = append(names, extra...)
+	if len(names) == 0 {
+		return nil
+	}
+
+	extInfo := &extInfoMsg{NumExtensions: uint32(len(names))}
+	for _, name := range names {
+		extInfo.Payload = appendString(extInfo.Payload, name)
+		extInfo.Payload = appendString(extInfo.Payload, string(values[name]))
+	}
+	return extInfo
+}
+
+// parseExtInfo parses a SSH_MSG_EXT_INFO message and returns the extensions
+// it contains, keyed by name. If a name is repeated, the last value wins.
+func parseExtInfo(packet []byte) (map[string][]byte, error) {
+	var extInfo extInfoMsg
+	if err := Unmarshal(packet, &extInfo); err != nil {
+		return nil, err
+	}
+	extensions := make(map[string][]byte)
+	payload := extInfo.Payload
+	for i := uint32(0); i < extInfo.NumExtensions; i++ {
+		name, rest, ok := parseString(payload)
+		if !ok {
+			return nil, parseError(msgExtInfo)
+		}
+		value, rest, ok := parseString(rest)
+		if !ok {
+			return nil, parseError(msgExtInfo)
+		}
+		extensions[string(name)] = value
+		payload = rest
+	}
+	return extensions, nil
+}
+
+// recordExtInfo parses a SSH_MSG_EXT_INFO message received from the peer and
+// merges its extensions into peerExtInfo, replacing earlier values.
+func (t *handshakeTransport) recordExtInfo(packet []byte) error {
+	extensions, err := parseExtInfo(packet)
+	if err != nil {
+		return err
+	}
+	for name, value := range extensions {
+		t.peerExtInfo[name] = value
+	}
+	return nil
+}
+
 // algorithmSignerWrapper is an AlgorithmSigner that only supports the default
 // key format algorithm.
 //
@@ -747,6 +902,13 @@ func (a algorithmSignerWrapper) SignWithAlgorithm(rand io.Reader, data []byte, a
 	return a.Sign(rand, data)
 }
 
+func (a algorithmSignerWrapper) SignWithContext(ctx context.Context, rand io.Reader, data []byte, algorithm string) (*Signature, error) {
+	if algorithm != underlyingAlgo(a.PublicKey().Type()) {
+		return nil, errors.New("ssh: internal error: algorithmSignerWrapper invoked with non-default algorithm")
+	}
+	return signWithContext(ctx, a.Signer, rand, data, algorithm)
+}
+
 func pickHostKey(hostKeys []Signer, algo string) AlgorithmSigner {
 	for _, k := range hostKeys {
 		if s, ok := k.(MultiAlgorithmSigner); ok {
@@ -773,16 +935,35 @@ func pickHostKey(hostKeys []Signer, algo string) AlgorithmSigner {
 }
 
 func (t *handshakeTransport) server(kex kexAlgorithm, magics *handshakeMagics) (*kexResult, error) {
+	if gss, ok := kex.(*gssKex); ok {
+		if t.gssKexServer == nil {
+			return nil, errGSSKexNotConfigured
+		}
+		// The host key is only sent, not used to sign, and there is none
+		// for the null host key algorithm.
+		var hostKey PublicKey
+		if k := pickHostKey(t.hostKeys, t.algorithms.hostKey); k != nil {
+			hostKey = k.PublicKey()
+		}
+		return gss.server(t.conn, t.config.Rand, magics, t.gssKexServer, hostKey)
+	}
+
 	hostKey := pickHostKey(t.hostKeys, t.algorithms.hostKey)
 	if hostKey == nil {
 		return nil, errors.New("ssh: internal error: negotiated unsupported signature type")
 	}
-
 	r, err := kex.Server(t.conn, t.config.Rand, magics, hostKey, t.algorithms.hostKey)
 	return r, err
 }
 
 func (t *handshakeTransport) client(kex kexAlgorithm, magics *handshakeMagics) (*kexResult, error) {
+	if gss, ok := kex.(*gssKex); ok {
+		if t.gssKexConfig == nil {
+			return nil, errGSSKexNotConfigured
+		}
+		// The server is authenticated by the GSSAPI security context.
+		return gss.client(t.conn, t.config.Rand, magics, t.gssKexConfig)
+	}
 	result, err := kex.Client(t.conn, t.config.Rand, magics)
 	if err != nil {
 		return nil, err
@@ -799,7 +980,12 @@ func (t *handshakeTransport) client(kex kexAlgorithm, magics *handshakeMagics) (
 
 	err = t.hostKeyCallback(t.dialAddress, t.remoteAddr, hostKey)
 	if err != nil {
-		return nil, err
+		return nil, &HostKeyError{
+			Hostname: t.dialAddress,
+			Remote:   t.remoteAddr,
+			Key:      hostKey,
+			Err:      err,
+		}
 	}
 
 	return result, nil
@@ -16,6 +16,7 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
 type testChecker struct {
@@ -440,6 +441,7 @@ func testHandshakeErrorHandlingN(t *testing.T, readLimit, writeLimit int, couple
 	serverConf := Config{RekeyThreshold: minRekeyThreshold}
 	serverConf.SetDefaults()
 	serverConn := newHandshakeTransport(&errorKeyingTransport{a, readLimit, writeLimit}, &serverConf, []byte{'a'}, []byte{'b'})
+	serverConn.isServer = true
 	serverConn.hostKeys = []Signer{key}
 	go serverConn.readLoop()
 	go serverConn.kexLoop()
@@ -590,6 +592,51 @@ func TestHandshakeAEADCipherNoMAC(t *testing.T) {
 	}
 }
 
+// TestHandshakeAES128GCMOnly checks that clients and servers with the
+// default configuration agree on aes128-gcm@openssh.com with peers that
+// offer nothing else, as some embedded implementations do.
+func TestHandshakeAES128GCMOnly(t *testing.T) {
+	for _, side := range []string{"client", "server"} {
+		c1, c2, err := netPipe()
+		if err != nil {
+			t.Fatalf("netPipe: %v", err)
+		}
+		defer c1.Close()
+		defer c2.Close()
+
+		serverConf := &ServerConfig{NoClientAuth: true}
+		serverConf.AddHostKey(testSigners["ecdsa"])
+		clientConf := &ClientConfig{HostKeyCallback: InsecureIgnoreHostKey()}
+		if side == "server" {
+			serverConf.Ciphers = []string{gcm128CipherID}
+		} else {
+			clientConf.Ciphers = []string{gcm128CipherID}
+		}
+		go func() {
+			conn, chans, reqs, err := NewServerConn(c2, serverConf)
+			if err != nil {
+				return
+			}
+			go DiscardRequests(reqs)
+			go func() {
+				for range chans {
+				}
+			}()
+			conn.Wait()
+		}()
+		conn, chans, reqs, err := NewClientConn(c1, "", clientConf)
+		if err != nil {
+			t.Fatalf("%s offering only %s: NewClientConn: %v", side, gcm128CipherID, err)
+		}
+		client := NewClient(conn, chans, reqs)
+		algs := client.Algorithms()
+		if algs.Read.Cipher != gcm128CipherID || algs.Write.Cipher != gcm128CipherID {
+			t.Errorf("%s offering only %s: negotiated %q and %q", side, gcm128CipherID, algs.Read.Cipher, algs.Write.Cipher)
+		}
+		client.Close()
+	}
+}
+
 // TestNoSHA2Support tests a host key Signer that is not an AlgorithmSigner and
 // therefore can't do SHA-2 signatures. Ensures the server does not advertise
 // support for them in this case.
@@ -966,6 +1013,7 @@ func TestStrictKEXMixed(t *testing.T) {
 	serverConf.SetDefaults()
 
 	transport := newHandshakeTransport(trS, &serverConf.Config, []byte("version"), []byte("version"))
+	transport.isServer = true
 	transport.hostKeys = serverConf.hostKeys
 	transport.publicKeyAuthAlgorithms = serverConf.PublicKeyAuthAlgorithms
 
@@ -1019,3 +1067,289 @@ func TestStrictKEXMixed(t *testing.T) {
 		t.Fatalf("client.waitSession: %v", err)
 	}
 }
+
+func TestExtInfoCustomExtensions(t *testing.T) {
+	c1, c2, err := netPipe()
+	if err != nil {
+		t.Fatalf("netPipe: %v", err)
+	}
+	defer c1.Close()
+	defer c2.Close()
+
+	clientSeen := make(chan map[string][]byte, 1)
+	serverConf := &ServerConfig{
+		Config: Config{
+			ExtInfo: map[string][]byte{
+				"server-sig-algs":   []byte("ignored"),
+				"custom@server.com": []byte("server value"),
+			},
+		},
+		PasswordCallback: func(conn ConnMetadata, password []byte) (*Permissions, error) {
+			clientSeen <- conn.(ExtInfoConnMetadata).PeerExtInfo()
+			return nil, nil
+		},
+	}
+	serverConf.AddHostKey(testSigners["ecdsa"])
+	go func() {
+		conn, _, _, err := NewServerConn(c2, serverConf)
+		if err == nil {
+			conn.Close()
+		}
+	}()
+
+	clientConf := &ClientConfig{
+		Config: Config{
+			ExtInfo: map[string][]byte{"custom@client.com": []byte("client value")},
+		},
+		User:            "user",
+		Auth:            []AuthMethod{Password("password")},
+		HostKeyCallback: InsecureIgnoreHostKey(),
+	}
+	conn, _, _, err := NewClientConn(c1, "", clientConf)
+	if err != nil {
+		t.Fatalf("NewClientConn: %v", err)
+	}
+	defer conn.Close()
+
+	got := conn.(ExtInfoConnMetadata).PeerExtInfo()
+	if v := string(got["custom@server.com"]); v != "server value" {
+		t.Errorf("client saw custom@server.com %q, want %q", v, "server value")
+	}
+	if v := string(got["server-sig-algs"]); v == "ignored" || !strings.Contains(v, KeyAlgoED25519) {
+		t.Errorf("client saw server-sig-algs %q", v)
+	}
+	if v := string(got["ping@openssh.com"]); v != "0" {
+		t.Errorf("client saw ping@openssh.com %q, want %q", v, "0")
+	}
+
+	serverGot := <-clientSeen
+	if v := string(serverGot["custom@client.com"]); v != "client value" || len(serverGot) != 1 {
+		t.Errorf("server saw extensions %q, want only custom@client.com", serverGot)
+	}
+}
+
+func TestParseExtInfo(t *testing.T) {
+	valid := &extInfoMsg{NumExtensions: 3}
+	for _, s := range []string{"a", "1", "b", "2", "a", "3"} {
+		valid.Payload = appendString(valid.Payload, s)
+	}
+	got, err := parseExtInfo(Marshal(valid))
+	if err != nil {
+		t.Fatalf("parseExtInfo: %v", err)
+	}
+	if len(got) != 2 || string(got["a"]) != "3" || string(got["b"]) != "2" {
+		t.Errorf("parseExtInfo returned %q", got)
+	}
+
+	truncated := &extInfoMsg{NumExtensions: 2, Payload: appendString(nil, "a")}
+	if _, err := parseExtInfo(Marshal(truncated)); err == nil {
+		t.Error("parseExtInfo succeeded on truncated payload")
+	}
+}
+
+func TestNegotiatedAlgorithms(t *testing.T) {
+	c1, c2, err := netPipe()
+	if err != nil {
+		t.Fatalf("netPipe: %v", err)
+	}
+	defer c1.Close()
+	defer c2.Close()
+
+	conf := Config{
+		KeyExchanges: []string{kexAlgoECDH256},
+		Ciphers:      []string{"aes128-ctr", "aes256-ctr"},
+		MACs:         []string{"hmac-sha2-256"},
+		Compressions: []string{compressionZlib},
+	}
+	authAlgs := make(chan NegotiatedAlgorithms, 1)
+	serverConf := &ServerConfig{
+		Config: conf,
+		PasswordCallback: func(conn ConnMetadata, password []byte) (*Permissions, error) {
+			authAlgs <- conn.(AlgorithmsConnMetadata).Algorithms()
+			return nil, nil
+		},
+	}
+	serverConf.AddHostKey(testSigners["ecdsa"])
+	serverDone := make(chan *ServerConn, 1)
+	go func() {
+		conn, _, reqs, err := NewServerConn(c2, serverConf)
+		if err != nil {
+			serverDone <- nil
+			return
+		}
+		go DiscardRequests(reqs)
+		serverDone <- conn
+	}()
+
+	clientConf := &ClientConfig{
+		Config:          conf,
+		User:            "user",
+		Auth:            []AuthMethod{Password("password")},
+		HostKeyCallback: InsecureIgnoreHostKey(),
+	}
+	clientConf.Ciphers = []string{"aes256-ctr", "aes128-ctr"}
+	conn, chans, reqs, err := NewClientConn(c1, "", clientConf)
+	if err != nil {
+		t.Fatalf("NewClientConn: %v", err)
+	}
+	client := NewClient(conn, chans, reqs)
+	defer client.Close()
+
+	server := <-serverDone
+	if server == nil {
+		t.Fatal("NewServerConn failed")
+	}
+	defer server.Close()
+
+	dir := DirectionAlgorithms{Cipher: "aes256-ctr", MAC: "hmac-sha2-256", Compression: compressionZlib}
+	want := NegotiatedAlgorithms{
+		KeyExchange: kexAlgoECDH256,
+		HostKey:     KeyAlgoECDSA256,
+		Read:        dir,
+		Write:       dir,
+	}
+	if got := client.Algorithms(); got != want {
+		t.Errorf("client negotiated %+v, want %+v", got, want)
+	}
+	if got := server.Algorithms(); got != want {
+		t.Errorf("server negotiated %+v, want %+v", got, want)
+	}
+	if got := <-authAlgs; got != want {
+		t.Errorf("server saw %+v during authentication, want %+v", got, want)
+	}
+}
+
+func TestHandshakeRekeyAfterDuration(t *testing.T) {
+	checker := &syncChecker{called: make(chan int, 10)}
+	clientConf := &ClientConfig{HostKeyCallback: checker.Check}
+	clientConf.RekeyAfterDuration = 20 * time.Millisecond
+	trC, trS, err := handshakePair(clientConf, "addr", false)
+	if err != nil {
+		t.Fatalf("handshakePair: %v", err)
+	}
+	defer trC.Close()
+	defer trS.Close()
+
+	go func() {
+		for {
+			if _, err := trS.readPacket(); err != nil {
+				return
+			}
+		}
+	}()
+
+	// The first call is for the initial key exchange.
+	for i := 0; i < 3; i++ {
+		select {
+		case <-checker.called:
+		case <-time.After(10 * time.Second):
+			t.Fatalf("got %d key exchanges, want 3", i)
+		}
+	}
+}
+
+func TestForceRekey(t *testing.T) {
+	c1, c2, err := netPipe()
+	if err != nil {
+		t.Fatalf("netPipe: %v", err)
+	}
+	defer c1.Close()
+	defer c2.Close()
+
+	serverConf := &ServerConfig{NoClientAuth: true}
+	serverConf.AddHostKey(testSigners["ecdsa"])
+	go func() {
+		conn, chans, reqs, err := NewServerConn(c2, serverConf)
+		if err != nil {
+			return
+		}
+		go DiscardRequests(reqs)
+		for range chans {
+		}
+		conn.Close()
+	}()
+
+	checker := &syncChecker{called: make(chan int, 10)}
+	clientConf := &ClientConfig{
+		User:            "user",
+		HostKeyCallback: checker.Check,
+	}
+	conn, chans, reqs, err := NewClientConn(c1, "", clientConf)
+	if err != nil {
+		t.Fatalf("NewClientConn: %v", err)
+	}
+	client := NewClient(conn, chans, reqs)
+	defer client.Close()
+	<-checker.called
+
+	if err := client.ForceRekey(); err != nil {
+		t.Fatalf("ForceRekey: %v", err)
+	}
+	select {
+	case <-checker.called:
+	case <-time.After(10 * time.Second):
+		t.Fatal("ForceRekey did not start a key exchange")
+	}
+
+	client.Close()
+	client.Wait()
+	if err := client.ForceRekey(); err == nil {
+		t.Error("ForceRekey on a closed connection succeeded")
+	}
+}
+
+func TestCurve448Handshake(t *testing.T) {
+	c1, c2, err := netPipe()
+	if err != nil {
+		t.Fatalf("netPipe: %v", err)
+	}
+	defer c1.Close()
+	defer c2.Close()
+
+	hostKey, userKey := testSigners["ed25519"], testSigners["ecdsa"]
+
+	conf := Config{KeyExchanges: []string{kexAlgoCurve448SHA512}}
+	serverConf := &ServerConfig{
+		Config: conf,
+		PublicKeyCallback: func(conn ConnMetadata, key PublicKey) (*Permissions, error) {
+			if !bytes.Equal(key.Marshal(), userKey.PublicKey().Marshal()) {
+				return nil, errors.New("unknown key")
+			}
+			return nil, nil
+		},
+	}
+	serverConf.AddHostKey(hostKey)
+	serverDone := make(chan *ServerConn, 1)
+	go func() {
+		conn, _, reqs, err := NewServerConn(c2, serverConf)
+		if err != nil {
+			serverDone <- nil
+			return
+		}
+		go DiscardRequests(reqs)
+		serverDone <- conn
+	}()
+
+	clientConf := &ClientConfig{
+		Config:          conf,
+		User:            "user",
+		Auth:            []AuthMethod{PublicKeys(userKey)},
+		HostKeyCallback: FixedHostKey(hostKey.PublicKey()),
+	}
+	conn, chans, reqs, err := NewClientConn(c1, "", clientConf)
+	if err != nil {
+		t.Fatalf("NewClientConn: %v", err)
+	}
+	client := NewClient(conn, chans, reqs)
+	defer client.Close()
+
+	server := <-serverDone
+	if server == nil {
+		t.Fatal("NewServerConn failed")
+	}
+	defer server.Close()
+
+	if got := client.Algorithms(); got.KeyExchange != kexAlgoCurve448SHA512 {
+		t.Errorf("negotiated %s, want %s", got.KeyExchange, kexAlgoCurve448SHA512)
+	}
+}
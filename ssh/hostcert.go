@@ -0,0 +1,111 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// A HostAuthority is a certificate authority trusted to sign host
+// certificates, like a @cert-authority line of known_hosts.
+type HostAuthority struct {
+	Key PublicKey
+
+	// Hosts limits the authority to hosts matching the patterns, in the
+	// format of MatchPrincipalPattern. Like in known_hosts, hosts dialed on
+	// port 22 are matched by name and others as "[name]:port", and names
+	// are matched in lower case. If empty, the authority may sign for any
+	// host.
+	Hosts []string
+}
+
+// HostCertChecker verifies host certificates for clients. Its CheckHostKey
+// method can be used as ClientConfig.HostKeyCallback.
+type HostCertChecker struct {
+	// Authorities are the certificate authorities trusted to sign host
+	// certificates.
+	Authorities []HostAuthority
+
+	// Clock is used for verifying time stamps. If nil, time.Now is used.
+	Clock func() time.Time
+
+	// ClockSkew is how far the clock may differ from the one of the
+	// authorities, see CertChecker.ClockSkew.
+	ClockSkew time.Duration
+
+	// MatchPrincipal is used to match the host name against the
+	// principals of certificates. If nil, MatchPrincipalExact is used.
+	MatchPrincipal PrincipalMatcher
+
+	// IsRevoked, if not nil, should report whether a certificate is
+	// revoked.
+	IsRevoked func(cert *Certificate) bool
+
+	// Fallback is called with keys that are not certificates, for example
+	// to check them against known_hosts. If nil, such keys are rejected.
+	Fallback HostKeyCallback
+}
+
+// HostCertCallback returns a HostKeyCallback that accepts host certificates
+// signed by any of authorities for the dialed host, and passes plain keys to
+// fallback, which may be nil to reject them.
+func HostCertCallback(fallback HostKeyCallback, authorities ...PublicKey) HostKeyCallback {
+	h := &HostCertChecker{Fallback: fallback}
+	for _, key := range authorities {
+		h.Authorities = append(h.Authorities, HostAuthority{Key: key})
+	}
+	return h.CheckHostKey
+}
+
+// CheckHostKey checks the host key of the server dialed at addr.
+func (h *HostCertChecker) CheckHostKey(addr string, remote net.Addr, key PublicKey) error {
+	cert, ok := key.(*Certificate)
+	if !ok {
+		if h.Fallback != nil {
+			return h.Fallback(addr, remote, key)
+		}
+		return errors.New("ssh: non-certificate host key")
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	target := strings.ToLower(host)
+	if port != "22" {
+		target = "[" + target + "]:" + port
+	}
+
+	checker := CertChecker{
+		IsHostAuthority: func(auth PublicKey, _ string) bool {
+			return h.isAuthority(auth, target)
+		},
+		Clock:              h.Clock,
+		ClockSkew:          h.ClockSkew,
+		MatchHostPrincipal: h.MatchPrincipal,
+		IsRevoked:          h.IsRevoked,
+	}
+	if err := checker.CheckHostKey(addr, remote, cert); err != nil {
+		return fmt.Errorf("ssh: host certificate for %s rejected: %w", addr, err)
+	}
+	return nil
+}
+
+func (h *HostCertChecker) isAuthority(auth PublicKey, target string) bool {
+	b := auth.Marshal()
+	for _, a := range h.Authorities {
+		if !bytes.Equal(a.Key.Marshal(), b) {
+			continue
+		}
+		if len(a.Hosts) == 0 || MatchPrincipalPattern(target, a.Hosts) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"crypto/rand"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func testHostCert(t *testing.T, signer Signer, principals []string, after, before uint64) *Certificate {
+	t.Helper()
+	cert := &Certificate{
+		Key:             testPublicKeys["rsa"],
+		CertType:        HostCert,
+		ValidPrincipals: principals,
+		ValidAfter:      after,
+		ValidBefore:     before,
+	}
+	if err := cert.SignCert(rand.Reader, signer); err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestHostCertChecker(t *testing.T) {
+	ca := testSigners["ecdsa"]
+	remote := &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 22}
+	h := &HostCertChecker{
+		Authorities: []HostAuthority{
+			{Key: ca.PublicKey(), Hosts: []string{"*.example.com", "[*.example.com]:2222"}},
+		},
+	}
+	cert := testHostCert(t, ca, []string{"web.example.com"}, 0, CertTimeInfinity)
+
+	for addr, ok := range map[string]bool{
+		"web.example.com:22":   true,
+		"WEB.example.com:22":   false, // Principals are matched exactly.
+		"web.example.com:2222": true,
+		"web.example.com:2200": false, // Authority not trusted for the port.
+		"db.example.com:22":    false, // Not a principal.
+		"web.example.org:22":   false,
+	} {
+		if err := h.CheckHostKey(addr, remote, cert); (err == nil) != ok {
+			t.Errorf("CheckHostKey(%q): %v", addr, err)
+		}
+	}
+
+	other := testHostCert(t, testSigners["ed25519"], []string{"web.example.com"}, 0, CertTimeInfinity)
+	if err := h.CheckHostKey("web.example.com:22", remote, other); err == nil {
+		t.Error("accepted a certificate of an unknown authority")
+	}
+
+	if err := h.CheckHostKey("web.example.com:22", remote, testPublicKeys["rsa"]); err == nil {
+		t.Error("accepted a plain key without a fallback")
+	}
+	errFallback := errors.New("fallback")
+	h.Fallback = func(string, net.Addr, PublicKey) error { return errFallback }
+	if err := h.CheckHostKey("web.example.com:22", remote, testPublicKeys["rsa"]); err != errFallback {
+		t.Errorf("got %v, want the fallback's error", err)
+	}
+
+	h.IsRevoked = func(*Certificate) bool { return true }
+	if err := h.CheckHostKey("web.example.com:22", remote, cert); err == nil {
+		t.Error("accepted a revoked certificate")
+	}
+}
+
+func TestHostCertCheckerClockSkew(t *testing.T) {
+	ca := testSigners["ecdsa"]
+	now := time.Unix(1000000, 0)
+	h := &HostCertChecker{
+		Authorities: []HostAuthority{{Key: ca.PublicKey()}},
+		Clock:       func() time.Time { return now },
+	}
+	notYet := testHostCert(t, ca, []string{"host"}, uint64(now.Unix()+60), CertTimeInfinity)
+	expired := testHostCert(t, ca, []string{"host"}, 0, uint64(now.Unix()-60))
+	for _, cert := range []*Certificate{notYet, expired} {
+		h.ClockSkew = 0
+		if err := h.CheckHostKey("host:22", nil, cert); err == nil {
+			t.Errorf("accepted certificate valid [%d, %d) without skew", cert.ValidAfter, cert.ValidBefore)
+		}
+		h.ClockSkew = 2 * time.Minute
+		if err := h.CheckHostKey("host:22", nil, cert); err != nil {
+			t.Errorf("certificate valid [%d, %d) rejected with skew: %v", cert.ValidAfter, cert.ValidBefore, err)
+		}
+	}
+}
+
+func TestHostCertCallbackHandshake(t *testing.T) {
+	ca := testSigners["ecdsa"]
+	cert := testHostCert(t, ca, []string{"server.example.com"}, 0, CertTimeInfinity)
+	hostSigner, err := NewCertSigner(cert, testSigners["rsa"])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c1, c2, err := netPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c1.Close()
+	defer c2.Close()
+
+	serverConf := &ServerConfig{NoClientAuth: true}
+	serverConf.AddHostKey(hostSigner)
+	go NewServerConn(c1, serverConf)
+
+	clientConf := &ClientConfig{
+		User:            "user",
+		HostKeyCallback: HostCertCallback(nil, ca.PublicKey()),
+	}
+	conn, _, _, err := NewClientConn(c2, "server.example.com:22", clientConf)
+	if err != nil {
+		t.Fatalf("NewClientConn: %v", err)
+	}
+	conn.Close()
+}
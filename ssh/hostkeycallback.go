@@ -0,0 +1,127 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"errors"
+	"net"
+)
+
+// ErrUnknownHostKey is matched, with errors.Is, by the errors of host key
+// callbacks that have no key at all for the host, as opposed to a different
+// one. A *knownhosts.KeyError without wanted keys matches it.
+var ErrUnknownHostKey = errors.New("ssh: unknown host key")
+
+// errNoHostKeyCallbacks is returned by the callbacks of AnyHostKeyCallback
+// and AllHostKeyCallbacks when they have nothing to combine.
+var errNoHostKeyCallbacks = errors.New("ssh: no host key callbacks to check with")
+
+// AnyHostKeyCallback returns a HostKeyCallback that accepts a host key if
+// any of callbacks does. The callbacks are tried in order until one
+// accepts the key; if none does, the errors of all of them are returned
+// joined, so that they can be inspected with errors.Is and errors.As.
+// Without callbacks, every key is rejected.
+func AnyHostKeyCallback(callbacks ...HostKeyCallback) HostKeyCallback {
+	return func(hostname string, remote net.Addr, key PublicKey) error {
+		if len(callbacks) == 0 {
+			return errNoHostKeyCallbacks
+		}
+		var errs []error
+		for _, cb := range callbacks {
+			err := cb(hostname, remote, key)
+			if err == nil {
+				return nil
+			}
+			errs = append(errs, err)
+		}
+		return errors.Join(errs...)
+	}
+}
+
+// AllHostKeyCallbacks returns a HostKeyCallback that accepts a host key only
+// if all of callbacks do. It returns the error of the first callback that
+// rejects the key, without calling the remaining ones. Without callbacks,
+// every key is rejected.
+func AllHostKeyCallbacks(callbacks ...HostKeyCallback) HostKeyCallback {
+	return func(hostname string, remote net.Addr, key PublicKey) error {
+		if len(callbacks) == 0 {
+			return errNoHostKeyCallbacks
+		}
+		for _, cb := range callbacks {
+			if err := cb(hostname, remote, key); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// PinnedHostKey returns a HostKeyCallback that accepts only host keys
+// matching one of fingerprints, such as the SHA256 fingerprints printed by
+// ssh-keygen -l and parsed by ParseFingerprint. A host certificate matches
+// by the fingerprint of the certificate, not of its key.
+func PinnedHostKey(fingerprints ...Fingerprint) HostKeyCallback {
+	return func(hostname string, remote net.Addr, key PublicKey) error {
+		for _, fp := range fingerprints {
+			if fp.Matches(key) {
+				return nil
+			}
+		}
+		return errors.New("ssh: host key " + FingerprintSHA256(key) + " is not pinned")
+	}
+}
+
+// FixedFingerprints returns a HostKeyCallback that accepts host keys whose
+// fingerprint is one of fps, in the format parsed by ParseFingerprint, and
+// host certificates signed by a key whose fingerprint is one of fps. Like
+// with CertChecker, certificates must be valid host certificates for the
+// dialed host.
+func FixedFingerprints(fps ...string) (HostKeyCallback, error) {
+	var pinned []Fingerprint
+	for _, s := range fps {
+		fp, err := ParseFingerprint(s)
+		if err != nil {
+			return nil, err
+		}
+		pinned = append(pinned, fp)
+	}
+	checker := &CertChecker{
+		IsHostAuthority: func(auth PublicKey, address string) bool {
+			for _, fp := range pinned {
+				if fp.Matches(auth) {
+					return true
+				}
+			}
+			return false
+		},
+		HostKeyFallback: PinnedHostKey(pinned...),
+	}
+	return checker.CheckHostKey, nil
+}
+
+// A HostKeyStore records trusted host keys, such as a knownhosts.Writer or
+// knownhosts.DB.
+type HostKeyStore interface {
+	Add(addresses []string, key PublicKey) error
+}
+
+// AcceptNewHostKey returns a HostKeyCallback implementing the trust on first
+// use policy of ssh's StrictHostKeyChecking=accept-new: host keys accepted
+// by check are accepted, and if check has no key for the host, reporting an
+// error that matches ErrUnknownHostKey, the key is added to store under the
+// host name and accepted. Other errors, such as those of changed keys, are
+// returned as they are.
+//
+// check should see the keys added to store, as the callback of a
+// knownhosts.DB does; otherwise each connection adds the key again.
+func AcceptNewHostKey(check HostKeyCallback, store HostKeyStore) HostKeyCallback {
+	return func(hostname string, remote net.Addr, key PublicKey) error {
+		err := check(hostname, remote, key)
+		if err == nil || !errors.Is(err, ErrUnknownHostKey) {
+			return err
+		}
+		return store.Add([]string{hostname}, key)
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"crypto"
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestAnyAllHostKeyCallbacks(t *testing.T) {
+	errA, errB := errors.New("a"), errors.New("b")
+	reject := func(err error) HostKeyCallback {
+		return func(string, net.Addr, PublicKey) error { return err }
+	}
+	accept := reject(nil)
+	key := testPublicKeys["ecdsa"]
+	addr := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 22}
+
+	if err := AnyHostKeyCallback(reject(errA), accept)("h:22", addr, key); err != nil {
+		t.Errorf("Any with an accepting callback: %v", err)
+	}
+	err := AnyHostKeyCallback(reject(errA), reject(errB))("h:22", addr, key)
+	if !errors.Is(err, errA) || !errors.Is(err, errB) {
+		t.Errorf("Any = %v, want both errors", err)
+	}
+	if err := AllHostKeyCallbacks(accept, accept)("h:22", addr, key); err != nil {
+		t.Errorf("All accepting: %v", err)
+	}
+	if err := AllHostKeyCallbacks(accept, reject(errA), reject(errB))("h:22", addr, key); err != errA {
+		t.Errorf("All = %v, want the first error", err)
+	}
+	if AnyHostKeyCallback()("h:22", addr, key) == nil || AllHostKeyCallbacks()("h:22", addr, key) == nil {
+		t.Error("empty combination accepted a key")
+	}
+}
+
+func TestPinnedHostKey(t *testing.T) {
+	fp, err := ParseFingerprint(FingerprintSHA256(testPublicKeys["ed25519"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	md5fp, err := NewFingerprint(testPublicKeys["rsa"], crypto.MD5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cb := PinnedHostKey(fp, md5fp)
+	for name, want := range map[string]bool{"ed25519": true, "rsa": true, "ecdsa": false} {
+		if err := cb("h:22", nil, testPublicKeys[name]); (err == nil) != want {
+			t.Errorf("%s: got %v, want accepted %v", name, err, want)
+		}
+	}
+}
+
+type testHostKeyStore map[string]PublicKey
+
+func (s testHostKeyStore) Add(addresses []string, key PublicKey) error {
+	for _, a := range addresses {
+		s[a] = key
+	}
+	return nil
+}
+
+func TestAcceptNewHostKey(t *testing.T) {
+	store := testHostKeyStore{}
+	mismatch := errors.New("mismatch")
+	check := func(hostname string, remote net.Addr, key PublicKey) error {
+		known, ok := store[hostname]
+		if !ok {
+			return ErrUnknownHostKey
+		}
+		if string(known.Marshal()) != string(key.Marshal()) {
+			return mismatch
+		}
+		return nil
+	}
+	cb := AcceptNewHostKey(check, store)
+
+	if err := cb("h:22", nil, testPublicKeys["ecdsa"]); err != nil {
+		t.Fatalf("new host: %v", err)
+	}
+	if store["h:22"] == nil {
+		t.Fatal("key not stored")
+	}
+	if err := cb("h:22", nil, testPublicKeys["ecdsa"]); err != nil {
+		t.Errorf("known host: %v", err)
+	}
+	if err := cb("h:22", nil, testPublicKeys["rsa"]); err != mismatch {
+		t.Errorf("changed key: got %v, want mismatch", err)
+	}
+}
+
+func TestFixedFingerprints(t *testing.T) {
+	ca := testSigners["ecdsa"]
+	cb, err := FixedFingerprints(FingerprintSHA256(testPublicKeys["ed25519"]), FingerprintSHA256(ca.PublicKey()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cb("web.example.com:22", nil, testPublicKeys["ed25519"]); err != nil {
+		t.Errorf("pinned key: %v", err)
+	}
+	if err := cb("web.example.com:22", nil, testPublicKeys["rsa"]); err == nil {
+		t.Error("unpinned key accepted")
+	}
+	if err := cb("web.example.com:22", nil, testHostCert(t, ca, []string{"web.example.com"}, 0, CertTimeInfinity)); err != nil {
+		t.Errorf("certificate of a pinned CA: %v", err)
+	}
+	if err := cb("db.example.com:22", nil, testHostCert(t, ca, []string{"web.example.com"}, 0, CertTimeInfinity)); err == nil {
+		t.Error("certificate for another host accepted")
+	}
+	if err := cb("web.example.com:22", nil, testHostCert(t, testSigners["rsa"], []string{"web.example.com"}, 0, CertTimeInfinity)); err == nil {
+		t.Error("certificate of an unpinned CA accepted")
+	}
+
+	if _, err := FixedFingerprints("SHA256:not base64!"); err == nil {
+		t.Error("malformed fingerprint accepted")
+	}
+}
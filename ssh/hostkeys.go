@@ -0,0 +1,211 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+)
+
+// hostKeysRequest is the global request with which OpenSSH servers announce
+// all their host keys once the user is authenticated, and
+// hostKeysProveRequest the one with which clients ask the server to prove it
+// holds the corresponding private keys. See [PROTOCOL], section 2.5.
+const (
+	hostKeysRequest      = "hostkeys-00@openssh.com"
+	hostKeysProveRequest = "hostkeys-prove-00@openssh.com"
+)
+
+// HostKeysCallback is the function type used to receive the host keys that a
+// server announces with the hostkeys-00@openssh.com extension, so that
+// additional or rotated keys can be added to known_hosts before the old key
+// is retired. keys holds all the host keys of the server, including the one
+// used in the key exchange. Keys of unknown types are skipped. The hostname
+// and remote address are those passed to HostKeyCallback.
+//
+// The keys have not been proven to belong to the server, beyond having been
+// sent over the authenticated connection.
+type HostKeysCallback func(hostname string, remote net.Addr, keys []PublicKey)
+
+// HostKeysHandler is like HostKeysCallback, but is also passed the Client
+// that received the keys, so that it can call ProveHostKeys before
+// trusting them, as knownhosts.HostKeyUpdater does. It is called in a
+// goroutine of its own and may block.
+type HostKeysHandler func(client *Client, hostname string, remote net.Addr, keys []PublicKey)
+
+// parseHostKeys parses the payload of a hostkeys-00@openssh.com request,
+// skipping keys that cannot be parsed.
+func parseHostKeys(payload []byte) ([]PublicKey, error) {
+	var keys []PublicKey
+	for len(payload) > 0 {
+		blob, rest, ok := parseString(payload)
+		if !ok {
+			return nil, parseError(msgGlobalRequest)
+		}
+		payload = rest
+		if key, err := ParsePublicKey(blob); err == nil {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// handleHostKeys passes the keys of a hostkeys-00@openssh.com request on to
+// the HostKeysCallback and HostKeysHandler of the connection, if any.
+func (c *Client) handleHostKeys(r *Request) {
+	conn, ok := c.Conn.(*connection)
+	if !ok || conn.hostKeysCallback == nil && conn.hostKeysHandler == nil {
+		return
+	}
+	keys, err := parseHostKeys(r.Payload)
+	if err != nil || len(keys) == 0 {
+		return
+	}
+	if conn.hostKeysCallback != nil {
+		conn.hostKeysCallback(conn.transport.dialAddress, conn.RemoteAddr(), keys)
+	}
+	if conn.hostKeysHandler != nil {
+		go conn.hostKeysHandler(c, conn.transport.dialAddress, conn.RemoteAddr(), keys)
+	}
+}
+
+// marshalHostKeys returns the payload of a hostkeys-00@openssh.com or
+// hostkeys-prove-00@openssh.com request for keys.
+func marshalHostKeys(keys []PublicKey) []byte {
+	var payload []byte
+	for _, k := range keys {
+		payload = appendString(payload, string(k.Marshal()))
+	}
+	return payload
+}
+
+// hostKeyProofData returns the data signed to prove possession of a host
+// key.
+func hostKeyProofData(sessionID, hostKey []byte) []byte {
+	return Marshal(struct {
+		Request   string
+		SessionID []byte
+		HostKey   []byte
+	}{hostKeysProveRequest, sessionID, hostKey})
+}
+
+// hostKeyProofAlgo returns the signature algorithm a server uses to prove
+// possession of key. RSA keys use the algorithm negotiated for the host key
+// if it is an RSA one, and all other keys their default algorithm, as in
+// OpenSSH. Clients only insist on the former.
+func hostKeyProofAlgo(key PublicKey, negotiated string) string {
+	if key.Type() == KeyAlgoRSA && isRSA(negotiated) {
+		return underlyingAlgo(negotiated)
+	}
+	return underlyingAlgo(key.Type())
+}
+
+// ProveHostKeys asks the server to prove, with the
+// hostkeys-prove-00@openssh.com extension, that it holds the private keys
+// of keys, which typically were received by a HostKeysCallback. It returns
+// nil only if the server proved possession of all of them. It may be called
+// from a HostKeysCallback.
+func (c *Client) ProveHostKeys(keys []PublicKey) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	ok, reply, err := c.SendRequest(hostKeysProveRequest, true, marshalHostKeys(keys))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("ssh: server refused to prove its host keys")
+	}
+
+	sessionID := c.SessionID()
+	var negotiated string
+	if a, ok := c.Conn.(AlgorithmsConnMetadata); ok {
+		negotiated = a.Algorithms().HostKey
+	}
+	for _, key := range keys {
+		blob, rest, ok := parseString(reply)
+		if !ok {
+			return errors.New("ssh: too few host key proofs from server")
+		}
+		reply = rest
+		sig, rest, ok := parseSignatureBody(blob)
+		if !ok || len(rest) > 0 {
+			return errors.New("ssh: invalid host key proof from server")
+		}
+		if key.Type() == KeyAlgoRSA && isRSA(negotiated) && sig.Format != underlyingAlgo(negotiated) {
+			return fmt.Errorf("ssh: host key proof uses algorithm %q, want %q", sig.Format, underlyingAlgo(negotiated))
+		}
+		if err := key.Verify(hostKeyProofData(sessionID, key.Marshal()), sig); err != nil {
+			return fmt.Errorf("ssh: invalid proof for %s host key: %w", key.Type(), err)
+		}
+	}
+	if len(reply) > 0 {
+		return errors.New("ssh: too many host key proofs from server")
+	}
+	return nil
+}
+
+// announcedHostKeys returns the host keys a server announces and proves
+// possession of.
+func (s *ServerConfig) announcedHostKeys() []Signer {
+	return append(append([]Signer(nil), s.hostKeys...), s.secondaryHostKeys...)
+}
+
+// announceHostKeys sends the server's host keys to the client, and sets up
+// the mux to answer hostkeys-prove-00@openssh.com requests for them.
+func (s *connection) announceHostKeys(config *ServerConfig) error {
+	signers := config.announcedHostKeys()
+	keys := make([]PublicKey, len(signers))
+	for i, signer := range signers {
+		keys[i] = signer.PublicKey()
+	}
+
+	s.mux.handleRequests(hostKeysProveRequest, func(payload []byte) (bool, []byte) {
+		requested, err := parseHostKeys(payload)
+		if err != nil || len(requested) == 0 {
+			return false, nil
+		}
+		negotiated := s.transport.getAlgorithms().HostKey
+		var reply []byte
+		for _, key := range requested {
+			signer := findHostKeySigner(signers, key)
+			if signer == nil {
+				return false, nil
+			}
+			sig, err := signHostKeyProof(signer, config.Rand, hostKeyProofData(s.sessionID, key.Marshal()), hostKeyProofAlgo(key, negotiated))
+			if err != nil {
+				return false, nil
+			}
+			reply = appendString(reply, string(Marshal(sig)))
+		}
+		return true, reply
+	})
+
+	_, _, err := s.mux.SendRequest(hostKeysRequest, false, marshalHostKeys(keys))
+	return err
+}
+
+func findHostKeySigner(signers []Signer, key PublicKey) Signer {
+	blob := key.Marshal()
+	for _, s := range signers {
+		if bytes.Equal(s.PublicKey().Marshal(), blob) {
+			return s
+		}
+	}
+	return nil
+}
+
+func signHostKeyProof(signer Signer, rand io.Reader, data []byte, algo string) (*Signature, error) {
+	if as, ok := signer.(AlgorithmSigner); ok {
+		return as.SignWithAlgorithm(rand, data, algo)
+	}
+	if algo != underlyingAlgo(signer.PublicKey().Type()) {
+		return nil, fmt.Errorf("ssh: host key cannot sign with algorithm %q", algo)
+	}
+	return signer.Sign(rand, data)
+}
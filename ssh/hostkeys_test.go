@@ -0,0 +1,192 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestHostKeysCallback(t *testing.T) {
+	c1, c2, err := netPipe()
+	if err != nil {
+		t.Fatalf("netPipe: %v", err)
+	}
+	defer c1.Close()
+	defer c2.Close()
+
+	announced := []PublicKey{testSigners["ecdsa"].PublicKey(), testSigners["rsa"].PublicKey()}
+	var payload []byte
+	for _, k := range announced {
+		payload = appendString(payload, string(k.Marshal()))
+	}
+	unknown := appendString(appendString(nil, "unknown-key@example.com"), "blob")
+	payload = appendString(payload, string(unknown))
+
+	serverConf := &ServerConfig{NoClientAuth: true}
+	serverConf.AddHostKey(testSigners["ecdsa"])
+	go func() {
+		conn, chans, reqs, err := NewServerConn(c2, serverConf)
+		if err != nil {
+			return
+		}
+		go DiscardRequests(reqs)
+		go func() {
+			for range chans {
+			}
+		}()
+		conn.SendRequest(hostKeysRequest, false, payload)
+		conn.Wait()
+	}()
+
+	got := make(chan []PublicKey, 1)
+	clientConf := &ClientConfig{
+		User:            "user",
+		HostKeyCallback: InsecureIgnoreHostKey(),
+		HostKeysCallback: func(hostname string, remote net.Addr, keys []PublicKey) {
+			if hostname != "server:22" {
+				t.Errorf("got hostname %q, want %q", hostname, "server:22")
+			}
+			got <- keys
+		},
+	}
+	conn, chans, reqs, err := NewClientConn(c1, "server:22", clientConf)
+	if err != nil {
+		t.Fatalf("NewClientConn: %v", err)
+	}
+	client := NewClient(conn, chans, reqs)
+	defer client.Close()
+
+	select {
+	case keys := <-got:
+		if len(keys) != len(announced) {
+			t.Fatalf("got %d keys, want %d", len(keys), len(announced))
+		}
+		for i, k := range keys {
+			if !bytes.Equal(k.Marshal(), announced[i].Marshal()) {
+				t.Errorf("key %d: got %s, want %s", i, k.Type(), announced[i].Type())
+			}
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("HostKeysCallback was not called")
+	}
+}
+
+func TestParseHostKeysMalformed(t *testing.T) {
+	payload := appendString(nil, string(testSigners["ecdsa"].PublicKey().Marshal()))
+	if _, err := parseHostKeys(payload[:len(payload)-1]); err == nil {
+		t.Error("parseHostKeys succeeded on a truncated payload")
+	}
+}
+
+func TestProveHostKeys(t *testing.T) {
+	c1, c2, err := netPipe()
+	if err != nil {
+		t.Fatalf("netPipe: %v", err)
+	}
+	defer c1.Close()
+	defer c2.Close()
+
+	serverConf := &ServerConfig{NoClientAuth: true, AnnounceHostKeys: true}
+	serverConf.AddHostKey(testSigners["ecdsa"])
+	serverConf.AddSecondaryHostKey(testSigners["rsa"])
+	go func() {
+		conn, chans, reqs, err := NewServerConn(c2, serverConf)
+		if err != nil {
+			return
+		}
+		go DiscardRequests(reqs)
+		go func() {
+			for range chans {
+			}
+		}()
+		conn.Wait()
+	}()
+
+	got := make(chan []PublicKey, 1)
+	clientConf := &ClientConfig{
+		User:            "user",
+		HostKeyCallback: InsecureIgnoreHostKey(),
+		HostKeysCallback: func(hostname string, remote net.Addr, keys []PublicKey) {
+			got <- keys
+		},
+	}
+	conn, chans, reqs, err := NewClientConn(c1, "server:22", clientConf)
+	if err != nil {
+		t.Fatalf("NewClientConn: %v", err)
+	}
+	client := NewClient(conn, chans, reqs)
+	defer client.Close()
+
+	var keys []PublicKey
+	select {
+	case keys = <-got:
+	case <-time.After(10 * time.Second):
+		t.Fatal("HostKeysCallback was not called")
+	}
+	if len(keys) != 2 {
+		t.Fatalf("got %d announced keys, want 2", len(keys))
+	}
+	if err := client.ProveHostKeys(keys); err != nil {
+		t.Errorf("ProveHostKeys: %v", err)
+	}
+	if err := client.ProveHostKeys([]PublicKey{testSigners["ed25519"].PublicKey()}); err == nil {
+		t.Error("ProveHostKeys succeeded for a key the server does not hold")
+	}
+}
+
+func TestHostKeysHandler(t *testing.T) {
+	c1, c2, err := netPipe()
+	if err != nil {
+		t.Fatalf("netPipe: %v", err)
+	}
+	defer c1.Close()
+	defer c2.Close()
+
+	serverConf := &ServerConfig{NoClientAuth: true, AnnounceHostKeys: true}
+	serverConf.AddHostKey(testSigners["ecdsa"])
+	serverConf.AddSecondaryHostKey(testSigners["rsa"])
+	go func() {
+		conn, chans, reqs, err := NewServerConn(c2, serverConf)
+		if err != nil {
+			return
+		}
+		go DiscardRequests(reqs)
+		go func() {
+			for range chans {
+			}
+		}()
+		conn.Wait()
+	}()
+
+	proved := make(chan error, 1)
+	clientConf := &ClientConfig{
+		User:            "user",
+		HostKeyCallback: InsecureIgnoreHostKey(),
+		HostKeysHandler: func(client *Client, hostname string, remote net.Addr, keys []PublicKey) {
+			if hostname != "server:22" {
+				t.Errorf("got hostname %q", hostname)
+			}
+			proved <- client.ProveHostKeys(keys)
+		},
+	}
+	conn, chans, reqs, err := NewClientConn(c1, "server:22", clientConf)
+	if err != nil {
+		t.Fatalf("NewClientConn: %v", err)
+	}
+	client := NewClient(conn, chans, reqs)
+	defer client.Close()
+
+	select {
+	case err := <-proved:
+		if err != nil {
+			t.Errorf("ProveHostKeys: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("HostKeysHandler was not called")
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package curve448 implements the X448 function of RFC 7748 and the
+// verification of Ed448 signatures of RFC 8032, as used by the
+// curve448-sha512 SSH key exchange and the ssh-ed448 public key algorithm.
+//
+// The arithmetic is done with math/big, so it is not constant time. This is
+// acceptable for the ephemeral keys of a key exchange, as for the NIST
+// curves larger than P-256, and for verification, which handles no secrets.
+// It is not for long-term private keys, so Ed448 signing is not provided.
+package curve448
+
+import (
+	"errors"
+	"math/big"
+)
+
+var (
+	// p is the field prime 2^448 - 2^224 - 1.
+	p = func() *big.Int {
+		p := new(big.Int).Lsh(big.NewInt(1), 448)
+		p.Sub(p, new(big.Int).Lsh(big.NewInt(1), 224))
+		return p.Sub(p, big.NewInt(1))
+	}()
+
+	one = big.NewInt(1)
+	two = big.NewInt(2)
+)
+
+// decodeLittleEndian interprets b as a little-endian integer.
+func decodeLittleEndian(b []byte) *big.Int {
+	be := make([]byte, len(b))
+	for i, c := range b {
+		be[len(b)-1-i] = c
+	}
+	return new(big.Int).SetBytes(be)
+}
+
+// encodeLittleEndian encodes x, which must be non-negative, as a
+// little-endian integer of size bytes.
+func encodeLittleEndian(x *big.Int, size int) []byte {
+	be := x.FillBytes(make([]byte, size))
+	out := make([]byte, size)
+	for i, c := range be {
+		out[size-1-i] = c
+	}
+	return out
+}
+
+// fieldInv returns 1/x mod p, or 0 if x is 0.
+func fieldInv(x *big.Int) *big.Int {
+	return new(big.Int).Exp(x, new(big.Int).Sub(p, two), p)
+}
+
+const (
+	// ScalarSize is the size of an X448 scalar.
+	ScalarSize = 56
+	// PointSize is the size of an X448 u-coordinate.
+	PointSize = 56
+)
+
+// Basepoint is the u-coordinate of the X448 base point.
+var Basepoint = func() []byte {
+	b := make([]byte, PointSize)
+	b[0] = 5
+	return b
+}()
+
+var a24 = big.NewInt(39081)
+
+// X448 returns the result of the scalar multiplication scalar * point,
+// following RFC 7748, section 5. It returns an error if the result is the
+// all-zero value, which happens for low order points.
+func X448(scalar, point []byte) ([]byte, error) {
+	if len(scalar) != ScalarSize {
+		return nil, errors.New("curve448: bad scalar length")
+	}
+	if len(point) != PointSize {
+		return nil, errors.New("curve448: bad point length")
+	}
+
+	e := make([]byte, ScalarSize)
+	copy(e, scalar)
+	e[0] &= 252
+	e[55] |= 128
+	k := decodeLittleEndian(e)
+	u := decodeLittleEndian(point)
+	u.Mod(u, p)
+
+	x1 := u
+	x2, z2 := big.NewInt(1), big.NewInt(0)
+	x3, z3 := new(big.Int).Set(u), big.NewInt(1)
+	swap := uint(0)
+	a, aa, b, bb, ee, c, d, da, cb := new(big.Int), new(big.Int), new(big.Int), new(big.Int), new(big.Int), new(big.Int), new(big.Int), new(big.Int), new(big.Int)
+	for t := 447; t >= 0; t-- {
+		kt := k.Bit(t)
+		if swap^kt == 1 {
+			x2, x3 = x3, x2
+			z2, z3 = z3, z2
+		}
+		swap = kt
+
+		a.Add(x2, z2)
+		aa.Mul(a, a).Mod(aa, p)
+		b.Sub(x2, z2)
+		bb.Mul(b, b).Mod(bb, p)
+		ee.Sub(aa, bb)
+		c.Add(x3, z3)
+		d.Sub(x3, z3)
+		da.Mul(d, a).Mod(da, p)
+		cb.Mul(c, b).Mod(cb, p)
+
+		x3 = new(big.Int).Add(da, cb)
+		x3.Mul(x3, x3).Mod(x3, p)
+		z3 = new(big.Int).Sub(da, cb)
+		z3.Mul(z3, z3).Mul(z3, x1).Mod(z3, p)
+		x2 = new(big.Int).Mul(aa, bb)
+		x2.Mod(x2, p)
+		z2 = new(big.Int).Mul(a24, ee)
+		z2.Add(z2, aa).Mul(z2, ee).Mod(z2, p)
+	}
+	if swap == 1 {
+		x2, z2 = x3, z3
+	}
+
+	r := new(big.Int).Mul(x2, fieldInv(z2))
+	r.Mod(r, p)
+	if r.Sign() == 0 {
+		return nil, errors.New("curve448: low order point")
+	}
+	return encodeLittleEndian(r, PointSize), nil
+}
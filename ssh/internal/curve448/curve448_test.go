@@ -0,0 +1,112 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package curve448
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+)
+
+func fromHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// Test vectors from RFC 7748, section 5.2.
+func TestX448Vectors(t *testing.T) {
+	for _, tt := range []struct {
+		scalar, point, want string
+	}{
+		{
+			"3d262fddf9ec8e88495266fea19a34d28882acef045104d0d1aae121700a779c984c24f8cdd78fbff44943eba368f54b29259a4f1c600ad3",
+			"06fce640fa3487bfda5f6cf2d5263f8aad88334cbd07437f020f08f9814dc031ddbdc38c19c6da2583fa5429db94ada18aa7a7fb4ef8a086",
+			"ce3e4ff95a60dc6697da1db1d85e6afbdf79b50a2412d7546d5f239fe14fbaadeb445fc66a01b0779d98223961111e21766282f73dd96b6f",
+		},
+		{
+			"203d494428b8399352665ddca42f9de8fef600908e0d461cb021f8c538345dd77c3e4806e25f46d3315c44e0a5b4371282dd2c8d5be3095f",
+			"0fbcc2f993cd56d3305b0b7d9e55d4c1a8fb5dbb52f8e9a1e9b6201b165d015894e56c4d3570bee52fe205e28a78b91cdfbde71ce8d157db",
+			"884a02576239ff7a2f2f63b2db6a9ff37047ac13568e1e30fe63c4a7ad1b3ee3a5700df34321d62077e63633c575c1c954514e99da7c179d",
+		},
+	} {
+		got, err := X448(fromHex(t, tt.scalar), fromHex(t, tt.point))
+		if err != nil {
+			t.Fatalf("X448: %v", err)
+		}
+		if want := fromHex(t, tt.want); !bytes.Equal(got, want) {
+			t.Errorf("X448 = %x, want %x", got, want)
+		}
+	}
+}
+
+// Test vectors from RFC 7748, section 6.2.
+func TestX448DiffieHellman(t *testing.T) {
+	alicePriv := fromHex(t, "9a8f4925d1519f5775cf46b04b5800d4ee9ee8bae8bc5565d498c28dd9c9baf574a9419744897391006382a6f127ab1d9ac2d8c0a598726b")
+	alicePub := fromHex(t, "9b08f7cc31b7e3e67d22d5aea121074a273bd2b83de09c63faa73d2c22c5d9bbc836647241d953d40c5b12da88120d53177f80e532c41fa0")
+	bobPriv := fromHex(t, "1c306a7ac2a0e2e0990b294470cba339e6453772b075811d8fad0d1d6927c120bb5ee8972b0d3e21374c9c921b09d1b0366f10b65173992d")
+	bobPub := fromHex(t, "3eb7a829b0cd20f5bcfc0b599b6feccf6da4627107bdb0d4f345b43027d8b972fc3e34fb4232a13ca706dcb57aec3dae07bdc1c67bf33609")
+	shared := fromHex(t, "07fff4181ac6cc95ec1c16a94a0f74d12da232ce40a77552281d282bb60c0b56fd2464c335543936521c24403085d59a449a5037514a879d")
+
+	for _, tt := range []struct {
+		name                string
+		scalar, point, want []byte
+	}{
+		{"alice public", alicePriv, Basepoint, alicePub},
+		{"bob public", bobPriv, Basepoint, bobPub},
+		{"alice shared", alicePriv, bobPub, shared},
+		{"bob shared", bobPriv, alicePub, shared},
+	} {
+		got, err := X448(tt.scalar, tt.point)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !bytes.Equal(got, tt.want) {
+			t.Errorf("%s: got %x, want %x", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestX448LowOrder(t *testing.T) {
+	scalar := make([]byte, ScalarSize)
+	rand.Read(scalar)
+	if _, err := X448(scalar, make([]byte, PointSize)); err == nil {
+		t.Error("X448 accepted the zero point")
+	}
+}
+
+// Test vectors from RFC 8032, section 7.4.
+func TestEd448Vectors(t *testing.T) {
+	for _, tt := range []struct {
+		pub, msg, sig string
+	}{
+		{
+			"5fd7449b59b461fd2ce787ec616ad46a1da1342485a70e1f8a0ea75d80e96778edf124769b46c7061bd6783df1e50f6cd1fa1abeafe8256180",
+			"",
+			"533a37f6bbe457251f023c0d88f976ae2dfb504a843e34d2074fd823d41a591f2b233f034f628281f2fd7a22ddd47d7828c59bd0a21bfd3980ff0d2028d4b18a9df63e006c5d1c2d345b925d8dc00b4104852db99ac5c7cdda8530a113a0f4dbb61149f05a7363268c71d95808ff2e652600",
+		},
+		{
+			"43ba28f430cdff456ae531545f7ecd0ac834a55d9358c0372bfa0c6c6798c0866aea01eb00742802b8438ea4cb82169c235160627b4c3a9480",
+			"03",
+			"26b8f91727bd62897af15e41eb43c377efb9c610d48f2335cb0bd0087810f4352541b143c4b981b7e18f62de8ccdf633fc1bf037ab7cd779805e0dbcc0aae1cbcee1afb2e027df36bc04dcecbf154336c19f0af7e0a6472905e799f1953d2a0ff3348ab21aa4adafd1d234441cf807c03a00",
+		},
+	} {
+		pub, msg, sig := fromHex(t, tt.pub), fromHex(t, tt.msg), fromHex(t, tt.sig)
+		if !Verify(pub, msg, sig) {
+			t.Error("Verify failed on the test vector")
+		}
+		if Verify(pub, append(msg, 0), sig) {
+			t.Error("signature of another message accepted")
+		}
+		sig[0] ^= 1
+		if Verify(pub, msg, sig) {
+			t.Error("corrupted signature accepted")
+		}
+	}
+}
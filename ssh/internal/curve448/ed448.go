@@ -0,0 +1,177 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package curve448
+
+import (
+	"errors"
+	"math/big"
+
+	"golang.org/x/crypto/sha3"
+)
+
+const (
+	// PublicKeySize is the size of an Ed448 public key.
+	PublicKeySize = 57
+	// SignatureSize is the size of an Ed448 signature.
+	SignatureSize = 114
+)
+
+var (
+	// d is the curve constant of edwards448, -39081.
+	d = new(big.Int).Sub(p, big.NewInt(39081))
+
+	// l is the order of the prime subgroup,
+	// 2^446 - 13818066809895115352007386748515426880336692474882178609894547503885.
+	l = func() *big.Int {
+		c, _ := new(big.Int).SetString("13818066809895115352007386748515426880336692474882178609894547503885", 10)
+		l := new(big.Int).Lsh(big.NewInt(1), 446)
+		return l.Sub(l, c)
+	}()
+
+	basePoint = func() *point {
+		x, _ := new(big.Int).SetString("224580040295924300187604334099896036246789641632564134246125461686950415467406032909029192869357953282578032075146446173674602635247710", 10)
+		y, _ := new(big.Int).SetString("298819210078481492676017930443930673437544040154080242095928241372331506189835876003536878655418784733982303233503462500531545062832660", 10)
+		return &point{x, y, big.NewInt(1)}
+	}()
+)
+
+// point is a point of edwards448 in projective coordinates, x = X/Z and
+// y = Y/Z.
+type point struct {
+	X, Y, Z *big.Int
+}
+
+func identity() *point {
+	return &point{big.NewInt(0), big.NewInt(1), big.NewInt(1)}
+}
+
+func mulMod(a, b *big.Int) *big.Int {
+	r := new(big.Int).Mul(a, b)
+	return r.Mod(r, p)
+}
+
+// add returns p1 + p2, using the formulas of RFC 8032, section 5.2.4.
+func add(p1, p2 *point) *point {
+	a := mulMod(p1.Z, p2.Z)
+	b := mulMod(a, a)
+	c := mulMod(p1.X, p2.X)
+	dd := mulMod(p1.Y, p2.Y)
+	e := mulMod(mulMod(d, c), dd)
+	f := new(big.Int).Sub(b, e)
+	g := new(big.Int).Add(b, e)
+	h := mulMod(new(big.Int).Add(p1.X, p1.Y), new(big.Int).Add(p2.X, p2.Y))
+	h.Sub(h, c).Sub(h, dd)
+	return &point{
+		X: mulMod(mulMod(a, f), h),
+		Y: mulMod(mulMod(a, g), new(big.Int).Sub(dd, c)),
+		Z: mulMod(f, g),
+	}
+}
+
+// scalarMult returns k * q.
+func scalarMult(k *big.Int, q *point) *point {
+	r := identity()
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		r = add(r, r)
+		if k.Bit(i) == 1 {
+			r = add(r, q)
+		}
+	}
+	return r
+}
+
+// encode returns the 57-byte encoding of q, see RFC 8032, section 5.2.2.
+func (q *point) encode() []byte {
+	zInv := fieldInv(q.Z)
+	x := mulMod(q.X, zInv)
+	y := mulMod(q.Y, zInv)
+	out := encodeLittleEndian(y, PublicKeySize)
+	out[56] |= byte(x.Bit(0)) << 7
+	return out
+}
+
+// decodePoint decodes a point encoded as in RFC 8032, section 5.2.3.
+func decodePoint(b []byte) (*point, error) {
+	if len(b) != PublicKeySize {
+		return nil, errors.New("curve448: bad point length")
+	}
+	if b[56]&0x7f != 0 {
+		return nil, errors.New("curve448: invalid point encoding")
+	}
+	sign := uint(b[56] >> 7)
+	enc := make([]byte, PublicKeySize)
+	copy(enc, b)
+	enc[56] = 0
+	y := decodeLittleEndian(enc)
+	if y.Cmp(p) >= 0 {
+		return nil, errors.New("curve448: invalid point encoding")
+	}
+
+	// x^2 = (y^2 - 1) / (d y^2 - 1)
+	yy := mulMod(y, y)
+	u := new(big.Int).Sub(yy, one)
+	u.Mod(u, p)
+	v := mulMod(d, yy)
+	v.Sub(v, one).Mod(v, p)
+	xx := mulMod(u, fieldInv(v))
+	// p = 3 mod 4, so the square root is xx^((p+1)/4).
+	x := new(big.Int).Exp(xx, new(big.Int).Rsh(new(big.Int).Add(p, one), 2), p)
+	if mulMod(x, x).Cmp(xx) != 0 {
+		return nil, errors.New("curve448: invalid point")
+	}
+	if x.Sign() == 0 && sign == 1 {
+		return nil, errors.New("curve448: invalid point encoding")
+	}
+	if x.Bit(0) != sign {
+		x.Sub(p, x)
+	}
+	return &point{x, y, big.NewInt(1)}, nil
+}
+
+func (q *point) equal(o *point) bool {
+	// X1/Z1 == X2/Z2 and Y1/Z1 == Y2/Z2.
+	return mulMod(q.X, o.Z).Cmp(mulMod(o.X, q.Z)) == 0 &&
+		mulMod(q.Y, o.Z).Cmp(mulMod(o.Y, q.Z)) == 0
+}
+
+// hash returns SHAKE256(dom4(0, "") || parts...) as a little-endian integer
+// modulo l, see RFC 8032, section 5.2.
+func hash(parts ...[]byte) *big.Int {
+	h := sha3.NewShake256()
+	h.Write([]byte("SigEd448\x00\x00"))
+	for _, part := range parts {
+		h.Write(part)
+	}
+	out := make([]byte, 114)
+	h.Read(out)
+	k := decodeLittleEndian(out)
+	return k.Mod(k, l)
+}
+
+// Verify reports whether sig is a valid signature of message by publicKey.
+func Verify(publicKey, message, sig []byte) bool {
+	if len(publicKey) != PublicKeySize || len(sig) != SignatureSize {
+		return false
+	}
+	A, err := decodePoint(publicKey)
+	if err != nil {
+		return false
+	}
+	R, err := decodePoint(sig[:57])
+	if err != nil {
+		return false
+	}
+	S := decodeLittleEndian(sig[57:])
+	if S.Cmp(l) >= 0 {
+		return false
+	}
+	k := hash(sig[:57], publicKey, message)
+
+	// Check the cofactored equation [4][S]B = [4]R + [4][k]A.
+	four := big.NewInt(4)
+	lhs := scalarMult(four, scalarMult(S, basePoint))
+	rhs := scalarMult(four, add(R, scalarMult(k, A)))
+	return lhs.equal(rhs)
+}
@@ -0,0 +1,603 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package sntrup761 implements the Streamlined NTRU Prime 761 key
+// encapsulation mechanism, as used by the sntrup761x25519-sha512 SSH key
+// exchange.
+//
+// The implementation follows the reference implementation that is included
+// in OpenSSH and SUPERCOP. See https://ntruprime.cr.yp.to/.
+package sntrup761
+
+import (
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+const (
+	p   = 761
+	q   = 4591
+	w   = 286
+	q12 = (q - 1) / 2
+
+	hashSize    = 32
+	smallSize   = (p + 3) / 4
+	rqSize      = 1158
+	roundedSize = 1007
+	confirmSize = hashSize
+	inputsSize  = smallSize
+	secretZSize = 2 * smallSize
+)
+
+const (
+	// PublicKeySize is the size of an encoded public key.
+	PublicKeySize = rqSize
+	// PrivateKeySize is the size of an encoded private key.
+	PrivateKeySize = secretZSize + PublicKeySize + inputsSize + hashSize
+	// CiphertextSize is the size of a ciphertext.
+	CiphertextSize = roundedSize + confirmSize
+	// SharedKeySize is the size of the shared key.
+	SharedKeySize = hashSize
+)
+
+// small holds coefficients in {-1, 0, 1}, fq holds coefficients modulo q,
+// in the range [-q12, q12].
+type (
+	small int8
+	fq    int16
+)
+
+// mod returns x modulo m, in the range [0, m). m must be a positive
+// constant, so that the compiler replaces the division with a
+// multiplication.
+func mod(x, m int32) int32 {
+	r := x % m
+	return r + (m & (r >> 31))
+}
+
+func f3Freeze(x int32) small {
+	return small(mod(x+1, 3) - 1)
+}
+
+func fqFreeze(x int32) fq {
+	return fq(mod(x+q12, q) - q12)
+}
+
+// fqRecip returns the inverse of a1 modulo q, computed as a1^(q-2).
+func fqRecip(a1 fq) fq {
+	ai := a1
+	for i := 1; i < q-2; i++ {
+		ai = fqFreeze(int32(a1) * int32(ai))
+	}
+	return ai
+}
+
+// nonzeroMask returns -1 if x is not zero, and 0 otherwise.
+func nonzeroMask(x int32) int32 {
+	return -int32((uint32(x) | uint32(-x)) >> 31)
+}
+
+// negativeMask returns -1 if x is negative, and 0 otherwise.
+func negativeMask(x int32) int32 {
+	return x >> 31
+}
+
+// weightwMask returns 0 if r has exactly w nonzero coefficients, and -1
+// otherwise.
+func weightwMask(r []small) int32 {
+	weight := int32(0)
+	for i := 0; i < p; i++ {
+		weight += int32(r[i] & 1)
+	}
+	return nonzeroMask(weight - w)
+}
+
+// r3FromRq reduces the coefficients of r modulo 3.
+func r3FromRq(out []small, r []fq) {
+	for i := 0; i < p; i++ {
+		out[i] = f3Freeze(int32(r[i]))
+	}
+}
+
+// r3Mult sets h = f*g in the ring Z/3[x]/(x^p-x-1).
+func r3Mult(h, f, g []small) {
+	var fg [p + p - 1]small
+	for i := 0; i < p; i++ {
+		var result small
+		for j := 0; j <= i; j++ {
+			result = f3Freeze(int32(result) + int32(f[j])*int32(g[i-j]))
+		}
+		fg[i] = result
+	}
+	for i := p; i < p+p-1; i++ {
+		var result small
+		for j := i - p + 1; j < p; j++ {
+			result = f3Freeze(int32(result) + int32(f[j])*int32(g[i-j]))
+		}
+		fg[i] = result
+	}
+	for i := p + p - 2; i >= p; i-- {
+		fg[i-p] = f3Freeze(int32(fg[i-p]) + int32(fg[i]))
+		fg[i-p+1] = f3Freeze(int32(fg[i-p+1]) + int32(fg[i]))
+	}
+	copy(h, fg[:p])
+}
+
+// r3Recip sets out = 1/in in Z/3[x]/(x^p-x-1). It returns 0 on success, and
+// -1 if in is not invertible.
+func r3Recip(out, in []small) int32 {
+	var f, g, v, r [p + 1]small
+	r[0] = 1
+	f[0] = 1
+	f[p-1], f[p] = -1, -1
+	for i := 0; i < p; i++ {
+		g[p-1-i] = in[i]
+	}
+
+	delta := int32(1)
+	for loop := 0; loop < 2*p-1; loop++ {
+		copy(v[1:], v[:p])
+		v[0] = 0
+
+		sign := -int32(g[0]) * int32(f[0])
+		swap := negativeMask(-delta) & nonzeroMask(int32(g[0]))
+		delta ^= swap & (delta ^ -delta)
+		delta++
+
+		for i := 0; i < p+1; i++ {
+			t := small(swap) & (f[i] ^ g[i])
+			f[i] ^= t
+			g[i] ^= t
+			t = small(swap) & (v[i] ^ r[i])
+			v[i] ^= t
+			r[i] ^= t
+		}
+
+		for i := 0; i < p+1; i++ {
+			g[i] = f3Freeze(int32(g[i]) + sign*int32(f[i]))
+		}
+		for i := 0; i < p+1; i++ {
+			r[i] = f3Freeze(int32(r[i]) + sign*int32(v[i]))
+		}
+
+		copy(g[:p], g[1:])
+		g[p] = 0
+	}
+
+	sign := f[0]
+	for i := 0; i < p; i++ {
+		out[i] = sign * v[p-1-i]
+	}
+	return nonzeroMask(delta)
+}
+
+// rqMultSmall sets h = f*g in the ring Z/q[x]/(x^p-x-1).
+func rqMultSmall(h []fq, f []fq, g []small) {
+	var fg [p + p - 1]fq
+	for i := 0; i < p; i++ {
+		var result fq
+		for j := 0; j <= i; j++ {
+			result = fqFreeze(int32(result) + int32(f[j])*int32(g[i-j]))
+		}
+		fg[i] = result
+	}
+	for i := p; i < p+p-1; i++ {
+		var result fq
+		for j := i - p + 1; j < p; j++ {
+			result = fqFreeze(int32(result) + int32(f[j])*int32(g[i-j]))
+		}
+		fg[i] = result
+	}
+	for i := p + p - 2; i >= p; i-- {
+		fg[i-p] = fqFreeze(int32(fg[i-p]) + int32(fg[i]))
+		fg[i-p+1] = fqFreeze(int32(fg[i-p+1]) + int32(fg[i]))
+	}
+	copy(h, fg[:p])
+}
+
+// rqMult3 sets h = 3*f.
+func rqMult3(h, f []fq) {
+	for i := 0; i < p; i++ {
+		h[i] = fqFreeze(3 * int32(f[i]))
+	}
+}
+
+// rqRecip3 sets out = 1/(3*in) in Z/q[x]/(x^p-x-1). It returns 0 on
+// success, and -1 if in is not invertible.
+func rqRecip3(out []fq, in []small) int32 {
+	var f, g, v, r [p + 1]fq
+	r[0] = fqRecip(3)
+	f[0] = 1
+	f[p-1], f[p] = -1, -1
+	for i := 0; i < p; i++ {
+		g[p-1-i] = fq(in[i])
+	}
+
+	delta := int32(1)
+	for loop := 0; loop < 2*p-1; loop++ {
+		copy(v[1:], v[:p])
+		v[0] = 0
+
+		swap := negativeMask(-delta) & nonzeroMask(int32(g[0]))
+		delta ^= swap & (delta ^ -delta)
+		delta++
+
+		for i := 0; i < p+1; i++ {
+			t := fq(swap) & (f[i] ^ g[i])
+			f[i] ^= t
+			g[i] ^= t
+			t = fq(swap) & (v[i] ^ r[i])
+			v[i] ^= t
+			r[i] ^= t
+		}
+
+		f0, g0 := int32(f[0]), int32(g[0])
+		for i := 0; i < p+1; i++ {
+			g[i] = fqFreeze(f0*int32(g[i]) - g0*int32(f[i]))
+		}
+		for i := 0; i < p+1; i++ {
+			r[i] = fqFreeze(f0*int32(r[i]) - g0*int32(v[i]))
+		}
+
+		copy(g[:p], g[1:])
+		g[p] = 0
+	}
+
+	scale := int32(fqRecip(f[0]))
+	for i := 0; i < p; i++ {
+		out[i] = fqFreeze(scale * int32(v[p-1-i]))
+	}
+	return nonzeroMask(delta)
+}
+
+// round rounds each coefficient of a to the nearest multiple of 3.
+func round(out, a []fq) {
+	for i := 0; i < p; i++ {
+		out[i] = a[i] - fq(f3Freeze(int32(a[i])))
+	}
+}
+
+// minMax sorts a and b in constant time.
+func minMax(a, b *uint32) {
+	mask := uint32(0 - ((uint64(*b) - uint64(*a)) >> 63))
+	t := (*a ^ *b) & mask
+	*a ^= t
+	*b ^= t
+}
+
+// sortUint32 sorts x in constant time with Batcher's odd-even merge sort.
+func sortUint32(x []uint32) {
+	n := len(x)
+	for t := 1; t < n; t <<= 1 {
+		for k := t; k >= 1; k >>= 1 {
+			for j := k % t; j+k < n; j += 2 * k {
+				for i := 0; i < k && i+j+k < n; i++ {
+					if (i+j)/(2*t) == (i+j+k)/(2*t) {
+						minMax(&x[i+j], &x[i+j+k])
+					}
+				}
+			}
+		}
+	}
+}
+
+// shortFromList sets out to a polynomial with exactly w nonzero
+// coefficients, chosen by the random values in.
+func shortFromList(out []small, in []uint32) {
+	var l [p]uint32
+	for i := 0; i < w; i++ {
+		l[i] = in[i] & ^uint32(1)
+	}
+	for i := w; i < p; i++ {
+		l[i] = (in[i] & ^uint32(3)) | 1
+	}
+	sortUint32(l[:])
+	for i := 0; i < p; i++ {
+		out[i] = small(l[i]&3) - 1
+	}
+}
+
+func randomUint32s(rand io.Reader, n int) ([]uint32, error) {
+	buf := make([]byte, 4*n)
+	if _, err := io.ReadFull(rand, buf); err != nil {
+		return nil, err
+	}
+	out := make([]uint32, n)
+	for i := range out {
+		out[i] = binary.LittleEndian.Uint32(buf[4*i:])
+	}
+	return out, nil
+}
+
+func shortRandom(rand io.Reader, out []small) error {
+	l, err := randomUint32s(rand, p)
+	if err != nil {
+		return err
+	}
+	shortFromList(out, l)
+	return nil
+}
+
+func smallRandom(rand io.Reader, out []small) error {
+	l, err := randomUint32s(rand, p)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < p; i++ {
+		out[i] = small((((l[i] & 0x3fffffff) * 3) >> 30)) - 1
+	}
+	return nil
+}
+
+// hashPrefix returns the first 32 bytes of SHA-512(b || in).
+func hashPrefix(b byte, in ...[]byte) []byte {
+	h := sha512.New()
+	h.Write([]byte{b})
+	for _, x := range in {
+		h.Write(x)
+	}
+	return h.Sum(nil)[:hashSize]
+}
+
+// encode encodes the values r, where 0 <= r[i] < m[i], as a byte string.
+func encode(out []byte, r, m []uint16) []byte {
+	if len(m) == 1 {
+		rr, mm := r[0], m[0]
+		for mm > 1 {
+			out = append(out, byte(rr))
+			rr >>= 8
+			mm = (mm + 255) >> 8
+		}
+		return out
+	}
+
+	n := (len(m) + 1) / 2
+	r2 := make([]uint16, n)
+	m2 := make([]uint16, n)
+	i := 0
+	for ; i < len(m)-1; i += 2 {
+		m0 := uint32(m[i])
+		rr := uint32(r[i]) + uint32(r[i+1])*m0
+		mm := uint32(m[i+1]) * m0
+		for mm >= 16384 {
+			out = append(out, byte(rr))
+			rr >>= 8
+			mm = (mm + 255) >> 8
+		}
+		r2[i/2] = uint16(rr)
+		m2[i/2] = uint16(mm)
+	}
+	if i < len(m) {
+		r2[i/2] = r[i]
+		m2[i/2] = m[i]
+	}
+	return encode(out, r2, m2)
+}
+
+// decode is the inverse of encode. It returns the decoded values, and the
+// number of bytes of s that have been consumed.
+func decode(s []byte, m []uint16) ([]uint16, int) {
+	if len(m) == 1 {
+		switch {
+		case m[0] == 1:
+			return []uint16{0}, 0
+		case m[0] <= 256:
+			return []uint16{uint16(uint32(s[0]) % uint32(m[0]))}, 1
+		default:
+			return []uint16{uint16((uint32(s[0]) + uint32(s[1])<<8) % uint32(m[0]))}, 2
+		}
+	}
+
+	n := (len(m) + 1) / 2
+	m2 := make([]uint16, n)
+	bottomr := make([]uint32, len(m)/2)
+	bottomt := make([]uint32, len(m)/2)
+	used := 0
+	i := 0
+	for ; i < len(m)-1; i += 2 {
+		mm := uint32(m[i]) * uint32(m[i+1])
+		switch {
+		case mm > 256*16383:
+			bottomt[i/2] = 256 * 256
+			bottomr[i/2] = uint32(s[used]) + 256*uint32(s[used+1])
+			used += 2
+			m2[i/2] = uint16((((mm + 255) >> 8) + 255) >> 8)
+		case mm >= 16384:
+			bottomt[i/2] = 256
+			bottomr[i/2] = uint32(s[used])
+			used++
+			m2[i/2] = uint16((mm + 255) >> 8)
+		default:
+			bottomt[i/2] = 1
+			bottomr[i/2] = 0
+			m2[i/2] = uint16(mm)
+		}
+	}
+	if i < len(m) {
+		m2[i/2] = m[i]
+	}
+
+	r2, n2 := decode(s[used:], m2)
+	out := make([]uint16, 0, len(m))
+	for i = 0; i < len(m)-1; i += 2 {
+		r := bottomr[i/2] + bottomt[i/2]*uint32(r2[i/2])
+		r0 := r % uint32(m[i])
+		// The reduction of r1 is only needed for invalid inputs.
+		r1 := (r / uint32(m[i])) % uint32(m[i+1])
+		out = append(out, uint16(r0), uint16(r1))
+	}
+	if i < len(m) {
+		out = append(out, r2[i/2])
+	}
+	return out, used + n2
+}
+
+func constModuli(v uint16) []uint16 {
+	m := make([]uint16, p)
+	for i := range m {
+		m[i] = v
+	}
+	return m
+}
+
+func rqEncode(r []fq) []byte {
+	rr := make([]uint16, p)
+	for i := range rr {
+		rr[i] = uint16(r[i] + q12)
+	}
+	return encode(make([]byte, 0, rqSize), rr, constModuli(q))
+}
+
+func rqDecode(r []fq, s []byte) {
+	rr, _ := decode(s, constModuli(q))
+	for i := range rr {
+		r[i] = fq(rr[i]) - q12
+	}
+}
+
+func roundedEncode(r []fq) []byte {
+	rr := make([]uint16, p)
+	for i := range rr {
+		rr[i] = uint16(((int32(r[i]) + q12) * 10923) >> 15)
+	}
+	return encode(make([]byte, 0, roundedSize), rr, constModuli((q+2)/3))
+}
+
+func roundedDecode(r []fq, s []byte) {
+	rr, _ := decode(s, constModuli((q+2)/3))
+	for i := range rr {
+		r[i] = fq(rr[i])*3 - q12
+	}
+}
+
+func smallEncode(f []small) []byte {
+	s := make([]byte, 0, smallSize)
+	for i := 0; i < p/4; i++ {
+		x := byte(f[4*i]+1) | byte(f[4*i+1]+1)<<2 | byte(f[4*i+2]+1)<<4 | byte(f[4*i+3]+1)<<6
+		s = append(s, x)
+	}
+	return append(s, byte(f[p-1]+1))
+}
+
+func smallDecode(f []small, s []byte) {
+	for i := 0; i < p/4; i++ {
+		x := s[i]
+		f[4*i] = small(x&3) - 1
+		f[4*i+1] = small(x>>2&3) - 1
+		f[4*i+2] = small(x>>4&3) - 1
+		f[4*i+3] = small(x>>6&3) - 1
+	}
+	f[p-1] = small(s[p/4]&3) - 1
+}
+
+// hide returns the ciphertext for the input r, and the encoding of r.
+func hide(r []small, pk, cache []byte) (c, rEnc []byte) {
+	rEnc = smallEncode(r)
+
+	var h, cc, hr [p]fq
+	rqDecode(h[:], pk)
+	rqMultSmall(hr[:], h[:], r)
+	round(cc[:], hr[:])
+	c = roundedEncode(cc[:])
+
+	return append(c, hashConfirm(rEnc, cache)...), rEnc
+}
+
+func hashConfirm(rEnc, cache []byte) []byte {
+	return hashPrefix(2, hashPrefix(3, rEnc), cache)
+}
+
+func hashSession(b byte, rEnc, c []byte) []byte {
+	return hashPrefix(b, hashPrefix(3, rEnc), c)
+}
+
+// GenerateKey returns a new key pair.
+func GenerateKey(rand io.Reader) (publicKey, privateKey []byte, err error) {
+	var g, ginv, f [p]small
+	for {
+		if err := smallRandom(rand, g[:]); err != nil {
+			return nil, nil, err
+		}
+		if r3Recip(ginv[:], g[:]) == 0 {
+			break
+		}
+	}
+	if err := shortRandom(rand, f[:]); err != nil {
+		return nil, nil, err
+	}
+	var finv, h [p]fq
+	rqRecip3(finv[:], f[:]) // always works
+	rqMultSmall(h[:], finv[:], g[:])
+
+	publicKey = rqEncode(h[:])
+
+	privateKey = make([]byte, 0, PrivateKeySize)
+	privateKey = append(privateKey, smallEncode(f[:])...)
+	privateKey = append(privateKey, smallEncode(ginv[:])...)
+	privateKey = append(privateKey, publicKey...)
+	rho := make([]byte, inputsSize)
+	if _, err := io.ReadFull(rand, rho); err != nil {
+		return nil, nil, err
+	}
+	privateKey = append(privateKey, rho...)
+	privateKey = append(privateKey, hashPrefix(4, publicKey)...)
+	return publicKey, privateKey, nil
+}
+
+// Encapsulate returns a ciphertext and the shared key it encapsulates for
+// publicKey.
+func Encapsulate(rand io.Reader, publicKey []byte) (ciphertext, sharedKey []byte, err error) {
+	if len(publicKey) != PublicKeySize {
+		return nil, nil, errors.New("sntrup761: invalid public key length")
+	}
+	var r [p]small
+	if err := shortRandom(rand, r[:]); err != nil {
+		return nil, nil, err
+	}
+	c, rEnc := hide(r[:], publicKey, hashPrefix(4, publicKey))
+	return c, hashSession(1, rEnc, c), nil
+}
+
+// Decapsulate returns the shared key encapsulated in ciphertext. Invalid
+// ciphertexts are implicitly rejected: they yield a pseudorandom key that
+// will not match the peer's.
+func Decapsulate(privateKey, ciphertext []byte) ([]byte, error) {
+	if len(privateKey) != PrivateKeySize {
+		return nil, errors.New("sntrup761: invalid private key length")
+	}
+	if len(ciphertext) != CiphertextSize {
+		return nil, errors.New("sntrup761: invalid ciphertext length")
+	}
+	sk := privateKey[:secretZSize]
+	pk := privateKey[secretZSize : secretZSize+PublicKeySize]
+	rho := privateKey[secretZSize+PublicKeySize : secretZSize+PublicKeySize+inputsSize]
+	cache := privateKey[secretZSize+PublicKeySize+inputsSize:]
+
+	var f, v, e, ev, r [p]small
+	var c, cf, cf3 [p]fq
+	smallDecode(f[:], sk)
+	smallDecode(v[:], sk[smallSize:])
+	roundedDecode(c[:], ciphertext)
+
+	rqMultSmall(cf[:], c[:], f[:])
+	rqMult3(cf3[:], cf[:])
+	r3FromRq(e[:], cf3[:])
+	r3Mult(ev[:], e[:], v[:])
+
+	mask := small(weightwMask(ev[:])) // 0 if weight w, else -1
+	for i := 0; i < w; i++ {
+		r[i] = ((ev[i] ^ 1) &^ mask) ^ 1
+	}
+	for i := w; i < p; i++ {
+		r[i] = ev[i] &^ mask
+	}
+
+	cnew, rEnc := hide(r[:], pk, cache)
+	ok := subtle.ConstantTimeCompare(ciphertext, cnew)
+	subtle.ConstantTimeCopy(1-ok, rEnc, rho)
+	return hashSession(byte(ok), rEnc, ciphertext), nil
+}
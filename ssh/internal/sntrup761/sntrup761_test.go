@@ -0,0 +1,159 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sntrup761
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	mathrand "math/rand"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	for i := 0; i < 3; i++ {
+		pk, sk, err := GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("GenerateKey: %v", err)
+		}
+		if len(pk) != PublicKeySize || len(sk) != PrivateKeySize {
+			t.Fatalf("got key sizes %d, %d, want %d, %d", len(pk), len(sk), PublicKeySize, PrivateKeySize)
+		}
+
+		c, k1, err := Encapsulate(rand.Reader, pk)
+		if err != nil {
+			t.Fatalf("Encapsulate: %v", err)
+		}
+		if len(c) != CiphertextSize || len(k1) != SharedKeySize {
+			t.Fatalf("got ciphertext and key sizes %d, %d, want %d, %d", len(c), len(k1), CiphertextSize, SharedKeySize)
+		}
+
+		k2, err := Decapsulate(sk, c)
+		if err != nil {
+			t.Fatalf("Decapsulate: %v", err)
+		}
+		if !bytes.Equal(k1, k2) {
+			t.Fatalf("shared keys differ: %x != %x", k1, k2)
+		}
+
+		c[0] ^= 1
+		k3, err := Decapsulate(sk, c)
+		if err != nil {
+			t.Fatalf("Decapsulate: %v", err)
+		}
+		if bytes.Equal(k1, k3) {
+			t.Fatal("tampered ciphertext yields the same shared key")
+		}
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	r := mathrand.New(mathrand.NewSource(1))
+	var f, g [p]fq
+	for i := range f {
+		f[i] = fq(r.Intn(q) - q12)
+	}
+	s := rqEncode(f[:])
+	if len(s) != rqSize {
+		t.Fatalf("rqEncode returned %d bytes, want %d", len(s), rqSize)
+	}
+	rqDecode(g[:], s)
+	if f != g {
+		t.Error("rqDecode(rqEncode(f)) != f")
+	}
+
+	for i := range f {
+		f[i] = fq(3*r.Intn((q+2)/3) - q12)
+	}
+	s = roundedEncode(f[:])
+	if len(s) != roundedSize {
+		t.Fatalf("roundedEncode returned %d bytes, want %d", len(s), roundedSize)
+	}
+	roundedDecode(g[:], s)
+	if f != g {
+		t.Error("roundedDecode(roundedEncode(f)) != f")
+	}
+
+	var a, b [p]small
+	for i := range a {
+		a[i] = small(r.Intn(3) - 1)
+	}
+	smallDecode(b[:], smallEncode(a[:]))
+	if a != b {
+		t.Error("smallDecode(smallEncode(a)) != a")
+	}
+}
+
+func TestSort(t *testing.T) {
+	r := mathrand.New(mathrand.NewSource(1))
+	for _, n := range []int{0, 1, 2, 3, 17, 100, p} {
+		x := make([]uint32, n)
+		for i := range x {
+			x[i] = r.Uint32()
+		}
+		want := append([]uint32(nil), x...)
+		sort.Slice(want, func(i, j int) bool { return want[i] < want[j] })
+		sortUint32(x)
+		for i := range x {
+			if x[i] != want[i] {
+				t.Fatalf("n=%d: sortUint32 result is not sorted", n)
+			}
+		}
+	}
+}
+
+func TestShortWeight(t *testing.T) {
+	var r [p]small
+	if err := shortRandom(rand.Reader, r[:]); err != nil {
+		t.Fatal(err)
+	}
+	if weightwMask(r[:]) != 0 {
+		t.Error("random short polynomial does not have weight w")
+	}
+}
+
+// TestOpenSSHEncapsulation checks an encapsulation that OpenSSH accepted in
+// a key exchange with a key that it generated.
+func TestOpenSSHEncapsulation(t *testing.T) {
+	f, err := os.Open("testdata/openssh-encapsulation.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	vector := make(map[string][]byte)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<16)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			t.Fatalf("malformed line %q", line)
+		}
+		if vector[name], err = hex.DecodeString(value); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	c, k, err := Encapsulate(bytes.NewReader(vector["rand"]), vector["pk"])
+	if err != nil {
+		t.Fatalf("Encapsulate: %v", err)
+	}
+	if !bytes.Equal(c, vector["ct"]) {
+		t.Errorf("got ciphertext %x, want %x", c, vector["ct"])
+	}
+	if !bytes.Equal(k, vector["ss"]) {
+		t.Errorf("got shared key %x, want %x", k, vector["ss"])
+	}
+}
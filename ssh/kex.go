@@ -9,6 +9,7 @@ import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/sha512"
 	"crypto/subtle"
 	"encoding/binary"
 	"errors"
@@ -17,18 +18,26 @@ import (
 	"math/big"
 
 	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/ssh/internal/curve448"
+	"golang.org/x/crypto/ssh/internal/sntrup761"
 )
 
 const (
 	kexAlgoDH1SHA1                = "diffie-hellman-group1-sha1"
 	kexAlgoDH14SHA1               = "diffie-hellman-group14-sha1"
 	kexAlgoDH14SHA256             = "diffie-hellman-group14-sha256"
+	kexAlgoDH15SHA512             = "diffie-hellman-group15-sha512"
 	kexAlgoDH16SHA512             = "diffie-hellman-group16-sha512"
+	kexAlgoDH17SHA512             = "diffie-hellman-group17-sha512"
+	kexAlgoDH18SHA512             = "diffie-hellman-group18-sha512"
 	kexAlgoECDH256                = "ecdh-sha2-nistp256"
 	kexAlgoECDH384                = "ecdh-sha2-nistp384"
 	kexAlgoECDH521                = "ecdh-sha2-nistp521"
 	kexAlgoCurve25519SHA256LibSSH = "curve25519-sha256@libssh.org"
 	kexAlgoCurve25519SHA256       = "curve25519-sha256"
+	kexAlgoCurve448SHA512         = "curve448-sha512"
+	kexAlgoSNTRUP761SHA512        = "sntrup761x25519-sha512"
+	kexAlgoSNTRUP761SHA512OpenSSH = "sntrup761x25519-sha512@openssh.com"
 
 	// For the following kex only the client half contains a production
 	// ready implementation. The server half only consists of a minimal
@@ -91,6 +100,29 @@ type kexAlgorithm interface {
 type dhGroup struct {
 	g, p, pMinus1 *big.Int
 	hashFunc      crypto.Hash
+
+	// privBits, if not zero, is the size of the private exponents, which
+	// are otherwise chosen in [1, p-1). Short exponents are safe with safe
+	// primes, and make the large groups much faster, see RFC 3526, section
+	// 8.
+	privBits int
+}
+
+// privateExponent returns a random private exponent for the group.
+func (group *dhGroup) privateExponent(randSource io.Reader) (*big.Int, error) {
+	max := group.pMinus1
+	if group.privBits != 0 {
+		max = new(big.Int).Lsh(bigOne, uint(group.privBits))
+	}
+	for {
+		x, err := rand.Int(randSource, max)
+		if err != nil {
+			return nil, err
+		}
+		if x.Sign() > 0 {
+			return x, nil
+		}
+	}
 }
 
 func (group *dhGroup) diffieHellman(theirPublic, myPrivate *big.Int) (*big.Int, error) {
@@ -101,15 +133,9 @@ func (group *dhGroup) diffieHellman(theirPublic, myPrivate *big.Int) (*big.Int,
 }
 
 func (group *dhGroup) Client(c packetConn, randSource io.Reader, magics *handshakeMagics) (*kexResult, error) {
-	var x *big.Int
-	for {
-		var err error
-		if x, err = rand.Int(randSource, group.pMinus1); err != nil {
-			return nil, err
-		}
-		if x.Sign() > 0 {
-			break
-		}
+	x, err := group.privateExponent(randSource)
+	if err != nil {
+		return nil, err
 	}
 
 	X := new(big.Int).Exp(group.g, x, group.p)
@@ -163,14 +189,9 @@ func (group *dhGroup) Server(c packetConn, randSource io.Reader, magics *handsha
 		return
 	}
 
-	var y *big.Int
-	for {
-		if y, err = rand.Int(randSource, group.pMinus1); err != nil {
-			return
-		}
-		if y.Sign() > 0 {
-			break
-		}
+	y, err := group.privateExponent(randSource)
+	if err != nil {
+		return nil, err
 	}
 
 	Y := new(big.Int).Exp(group.g, y, group.p)
@@ -442,13 +463,43 @@ func init() {
 		hashFunc: crypto.SHA512,
 	}
 
+	// These are the groups called diffie-hellman-group15-sha512,
+	// diffie-hellman-group17-sha512 and diffie-hellman-group18-sha512 in
+	// RFC 8268, and Oakley Groups 15, 17 and 18 in RFC 3526. Their
+	// private exponents are twice as large as the 256-bit security level
+	// of the strongest ciphers.
+	for algo, hex := range map[string]string{
+		kexAlgoDH15SHA512: "FFFFFFFFFFFFFFFFC90FDAA22168C234C4C6628B80DC1CD129024E088A67CC74020BBEA63B139B22514A08798E3404DDEF9519B3CD3A431B302B0A6DF25F14374FE1356D6D51C245E485B576625E7EC6F44C42E9A637ED6B0BFF5CB6F406B7EDEE386BFB5A899FA5AE9F24117C4B1FE649286651ECE45B3DC2007CB8A163BF0598DA48361C55D39A69163FA8FD24CF5F83655D23DCA3AD961C62F356208552BB9ED529077096966D670C354E4ABC9804F1746C08CA18217C32905E462E36CE3BE39E772C180E86039B2783A2EC07A28FB5C55DF06F4C52C9DE2BCBF6955817183995497CEA956AE515D2261898FA051015728E5A8AAAC42DAD33170D04507A33A85521ABDF1CBA64ECFB850458DBEF0A8AEA71575D060C7DB3970F85A6E1E4C7ABF5AE8CDB0933D71E8C94E04A25619DCEE3D2261AD2EE6BF12FFA06D98A0864D87602733EC86A64521F2B18177B200CBBE117577A615D6C770988C0BAD946E208E24FA074E5AB3143DB5BFCE0FD108E4B82D120A93AD2CAFFFFFFFFFFFFFFFF",
+		kexAlgoDH17SHA512: "FFFFFFFFFFFFFFFFC90FDAA22168C234C4C6628B80DC1CD129024E088A67CC74020BBEA63B139B22514A08798E3404DDEF9519B3CD3A431B302B0A6DF25F14374FE1356D6D51C245E485B576625E7EC6F44C42E9A637ED6B0BFF5CB6F406B7EDEE386BFB5A899FA5AE9F24117C4B1FE649286651ECE45B3DC2007CB8A163BF0598DA48361C55D39A69163FA8FD24CF5F83655D23DCA3AD961C62F356208552BB9ED529077096966D670C354E4ABC9804F1746C08CA18217C32905E462E36CE3BE39E772C180E86039B2783A2EC07A28FB5C55DF06F4C52C9DE2BCBF6955817183995497CEA956AE515D2261898FA051015728E5A8AAAC42DAD33170D04507A33A85521ABDF1CBA64ECFB850458DBEF0A8AEA71575D060C7DB3970F85A6E1E4C7ABF5AE8CDB0933D71E8C94E04A25619DCEE3D2261AD2EE6BF12FFA06D98A0864D87602733EC86A64521F2B18177B200CBBE117577A615D6C770988C0BAD946E208E24FA074E5AB3143DB5BFCE0FD108E4B82D120A92108011A723C12A787E6D788719A10BDBA5B2699C327186AF4E23C1A946834B6150BDA2583E9CA2AD44CE8DBBBC2DB04DE8EF92E8EFC141FBECAA6287C59474E6BC05D99B2964FA090C3A2233BA186515BE7ED1F612970CEE2D7AFB81BDD762170481CD0069127D5B05AA993B4EA988D8FDDC186FFB7DC90A6C08F4DF435C93402849236C3FAB4D27C7026C1D4DCB2602646DEC9751E763DBA37BDF8FF9406AD9E530EE5DB382F413001AEB06A53ED9027D831179727B0865A8918DA3EDBEBCF9B14ED44CE6CBACED4BB1BDB7F1447E6CC254B332051512BD7AF426FB8F401378CD2BF5983CA01C64B92ECF032EA15D1721D03F482D7CE6E74FEF6D55E702F46980C82B5A84031900B1C9E59E7C97FBEC7E8F323A97A7E36CC88BE0F1D45B7FF585AC54BD407B22B4154AACC8F6D7EBF48E1D814CC5ED20F8037E0A79715EEF29BE32806A1D58BB7C5DA76F550AA3D8A1FBFF0EB19CCB1A313D55CDA56C9EC2EF29632387FE8D76E3C0468043E8F663F4860EE12BF2D5B0B7474D6E694F91E6DCC4024FFFFFFFFFFFFFFFF",
+		kexAlgoDH18SHA512: "FFFFFFFFFFFFFFFFC90FDAA22168C234C4C6628B80DC1CD129024E088A67CC74020BBEA63B139B22514A08798E3404DDEF9519B3CD3A431B302B0A6DF25F14374FE1356D6D51C245E485B576625E7EC6F44C42E9A637ED6B0BFF5CB6F406B7EDEE386BFB5A899FA5AE9F24117C4B1FE649286651ECE45B3DC2007CB8A163BF0598DA48361C55D39A69163FA8FD24CF5F83655D23DCA3AD961C62F356208552BB9ED529077096966D670C354E4ABC9804F1746C08CA18217C32905E462E36CE3BE39E772C180E86039B2783A2EC07A28FB5C55DF06F4C52C9DE2BCBF6955817183995497CEA956AE515D2261898FA051015728E5A8AAAC42DAD33170D04507A33A85521ABDF1CBA64ECFB850458DBEF0A8AEA71575D060C7DB3970F85A6E1E4C7ABF5AE8CDB0933D71E8C94E04A25619DCEE3D2261AD2EE6BF12FFA06D98A0864D87602733EC86A64521F2B18177B200CBBE117577A615D6C770988C0BAD946E208E24FA074E5AB3143DB5BFCE0FD108E4B82D120A92108011A723C12A787E6D788719A10BDBA5B2699C327186AF4E23C1A946834B6150BDA2583E9CA2AD44CE8DBBBC2DB04DE8EF92E8EFC141FBECAA6287C59474E6BC05D99B2964FA090C3A2233BA186515BE7ED1F612970CEE2D7AFB81BDD762170481CD0069127D5B05AA993B4EA988D8FDDC186FFB7DC90A6C08F4DF435C93402849236C3FAB4D27C7026C1D4DCB2602646DEC9751E763DBA37BDF8FF9406AD9E530EE5DB382F413001AEB06A53ED9027D831179727B0865A8918DA3EDBEBCF9B14ED44CE6CBACED4BB1BDB7F1447E6CC254B332051512BD7AF426FB8F401378CD2BF5983CA01C64B92ECF032EA15D1721D03F482D7CE6E74FEF6D55E702F46980C82B5A84031900B1C9E59E7C97FBEC7E8F323A97A7E36CC88BE0F1D45B7FF585AC54BD407B22B4154AACC8F6D7EBF48E1D814CC5ED20F8037E0A79715EEF29BE32806A1D58BB7C5DA76F550AA3D8A1FBFF0EB19CCB1A313D55CDA56C9EC2EF29632387FE8D76E3C0468043E8F663F4860EE12BF2D5B0B7474D6E694F91E6DBE115974A3926F12FEE5E438777CB6A932DF8CD8BEC4D073B931BA3BC832B68D9DD300741FA7BF8AFC47ED2576F6936BA424663AAB639C5AE4F5683423B4742BF1C978238F16CBE39D652DE3FDB8BEFC848AD922222E04A4037C0713EB57A81A23F0C73473FC646CEA306B4BCBC8862F8385DDFA9D4B7FA2C087E879683303ED5BDD3A062B3CF5B3A278A66D2A13F83F44F82DDF310EE074AB6A364597E899A0255DC164F31CC50846851DF9AB48195DED7EA1B1D510BD7EE74D73FAF36BC31ECFA268359046F4EB879F924009438B481C6CD7889A002ED5EE382BC9190DA6FC026E479558E4475677E9AA9E3050E2765694DFC81F56E880B96E7160C980DD98EDD3DFFFFFFFFFFFFFFFFF",
+	} {
+		p, _ = new(big.Int).SetString(hex, 16)
+		kexAlgoMap[algo] = &dhGroup{
+			g:        new(big.Int).SetInt64(2),
+			p:        p,
+			pMinus1:  new(big.Int).Sub(p, bigOne),
+			hashFunc: crypto.SHA512,
+			privBits: 512,
+		}
+	}
+
 	kexAlgoMap[kexAlgoECDH521] = &ecdh{elliptic.P521()}
 	kexAlgoMap[kexAlgoECDH384] = &ecdh{elliptic.P384()}
 	kexAlgoMap[kexAlgoECDH256] = &ecdh{elliptic.P256()}
 	kexAlgoMap[kexAlgoCurve25519SHA256] = &curve25519sha256{}
 	kexAlgoMap[kexAlgoCurve25519SHA256LibSSH] = &curve25519sha256{}
+	kexAlgoMap[kexAlgoCurve448SHA512] = &curve448sha512{}
+	kexAlgoMap[kexAlgoSNTRUP761SHA512] = &sntrup761sha512{}
+	kexAlgoMap[kexAlgoSNTRUP761SHA512OpenSSH] = &sntrup761sha512{}
 	kexAlgoMap[kexAlgoDHGEXSHA1] = &dhGEXSHA{hashFunc: crypto.SHA1}
 	kexAlgoMap[kexAlgoDHGEXSHA256] = &dhGEXSHA{hashFunc: crypto.SHA256}
+
+	// The GSSAPI key exchange methods of RFC 8732, see gss_kex.go.
+	kexAlgoMap[kexAlgoGSSGroup14SHA256] = &gssKex{dh: &dhGroup{
+		g: group14.g, p: group14.p, pMinus1: group14.pMinus1,
+		hashFunc: crypto.SHA256,
+	}}
+	kexAlgoMap[kexAlgoGSSNISTP256] = &gssKex{curve: elliptic.P256()}
 }
 
 // curve25519sha256 implements the curve25519-sha256 (formerly known as
@@ -585,6 +636,151 @@ func (kex *curve25519sha256) Server(c packetConn, rand io.Reader, magics *handsh
 	}, nil
 }
 
+// sntrup761sha512 implements the hybrid Streamlined NTRU Prime 761 with
+// X25519 key exchange method, as described in
+// draft-ietf-sshm-ntruprime-ssh. The client and server public values are
+// the concatenations of the KEM public key or ciphertext and the X25519
+// public key.
+type sntrup761sha512 struct{}
+
+// sntrup761Secret combines the shared secrets of both key exchanges, and
+// returns it encoded as the string K.
+func sntrup761Secret(kemSecret, x25519Secret []byte) []byte {
+	h := sha512.New()
+	h.Write(kemSecret)
+	h.Write(x25519Secret)
+	secret := h.Sum(nil)
+
+	K := make([]byte, stringLength(len(secret)))
+	marshalString(K, secret)
+	return K
+}
+
+func (kex *sntrup761sha512) Client(c packetConn, rand io.Reader, magics *handshakeMagics) (*kexResult, error) {
+	var kp curve25519KeyPair
+	if err := kp.generate(rand); err != nil {
+		return nil, err
+	}
+	pk, sk, err := sntrup761.GenerateKey(rand)
+	if err != nil {
+		return nil, err
+	}
+
+	clientPub := append(pk, kp.pub[:]...)
+	if err := c.writePacket(Marshal(&kexECDHInitMsg{clientPub})); err != nil {
+		return nil, err
+	}
+
+	packet, err := c.readPacket()
+	if err != nil {
+		return nil, err
+	}
+
+	var reply kexECDHReplyMsg
+	if err = Unmarshal(packet, &reply); err != nil {
+		return nil, err
+	}
+	if len(reply.EphemeralPubKey) != sntrup761.CiphertextSize+32 {
+		return nil, errors.New("ssh: peer's sntrup761x25519 public value has wrong length")
+	}
+
+	kemSecret, err := sntrup761.Decapsulate(sk, reply.EphemeralPubKey[:sntrup761.CiphertextSize])
+	if err != nil {
+		return nil, err
+	}
+
+	var servPub, x25519Secret [32]byte
+	copy(servPub[:], reply.EphemeralPubKey[sntrup761.CiphertextSize:])
+	curve25519.ScalarMult(&x25519Secret, &kp.priv, &servPub)
+	if subtle.ConstantTimeCompare(x25519Secret[:], curve25519Zeros[:]) == 1 {
+		return nil, errors.New("ssh: peer's curve25519 public value has wrong order")
+	}
+
+	h := crypto.SHA512.New()
+	magics.write(h)
+	writeString(h, reply.HostKey)
+	writeString(h, clientPub)
+	writeString(h, reply.EphemeralPubKey)
+
+	K := sntrup761Secret(kemSecret, x25519Secret[:])
+	h.Write(K)
+
+	return &kexResult{
+		H:         h.Sum(nil),
+		K:         K,
+		HostKey:   reply.HostKey,
+		Signature: reply.Signature,
+		Hash:      crypto.SHA512,
+	}, nil
+}
+
+func (kex *sntrup761sha512) Server(c packetConn, rand io.Reader, magics *handshakeMagics, priv AlgorithmSigner, algo string) (*kexResult, error) {
+	packet, err := c.readPacket()
+	if err != nil {
+		return nil, err
+	}
+
+	var kexInit kexECDHInitMsg
+	if err = Unmarshal(packet, &kexInit); err != nil {
+		return nil, err
+	}
+	if len(kexInit.ClientPubKey) != sntrup761.PublicKeySize+32 {
+		return nil, errors.New("ssh: peer's sntrup761x25519 public value has wrong length")
+	}
+
+	ciphertext, kemSecret, err := sntrup761.Encapsulate(rand, kexInit.ClientPubKey[:sntrup761.PublicKeySize])
+	if err != nil {
+		return nil, err
+	}
+
+	var kp curve25519KeyPair
+	if err := kp.generate(rand); err != nil {
+		return nil, err
+	}
+
+	var clientPub, x25519Secret [32]byte
+	copy(clientPub[:], kexInit.ClientPubKey[sntrup761.PublicKeySize:])
+	curve25519.ScalarMult(&x25519Secret, &kp.priv, &clientPub)
+	if subtle.ConstantTimeCompare(x25519Secret[:], curve25519Zeros[:]) == 1 {
+		return nil, errors.New("ssh: peer's curve25519 public value has wrong order")
+	}
+
+	serverPub := append(ciphertext, kp.pub[:]...)
+	hostKeyBytes := priv.PublicKey().Marshal()
+
+	h := crypto.SHA512.New()
+	magics.write(h)
+	writeString(h, hostKeyBytes)
+	writeString(h, kexInit.ClientPubKey)
+	writeString(h, serverPub)
+
+	K := sntrup761Secret(kemSecret, x25519Secret[:])
+	h.Write(K)
+
+	H := h.Sum(nil)
+
+	sig, err := signAndMarshal(priv, rand, H, algo)
+	if err != nil {
+		return nil, err
+	}
+
+	reply := kexECDHReplyMsg{
+		EphemeralPubKey: serverPub,
+		HostKey:         hostKeyBytes,
+		Signature:       sig,
+	}
+	if err := c.writePacket(Marshal(&reply)); err != nil {
+		return nil, err
+	}
+	return &kexResult{
+		H:         H,
+		K:         K,
+		HostKey:   hostKeyBytes,
+		Signature: sig,
+		Hash:      crypto.SHA512,
+	}, nil
+}
+
 // dhGEXSHA implements the diffie-hellman-group-exchange-sha1 and
 // diffie-hellman-group-exchange-sha256 key agreement protocols,
 // as described in RFC 4419
@@ -784,3 +980,126 @@ func (gex dhGEXSHA) Server(c packetConn, randSource io.Reader, magics *handshake
 		Hash:      gex.hashFunc,
 	}, err
 }
+
+// curve448sha512 implements the curve448-sha512 key exchange method, as
+// described in RFC 8731.
+type curve448sha512 struct{}
+
+func curve448GenerateKey(rand io.Reader) (priv, pub []byte, err error) {
+	priv = make([]byte, curve448.ScalarSize)
+	if _, err := io.ReadFull(rand, priv); err != nil {
+		return nil, nil, err
+	}
+	pub, err = curve448.X448(priv, curve448.Basepoint)
+	if err != nil {
+		return nil, nil, err
+	}
+	return priv, pub, nil
+}
+
+// curve448SharedSecret returns the shared secret for priv and the peer's
+// public value, encoded as an mpint.
+func curve448SharedSecret(priv, peerPub []byte) ([]byte, error) {
+	if len(peerPub) != curve448.PointSize {
+		return nil, errors.New("ssh: peer's curve448 public value has wrong length")
+	}
+	secret, err := curve448.X448(priv, peerPub)
+	if err != nil {
+		return nil, errors.New("ssh: peer's curve448 public value has wrong order")
+	}
+	ki := new(big.Int).SetBytes(secret)
+	K := make([]byte, intLength(ki))
+	marshalInt(K, ki)
+	return K, nil
+}
+
+func (kex *curve448sha512) Client(c packetConn, rand io.Reader, magics *handshakeMagics) (*kexResult, error) {
+	priv, pub, err := curve448GenerateKey(rand)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.writePacket(Marshal(&kexECDHInitMsg{pub})); err != nil {
+		return nil, err
+	}
+
+	packet, err := c.readPacket()
+	if err != nil {
+		return nil, err
+	}
+
+	var reply kexECDHReplyMsg
+	if err = Unmarshal(packet, &reply); err != nil {
+		return nil, err
+	}
+	K, err := curve448SharedSecret(priv, reply.EphemeralPubKey)
+	if err != nil {
+		return nil, err
+	}
+
+	h := crypto.SHA512.New()
+	magics.write(h)
+	writeString(h, reply.HostKey)
+	writeString(h, pub)
+	writeString(h, reply.EphemeralPubKey)
+	h.Write(K)
+
+	return &kexResult{
+		H:         h.Sum(nil),
+		K:         K,
+		HostKey:   reply.HostKey,
+		Signature: reply.Signature,
+		Hash:      crypto.SHA512,
+	}, nil
+}
+
+func (kex *curve448sha512) Server(c packetConn, rand io.Reader, magics *handshakeMagics, priv AlgorithmSigner, algo string) (result *kexResult, err error) {
+	packet, err := c.readPacket()
+	if err != nil {
+		return
+	}
+	var kexInit kexECDHInitMsg
+	if err = Unmarshal(packet, &kexInit); err != nil {
+		return
+	}
+
+	ephPriv, ephPub, err := curve448GenerateKey(rand)
+	if err != nil {
+		return nil, err
+	}
+	K, err := curve448SharedSecret(ephPriv, kexInit.ClientPubKey)
+	if err != nil {
+		return nil, err
+	}
+
+	hostKeyBytes := priv.PublicKey().Marshal()
+
+	h := crypto.SHA512.New()
+	magics.write(h)
+	writeString(h, hostKeyBytes)
+	writeString(h, kexInit.ClientPubKey)
+	writeString(h, ephPub)
+	h.Write(K)
+
+	H := h.Sum(nil)
+
+	sig, err := signAndMarshal(priv, rand, H, algo)
+	if err != nil {
+		return nil, err
+	}
+
+	reply := kexECDHReplyMsg{
+		EphemeralPubKey: ephPub,
+		HostKey:         hostKeyBytes,
+		Signature:       sig,
+	}
+	if err := c.writePacket(Marshal(&reply)); err != nil {
+		return nil, err
+	}
+	return &kexResult{
+		H:         H,
+		K:         K,
+		HostKey:   hostKeyBytes,
+		Signature: sig,
+		Hash:      crypto.SHA512,
+	}, nil
+}
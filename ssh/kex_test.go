@@ -9,6 +9,7 @@ package ssh
 import (
 	"crypto/rand"
 	"fmt"
+	"math/big"
 	"reflect"
 	"sync"
 	"testing"
@@ -25,6 +26,10 @@ func TestKexes(t *testing.T) {
 	}
 
 	for name, kex := range kexAlgoMap {
+		if _, ok := kex.(*gssKex); ok {
+			// Tested with a security context in TestGSSAPIKeyExchange.
+			continue
+		}
 		t.Run(name, func(t *testing.T) {
 			wg := sync.WaitGroup{}
 			for i := 0; i < 3; i++ {
@@ -65,6 +70,41 @@ func TestKexes(t *testing.T) {
 	}
 }
 
+func TestDHGroupParameters(t *testing.T) {
+	for name, bits := range map[string]int{
+		kexAlgoDH1SHA1:    1024,
+		kexAlgoDH14SHA256: 2048,
+		kexAlgoDH15SHA512: 3072,
+		kexAlgoDH16SHA512: 4096,
+		kexAlgoDH17SHA512: 6144,
+		kexAlgoDH18SHA512: 8192,
+	} {
+		group := kexAlgoMap[name].(*dhGroup)
+		if got := group.p.BitLen(); got != bits {
+			t.Errorf("%s: got a %d-bit prime, want %d bits", name, got, bits)
+		}
+		// The groups are safe primes with generator 2. Checking the
+		// largest ones takes a few seconds.
+		q := new(big.Int).Rsh(group.p, 1)
+		if (bits <= 4096 || !testing.Short()) && (!group.p.ProbablyPrime(0) || !q.ProbablyPrime(0)) {
+			t.Errorf("%s: p is not a safe prime", name)
+		}
+		if group.g.Cmp(big.NewInt(2)) != 0 {
+			t.Errorf("%s: got generator %v, want 2", name, group.g)
+		}
+		if new(big.Int).Sub(group.p, group.pMinus1).Cmp(bigOne) != 0 {
+			t.Errorf("%s: pMinus1 is not p-1", name)
+		}
+
+		// Peer values outside of [2, p-2] are rejected.
+		for _, y := range []*big.Int{big.NewInt(0), bigOne, group.pMinus1, group.p} {
+			if _, err := group.diffieHellman(y, big.NewInt(3)); err == nil {
+				t.Errorf("%s: public value %v accepted", name, y)
+			}
+		}
+	}
+}
+
 func BenchmarkKexes(b *testing.B) {
 	type kexResultErr struct {
 		result *kexResult
@@ -72,6 +112,9 @@ func BenchmarkKexes(b *testing.B) {
 	}
 
 	for name, kex := range kexAlgoMap {
+		if _, ok := kex.(*gssKex); ok {
+			continue
+		}
 		b.Run(name, func(b *testing.B) {
 			for i := 0; i < b.N; i++ {
 				t1, t2 := memPipe()
@@ -0,0 +1,151 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// maxKeyFileLine is the longest line a KeyFileScanner accepts, well above
+// the size of the largest keys and of known_hosts lines with many hosts.
+const maxKeyFileLine = 1 << 20
+
+// A KeyFileEntry is an entry of an authorized_keys or known_hosts file, as
+// returned by KeyFileScanner.
+type KeyFileEntry struct {
+	// Line is the line number of the entry, starting at 1.
+	Line int
+
+	// Err is a *KeyFileError if the line could not be parsed, in which
+	// case only Line is set.
+	Err error
+
+	Key     PublicKey
+	Comment string
+
+	// Options are the options of an authorized_keys entry, as returned by
+	// ParseAuthorizedKey.
+	Options []string
+
+	// Marker and Hosts are the marker and host patterns of a known_hosts
+	// entry, as returned by ParseKnownHosts.
+	Marker string
+	Hosts  []string
+}
+
+// KeyFileError is the error of a malformed line in an authorized_keys or
+// known_hosts file.
+type KeyFileError struct {
+	Line int
+	Err  error
+}
+
+func (e *KeyFileError) Error() string {
+	return fmt.Sprintf("ssh: line %d: %s", e.Line, strings.TrimPrefix(e.Err.Error(), "ssh: "))
+}
+
+func (e *KeyFileError) Unwrap() error {
+	return e.Err
+}
+
+// A KeyFileScanner reads the entries of an authorized_keys or known_hosts
+// file one line at a time, so that large files need not be held in memory.
+// Comments and blank lines are skipped. Malformed lines are returned as
+// entries with Err set, which reports the line number, unless
+// SkipMalformed is set.
+//
+//	s := NewAuthorizedKeysScanner(f)
+//	for s.Scan() {
+//		e := s.Entry()
+//		if e.Err != nil {
+//			log.Print(e.Err)
+//			continue
+//		}
+//		...
+//	}
+//	if err := s.Err(); err != nil {
+//		...
+//	}
+type KeyFileScanner struct {
+	// SkipMalformed makes Scan skip lines that can't be parsed.
+	SkipMalformed bool
+
+	s          *bufio.Scanner
+	knownHosts bool
+	line       int
+	entry      KeyFileEntry
+}
+
+// NewAuthorizedKeysScanner returns a KeyFileScanner that reads an
+// authorized_keys file from r.
+func NewAuthorizedKeysScanner(r io.Reader) *KeyFileScanner {
+	return newKeyFileScanner(r, false)
+}
+
+// NewKnownHostsScanner returns a KeyFileScanner that reads a known_hosts
+// file from r.
+func NewKnownHostsScanner(r io.Reader) *KeyFileScanner {
+	return newKeyFileScanner(r, true)
+}
+
+func newKeyFileScanner(r io.Reader, knownHosts bool) *KeyFileScanner {
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, maxKeyFileLine)
+	return &KeyFileScanner{s: s, knownHosts: knownHosts}
+}
+
+// Scan advances to the next entry, which is then available from Entry. It
+// returns false at the end of the input or on a read error.
+func (s *KeyFileScanner) Scan() bool {
+	for s.s.Scan() {
+		s.line++
+		line := bytes.TrimSpace(s.s.Bytes())
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		s.entry = KeyFileEntry{Line: s.line}
+		if err := s.parse(line); err != nil {
+			if s.SkipMalformed {
+				continue
+			}
+			s.entry = KeyFileEntry{Line: s.line, Err: &KeyFileError{Line: s.line, Err: err}}
+		}
+		return true
+	}
+	return false
+}
+
+func (s *KeyFileScanner) parse(line []byte) error {
+	e := &s.entry
+	var err error
+	if s.knownHosts {
+		e.Marker, e.Hosts, e.Key, e.Comment, _, err = ParseKnownHosts(line)
+		if err == io.EOF {
+			err = errors.New("ssh: invalid entry in known_hosts data")
+		}
+	} else {
+		e.Key, e.Comment, e.Options, _, err = ParseAuthorizedKey(line)
+	}
+	return err
+}
+
+// Entry returns the entry read by the last call to Scan.
+func (s *KeyFileScanner) Entry() *KeyFileEntry {
+	return &s.entry
+}
+
+// Err returns the first error reading the input, other than io.EOF. Lines
+// that can't be parsed are not reported here but in KeyFileEntry.Err.
+func (s *KeyFileScanner) Err() error {
+	if err := s.s.Err(); err != nil {
+		return fmt.Errorf("ssh: after line %d: %w", s.line, err)
+	}
+	return nil
+}
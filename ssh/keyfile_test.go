@@ -0,0 +1,94 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"bufio"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAuthorizedKeysScanner(t *testing.T) {
+	ed := strings.TrimSpace(string(MarshalAuthorizedKey(testPublicKeys["ed25519"])))
+	ec := strings.TrimSpace(string(MarshalAuthorizedKey(testPublicKeys["ecdsa"])))
+	input := "# comment\n" +
+		ed + " first\n" +
+		"\n" +
+		"not a key\n" +
+		`no-pty,command="x" ` + ec + " second\r\n" +
+		"ssh-ed25519 AAAAbroken\n" +
+		ed
+
+	for _, skip := range []bool{false, true} {
+		s := NewAuthorizedKeysScanner(strings.NewReader(input))
+		s.SkipMalformed = skip
+		var lines []int
+		var comments []string
+		var errs int
+		for s.Scan() {
+			e := s.Entry()
+			lines = append(lines, e.Line)
+			if e.Err != nil {
+				var kfe *KeyFileError
+				if !errors.As(e.Err, &kfe) || kfe.Line != e.Line || !strings.HasPrefix(e.Err.Error(), "ssh: line ") {
+					t.Errorf("got error %v", e.Err)
+				}
+				errs++
+				continue
+			}
+			comments = append(comments, e.Comment)
+			if e.Line == 5 && !reflect.DeepEqual(e.Options, []string{"no-pty", `command="x"`}) {
+				t.Errorf("got options %q", e.Options)
+			}
+		}
+		if err := s.Err(); err != nil {
+			t.Fatal(err)
+		}
+
+		wantLines, wantErrs := []int{2, 4, 5, 6, 7}, 2
+		if skip {
+			wantLines, wantErrs = []int{2, 5, 7}, 0
+		}
+		if !reflect.DeepEqual(lines, wantLines) || errs != wantErrs {
+			t.Errorf("SkipMalformed=%v: got lines %v with %d errors, want %v with %d", skip, lines, errs, wantLines, wantErrs)
+		}
+		if want := []string{"first", "second", ""}; !reflect.DeepEqual(comments, want) {
+			t.Errorf("got comments %q, want %q", comments, want)
+		}
+	}
+}
+
+func TestKnownHostsScanner(t *testing.T) {
+	ed := strings.TrimSpace(string(MarshalAuthorizedKey(testPublicKeys["ed25519"])))
+	input := "host1,host2 " + ed + "\n" +
+		"@cert-authority *.example.com " + ed + " ca\n" +
+		"justonefield\n"
+	s := NewKnownHostsScanner(strings.NewReader(input))
+	var entries []KeyFileEntry
+	for s.Scan() {
+		entries = append(entries, *s.Entry())
+	}
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries, want 3", len(entries))
+	}
+	if !reflect.DeepEqual(entries[0].Hosts, []string{"host1", "host2"}) || entries[1].Marker != "cert-authority" || entries[1].Comment != "ca" {
+		t.Errorf("got entries %+v", entries[:2])
+	}
+	if entries[2].Err == nil || entries[2].Line != 3 {
+		t.Errorf("got %+v, want an error on line 3", entries[2])
+	}
+}
+
+func TestKeyFileScannerLongLine(t *testing.T) {
+	s := NewAuthorizedKeysScanner(strings.NewReader(strings.Repeat("a", maxKeyFileLine+1)))
+	if s.Scan() {
+		t.Error("Scan returned an entry for an overlong line")
+	}
+	if err := s.Err(); !errors.Is(err, bufio.ErrTooLong) {
+		t.Errorf("got %v, want bufio.ErrTooLong", err)
+	}
+}
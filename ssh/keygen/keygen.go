@@ -0,0 +1,200 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package keygen generates SSH key pairs and writes them in the formats of
+// ssh-keygen: OpenSSH private key files and authorized_keys lines.
+package keygen
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Key types, as accepted by the -t option of ssh-keygen.
+const (
+	RSA     = "rsa"
+	ECDSA   = "ecdsa"
+	Ed25519 = "ed25519"
+)
+
+// Key sizes used when GenerateKey is passed zero bits, the defaults of
+// ssh-keygen.
+const (
+	DefaultRSABits   = 3072
+	DefaultECDSABits = 256
+)
+
+// minRSABits is the smallest RSA key ssh-keygen generates.
+const minRSABits = 1024
+
+// A Key is a generated key pair.
+type Key struct {
+	// PrivateKey is an *rsa.PrivateKey, *ecdsa.PrivateKey or
+	// ed25519.PrivateKey.
+	PrivateKey crypto.Signer
+
+	// Comment is stored in the private key file and appended to the
+	// authorized_keys line, usually the user@host the key belongs to.
+	Comment string
+
+	signer ssh.Signer
+}
+
+// GenerateKey generates a key of type keyType, one of RSA, ECDSA and
+// Ed25519. bits is the modulus size of RSA keys, at least 1024, or the
+// curve size of ECDSA keys, 256, 384 or 521. If bits is zero, the default
+// of ssh-keygen is used. bits must be zero for Ed25519 keys.
+func GenerateKey(rand io.Reader, keyType string, bits int) (*Key, error) {
+	var priv crypto.Signer
+	var err error
+	switch keyType {
+	case RSA:
+		if bits == 0 {
+			bits = DefaultRSABits
+		}
+		if bits < minRSABits {
+			return nil, fmt.Errorf("keygen: RSA keys must have at least %d bits", minRSABits)
+		}
+		priv, err = rsa.GenerateKey(rand, bits)
+	case ECDSA:
+		var curve elliptic.Curve
+		switch bits {
+		case 0, 256:
+			curve = elliptic.P256()
+		case 384:
+			curve = elliptic.P384()
+		case 521:
+			curve = elliptic.P521()
+		default:
+			return nil, errors.New("keygen: ECDSA keys must have 256, 384 or 521 bits")
+		}
+		priv, err = ecdsa.GenerateKey(curve, rand)
+	case Ed25519:
+		if bits != 0 {
+			return nil, errors.New("keygen: Ed25519 keys have a fixed size")
+		}
+		_, priv, err = ed25519.GenerateKey(rand)
+	default:
+		return nil, fmt.Errorf("keygen: unsupported key type %q", keyType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return NewKey(priv)
+}
+
+// NewKey returns a Key for an existing private key: an *rsa.PrivateKey,
+// *ecdsa.PrivateKey or ed25519.PrivateKey.
+func NewKey(priv crypto.Signer) (*Key, error) {
+	signer, err := ssh.NewSignerFromSigner(priv)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{PrivateKey: priv, signer: signer}, nil
+}
+
+// DefaultComment returns the comment ssh-keygen gives new keys, the name of
+// the current user and the host name joined by "@".
+func DefaultComment() string {
+	name := "unknown"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		host = "localhost"
+	}
+	return name + "@" + host
+}
+
+// Signer returns an ssh.Signer for the key.
+func (k *Key) Signer() ssh.Signer {
+	return k.signer
+}
+
+// PublicKey returns the public key.
+func (k *Key) PublicKey() ssh.PublicKey {
+	return k.signer.PublicKey()
+}
+
+// Fingerprint returns the SHA-256 fingerprint of the public key, as printed
+// by ssh-keygen.
+func (k *Key) Fingerprint() string {
+	return ssh.FingerprintSHA256(k.PublicKey())
+}
+
+// comment returns the comment on a single line.
+func (k *Key) comment() string {
+	return strings.Join(strings.Fields(k.Comment), " ")
+}
+
+// AuthorizedKey returns the public key as an authorized_keys line, the
+// format of ssh-keygen's .pub files, ending with the comment if there is
+// one.
+func (k *Key) AuthorizedKey() []byte {
+	line := ssh.MarshalAuthorizedKey(k.PublicKey())
+	if c := k.comment(); c != "" {
+		line = append(line[:len(line)-1], " "+c+"\n"...)
+	}
+	return line
+}
+
+// MarshalPrivateKey returns the private key as a PEM encoded OpenSSH private
+// key file. If passphrase is not empty, the file is encrypted with it.
+func (k *Key) MarshalPrivateKey(passphrase []byte) ([]byte, error) {
+	var block *pem.Block
+	var err error
+	if len(passphrase) == 0 {
+		block, err = ssh.MarshalPrivateKey(k.PrivateKey, k.comment())
+	} else {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(k.PrivateKey, k.comment(), passphrase)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(block), nil
+}
+
+// WriteFiles writes the private key to path, readable only by its owner,
+// and the public key to path+".pub", like ssh-keygen -f path. Existing
+// files are not overwritten.
+func (k *Key) WriteFiles(path string, passphrase []byte) error {
+	priv, err := k.MarshalPrivateKey(passphrase)
+	if err != nil {
+		return err
+	}
+	if err := writeNewFile(path, priv, 0600); err != nil {
+		return err
+	}
+	if err := writeNewFile(path+".pub", k.AuthorizedKey(), 0644); err != nil {
+		os.Remove(path)
+		return err
+	}
+	return nil
+}
+
+func writeNewFile(path string, data []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,145 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package keygen
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestGenerateKey(t *testing.T) {
+	for _, tt := range []struct {
+		keyType string
+		bits    int
+		want    string
+	}{
+		{RSA, 2048, ssh.KeyAlgoRSA},
+		{ECDSA, 0, ssh.KeyAlgoECDSA256},
+		{ECDSA, 384, ssh.KeyAlgoECDSA384},
+		{ECDSA, 521, ssh.KeyAlgoECDSA521},
+		{Ed25519, 0, ssh.KeyAlgoED25519},
+	} {
+		key, err := GenerateKey(rand.Reader, tt.keyType, tt.bits)
+		if err != nil {
+			t.Fatalf("GenerateKey(%s, %d): %v", tt.keyType, tt.bits, err)
+		}
+		if got := key.PublicKey().Type(); got != tt.want {
+			t.Errorf("GenerateKey(%s, %d) made a %s key, want %s", tt.keyType, tt.bits, got, tt.want)
+		}
+		if k, ok := key.PrivateKey.(*rsa.PrivateKey); ok && k.N.BitLen() != tt.bits {
+			t.Errorf("got a %d-bit RSA key, want %d bits", k.N.BitLen(), tt.bits)
+		}
+
+		key.Comment = "alice@example.com"
+		line := key.AuthorizedKey()
+		pub, comment, _, rest, err := ssh.ParseAuthorizedKey(line)
+		if err != nil {
+			t.Fatalf("ParseAuthorizedKey(%q): %v", line, err)
+		}
+		if !bytes.Equal(pub.Marshal(), key.PublicKey().Marshal()) || comment != key.Comment || len(rest) != 0 {
+			t.Errorf("AuthorizedKey() = %q, does not round trip", line)
+		}
+
+		for _, passphrase := range []string{"", "secret"} {
+			pemBytes, err := key.MarshalPrivateKey([]byte(passphrase))
+			if err != nil {
+				t.Fatalf("MarshalPrivateKey: %v", err)
+			}
+			var signer ssh.Signer
+			if passphrase == "" {
+				signer, err = ssh.ParsePrivateKey(pemBytes)
+			} else {
+				signer, err = ssh.ParsePrivateKeyWithPassphrase(pemBytes, []byte(passphrase))
+			}
+			if err != nil {
+				t.Fatalf("parsing the private key: %v", err)
+			}
+			if !bytes.Equal(signer.PublicKey().Marshal(), key.PublicKey().Marshal()) {
+				t.Errorf("%s: private key file has the wrong key", tt.keyType)
+			}
+		}
+	}
+
+	for _, tt := range []struct {
+		keyType string
+		bits    int
+	}{
+		{RSA, 512},
+		{ECDSA, 224},
+		{Ed25519, 256},
+		{"dsa", 0},
+	} {
+		if _, err := GenerateKey(rand.Reader, tt.keyType, tt.bits); err == nil {
+			t.Errorf("GenerateKey(%s, %d) succeeded", tt.keyType, tt.bits)
+		}
+	}
+}
+
+func TestAuthorizedKeyComment(t *testing.T) {
+	key, err := GenerateKey(rand.Reader, Ed25519, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line := string(key.AuthorizedKey()); strings.Count(line, " ") != 1 || !strings.HasSuffix(line, "\n") {
+		t.Errorf("AuthorizedKey() without a comment = %q", line)
+	}
+	key.Comment = "multi\nline  comment\n"
+	line := key.AuthorizedKey()
+	if !bytes.HasSuffix(line, []byte(" multi line comment\n")) || bytes.Count(line, []byte("\n")) != 1 {
+		t.Errorf("AuthorizedKey() = %q, want the comment on one line", line)
+	}
+	if !strings.Contains(DefaultComment(), "@") {
+		t.Errorf("DefaultComment() = %q, want user@host", DefaultComment())
+	}
+}
+
+func TestWriteFiles(t *testing.T) {
+	key, err := GenerateKey(rand.Reader, ECDSA, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key.Comment = "test"
+	path := filepath.Join(t.TempDir(), "id_ecdsa")
+	if err := key.WriteFiles(path, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	priv, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ssh.ParseRawPrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !parsed.(*ecdsa.PrivateKey).Equal(key.PrivateKey) {
+		t.Error("private key file has the wrong key")
+	}
+	pub, err := os.ReadFile(path + ".pub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pub, key.AuthorizedKey()) {
+		t.Errorf("public key file = %q, want %q", pub, key.AuthorizedKey())
+	}
+	if fi, err := os.Stat(path); err == nil && os.PathSeparator == '/' && fi.Mode().Perm() != 0600 {
+		t.Errorf("private key file has mode %v, want 0600", fi.Mode().Perm())
+	}
+
+	if err := key.WriteFiles(path, nil); err == nil {
+		t.Error("WriteFiles overwrote existing files")
+	}
+	if !strings.HasPrefix(key.Fingerprint(), "SHA256:") {
+		t.Errorf("Fingerprint() = %q", key.Fingerprint())
+	}
+}
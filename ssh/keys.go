@@ -6,6 +6,7 @@ package ssh
 
 import (
 	"bytes"
+	"context"
 	"crypto"
 	"crypto/aes"
 	"crypto/cipher"
@@ -26,10 +27,12 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"math/big"
 	"strings"
 
 	"golang.org/x/crypto/ssh/internal/bcrypt_pbkdf"
+	"golang.org/x/crypto/ssh/internal/curve448"
 )
 
 // Public key algorithms names. These values can appear in PublicKey.Type,
@@ -44,6 +47,7 @@ const (
 	KeyAlgoECDSA521   = "ecdsa-sha2-nistp521"
 	KeyAlgoED25519    = "ssh-ed25519"
 	KeyAlgoSKED25519  = "sk-ssh-ed25519@openssh.com"
+	KeyAlgoED448      = "ssh-ed448"
 
 	// KeyAlgoRSASHA256 and KeyAlgoRSASHA512 are only public key algorithms, not
 	// public key formats, so they can't appear as a PublicKey.Type. The
@@ -77,6 +81,8 @@ func parsePubKey(in []byte, algo string) (pubKey PublicKey, rest []byte, err err
 		return parseED25519(in)
 	case KeyAlgoSKED25519:
 		return parseSKEd25519(in)
+	case KeyAlgoED448:
+		return parseED448(in)
 	case CertAlgoRSAv01, CertAlgoDSAv01, CertAlgoECDSA256v01, CertAlgoECDSA384v01, CertAlgoECDSA521v01, CertAlgoSKECDSA256v01, CertAlgoED25519v01, CertAlgoSKED25519v01:
 		cert, err := parseCert(in, certKeyAlgoNames[algo])
 		if err != nil {
@@ -298,17 +304,37 @@ func MarshalAuthorizedKey(key PublicKey) []byte {
 }
 
 // MarshalPrivateKey returns a PEM block with the private key serialized in the
-// OpenSSH format.
+// OpenSSH format. The key may be a *rsa.PrivateKey, a *ecdsa.PrivateKey, or an
+// ed25519.PrivateKey or a pointer to one, and comment is stored alongside it
+// as ssh-keygen does. [pem.Encode] writes the block in the format expected by
+// OpenSSH and ParsePrivateKey.
 func MarshalPrivateKey(key crypto.PrivateKey, comment string) (*pem.Block, error) {
 	return marshalOpenSSHPrivateKey(key, comment, unencryptedOpenSSHMarshaler)
 }
 
 // MarshalPrivateKeyWithPassphrase returns a PEM block holding the encrypted
-// private key serialized in the OpenSSH format.
+// private key serialized in the OpenSSH format. The key is encrypted with
+// aes256-ctr under a key derived from passphrase by bcrypt_pbkdf with 16
+// rounds, the default of ssh-keygen.
 func MarshalPrivateKeyWithPassphrase(key crypto.PrivateKey, comment string, passphrase []byte) (*pem.Block, error) {
-	return marshalOpenSSHPrivateKey(key, comment, passphraseProtectedOpenSSHMarshaler(passphrase))
+	return MarshalPrivateKeyWithPassphraseRounds(key, comment, passphrase, defaultBcryptRounds)
 }
 
+// MarshalPrivateKeyWithPassphraseRounds is like
+// MarshalPrivateKeyWithPassphrase, but runs rounds rounds of bcrypt_pbkdf,
+// like the -a option of ssh-keygen. More rounds make guessing the passphrase
+// slower, and so does loading the key.
+func MarshalPrivateKeyWithPassphraseRounds(key crypto.PrivateKey, comment string, passphrase []byte, rounds int) (*pem.Block, error) {
+	if rounds < 1 || int64(rounds) > math.MaxUint32 {
+		return nil, fmt.Errorf("ssh: invalid number of bcrypt_pbkdf rounds %d", rounds)
+	}
+	return marshalOpenSSHPrivateKey(key, comment, passphraseProtectedOpenSSHMarshaler(passphrase, uint32(rounds)))
+}
+
+// defaultBcryptRounds is the number of bcrypt_pbkdf rounds used by
+// ssh-keygen unless told otherwise.
+const defaultBcryptRounds = 16
+
 // PublicKey represents a public key using an unspecified algorithm.
 //
 // Some PublicKeys provided by this package also implement CryptoPublicKey.
@@ -373,6 +399,39 @@ type MultiAlgorithmSigner interface {
 	Algorithms() []string
 }
 
+// A SignerWithContext is a Signer whose signatures may need to be abandoned,
+// such as one whose key is held by a remote key management service. This
+// package calls SignWithContext instead of the other signing methods for
+// user authentication and certificate signing, with the context passed to
+// NewClientConnContext, DialContext or Certificate.SignCertContext.
+//
+// Signers returned by NewCertSigner and NewSignerWithAlgorithms implement
+// SignerWithContext, and pass the context on to the signer they wrap. A
+// SignerWithContext that should offer more than one algorithm must also
+// implement MultiAlgorithmSigner.
+type SignerWithContext interface {
+	Signer
+
+	// SignWithContext is like AlgorithmSigner.SignWithAlgorithm, but should
+	// return ctx.Err() if ctx is done before the signature is made.
+	SignWithContext(ctx context.Context, rand io.Reader, data []byte, algorithm string) (*Signature, error)
+}
+
+// signWithContext signs data with s, preferring SignWithContext, then
+// SignWithAlgorithm if algorithm is not empty, then Sign.
+func signWithContext(ctx context.Context, s Signer, rand io.Reader, data []byte, algorithm string) (*Signature, error) {
+	if cs, ok := s.(SignerWithContext); ok {
+		return cs.SignWithContext(ctx, rand, data, algorithm)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if as, ok := s.(AlgorithmSigner); ok && algorithm != "" {
+		return as.SignWithAlgorithm(rand, data, algorithm)
+	}
+	return s.Sign(rand, data)
+}
+
 // NewSignerWithAlgorithms returns a signer restricted to the specified
 // algorithms. The algorithms must be set in preference order. The list must not
 // be empty, and it must not include certificate types. An error is returned if
@@ -432,6 +491,16 @@ func (s *multiAlgorithmSigner) SignWithAlgorithm(rand io.Reader, data []byte, al
 	return s.AlgorithmSigner.SignWithAlgorithm(rand, data, algorithm)
 }
 
+func (s *multiAlgorithmSigner) SignWithContext(ctx context.Context, rand io.Reader, data []byte, algorithm string) (*Signature, error) {
+	if !s.isAlgorithmSupported(algorithm) {
+		return nil, fmt.Errorf("ssh: algorithm %q is not supported: %v", algorithm, s.supportedAlgorithms)
+	}
+	if algorithm == "" {
+		algorithm = underlyingAlgo(s.PublicKey().Type())
+	}
+	return signWithContext(ctx, s.AlgorithmSigner, rand, data, algorithm)
+}
+
 type rsaPublicKey rsa.PublicKey
 
 func (r *rsaPublicKey) Type() string {
@@ -696,6 +765,56 @@ func (k ed25519PublicKey) CryptoPublicKey() crypto.PublicKey {
 	return ed25519.PublicKey(k)
 }
 
+// ed448PublicKey is an Ed448 public key, see RFC 8709.
+type ed448PublicKey []byte
+
+func (k ed448PublicKey) Type() string {
+	return KeyAlgoED448
+}
+
+func parseED448(in []byte) (out PublicKey, rest []byte, err error) {
+	var w struct {
+		KeyBytes []byte
+		Rest     []byte `ssh:"rest"`
+	}
+
+	if err := Unmarshal(in, &w); err != nil {
+		return nil, nil, err
+	}
+
+	if l := len(w.KeyBytes); l != curve448.PublicKeySize {
+		return nil, nil, fmt.Errorf("invalid size %d for Ed448 public key", l)
+	}
+
+	return ed448PublicKey(w.KeyBytes), w.Rest, nil
+}
+
+func (k ed448PublicKey) Marshal() []byte {
+	w := struct {
+		Name     string
+		KeyBytes []byte
+	}{
+		KeyAlgoED448,
+		[]byte(k),
+	}
+	return Marshal(&w)
+}
+
+func (k ed448PublicKey) Verify(b []byte, sig *Signature) error {
+	if sig.Format != k.Type() {
+		return fmt.Errorf("ssh: signature type %s for key type %s", sig.Format, k.Type())
+	}
+	if l := len(k); l != curve448.PublicKeySize {
+		return fmt.Errorf("ssh: invalid size %d for Ed448 public key", l)
+	}
+
+	if ok := curve448.Verify(k, b, sig.Blob); !ok {
+		return errors.New("ssh: signature did not verify")
+	}
+
+	return nil
+}
+
 func supportedEllipticCurve(curve elliptic.Curve) bool {
 	return curve == elliptic.P256() || curve == elliptic.P384() || curve == elliptic.P521()
 }
@@ -782,10 +901,22 @@ func (k *ecdsaPublicKey) CryptoPublicKey() crypto.PublicKey {
 	return (*ecdsa.PublicKey)(k)
 }
 
-// skFields holds the additional fields present in U2F/FIDO2 signatures.
-// See openssh/PROTOCOL.u2f 'SSH U2F Signatures' for details.
-type skFields struct {
-	// Flags contains U2F/FIDO2 flags such as 'user present'
+// Flags of the signatures made by U2F/FIDO2 security keys, see
+// SKSignature.
+const (
+	// SKFlagUserPresent is set if the user touched the security key.
+	SKFlagUserPresent = 0x01
+	// SKFlagUserVerified is set if the security key verified the
+	// identity of the user, for example with a PIN or a fingerprint.
+	SKFlagUserVerified = 0x04
+)
+
+// SKSignature holds the additional fields present in U2F/FIDO2 signatures,
+// made by sk-ecdsa-sha2-nistp256@openssh.com and sk-ssh-ed25519@openssh.com
+// keys and their certificates. See openssh/PROTOCOL.u2f 'SSH U2F
+// Signatures' for details. The fields are covered by the signature.
+type SKSignature struct {
+	// Flags contains U2F/FIDO2 flags such as SKFlagUserPresent.
 	Flags byte
 	// Counter is a monotonic signature counter which can be
 	// used to detect concurrent use of a private key, should
@@ -793,6 +924,32 @@ type skFields struct {
 	Counter uint32
 }
 
+// UserPresent reports whether the user touched the security key.
+func (s *SKSignature) UserPresent() bool {
+	return s.Flags&SKFlagUserPresent != 0
+}
+
+// UserVerified reports whether the security key verified the user.
+func (s *SKSignature) UserVerified() bool {
+	return s.Flags&SKFlagUserVerified != 0
+}
+
+// ParseSKSignature returns the security key fields of sig, which must have
+// been made by a security key. The fields are only trustworthy once the
+// signature has been verified.
+func ParseSKSignature(sig *Signature) (*SKSignature, error) {
+	switch sig.Format {
+	case KeyAlgoSKECDSA256, CertAlgoSKECDSA256v01, KeyAlgoSKED25519, CertAlgoSKED25519v01:
+	default:
+		return nil, fmt.Errorf("ssh: signature type %s is not made by a security key", sig.Format)
+	}
+	var skf SKSignature
+	if err := Unmarshal(sig.Rest, &skf); err != nil {
+		return nil, err
+	}
+	return &skf, nil
+}
+
 type skECDSAPublicKey struct {
 	// application is a URL-like string, typically "ssh:" for SSH.
 	// see openssh/PROTOCOL.u2f for details.
@@ -875,7 +1032,7 @@ func (k *skECDSAPublicKey) Verify(data []byte, sig *Signature) error {
 		return err
 	}
 
-	var skf skFields
+	var skf SKSignature
 	if err := Unmarshal(sig.Rest, &skf); err != nil {
 		return err
 	}
@@ -978,7 +1135,7 @@ func (k *skEd25519PublicKey) Verify(data []byte, sig *Signature) error {
 		return err
 	}
 
-	var skf skFields
+	var skf SKSignature
 	if err := Unmarshal(sig.Rest, &skf); err != nil {
 		return err
 	}
@@ -1355,7 +1512,7 @@ func unencryptedOpenSSHMarshaler(privKeyBlock []byte) ([]byte, string, string, s
 	return key, "none", "none", "", nil
 }
 
-func passphraseProtectedOpenSSHMarshaler(passphrase []byte) openSSHEncryptFunc {
+func passphraseProtectedOpenSSHMarshaler(passphrase []byte, rounds uint32) openSSHEncryptFunc {
 	return func(privKeyBlock []byte) ([]byte, string, string, string, error) {
 		salt := make([]byte, 16)
 		if _, err := rand.Read(salt); err != nil {
@@ -1365,7 +1522,7 @@ func passphraseProtectedOpenSSHMarshaler(passphrase []byte) openSSHEncryptFunc {
 		opts := struct {
 			Salt   []byte
 			Rounds uint32
-		}{salt, 16}
+		}{salt, rounds}
 
 		// Derive key to encrypt the private key block.
 		k, err := bcrypt_pbkdf.Key(passphrase, salt, int(opts.Rounds), 32+aes.BlockSize)
@@ -1439,11 +1596,9 @@ type openSSHECDSAPrivateKey struct {
 	Pad     []byte `ssh:"rest"`
 }
 
-// parseOpenSSHPrivateKey parses an OpenSSH private key, using the decrypt
-// function to unwrap the encrypted portion. unencryptedOpenSSHKey can be used
-// as the decrypt function to parse an unencrypted private key. See
-// https://github.com/openssh/openssh-portable/blob/master/PROTOCOL.key.
-func parseOpenSSHPrivateKey(key []byte, decrypt openSSHDecryptFunc) (crypto.PrivateKey, error) {
+// decodeOpenSSHPrivateKey returns the private key section of an OpenSSH
+// private key, using the decrypt function to unwrap it.
+func decodeOpenSSHPrivateKey(key []byte, decrypt openSSHDecryptFunc) (*openSSHPrivateKey, error) {
 	if len(key) < len(privateKeyAuthMagic) || string(key[:len(privateKeyAuthMagic)]) != privateKeyAuthMagic {
 		return nil, errors.New("ssh: invalid openssh private key format")
 	}
@@ -1478,6 +1633,18 @@ func parseOpenSSHPrivateKey(key []byte, decrypt openSSHDecryptFunc) (crypto.Priv
 		}
 		return nil, errors.New("ssh: malformed OpenSSH key")
 	}
+	return &pk1, nil
+}
+
+// parseOpenSSHPrivateKey parses an OpenSSH private key, using the decrypt
+// function to unwrap the encrypted portion. unencryptedOpenSSHKey can be used
+// as the decrypt function to parse an unencrypted private key. See
+// https://github.com/openssh/openssh-portable/blob/master/PROTOCOL.key.
+func parseOpenSSHPrivateKey(key []byte, decrypt openSSHDecryptFunc) (crypto.PrivateKey, error) {
+	pk1, err := decodeOpenSSHPrivateKey(key, decrypt)
+	if err != nil {
+		return nil, err
+	}
 
 	switch pk1.Keytype {
 	case KeyAlgoRSA:
@@ -360,6 +360,42 @@ func TestMarshalPrivateKeyWithPassphrase(t *testing.T) {
 	}
 }
 
+func TestMarshalPrivateKeyWithPassphraseRounds(t *testing.T) {
+	expected := testPrivateKeys["ed25519"]
+	passphrase := []byte("test-passphrase")
+	block, err := MarshalPrivateKeyWithPassphraseRounds(expected, "test@golang.org", passphrase, 4)
+	if err != nil {
+		t.Fatalf("cannot marshal: %v", err)
+	}
+
+	var w openSSHEncryptedPrivateKey
+	if err := Unmarshal(block.Bytes[len(privateKeyAuthMagic):], &w); err != nil {
+		t.Fatalf("cannot unmarshal the key container: %v", err)
+	}
+	var opts struct {
+		Salt   []byte
+		Rounds uint32
+	}
+	if err := Unmarshal([]byte(w.KdfOpts), &opts); err != nil {
+		t.Fatalf("cannot unmarshal the KDF options: %v", err)
+	}
+	if w.CipherName != "aes256-ctr" || w.KdfName != "bcrypt" || opts.Rounds != 4 {
+		t.Errorf("got cipher %q, KDF %q with %d rounds, want aes256-ctr, bcrypt with 4 rounds", w.CipherName, w.KdfName, opts.Rounds)
+	}
+
+	key, err := ParseRawPrivateKeyWithPassphrase(pem.EncodeToMemory(block), passphrase)
+	if err != nil {
+		t.Fatalf("cannot parse: %v", err)
+	}
+	if !reflect.DeepEqual(expected, key) {
+		t.Error("unexpected marshaled key")
+	}
+
+	if _, err := MarshalPrivateKeyWithPassphraseRounds(expected, "", passphrase, 0); err == nil {
+		t.Error("marshaling with 0 rounds succeeded")
+	}
+}
+
 type testAuthResult struct {
 	pubKey   PublicKey
 	options  []string
@@ -772,3 +808,67 @@ func TestCryptoPublicKey(t *testing.T) {
 		}
 	}
 }
+
+func TestEd448Keys(t *testing.T) {
+	// The first test vector of RFC 8032, section 7.4.
+	keyBytes, _ := hex.DecodeString("5fd7449b59b461fd2ce787ec616ad46a1da1342485a70e1f8a0ea75d80e96778edf124769b46c7061bd6783df1e50f6cd1fa1abeafe8256180")
+	blob, _ := hex.DecodeString("533a37f6bbe457251f023c0d88f976ae2dfb504a843e34d2074fd823d41a591f2b233f034f628281f2fd7a22ddd47d7828c59bd0a21bfd3980ff0d2028d4b18a9df63e006c5d1c2d345b925d8dc00b4104852db99ac5c7cdda8530a113a0f4dbb61149f05a7363268c71d95808ff2e652600")
+
+	pub, err := ParsePublicKey(Marshal(struct {
+		Name     string
+		KeyBytes []byte
+	}{KeyAlgoED448, keyBytes}))
+	if err != nil {
+		t.Fatalf("ParsePublicKey: %v", err)
+	}
+	if pub.Type() != KeyAlgoED448 {
+		t.Errorf("got key type %q, want %q", pub.Type(), KeyAlgoED448)
+	}
+	if _, err := ParsePublicKey(Marshal(struct {
+		Name     string
+		KeyBytes []byte
+	}{KeyAlgoED448, keyBytes[:32]})); err == nil {
+		t.Error("ParsePublicKey accepted a short Ed448 key")
+	}
+
+	sig := &Signature{Format: KeyAlgoED448, Blob: blob}
+	if err := pub.Verify(nil, sig); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+	sig.Blob[5]++
+	if err := pub.Verify(nil, sig); err == nil {
+		t.Error("Verify on broken sig did not fail")
+	}
+
+	if contains(DefaultAlgorithmPolicy().HostKeyAlgorithms, KeyAlgoED448) || contains(DefaultAlgorithmPolicy().PublicKeyAuthAlgorithms, KeyAlgoED448) {
+		t.Errorf("%s is enabled by default", KeyAlgoED448)
+	}
+}
+
+func TestParseSKSignature(t *testing.T) {
+	for _, d := range testdata.SKData {
+		sigBuf := make([]byte, hex.DecodedLen(len(d.HexSignature)))
+		if _, err := hex.Decode(sigBuf, d.HexSignature); err != nil {
+			t.Fatalf("hex.Decode() failed: %v", err)
+		}
+		sig, _, ok := parseSignature(sigBuf)
+		if !ok {
+			t.Fatalf("parseSignature(%v) failed", sigBuf)
+		}
+		skf, err := ParseSKSignature(sig)
+		if err != nil {
+			t.Fatalf("%s: ParseSKSignature: %v", d.Name, err)
+		}
+		if !skf.UserPresent() || skf.UserVerified() {
+			t.Errorf("%s: got flags %#x, want user presence only", d.Name, skf.Flags)
+		}
+	}
+
+	sig, err := testSigners["ed25519"].Sign(rand.Reader, []byte("data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseSKSignature(sig); err == nil {
+		t.Error("ParseSKSignature accepted an ssh-ed25519 signature")
+	}
+}
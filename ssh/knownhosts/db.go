@@ -0,0 +1,395 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package knownhosts
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// A DB is a known_hosts database held in memory, which can be changed while
+// it is used to check host keys and written back in the known_hosts
+// format. It is safe for concurrent use. The zero value is an empty
+// database.
+type DB struct {
+	// Hash makes Add and the callback returned by TOFU hash host names,
+	// see WriteKnownHost.
+	Hash bool
+
+	mu    sync.RWMutex
+	db    *hostKeyDB
+	lines []dbLine
+}
+
+// dbLine is a line of a DB, kept as written so that WriteTo preserves
+// markers, patterns, hashes and comments.
+type dbLine struct {
+	text     string
+	filename string
+	lineNum  int
+
+	// comment is set for comment and blank lines, which have no entry.
+	comment bool
+}
+
+// NewDB returns a DB with the contents of the given known_hosts files.
+func NewDB(files ...string) (*DB, error) {
+	d := &DB{}
+	for _, fn := range files {
+		f, err := os.Open(fn)
+		if err != nil {
+			return nil, err
+		}
+		err = d.Read(f, fn)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// Read adds the entries of a known_hosts file read from r. filename is used
+// in KnownKey and error messages. Comments and blank lines are kept for
+// WriteTo.
+func (d *DB) Read(r io.Reader, filename string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		text := scanner.Text()
+		line := strings.TrimSpace(text)
+		if len(line) == 0 || line[0] == '#' {
+			d.lines = append(d.lines, dbLine{text: text, comment: true})
+			continue
+		}
+		if err := d.addLine(dbLine{text: text, filename: filename, lineNum: lineNum}); err != nil {
+			return fmt.Errorf("knownhosts: %s:%d: %v", filename, lineNum, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// entry returns the entry of l, without surrounding white space.
+func (l *dbLine) entry() []byte {
+	return bytes.TrimSpace([]byte(l.text))
+}
+
+func (d *DB) addLine(l dbLine) error {
+	if d.db == nil {
+		d.db = newHostKeyDB()
+	}
+	if err := d.db.parseLine(l.entry(), l.filename, l.lineNum); err != nil {
+		return err
+	}
+	d.lines = append(d.lines, l)
+	return nil
+}
+
+// Add adds the entry of key for addresses, formatted as by
+// WriteKnownHost. Added entries have no file name or line number in
+// KnownKey.
+func (d *DB) Add(addresses []string, key ssh.PublicKey) error {
+	if len(addresses) == 0 {
+		return errors.New("knownhosts: no addresses to add")
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, line := range knownHostLines(addresses, key, d.Hash) {
+		if err := d.addLine(dbLine{text: strings.TrimSpace(line)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Remove removes the entries for address, which is normalized like the
+// addresses passed to a HostKeyCallback, and returns how many were
+// removed. If key is not nil, only entries for key are removed. Like
+// ssh-keygen -R, a line listing several hosts is removed as a whole, and
+// @cert-authority and @revoked lines, like comments, are kept.
+func (d *DB) Remove(address string, key ssh.PublicKey) (int, error) {
+	a, err := parseAddr(address)
+	if err != nil {
+		return 0, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	db := newHostKeyDB()
+	var kept []dbLine
+	removed := 0
+	for _, l := range d.lines {
+		if l.comment {
+			kept = append(kept, l)
+			continue
+		}
+		single := newHostKeyDB()
+		single.parseLine(l.entry(), l.filename, l.lineNum)
+		if len(single.lines) == 1 && !single.lines[0].cert && single.lines[0].match(a) &&
+			(key == nil || keyEq(single.lines[0].knownKey.Key, key)) {
+			removed++
+			continue
+		}
+		db.parseLine(l.entry(), l.filename, l.lineNum)
+		kept = append(kept, l)
+	}
+	d.db, d.lines = db, kept
+	return removed, nil
+}
+
+// hasComplexEntry reports whether a host key entry for address lists
+// several hosts, or uses a wildcard, a negation, a network or a port range.
+func (d *DB) hasComplexEntry(address string) (bool, error) {
+	a, err := parseAddr(address)
+	if err != nil {
+		return false, err
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.db == nil {
+		return false, nil
+	}
+	for _, l := range d.db.lines {
+		if l.cert || !l.match(a) {
+			continue
+		}
+		if hps, ok := l.matcher.(hostPatterns); ok && hps.complex() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Lookup returns the host keys known for address, which is normalized like
+// the addresses passed to a HostKeyCallback. Certificate authorities and
+// revoked keys are not included.
+func (d *DB) Lookup(address string) ([]KnownKey, error) {
+	return d.lookup(address, false)
+}
+
+// Authorities returns the keys of the @cert-authority entries for address,
+// the certificate authorities trusted to sign its host certificates.
+func (d *DB) Authorities(address string) ([]KnownKey, error) {
+	return d.lookup(address, true)
+}
+
+func (d *DB) lookup(address string, cert bool) ([]KnownKey, error) {
+	a, err := parseAddr(address)
+	if err != nil {
+		return nil, err
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.db == nil {
+		return nil, nil
+	}
+	var keys []KnownKey
+	for _, l := range d.db.lines {
+		if l.cert == cert && l.match(a) {
+			keys = append(keys, l.knownKey)
+		}
+	}
+	return keys, nil
+}
+
+// IsHostAuthority reports whether auth is trusted to sign the host
+// certificates of address, in the form expected by
+// ssh.CertChecker.IsHostAuthority.
+func (d *DB) IsHostAuthority(auth ssh.PublicKey, address string) bool {
+	keys, err := d.Authorities(address)
+	if err != nil {
+		return false
+	}
+	for _, k := range keys {
+		if keyEq(k.Key, auth) {
+			return true
+		}
+	}
+	return false
+}
+
+// Revoked returns the @revoked entry for key, or nil if it isn't revoked.
+// A certificate is also revoked if its key or the key of its signer is.
+func (d *DB) Revoked(key ssh.PublicKey) *KnownKey {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.db == nil {
+		return nil
+	}
+	keys := []ssh.PublicKey{key}
+	if cert, ok := key.(*ssh.Certificate); ok {
+		keys = append(keys, cert.Key, cert.SignatureKey)
+	}
+	for _, k := range keys {
+		if revoked := d.db.revoked[string(k.Marshal())]; revoked != nil {
+			r := *revoked
+			return &r
+		}
+	}
+	return nil
+}
+
+// IsRevoked reports whether key is revoked, see Revoked.
+func (d *DB) IsRevoked(key ssh.PublicKey) bool {
+	return d.Revoked(key) != nil
+}
+
+// RevokedKeys returns the @revoked entries, sorted by file name and line.
+func (d *DB) RevokedKeys() []KnownKey {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.db == nil {
+		return nil
+	}
+	var keys []KnownKey
+	for _, k := range d.db.revoked {
+		keys = append(keys, *k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Filename != keys[j].Filename {
+			return keys[i].Filename < keys[j].Filename
+		}
+		return keys[i].Line < keys[j].Line
+	})
+	return keys
+}
+
+func parseAddr(address string) (addr, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		host, port = address, "22"
+	}
+	if host == "" {
+		return addr{}, fmt.Errorf("knownhosts: invalid address %q", address)
+	}
+	return addr{host, port}, nil
+}
+
+// HostKeyCallback returns a host key callback that checks host keys against
+// the current contents of the database, like the callback returned by New.
+func (d *DB) HostKeyCallback() ssh.HostKeyCallback {
+	checker := d.certChecker()
+	return checker.CheckHostKey
+}
+
+func (d *DB) certChecker() *ssh.CertChecker {
+	return &ssh.CertChecker{
+		IsHostAuthority: func(auth ssh.PublicKey, address string) bool {
+			d.mu.RLock()
+			defer d.mu.RUnlock()
+			return d.db != nil && d.db.IsHostAuthority(auth, address)
+		},
+		IsRevoked: func(cert *ssh.Certificate) bool {
+			d.mu.RLock()
+			defer d.mu.RUnlock()
+			return d.db != nil && d.db.IsRevoked(cert)
+		},
+		HostKeyFallback: func(address string, remote net.Addr, key ssh.PublicKey) error {
+			d.mu.RLock()
+			defer d.mu.RUnlock()
+			if d.db == nil {
+				return &KeyError{}
+			}
+			return d.db.check(address, remote, key)
+		},
+	}
+}
+
+// A PromptFunc asks whether to trust key, the unknown host key of the
+// server dialed at hostname.
+type PromptFunc func(hostname string, remote net.Addr, key ssh.PublicKey) (bool, error)
+
+// TOFU returns a host key callback that checks host keys like
+// HostKeyCallback, but calls prompt for hosts the database has no key
+// for. If prompt accepts the key, it is added to the database for
+// hostname. Changed keys, revoked keys and certificates are never
+// prompted for. If prompt is nil, unknown keys are accepted without asking.
+func (d *DB) TOFU(prompt PromptFunc) ssh.HostKeyCallback {
+	checker := d.certChecker()
+	var mu sync.Mutex
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		// Serialize the check and the addition, so that two connections
+		// to a new host don't both prompt.
+		mu.Lock()
+		defer mu.Unlock()
+		err := checker.CheckHostKey(hostname, remote, key)
+		var keyErr *KeyError
+		if !errors.As(err, &keyErr) || len(keyErr.Want) > 0 {
+			return err
+		}
+		if _, isCert := key.(*ssh.Certificate); isCert {
+			return err
+		}
+		if prompt != nil {
+			ok, perr := prompt(hostname, remote, key)
+			if perr != nil {
+				return perr
+			}
+			if !ok {
+				return err
+			}
+		}
+		return d.Add([]string{hostname}, key)
+	}
+}
+
+// WriteTo writes the database in the known_hosts format.
+func (d *DB) WriteTo(w io.Writer) (int64, error) {
+	d.mu.RLock()
+	var b bytes.Buffer
+	for _, l := range d.lines {
+		b.WriteString(l.text)
+		b.WriteByte('\n')
+	}
+	d.mu.RUnlock()
+	return b.WriteTo(w)
+}
+
+// WriteFile replaces the file at path with the database, writing a
+// temporary file and renaming it so that readers never see a partial file.
+// A new file has permissions 0600; the permissions of an existing file are
+// kept.
+func (d *DB) WriteFile(path string) error {
+	perm := os.FileMode(0600)
+	if fi, err := os.Stat(path); err == nil {
+		perm = fi.Mode().Perm()
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	_, err = d.WriteTo(f)
+	if err == nil {
+		err = f.Chmod(perm)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
@@ -0,0 +1,216 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package knownhosts
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestDB(t *testing.T) {
+	const file = "# comment\n@revoked * " + alternateEdKeyStr + "\n\n  server.org,[server.org]:2222 " + edKeyStr + " \n"
+	d := &DB{}
+	if err := d.Read(strings.NewReader(file), "known_hosts"); err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if _, err := d.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != file {
+		t.Errorf("WriteTo wrote %q, want the file read %q", b.String(), file)
+	}
+	cb := d.HostKeyCallback()
+	if err := cb("server.org:22", testAddr, edKey); err != nil {
+		t.Errorf("known key: %v", err)
+	}
+	var revoked *RevokedError
+	if err := cb("server.org:22", testAddr, alternateEdKey); !errors.As(err, &revoked) {
+		t.Errorf("revoked key: got %v, want a RevokedError", err)
+	}
+
+	keys, err := d.Lookup("server.org:2222")
+	if err != nil || len(keys) != 1 || !keyEq(keys[0].Key, edKey) || keys[0].Line != 4 {
+		t.Errorf("Lookup = %v, %v", keys, err)
+	}
+	if keys, _ := d.Lookup("other.org"); len(keys) != 0 {
+		t.Errorf("Lookup(other.org) = %v", keys)
+	}
+
+	// The callback sees changes made after it was created.
+	if err := d.Add([]string{"other.org"}, ecKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := cb("other.org:22", testAddr, ecKey); err != nil {
+		t.Errorf("added key: %v", err)
+	}
+	if n, err := d.Remove("other.org", edKey); err != nil || n != 0 {
+		t.Errorf("Remove with another key = %d, %v", n, err)
+	}
+	if n, err := d.Remove("[server.org]:2222", nil); err != nil || n != 1 {
+		t.Errorf("Remove = %d, %v", n, err)
+	}
+	var keyErr *KeyError
+	if err := cb("server.org:22", testAddr, edKey); !errors.As(err, &keyErr) || len(keyErr.Want) != 0 {
+		t.Errorf("removed key: got %v, want an unknown host", err)
+	}
+
+	b.Reset()
+	if _, err := d.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	if want := "# comment\n@revoked * " + alternateEdKeyStr + "\n\nother.org " + serialize(ecKey) + "\n"; b.String() != want {
+		t.Errorf("WriteTo wrote %q, want %q", b.String(), want)
+	}
+}
+
+func TestDBWriteFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	d := &DB{Hash: true}
+	if err := d.Add([]string{"server.org", "server.org:2222"}, edKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := fi.Mode().Perm(); perm != 0600 {
+		t.Errorf("permissions %v, want 0600", perm)
+	}
+
+	d, err = NewDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cb := d.HostKeyCallback()
+	for _, addr := range []string{"server.org:22", "server.org:2222"} {
+		if err := cb(addr, testAddr, edKey); err != nil {
+			t.Errorf("reloaded %s: %v", addr, err)
+		}
+	}
+	if n, err := d.Remove("server.org", nil); err != nil || n != 1 {
+		t.Errorf("Remove of a hashed host = %d, %v", n, err)
+	}
+}
+
+func TestDBTOFU(t *testing.T) {
+	d := &DB{}
+	var prompts []string
+	accept := true
+	cb := d.TOFU(func(hostname string, remote net.Addr, key ssh.PublicKey) (bool, error) {
+		prompts = append(prompts, hostname)
+		return accept, nil
+	})
+
+	if err := cb("server.org:22", testAddr, edKey); err != nil {
+		t.Fatalf("accepted key: %v", err)
+	}
+	if err := cb("server.org:22", testAddr, edKey); err != nil {
+		t.Fatalf("recorded key: %v", err)
+	}
+	if err := cb("server.org:22", testAddr, alternateEdKey); err == nil {
+		t.Error("changed key accepted")
+	}
+	accept = false
+	if err := cb("other.org:22", testAddr, edKey); err == nil {
+		t.Error("declined key accepted")
+	}
+	if keys, _ := d.Lookup("other.org"); len(keys) != 0 {
+		t.Errorf("declined key recorded: %v", keys)
+	}
+	if want := []string{"server.org:22", "other.org:22"}; strings.Join(prompts, " ") != strings.Join(want, " ") {
+		t.Errorf("prompted for %q, want %q", prompts, want)
+	}
+
+	promptErr := errors.New("no terminal")
+	cb = d.TOFU(func(string, net.Addr, ssh.PublicKey) (bool, error) { return false, promptErr })
+	if err := cb("new.org:22", testAddr, edKey); err != promptErr {
+		t.Errorf("got %v, want the prompt error", err)
+	}
+}
+
+func TestDBConcurrent(t *testing.T) {
+	d := &DB{}
+	cb := d.TOFU(nil)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := cb("server.org:22", testAddr, edKey); err != nil {
+				t.Error(err)
+			}
+			d.Lookup("server.org")
+		}()
+	}
+	wg.Wait()
+	if keys, _ := d.Lookup("server.org"); len(keys) != 1 {
+		t.Errorf("got %d entries, want 1", len(keys))
+	}
+}
+
+func TestDBMarkers(t *testing.T) {
+	const file = "@cert-authority *.example.org " + edKeyStr + "\n" +
+		"@revoked * " + alternateEdKeyStr + "\n" +
+		"host.example.org " + ecKeyStr + "\n"
+	d := &DB{}
+	if err := d.Read(strings.NewReader(file), "known_hosts"); err != nil {
+		t.Fatal(err)
+	}
+
+	cas, err := d.Authorities("host.example.org:22")
+	if err != nil || len(cas) != 1 || !keyEq(cas[0].Key, edKey) || cas[0].Line != 1 {
+		t.Errorf("Authorities = %v, %v", cas, err)
+	}
+	if cas, _ := d.Authorities("host.example.com"); len(cas) != 0 {
+		t.Errorf("Authorities of another domain = %v", cas)
+	}
+	if keys, _ := d.Lookup("host.example.org"); len(keys) != 1 || !keyEq(keys[0].Key, ecKey) {
+		t.Errorf("Lookup included marked entries: %v", keys)
+	}
+	if !d.IsHostAuthority(edKey, "host.example.org:22") || d.IsHostAuthority(ecKey, "host.example.org:22") {
+		t.Error("IsHostAuthority is wrong")
+	}
+
+	if r := d.Revoked(alternateEdKey); r == nil || r.Line != 2 || r.Filename != "known_hosts" {
+		t.Errorf("Revoked = %v", r)
+	}
+	if d.IsRevoked(edKey) {
+		t.Error("unrevoked key reported revoked")
+	}
+	cert := &ssh.Certificate{Key: ecKey, SignatureKey: alternateEdKey}
+	if !d.IsRevoked(cert) {
+		t.Error("certificate signed by a revoked key not reported revoked")
+	}
+	if keys := d.RevokedKeys(); len(keys) != 1 || !keyEq(keys[0].Key, alternateEdKey) {
+		t.Errorf("RevokedKeys = %v", keys)
+	}
+}
+
+func TestDBAcceptNewHostKey(t *testing.T) {
+	d := &DB{}
+	cb := ssh.AcceptNewHostKey(d.HostKeyCallback(), d)
+	if err := cb("server.org:22", testAddr, edKey); err != nil {
+		t.Fatalf("new host: %v", err)
+	}
+	var keyErr *KeyError
+	if err := cb("server.org:22", testAddr, alternateEdKey); !errors.As(err, &keyErr) || errors.Is(err, ssh.ErrUnknownHostKey) {
+		t.Errorf("changed key: got %v, want a mismatch", err)
+	}
+	if keys, _ := d.Lookup("server.org"); len(keys) != 1 {
+		t.Errorf("got %d entries, want 1", len(keys))
+	}
+}
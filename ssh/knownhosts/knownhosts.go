@@ -19,6 +19,7 @@ import (
 	"io"
 	"net"
 	"os"
+	"strconv"
 	"strings"
 
 	"golang.org/x/crypto/ssh"
@@ -27,6 +28,11 @@ import (
 // See the sshd manpage
 // (http://man.openbsd.org/sshd#SSH_KNOWN_HOSTS_FILE_FORMAT) for
 // background.
+//
+// As an extension, host patterns may also be IP networks in CIDR notation,
+// such as 10.0.0.0/8 or [fd00::/8]:2222, and the port of a [host]:port
+// pattern may use wildcards or be a range such as 2200-2299. OpenSSH
+// doesn't understand such patterns and ignores the lines using them.
 
 type addr struct{ host, port string }
 
@@ -45,6 +51,12 @@ type matcher interface {
 type hostPattern struct {
 	negate bool
 	addr   addr
+
+	// network is set for hosts in CIDR notation.
+	network *net.IPNet
+
+	// portMin and portMax are set for port ranges.
+	portMin, portMax int
 }
 
 func (p *hostPattern) String() string {
@@ -72,6 +84,16 @@ func (ps hostPatterns) match(a addr) bool {
 	return matched
 }
 
+// complex reports whether the patterns match anything but a single host.
+func (ps hostPatterns) complex() bool {
+	if len(ps) != 1 {
+		return true
+	}
+	p := ps[0]
+	return p.negate || p.network != nil || p.portMax > 0 ||
+		strings.ContainsAny(p.addr.host+p.addr.port, "*?")
+}
+
 // See
 // https://android.googlesource.com/platform/external/openssh/+/ab28f5495c85297e7a597c1ba62e996416da7c7e/addrmatch.c
 // The matching of * has no regard for separators, unlike filesystem globs
@@ -107,7 +129,34 @@ func wildcardMatch(pat []byte, str []byte) bool {
 }
 
 func (p *hostPattern) match(a addr) bool {
-	return wildcardMatch([]byte(p.addr.host), []byte(a.host)) && p.addr.port == a.port
+	return p.matchHost(a.host) && p.matchPort(a.port)
+}
+
+func (p *hostPattern) matchHost(host string) bool {
+	if p.network != nil {
+		ip := net.ParseIP(host)
+		return ip != nil && p.network.Contains(ip)
+	}
+	return wildcardMatch([]byte(p.addr.host), []byte(host))
+}
+
+func (p *hostPattern) matchPort(port string) bool {
+	if p.portMax > 0 {
+		n, err := strconv.Atoi(port)
+		return err == nil && n >= p.portMin && n <= p.portMax
+	}
+	return wildcardMatch([]byte(p.addr.port), []byte(port))
+}
+
+// parsePortRange parses a port range such as 2200-2299.
+func parsePortRange(port string) (min, max int, err error) {
+	lo, hi, _ := strings.Cut(port, "-")
+	min, err1 := strconv.Atoi(lo)
+	max, err2 := strconv.Atoi(hi)
+	if err1 != nil || err2 != nil || min < 1 || min > max || max > 65535 {
+		return 0, 0, fmt.Errorf("knownhosts: invalid port range %q", port)
+	}
+	return min, max, nil
 }
 
 type keyDBLine struct {
@@ -277,10 +326,21 @@ func newHostnameMatcher(pattern string) (matcher, error) {
 				a.port = "22"
 			}
 		}
-		hps = append(hps, hostPattern{
+		hp := hostPattern{
 			negate: negate,
 			addr:   a,
-		})
+		}
+		if strings.Contains(a.host, "/") {
+			if _, hp.network, err = net.ParseCIDR(a.host); err != nil {
+				return nil, fmt.Errorf("knownhosts: invalid network %q", a.host)
+			}
+		}
+		if strings.Contains(a.port, "-") {
+			if hp.portMin, hp.portMax, err = parsePortRange(a.port); err != nil {
+				return nil, err
+			}
+		}
+		hps = append(hps, hp)
 	}
 	return hps, nil
 }
@@ -315,6 +375,12 @@ func (u *KeyError) Error() string {
 	return "knownhosts: key mismatch"
 }
 
+// Is reports whether target is ssh.ErrUnknownHostKey and the host is
+// unknown.
+func (u *KeyError) Is(target error) bool {
+	return target == ssh.ErrUnknownHostKey && len(u.Want) == 0
+}
+
 // RevokedError is returned if we found a key that was revoked.
 type RevokedError struct {
 	Revoked KnownKey
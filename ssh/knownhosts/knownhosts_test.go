@@ -242,6 +242,40 @@ func TestWildcard(t *testing.T) {
 	}
 }
 
+func TestNetworkAndPortPatterns(t *testing.T) {
+	db := testDB(t, "10.0.0.0/8,!10.1.0.0/16,[fd00::/8]:2222,[192.168.0.0/24]:*,[host.org]:2200-2299 "+edKeyStr)
+	for _, c := range []struct {
+		addr string
+		want bool
+	}{
+		{"10.2.3.4:22", true},
+		{"10.1.3.4:22", false},
+		{"10.2.3.4:2222", false},
+		{"11.2.3.4:22", false},
+		{"[fd00::1]:2222", true},
+		{"[fd00::1]:22", false},
+		{"192.168.0.7:22", true},
+		{"192.168.0.7:8022", true},
+		{"192.168.1.7:22", false},
+		{"host.org:2200", true},
+		{"host.org:2299", true},
+		{"host.org:2300", false},
+		{"host.org:22", false},
+	} {
+		err := db.check(c.addr, testAddr, edKey)
+		if got := err == nil; got != c.want {
+			t.Errorf("check(%s) = %v, want match %v", c.addr, err, c.want)
+		}
+	}
+
+	for _, pattern := range []string{"10.0.0.0/33", "[h]:2299-2200", "[h]:0-10", "[h]:1-65536", "[h]:a-b"} {
+		db := newHostKeyDB()
+		if err := db.parseLine([]byte(pattern+" "+edKeyStr), "testdb", 1); err == nil {
+			t.Errorf("pattern %q accepted", pattern)
+		}
+	}
+}
+
 func TestLine(t *testing.T) {
 	for in, want := range map[string]string{
 		"server.org":                             "server.org " + edKeyStr,
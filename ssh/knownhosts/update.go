@@ -0,0 +1,164 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package knownhosts
+
+import (
+	"errors"
+	"net"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// An UpdatePolicy says whether a HostKeyUpdater changes the database, like
+// the UpdateHostKeys option of ssh.
+type UpdatePolicy int
+
+const (
+	// UpdateNo leaves the database unchanged.
+	UpdateNo UpdatePolicy = iota
+
+	// UpdateAsk changes the database if HostKeyUpdater.Prompt agrees.
+	UpdateAsk
+
+	// UpdateYes changes the database without asking.
+	UpdateYes
+)
+
+// A HostKeyUpdater keeps a DB up to date when the host keys of servers
+// change, using the host keys that OpenSSH servers announce after
+// authentication. Keys are only learned from servers that authenticated
+// with a key the database has for the host, and only once the server has
+// proven it holds them. Its
+// HandleHostKeys method can be used as ssh.ClientConfig.HostKeysHandler.
+type HostKeyUpdater struct {
+	DB *DB
+
+	// Path, if not empty, is the file the database is written to, with
+	// DB.WriteFile, after it is changed.
+	Path string
+
+	Policy UpdatePolicy
+
+	// Prompt is called with the keys to be added and removed if Policy is
+	// UpdateAsk, and reports whether to go ahead. If nil, nothing is
+	// changed.
+	Prompt func(hostname string, added, removed []ssh.PublicKey) bool
+
+	// RemoveDeprecated makes the updater also remove the keys of the host
+	// that the server no longer announces.
+	RemoveDeprecated bool
+
+	// ErrorLog, if not nil, is called with the errors of HandleHostKeys.
+	ErrorLog func(hostname string, err error)
+
+	mu sync.Mutex
+}
+
+// HandleHostKeys updates the database with the keys announced by the server
+// of client, reporting errors to ErrorLog.
+func (u *HostKeyUpdater) HandleHostKeys(client *ssh.Client, hostname string, remote net.Addr, keys []ssh.PublicKey) {
+	if err := u.Update(client, hostname, keys); err != nil && u.ErrorLog != nil {
+		u.ErrorLog(hostname, err)
+	}
+}
+
+// Update adds the keys announced by the server of client at hostname that
+// the database doesn't have yet, after asking the server to prove it holds
+// them, and, with RemoveDeprecated, removes those it no longer announces.
+// Certificates and revoked keys are ignored. It does nothing unless the
+// host key that the server authenticated the session with is one of the
+// keys the database has for hostname. Like ssh, it also does nothing if an
+// entry for hostname lists other hosts as well, or uses a wildcard, a
+// negation, a network or a port range, so that only the entries of the
+// host alone are changed.
+func (u *HostKeyUpdater) Update(client *ssh.Client, hostname string, keys []ssh.PublicKey) error {
+	if u.Policy == UpdateNo {
+		return nil
+	}
+	if u.DB == nil {
+		return errors.New("knownhosts: HostKeyUpdater has no DB")
+	}
+	// Serialize updates, so that concurrent connections to a host don't
+	// add its keys twice.
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	known, err := u.DB.Lookup(hostname)
+	if err != nil {
+		return err
+	}
+	// Announced keys are only proven to belong to the server the
+	// session is with, so that server must be the one the database
+	// knows.
+	if hostKey, _ := client.SessionHostKey(); hostKey == nil || !containsKnownKey(known, hostKey) {
+		return nil
+	}
+	if complex, err := u.DB.hasComplexEntry(hostname); err != nil || complex {
+		return err
+	}
+
+	var announced, added []ssh.PublicKey
+	for _, k := range keys {
+		if _, ok := k.(*ssh.Certificate); ok || u.DB.IsRevoked(k) {
+			continue
+		}
+		announced = append(announced, k)
+		if !containsKnownKey(known, k) {
+			added = append(added, k)
+		}
+	}
+	var removed []ssh.PublicKey
+	for _, k := range known {
+		if u.RemoveDeprecated && !containsKey(announced, k.Key) && !containsKey(removed, k.Key) {
+			removed = append(removed, k.Key)
+		}
+	}
+	if len(added) == 0 && len(removed) == 0 {
+		return nil
+	}
+
+	if len(added) > 0 {
+		if err := client.ProveHostKeys(added); err != nil {
+			return err
+		}
+	}
+	if u.Policy == UpdateAsk && (u.Prompt == nil || !u.Prompt(hostname, added, removed)) {
+		return nil
+	}
+
+	for _, k := range removed {
+		if _, err := u.DB.Remove(hostname, k); err != nil {
+			return err
+		}
+	}
+	for _, k := range added {
+		if err := u.DB.Add([]string{hostname}, k); err != nil {
+			return err
+		}
+	}
+	if u.Path != "" {
+		return u.DB.WriteFile(u.Path)
+	}
+	return nil
+}
+
+func containsKnownKey(known []KnownKey, key ssh.PublicKey) bool {
+	for _, k := range known {
+		if keyEq(k.Key, key) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsKey(keys []ssh.PublicKey, key ssh.PublicKey) bool {
+	for _, k := range keys {
+		if keyEq(k, key) {
+			return true
+		}
+	}
+	return false
+}
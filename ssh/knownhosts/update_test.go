@@ -0,0 +1,181 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package knownhosts
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/testdata"
+)
+
+func testSigner(t *testing.T, name string) ssh.Signer {
+	t.Helper()
+	s, err := ssh.ParsePrivateKey(testdata.PEMBytes[name])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// dialAnnouncing connects to a server that announces its host keys, and
+// returns the client and the keys it announced.
+func dialAnnouncing(t *testing.T, hostKey ssh.Signer, secondary ...ssh.Signer) (*ssh.Client, []ssh.PublicKey) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	serverConf := &ssh.ServerConfig{NoClientAuth: true, AnnounceHostKeys: true}
+	serverConf.AddHostKey(hostKey)
+	for _, s := range secondary {
+		serverConf.AddSecondaryHostKey(s)
+	}
+	go func() {
+		c, err := l.Accept()
+		if err != nil {
+			return
+		}
+		conn, chans, reqs, err := ssh.NewServerConn(c, serverConf)
+		if err != nil {
+			return
+		}
+		go ssh.DiscardRequests(reqs)
+		go func() {
+			for range chans {
+			}
+		}()
+		conn.Wait()
+	}()
+
+	announced := make(chan []ssh.PublicKey, 1)
+	client, err := ssh.Dial("tcp", l.Addr().String(), &ssh.ClientConfig{
+		User:            "user",
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		HostKeysCallback: func(hostname string, remote net.Addr, keys []ssh.PublicKey) {
+			announced <- keys
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client, <-announced
+}
+
+func TestHostKeyUpdater(t *testing.T) {
+	ecdsa, rsa := testSigner(t, "ecdsa"), testSigner(t, "rsa")
+	client, keys := dialAnnouncing(t, ecdsa, rsa)
+
+	d := &DB{}
+	d.Add([]string{"server.org"}, ecdsa.PublicKey())
+	d.Add([]string{"server.org"}, edKey)
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	u := &HostKeyUpdater{DB: d, Path: path, Policy: UpdateAsk, RemoveDeprecated: true}
+
+	var prompted bool
+	u.Prompt = func(hostname string, added, removed []ssh.PublicKey) bool {
+		prompted = true
+		if hostname != "server.org:22" || len(added) != 1 || !keyEq(added[0], rsa.PublicKey()) ||
+			len(removed) != 1 || !keyEq(removed[0], edKey) {
+			t.Errorf("Prompt(%q, %d added, %d removed)", hostname, len(added), len(removed))
+		}
+		return false
+	}
+	if err := u.Update(client, "server.org:22", keys); err != nil {
+		t.Fatal(err)
+	}
+	if !prompted {
+		t.Fatal("Prompt was not called")
+	}
+	if known, _ := d.Lookup("server.org"); len(known) != 2 || !keyEq(known[1].Key, edKey) {
+		t.Errorf("declined update changed the database: %v", known)
+	}
+
+	u.Policy = UpdateYes
+	if err := u.Update(client, "server.org:22", keys); err != nil {
+		t.Fatal(err)
+	}
+	known, _ := d.Lookup("server.org")
+	if len(known) != 2 || !keyEq(known[0].Key, ecdsa.PublicKey()) || !keyEq(known[1].Key, rsa.PublicKey()) {
+		t.Errorf("got keys %v, want the announced ones", known)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "\n"); n != 2 {
+		t.Errorf("wrote %d lines, want 2", n)
+	}
+}
+
+func TestHostKeyUpdaterUnknownHost(t *testing.T) {
+	ecdsa, rsa := testSigner(t, "ecdsa"), testSigner(t, "rsa")
+	client, keys := dialAnnouncing(t, ecdsa, rsa)
+
+	d := &DB{}
+	d.Add([]string{"server.org"}, edKey)
+	u := &HostKeyUpdater{DB: d, Policy: UpdateYes, RemoveDeprecated: true}
+	for _, host := range []string{"server.org:22", "other.org:22"} {
+		if err := u.Update(client, host, keys); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if known, _ := d.Lookup("server.org"); len(known) != 1 || !keyEq(known[0].Key, edKey) {
+		t.Errorf("host with none of the announced keys was updated: %v", known)
+	}
+	if known, _ := d.Lookup("other.org"); len(known) != 0 {
+		t.Errorf("unknown host was added: %v", known)
+	}
+}
+
+func TestHostKeyUpdaterUnknownSessionKey(t *testing.T) {
+	ecdsa, rsa := testSigner(t, "ecdsa"), testSigner(t, "rsa")
+	// The session is authenticated with the rsa key, which the database
+	// doesn't know, though the server also announces the known one.
+	client, keys := dialAnnouncing(t, rsa, ecdsa)
+
+	d := &DB{}
+	d.Add([]string{"server.org"}, ecdsa.PublicKey())
+	d.Add([]string{"server.org"}, edKey)
+	u := &HostKeyUpdater{DB: d, Policy: UpdateYes, RemoveDeprecated: true}
+	if err := u.Update(client, "server.org:22", keys); err != nil {
+		t.Fatal(err)
+	}
+	if known, _ := d.Lookup("server.org"); len(known) != 2 || !keyEq(known[0].Key, ecdsa.PublicKey()) || !keyEq(known[1].Key, edKey) {
+		t.Errorf("host was updated by a session with an unknown host key: %v", known)
+	}
+}
+
+func TestHostKeyUpdaterComplexEntry(t *testing.T) {
+	ecdsa, rsa := testSigner(t, "ecdsa"), testSigner(t, "rsa")
+	client, keys := dialAnnouncing(t, ecdsa, rsa)
+
+	for _, tc := range []struct{ host, pattern string }{
+		{"server.org:22", "*.org"},
+		{"server.org:22", "server.org,other.org"},
+		{"server.org:22", "!bad.org,server.*"},
+		{"10.1.2.3:22", "10.0.0.0/8"},
+		{"server.org:50", "[server.org]:1-100"},
+	} {
+		d := &DB{}
+		d.Add([]string{tc.host}, ecdsa.PublicKey())
+		d.Add([]string{tc.pattern}, edKey)
+		u := &HostKeyUpdater{DB: d, Policy: UpdateYes, RemoveDeprecated: true}
+		if err := u.Update(client, tc.host, keys); err != nil {
+			t.Fatal(err)
+		}
+		known, _ := d.Lookup(tc.host)
+		if len(known) != 2 || !keyEq(known[0].Key, ecdsa.PublicKey()) || !keyEq(known[1].Key, edKey) {
+			t.Errorf("%s: host with a complex entry was updated: %v", tc.pattern, known)
+		}
+	}
+}
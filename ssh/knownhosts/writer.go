@@ -0,0 +1,148 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package knownhosts
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// WriteKnownHost writes the known_hosts entry of key for addresses to w.
+// Addresses are normalized with Normalize, so that hosts on ports other
+// than 22 are written as [host]:port. If hash is true, each address is
+// hashed with HashHostname on a line of its own, like ssh-keygen -H and the
+// HashKnownHosts option of ssh do; otherwise a single line lists all the
+// addresses.
+func WriteKnownHost(w io.Writer, addresses []string, key ssh.PublicKey, hash bool) error {
+	_, err := io.WriteString(w, strings.Join(knownHostLines(addresses, key, hash), ""))
+	return err
+}
+
+// knownHostLines returns the lines of the entry of key for addresses, each
+// ending with a newline.
+func knownHostLines(addresses []string, key ssh.PublicKey, hash bool) []string {
+	if !hash {
+		return []string{Line(addresses, key) + "\n"}
+	}
+	var lines []string
+	for _, a := range addresses {
+		lines = append(lines, HashHostname(Normalize(a))+" "+serialize(key)+"\n")
+	}
+	return lines
+}
+
+// A Writer appends entries to a known_hosts file. It is safe for
+// concurrent use, but does not coordinate with other processes writing
+// the same file.
+type Writer struct {
+	// Hash makes the Writer hash host names, see WriteKnownHost.
+	Hash bool
+
+	path string
+	mu   sync.Mutex
+}
+
+// NewWriter returns a Writer that appends to the known_hosts file at path,
+// which is created with permissions 0600 if needed.
+func NewWriter(path string) *Writer {
+	return &Writer{path: path}
+}
+
+// Add appends the entry of key for addresses.
+func (w *Writer) Add(addresses []string, key ssh.PublicKey) error {
+	_, _, err := w.add(addresses, key)
+	return err
+}
+
+// add appends the entry of key for addresses and returns the lines it
+// wrote and the line number of the first one.
+func (w *Writer) add(addresses []string, key ssh.PublicKey) (lines []string, lineNum int, err error) {
+	if len(addresses) == 0 {
+		return nil, 0, errors.New("knownhosts: no addresses to add")
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	f, err := os.OpenFile(w.path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+	existing, err := io.ReadAll(f)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	lines = knownHostLines(addresses, key, w.Hash)
+	data := strings.Join(lines, "")
+	lineNum = bytes.Count(existing, []byte("\n")) + 1
+	if len(existing) > 0 && existing[len(existing)-1] != '\n' {
+		data = "\n" + data
+		lineNum++
+	}
+	// Write everything at once, so that readers don't see partial lines.
+	if _, err := f.WriteString(data); err != nil {
+		return nil, 0, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, 0, err
+	}
+	return lines, lineNum, nil
+}
+
+// NewTOFU returns a host key callback that checks host keys against the
+// known_hosts file of w like New, but trusts the key of a host the file
+// has no key for, and adds it to the file with the host name passed to
+// the callback. This is the trust on first use policy of ssh's
+// StrictHostKeyChecking=accept-new. Changed keys, revoked keys and
+// certificates of unknown authorities are still rejected. The file need not
+// exist yet.
+func NewTOFU(w *Writer) (ssh.HostKeyCallback, error) {
+	db := newHostKeyDB()
+	f, err := os.Open(w.path)
+	if err == nil {
+		err = db.Read(f, w.path)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	certChecker := ssh.CertChecker{
+		IsHostAuthority: db.IsHostAuthority,
+		IsRevoked:       db.IsRevoked,
+		HostKeyFallback: db.check,
+	}
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		mu.Lock()
+		defer mu.Unlock()
+		err := certChecker.CheckHostKey(hostname, remote, key)
+		var keyErr *KeyError
+		if !errors.As(err, &keyErr) || len(keyErr.Want) > 0 {
+			return err
+		}
+		lines, lineNum, err := w.add([]string{hostname}, key)
+		if err != nil {
+			return err
+		}
+		for i, line := range lines {
+			if err := db.parseLine([]byte(strings.TrimSpace(line)), w.path, lineNum+i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, nil
+}
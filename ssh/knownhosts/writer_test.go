@@ -0,0 +1,120 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package knownhosts
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteKnownHost(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteKnownHost(&b, []string{"server.org:22", "server.org:2222"}, edKey, false); err != nil {
+		t.Fatal(err)
+	}
+	if want := "server.org,[server.org]:2222 " + edKeyStr + "\n"; b.String() != want {
+		t.Errorf("got %q, want %q", b.String(), want)
+	}
+
+	b.Reset()
+	if err := WriteKnownHost(&b, []string{"server.org:22", "server.org:2222"}, edKey, true); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "|1|") || strings.Contains(b.String(), "server.org") {
+		t.Fatalf("got %q, want two hashed lines", b.String())
+	}
+	db := testDB(t, b.String())
+	for _, addr := range []string{"server.org:22", "server.org:2222"} {
+		if err := db.check(addr, testAddr, edKey); err != nil {
+			t.Errorf("check(%s): %v", addr, err)
+		}
+	}
+	if err := db.check("server.org:2200", testAddr, edKey); err == nil {
+		t.Error("hashed entry matched the wrong port")
+	}
+}
+
+func TestWriterAdd(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	if err := os.WriteFile(path, []byte("# no trailing newline"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w := NewWriter(path)
+	if err := w.Add([]string{"a.org:22"}, edKey); err != nil {
+		t.Fatal(err)
+	}
+	w.Hash = true
+	if err := w.Add([]string{"b.org:22"}, ecKey); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(string(data), "\n")
+	if len(lines) != 4 || lines[1] != "a.org "+edKeyStr || !strings.HasPrefix(lines[2], "|1|") || lines[3] != "" {
+		t.Errorf("got file %q", data)
+	}
+	if err := w.Add(nil, edKey); err == nil {
+		t.Error("added an entry without addresses")
+	}
+}
+
+func TestTOFU(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	w := NewWriter(path)
+	cb, err := NewTOFU(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cb("server.org:22", testAddr, edKey); err != nil {
+		t.Fatalf("first use: %v", err)
+	}
+	if err := cb("server.org:22", testAddr, edKey); err != nil {
+		t.Fatalf("second use: %v", err)
+	}
+	var keyErr *KeyError
+	if err := cb("server.org:22", testAddr, alternateEdKey); !errors.As(err, &keyErr) || len(keyErr.Want) != 1 {
+		t.Fatalf("changed key: got %v, want a mismatch", err)
+	} else if keyErr.Want[0].Line != 1 || keyErr.Want[0].Filename != path {
+		t.Errorf("mismatch reported at %s:%d", keyErr.Want[0].Filename, keyErr.Want[0].Line)
+	}
+	if err := cb("other.org:2222", testAddr, alternateEdKey); err != nil {
+		t.Fatalf("other host: %v", err)
+	}
+
+	// A new callback reads what the first one wrote.
+	cb, err = NewTOFU(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cb("server.org:22", testAddr, alternateEdKey); err == nil {
+		t.Error("changed key accepted after reload")
+	}
+	if err := cb("other.org:2222", testAddr, alternateEdKey); err != nil {
+		t.Errorf("recorded key rejected after reload: %v", err)
+	}
+}
+
+func TestTOFURevoked(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	if err := os.WriteFile(path, []byte("@revoked * "+edKeyStr+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cb, err := NewTOFU(NewWriter(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var revoked *RevokedError
+	if err := cb("server.org:22", testAddr, edKey); !errors.As(err, &revoked) {
+		t.Errorf("got %v, want a RevokedError", err)
+	}
+}
@@ -0,0 +1,397 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package krl implements a parser and writer for OpenSSH Key Revocation
+// Lists, the binary format produced by ssh-keygen -k and accepted by the
+// RevokedKeys option of sshd. The format is described in the PROTOCOL.krl
+// file of OpenSSH.
+//
+// A parsed KRL can check plain keys and certificates, and its
+// IsCertRevoked method can be used as the IsRevoked callback of an
+// ssh.CertChecker:
+//
+//	revoked, err := krl.Parse(data)
+//	if err != nil {
+//		return err
+//	}
+//	checker := &ssh.CertChecker{
+//		IsUserAuthority: isCA,
+//		IsRevoked:       revoked.IsCertRevoked,
+//	}
+package krl
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	magic         = "SSHKRL\n\x00"
+	formatVersion = 1
+)
+
+// Section types, see PROTOCOL.krl.
+const (
+	sectionCertificates      = 1
+	sectionExplicitKey       = 2
+	sectionFingerprintSHA1   = 3
+	sectionSignature         = 4
+	sectionFingerprintSHA256 = 5
+
+	certSectionSerialList   = 0x20
+	certSectionSerialRange  = 0x21
+	certSectionSerialBitmap = 0x22
+	certSectionKeyID        = 0x23
+)
+
+// A KRL is a Key Revocation List, parsed with Parse or built up from the
+// zero value with the Revoke methods. Its lists should only be changed with
+// those methods, which keep the lookups of IsRevoked in sync.
+type KRL struct {
+	// Version is the version number of the KRL, which ssh-keygen -z sets
+	// and which increases when the KRL is updated.
+	Version uint64
+
+	// GeneratedDate is when the KRL was generated.
+	GeneratedDate time.Time
+
+	// Comment is the comment of the KRL.
+	Comment string
+
+	// Certificates lists the revoked certificates, grouped by authority.
+	Certificates []*CertificateSection
+
+	// RevokedKeys are revoked plain keys.
+	RevokedKeys []ssh.PublicKey
+
+	// RevokedSHA1 and RevokedSHA256 are hashes of the wire encoding of
+	// revoked plain keys.
+	RevokedSHA1   [][]byte
+	RevokedSHA256 [][]byte
+
+	// SigningKeys are the keys that signed the KRL. Their signatures have
+	// been verified by Parse, but whether to trust them is up to the
+	// caller.
+	SigningKeys []ssh.PublicKey
+
+	keys   map[string]bool
+	sha1   map[string]bool
+	sha256 map[string]bool
+}
+
+// A CertificateSection lists certificates revoked for one authority.
+type CertificateSection struct {
+	// CA is the key of the authority that signed the certificates, or nil
+	// if the section applies to certificates of any authority.
+	CA ssh.PublicKey
+
+	// Serials are the ranges of revoked serial numbers.
+	Serials []SerialRange
+
+	// KeyIDs are the revoked key IDs.
+	KeyIDs []string
+
+	caBytes []byte
+}
+
+// A SerialRange is an inclusive range of certificate serial numbers.
+type SerialRange struct {
+	Min, Max uint64
+}
+
+// Parse parses a binary KRL. If the KRL is signed, the signatures are
+// verified.
+func Parse(data []byte) (*KRL, error) {
+	s := cryptobyte.String(data)
+	var (
+		m                 []byte
+		version           uint32
+		date, flags       uint64
+		reserved, comment cryptobyte.String
+	)
+	if !s.ReadBytes(&m, len(magic)) || string(m) != magic {
+		return nil, errors.New("krl: not a KRL")
+	}
+	k := &KRL{
+		keys:   make(map[string]bool),
+		sha1:   make(map[string]bool),
+		sha256: make(map[string]bool),
+	}
+	if !s.ReadUint32(&version) || !s.ReadUint64(&k.Version) || !s.ReadUint64(&date) ||
+		!s.ReadUint64(&flags) || !readString(&s, &reserved) ||
+		!readString(&s, &comment) {
+		return nil, errors.New("krl: truncated header")
+	}
+	if version != formatVersion {
+		return nil, fmt.Errorf("krl: unsupported format version %d", version)
+	}
+	k.GeneratedDate = time.Unix(int64(date), 0)
+	k.Comment = string(comment)
+
+	sigOff := -1
+	for !s.Empty() {
+		off := len(data) - len(s)
+		var typ uint8
+		var sec cryptobyte.String
+		if !s.ReadUint8(&typ) || !readString(&s, &sec) {
+			return nil, errors.New("krl: truncated section")
+		}
+		if typ == sectionSignature {
+			if sigOff < 0 {
+				sigOff = off
+			}
+			if err := k.verify(sec, data[:sigOff]); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if sigOff >= 0 {
+			return nil, errors.New("krl: section after signature")
+		}
+		if err := k.parseSection(typ, sec); err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+func (k *KRL) parseSection(typ uint8, s cryptobyte.String) error {
+	switch typ {
+	case sectionCertificates:
+		cs, err := parseCertificateSection(s)
+		if err != nil {
+			return err
+		}
+		k.Certificates = append(k.Certificates, cs)
+	case sectionExplicitKey:
+		for !s.Empty() {
+			var blob []byte
+			if !readString(&s, (*cryptobyte.String)(&blob)) {
+				return errors.New("krl: malformed key section")
+			}
+			pub, err := ssh.ParsePublicKey(blob)
+			if err != nil {
+				return fmt.Errorf("krl: invalid revoked key: %w", err)
+			}
+			k.RevokedKeys = append(k.RevokedKeys, pub)
+			k.keys[string(blob)] = true
+		}
+	case sectionFingerprintSHA1, sectionFingerprintSHA256:
+		size, list, set := sha1.Size, &k.RevokedSHA1, k.sha1
+		if typ == sectionFingerprintSHA256 {
+			size, list, set = sha256.Size, &k.RevokedSHA256, k.sha256
+		}
+		for !s.Empty() {
+			var hash []byte
+			if !readString(&s, (*cryptobyte.String)(&hash)) || len(hash) != size {
+				return errors.New("krl: malformed fingerprint section")
+			}
+			*list = append(*list, hash)
+			set[string(hash)] = true
+		}
+	default:
+		return fmt.Errorf("krl: unsupported section type %d", typ)
+	}
+	return nil
+}
+
+func parseCertificateSection(s cryptobyte.String) (*CertificateSection, error) {
+	var ca, reserved cryptobyte.String
+	if !readString(&s, &ca) || !readString(&s, &reserved) {
+		return nil, errors.New("krl: malformed certificate section")
+	}
+	cs := &CertificateSection{caBytes: ca}
+	if len(ca) > 0 {
+		pub, err := ssh.ParsePublicKey(ca)
+		if err != nil {
+			return nil, fmt.Errorf("krl: invalid CA key: %w", err)
+		}
+		cs.CA = pub
+	}
+
+	for !s.Empty() {
+		var typ uint8
+		var sub cryptobyte.String
+		if !s.ReadUint8(&typ) || !readString(&s, &sub) {
+			return nil, errors.New("krl: truncated certificate section")
+		}
+		if cs.CA == nil && typ != certSectionKeyID {
+			return nil, errors.New("krl: serials revoked without a CA key")
+		}
+		switch typ {
+		case certSectionSerialList:
+			for !sub.Empty() {
+				var serial uint64
+				if !sub.ReadUint64(&serial) {
+					return nil, errors.New("krl: malformed serial list")
+				}
+				cs.Serials = append(cs.Serials, SerialRange{serial, serial})
+			}
+		case certSectionSerialRange:
+			var r SerialRange
+			if !sub.ReadUint64(&r.Min) || !sub.ReadUint64(&r.Max) || !sub.Empty() || r.Min > r.Max {
+				return nil, errors.New("krl: malformed serial range")
+			}
+			cs.Serials = append(cs.Serials, r)
+		case certSectionSerialBitmap:
+			var offset uint64
+			var bitmap cryptobyte.String
+			if !sub.ReadUint64(&offset) || !readString(&sub, &bitmap) || !sub.Empty() {
+				return nil, errors.New("krl: malformed serial bitmap")
+			}
+			ranges, err := bitmapRanges(offset, bitmap)
+			if err != nil {
+				return nil, err
+			}
+			cs.Serials = append(cs.Serials, ranges...)
+		case certSectionKeyID:
+			for !sub.Empty() {
+				var id cryptobyte.String
+				if !readString(&sub, &id) {
+					return nil, errors.New("krl: malformed key ID list")
+				}
+				cs.KeyIDs = append(cs.KeyIDs, string(id))
+			}
+		default:
+			return nil, fmt.Errorf("krl: unsupported certificate section type %d", typ)
+		}
+	}
+	cs.Serials = normalizeSerials(cs.Serials)
+	return cs, nil
+}
+
+// bitmapRanges returns the serials revoked by a bitmap, an mpint whose bit
+// i is set if serial offset+i is revoked.
+func bitmapRanges(offset uint64, mpint []byte) ([]SerialRange, error) {
+	if len(mpint) > 0 && mpint[0]&0x80 != 0 {
+		return nil, errors.New("krl: negative serial bitmap")
+	}
+	bitmap := new(big.Int).SetBytes(mpint)
+	if bitmap.BitLen() > 0 && offset+uint64(bitmap.BitLen()-1) < offset {
+		return nil, errors.New("krl: serial bitmap overflows")
+	}
+	var ranges []SerialRange
+	for i := 0; i < bitmap.BitLen(); i++ {
+		if bitmap.Bit(i) == 0 {
+			continue
+		}
+		serial := offset + uint64(i)
+		if n := len(ranges); n > 0 && ranges[n-1].Max+1 == serial {
+			ranges[n-1].Max = serial
+		} else {
+			ranges = append(ranges, SerialRange{serial, serial})
+		}
+	}
+	return ranges, nil
+}
+
+// verify checks a signature section over signed, the KRL up to the first
+// signature.
+func (k *KRL) verify(s cryptobyte.String, signed []byte) error {
+	var keyBytes, sigBytes []byte
+	if !readString(&s, (*cryptobyte.String)(&keyBytes)) ||
+		!readString(&s, (*cryptobyte.String)(&sigBytes)) || !s.Empty() {
+		return errors.New("krl: malformed signature section")
+	}
+	pub, err := ssh.ParsePublicKey(keyBytes)
+	if err != nil {
+		return fmt.Errorf("krl: invalid signing key: %w", err)
+	}
+	for _, other := range k.SigningKeys {
+		if bytes.Equal(other.Marshal(), keyBytes) {
+			return errors.New("krl: duplicate signing key")
+		}
+	}
+	sig := new(ssh.Signature)
+	if err := ssh.Unmarshal(sigBytes, sig); err != nil {
+		return errors.New("krl: malformed signature")
+	}
+	if err := pub.Verify(signed, sig); err != nil {
+		return errors.New("krl: signature does not verify")
+	}
+	k.SigningKeys = append(k.SigningKeys, pub)
+	return nil
+}
+
+// IsRevoked reports whether pub is revoked. If pub is a certificate, it is
+// revoked if it is listed in the KRL, or if its key or the key of its
+// authority is revoked.
+func (k *KRL) IsRevoked(pub ssh.PublicKey) bool {
+	cert, ok := pub.(*ssh.Certificate)
+	if !ok {
+		return k.isKeyRevoked(pub)
+	}
+	if k.isKeyRevoked(cert.Key) || k.isKeyRevoked(cert.SignatureKey) {
+		return true
+	}
+	for _, cs := range k.Certificates {
+		if cs.revokes(cert) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsCertRevoked is like IsRevoked, with the signature of the IsRevoked
+// callback of ssh.CertChecker.
+func (k *KRL) IsCertRevoked(cert *ssh.Certificate) bool {
+	return k.IsRevoked(cert)
+}
+
+func (k *KRL) isKeyRevoked(pub ssh.PublicKey) bool {
+	if pub == nil {
+		return false
+	}
+	blob := pub.Marshal()
+	if k.keys[string(blob)] {
+		return true
+	}
+	if h := sha1.Sum(blob); k.sha1[string(h[:])] {
+		return true
+	}
+	h := sha256.Sum256(blob)
+	return k.sha256[string(h[:])]
+}
+
+// revokes reports whether cert is revoked by the section.
+func (cs *CertificateSection) revokes(cert *ssh.Certificate) bool {
+	if cs.CA != nil && (cert.SignatureKey == nil || !bytes.Equal(cert.SignatureKey.Marshal(), cs.caBytes)) {
+		return false
+	}
+	for _, id := range cs.KeyIDs {
+		if id == cert.KeyId {
+			return true
+		}
+	}
+	// Like OpenSSH, ignore serial zero, which CAs use when they don't
+	// number their certificates.
+	if cert.Serial == 0 {
+		return false
+	}
+	for _, r := range cs.Serials {
+		if r.Min <= cert.Serial && cert.Serial <= r.Max {
+			return true
+		}
+	}
+	return false
+}
+
+// readString reads an SSH string, a uint32 length followed by the data.
+func readString(s, out *cryptobyte.String) bool {
+	var n uint32
+	var b []byte
+	if !s.ReadUint32(&n) || !s.ReadBytes(&b, int(n)) {
+		return false
+	}
+	*out = b
+	return true
+}
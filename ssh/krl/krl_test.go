@@ -0,0 +1,278 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package krl
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/testdata"
+)
+
+// keygenKRL was made by ssh-keygen -k -z 7 -s with the CA testdata key
+// "ed25519", revoking serials 1, 10-20 and 100, 102, ..., 110 and the key ID
+// "revoked-id", then updated with -u to revoke the "p256-openssh-format" key
+// and the SHA-256 fingerprint of the "rsa" key.
+const keygenKRL = `U1NIS1JMCgAAAAABAAAAAAAAAAcAAAAAas8oMAAAAAAAAAAAAAAAAAAAAAABAAAAdQAAADMAAAALc3NoLWVkMjU1MTkAAAAgPt3+4Uu4OVFsFzhlU6zH4Zscq46s+0scW8eyNY/A778AAAAAIgAAAA8AAAAAAAAAAQAAAAMP/gEiAAAADgAAAAAAAABkAAAAAgVVIwAAAA4AAAAKcmV2b2tlZC1pZAIAAABsAAAAaAAAABNlY2RzYS1zaGEyLW5pc3RwMjU2AAAACG5pc3RwMjU2AAAAQQSN5Ld/DFy8LJK0yrWg+Ryhq4/ifHryQyCQeT4UXSB+UGdRct7kWA0hARbTaSCh+8U/Gs5O+IkDNoTKVsgxKUMQBQAAACQAAAAgfi5+D7UmDZDE9Q2sAVvvlpcQSIakN4DERdINgXd2AnE=`
+
+func signer(t *testing.T, name string) ssh.Signer {
+	t.Helper()
+	s, err := ssh.ParsePrivateKey(testdata.PEMBytes[name])
+	if err != nil {
+		t.Fatalf("ParsePrivateKey(%q): %v", name, err)
+	}
+	return s
+}
+
+func cert(t *testing.T, ca ssh.Signer, serial uint64, keyID string) *ssh.Certificate {
+	t.Helper()
+	c := &ssh.Certificate{
+		Key:         signer(t, "ecdsa").PublicKey(),
+		Serial:      serial,
+		KeyId:       keyID,
+		CertType:    ssh.UserCert,
+		ValidBefore: ssh.CertTimeInfinity,
+	}
+	if err := c.SignCert(rand.Reader, ca); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestParseKeygenKRL(t *testing.T) {
+	data, err := base64.StdEncoding.DecodeString(keygenKRL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if k.Version != 7 {
+		t.Errorf("got version %d, want 7", k.Version)
+	}
+	if len(k.Certificates) != 1 {
+		t.Fatalf("got %d certificate sections, want 1", len(k.Certificates))
+	}
+	cs := k.Certificates[0]
+	wantSerials := []SerialRange{{1, 1}, {10, 20}, {100, 100}, {102, 102}, {104, 104}, {106, 106}, {108, 108}, {110, 110}}
+	if !reflect.DeepEqual(cs.Serials, wantSerials) {
+		t.Errorf("got serials %v, want %v", cs.Serials, wantSerials)
+	}
+	if !reflect.DeepEqual(cs.KeyIDs, []string{"revoked-id"}) {
+		t.Errorf("got key IDs %q", cs.KeyIDs)
+	}
+
+	ca := signer(t, "ed25519")
+	if !reflect.DeepEqual(cs.CA.Marshal(), ca.PublicKey().Marshal()) {
+		t.Error("wrong CA key")
+	}
+	if !k.IsRevoked(signer(t, "p256-openssh-format").PublicKey()) {
+		t.Error("explicitly revoked key is not revoked")
+	}
+	if !k.IsRevoked(signer(t, "rsa").PublicKey()) {
+		t.Error("key revoked by fingerprint is not revoked")
+	}
+	if k.IsRevoked(ca.PublicKey()) {
+		t.Error("CA key is revoked")
+	}
+
+	for _, tt := range []struct {
+		serial  uint64
+		keyID   string
+		revoked bool
+	}{
+		{0, "", false},
+		{1, "", true},
+		{2, "", false},
+		{15, "", true},
+		{20, "", true},
+		{21, "", false},
+		{104, "", true},
+		{105, "", false},
+		{0, "revoked-id", true},
+		{3, "other-id", false},
+	} {
+		c := cert(t, ca, tt.serial, tt.keyID)
+		if got := k.IsCertRevoked(c); got != tt.revoked {
+			t.Errorf("serial %d, key ID %q: got revoked %v, want %v", tt.serial, tt.keyID, got, tt.revoked)
+		}
+	}
+
+	// The same serial from another CA is not revoked, unless the CA is.
+	other := signer(t, "p256-openssh-format")
+	if k.IsCertRevoked(cert(t, signer(t, "dsa"), 1, "")) {
+		t.Error("certificate of another CA is revoked")
+	}
+	if !k.IsCertRevoked(cert(t, other, 3, "")) {
+		t.Error("certificate of a revoked CA is not revoked")
+	}
+}
+
+func TestCertCheckerIntegration(t *testing.T) {
+	data, _ := base64.StdEncoding.DecodeString(keygenKRL)
+	k, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca := signer(t, "ed25519")
+	checker := &ssh.CertChecker{
+		IsUserAuthority: func(auth ssh.PublicKey) bool {
+			return reflect.DeepEqual(auth.Marshal(), ca.PublicKey().Marshal())
+		},
+		IsRevoked: k.IsCertRevoked,
+	}
+	if _, err := checker.Authenticate(connMeta("user"), cert(t, ca, 15, "")); err == nil {
+		t.Error("revoked certificate was accepted")
+	}
+	good := cert(t, ca, 16000, "")
+	good.ValidPrincipals = []string{"user"}
+	if err := good.SignCert(rand.Reader, ca); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := checker.Authenticate(connMeta("user"), good); err != nil {
+		t.Errorf("certificate was rejected: %v", err)
+	}
+}
+
+type connMeta string
+
+func (c connMeta) User() string        { return string(c) }
+func (connMeta) SessionID() []byte     { return nil }
+func (connMeta) ClientVersion() []byte { return nil }
+func (connMeta) ServerVersion() []byte { return nil }
+func (connMeta) RemoteAddr() net.Addr  { return nil }
+func (connMeta) LocalAddr() net.Addr   { return nil }
+
+// buildKRL returns a KRL with the given sections, each a type byte followed
+// by its contents.
+func buildKRL(sections ...func(*cryptobyte.Builder)) []byte {
+	var b cryptobyte.Builder
+	b.AddBytes([]byte(magic))
+	b.AddUint32(formatVersion)
+	b.AddUint64(1)
+	b.AddUint64(uint64(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC).Unix()))
+	b.AddUint64(0)
+	addString(&b, nil)
+	addString(&b, []byte("test"))
+	for _, s := range sections {
+		s(&b)
+	}
+	return b.BytesOrPanic()
+}
+
+func section(typ uint8, contents func(*cryptobyte.Builder)) func(*cryptobyte.Builder) {
+	return func(b *cryptobyte.Builder) {
+		b.AddUint8(typ)
+		b.AddUint32LengthPrefixed(contents)
+	}
+}
+
+func signature(t *testing.T, s ssh.Signer, signed *[]byte) func(*cryptobyte.Builder) {
+	return section(sectionSignature, func(b *cryptobyte.Builder) {
+		sig, err := s.Sign(rand.Reader, *signed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		addString(b, s.PublicKey().Marshal())
+		addString(b, ssh.Marshal(sig))
+	})
+}
+
+func TestParseSigned(t *testing.T) {
+	ca := signer(t, "ecdsa")
+	certs := section(sectionCertificates, func(b *cryptobyte.Builder) {
+		addString(b, ca.PublicKey().Marshal())
+		addString(b, nil)
+		b.AddUint8(certSectionSerialRange)
+		b.AddUint32LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint64(5)
+			b.AddUint64(9)
+		})
+		b.AddUint8(certSectionSerialList)
+		b.AddUint32LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint64(42)
+		})
+	})
+	unsigned := buildKRL(certs)
+
+	k1, k2 := signer(t, "ed25519"), signer(t, "rsa")
+	data := buildKRL(certs, signature(t, k1, &unsigned), signature(t, k2, &unsigned))
+	k, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(k.SigningKeys) != 2 {
+		t.Errorf("got %d signing keys, want 2", len(k.SigningKeys))
+	}
+	if k.Comment != "test" || !k.GeneratedDate.Equal(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Errorf("got comment %q, date %v", k.Comment, k.GeneratedDate)
+	}
+	if !reflect.DeepEqual(k.Certificates[0].Serials, []SerialRange{{5, 9}, {42, 42}}) {
+		t.Errorf("got serials %v", k.Certificates[0].Serials)
+	}
+
+	bad := append([]byte(nil), unsigned...)
+	bad[len(bad)-1] ^= 1
+	if _, err := Parse(buildKRL(certs, signature(t, k1, &bad))); err == nil {
+		t.Error("KRL with a bad signature was parsed")
+	}
+	if _, err := Parse(buildKRL(certs, signature(t, k1, &unsigned), signature(t, k1, &unsigned))); err == nil {
+		t.Error("KRL signed twice by the same key was parsed")
+	}
+	if _, err := Parse(buildKRL(signature(t, k1, &unsigned), certs)); err == nil {
+		t.Error("KRL with a section after the signature was parsed")
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	hash := sha256.Sum256([]byte("key"))
+	valid := buildKRL(section(sectionFingerprintSHA256, func(b *cryptobyte.Builder) {
+		addString(b, hash[:])
+	}))
+	if _, err := Parse(valid); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	// A KRL without sections is valid, so don't cut at the end of the header.
+	header := len(buildKRL())
+	for i := 0; i < len(valid); i++ {
+		if _, err := Parse(valid[:i]); err == nil && i != header {
+			t.Errorf("KRL truncated to %d bytes was parsed", i)
+		}
+	}
+
+	for name, data := range map[string][]byte{
+		"unknown section": buildKRL(section(9, func(*cryptobyte.Builder) {})),
+		"short hash": buildKRL(section(sectionFingerprintSHA256, func(b *cryptobyte.Builder) {
+			addString(b, hash[:8])
+		})),
+		"serials without CA": buildKRL(section(sectionCertificates, func(b *cryptobyte.Builder) {
+			addString(b, nil)
+			addString(b, nil)
+			b.AddUint8(certSectionSerialList)
+			b.AddUint32LengthPrefixed(func(b *cryptobyte.Builder) { b.AddUint64(1) })
+		})),
+		"backwards range": buildKRL(section(sectionCertificates, func(b *cryptobyte.Builder) {
+			addString(b, signer(t, "ecdsa").PublicKey().Marshal())
+			addString(b, nil)
+			b.AddUint8(certSectionSerialRange)
+			b.AddUint32LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddUint64(9)
+				b.AddUint64(5)
+			})
+		})),
+	} {
+		if _, err := Parse(data); err == nil {
+			t.Errorf("%s: KRL was parsed", name)
+		}
+	}
+}
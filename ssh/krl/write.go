@@ -0,0 +1,275 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package krl
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"math"
+	"sort"
+
+	"golang.org/x/crypto/cryptobyte"
+	"golang.org/x/crypto/ssh"
+)
+
+// RevokeSerials revokes the certificates signed by ca with serial numbers
+// from min to max, inclusive. Serial zero can't be revoked, since CAs use it
+// for certificates they don't number.
+func (k *KRL) RevokeSerials(ca ssh.PublicKey, min, max uint64) error {
+	if ca == nil {
+		return errors.New("krl: revoking serials requires a CA key")
+	}
+	if min == 0 || min > max {
+		return errors.New("krl: invalid serial range")
+	}
+	cs := k.section(ca)
+	cs.Serials = normalizeSerials(append(cs.Serials, SerialRange{min, max}))
+	return nil
+}
+
+// RevokeKeyID revokes the certificates signed by ca with key ID id. If ca is
+// nil, certificates with the key ID are revoked whatever their authority.
+func (k *KRL) RevokeKeyID(ca ssh.PublicKey, id string) {
+	cs := k.section(ca)
+	for _, other := range cs.KeyIDs {
+		if other == id {
+			return
+		}
+	}
+	cs.KeyIDs = append(cs.KeyIDs, id)
+}
+
+// RevokeKey revokes pub. A plain key is revoked along with all certificates
+// for it. A certificate is revoked by its serial number, or by its key ID if
+// it has no serial number, like ssh-keygen -k does.
+func (k *KRL) RevokeKey(pub ssh.PublicKey) error {
+	cert, ok := pub.(*ssh.Certificate)
+	if !ok {
+		k.init()
+		blob := pub.Marshal()
+		if !k.keys[string(blob)] {
+			k.keys[string(blob)] = true
+			k.RevokedKeys = append(k.RevokedKeys, pub)
+		}
+		return nil
+	}
+	if cert.SignatureKey == nil {
+		return errors.New("krl: certificate is not signed")
+	}
+	if cert.Serial == 0 {
+		k.RevokeKeyID(cert.SignatureKey, cert.KeyId)
+		return nil
+	}
+	return k.RevokeSerials(cert.SignatureKey, cert.Serial, cert.Serial)
+}
+
+// RevokeSHA256 revokes the plain key whose wire encoding has the SHA-256
+// hash sum, as in the Sum of an ssh.Fingerprint.
+func (k *KRL) RevokeSHA256(sum []byte) error {
+	if len(sum) != sha256.Size {
+		return errors.New("krl: invalid SHA-256 hash")
+	}
+	k.init()
+	k.addHash(&k.RevokedSHA256, k.sha256, sum)
+	return nil
+}
+
+// RevokeSHA1 revokes the plain key whose wire encoding has the SHA-1 hash
+// sum.
+func (k *KRL) RevokeSHA1(sum []byte) error {
+	if len(sum) != sha1.Size {
+		return errors.New("krl: invalid SHA-1 hash")
+	}
+	k.init()
+	k.addHash(&k.RevokedSHA1, k.sha1, sum)
+	return nil
+}
+
+func (k *KRL) addHash(list *[][]byte, set map[string]bool, sum []byte) {
+	if !set[string(sum)] {
+		set[string(sum)] = true
+		*list = append(*list, append([]byte(nil), sum...))
+	}
+}
+
+// Merge adds the revocations of other to k, like ssh-keygen -u does when
+// updating a KRL. The header and signing keys of k are kept.
+func (k *KRL) Merge(other *KRL) {
+	for _, ocs := range other.Certificates {
+		cs := k.section(ocs.CA)
+		cs.Serials = normalizeSerials(append(cs.Serials, ocs.Serials...))
+		for _, id := range ocs.KeyIDs {
+			k.RevokeKeyID(ocs.CA, id)
+		}
+	}
+	for _, pub := range other.RevokedKeys {
+		k.RevokeKey(pub)
+	}
+	k.init()
+	for _, sum := range other.RevokedSHA1 {
+		k.addHash(&k.RevokedSHA1, k.sha1, sum)
+	}
+	for _, sum := range other.RevokedSHA256 {
+		k.addHash(&k.RevokedSHA256, k.sha256, sum)
+	}
+}
+
+func (k *KRL) init() {
+	if k.keys == nil {
+		k.keys = make(map[string]bool)
+		k.sha1 = make(map[string]bool)
+		k.sha256 = make(map[string]bool)
+	}
+}
+
+// section returns the certificate section for ca, adding it if needed.
+func (k *KRL) section(ca ssh.PublicKey) *CertificateSection {
+	var caBytes []byte
+	if ca != nil {
+		caBytes = ca.Marshal()
+	}
+	for _, cs := range k.Certificates {
+		if bytes.Equal(cs.caBytes, caBytes) {
+			return cs
+		}
+	}
+	cs := &CertificateSection{CA: ca, caBytes: caBytes}
+	k.Certificates = append(k.Certificates, cs)
+	return cs
+}
+
+// normalizeSerials sorts ranges and merges those that overlap or touch.
+func normalizeSerials(ranges []SerialRange) []SerialRange {
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Min < ranges[j].Min })
+	var out []SerialRange
+	for _, r := range ranges {
+		if n := len(out); n > 0 && (out[n-1].Max == math.MaxUint64 || r.Min <= out[n-1].Max+1) {
+			if r.Max > out[n-1].Max {
+				out[n-1].Max = r.Max
+			}
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// Marshal returns the KRL in the binary format of OpenSSH, unsigned.
+func (k *KRL) Marshal() []byte {
+	return k.marshal()
+}
+
+// MarshalSigned returns the KRL in the binary format of OpenSSH, signed by
+// each of signers.
+func (k *KRL) MarshalSigned(rand io.Reader, signers ...ssh.Signer) ([]byte, error) {
+	data := k.marshal()
+	var b cryptobyte.Builder
+	b.AddBytes(data)
+	for _, s := range signers {
+		sig, err := s.Sign(rand, data)
+		if err != nil {
+			return nil, err
+		}
+		addSection(&b, sectionSignature, func(b *cryptobyte.Builder) {
+			addString(b, s.PublicKey().Marshal())
+			addString(b, ssh.Marshal(sig))
+		})
+	}
+	return b.Bytes()
+}
+
+func (k *KRL) marshal() []byte {
+	var b cryptobyte.Builder
+	b.AddBytes([]byte(magic))
+	b.AddUint32(formatVersion)
+	b.AddUint64(k.Version)
+	var date uint64
+	if !k.GeneratedDate.IsZero() && k.GeneratedDate.Unix() > 0 {
+		date = uint64(k.GeneratedDate.Unix())
+	}
+	b.AddUint64(date)
+	b.AddUint64(0) // flags
+	addString(&b, nil)
+	addString(&b, []byte(k.Comment))
+
+	for _, cs := range k.Certificates {
+		if len(cs.Serials) == 0 && len(cs.KeyIDs) == 0 {
+			continue
+		}
+		addSection(&b, sectionCertificates, cs.marshal)
+	}
+	if len(k.RevokedKeys) > 0 {
+		addSection(&b, sectionExplicitKey, func(b *cryptobyte.Builder) {
+			for _, pub := range k.RevokedKeys {
+				addString(b, pub.Marshal())
+			}
+		})
+	}
+	for _, hashes := range []struct {
+		typ  uint8
+		sums [][]byte
+	}{
+		{sectionFingerprintSHA1, k.RevokedSHA1},
+		{sectionFingerprintSHA256, k.RevokedSHA256},
+	} {
+		if len(hashes.sums) == 0 {
+			continue
+		}
+		addSection(&b, hashes.typ, func(b *cryptobyte.Builder) {
+			for _, sum := range hashes.sums {
+				addString(b, sum)
+			}
+		})
+	}
+	return b.BytesOrPanic()
+}
+
+func (cs *CertificateSection) marshal(b *cryptobyte.Builder) {
+	addString(b, cs.caBytes)
+	addString(b, nil) // reserved
+
+	// Single serials go in a list, longer runs in ranges.
+	var singles []uint64
+	for _, r := range cs.Serials {
+		if r.Min == r.Max {
+			singles = append(singles, r.Min)
+		}
+	}
+	if len(singles) > 0 {
+		addSection(b, certSectionSerialList, func(b *cryptobyte.Builder) {
+			for _, serial := range singles {
+				b.AddUint64(serial)
+			}
+		})
+	}
+	for _, r := range cs.Serials {
+		if r.Min != r.Max {
+			r := r
+			addSection(b, certSectionSerialRange, func(b *cryptobyte.Builder) {
+				b.AddUint64(r.Min)
+				b.AddUint64(r.Max)
+			})
+		}
+	}
+	if len(cs.KeyIDs) > 0 {
+		addSection(b, certSectionKeyID, func(b *cryptobyte.Builder) {
+			for _, id := range cs.KeyIDs {
+				addString(b, []byte(id))
+			}
+		})
+	}
+}
+
+func addSection(b *cryptobyte.Builder, typ uint8, contents cryptobyte.BuilderContinuation) {
+	b.AddUint8(typ)
+	b.AddUint32LengthPrefixed(contents)
+}
+
+func addString(b *cryptobyte.Builder, s []byte) {
+	b.AddUint32LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(s) })
+}
@@ -0,0 +1,158 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package krl
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNormalizeSerials(t *testing.T) {
+	got := normalizeSerials([]SerialRange{{20, 30}, {1, 1}, {31, 35}, {2, 4}, {25, 26}, {40, 40}, {1<<64 - 1, 1<<64 - 1}, {50, 1<<64 - 1}})
+	want := []SerialRange{{1, 4}, {20, 35}, {40, 40}, {50, 1<<64 - 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	ca, other := signer(t, "ed25519"), signer(t, "ecdsa")
+	k := &KRL{Version: 3, Comment: "ca", GeneratedDate: time.Unix(1700000000, 0)}
+	if err := k.RevokeSerials(ca.PublicKey(), 10, 20); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.RevokeSerials(ca.PublicKey(), 21, 21); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.RevokeSerials(ca.PublicKey(), 99, 99); err != nil {
+		t.Fatal(err)
+	}
+	k.RevokeKeyID(ca.PublicKey(), "alice")
+	k.RevokeKeyID(ca.PublicKey(), "alice")
+	k.RevokeKeyID(nil, "mallory")
+	if err := k.RevokeKey(signer(t, "rsa").PublicKey()); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.RevokeKey(cert(t, other, 7, "")); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.RevokeKey(cert(t, other, 0, "bob")); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(signer(t, "dsa").PublicKey().Marshal())
+	if err := k.RevokeSHA256(sum[:]); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		ca      string
+		serial  uint64
+		keyID   string
+		revoked bool
+	}{
+		{"ed25519", 15, "", true},
+		{"ed25519", 21, "", true},
+		{"ed25519", 22, "", false},
+		{"ed25519", 99, "", true},
+		{"ed25519", 5, "alice", true},
+		{"ecdsa", 7, "", true},
+		{"ecdsa", 8, "", false},
+		{"ecdsa", 0, "bob", true},
+		{"ecdsa", 0, "mallory", true},
+		{"ecdsa", 0, "alice", false},
+	} {
+		c := cert(t, signer(t, tt.ca), tt.serial, tt.keyID)
+		if got := k.IsCertRevoked(c); got != tt.revoked {
+			t.Errorf("%s, serial %d, key ID %q: got revoked %v, want %v", tt.ca, tt.serial, tt.keyID, got, tt.revoked)
+		}
+	}
+
+	parsed, err := Parse(k.Marshal())
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if parsed.Version != 3 || parsed.Comment != "ca" || !parsed.GeneratedDate.Equal(k.GeneratedDate) {
+		t.Errorf("got header %d, %q, %v", parsed.Version, parsed.Comment, parsed.GeneratedDate)
+	}
+	if !bytes.Equal(parsed.Marshal(), k.Marshal()) {
+		t.Error("marshaling the parsed KRL gave different bytes")
+	}
+	if !reflect.DeepEqual(parsed.Certificates[0].Serials, []SerialRange{{10, 21}, {99, 99}}) {
+		t.Errorf("got serials %v", parsed.Certificates[0].Serials)
+	}
+	if !parsed.IsRevoked(signer(t, "rsa").PublicKey()) || !parsed.IsRevoked(signer(t, "dsa").PublicKey()) {
+		t.Error("revoked keys were lost")
+	}
+
+	signed, err := k.MarshalSigned(rand.Reader, ca, other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err = Parse(signed)
+	if err != nil {
+		t.Fatalf("Parse signed: %v", err)
+	}
+	if len(parsed.SigningKeys) != 2 {
+		t.Errorf("got %d signing keys, want 2", len(parsed.SigningKeys))
+	}
+}
+
+func TestRevokeInvalid(t *testing.T) {
+	var k KRL
+	ca := signer(t, "ed25519").PublicKey()
+	if err := k.RevokeSerials(nil, 1, 2); err == nil {
+		t.Error("revoked serials without a CA")
+	}
+	if err := k.RevokeSerials(ca, 0, 2); err == nil {
+		t.Error("revoked serial zero")
+	}
+	if err := k.RevokeSerials(ca, 3, 2); err == nil {
+		t.Error("revoked a backwards range")
+	}
+	if err := k.RevokeSHA256([]byte("short")); err == nil {
+		t.Error("revoked a short hash")
+	}
+	if len(k.Certificates) != 0 {
+		// Failed calls mustn't leave empty sections behind.
+		t.Errorf("got %d certificate sections", len(k.Certificates))
+	}
+}
+
+func TestMerge(t *testing.T) {
+	data, err := base64.StdEncoding.DecodeString(keygenKRL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keygen, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca := signer(t, "ed25519")
+	k := &KRL{Version: 8}
+	if err := k.RevokeSerials(ca.PublicKey(), 21, 30); err != nil {
+		t.Fatal(err)
+	}
+	k.Merge(keygen)
+	k.Merge(keygen)
+
+	if k.Version != 8 {
+		t.Errorf("got version %d, want 8", k.Version)
+	}
+	want := []SerialRange{{1, 1}, {10, 30}, {100, 100}, {102, 102}, {104, 104}, {106, 106}, {108, 108}, {110, 110}}
+	if len(k.Certificates) != 1 || !reflect.DeepEqual(k.Certificates[0].Serials, want) {
+		t.Fatalf("got certificate sections %v", k.Certificates)
+	}
+	if len(k.Certificates[0].KeyIDs) != 1 || len(k.RevokedKeys) != 1 || len(k.RevokedSHA256) != 1 {
+		t.Errorf("merging twice duplicated revocations")
+	}
+	if !k.IsRevoked(signer(t, "rsa").PublicKey()) || !k.IsCertRevoked(cert(t, ca, 25, "")) {
+		t.Error("merged revocations are missing")
+	}
+}
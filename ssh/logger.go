@@ -0,0 +1,33 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+// Logger receives structured messages about the life cycle of connections.
+// The arguments following the message are alternating keys and values, as
+// in package log/slog, so a *slog.Logger implements Logger.
+//
+// Info is used for events that happen once per connection or per
+// authentication attempt, Debug for events that may happen many times,
+// such as key exchanges and channels being opened and closed.
+type Logger interface {
+	Info(msg string, args ...any)
+	Debug(msg string, args ...any)
+}
+
+// discardLogger is the Logger used if Config.Logger is nil.
+type discardLogger struct{}
+
+func (discardLogger) Info(msg string, args ...any)  {}
+func (discardLogger) Debug(msg string, args ...any) {}
+
+func (r authResult) String() string {
+	switch r {
+	case authSuccess:
+		return "success"
+	case authPartialSuccess:
+		return "partial success"
+	}
+	return "failure"
+}
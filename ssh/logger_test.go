@@ -0,0 +1,118 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"sync"
+	"testing"
+)
+
+// recordingLogger records the messages it receives.
+type recordingLogger struct {
+	mu   sync.Mutex
+	msgs map[string]int
+}
+
+func (l *recordingLogger) record(msg string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.msgs == nil {
+		l.msgs = make(map[string]int)
+	}
+	l.msgs[msg]++
+}
+
+func (l *recordingLogger) Info(msg string, args ...any) {
+	if len(args)%2 != 0 {
+		panic("odd number of arguments for " + msg)
+	}
+	l.record(msg)
+}
+
+func (l *recordingLogger) Debug(msg string, args ...any) {
+	l.Info(msg, args...)
+}
+
+func (l *recordingLogger) count(msg string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.msgs[msg]
+}
+
+func TestLogger(t *testing.T) {
+	c1, c2, err := netPipe()
+	if err != nil {
+		t.Fatalf("netPipe: %v", err)
+	}
+	defer c1.Close()
+	defer c2.Close()
+
+	serverLog := &recordingLogger{}
+	serverConf := &ServerConfig{
+		Config: Config{Logger: serverLog},
+		PasswordCallback: func(conn ConnMetadata, password []byte) (*Permissions, error) {
+			return nil, nil
+		},
+	}
+	serverConf.AddHostKey(testSigners["ecdsa"])
+	serverDone := make(chan struct{})
+	go func() {
+		defer close(serverDone)
+		conn, chans, reqs, err := NewServerConn(c2, serverConf)
+		if err != nil {
+			return
+		}
+		go DiscardRequests(reqs)
+		for newCh := range chans {
+			ch, reqs, err := newCh.Accept()
+			if err != nil {
+				break
+			}
+			go DiscardRequests(reqs)
+			ch.Close()
+		}
+		conn.Wait()
+	}()
+
+	clientLog := &recordingLogger{}
+	clientConf := &ClientConfig{
+		Config:          Config{Logger: clientLog},
+		User:            "user",
+		Auth:            []AuthMethod{Password("password")},
+		HostKeyCallback: InsecureIgnoreHostKey(),
+	}
+	conn, _, reqs, err := NewClientConn(c1, "", clientConf)
+	if err != nil {
+		t.Fatalf("NewClientConn: %v", err)
+	}
+	go DiscardRequests(reqs)
+	ch, reqs, err := conn.OpenChannel("test", nil)
+	if err != nil {
+		t.Fatalf("OpenChannel: %v", err)
+	}
+	go DiscardRequests(reqs)
+	ch.Close()
+	conn.Close()
+	conn.Wait()
+	<-serverDone
+
+	for _, l := range []*recordingLogger{clientLog, serverLog} {
+		for _, msg := range []string{
+			"ssh: version exchanged",
+			"ssh: key exchange started",
+			"ssh: key exchange finished",
+			"ssh: authentication attempt",
+			"ssh: channel opened",
+			"ssh: connection closed",
+		} {
+			if l.count(msg) == 0 {
+				t.Errorf("no %q message logged", msg)
+			}
+		}
+	}
+	if n := serverLog.count("ssh: authentication attempt"); n != 2 {
+		t.Errorf("server logged %d authentication attempts, want 2 (none and password)", n)
+	}
+}
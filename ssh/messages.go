@@ -47,6 +47,18 @@ func (d *disconnectMsg) Error() string {
 	return fmt.Sprintf("ssh: disconnect, reason %d: %s", d.Reason, d.Message)
 }
 
+// See RFC 4253, section 11.2.
+type ignoreMsg struct {
+	Data []byte `sshtype:"2"`
+}
+
+// See RFC 4253, section 11.3.
+type debugMsg struct {
+	AlwaysDisplay bool `sshtype:"4"`
+	Message       string
+	Language      string
+}
+
 // See RFC 4253, section 7.1.
 const msgKexInit = 20
 
@@ -183,6 +195,14 @@ type userAuthBannerMsg struct {
 	Language string
 }
 
+// See RFC 4252, section 8
+const msgUserAuthPasswdChangeReq = 60
+
+type userAuthPasswdChangeReqMsg struct {
+	Prompt   string `sshtype:"60"`
+	Language string
+}
+
 // See RFC 4256, section 3.2
 const msgUserAuthInfoRequest = 60
 const msgUserAuthInfoResponse = 61
@@ -349,9 +369,47 @@ type userAuthGSSAPIError struct {
 	LanguageTag string
 }
 
+// See RFC 4462, section 2.1. The INIT and COMPLETE messages carry the
+// ephemeral public keys, whose encoding depends on the key exchange method,
+// so they are built and parsed in gssKex.
+const (
+	msgKexGSSInit     = 30
+	msgKexGSSComplete = 32
+)
+
+type kexGSSInitMsg struct {
+	Token  []byte `sshtype:"30"`
+	PubKey []byte `ssh:"rest"`
+}
+
+const msgKexGSSContinue = 31
+
+type kexGSSContinueMsg struct {
+	Token []byte `sshtype:"31"`
+}
+
+const msgKexGSSHostKey = 33
+
+type kexGSSHostKeyMsg struct {
+	HostKey []byte `sshtype:"33"`
+}
+
+const msgKexGSSError = 34
+
+type kexGSSErrorMsg struct {
+	MajorStatus uint32 `sshtype:"34"`
+	MinorStatus uint32
+	Message     string
+	LanguageTag string
+}
+
 // Transport layer OpenSSH extension. See [PROTOCOL], section 1.9
 const msgPing = 192
 
+// pingExtension is the name under which support for msgPing is advertised
+// in SSH_MSG_EXT_INFO.
+const pingExtension = "ping@openssh.com"
+
 type pingMsg struct {
 	Data string `sshtype:"192"`
 }
@@ -0,0 +1,93 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+// Metrics receives counters about a connection, for example to export them
+// to a monitoring system. Several connections may share a Metrics, so
+// implementations must be safe for concurrent use. The methods are called
+// on the data path and should return quickly.
+type Metrics interface {
+	// PacketSent and PacketReceived are called for every packet, with the
+	// length of its payload before compression and encryption.
+	PacketSent(n int)
+	PacketReceived(n int)
+
+	// ChannelBytesSent and ChannelBytesReceived are called with the
+	// amount of data, including extended data, sent and received on a
+	// channel of the given type.
+	ChannelBytesSent(chanType string, n int)
+	ChannelBytesReceived(chanType string, n int)
+
+	// ChannelOpened is called when a channel is opened or accepted, and
+	// ChannelClosed when it is closed, so their difference is the number of
+	// open channels.
+	ChannelOpened(chanType string)
+	ChannelClosed(chanType string)
+
+	// KeyExchange is called whenever a key exchange completes, including
+	// the initial one.
+	KeyExchange()
+
+	// AuthFailure is called on servers for every rejected authentication
+	// attempt, except those using the "none" method.
+	AuthFailure(method string)
+}
+
+// discardMetrics is the Metrics used if Config.Metrics is nil.
+type discardMetrics struct{}
+
+func (discardMetrics) PacketSent(n int)                            {}
+func (discardMetrics) PacketReceived(n int)                        {}
+func (discardMetrics) ChannelBytesSent(chanType string, n int)     {}
+func (discardMetrics) ChannelBytesReceived(chanType string, n int) {}
+func (discardMetrics) ChannelOpened(chanType string)               {}
+func (discardMetrics) ChannelClosed(chanType string)               {}
+func (discardMetrics) KeyExchange()                                {}
+func (discardMetrics) AuthFailure(method string)                   {}
+
+// metricsTransport counts the packets passing through the underlying
+// keyingTransport.
+type metricsTransport struct {
+	keyingTransport
+	metrics Metrics
+}
+
+func newMetricsTransport(conn keyingTransport, config *Config) keyingTransport {
+	if _, ok := config.Metrics.(discardMetrics); ok || config.Metrics == nil {
+		return conn
+	}
+	return &metricsTransport{conn, config.Metrics}
+}
+
+func (t *metricsTransport) writePacket(packet []byte) error {
+	n := len(packet)
+	if err := t.keyingTransport.writePacket(packet); err != nil {
+		return err
+	}
+	t.metrics.PacketSent(n)
+	return nil
+}
+
+func (t *metricsTransport) readPacket() ([]byte, error) {
+	packet, err := t.keyingTransport.readPacket()
+	if err == nil {
+		t.metrics.PacketReceived(len(packet))
+	}
+	return packet, err
+}
+
+// countOpened reports the channel as open to the metrics.
+func (ch *channel) countOpened() {
+	ch.countedOpen.Store(true)
+	ch.mux.metrics.ChannelOpened(ch.chanType)
+}
+
+// countClosed reports the channel as closed to the metrics, if it was
+// reported as open and not yet as closed.
+func (ch *channel) countClosed() {
+	if ch.countedOpen.CompareAndSwap(true, false) {
+		ch.mux.metrics.ChannelClosed(ch.chanType)
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"testing"
+)
+
+// countingMetrics sums up the counters it receives.
+type countingMetrics struct {
+	mu                                    sync.Mutex
+	packetsSent, packetsReceived          int
+	channelBytesSent, channelBytesRecvd   int
+	channelsOpened, channelsClosed, kexes int
+	authFailures                          []string
+}
+
+func (m *countingMetrics) do(f func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f()
+}
+
+func (m *countingMetrics) PacketSent(n int)     { m.do(func() { m.packetsSent++ }) }
+func (m *countingMetrics) PacketReceived(n int) { m.do(func() { m.packetsReceived++ }) }
+func (m *countingMetrics) ChannelBytesSent(chanType string, n int) {
+	m.do(func() { m.channelBytesSent += n })
+}
+func (m *countingMetrics) ChannelBytesReceived(chanType string, n int) {
+	m.do(func() { m.channelBytesRecvd += n })
+}
+func (m *countingMetrics) ChannelOpened(chanType string) { m.do(func() { m.channelsOpened++ }) }
+func (m *countingMetrics) ChannelClosed(chanType string) { m.do(func() { m.channelsClosed++ }) }
+func (m *countingMetrics) KeyExchange()                  { m.do(func() { m.kexes++ }) }
+func (m *countingMetrics) AuthFailure(method string) {
+	m.do(func() { m.authFailures = append(m.authFailures, method) })
+}
+
+func TestMetrics(t *testing.T) {
+	c1, c2, err := netPipe()
+	if err != nil {
+		t.Fatalf("netPipe: %v", err)
+	}
+	defer c1.Close()
+	defer c2.Close()
+
+	serverMetrics := &countingMetrics{}
+	serverConf := &ServerConfig{
+		Config: Config{Metrics: serverMetrics},
+		PasswordCallback: func(conn ConnMetadata, password []byte) (*Permissions, error) {
+			if string(password) != "right" {
+				return nil, errors.New("wrong password")
+			}
+			return nil, nil
+		},
+	}
+	serverConf.AddHostKey(testSigners["ecdsa"])
+	serverDone := make(chan struct{})
+	go func() {
+		defer close(serverDone)
+		conn, chans, reqs, err := NewServerConn(c2, serverConf)
+		if err != nil {
+			t.Errorf("NewServerConn: %v", err)
+			return
+		}
+		go DiscardRequests(reqs)
+		for newCh := range chans {
+			ch, reqs, err := newCh.Accept()
+			if err != nil {
+				break
+			}
+			go DiscardRequests(reqs)
+			io.Copy(ch, ch)
+			ch.Close()
+		}
+		conn.Wait()
+	}()
+
+	passwords := []string{"wrong", "right"}
+	clientMetrics := &countingMetrics{}
+	clientConf := &ClientConfig{
+		Config: Config{Metrics: clientMetrics},
+		User:   "user",
+		Auth: []AuthMethod{RetryableAuthMethod(PasswordCallback(func() (string, error) {
+			p := passwords[0]
+			passwords = passwords[1:]
+			return p, nil
+		}), 2)},
+		HostKeyCallback: InsecureIgnoreHostKey(),
+	}
+	conn, _, reqs, err := NewClientConn(c1, "", clientConf)
+	if err != nil {
+		t.Fatalf("NewClientConn: %v", err)
+	}
+	go DiscardRequests(reqs)
+	ch, reqs, err := conn.OpenChannel("echo", nil)
+	if err != nil {
+		t.Fatalf("OpenChannel: %v", err)
+	}
+	go DiscardRequests(reqs)
+	const size = 100000
+	go func() {
+		ch.Write(make([]byte, size))
+		ch.CloseWrite()
+	}()
+	if n, err := io.Copy(io.Discard, ch); n != size || err != nil {
+		t.Fatalf("read %d bytes, %v; want %d bytes", n, err, size)
+	}
+	ch.Close()
+	conn.Close()
+	conn.Wait()
+	<-serverDone
+
+	for _, m := range []*countingMetrics{clientMetrics, serverMetrics} {
+		m.mu.Lock()
+		if m.packetsSent == 0 || m.packetsReceived == 0 {
+			t.Errorf("counted %d packets sent and %d received", m.packetsSent, m.packetsReceived)
+		}
+		if m.channelBytesSent != size || m.channelBytesRecvd != size {
+			t.Errorf("counted %d channel bytes sent and %d received, want %d", m.channelBytesSent, m.channelBytesRecvd, size)
+		}
+		if m.channelsOpened != 1 || m.channelsClosed != 1 {
+			t.Errorf("counted %d channels opened and %d closed, want 1", m.channelsOpened, m.channelsClosed)
+		}
+		if m.kexes != 1 {
+			t.Errorf("counted %d key exchanges, want 1", m.kexes)
+		}
+		m.mu.Unlock()
+	}
+	if len(serverMetrics.authFailures) != 1 || serverMetrics.authFailures[0] != "password" {
+		t.Errorf("server counted authentication failures %q, want [password]", serverMetrics.authFailures)
+	}
+}
@@ -0,0 +1,25 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.24
+
+package ssh
+
+import (
+	"testing"
+)
+
+func TestMLKEMIsDefault(t *testing.T) {
+	clientConf := &ClientConfig{HostKeyCallback: InsecureIgnoreHostKey()}
+	client, server, err := handshakePair(clientConf, "addr", false)
+	if err != nil {
+		t.Fatalf("handshakePair: %v", err)
+	}
+	defer client.Close()
+	defer server.Close()
+
+	if got := client.algorithms.kex; got != kexAlgoMLKEM768xX25519SHA256 {
+		t.Errorf("got kex %q, want %q", got, kexAlgoMLKEM768xX25519SHA256)
+	}
+}
@@ -5,12 +5,15 @@
 package ssh
 
 import (
+	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 // debugMux, if set, causes messages in the connection protocol to be
@@ -91,12 +94,61 @@ type mux struct {
 
 	incomingChannels chan NewChannel
 
-	globalSentMu     sync.Mutex
+	// globalSent is a semaphore held while a global request with
+	// WantReply set is waiting for its reply, since replies carry no ID.
+	globalSent       chan struct{}
 	globalResponses  chan interface{}
 	incomingRequests chan *Request
 
+	// windowSize is the initial and maximum flow-control window of the
+	// channels created on this mux. See Config.ChannelBufferSize.
+	windowSize uint32
+
+	logger  Logger
+	metrics Metrics
+
+	// lastActive is the time, in Unix nanoseconds, at which channel data
+	// or a request last flowed. See Config.IdleTimeout.
+	lastActive atomic.Int64
+
+	// pingMu serializes ping@openssh.com round trips. pongs receives the
+	// data of pong@openssh.com messages, and pingSeq numbers the pings.
+	pingMu  sync.Mutex
+	pongs   chan string
+	pingSeq uint64
+
+	// rtt accumulates the round-trip times of pings and keepalives.
+	rtt rttEstimator
+
+	// requestHandlers answer the global requests of the given types,
+	// instead of passing them on to incomingRequests. They are only added
+	// before the read loop starts, which then passes the requests to them
+	// in order through handledRequests, so that they may block.
+	requestHandlers map[string]func(payload []byte) (bool, []byte)
+	handledRequests chan handledRequest
+
+	// replies queues the replies to the global requests of the peer in
+	// the order of the requests, since the peer matches them by order.
+	// The read loop adds a globalReply for every request with WantReply
+	// set, and sendReplies sends each once it is set, wherever the
+	// request is answered.
+	replies chan *globalReply
+
+	// enforceNoMoreSessions makes the mux reject "session" channels once
+	// the peer has sent a no-more-sessions@openssh.com request, which sets
+	// noMoreSessions. Both are only used by the read loop.
+	enforceNoMoreSessions bool
+	noMoreSessions        bool
+
 	errCond *sync.Cond
 	err     error
+
+	// closeErr, if set, is reported by Wait instead of the error that
+	// terminated the read loop. It is protected by errCond.L.
+	closeErr error
+
+	// done is closed when the read loop exits.
+	done chan struct{}
 }
 
 // When debugging, each new chanList instantiation has a different
@@ -112,23 +164,56 @@ func (m *mux) Wait() error {
 	return m.err
 }
 
-// newMux returns a mux that runs over the given connection.
+// newMux returns a mux that runs over the given connection, using default
+// settings.
 func newMux(p packetConn) *mux {
+	config := &Config{}
+	config.SetDefaults()
+	return newMuxConfig(p, config)
+}
+
+// newMuxConfig returns a mux that runs over the given connection, using the
+// connection protocol settings of config, which must have its defaults set.
+func newMuxConfig(p packetConn, config *Config) *mux {
+	m := newMuxState(p, config)
+	go m.loop()
+	return m
+}
+
+// newMuxState is like newMuxConfig, but does not start the read loop, so
+// that the caller can finish setting up the mux.
+func newMuxState(p packetConn, config *Config) *mux {
 	m := &mux{
 		conn:             p,
+		windowSize:       config.ChannelBufferSize,
+		logger:           config.Logger,
+		metrics:          config.Metrics,
 		incomingChannels: make(chan NewChannel, chanSize),
+		globalSent:       make(chan struct{}, 1),
 		globalResponses:  make(chan interface{}, 1),
 		incomingRequests: make(chan *Request, chanSize),
 		errCond:          newCond(),
+		done:             make(chan struct{}),
+		pongs:            make(chan string, 1),
+		replies:          make(chan *globalReply, chanSize),
 	}
 	if debugMux {
 		m.chanList.offset = atomic.AddUint32(&globalOff, 1)
 	}
-
-	go m.loop()
+	m.markActive()
 	return m
 }
 
+// handleRequests makes the mux answer global requests of type name with h,
+// which is called outside of the read loop and may block. It must be called
+// before the read loop starts.
+func (m *mux) handleRequests(name string, h func(payload []byte) (bool, []byte)) {
+	if m.requestHandlers == nil {
+		m.requestHandlers = make(map[string]func(payload []byte) (bool, []byte))
+	}
+	m.requestHandlers[name] = h
+}
+
 func (m *mux) sendMessage(msg interface{}) error {
 	p := Marshal(msg)
 	if debugMux {
@@ -138,16 +223,46 @@ func (m *mux) sendMessage(msg interface{}) error {
 }
 
 func (m *mux) SendRequest(name string, wantReply bool, payload []byte) (bool, []byte, error) {
+	return m.SendRequestContext(context.Background(), name, wantReply, payload)
+}
+
+// SendRequestContext is like SendRequest, but stops waiting for the reply
+// once ctx is done, returning ErrReplyTimeout if its deadline passed. The
+// reply of an abandoned request is discarded when it arrives, before the
+// next request can receive its own.
+func (m *mux) SendRequestContext(ctx context.Context, name string, wantReply bool, payload []byte) (bool, []byte, error) {
+	return m.sendRequest(ctx, name, wantReply, payload, nil)
+}
+
+// sendRequest implements SendRequestContext. If sent is not nil, it is set
+// to the time the request is sent at, after waiting for earlier requests to
+// be answered, to measure round-trip times.
+func (m *mux) sendRequest(ctx context.Context, name string, wantReply bool, payload []byte, sent *time.Time) (bool, []byte, error) {
+	if err := ctx.Err(); err != nil {
+		return false, nil, err
+	}
+	if name != keepaliveRequest {
+		m.markActive()
+	}
 	if wantReply {
-		m.globalSentMu.Lock()
-		defer m.globalSentMu.Unlock()
+		select {
+		case m.globalSent <- struct{}{}:
+		case <-ctx.Done():
+			return false, nil, replyWaitError(ctx)
+		}
 	}
 
+	if sent != nil {
+		*sent = time.Now()
+	}
 	if err := m.sendMessage(globalRequestMsg{
 		Type:      name,
 		WantReply: wantReply,
 		Data:      payload,
 	}); err != nil {
+		if wantReply {
+			<-m.globalSent
+		}
 		return false, nil, err
 	}
 
@@ -155,7 +270,18 @@ func (m *mux) SendRequest(name string, wantReply bool, payload []byte) (bool, []
 		return false, nil, nil
 	}
 
-	msg, ok := <-m.globalResponses
+	var msg interface{}
+	var ok bool
+	select {
+	case msg, ok = <-m.globalResponses:
+		<-m.globalSent
+	case <-ctx.Done():
+		go func() {
+			<-m.globalResponses
+			<-m.globalSent
+		}()
+		return false, nil, replyWaitError(ctx)
+	}
 	if !ok {
 		return false, nil, io.EOF
 	}
@@ -169,8 +295,9 @@ func (m *mux) SendRequest(name string, wantReply bool, payload []byte) (bool, []
 	}
 }
 
-// ackRequest must be called after processing a global request that
-// has WantReply set.
+// ackRequest sends the reply to a global request that has WantReply set.
+// The replies to the requests received by the read loop are sent in order
+// by sendReplies.
 func (m *mux) ackRequest(ok bool, data []byte) error {
 	if ok {
 		return m.sendMessage(globalRequestSuccessMsg{Data: data})
@@ -182,9 +309,172 @@ func (m *mux) Close() error {
 	return m.conn.Close()
 }
 
+// closeWithError closes the connection, and makes Wait return err
+// instead of the error returned by the underlying transport.
+func (m *mux) closeWithError(err error) error {
+	m.errCond.L.Lock()
+	if m.err == nil && m.closeErr == nil {
+		m.closeErr = err
+	}
+	m.errCond.L.Unlock()
+	return m.conn.Close()
+}
+
+// ErrReplyTimeout is returned by the SendRequestContext methods if the
+// deadline of the context passed before the reply to the request arrived.
+// It matches context.DeadlineExceeded with errors.Is, and implements
+// net.Error with Timeout() == true.
+var ErrReplyTimeout error = replyTimeoutError{}
+
+type replyTimeoutError struct{}
+
+func (replyTimeoutError) Error() string   { return "ssh: timed out waiting for reply to request" }
+func (replyTimeoutError) Timeout() bool   { return true }
+func (replyTimeoutError) Temporary() bool { return true }
+
+func (replyTimeoutError) Is(target error) bool {
+	return target == context.DeadlineExceeded
+}
+
+// replyWaitError returns the error for a request whose reply is no longer
+// waited for because ctx is done.
+func replyWaitError(ctx context.Context) error {
+	if err := ctx.Err(); err != context.DeadlineExceeded {
+		return err
+	}
+	return ErrReplyTimeout
+}
+
+// ErrKeepaliveTimeout is returned by Wait if the connection was closed
+// because the peer did not answer keepalive requests. See
+// Config.KeepaliveInterval.
+var ErrKeepaliveTimeout = errors.New("ssh: peer did not respond to keepalive requests")
+
+const keepaliveRequest = "keepalive@openssh.com"
+
+// noMoreSessionsRequest is the global request with which OpenSSH clients
+// announce that they will not open any further session channels. See
+// [PROTOCOL], section 2.2.
+const noMoreSessionsRequest = "no-more-sessions@openssh.com"
+
+// keepalive sends a keepalive request every interval, and closes the
+// connection with ErrKeepaliveTimeout once maxMissed intervals pass without
+// a reply. At most one keepalive request is outstanding at any time. Any
+// reply, including a failure, counts as a sign of life.
+func (m *mux) keepalive(interval time.Duration, maxMissed int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	replied := make(chan struct{}, 1)
+	pending := false
+	missed := 0
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-replied:
+			pending = false
+			missed = 0
+			continue
+		case <-ticker.C:
+		}
+
+		if pending {
+			missed++
+			if missed >= maxMissed {
+				m.closeWithError(ErrKeepaliveTimeout)
+				return
+			}
+			continue
+		}
+
+		pending = true
+		go func() {
+			var sent time.Time
+			if _, _, err := m.sendRequest(context.Background(), keepaliveRequest, true, nil, &sent); err == nil {
+				m.rtt.add(time.Since(sent))
+				replied <- struct{}{}
+			}
+		}()
+	}
+}
+
+// ping sends a ping@openssh.com message and waits for the matching
+// pong@openssh.com reply, returning the round-trip time.
+func (m *mux) ping(ctx context.Context) (time.Duration, error) {
+	m.pingMu.Lock()
+	defer m.pingMu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	m.pingSeq++
+	data := fmt.Sprintf("ping-%d", m.pingSeq)
+	start := time.Now()
+	if err := m.sendMessage(pingMsg{Data: data}); err != nil {
+		return 0, err
+	}
+	for {
+		select {
+		case got := <-m.pongs:
+			// Skip late replies to pings that were given up on.
+			if got == data {
+				d := time.Since(start)
+				m.rtt.add(d)
+				return d, nil
+			}
+		case <-m.done:
+			return 0, io.EOF
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+}
+
+// ErrIdleTimeout is returned by Wait if the connection was closed because
+// it was idle for too long. See Config.IdleTimeout.
+var ErrIdleTimeout = errors.New("ssh: connection idle for too long")
+
+// markActive records that channel data or a request flowed on the
+// connection.
+func (m *mux) markActive() {
+	m.lastActive.Store(time.Now().UnixNano())
+}
+
+// idleTimeout disconnects the peer and closes the connection with
+// ErrIdleTimeout once it has not been active for timeout.
+func (m *mux) idleTimeout(timeout time.Duration) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-timer.C:
+		}
+
+		idle := time.Since(time.Unix(0, m.lastActive.Load()))
+		if idle < timeout {
+			timer.Reset(timeout - idle)
+			continue
+		}
+		// Reason 11 is SSH_DISCONNECT_BY_APPLICATION, see RFC 4253,
+		// section 11.1.
+		m.sendMessage(disconnectMsg{Reason: 11, Message: "idle timeout"})
+		m.closeWithError(ErrIdleTimeout)
+		return
+	}
+}
+
 // loop runs the connection machine. It will process packets until an
 // error is encountered. To synchronize on loop exit, use mux.Wait.
 func (m *mux) loop() {
+	go m.sendReplies()
+	if m.requestHandlers != nil {
+		m.handledRequests = make(chan handledRequest, chanSize)
+		go m.answerRequests(m.handledRequests)
+	}
+
 	var err error
 	for err == nil {
 		err = m.onePacket()
@@ -192,18 +482,29 @@ func (m *mux) loop() {
 
 	for _, ch := range m.chanList.dropAll() {
 		ch.close()
+		ch.countClosed()
 	}
 
 	close(m.incomingChannels)
 	close(m.incomingRequests)
 	close(m.globalResponses)
+	close(m.replies)
+	if m.handledRequests != nil {
+		close(m.handledRequests)
+	}
 
 	m.conn.Close()
 
 	m.errCond.L.Lock()
+	if m.closeErr != nil {
+		err = m.closeErr
+	}
 	m.err = err
 	m.errCond.Broadcast()
 	m.errCond.L.Unlock()
+	close(m.done)
+
+	m.logger.Info("ssh: connection closed", "error", err)
 
 	if debugMux {
 		log.Println("loop exit", err)
@@ -237,6 +538,17 @@ func (m *mux) onePacket() error {
 			return fmt.Errorf("failed to unmarshal ping@openssh.com message: %w", err)
 		}
 		return m.sendMessage(pongMsg(msg))
+	case msgPong:
+		var msg pongMsg
+		if err := Unmarshal(packet, &msg); err != nil {
+			return fmt.Errorf("failed to unmarshal pong@openssh.com message: %w", err)
+		}
+		select {
+		case m.pongs <- msg.Data:
+		default:
+			// Nobody is waiting for this pong.
+		}
+		return nil
 	}
 
 	// assume a channel packet.
@@ -252,6 +564,59 @@ func (m *mux) onePacket() error {
 	return ch.handlePacket(packet)
 }
 
+// A globalReply is the reply to a global request of the peer, which is
+// sent once it is set and the replies to earlier requests are sent.
+type globalReply struct {
+	once sync.Once
+	set  chan struct{}
+	ok   bool
+	data []byte
+}
+
+func newGlobalReply() *globalReply {
+	return &globalReply{set: make(chan struct{})}
+}
+
+// reply sets the reply. Only the first call has an effect.
+func (r *globalReply) reply(ok bool, data []byte) {
+	r.once.Do(func() {
+		r.ok, r.data = ok, data
+		close(r.set)
+	})
+}
+
+// sendReplies sends the replies queued in m.replies in order.
+func (m *mux) sendReplies() {
+	for r := range m.replies {
+		select {
+		case <-r.set:
+		case <-m.done:
+			return
+		}
+		if err := m.ackRequest(r.ok, r.data); err != nil {
+			return
+		}
+	}
+}
+
+// handledRequest is a global request for requestHandlers, and its reply
+// if it has WantReply set.
+type handledRequest struct {
+	msg   *globalRequestMsg
+	reply *globalReply
+}
+
+// answerRequests answers the global requests for requestHandlers, outside
+// of the read loop.
+func (m *mux) answerRequests(reqs <-chan handledRequest) {
+	for req := range reqs {
+		ok, data := m.requestHandlers[req.msg.Type](req.msg.Data)
+		if req.reply != nil {
+			req.reply.reply(ok, data)
+		}
+	}
+}
+
 func (m *mux) handleGlobalPacket(packet []byte) error {
 	msg, err := decode(packet)
 	if err != nil {
@@ -260,11 +625,31 @@ func (m *mux) handleGlobalPacket(packet []byte) error {
 
 	switch msg := msg.(type) {
 	case *globalRequestMsg:
+		if msg.Type != keepaliveRequest {
+			m.markActive()
+		}
+		var reply *globalReply
+		if msg.WantReply {
+			reply = newGlobalReply()
+			m.replies <- reply
+		}
+		if m.requestHandlers[msg.Type] != nil {
+			m.handledRequests <- handledRequest{msg, reply}
+			return nil
+		}
+		if msg.Type == noMoreSessionsRequest && m.enforceNoMoreSessions {
+			m.noMoreSessions = true
+			if reply != nil {
+				reply.reply(true, nil)
+			}
+			return nil
+		}
 		m.incomingRequests <- &Request{
 			Type:      msg.Type,
 			WantReply: msg.WantReply,
 			Payload:   msg.Data,
 			mux:       m,
+			reply:     reply,
 		}
 	case *globalRequestSuccessMsg, *globalRequestFailureMsg:
 		m.globalResponses <- msg
@@ -292,6 +677,17 @@ func (m *mux) handleChannelOpen(packet []byte) error {
 		return m.sendMessage(failMsg)
 	}
 
+	if m.noMoreSessions && msg.ChanType == "session" {
+		m.logger.Debug("ssh: channel open rejected", "type", msg.ChanType, "reason", Prohibited, "message", "no more sessions")
+		failMsg := channelOpenFailureMsg{
+			PeersID:  msg.PeersID,
+			Reason:   Prohibited,
+			Message:  "no more sessions",
+			Language: "en_US.UTF-8",
+		}
+		return m.sendMessage(failMsg)
+	}
+
 	c := m.newChannel(msg.ChanType, channelInbound, msg.TypeSpecificData)
 	c.remoteId = msg.PeersID
 	c.maxRemotePayload = msg.MaxPacketSize
@@ -301,7 +697,14 @@ func (m *mux) handleChannelOpen(packet []byte) error {
 }
 
 func (m *mux) OpenChannel(chanType string, extra []byte) (Channel, <-chan *Request, error) {
-	ch, err := m.openChannel(chanType, extra)
+	return m.OpenChannelContext(context.Background(), chanType, extra)
+}
+
+// OpenChannelContext is like OpenChannel, but stops waiting for the peer to
+// accept or reject the channel once ctx is done. A channel accepted after
+// that is closed right away.
+func (m *mux) OpenChannelContext(ctx context.Context, chanType string, extra []byte) (Channel, <-chan *Request, error) {
+	ch, err := m.openChannelContext(ctx, chanType, extra)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -310,6 +713,23 @@ func (m *mux) OpenChannel(chanType string, extra []byte) (Channel, <-chan *Reque
 }
 
 func (m *mux) openChannel(chanType string, extra []byte) (*channel, error) {
+	return m.openChannelContext(context.Background(), chanType, extra)
+}
+
+func (m *mux) openChannelContext(ctx context.Context, chanType string, extra []byte) (*channel, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	ch, err := m.sendChannelOpen(chanType, extra)
+	if err != nil {
+		return nil, err
+	}
+	return m.waitChannelOpen(ctx, ch)
+}
+
+// sendChannelOpen creates an outbound channel and asks the peer to open
+// it.
+func (m *mux) sendChannelOpen(chanType string, extra []byte) (*channel, error) {
 	ch := m.newChannel(chanType, channelOutbound, extra)
 
 	ch.maxIncomingPayload = channelMaxPacket
@@ -324,17 +744,87 @@ func (m *mux) openChannel(chanType string, extra []byte) (*channel, error) {
 	if err := m.sendMessage(open); err != nil {
 		return nil, err
 	}
+	return ch, nil
+}
 
-	switch msg := (<-ch.msg).(type) {
+// waitChannelOpen waits for the peer to accept or reject ch.
+func (m *mux) waitChannelOpen(ctx context.Context, ch *channel) (*channel, error) {
+	var reply interface{}
+	select {
+	case reply = <-ch.msg:
+	case <-ctx.Done():
+		go func() {
+			if _, ok := (<-ch.msg).(*channelOpenConfirmMsg); ok {
+				go DiscardRequests(ch.incomingRequests)
+				ch.Close()
+			}
+		}()
+		return nil, ctx.Err()
+	}
+
+	switch msg := reply.(type) {
 	case *channelOpenConfirmMsg:
+		ch.countOpened()
+		m.logger.Debug("ssh: channel opened", "type", ch.chanType, "id", ch.localId, "inbound", false)
 		return ch, nil
 	case *channelOpenFailureMsg:
+		m.logger.Debug("ssh: channel open rejected", "type", ch.chanType, "reason", msg.Reason, "message", msg.Message)
 		return nil, &OpenChannelError{msg.Reason, msg.Message}
 	default:
 		return nil, fmt.Errorf("ssh: unexpected packet in response to channel open: %T", msg)
 	}
 }
 
+// OpenChannelAsync sends the request to open a channel, and returns
+// without waiting for the reply of the peer.
+func (m *mux) OpenChannelAsync(ctx context.Context, chanType string, extra []byte) *PendingChannel {
+	p := &PendingChannel{done: make(chan struct{})}
+	if err := ctx.Err(); err != nil {
+		p.finish(nil, err)
+		return p
+	}
+	ch, err := m.sendChannelOpen(chanType, extra)
+	if err != nil {
+		p.finish(nil, err)
+		return p
+	}
+	go func() {
+		p.finish(m.waitChannelOpen(ctx, ch))
+	}()
+	return p
+}
+
+// PendingChannel is a channel that was requested with OpenChannelAsync,
+// whose opening may not be confirmed yet.
+type PendingChannel struct {
+	done chan struct{}
+	ch   Channel
+	reqs <-chan *Request
+	err  error
+}
+
+func (p *PendingChannel) finish(ch *channel, err error) {
+	if err == nil {
+		p.ch, p.reqs = ch, ch.incomingRequests
+	}
+	p.err = err
+	close(p.done)
+}
+
+// Done returns a channel that is closed once the peer accepted or
+// rejected the channel, or opening it failed otherwise.
+func (p *PendingChannel) Done() <-chan struct{} {
+	return p.done
+}
+
+// Wait waits for the outcome of the channel open, and returns the values
+// that OpenChannel would have returned. It may be called several times, and
+// from several goroutines.
+func (p *PendingChannel) Wait() (Channel, <-chan *Request, error) {
+	<-p.done
+	return p.ch, p.reqs, p.err
+}
+
 func (m *mux) handleUnknownChannelPacket(id uint32, packet []byte) error {
 	msg, err := decode(packet)
 	if err != nil {
@@ -5,11 +5,14 @@
 package ssh
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"reflect"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -779,6 +782,78 @@ func TestMuxGlobalRequest(t *testing.T) {
 	}
 }
 
+func TestMuxKeepalive(t *testing.T) {
+	clientMux, serverMux := muxPair()
+	defer serverMux.Close()
+	defer clientMux.Close()
+
+	go DiscardRequests(serverMux.incomingRequests)
+	go clientMux.keepalive(5*time.Millisecond, 2)
+
+	// The peer answers every keepalive, so the connection must stay up.
+	time.Sleep(50 * time.Millisecond)
+	if _, _, err := clientMux.SendRequest("ping", true, nil); err != nil {
+		t.Fatalf("SendRequest: %v", err)
+	}
+}
+
+func TestMuxKeepaliveTimeout(t *testing.T) {
+	clientMux, serverMux := muxPair()
+	defer serverMux.Close()
+	defer clientMux.Close()
+
+	// Nobody services serverMux.incomingRequests, so keepalives are never
+	// answered.
+	go clientMux.keepalive(5*time.Millisecond, 2)
+
+	done := make(chan error, 1)
+	go func() { done <- clientMux.Wait() }()
+	select {
+	case err := <-done:
+		if err != ErrKeepaliveTimeout {
+			t.Errorf("Wait: got %v, want %v", err, ErrKeepaliveTimeout)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("connection was not closed after missed keepalives")
+	}
+}
+
+func TestMuxIdleTimeout(t *testing.T) {
+	clientMux, serverMux := muxPair()
+	defer serverMux.Close()
+	defer clientMux.Close()
+
+	go DiscardRequests(serverMux.incomingRequests)
+	go clientMux.idleTimeout(20 * time.Millisecond)
+
+	// Requests keep the connection active, keepalives do not.
+	for i := 0; i < 10; i++ {
+		time.Sleep(5 * time.Millisecond)
+		if _, _, err := clientMux.SendRequest("activity", true, nil); err != nil {
+			t.Fatalf("SendRequest: %v", err)
+		}
+		if _, _, err := clientMux.SendRequest(keepaliveRequest, true, nil); err != nil {
+			t.Fatalf("SendRequest: %v", err)
+		}
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- clientMux.Wait() }()
+	for {
+		select {
+		case err := <-done:
+			if err != ErrIdleTimeout {
+				t.Errorf("Wait: got %v, want %v", err, ErrIdleTimeout)
+			}
+			return
+		case <-time.After(5 * time.Second):
+			t.Fatal("idle connection was not closed")
+		case <-time.After(5 * time.Millisecond):
+			clientMux.SendRequest(keepaliveRequest, true, nil)
+		}
+	}
+}
+
 func TestMuxGlobalRequestUnblock(t *testing.T) {
 	clientMux, serverMux := muxPair()
 	defer serverMux.Close()
@@ -977,6 +1052,65 @@ func TestMuxChannelWindowDeferredUpdates(t *testing.T) {
 	}
 }
 
+func TestMuxChannelBufferSize(t *testing.T) {
+	const bufSize = 2 * channelMaxPacket
+	a, b := memPipe()
+	config := &Config{ChannelBufferSize: bufSize}
+	config.SetDefaults()
+	s := newMuxConfig(a, config)
+	c := newMux(b)
+	defer s.Close()
+	defer c.Close()
+
+	res := make(chan *channel, 1)
+	go func() {
+		newCh := <-s.incomingChannels
+		ch, _, err := newCh.Accept()
+		if err != nil {
+			t.Errorf("Accept: %v", err)
+			close(res)
+			return
+		}
+		res <- ch.(*channel)
+	}()
+	writer, err := c.openChannel("chan", nil)
+	if err != nil {
+		t.Fatalf("OpenChannel: %v", err)
+	}
+	reader := <-res
+	if reader == nil {
+		t.FailNow()
+	}
+
+	data := make([]byte, 4*bufSize)
+	var written atomic.Int64
+	go func() {
+		for i := 0; i < len(data); i += 1024 {
+			n, err := writer.Write(data[i : i+1024])
+			written.Add(int64(n))
+			if err != nil {
+				t.Errorf("Write: %v", err)
+				return
+			}
+		}
+		writer.CloseWrite()
+	}()
+
+	// Without a reader, the writer must block once the buffer is full.
+	writer.remoteWin.waitWriterBlocked()
+	if n := written.Load(); n > bufSize {
+		t.Errorf("wrote %d bytes without a reader, want at most %d", n, bufSize)
+	}
+
+	got, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if len(got) != len(data) {
+		t.Errorf("read %d bytes, want %d", len(got), len(data))
+	}
+}
+
 // Don't ship code with debug=true.
 func TestDebug(t *testing.T) {
 	if debugMux {
@@ -989,3 +1123,228 @@ func TestDebug(t *testing.T) {
 		t.Error("transport debug switched on")
 	}
 }
+
+func TestMuxChannelCloseRead(t *testing.T) {
+	reader, writer, mux := channelPair(t)
+	defer mux.Close()
+	defer reader.Close()
+	defer writer.Close()
+
+	// Fill the window, so that the writer blocks until the eow request
+	// arrives.
+	errc := make(chan error, 1)
+	go func() {
+		_, err := writer.Write(make([]byte, 2*channelWindowSize))
+		errc <- err
+	}()
+	writer.remoteWin.waitWriterBlocked()
+
+	if err := reader.CloseRead(); err != nil {
+		t.Fatalf("CloseRead: %v", err)
+	}
+	select {
+	case err := <-errc:
+		if err != io.EOF {
+			t.Errorf("blocked Write: got %v, want io.EOF", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Write still blocked after eow@openssh.com")
+	}
+	if _, err := writer.Write([]byte("x")); err != io.EOF {
+		t.Errorf("Write after eow@openssh.com: got %v, want io.EOF", err)
+	}
+
+	// The other direction is unaffected.
+	go reader.Write([]byte("hello"))
+	buf := make([]byte, 5)
+	if _, err := io.ReadFull(writer, buf); err != nil || string(buf) != "hello" {
+		t.Errorf("Read: got %q, %v", buf, err)
+	}
+}
+
+func TestMuxSendRequestContext(t *testing.T) {
+	client, server := muxPair()
+	defer client.Close()
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		_, _, err := client.SendRequestContext(ctx, "slow", true, nil)
+		errc <- err
+	}()
+	slow := <-server.incomingRequests
+	cancel()
+	if err := <-errc; err != context.Canceled {
+		t.Fatalf("SendRequestContext: got %v, want %v", err, context.Canceled)
+	}
+
+	// The late reply to the abandoned request must not be taken for the
+	// reply to the next one.
+	go func() {
+		slow.Reply(false, nil)
+		r := <-server.incomingRequests
+		r.Reply(r.Type == "yes", nil)
+	}()
+	ok, _, err := client.SendRequestContext(context.Background(), "yes", true, nil)
+	if err != nil || !ok {
+		t.Errorf("SendRequestContext after abandoned request: got %v, %v; want true", ok, err)
+	}
+}
+
+func TestChannelSendRequestContext(t *testing.T) {
+	client, server, mux := channelPair(t)
+	defer server.Close()
+	defer client.Close()
+	defer mux.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	errc := make(chan error, 1)
+	go func() {
+		_, err := client.SendRequestContext(ctx, "slow", true, nil)
+		errc <- err
+	}()
+	slow := <-server.incomingRequests
+	err := <-errc
+	if err != ErrReplyTimeout {
+		t.Fatalf("SendRequestContext: got %v, want %v", err, ErrReplyTimeout)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("errors.Is(%v, context.DeadlineExceeded) = false", err)
+	}
+
+	// The late reply to the abandoned request must not be taken for the
+	// reply to the next one.
+	go func() {
+		slow.Reply(false, nil)
+		r := <-server.incomingRequests
+		r.Reply(r.Type == "yes", nil)
+	}()
+	ok, err := client.SendRequest("yes", true, nil)
+	if err != nil || !ok {
+		t.Errorf("SendRequest after abandoned request: got %v, %v; want true", ok, err)
+	}
+}
+
+func TestMuxOpenChannelContext(t *testing.T) {
+	client, server := muxPair()
+	defer client.Close()
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		_, _, err := client.OpenChannelContext(ctx, "slow", nil)
+		errc <- err
+	}()
+	newCh := <-server.incomingChannels
+	cancel()
+	if err := <-errc; err != context.Canceled {
+		t.Fatalf("OpenChannelContext: got %v, want %v", err, context.Canceled)
+	}
+
+	// A channel accepted after the open was abandoned is closed by the
+	// opener.
+	ch, reqs, err := newCh.Accept()
+	if err != nil {
+		t.Fatalf("Accept: %v", err)
+	}
+	go DiscardRequests(reqs)
+	if _, err := ch.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("Read on abandoned channel: got %v, want io.EOF", err)
+	}
+}
+
+func TestMuxOpenChannelAsync(t *testing.T) {
+	client, server := muxPair()
+	defer client.Close()
+	defer server.Close()
+
+	// All the opens are sent before any is answered.
+	var pending []*PendingChannel
+	for _, name := range []string{"a", "b", "c"} {
+		pending = append(pending, client.OpenChannelAsync(context.Background(), name, nil))
+	}
+	var incoming []NewChannel
+	for i := 0; i < 3; i++ {
+		incoming = append(incoming, <-server.incomingChannels)
+	}
+	select {
+	case <-pending[0].Done():
+		t.Fatal("pending channel done before the reply")
+	default:
+	}
+
+	// Answer them in reverse order.
+	if err := incoming[2].Reject(Prohibited, "no"); err != nil {
+		t.Fatalf("Reject: %v", err)
+	}
+	for _, newCh := range incoming[:2] {
+		ch, reqs, err := newCh.Accept()
+		if err != nil {
+			t.Fatalf("Accept: %v", err)
+		}
+		go DiscardRequests(reqs)
+		defer ch.Close()
+	}
+
+	for i, p := range pending[:2] {
+		ch, _, err := p.Wait()
+		if err != nil {
+			t.Fatalf("Wait %d: %v", i, err)
+		}
+		ch.Close()
+	}
+	_, _, err := pending[2].Wait()
+	var openErr *OpenChannelError
+	if !errors.As(err, &openErr) || openErr.Reason != Prohibited {
+		t.Errorf("Wait on rejected channel: got %v, want an OpenChannelError", err)
+	}
+}
+
+func TestMuxGlobalReplyOrder(t *testing.T) {
+	a, b := memPipe()
+	defer a.Close()
+	config := &Config{}
+	config.SetDefaults()
+	serverMux := newMuxState(b, config)
+	serverMux.enforceNoMoreSessions = true
+	serverMux.handleRequests("handled", func(payload []byte) (bool, []byte) {
+		// Answer after the request that follows.
+		time.Sleep(50 * time.Millisecond)
+		return true, []byte("handled")
+	})
+	go serverMux.loop()
+	defer serverMux.Close()
+	go func() {
+		for r := range serverMux.incomingRequests {
+			r.Reply(false, []byte(r.Type))
+		}
+	}()
+
+	// Pipeline requests that are answered by a handler, the application
+	// and the read loop.
+	for _, name := range []string{"handled", "unhandled", noMoreSessionsRequest} {
+		if err := a.writePacket(Marshal(&globalRequestMsg{Type: name, WantReply: true})); err != nil {
+			t.Fatalf("writePacket: %v", err)
+		}
+	}
+	for _, want := range []interface{}{
+		&globalRequestSuccessMsg{Data: []byte("handled")},
+		&globalRequestFailureMsg{Data: []byte("unhandled")},
+		&globalRequestSuccessMsg{Data: []byte{}},
+	} {
+		packet, err := a.readPacket()
+		if err != nil {
+			t.Fatalf("readPacket: %v", err)
+		}
+		got, err := decode(packet)
+		if err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got reply %#v, want %#v", got, want)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build pkcs11 && cgo && (darwin || freebsd || linux || netbsd || openbsd)
+
+package pkcs11
+
+/*
+#cgo linux LDFLAGS: -ldl
+
+#include <dlfcn.h>
+#include <stdlib.h>
+#include <string.h>
+
+// The declarations of the PKCS#11 headers that are needed, with the
+// default Unix packing.
+
+typedef unsigned long CK_ULONG;
+typedef unsigned char CK_BYTE;
+typedef CK_ULONG CK_RV;
+
+typedef struct {
+	CK_BYTE major, minor;
+} CK_VERSION;
+
+typedef struct {
+	CK_ULONG type;
+	void *pValue;
+	CK_ULONG ulValueLen;
+} CK_ATTRIBUTE;
+
+typedef struct {
+	CK_ULONG mechanism;
+	void *pParameter;
+	CK_ULONG ulParameterLen;
+} CK_MECHANISM;
+
+typedef struct {
+	void *CreateMutex, *DestroyMutex, *LockMutex, *UnlockMutex;
+	CK_ULONG flags;
+	void *pReserved;
+} CK_C_INITIALIZE_ARGS;
+
+// CK_FUNCTION_LIST up to C_Sign, in the order of pkcs11f.h.
+typedef struct {
+	CK_VERSION version;
+	CK_RV (*C_Initialize)(void *);
+	CK_RV (*C_Finalize)(void *);
+	void *C_GetInfo, *C_GetFunctionList;
+	CK_RV (*C_GetSlotList)(CK_BYTE, CK_ULONG *, CK_ULONG *);
+	void *C_GetSlotInfo, *C_GetTokenInfo, *C_GetMechanismList, *C_GetMechanismInfo;
+	void *C_InitToken, *C_InitPIN, *C_SetPIN;
+	CK_RV (*C_OpenSession)(CK_ULONG, CK_ULONG, void *, void *, CK_ULONG *);
+	CK_RV (*C_CloseSession)(CK_ULONG);
+	void *C_CloseAllSessions, *C_GetSessionInfo, *C_GetOperationState, *C_SetOperationState;
+	CK_RV (*C_Login)(CK_ULONG, CK_ULONG, CK_BYTE *, CK_ULONG);
+	void *C_Logout, *C_CreateObject, *C_CopyObject, *C_DestroyObject, *C_GetObjectSize;
+	CK_RV (*C_GetAttributeValue)(CK_ULONG, CK_ULONG, CK_ATTRIBUTE *, CK_ULONG);
+	void *C_SetAttributeValue;
+	CK_RV (*C_FindObjectsInit)(CK_ULONG, CK_ATTRIBUTE *, CK_ULONG);
+	CK_RV (*C_FindObjects)(CK_ULONG, CK_ULONG *, CK_ULONG, CK_ULONG *);
+	CK_RV (*C_FindObjectsFinal)(CK_ULONG);
+	void *C_EncryptInit, *C_Encrypt, *C_EncryptUpdate, *C_EncryptFinal;
+	void *C_DecryptInit, *C_Decrypt, *C_DecryptUpdate, *C_DecryptFinal;
+	void *C_DigestInit, *C_Digest, *C_DigestUpdate, *C_DigestKey, *C_DigestFinal;
+	CK_RV (*C_SignInit)(CK_ULONG, CK_MECHANISM *, CK_ULONG);
+	CK_RV (*C_Sign)(CK_ULONG, CK_BYTE *, CK_ULONG, CK_BYTE *, CK_ULONG *);
+} CK_FUNCTION_LIST;
+
+typedef CK_RV (*CK_C_GetFunctionList)(CK_FUNCTION_LIST **);
+
+#define CKF_OS_LOCKING_OK 0x2
+#define CKF_SERIAL_SESSION 0x4
+#define CKU_USER 1
+#define CKA_CLASS 0x000
+#define CKA_KEY_TYPE 0x100
+#define CKA_ID 0x102
+#define CKR_CRYPTOKI_ALREADY_INITIALIZED 0x191
+#define CKR_FUNCTION_NOT_SUPPORTED 0x054
+
+static CK_RV load(const char *path, void **handle, CK_FUNCTION_LIST **fl) {
+	CK_C_GetFunctionList getFunctionList;
+	CK_C_INITIALIZE_ARGS args;
+	CK_RV rv;
+
+	*handle = dlopen(path, RTLD_NOW | RTLD_LOCAL);
+	if (*handle == NULL) {
+		return CKR_FUNCTION_NOT_SUPPORTED;
+	}
+	getFunctionList = (CK_C_GetFunctionList)dlsym(*handle, "C_GetFunctionList");
+	if (getFunctionList == NULL || getFunctionList(fl) != 0 || *fl == NULL) {
+		dlclose(*handle);
+		return CKR_FUNCTION_NOT_SUPPORTED;
+	}
+	memset(&args, 0, sizeof(args));
+	args.flags = CKF_OS_LOCKING_OK;
+	rv = (*fl)->C_Initialize(&args);
+	if (rv != 0 && rv != CKR_CRYPTOKI_ALREADY_INITIALIZED) {
+		dlclose(*handle);
+		return rv;
+	}
+	return 0;
+}
+
+static CK_RV finalize(void *handle, CK_FUNCTION_LIST *fl) {
+	CK_RV rv = fl->C_Finalize(NULL);
+	dlclose(handle);
+	return rv;
+}
+
+static CK_RV getSlotList(CK_FUNCTION_LIST *fl, CK_ULONG *slots, CK_ULONG *n) {
+	return fl->C_GetSlotList(1, slots, n);
+}
+
+static CK_RV openSession(CK_FUNCTION_LIST *fl, CK_ULONG slot, CK_ULONG *session) {
+	return fl->C_OpenSession(slot, CKF_SERIAL_SESSION, NULL, NULL, session);
+}
+
+static CK_RV closeSession(CK_FUNCTION_LIST *fl, CK_ULONG session) {
+	return fl->C_CloseSession(session);
+}
+
+static CK_RV login(CK_FUNCTION_LIST *fl, CK_ULONG session, CK_BYTE *pin, CK_ULONG pinLen) {
+	return fl->C_Login(session, CKU_USER, pin, pinLen);
+}
+
+// findObjects stores up to max handles of the matching objects in objects.
+static CK_RV findObjects(CK_FUNCTION_LIST *fl, CK_ULONG session, CK_ULONG class, CK_ULONG keyType,
+		CK_BYTE *id, CK_ULONG idLen, CK_ULONG *objects, CK_ULONG max, CK_ULONG *n) {
+	CK_ATTRIBUTE template[3] = {
+		{CKA_CLASS, &class, sizeof(class)},
+		{CKA_KEY_TYPE, &keyType, sizeof(keyType)},
+		{CKA_ID, id, idLen},
+	};
+	CK_RV rv = fl->C_FindObjectsInit(session, template, id != NULL ? 3 : 2);
+	if (rv != 0) {
+		return rv;
+	}
+	rv = fl->C_FindObjects(session, objects, max, n);
+	fl->C_FindObjectsFinal(session);
+	return rv;
+}
+
+static CK_RV getAttributeValue(CK_FUNCTION_LIST *fl, CK_ULONG session, CK_ULONG object,
+		CK_ATTRIBUTE *attrs, CK_ULONG n) {
+	return fl->C_GetAttributeValue(session, object, attrs, n);
+}
+
+static CK_RV sign(CK_FUNCTION_LIST *fl, CK_ULONG session, CK_ULONG key, CK_ULONG mechanism,
+		CK_BYTE *data, CK_ULONG dataLen, CK_BYTE *sig, CK_ULONG *sigLen) {
+	CK_MECHANISM m = {mechanism, NULL, 0};
+	CK_RV rv = fl->C_SignInit(session, &m, key);
+	if (rv != 0) {
+		return rv;
+	}
+	return fl->C_Sign(session, data, dataLen, sig, sigLen);
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// maxObjects bounds the number of keys of each type read from a token.
+const maxObjects = 64
+
+// maxSignatureSize is enough for RSA keys of 16384 bits.
+const maxSignatureSize = 2048
+
+type cModule struct {
+	handle unsafe.Pointer
+	fl     *C.CK_FUNCTION_LIST
+}
+
+// Open loads and initializes the PKCS#11 module at path, a shared library
+// such as opensc-pkcs11.so.
+func Open(path string) (*Module, error) {
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+	m := new(cModule)
+	if rv := C.load(cpath, &m.handle, &m.fl); rv != ckrOK {
+		if m.handle == nil {
+			return nil, fmt.Errorf("pkcs11: cannot load %s: %s", path, C.GoString(C.dlerror()))
+		}
+		return nil, fmt.Errorf("pkcs11: cannot initialize %s: %w", path, Error(rv))
+	}
+	return &Module{m: m}, nil
+}
+
+func check(rv C.CK_RV) error {
+	if rv != ckrOK {
+		return Error(rv)
+	}
+	return nil
+}
+
+func (m *cModule) finalize() error {
+	return check(C.finalize(m.handle, m.fl))
+}
+
+func (m *cModule) slots() ([]uint, error) {
+	var n C.CK_ULONG
+	if err := check(C.getSlotList(m.fl, nil, &n)); err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	slots := make([]C.CK_ULONG, n)
+	if err := check(C.getSlotList(m.fl, &slots[0], &n)); err != nil {
+		return nil, err
+	}
+	res := make([]uint, n)
+	for i := range res {
+		res[i] = uint(slots[i])
+	}
+	return res, nil
+}
+
+func (m *cModule) openSession(slot uint) (uint, error) {
+	var session C.CK_ULONG
+	err := check(C.openSession(m.fl, C.CK_ULONG(slot), &session))
+	return uint(session), err
+}
+
+func (m *cModule) closeSession(session uint) error {
+	return check(C.closeSession(m.fl, C.CK_ULONG(session)))
+}
+
+func (m *cModule) login(session uint, pin string) error {
+	cpin := C.CBytes([]byte(pin))
+	defer C.free(cpin)
+	return check(C.login(m.fl, C.CK_ULONG(session), (*C.CK_BYTE)(cpin), C.CK_ULONG(len(pin))))
+}
+
+func (m *cModule) findObjects(session, class, keyType uint, id []byte) ([]uint, error) {
+	var cid *C.CK_BYTE
+	if id != nil {
+		p := C.CBytes(id)
+		defer C.free(p)
+		cid = (*C.CK_BYTE)(p)
+	}
+	objects := make([]C.CK_ULONG, maxObjects)
+	var n C.CK_ULONG
+	if err := check(C.findObjects(m.fl, C.CK_ULONG(session), C.CK_ULONG(class), C.CK_ULONG(keyType),
+		cid, C.CK_ULONG(len(id)), &objects[0], maxObjects, &n)); err != nil {
+		return nil, err
+	}
+	res := make([]uint, n)
+	for i := range res {
+		res[i] = uint(objects[i])
+	}
+	return res, nil
+}
+
+func (m *cModule) attributes(session, object uint, types ...uint) ([][]byte, error) {
+	// The template holds pointers, so it lives in C memory.
+	size := C.size_t(len(types)) * C.size_t(unsafe.Sizeof(C.CK_ATTRIBUTE{}))
+	p := C.calloc(1, size)
+	defer C.free(p)
+	attrs := unsafe.Slice((*C.CK_ATTRIBUTE)(p), len(types))
+	for i, t := range types {
+		attrs[i]._type = C.CK_ULONG(t)
+	}
+
+	// The first call returns the lengths of the values.
+	if err := check(C.getAttributeValue(m.fl, C.CK_ULONG(session), C.CK_ULONG(object), &attrs[0], C.CK_ULONG(len(types)))); err != nil {
+		return nil, err
+	}
+	for i := range attrs {
+		attrs[i].pValue = C.malloc(attrs[i].ulValueLen + 1)
+		defer C.free(attrs[i].pValue)
+	}
+	if err := check(C.getAttributeValue(m.fl, C.CK_ULONG(session), C.CK_ULONG(object), &attrs[0], C.CK_ULONG(len(types)))); err != nil {
+		return nil, err
+	}
+	values := make([][]byte, len(types))
+	for i, a := range attrs {
+		values[i] = C.GoBytes(a.pValue, C.int(a.ulValueLen))
+	}
+	return values, nil
+}
+
+func (m *cModule) sign(session, key, mechanism uint, data []byte) ([]byte, error) {
+	cdata := C.CBytes(data)
+	defer C.free(cdata)
+	csig := C.malloc(maxSignatureSize)
+	defer C.free(csig)
+	sigLen := C.CK_ULONG(maxSignatureSize)
+	if err := check(C.sign(m.fl, C.CK_ULONG(session), C.CK_ULONG(key), C.CK_ULONG(mechanism),
+		(*C.CK_BYTE)(cdata), C.CK_ULONG(len(data)), (*C.CK_BYTE)(csig), &sigLen)); err != nil {
+		return nil, err
+	}
+	return C.GoBytes(csig, C.int(sigLen)), nil
+}
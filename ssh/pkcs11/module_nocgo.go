@@ -0,0 +1,16 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !pkcs11 || !cgo || !(darwin || freebsd || linux || netbsd || openbsd)
+
+package pkcs11
+
+import "errors"
+
+// Open loads and initializes the PKCS#11 module at path, a shared library
+// such as opensc-pkcs11.so. It requires building with the pkcs11 tag and cgo
+// on a Unix system.
+func Open(path string) (*Module, error) {
+	return nil, errors.New("pkcs11: loading modules requires the pkcs11 build tag and cgo on a Unix system")
+}
@@ -0,0 +1,353 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package pkcs11 exposes the keys of PKCS#11 tokens, such as smartcards and
+// hardware security modules, as SSH signers, in the manner of the
+// PKCS11Provider option of OpenSSH.
+//
+// Loading PKCS#11 modules requires cgo, and is supported on Unix systems.
+// As cgo is not otherwise needed, it is only used when building with the
+// pkcs11 tag; without it, Open fails. RSA, ECDSA and Ed25519 keys are
+// supported.
+package pkcs11
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// PKCS#11 constants, see the PKCS#11 Cryptographic Token Interface Base
+// Specification.
+const (
+	ckoPublicKey  = 2
+	ckoPrivateKey = 3
+
+	ckkRSA       = 0x00
+	ckkEC        = 0x03
+	ckkECEdwards = 0x40
+
+	ckaLabel          = 0x003
+	ckaID             = 0x102
+	ckaModulus        = 0x120
+	ckaPublicExponent = 0x122
+	ckaECParams       = 0x180
+	ckaECPoint        = 0x181
+
+	ckmRSAPKCS = 0x0001
+	ckmECDSA   = 0x1041
+	ckmEdDSA   = 0x1057
+
+	ckrOK                  = 0x000
+	ckrPINIncorrect        = 0x0a0
+	ckrUserAlreadyLoggedIn = 0x100
+)
+
+// Error is a PKCS#11 return value other than CKR_OK.
+type Error uint
+
+func (e Error) Error() string {
+	switch e {
+	case ckrPINIncorrect:
+		return "pkcs11: incorrect PIN (CKR_PIN_INCORRECT)"
+	}
+	return fmt.Sprintf("pkcs11: error %#x", uint(e))
+}
+
+// module is the subset of the PKCS#11 API used by this package, with
+// sessions and objects identified by their handles.
+type module interface {
+	slots() ([]uint, error)
+	openSession(slot uint) (uint, error)
+	closeSession(session uint) error
+	login(session uint, pin string) error
+	// findObjects returns the objects of the given class and key type,
+	// with the given CKA_ID if id is not nil.
+	findObjects(session, class, keyType uint, id []byte) ([]uint, error)
+	// attributes returns the values of byte array attributes.
+	attributes(session, object uint, types ...uint) ([][]byte, error)
+	sign(session, key, mechanism uint, data []byte) ([]byte, error)
+	finalize() error
+}
+
+// A Module is a loaded PKCS#11 module.
+type Module struct {
+	// mu serializes the operations on the sessions, which PKCS#11 only
+	// allows one at a time.
+	mu       sync.Mutex
+	m        module
+	sessions []uint
+}
+
+// Close closes the sessions opened by Keys and Signers and finalizes the
+// module. Any keys returned by the Module stop working.
+func (m *Module) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, s := range m.sessions {
+		m.m.closeSession(s)
+	}
+	m.sessions = nil
+	return m.m.finalize()
+}
+
+// Keys opens a session on every token of the module, logs in with pin
+// unless it is empty, and returns the supported private keys. Keys of types
+// that are not supported are skipped.
+func (m *Module) Keys(pin string) ([]*Key, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	slots, err := m.m.slots()
+	if err != nil {
+		return nil, err
+	}
+	var keys []*Key
+	for _, slot := range slots {
+		session, err := m.m.openSession(slot)
+		if err != nil {
+			return nil, err
+		}
+		m.sessions = append(m.sessions, session)
+		if pin != "" {
+			if err := m.m.login(session, pin); err != nil && err != Error(ckrUserAlreadyLoggedIn) {
+				return nil, err
+			}
+		}
+		for _, keyType := range []uint{ckkRSA, ckkEC, ckkECEdwards} {
+			objects, err := m.m.findObjects(session, ckoPrivateKey, keyType, nil)
+			if err != nil {
+				return nil, err
+			}
+			for _, obj := range objects {
+				k, err := m.newKey(session, obj, keyType)
+				if err != nil {
+					return nil, err
+				}
+				if k != nil {
+					keys = append(keys, k)
+				}
+			}
+		}
+	}
+	return keys, nil
+}
+
+// Signers is like Keys, but returns the keys as SSH signers. RSA signers
+// support the rsa-sha2-256 and rsa-sha2-512 algorithms as well as ssh-rsa.
+func (m *Module) Signers(pin string) ([]ssh.MultiAlgorithmSigner, error) {
+	keys, err := m.Keys(pin)
+	if err != nil {
+		return nil, err
+	}
+	signers := make([]ssh.MultiAlgorithmSigner, 0, len(keys))
+	for _, k := range keys {
+		s, err := ssh.NewSignerFromSigner(k)
+		if err != nil {
+			return nil, err
+		}
+		signers = append(signers, s.(ssh.MultiAlgorithmSigner))
+	}
+	return signers, nil
+}
+
+// A Key is a private key stored on a token. It implements crypto.Signer.
+type Key struct {
+	// Label and ID are the CKA_LABEL and CKA_ID attributes of the key.
+	Label string
+	ID    []byte
+
+	module  *Module
+	session uint
+	handle  uint
+	keyType uint
+	pub     crypto.PublicKey
+}
+
+// newKey reads the public part of a private key, which tokens usually keep
+// in the public key object of the same CKA_ID. It returns nil if the public
+// key can't be found, or uses an unsupported curve.
+func (m *Module) newKey(session, obj, keyType uint) (*Key, error) {
+	attrs, err := m.m.attributes(session, obj, ckaLabel, ckaID)
+	if err != nil {
+		return nil, err
+	}
+	k := &Key{
+		Label:   string(attrs[0]),
+		ID:      attrs[1],
+		module:  m,
+		session: session,
+		handle:  obj,
+		keyType: keyType,
+	}
+
+	// Fall back to the private key object, which carries the public
+	// attributes on many tokens.
+	candidates := []uint{obj}
+	if objects, err := m.m.findObjects(session, ckoPublicKey, keyType, k.ID); err == nil {
+		candidates = append(objects, obj)
+	}
+	for _, c := range candidates {
+		if k.pub = m.publicKey(session, c, keyType); k.pub != nil {
+			return k, nil
+		}
+	}
+	return nil, nil
+}
+
+// publicKey reads the public key attributes of obj, returning nil if they
+// are missing or unsupported.
+func (m *Module) publicKey(session, obj, keyType uint) crypto.PublicKey {
+	switch keyType {
+	case ckkRSA:
+		attrs, err := m.m.attributes(session, obj, ckaModulus, ckaPublicExponent)
+		if err != nil || len(attrs[0]) == 0 {
+			return nil
+		}
+		e := new(big.Int).SetBytes(attrs[1])
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(attrs[0]), E: int(e.Int64())}
+	case ckkEC, ckkECEdwards:
+		attrs, err := m.m.attributes(session, obj, ckaECParams, ckaECPoint)
+		if err != nil {
+			return nil
+		}
+		return parseECPublicKey(keyType, attrs[0], attrs[1])
+	}
+	return nil
+}
+
+var (
+	oidP256    = asn1.ObjectIdentifier{1, 2, 840, 10045, 3, 1, 7}
+	oidP384    = asn1.ObjectIdentifier{1, 3, 132, 0, 34}
+	oidP521    = asn1.ObjectIdentifier{1, 3, 132, 0, 35}
+	oidEd25519 = asn1.ObjectIdentifier{1, 3, 101, 112}
+)
+
+// parseECPublicKey parses the CKA_EC_PARAMS and CKA_EC_POINT attributes of
+// an elliptic curve key. It returns nil if the curve is not supported.
+func parseECPublicKey(keyType uint, params, point []byte) crypto.PublicKey {
+	// CKA_EC_POINT is a DER encoded OCTET STRING, but some tokens return
+	// the bare point, which may happen to parse as one.
+	points := [][]byte{point}
+	var octets []byte
+	if rest, err := asn1.Unmarshal(point, &octets); err == nil && len(rest) == 0 {
+		points = [][]byte{octets, point}
+	}
+
+	if keyType == ckkECEdwards {
+		// The curve is given by OID, or by the name "edwards25519".
+		var oid asn1.ObjectIdentifier
+		var name string
+		if _, err := asn1.Unmarshal(params, &oid); err == nil && !oid.Equal(oidEd25519) {
+			return nil
+		} else if err != nil {
+			if _, err := asn1.Unmarshal(params, &name); err != nil || name != "edwards25519" {
+				return nil
+			}
+		}
+		for _, p := range points {
+			if len(p) == ed25519.PublicKeySize {
+				return ed25519.PublicKey(p)
+			}
+		}
+		return nil
+	}
+
+	var oid asn1.ObjectIdentifier
+	if _, err := asn1.Unmarshal(params, &oid); err != nil {
+		return nil
+	}
+	var curve elliptic.Curve
+	switch {
+	case oid.Equal(oidP256):
+		curve = elliptic.P256()
+	case oid.Equal(oidP384):
+		curve = elliptic.P384()
+	case oid.Equal(oidP521):
+		curve = elliptic.P521()
+	default:
+		return nil
+	}
+	for _, p := range points {
+		if x, y := elliptic.Unmarshal(curve, p); x != nil {
+			return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+		}
+	}
+	return nil
+}
+
+// Public returns the public key of k.
+func (k *Key) Public() crypto.PublicKey {
+	return k.pub
+}
+
+// digestInfoPrefixes are the DER encodings of the DigestInfo structures of
+// PKCS #1 v1.5 signatures, without the digest.
+var digestInfoPrefixes = map[crypto.Hash][]byte{
+	crypto.SHA1:   {0x30, 0x21, 0x30, 0x09, 0x06, 0x05, 0x2b, 0x0e, 0x03, 0x02, 0x1a, 0x05, 0x00, 0x04, 0x14},
+	crypto.SHA256: {0x30, 0x31, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x01, 0x05, 0x00, 0x04, 0x20},
+	crypto.SHA384: {0x30, 0x41, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x02, 0x05, 0x00, 0x04, 0x30},
+	crypto.SHA512: {0x30, 0x51, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x03, 0x05, 0x00, 0x04, 0x40},
+}
+
+// Sign signs digest on the token. RSA keys produce PKCS #1 v1.5 signatures;
+// PSS is not supported. ECDSA signatures are ASN.1 encoded. Ed25519 keys
+// sign the message itself, and opts.HashFunc() must be zero.
+func (k *Key) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	var mechanism uint
+	data := digest
+	switch k.keyType {
+	case ckkRSA:
+		if _, ok := opts.(*rsa.PSSOptions); ok {
+			return nil, errors.New("pkcs11: RSA-PSS signatures are not supported")
+		}
+		prefix, ok := digestInfoPrefixes[opts.HashFunc()]
+		if !ok {
+			return nil, fmt.Errorf("pkcs11: unsupported hash function %v", opts.HashFunc())
+		}
+		if len(digest) != opts.HashFunc().Size() {
+			return nil, errors.New("pkcs11: digest has the wrong length")
+		}
+		mechanism = ckmRSAPKCS
+		data = append(append([]byte(nil), prefix...), digest...)
+	case ckkEC:
+		mechanism = ckmECDSA
+	case ckkECEdwards:
+		if opts.HashFunc() != 0 {
+			return nil, errors.New("pkcs11: Ed25519 keys sign unhashed messages")
+		}
+		mechanism = ckmEdDSA
+	}
+
+	k.module.mu.Lock()
+	sig, err := k.module.m.sign(k.session, k.handle, mechanism, data)
+	k.module.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if k.keyType == ckkEC {
+		// PKCS#11 returns r and s concatenated, crypto.Signer returns
+		// them ASN.1 encoded.
+		if len(sig) == 0 || len(sig)%2 != 0 {
+			return nil, errors.New("pkcs11: token returned a malformed ECDSA signature")
+		}
+		r := new(big.Int).SetBytes(sig[:len(sig)/2])
+		s := new(big.Int).SetBytes(sig[len(sig)/2:])
+		return asn1.Marshal(struct{ R, S *big.Int }{r, s})
+	}
+	return sig, nil
+}
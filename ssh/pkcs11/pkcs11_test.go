@@ -0,0 +1,279 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pkcs11
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/asn1"
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/testdata"
+)
+
+type fakeObject struct {
+	class, keyType uint
+	attrs          map[uint][]byte
+	key            crypto.Signer
+}
+
+// fakeModule is a token holding private keys, and public key objects for
+// some of them.
+type fakeModule struct {
+	t        *testing.T
+	pin      string
+	objects  []fakeObject
+	sessions map[uint]bool
+	loggedIn bool
+}
+
+func (m *fakeModule) slots() ([]uint, error) { return []uint{7}, nil }
+
+func (m *fakeModule) openSession(slot uint) (uint, error) {
+	if slot != 7 {
+		m.t.Fatalf("openSession(%d)", slot)
+	}
+	m.sessions[100] = true
+	return 100, nil
+}
+
+func (m *fakeModule) closeSession(session uint) error {
+	delete(m.sessions, session)
+	return nil
+}
+
+func (m *fakeModule) login(session uint, pin string) error {
+	if pin != m.pin {
+		return Error(ckrPINIncorrect)
+	}
+	m.loggedIn = true
+	return nil
+}
+
+func (m *fakeModule) findObjects(session, class, keyType uint, id []byte) ([]uint, error) {
+	var res []uint
+	for i, o := range m.objects {
+		if o.class == class && o.keyType == keyType && (id == nil || bytes.Equal(o.attrs[ckaID], id)) {
+			res = append(res, uint(i))
+		}
+	}
+	return res, nil
+}
+
+func (m *fakeModule) attributes(session, object uint, types ...uint) ([][]byte, error) {
+	var res [][]byte
+	for _, t := range types {
+		v, ok := m.objects[object].attrs[t]
+		if !ok {
+			return nil, Error(0x12) // CKR_ATTRIBUTE_TYPE_INVALID
+		}
+		res = append(res, v)
+	}
+	return res, nil
+}
+
+func (m *fakeModule) sign(session, key, mechanism uint, data []byte) ([]byte, error) {
+	if !m.sessions[session] || !m.loggedIn {
+		m.t.Fatal("signing without a logged in session")
+	}
+	switch k := m.objects[key].key.(type) {
+	case *rsa.PrivateKey:
+		if mechanism != ckmRSAPKCS {
+			break
+		}
+		// With no hash, data is signed as the complete DigestInfo.
+		return rsa.SignPKCS1v15(rand.Reader, k, 0, data)
+	case *ecdsa.PrivateKey:
+		if mechanism != ckmECDSA {
+			break
+		}
+		r, s, err := ecdsa.Sign(rand.Reader, k, data)
+		if err != nil {
+			return nil, err
+		}
+		size := (k.Curve.Params().BitSize + 7) / 8
+		sig := make([]byte, 2*size)
+		r.FillBytes(sig[:size])
+		s.FillBytes(sig[size:])
+		return sig, nil
+	case ed25519.PrivateKey:
+		if mechanism != ckmEdDSA {
+			break
+		}
+		return ed25519.Sign(k, data), nil
+	}
+	return nil, Error(0x70) // CKR_MECHANISM_INVALID
+}
+
+func (m *fakeModule) finalize() error { return nil }
+
+func mustMarshal(t *testing.T, v interface{}) []byte {
+	b, err := asn1.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func newFakeModule(t *testing.T) *fakeModule {
+	m := &fakeModule{t: t, pin: "123456", sessions: make(map[uint]bool)}
+	for _, name := range []string{"rsa", "ecdsa", "ed25519", "p384"} {
+		var key crypto.Signer
+		if name == "p384" {
+			k, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			key = k
+		} else {
+			k, err := ssh.ParseRawPrivateKey(testdata.PEMBytes[name])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ed, ok := k.(*ed25519.PrivateKey); ok {
+				k = *ed
+			}
+			key = k.(crypto.Signer)
+		}
+
+		id := []byte("id-" + name)
+		priv := fakeObject{class: ckoPrivateKey, key: key, attrs: map[uint][]byte{ckaLabel: []byte(name), ckaID: id}}
+		pub := fakeObject{class: ckoPublicKey, attrs: map[uint][]byte{ckaID: id}}
+		switch k := key.(type) {
+		case *rsa.PrivateKey:
+			priv.keyType = ckkRSA
+			// Only the private key object carries the modulus.
+			priv.attrs[ckaModulus] = k.N.Bytes()
+			priv.attrs[ckaPublicExponent] = []byte{1, 0, 1}
+		case *ecdsa.PrivateKey:
+			priv.keyType = ckkEC
+			pub.keyType = ckkEC
+			oid := oidP256
+			if k.Curve == elliptic.P384() {
+				oid = oidP384
+			}
+			pub.attrs[ckaECParams] = mustMarshal(t, oid)
+			point := elliptic.Marshal(k.Curve, k.X, k.Y)
+			if name == "ecdsa" {
+				point = mustMarshal(t, point)
+			}
+			pub.attrs[ckaECPoint] = point
+		case ed25519.PrivateKey:
+			priv.keyType = ckkECEdwards
+			pub.keyType = ckkECEdwards
+			pub.attrs[ckaECParams] = mustMarshal(t, "edwards25519")
+			pub.attrs[ckaECPoint] = mustMarshal(t, []byte(k.Public().(ed25519.PublicKey)))
+		}
+		m.objects = append(m.objects, priv, pub)
+	}
+	// A key of an unsupported type is skipped.
+	m.objects = append(m.objects, fakeObject{class: ckoPrivateKey, keyType: ckkEC, attrs: map[uint][]byte{
+		ckaLabel:    []byte("brainpool"),
+		ckaID:       []byte("id-brainpool"),
+		ckaECParams: mustMarshal(t, asn1.ObjectIdentifier{1, 3, 36, 3, 3, 2, 8, 1, 1, 7}),
+		ckaECPoint:  {4, 1, 2},
+	}})
+	return m
+}
+
+func TestSigners(t *testing.T) {
+	fake := newFakeModule(t)
+	m := &Module{m: fake}
+
+	if _, err := m.Signers("wrong"); err != Error(ckrPINIncorrect) {
+		t.Errorf("Signers with a wrong PIN: got %v, want %v", err, Error(ckrPINIncorrect))
+	}
+
+	keys, err := m.Keys(fake.pin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var labels []string
+	for _, k := range keys {
+		labels = append(labels, k.Label)
+	}
+	if len(keys) != 4 {
+		t.Fatalf("got keys %q, want rsa, ecdsa, p384 and ed25519", labels)
+	}
+
+	signers, err := m.Signers(fake.pin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("session data")
+	for i, signer := range signers {
+		pub := signer.PublicKey()
+		if keys[i].Label != "p384" {
+			want, err := ssh.ParsePrivateKey(testdata.PEMBytes[keys[i].Label])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(want.PublicKey().Marshal(), pub.Marshal()) {
+				t.Errorf("%s: wrong public key", keys[i].Label)
+			}
+		}
+		algorithms := signer.Algorithms()
+		if pub.Type() == ssh.KeyAlgoRSA && (len(algorithms) != 3 || algorithms[0] != ssh.KeyAlgoRSASHA256 || algorithms[1] != ssh.KeyAlgoRSASHA512) {
+			t.Errorf("got RSA algorithms %q", algorithms)
+		}
+		for _, algo := range algorithms {
+			sig, err := signer.SignWithAlgorithm(rand.Reader, data, algo)
+			if err != nil {
+				t.Errorf("%s: SignWithAlgorithm(%s): %v", keys[i].Label, algo, err)
+				continue
+			}
+			if sig.Format != algo {
+				t.Errorf("%s: got signature format %s, want %s", keys[i].Label, sig.Format, algo)
+			}
+			if err := pub.Verify(data, sig); err != nil {
+				t.Errorf("%s: Verify(%s): %v", keys[i].Label, algo, err)
+			}
+		}
+	}
+
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.sessions) != 0 {
+		t.Error("Close left sessions open")
+	}
+}
+
+func TestKeySignOptions(t *testing.T) {
+	fake := newFakeModule(t)
+	keys, err := (&Module{m: fake}).Keys(fake.pin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey := keys[0]
+	if _, ok := rsaKey.Public().(*rsa.PublicKey); !ok {
+		t.Fatalf("first key is %T, want RSA", rsaKey.Public())
+	}
+	digest := make([]byte, 32)
+	if _, err := rsaKey.Sign(rand.Reader, digest, &rsa.PSSOptions{Hash: crypto.SHA256}); err == nil {
+		t.Error("RSA-PSS signature succeeded")
+	}
+	if _, err := rsaKey.Sign(rand.Reader, digest, crypto.MD5); err == nil {
+		t.Error("MD5 signature succeeded")
+	}
+	if _, err := rsaKey.Sign(rand.Reader, digest[:20], crypto.SHA256); err == nil {
+		t.Error("signature of a short digest succeeded")
+	}
+}
+
+func TestOpenMissingModule(t *testing.T) {
+	if _, err := Open("/nonexistent/pkcs11.so"); err == nil {
+		t.Error("Open of a missing module succeeded")
+	} else if errors.As(err, new(Error)) {
+		t.Errorf("Open of a missing module returned %v, want a loading error", err)
+	}
+}
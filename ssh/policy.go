@@ -0,0 +1,140 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+// AlgorithmPolicy is a named set of algorithms, to configure a connection
+// with ApplyClient or ApplyServer rather than by listing algorithms one by
+// one. Each list is in order of preference; algorithms that this package
+// does not support, such as those that need a newer Go version, are
+// ignored when the connection is established.
+type AlgorithmPolicy struct {
+	KeyExchanges []string
+	Ciphers      []string
+	MACs         []string
+
+	// HostKeyAlgorithms is only used by clients; servers advertise the
+	// algorithms of their host keys, which are restricted with
+	// NewSignerWithAlgorithms.
+	HostKeyAlgorithms []string
+
+	// PublicKeyAuthAlgorithms is only used by servers.
+	PublicKeyAuthAlgorithms []string
+}
+
+// DefaultAlgorithmPolicy returns the algorithms that are used when a Config
+// does not list any.
+func DefaultAlgorithmPolicy() *AlgorithmPolicy {
+	return &AlgorithmPolicy{
+		KeyExchanges:            cloneStrings(preferredKexAlgos),
+		Ciphers:                 cloneStrings(preferredCiphers),
+		MACs:                    cloneStrings(supportedMACs),
+		HostKeyAlgorithms:       cloneStrings(supportedHostKeyAlgos),
+		PublicKeyAuthAlgorithms: cloneStrings(preferredPubKeyAuthAlgos),
+	}
+}
+
+// StrictAlgorithmPolicy returns a small set of modern algorithms: hybrid
+// post-quantum and Curve25519 key exchanges, AEAD ciphers, Ed25519 and
+// RSA with SHA-2 signatures, and security keys. Peers older than OpenSSH
+// 8.2 may not support any of them.
+func StrictAlgorithmPolicy() *AlgorithmPolicy {
+	return &AlgorithmPolicy{
+		KeyExchanges: []string{
+			"mlkem768x25519-sha256",
+			kexAlgoCurve25519SHA256, kexAlgoCurve25519SHA256LibSSH,
+		},
+		Ciphers: []string{chacha20Poly1305ID, gcm256CipherID, gcm128CipherID},
+		// MACs are not used with AEAD ciphers; these are for configurations
+		// that add other ciphers to the policy.
+		MACs: []string{"hmac-sha2-512-etm@openssh.com", "hmac-sha2-256-etm@openssh.com"},
+		HostKeyAlgorithms: []string{
+			CertAlgoED25519v01, CertAlgoRSASHA512v01, CertAlgoRSASHA256v01,
+			KeyAlgoED25519, KeyAlgoRSASHA512, KeyAlgoRSASHA256,
+		},
+		PublicKeyAuthAlgorithms: []string{
+			KeyAlgoED25519, KeyAlgoSKED25519, KeyAlgoSKECDSA256,
+			KeyAlgoRSASHA512, KeyAlgoRSASHA256,
+		},
+	}
+}
+
+// FIPSAlgorithmPolicy returns the algorithms approved by FIPS 140-3: NIST
+// curves and MODP groups with SHA-2 for key exchange, AES ciphers, SHA-2
+// MACs, and ECDSA and RSA with SHA-2 signatures. Restricting a connection
+// to them does not make it FIPS compliant by itself, which also depends on
+// the cryptographic module that implements them.
+func FIPSAlgorithmPolicy() *AlgorithmPolicy {
+	return &AlgorithmPolicy{
+		KeyExchanges: []string{
+			kexAlgoECDH256, kexAlgoECDH384, kexAlgoECDH521,
+			kexAlgoDH16SHA512, kexAlgoDH15SHA512, kexAlgoDH17SHA512,
+			kexAlgoDH18SHA512, kexAlgoDH14SHA256,
+		},
+		Ciphers: []string{
+			gcm256CipherID, gcm128CipherID,
+			"aes256-ctr", "aes192-ctr", "aes128-ctr",
+		},
+		MACs: []string{
+			"hmac-sha2-256-etm@openssh.com", "hmac-sha2-512-etm@openssh.com",
+			"hmac-sha2-256", "hmac-sha2-512",
+		},
+		HostKeyAlgorithms: []string{
+			CertAlgoECDSA256v01, CertAlgoECDSA384v01, CertAlgoECDSA521v01,
+			CertAlgoRSASHA512v01, CertAlgoRSASHA256v01,
+			KeyAlgoECDSA256, KeyAlgoECDSA384, KeyAlgoECDSA521,
+			KeyAlgoRSASHA512, KeyAlgoRSASHA256,
+		},
+		PublicKeyAuthAlgorithms: []string{
+			KeyAlgoECDSA256, KeyAlgoECDSA384, KeyAlgoECDSA521, KeyAlgoSKECDSA256,
+			KeyAlgoRSASHA512, KeyAlgoRSASHA256,
+		},
+	}
+}
+
+// InsecureLegacyAlgorithmPolicy returns all the algorithms that this
+// package supports, including broken ones such as SHA-1 key exchanges and
+// signatures, CBC mode, 3DES and RC4 ciphers, and DSA keys, which are
+// preferred the least. It should only be used to talk to old devices that
+// cannot be upgraded. The diffie-hellman-group-exchange key exchanges are
+// left out since they are only supported by clients, which can add them.
+func InsecureLegacyAlgorithmPolicy() *AlgorithmPolicy {
+	p := DefaultAlgorithmPolicy()
+	for _, k := range supportedKexAlgos {
+		if !contains(p.KeyExchanges, k) {
+			p.KeyExchanges = append(p.KeyExchanges, k)
+		}
+	}
+	for _, c := range supportedCiphers {
+		if !contains(p.Ciphers, c) {
+			p.Ciphers = append(p.Ciphers, c)
+		}
+	}
+	return p
+}
+
+// ApplyClient sets the algorithms of config to those of the policy.
+func (p *AlgorithmPolicy) ApplyClient(config *ClientConfig) {
+	p.apply(&config.Config)
+	config.HostKeyAlgorithms = cloneStrings(p.HostKeyAlgorithms)
+}
+
+// ApplyServer sets the algorithms of config to those of the policy.
+func (p *AlgorithmPolicy) ApplyServer(config *ServerConfig) {
+	p.apply(&config.Config)
+	config.PublicKeyAuthAlgorithms = cloneStrings(p.PublicKeyAuthAlgorithms)
+}
+
+func (p *AlgorithmPolicy) apply(config *Config) {
+	config.KeyExchanges = cloneStrings(p.KeyExchanges)
+	config.Ciphers = cloneStrings(p.Ciphers)
+	config.MACs = cloneStrings(p.MACs)
+}
+
+func cloneStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	return append([]string(nil), s...)
+}
@@ -0,0 +1,76 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"testing"
+)
+
+func TestAlgorithmPolicies(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		policy  *AlgorithmPolicy
+		hostKey string
+	}{
+		{"default", DefaultAlgorithmPolicy(), "rsa"},
+		{"strict", StrictAlgorithmPolicy(), "ed25519"},
+		{"fips", FIPSAlgorithmPolicy(), "ecdsa"},
+		{"legacy", InsecureLegacyAlgorithmPolicy(), "dsa"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			c1, c2, err := netPipe()
+			if err != nil {
+				t.Fatalf("netPipe: %v", err)
+			}
+			defer c1.Close()
+			defer c2.Close()
+
+			serverConf := &ServerConfig{NoClientAuth: true}
+			serverConf.AddHostKey(testSigners[tt.hostKey])
+			tt.policy.ApplyServer(serverConf)
+			go func() {
+				conn, _, _, err := NewServerConn(c1, serverConf)
+				if err == nil {
+					conn.Close()
+				}
+			}()
+
+			clientConf := &ClientConfig{
+				User:            "user",
+				HostKeyCallback: InsecureIgnoreHostKey(),
+			}
+			tt.policy.ApplyClient(clientConf)
+			conn, _, _, err := NewClientConn(c2, "", clientConf)
+			if err != nil {
+				t.Fatalf("NewClientConn: %v", err)
+			}
+			defer conn.Close()
+
+			algs := conn.(AlgorithmsConnMetadata).Algorithms()
+			if !contains(tt.policy.KeyExchanges, algs.KeyExchange) {
+				t.Errorf("key exchange %q not in the policy", algs.KeyExchange)
+			}
+			if !contains(tt.policy.HostKeyAlgorithms, algs.HostKey) {
+				t.Errorf("host key algorithm %q not in the policy", algs.HostKey)
+			}
+			if !contains(tt.policy.Ciphers, algs.Read.Cipher) {
+				t.Errorf("cipher %q not in the policy", algs.Read.Cipher)
+			}
+		})
+	}
+}
+
+func TestAlgorithmPolicyCopies(t *testing.T) {
+	p := StrictAlgorithmPolicy()
+	var config ClientConfig
+	p.ApplyClient(&config)
+	config.Ciphers[0] = "changed"
+	if p.Ciphers[0] == "changed" {
+		t.Error("ApplyClient shares its lists with the config")
+	}
+	if DefaultAlgorithmPolicy().Ciphers[0] != preferredCiphers[0] || &DefaultAlgorithmPolicy().Ciphers[0] == &preferredCiphers[0] {
+		t.Error("DefaultAlgorithmPolicy does not return a copy of the defaults")
+	}
+}
@@ -0,0 +1,307 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultPoolMaxSessions matches the OpenSSH MaxSessions default.
+	defaultPoolMaxSessions   = 10
+	defaultPoolIdleTimeout   = time.Minute
+	defaultPoolHealthTimeout = 5 * time.Second
+)
+
+// errPoolClosed is returned by the methods of a closed Pool.
+var errPoolClosed = errors.New("ssh: pool is closed")
+
+// Pool shares client connections between goroutines, which is convenient
+// for programs that open many short sessions to the same servers.
+// Connections are shared between calls with the same network, address,
+// user and key, a string chosen by the caller; ClientConfig values are not
+// compared otherwise. Callers pass different keys for configurations that
+// must not share connections, such as ones with different credentials or
+// host key checks, and the same key for configurations that may. A new
+// connection is dialed when all the connections for a key have
+// MaxSessionsPerConn sessions in use.
+//
+// The zero value is ready to use. A Pool must not be copied after first
+// use.
+type Pool struct {
+	// Dial establishes a new connection. If nil, DialContext is used.
+	Dial func(ctx context.Context, network, addr string, config *ClientConfig) (*Client, error)
+
+	// MaxSessionsPerConn is the number of sessions opened concurrently on
+	// a connection. If zero, 10 is used, matching the OpenSSH MaxSessions
+	// default. The limit is also lowered for a connection whose server
+	// rejects a session while others are open.
+	MaxSessionsPerConn int
+
+	// IdleTimeout is how long a connection without sessions is kept
+	// before it is closed. If zero, 1 minute is used. If negative, idle
+	// connections are kept until the pool is closed.
+	IdleTimeout time.Duration
+
+	// HealthCheckTimeout bounds the wait for the reply to the keepalive
+	// request sent on an idle connection before it is reused. A
+	// connection that fails the check is closed and another one used. If
+	// zero, 5 seconds is used. If negative, connections are not checked.
+	HealthCheckTimeout time.Duration
+
+	mu     sync.Mutex
+	conns  map[poolKey][]*poolConn
+	closed bool
+}
+
+type poolKey struct {
+	network, addr, user, key string
+}
+
+// poolConn is a connection of a Pool.
+type poolConn struct {
+	client   *Client
+	key      poolKey
+	sessions int // sessions in use, including reserved slots
+	max      int
+	idle     *time.Timer // closes the connection once it is idle
+	removed  bool
+}
+
+// NewSession opens a session on a connection to addr for key, dialing one
+// with config if needed. The session counts towards the limit of the
+// connection until it is closed. ctx bounds the dial and the opening of the
+// session.
+func (p *Pool) NewSession(ctx context.Context, network, addr, key string, config *ClientConfig) (*Session, error) {
+	for {
+		pc, err := p.get(ctx, network, addr, key, config)
+		if err != nil {
+			return nil, err
+		}
+		ch, in, err := pc.client.OpenChannelContext(ctx, "session", nil)
+		if err != nil {
+			var openErr *OpenChannelError
+			if errors.As(err, &openErr) && (openErr.Reason == ResourceShortage || openErr.Reason == Prohibited) && p.lowerLimit(pc) {
+				continue
+			}
+			p.release(pc)
+			return nil, err
+		}
+		s, err := newSession(ch, in)
+		if err != nil {
+			p.release(pc)
+			return nil, err
+		}
+		s.client = pc.client
+		go func() {
+			<-s.exited
+			p.release(pc)
+		}()
+		return s, nil
+	}
+}
+
+// Get returns a connection to addr for key, dialing one with config if
+// needed, and reserves a session slot on it until release is called, for
+// callers that open other kinds of channels. The returned Client must not
+// be closed by the caller.
+func (p *Pool) Get(ctx context.Context, network, addr, key string, config *ClientConfig) (client *Client, release func(), err error) {
+	pc, err := p.get(ctx, network, addr, key, config)
+	if err != nil {
+		return nil, nil, err
+	}
+	var once sync.Once
+	return pc.client, func() { once.Do(func() { p.release(pc) }) }, nil
+}
+
+// Close closes all the connections of the pool, including those with
+// sessions in use. Afterwards, NewSession and Get fail.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	p.closed = true
+	var conns []*poolConn
+	for _, list := range p.conns {
+		conns = append(conns, list...)
+	}
+	for _, pc := range conns {
+		p.removeLocked(pc)
+	}
+	p.mu.Unlock()
+	for _, pc := range conns {
+		pc.client.Close()
+	}
+	return nil
+}
+
+func (p *Pool) maxSessions() int {
+	if p.MaxSessionsPerConn > 0 {
+		return p.MaxSessionsPerConn
+	}
+	return defaultPoolMaxSessions
+}
+
+// get reserves a session slot on a connection for key, checking the
+// health of idle connections, or dials a new one.
+func (p *Pool) get(ctx context.Context, network, addr, k string, config *ClientConfig) (*poolConn, error) {
+	key := poolKey{network, addr, config.User, k}
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return nil, errPoolClosed
+		}
+		var pc *poolConn
+		for _, c := range p.conns[key] {
+			if c.sessions < c.max {
+				pc = c
+				break
+			}
+		}
+		if pc == nil {
+			p.mu.Unlock()
+			break
+		}
+		wasIdle := pc.sessions == 0
+		pc.sessions++
+		if pc.idle != nil {
+			pc.idle.Stop()
+			pc.idle = nil
+		}
+		p.mu.Unlock()
+
+		if !wasIdle || p.healthy(ctx, pc) {
+			return pc, nil
+		}
+		if err := ctx.Err(); err != nil {
+			p.release(pc)
+			return nil, err
+		}
+		p.remove(pc)
+		pc.client.Close()
+	}
+
+	dial := p.Dial
+	if dial == nil {
+		dial = DialContext
+	}
+	client, err := dial(ctx, network, addr, config)
+	if err != nil {
+		return nil, err
+	}
+	pc := &poolConn{client: client, key: key, sessions: 1, max: p.maxSessions()}
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		client.Close()
+		return nil, errPoolClosed
+	}
+	if p.conns == nil {
+		p.conns = make(map[poolKey][]*poolConn)
+	}
+	p.conns[key] = append(p.conns[key], pc)
+	p.mu.Unlock()
+	go func() {
+		client.Wait()
+		p.remove(pc)
+	}()
+	return pc, nil
+}
+
+// healthy sends a keepalive request on pc, reporting whether the server
+// answered it in time. Any answer will do, as servers that do not know the
+// request reply with a failure.
+func (p *Pool) healthy(ctx context.Context, pc *poolConn) bool {
+	timeout := p.HealthCheckTimeout
+	if timeout < 0 {
+		return true
+	}
+	if timeout == 0 {
+		timeout = defaultPoolHealthTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	_, _, err := pc.client.SendRequestContext(ctx, keepaliveRequest, true, nil)
+	return err == nil
+}
+
+// lowerLimit lowers the limit of pc to the number of other sessions in
+// use after the server rejected a session, releasing the slot reserved for
+// it. It returns false, without changing anything, if no other session is
+// in use, in which case the rejection was not about the limit.
+func (p *Pool) lowerLimit(pc *poolConn) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if pc.sessions <= 1 {
+		return false
+	}
+	pc.sessions--
+	pc.max = pc.sessions
+	return true
+}
+
+// release frees a session slot of pc, arming its idle timer if it was the
+// last one in use.
+func (p *Pool) release(pc *poolConn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pc.sessions--
+	if pc.sessions > 0 || pc.removed {
+		return
+	}
+	timeout := p.IdleTimeout
+	if timeout < 0 {
+		return
+	}
+	if timeout == 0 {
+		timeout = defaultPoolIdleTimeout
+	}
+	var t *time.Timer
+	t = time.AfterFunc(timeout, func() {
+		p.mu.Lock()
+		if pc.idle != t {
+			// Reused, or removed, in the meantime.
+			p.mu.Unlock()
+			return
+		}
+		p.removeLocked(pc)
+		p.mu.Unlock()
+		pc.client.Close()
+	})
+	pc.idle = t
+}
+
+func (p *Pool) remove(pc *poolConn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.removeLocked(pc)
+}
+
+// removeLocked removes pc from the pool, so that it is not handed out
+// again. p.mu must be held.
+func (p *Pool) removeLocked(pc *poolConn) {
+	if pc.removed {
+		return
+	}
+	pc.removed = true
+	if pc.idle != nil {
+		pc.idle.Stop()
+		pc.idle = nil
+	}
+	list := p.conns[pc.key]
+	for i, c := range list {
+		if c == pc {
+			list = append(list[:i], list[i+1:]...)
+			break
+		}
+	}
+	if len(list) == 0 {
+		delete(p.conns, pc.key)
+	} else {
+		p.conns[pc.key] = list
+	}
+}
@@ -0,0 +1,242 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// listenSessionSSH serves SSH on a local TCP listener, accepting up to
+// maxSessions concurrent sessions per connection and rejecting the others
+// with ResourceShortage.
+func listenSessionSSH(t *testing.T, maxSessions int) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	config := &ServerConfig{NoClientAuth: true}
+	config.AddHostKey(testSigners["rsa"])
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, chans, reqs, err := NewServerConn(c, config)
+				if err != nil {
+					return
+				}
+				go DiscardRequests(reqs)
+				var mu sync.Mutex
+				open := 0
+				for newCh := range chans {
+					mu.Lock()
+					full := open >= maxSessions
+					if !full {
+						open++
+					}
+					mu.Unlock()
+					if full {
+						newCh.Reject(ResourceShortage, "too many sessions")
+						continue
+					}
+					ch, chReqs, err := newCh.Accept()
+					if err != nil {
+						continue
+					}
+					go func() {
+						DiscardRequests(chReqs)
+						ch.Close()
+						mu.Lock()
+						open--
+						mu.Unlock()
+					}()
+				}
+			}()
+		}
+	}()
+	return l
+}
+
+// countingPool returns a Pool whose Dial counts the connections dialed.
+func countingPool(dials *int) *Pool {
+	var mu sync.Mutex
+	return &Pool{
+		Dial: func(ctx context.Context, network, addr string, config *ClientConfig) (*Client, error) {
+			mu.Lock()
+			*dials++
+			mu.Unlock()
+			return DialContext(ctx, network, addr, config)
+		},
+	}
+}
+
+func poolClientConfig() *ClientConfig {
+	return &ClientConfig{
+		User:            "user",
+		HostKeyCallback: InsecureIgnoreHostKey(),
+	}
+}
+
+// waitReleased waits until the pool has no session slot in use.
+func waitReleased(t *testing.T, p *Pool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		p.mu.Lock()
+		inUse := 0
+		for _, list := range p.conns {
+			for _, pc := range list {
+				inUse += pc.sessions
+			}
+		}
+		p.mu.Unlock()
+		if inUse == 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("sessions were not released")
+}
+
+func TestPoolReuse(t *testing.T) {
+	server := listenSessionSSH(t, 10)
+	var dials int
+	p := countingPool(&dials)
+	p.MaxSessionsPerConn = 2
+	defer p.Close()
+	config := poolClientConfig()
+	ctx := context.Background()
+
+	var sessions []*Session
+	for i := 0; i < 3; i++ {
+		s, err := p.NewSession(ctx, "tcp", server.Addr().String(), "", config)
+		if err != nil {
+			t.Fatalf("NewSession: %v", err)
+		}
+		sessions = append(sessions, s)
+	}
+	if dials != 2 {
+		t.Errorf("got %d connections for 3 sessions, want 2", dials)
+	}
+	for _, s := range sessions {
+		s.Close()
+	}
+	waitReleased(t, p)
+
+	for i := 0; i < 3; i++ {
+		s, err := p.NewSession(ctx, "tcp", server.Addr().String(), "", config)
+		if err != nil {
+			t.Fatalf("NewSession: %v", err)
+		}
+		defer s.Close()
+	}
+	if dials != 2 {
+		t.Errorf("got %d connections after reuse, want 2", dials)
+	}
+
+	// Connections are shared by key rather than by config.
+	client, release, err := p.Get(ctx, "tcp", server.Addr().String(), "", poolClientConfig())
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	defer release()
+	if client != sessions[0].client && client != sessions[2].client {
+		t.Error("Get did not share a connection for the same key")
+	}
+	client, release, err = p.Get(ctx, "tcp", server.Addr().String(), "other", config)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	defer release()
+	if client == sessions[0].client || client == sessions[2].client {
+		t.Error("Get shared a connection of another key")
+	}
+	if dials != 3 {
+		t.Errorf("got %d connections, want 3", dials)
+	}
+}
+
+func TestPoolServerSessionLimit(t *testing.T) {
+	server := listenSessionSSH(t, 1)
+	var dials int
+	p := countingPool(&dials)
+	defer p.Close()
+	config := poolClientConfig()
+
+	for i := 0; i < 2; i++ {
+		s, err := p.NewSession(context.Background(), "tcp", server.Addr().String(), "", config)
+		if err != nil {
+			t.Fatalf("NewSession: %v", err)
+		}
+		defer s.Close()
+	}
+	if dials != 2 {
+		t.Errorf("got %d connections, want 2", dials)
+	}
+	p.mu.Lock()
+	first := p.conns[poolKey{"tcp", server.Addr().String(), "user", ""}][0]
+	if first.max != 1 {
+		t.Errorf("got limit %d for the first connection, want 1", first.max)
+	}
+	p.mu.Unlock()
+}
+
+func TestPoolIdleTimeout(t *testing.T) {
+	server := listenSessionSSH(t, 10)
+	var dials int
+	p := countingPool(&dials)
+	p.IdleTimeout = 10 * time.Millisecond
+	defer p.Close()
+	config := poolClientConfig()
+
+	s, err := p.NewSession(context.Background(), "tcp", server.Addr().String(), "", config)
+	if err != nil {
+		t.Fatalf("NewSession: %v", err)
+	}
+	client := s.client
+	s.Close()
+
+	done := make(chan error, 1)
+	go func() { done <- client.Wait() }()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("idle connection was not closed")
+	}
+
+	s, err = p.NewSession(context.Background(), "tcp", server.Addr().String(), "", config)
+	if err != nil {
+		t.Fatalf("NewSession: %v", err)
+	}
+	s.Close()
+	if dials != 2 {
+		t.Errorf("got %d connections, want 2", dials)
+	}
+}
+
+func TestPoolClose(t *testing.T) {
+	server := listenSessionSSH(t, 10)
+	p := &Pool{}
+	config := poolClientConfig()
+
+	s, err := p.NewSession(context.Background(), "tcp", server.Addr().String(), "", config)
+	if err != nil {
+		t.Fatalf("NewSession: %v", err)
+	}
+	defer s.Close()
+	p.Close()
+	// Wait returns once the connection is closed.
+	s.client.Wait()
+	if _, err := p.NewSession(context.Background(), "tcp", server.Addr().String(), "", config); err != errPoolClosed {
+		t.Errorf("NewSession after Close: got %v, want %v", err, errPoolClosed)
+	}
+}
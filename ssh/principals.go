@@ -0,0 +1,83 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import "strings"
+
+// A PrincipalMatcher reports whether principal, a host name, is allowed by
+// the ValidPrincipals of a certificate, which is not empty.
+type PrincipalMatcher func(principal string, validPrincipals []string) bool
+
+// MatchPrincipalExact is the PrincipalMatcher used by CertChecker by
+// default. It reports whether principal is one of validPrincipals.
+func MatchPrincipalExact(principal string, validPrincipals []string) bool {
+	for _, p := range validPrincipals {
+		if p == principal {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchPrincipalPattern is a PrincipalMatcher that treats validPrincipals
+// as OpenSSH patterns, as in ssh_config(5). In a pattern, '*' matches any
+// sequence of characters and '?' matches one character. Each principal may
+// be a comma separated list of patterns, and patterns prefixed with '!' are
+// negated: principal is allowed if it matches some pattern, and none of the
+// negated ones. For example, with
+//
+//	[]string{"*.internal.example.com,!db.internal.example.com"}
+//
+// web.internal.example.com is allowed but db.internal.example.com isn't.
+//
+// Patterns are matched case-sensitively; callers matching host names may
+// want to lowercase principals and hosts first.
+func MatchPrincipalPattern(principal string, validPrincipals []string) bool {
+	matched := false
+	for _, list := range validPrincipals {
+		for _, pattern := range strings.Split(list, ",") {
+			negated := strings.HasPrefix(pattern, "!")
+			if negated {
+				pattern = pattern[1:]
+			}
+			if !matchPattern(pattern, principal) {
+				continue
+			}
+			if negated {
+				return false
+			}
+			matched = true
+		}
+	}
+	return matched
+}
+
+// matchPattern reports whether s matches pattern, in which '*' matches any
+// sequence of characters and '?' any single character.
+func matchPattern(pattern, s string) bool {
+	// Backtrack only to the most recent '*', which is enough since a later
+	// star can match anything an earlier one could.
+	var p, i, starP, starI int
+	starP = -1
+	for i < len(s) {
+		switch {
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == s[i]):
+			p++
+			i++
+		case p < len(pattern) && pattern[p] == '*':
+			starP, starI = p, i
+			p++
+		case starP >= 0:
+			starI++
+			p, i = starP+1, starI
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
@@ -0,0 +1,107 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"crypto/rand"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestMatchPattern(t *testing.T) {
+	for _, tt := range []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"", "", true},
+		{"", "a", false},
+		{"*", "", true},
+		{"*", "anything", true},
+		{"a*", "a", true},
+		{"a*b", "ab", true},
+		{"a*b", "axxb", true},
+		{"a*b", "axxbc", false},
+		{"a?c", "abc", true},
+		{"a?c", "ac", false},
+		{"*.example.com", "host.example.com", true},
+		{"*.example.com", "example.com", false},
+		{"*a*a*a*a*b", strings.Repeat("a", 40), false},
+		{"host", "Host", false},
+	} {
+		if got := matchPattern(tt.pattern, tt.s); got != tt.want {
+			t.Errorf("matchPattern(%q, %q) = %v, want %v", tt.pattern, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestMatchPrincipalPattern(t *testing.T) {
+	valid := []string{"*.internal.example.com,!db.internal.example.com", "bastion", "!*.test.internal.example.com"}
+	for principal, want := range map[string]bool{
+		"web.internal.example.com":      true,
+		"bastion":                       true,
+		"db.internal.example.com":       false,
+		"a.test.internal.example.com":   false,
+		"internal.example.com":          false,
+		"web.internal.example.com.evil": false,
+	} {
+		if got := MatchPrincipalPattern(principal, valid); got != want {
+			t.Errorf("MatchPrincipalPattern(%q) = %v, want %v", principal, got, want)
+		}
+	}
+	if !MatchPrincipalExact("bastion", valid) || MatchPrincipalExact("web.internal.example.com", valid) {
+		t.Error("MatchPrincipalExact matched patterns")
+	}
+}
+
+func TestCertCheckerMatchPrincipal(t *testing.T) {
+	cert := &Certificate{
+		Key:             testPublicKeys["rsa"],
+		CertType:        HostCert,
+		ValidPrincipals: []string{"*.internal.example.com"},
+		ValidBefore:     CertTimeInfinity,
+	}
+	if err := cert.SignCert(rand.Reader, testSigners["ecdsa"]); err != nil {
+		t.Fatal(err)
+	}
+	checker := CertChecker{
+		IsHostAuthority: func(auth PublicKey, address string) bool {
+			return string(auth.Marshal()) == string(testPublicKeys["ecdsa"].Marshal())
+		},
+	}
+	addr := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 22}
+	if err := checker.CheckHostKey("web.internal.example.com:22", addr, cert); err == nil {
+		t.Error("exact matching accepted a pattern")
+	}
+
+	checker.MatchHostPrincipal = MatchPrincipalPattern
+	if err := checker.CheckHostKey("web.internal.example.com:22", addr, cert); err != nil {
+		t.Errorf("CheckHostKey: %v", err)
+	}
+	if err := checker.CheckHostKey("web.example.com:22", addr, cert); err == nil {
+		t.Error("CheckHostKey accepted a host not matching the pattern")
+	}
+}
+
+func TestCertCheckerMatchUserPrincipal(t *testing.T) {
+	cert := &Certificate{
+		Key:             testPublicKeys["rsa"],
+		CertType:        UserCert,
+		ValidPrincipals: []string{"*"},
+		ValidBefore:     CertTimeInfinity,
+	}
+	if err := cert.SignCert(rand.Reader, testSigners["ecdsa"]); err != nil {
+		t.Fatal(err)
+	}
+	checker := CertChecker{
+		IsUserAuthority: func(auth PublicKey) bool {
+			return string(auth.Marshal()) == string(testPublicKeys["ecdsa"].Marshal())
+		},
+		MatchHostPrincipal: MatchPrincipalPattern,
+	}
+	if err := checker.CheckCert("root", cert); err == nil {
+		t.Error("user certificate with principal \"*\" accepted for root")
+	}
+}
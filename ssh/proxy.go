@@ -0,0 +1,183 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/textproto"
+	"strings"
+)
+
+// ProxyDialer dials connections through a proxy. It is implemented by the
+// dialers of golang.org/x/net/proxy, such as the SOCKS5 one, and by
+// HTTPConnectProxy.
+type ProxyDialer interface {
+	Dial(network, addr string) (net.Conn, error)
+}
+
+// contextProxyDialer is implemented by ProxyDialers that support contexts,
+// like golang.org/x/net/proxy.ContextDialer.
+type contextProxyDialer interface {
+	DialContext(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+// dialContext connects to addr for DialContext, through config.ProxyDialer
+// if set. A ProxyDialer without DialContext is used as is, the context only
+// being checked before dialing.
+func dialContext(ctx context.Context, network, addr string, config *ClientConfig) (net.Conn, error) {
+	if config.ProxyDialer == nil {
+		d := net.Dialer{Timeout: config.Timeout}
+		return d.DialContext(ctx, network, addr)
+	}
+	if config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.Timeout)
+		defer cancel()
+	}
+	if d, ok := config.ProxyDialer.(contextProxyDialer); ok {
+		return d.DialContext(ctx, network, addr)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return config.ProxyDialer.Dial(network, addr)
+}
+
+// HTTPConnectProxy is a ProxyDialer that tunnels TCP connections through an
+// HTTP proxy with the CONNECT method, see RFC 9110, section 9.3.6.
+type HTTPConnectProxy struct {
+	// Addr is the host and port of the proxy.
+	Addr string
+
+	// Username and Password, if Username is not empty, are sent to the
+	// proxy with the Basic authentication scheme.
+	Username string
+	Password string
+
+	// Forward, if not nil, is used to connect to the proxy, for example
+	// through another proxy. Otherwise a net.Dialer is used.
+	Forward ProxyDialer
+}
+
+// Dial connects to addr through the proxy.
+func (p *HTTPConnectProxy) Dial(network, addr string) (net.Conn, error) {
+	return p.DialContext(context.Background(), network, addr)
+}
+
+// DialContext connects to addr through the proxy. The context bounds the
+// connection to the proxy and the CONNECT request.
+func (p *HTTPConnectProxy) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+	default:
+		return nil, fmt.Errorf("ssh: HTTP CONNECT proxy does not support network %q", network)
+	}
+	// addr goes into the request line and the Host header.
+	if err := checkConnectAddr(addr); err != nil {
+		return nil, err
+	}
+
+	var conn net.Conn
+	var err error
+	switch d := p.Forward.(type) {
+	case nil:
+		var nd net.Dialer
+		conn, err = nd.DialContext(ctx, "tcp", p.Addr)
+	case contextProxyDialer:
+		conn, err = d.DialContext(ctx, "tcp", p.Addr)
+	default:
+		conn, err = d.Dial("tcp", p.Addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Closing the connection unblocks the request if ctx is done first.
+	done := make(chan struct{})
+	interrupted := make(chan bool, 1)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+			interrupted <- true
+		case <-done:
+			interrupted <- false
+		}
+	}()
+
+	tunnel, err := p.connect(conn, addr)
+	close(done)
+	if <-interrupted {
+		conn.Close()
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tunnel, nil
+}
+
+// checkConnectAddr checks that addr is a host and port that can be sent in
+// an HTTP request as is.
+func checkConnectAddr(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("ssh: invalid HTTP CONNECT address %q: %w", addr, err)
+	}
+	if host == "" || strings.IndexFunc(addr, func(r rune) bool { return r <= ' ' || r >= 0x7f }) >= 0 {
+		return fmt.Errorf("ssh: invalid HTTP CONNECT address %q", addr)
+	}
+	return nil
+}
+
+// connect sends the CONNECT request for addr on conn and reads the reply.
+func (p *HTTPConnectProxy) connect(conn net.Conn, addr string) (net.Conn, error) {
+	req := "CONNECT " + addr + " HTTP/1.1\r\nHost: " + addr + "\r\n"
+	if p.Username != "" {
+		credentials := base64.StdEncoding.EncodeToString([]byte(p.Username + ":" + p.Password))
+		req += "Proxy-Authorization: Basic " + credentials + "\r\n"
+	}
+	req += "\r\n"
+	if _, err := conn.Write([]byte(req)); err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	tp := textproto.NewReader(br)
+	status, err := tp.ReadLine()
+	if err != nil {
+		return nil, fmt.Errorf("ssh: reading HTTP CONNECT reply: %w", err)
+	}
+	proto, code, ok := strings.Cut(status, " ")
+	if !ok || !strings.HasPrefix(proto, "HTTP/1.") {
+		return nil, fmt.Errorf("ssh: malformed HTTP CONNECT reply %q", status)
+	}
+	if _, err := tp.ReadMIMEHeader(); err != nil {
+		return nil, fmt.Errorf("ssh: reading HTTP CONNECT reply: %w", err)
+	}
+	if !strings.HasPrefix(code, "2") {
+		return nil, fmt.Errorf("ssh: HTTP CONNECT proxy refused connection to %s: %s", addr, code)
+	}
+	if br.Buffered() == 0 {
+		return conn, nil
+	}
+	// The server may already have sent its version line.
+	return &bufferedConn{Conn: conn, r: br}, nil
+}
+
+// bufferedConn is a net.Conn whose data starts with what is buffered in r.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
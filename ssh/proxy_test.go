@@ -0,0 +1,179 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"bufio"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+// listenSSH serves SSH connections without authentication on a local TCP
+// listener.
+func listenSSH(t *testing.T) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	config := &ServerConfig{NoClientAuth: true}
+	config.AddHostKey(testSigners["rsa"])
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				conn, chans, reqs, err := NewServerConn(c, config)
+				if err != nil {
+					return
+				}
+				go DiscardRequests(reqs)
+				go func() {
+					for newCh := range chans {
+						newCh.Reject(Prohibited, "")
+					}
+				}()
+				conn.Wait()
+			}()
+		}
+	}()
+	return l
+}
+
+// listenHTTPConnect runs an HTTP CONNECT proxy on a local TCP listener,
+// which requires the Proxy-Authorization header auth if it is not empty.
+func listenHTTPConnect(t *testing.T, auth string) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				tp := textproto.NewReader(bufio.NewReader(c))
+				line, err := tp.ReadLine()
+				if err != nil {
+					return
+				}
+				header, err := tp.ReadMIMEHeader()
+				if err != nil {
+					return
+				}
+				fields := strings.Fields(line)
+				if len(fields) != 3 || fields[0] != "CONNECT" {
+					c.Write([]byte("HTTP/1.1 400 Bad Request\r\n\r\n"))
+					return
+				}
+				if auth != "" && header.Get("Proxy-Authorization") != auth {
+					c.Write([]byte("HTTP/1.1 407 Proxy Authentication Required\r\n\r\n"))
+					return
+				}
+				target, err := net.Dial("tcp", fields[1])
+				if err != nil {
+					c.Write([]byte("HTTP/1.1 502 Bad Gateway\r\n\r\n"))
+					return
+				}
+				defer target.Close()
+				c.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+				proxyHalfClose(c, target)
+			}()
+		}
+	}()
+	return l
+}
+
+func TestDialHTTPConnectProxy(t *testing.T) {
+	server := listenSSH(t)
+	// "user:secret" in base64.
+	proxy := listenHTTPConnect(t, "Basic dXNlcjpzZWNyZXQ=")
+
+	for _, tt := range []struct {
+		name     string
+		password string
+		wantErr  string
+	}{
+		{name: "ok", password: "secret"},
+		{name: "refused", password: "wrong", wantErr: "407"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &ClientConfig{
+				User:            "user",
+				HostKeyCallback: InsecureIgnoreHostKey(),
+				ProxyDialer: &HTTPConnectProxy{
+					Addr:     proxy.Addr().String(),
+					Username: "user",
+					Password: tt.password,
+				},
+			}
+			client, err := Dial("tcp", server.Addr().String(), config)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Dial: %v", err)
+			}
+			client.Close()
+		})
+	}
+}
+
+func TestHTTPConnectProxyInvalidAddr(t *testing.T) {
+	d := &recordingDialer{}
+	p := &HTTPConnectProxy{Addr: "127.0.0.1:1", Forward: d}
+	for _, addr := range []string{
+		"server.org:22\r\nX-Injected: 1",
+		"server.org:22 HTTP/1.0",
+		"server.org\n:22",
+		"server.org",
+		":22",
+	} {
+		if _, err := p.Dial("tcp", addr); err == nil || !strings.Contains(err.Error(), "invalid HTTP CONNECT address") {
+			t.Errorf("Dial(%q): got error %v, want an invalid address", addr, err)
+		}
+	}
+	if len(d.addrs) != 0 {
+		t.Errorf("proxy was dialed for invalid addresses: %q", d.addrs)
+	}
+}
+
+type recordingDialer struct {
+	addrs []string
+}
+
+func (d *recordingDialer) Dial(network, addr string) (net.Conn, error) {
+	d.addrs = append(d.addrs, addr)
+	return net.Dial(network, addr)
+}
+
+func TestDialProxyDialer(t *testing.T) {
+	server := listenSSH(t)
+	d := &recordingDialer{}
+	config := &ClientConfig{
+		User:            "user",
+		HostKeyCallback: InsecureIgnoreHostKey(),
+		ProxyDialer:     d,
+	}
+	client, err := Dial("tcp", server.Addr().String(), config)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	client.Close()
+	if len(d.addrs) != 1 || d.addrs[0] != server.Addr().String() {
+		t.Errorf("got dialed addresses %q, want [%s]", d.addrs, server.Addr())
+	}
+}
@@ -0,0 +1,392 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/dsa"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// This file parses the private key files of PuTTY, described in appendix C
+// of the PuTTY manual.
+
+// maxPuTTYArgon2Memory and maxPuTTYArgon2Passes bound the cost of the key
+// derivation of a PPK file, so that a crafted file can't make decrypting it
+// use all the memory or time. puttygen uses 8 MiB and as many passes as
+// take a tenth of a second; the limits are well above that.
+const (
+	maxPuTTYArgon2Memory = 1 << 18 // in KiB
+	maxPuTTYArgon2Passes = 1 << 8
+)
+
+// ParsePuTTYPrivateKey returns a Signer from a private key file in the PPK
+// format of PuTTY, version 2 or 3. It supports RSA, DSA, ECDSA and Ed25519
+// keys. If the key is encrypted, it returns a *PassphraseMissingError
+// holding the public key.
+func ParsePuTTYPrivateKey(data []byte) (Signer, error) {
+	return parsePuTTYPrivateKey(data, nil, false)
+}
+
+// ParsePuTTYPrivateKeyWithPassphrase returns a Signer from a PPK private key
+// file encrypted with passphrase. If the passphrase is wrong, it returns
+// x509.IncorrectPasswordError.
+func ParsePuTTYPrivateKeyWithPassphrase(data, passphrase []byte) (Signer, error) {
+	return parsePuTTYPrivateKey(data, passphrase, true)
+}
+
+// puttyKeyFile holds the fields of a PPK file.
+type puttyKeyFile struct {
+	version    int
+	algorithm  string
+	encryption string
+	comment    string
+	public     []byte
+	private    []byte
+	mac        []byte
+
+	// Argon2 parameters, used by encrypted version 3 files.
+	kdf                       string
+	memory, passes, parallels uint32
+	salt                      []byte
+}
+
+// puttyLines reads the "Key: value" lines of a PPK file in order.
+type puttyLines struct {
+	lines []string
+}
+
+func (l *puttyLines) next() (string, error) {
+	if len(l.lines) == 0 {
+		return "", errors.New("ssh: truncated PuTTY key file")
+	}
+	line := strings.TrimRight(l.lines[0], "\r")
+	l.lines = l.lines[1:]
+	return line, nil
+}
+
+// field reads the value of the next line, which must be the header name.
+func (l *puttyLines) field(name string) (string, error) {
+	line, err := l.next()
+	if err != nil {
+		return "", err
+	}
+	value, ok := strings.CutPrefix(line, name+": ")
+	if !ok {
+		return "", fmt.Errorf("ssh: PuTTY key file has no %s header", name)
+	}
+	return value, nil
+}
+
+func (l *puttyLines) uint32Field(name string) (uint32, error) {
+	value, err := l.field(name)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("ssh: invalid %s in PuTTY key file: %q", name, value)
+	}
+	return uint32(n), nil
+}
+
+// blob reads a count header followed by that many lines of base64.
+func (l *puttyLines) blob(name string) ([]byte, error) {
+	n, err := l.uint32Field(name)
+	if err != nil {
+		return nil, err
+	}
+	if int(n) > len(l.lines) {
+		return nil, errors.New("ssh: truncated PuTTY key file")
+	}
+	var b strings.Builder
+	for i := uint32(0); i < n; i++ {
+		line, _ := l.next()
+		b.WriteString(line)
+	}
+	out, err := base64.StdEncoding.DecodeString(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("ssh: invalid %s in PuTTY key file: %v", name, err)
+	}
+	return out, nil
+}
+
+func parsePuTTYKeyFile(data []byte) (*puttyKeyFile, error) {
+	l := &puttyLines{lines: strings.Split(string(data), "\n")}
+	var f puttyKeyFile
+
+	line, err := l.next()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case strings.HasPrefix(line, "PuTTY-User-Key-File-2: "):
+		f.version = 2
+	case strings.HasPrefix(line, "PuTTY-User-Key-File-3: "):
+		f.version = 3
+	case strings.HasPrefix(line, "PuTTY-User-Key-File-"):
+		return nil, errors.New("ssh: unsupported PuTTY key file version")
+	default:
+		return nil, errors.New("ssh: not a PuTTY key file")
+	}
+	f.algorithm = line[len("PuTTY-User-Key-File-3: "):]
+
+	if f.encryption, err = l.field("Encryption"); err != nil {
+		return nil, err
+	}
+	if f.encryption != "none" && f.encryption != "aes256-cbc" {
+		return nil, fmt.Errorf("ssh: unsupported PuTTY key file encryption %q", f.encryption)
+	}
+	if f.comment, err = l.field("Comment"); err != nil {
+		return nil, err
+	}
+	if f.public, err = l.blob("Public-Lines"); err != nil {
+		return nil, err
+	}
+	if f.version == 3 && f.encryption != "none" {
+		if f.kdf, err = l.field("Key-Derivation"); err != nil {
+			return nil, err
+		}
+		if f.memory, err = l.uint32Field("Argon2-Memory"); err != nil {
+			return nil, err
+		}
+		if f.memory > maxPuTTYArgon2Memory {
+			return nil, errors.New("ssh: Argon2-Memory of PuTTY key file too large")
+		}
+		if f.passes, err = l.uint32Field("Argon2-Passes"); err != nil {
+			return nil, err
+		}
+		if f.passes > maxPuTTYArgon2Passes {
+			return nil, errors.New("ssh: Argon2-Passes of PuTTY key file too large")
+		}
+		if f.parallels, err = l.uint32Field("Argon2-Parallelism"); err != nil {
+			return nil, err
+		}
+		salt, err := l.field("Argon2-Salt")
+		if err != nil {
+			return nil, err
+		}
+		if f.salt, err = hex.DecodeString(salt); err != nil {
+			return nil, errors.New("ssh: invalid Argon2-Salt in PuTTY key file")
+		}
+	}
+	if f.private, err = l.blob("Private-Lines"); err != nil {
+		return nil, err
+	}
+	mac, err := l.field("Private-MAC")
+	if err != nil {
+		return nil, err
+	}
+	if f.mac, err = hex.DecodeString(mac); err != nil {
+		return nil, errors.New("ssh: invalid Private-MAC in PuTTY key file")
+	}
+	return &f, nil
+}
+
+// keys derives the encryption key, IV and MAC key of f from passphrase.
+func (f *puttyKeyFile) keys(passphrase []byte) (key, iv, macKey []byte, err error) {
+	if f.version == 2 {
+		if f.encryption != "none" {
+			var k []byte
+			for i := byte(0); i < 2; i++ {
+				h := sha1.New()
+				h.Write([]byte{0, 0, 0, i})
+				h.Write(passphrase)
+				k = h.Sum(k)
+			}
+			key, iv = k[:32], make([]byte, aes.BlockSize)
+		}
+		h := sha1.New()
+		h.Write([]byte("putty-private-key-file-mac-key"))
+		if f.encryption != "none" {
+			h.Write(passphrase)
+		}
+		return key, iv, h.Sum(nil), nil
+	}
+
+	if f.encryption == "none" {
+		return nil, nil, nil, nil
+	}
+	if f.parallels == 0 || f.parallels > 255 || f.passes == 0 {
+		return nil, nil, nil, errors.New("ssh: invalid Argon2 parameters in PuTTY key file")
+	}
+	var k []byte
+	switch f.kdf {
+	case "Argon2id":
+		k = argon2.IDKey(passphrase, f.salt, f.passes, f.memory, uint8(f.parallels), 80)
+	case "Argon2i":
+		k = argon2.Key(passphrase, f.salt, f.passes, f.memory, uint8(f.parallels), 80)
+	default:
+		return nil, nil, nil, fmt.Errorf("ssh: unsupported PuTTY key derivation %q", f.kdf)
+	}
+	return k[:32], k[32:48], k[48:], nil
+}
+
+func parsePuTTYPrivateKey(data, passphrase []byte, withPassphrase bool) (Signer, error) {
+	f, err := parsePuTTYKeyFile(data)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := ParsePublicKey(f.public)
+	if err != nil {
+		return nil, err
+	}
+	if pub.Type() != f.algorithm {
+		return nil, fmt.Errorf("ssh: PuTTY key file of type %s holds a %s key", f.algorithm, pub.Type())
+	}
+	encrypted := f.encryption != "none"
+	if encrypted && !withPassphrase {
+		return nil, &PassphraseMissingError{PublicKey: pub}
+	}
+	if !encrypted && withPassphrase {
+		return nil, errors.New("ssh: not an encrypted key")
+	}
+
+	key, iv, macKey, err := f.keys(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	private := f.private
+	if encrypted {
+		if len(private)%aes.BlockSize != 0 {
+			return nil, errors.New("ssh: PuTTY private key is not a multiple of the block size")
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		private = make([]byte, len(f.private))
+		cipher.NewCBCDecrypter(block, iv).CryptBlocks(private, f.private)
+	}
+
+	var mac hash.Hash
+	if f.version == 2 {
+		mac = hmac.New(sha1.New, macKey)
+	} else {
+		mac = hmac.New(sha256.New, macKey)
+	}
+	for _, s := range [][]byte{[]byte(f.algorithm), []byte(f.encryption), []byte(f.comment), f.public, private} {
+		writeString(mac, s)
+	}
+	if subtle.ConstantTimeCompare(mac.Sum(nil), f.mac) != 1 {
+		if encrypted {
+			return nil, x509.IncorrectPasswordError
+		}
+		return nil, errors.New("ssh: PuTTY key file MAC mismatch")
+	}
+
+	return puttySigner(pub, private)
+}
+
+// puttySigner returns a Signer for the public key pub and the private key
+// blob of a PPK file, which may be followed by padding.
+func puttySigner(pub PublicKey, private []byte) (Signer, error) {
+	switch pub := pub.(type) {
+	case *rsaPublicKey:
+		var k struct {
+			D, P, Q, Iqmp *big.Int
+			Rest          []byte `ssh:"rest"`
+		}
+		if err := Unmarshal(private, &k); err != nil {
+			return nil, err
+		}
+		key := &rsa.PrivateKey{
+			PublicKey: rsa.PublicKey(*pub),
+			D:         k.D,
+			Primes:    []*big.Int{k.P, k.Q},
+		}
+		if err := key.Validate(); err != nil {
+			return nil, err
+		}
+		key.Precompute()
+		return NewSignerFromKey(key)
+	case *dsaPublicKey:
+		var k struct {
+			X    *big.Int
+			Rest []byte `ssh:"rest"`
+		}
+		if err := Unmarshal(private, &k); err != nil {
+			return nil, err
+		}
+		key := &dsa.PrivateKey{PublicKey: dsa.PublicKey(*pub), X: k.X}
+		if y := new(big.Int).Exp(key.G, key.X, key.P); y.Cmp(key.Y) != 0 {
+			return nil, errors.New("ssh: public key does not match private key")
+		}
+		return NewSignerFromKey(key)
+	case *ecdsaPublicKey:
+		var k struct {
+			D    *big.Int
+			Rest []byte `ssh:"rest"`
+		}
+		if err := Unmarshal(private, &k); err != nil {
+			return nil, err
+		}
+		if k.D.Sign() <= 0 || k.D.Cmp(pub.Curve.Params().N) >= 0 {
+			return nil, errors.New("ssh: scalar is out of range")
+		}
+		x, y := pub.Curve.ScalarBaseMult(k.D.Bytes())
+		if x.Cmp(pub.X) != 0 || y.Cmp(pub.Y) != 0 {
+			return nil, errors.New("ssh: public key does not match private key")
+		}
+		return NewSignerFromKey(&ecdsa.PrivateKey{PublicKey: ecdsa.PublicKey(*pub), D: k.D})
+	case ed25519PublicKey:
+		seed, err := puttyEdDSASeed(private, ed25519.SeedSize)
+		if err != nil {
+			return nil, err
+		}
+		signer, err := NewSignerFromKey(ed25519.NewKeyFromSeed(seed))
+		if err != nil {
+			return nil, err
+		}
+		if err := checkPuTTYPublicKey(signer, pub); err != nil {
+			return nil, err
+		}
+		return signer, nil
+	default:
+		return nil, fmt.Errorf("ssh: unsupported PuTTY key type %s", pub.Type())
+	}
+}
+
+// puttyEdDSASeed returns the private key of an EdDSA key, which PuTTY stores
+// as the seed of RFC 8032.
+func puttyEdDSASeed(private []byte, size int) ([]byte, error) {
+	var k struct {
+		Seed []byte
+		Rest []byte `ssh:"rest"`
+	}
+	if err := Unmarshal(private, &k); err != nil {
+		return nil, err
+	}
+	if len(k.Seed) != size {
+		return nil, errors.New("ssh: private key unexpected length")
+	}
+	return k.Seed, nil
+}
+
+func checkPuTTYPublicKey(signer Signer, pub PublicKey) error {
+	if !bytes.Equal(signer.PublicKey().Marshal(), pub.Marshal()) {
+		return errors.New("ssh: public key does not match private key")
+	}
+	return nil
+}
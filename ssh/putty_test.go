@@ -0,0 +1,201 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/dsa"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// puttyPrivateBlob encodes key as in the private part of a PPK file.
+func puttyPrivateBlob(t *testing.T, key interface{}) []byte {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return Marshal(struct{ D, P, Q, Iqmp *big.Int }{k.D, k.Primes[0], k.Primes[1], k.Precomputed.Qinv})
+	case *dsa.PrivateKey:
+		return Marshal(struct{ X *big.Int }{k.X})
+	case *ecdsa.PrivateKey:
+		return Marshal(struct{ D *big.Int }{k.D})
+	case *ed25519.PrivateKey:
+		return Marshal(struct{ Seed []byte }{k.Seed()})
+	}
+	t.Fatalf("unsupported key type %T", key)
+	return nil
+}
+
+// base64Lines encodes b in lines of 64 characters, like PuTTY.
+func base64Lines(b []byte) (int, string) {
+	s := base64.StdEncoding.EncodeToString(b)
+	var lines []string
+	for len(s) > 64 {
+		lines = append(lines, s[:64])
+		s = s[64:]
+	}
+	lines = append(lines, s)
+	return len(lines), strings.Join(lines, "\n")
+}
+
+// marshalPuTTYKey writes a PPK file following appendix C of the PuTTY
+// manual. An empty passphrase leaves the key unencrypted.
+func marshalPuTTYKey(t *testing.T, version int, key interface{}, comment, passphrase string) []byte {
+	signer, err := NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	algorithm := signer.PublicKey().Type()
+	public := signer.PublicKey().Marshal()
+	private := puttyPrivateBlob(t, key)
+
+	encryption := "none"
+	var kdfHeaders string
+	var cipherKey, iv, macKey []byte
+	if passphrase != "" {
+		encryption = "aes256-cbc"
+		if pad := len(private) % aes.BlockSize; pad != 0 {
+			private = append(private, make([]byte, aes.BlockSize-pad)...)
+		}
+	}
+	switch {
+	case version == 2:
+		h := sha1.Sum([]byte("putty-private-key-file-mac-key" + passphrase))
+		macKey = h[:]
+		k0 := sha1.Sum(append([]byte{0, 0, 0, 0}, passphrase...))
+		k1 := sha1.Sum(append([]byte{0, 0, 0, 1}, passphrase...))
+		cipherKey = append(k0[:], k1[:12]...)
+		iv = make([]byte, aes.BlockSize)
+	case passphrase != "":
+		salt := []byte("0123456789abcdef")
+		k := argon2.IDKey([]byte(passphrase), salt, 1, 64, 1, 80)
+		cipherKey, iv, macKey = k[:32], k[32:48], k[48:]
+		kdfHeaders = fmt.Sprintf("Key-Derivation: Argon2id\nArgon2-Memory: 64\nArgon2-Passes: 1\nArgon2-Parallelism: 1\nArgon2-Salt: %x\n", salt)
+	}
+
+	newMAC := sha256.New
+	if version == 2 {
+		newMAC = sha1.New
+	}
+	mac := hmac.New(newMAC, macKey)
+	mac.Write(Marshal(struct{ A, E, C string }{algorithm, encryption, comment}))
+	mac.Write(Marshal(struct{ Pub, Priv []byte }{public, private}))
+
+	if passphrase != "" {
+		block, err := aes.NewCipher(cipherKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(private, private)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "PuTTY-User-Key-File-%d: %s\r\n", version, algorithm)
+	fmt.Fprintf(&b, "Encryption: %s\r\nComment: %s\r\n", encryption, comment)
+	n, lines := base64Lines(public)
+	fmt.Fprintf(&b, "Public-Lines: %d\n%s\n", n, lines)
+	b.WriteString(kdfHeaders)
+	n, lines = base64Lines(private)
+	fmt.Fprintf(&b, "Private-Lines: %d\n%s\n", n, lines)
+	fmt.Fprintf(&b, "Private-MAC: %x\n", mac.Sum(nil))
+	return []byte(b.String())
+}
+
+func TestParsePuTTYPrivateKey(t *testing.T) {
+	for _, name := range []string{"rsa", "dsa", "ecdsa", "ed25519"} {
+		for _, version := range []int{2, 3} {
+			for _, passphrase := range []string{"", "secret"} {
+				t.Run(fmt.Sprintf("%s/v%d/%q", name, version, passphrase), func(t *testing.T) {
+					data := marshalPuTTYKey(t, version, testPrivateKeys[name], "test key", passphrase)
+					want := testSigners[name].PublicKey().Marshal()
+
+					var signer Signer
+					var err error
+					if passphrase == "" {
+						signer, err = ParsePuTTYPrivateKey(data)
+					} else {
+						_, err = ParsePuTTYPrivateKey(data)
+						var missing *PassphraseMissingError
+						if !errors.As(err, &missing) || !bytes.Equal(missing.PublicKey.Marshal(), want) {
+							t.Errorf("parsing without a passphrase: got %v, want a PassphraseMissingError with the public key", err)
+						}
+						if _, err := ParsePuTTYPrivateKeyWithPassphrase(data, []byte("wrong")); err != x509.IncorrectPasswordError {
+							t.Errorf("parsing with a wrong passphrase: got %v, want %v", err, x509.IncorrectPasswordError)
+						}
+						signer, err = ParsePuTTYPrivateKeyWithPassphrase(data, []byte(passphrase))
+					}
+					if err != nil {
+						t.Fatalf("parsing: %v", err)
+					}
+					if got := signer.PublicKey().Marshal(); !bytes.Equal(got, want) {
+						t.Error("parsed key has the wrong public key")
+					}
+					sig, err := signer.Sign(rand.Reader, []byte("data"))
+					if err != nil {
+						t.Fatalf("Sign: %v", err)
+					}
+					if err := testSigners[name].PublicKey().Verify([]byte("data"), sig); err != nil {
+						t.Errorf("Verify: %v", err)
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestParsePuTTYPrivateKeyMAC(t *testing.T) {
+	data := marshalPuTTYKey(t, 3, testPrivateKeys["ed25519"], "test key", "")
+	tampered := bytes.Replace(data, []byte("Comment: test key"), []byte("Comment: evil key"), 1)
+	if _, err := ParsePuTTYPrivateKey(tampered); err == nil {
+		t.Error("parsed a key file with a modified comment")
+	}
+
+	wrongType := bytes.Replace(data, []byte(": ssh-ed25519"), []byte(": ssh-rsa"), 1)
+	if _, err := ParsePuTTYPrivateKey(wrongType); err == nil {
+		t.Error("parsed a key file with a mismatched algorithm")
+	}
+
+	if _, err := ParsePuTTYPrivateKey(data[:len(data)/2]); err == nil {
+		t.Error("parsed a truncated key file")
+	}
+}
+
+func TestParsePuTTYPrivateKeyArgon2Limits(t *testing.T) {
+	data := marshalPuTTYKey(t, 3, testPrivateKeys["ed25519"], "test key", "secret")
+	for _, field := range []string{"Argon2-Memory: 64", "Argon2-Passes: 1"} {
+		name := field[:strings.Index(field, ":")]
+		costly := bytes.Replace(data, []byte(field), []byte(name+": 4294967295"), 1)
+		if _, err := ParsePuTTYPrivateKeyWithPassphrase(costly, []byte("secret")); err == nil || !strings.Contains(err.Error(), name) {
+			t.Errorf("%s: got error %v, want one about %s", name, err, name)
+		}
+	}
+}
+
+func TestPuTTYSignerMismatch(t *testing.T) {
+	private := puttyPrivateBlob(t, testPrivateKeys["ed25519"])
+	other, err := NewSignerFromKey(ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := puttySigner(other.PublicKey(), private)
+	if err == nil || signer != nil {
+		t.Errorf("puttySigner with the wrong public key = %v, %v, want nil and an error", signer, err)
+	}
+}
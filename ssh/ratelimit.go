@@ -0,0 +1,153 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket limiting the number of bytes per second
+// that pass through the channels it is attached to with RateLimitChannel.
+// Attaching a RateLimiter to a single channel limits that channel; sharing
+// it between all the channels of a connection limits the connection as a
+// whole. It is safe for concurrent use.
+type RateLimiter struct {
+	rate  float64 // bytes per second
+	burst int
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter returns a RateLimiter allowing bytesPerSecond bytes per
+// second on average, and bursts of up to burst bytes. If burst is not
+// positive, bytesPerSecond is used, which allows bursts of one second
+// worth of data.
+func NewRateLimiter(bytesPerSecond, burst int) *RateLimiter {
+	if bytesPerSecond <= 0 {
+		panic("ssh: rate must be positive")
+	}
+	if burst <= 0 {
+		burst = bytesPerSecond
+	}
+	return &RateLimiter{
+		rate:   float64(bytesPerSecond),
+		burst:  burst,
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// take takes n tokens, at most the burst size, from the bucket, and
+// returns how long the caller must wait before using them. Tokens may be
+// taken in advance, so that concurrent callers are served in order.
+func (l *RateLimiter) take(n int) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > float64(l.burst) {
+		l.tokens = float64(l.burst)
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+func (l *RateLimiter) wait(n int) {
+	if d := l.take(n); d > 0 {
+		time.Sleep(d)
+	}
+}
+
+// readLimited reads from r at most l.burst bytes at a time, waiting for
+// the bytes read to be allowed by l.
+func readLimited(r io.Reader, l *RateLimiter, data []byte) (int, error) {
+	if l == nil {
+		return r.Read(data)
+	}
+	if len(data) > l.burst {
+		data = data[:l.burst]
+	}
+	n, err := r.Read(data)
+	if n > 0 {
+		l.wait(n)
+	}
+	return n, err
+}
+
+// writeLimited writes data to w in chunks of at most l.burst bytes,
+// waiting for each chunk to be allowed by l before writing it.
+func writeLimited(w io.Writer, l *RateLimiter, data []byte) (int, error) {
+	if l == nil {
+		return w.Write(data)
+	}
+	written := 0
+	for len(data) > 0 {
+		chunk := data
+		if len(chunk) > l.burst {
+			chunk = chunk[:l.burst]
+		}
+		l.wait(len(chunk))
+		n, err := w.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		data = data[n:]
+	}
+	return written, nil
+}
+
+// rateLimitedChannel is a Channel whose data, including extended data, is
+// limited by a RateLimiter in each direction.
+type rateLimitedChannel struct {
+	wrappedChannel
+	read, write *RateLimiter
+}
+
+// RateLimitChannel returns a Channel backed by ch whose reads are limited
+// by read and whose writes are limited by write, either of which may be nil
+// to leave that direction unlimited. Data read from and written to the
+// stderr stream of the channel count towards the same limits. Since reads
+// are throttled, the channel window fills up and the peer's writes are
+// slowed down too. The deadlines and CloseRead of the returned channel are
+// effective if ch implements ChannelWithDeadlines and ChannelWithCloseRead,
+// which is the case for all channels created by this package; a deadline does
+// not interrupt the wait for the limiter.
+func RateLimitChannel(ch Channel, read, write *RateLimiter) Channel {
+	return &rateLimitedChannel{wrappedChannel: wrappedChannel{ch}, read: read, write: write}
+}
+
+func (c *rateLimitedChannel) Read(data []byte) (int, error) {
+	return readLimited(c.Channel, c.read, data)
+}
+
+func (c *rateLimitedChannel) Write(data []byte) (int, error) {
+	return writeLimited(c.Channel, c.write, data)
+}
+
+func (c *rateLimitedChannel) Stderr() io.ReadWriter {
+	return &rateLimitedReadWriter{c.Channel.Stderr(), c.read, c.write}
+}
+
+type rateLimitedReadWriter struct {
+	rw          io.ReadWriter
+	read, write *RateLimiter
+}
+
+func (rw *rateLimitedReadWriter) Read(data []byte) (int, error) {
+	return readLimited(rw.rw, rw.read, data)
+}
+
+func (rw *rateLimitedReadWriter) Write(data []byte) (int, error) {
+	return writeLimited(rw.rw, rw.write, data)
+}
@@ -0,0 +1,58 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterTake(t *testing.T) {
+	l := NewRateLimiter(1000, 100)
+	if d := l.take(100); d != 0 {
+		t.Errorf("take within burst: got wait %v, want 0", d)
+	}
+	// The bucket is empty; 50 bytes take 50ms at 1000 bytes per second.
+	if d := l.take(50); d < 40*time.Millisecond || d > 50*time.Millisecond {
+		t.Errorf("take from empty bucket: got wait %v, want about 50ms", d)
+	}
+	// Tokens taken in advance delay the next callers.
+	if d := l.take(50); d < 90*time.Millisecond || d > 100*time.Millisecond {
+		t.Errorf("take after advance: got wait %v, want about 100ms", d)
+	}
+}
+
+func TestRateLimitChannel(t *testing.T) {
+	client, server, mux := channelPair(t)
+	defer server.Close()
+	defer client.Close()
+	defer mux.Close()
+
+	// 2000 bytes at 10000 bytes per second, with a 500 byte burst, take at
+	// least 150ms.
+	write := NewRateLimiter(10000, 500)
+	limited := RateLimitChannel(client, nil, write)
+	data := bytes.Repeat([]byte("x"), 2000)
+	start := time.Now()
+	go func() {
+		limited.Write(data)
+		limited.CloseWrite()
+	}()
+	got, err := io.ReadAll(server)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("got %d bytes, want %d", len(got), len(data))
+	}
+	if d := time.Since(start); d < 150*time.Millisecond {
+		t.Errorf("transfer took %v, want at least 150ms", d)
+	}
+	if _, ok := limited.(ChannelWithDeadlines); !ok {
+		t.Error("rate limited channel does not implement ChannelWithDeadlines")
+	}
+}
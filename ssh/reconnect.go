@@ -0,0 +1,377 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// ReconnectState is the state of a ReconnectingClient.
+type ReconnectState int
+
+const (
+	// ReconnectConnecting means that a connection is being dialed.
+	ReconnectConnecting ReconnectState = iota
+	// ReconnectConnected means that the client is connected.
+	ReconnectConnected
+	// ReconnectDisconnected means that the connection was lost, or could
+	// not be established, and that a new one will be dialed after a
+	// delay.
+	ReconnectDisconnected
+	// ReconnectClosed means that Close was called.
+	ReconnectClosed
+)
+
+func (s ReconnectState) String() string {
+	switch s {
+	case ReconnectConnecting:
+		return "connecting"
+	case ReconnectConnected:
+		return "connected"
+	case ReconnectDisconnected:
+		return "disconnected"
+	case ReconnectClosed:
+		return "closed"
+	}
+	return fmt.Sprintf("ReconnectState(%d)", int(s))
+}
+
+// ReconnectConfig configures a ReconnectingClient.
+type ReconnectConfig struct {
+	// Dial establishes a new connection, typically by calling
+	// DialContext, and returns it once the handshake succeeded. It is
+	// called again after the connection is lost, or if it fails. It must
+	// return once ctx is done.
+	Dial func(ctx context.Context) (*Client, error)
+
+	// MinBackoff and MaxBackoff bound the delay before dialing again
+	// after a failed attempt, which doubles with every consecutive
+	// failure. If zero, 1 second and 1 minute are used. The first attempt
+	// after the loss of a connection that lasted at least MinBackoff is
+	// made without delay, and the delay starts again from MinBackoff;
+	// connections lost sooner count as failed attempts, so that a server
+	// that drops connections right after the handshake is not dialed in a
+	// loop.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// StateCallback, if not nil, is called whenever the state of the
+	// client changes. err is the reason of the change to
+	// ReconnectDisconnected, which may be nil if the server closed the
+	// connection cleanly. Calls are not made concurrently, and block
+	// reconnecting until they return.
+	StateCallback func(state ReconnectState, err error)
+}
+
+// ReconnectingClient maintains a connection to an SSH server, dialing it
+// again whenever it is lost, and re-establishing the remote listeners
+// created with its Listen method, which is convenient for long-running
+// tunnels.
+type ReconnectingClient struct {
+	config ReconnectConfig
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	closeOnce sync.Once
+
+	mu        sync.Mutex
+	state     ReconnectState
+	client    *Client
+	ready     chan struct{} // closed once client is set
+	listeners map[*reconnectListener]bool
+}
+
+// NewReconnectingClient returns a ReconnectingClient that starts dialing in
+// the background with config.Dial.
+func NewReconnectingClient(config *ReconnectConfig) *ReconnectingClient {
+	rc := &ReconnectingClient{
+		config:    *config,
+		done:      make(chan struct{}),
+		ready:     make(chan struct{}),
+		listeners: make(map[*reconnectListener]bool),
+	}
+	if rc.config.MinBackoff <= 0 {
+		rc.config.MinBackoff = time.Second
+	}
+	if rc.config.MaxBackoff <= 0 {
+		rc.config.MaxBackoff = time.Minute
+	}
+	if rc.config.MaxBackoff < rc.config.MinBackoff {
+		rc.config.MaxBackoff = rc.config.MinBackoff
+	}
+	rc.ctx, rc.cancel = context.WithCancel(context.Background())
+	go rc.run()
+	return rc
+}
+
+func (rc *ReconnectingClient) run() {
+	defer close(rc.done)
+	backoff := rc.config.MinBackoff
+	// wait waits for backoff after a failed attempt, and reports whether
+	// to dial again.
+	wait := func() bool {
+		select {
+		case <-time.After(backoff):
+		case <-rc.ctx.Done():
+			return false
+		}
+		backoff *= 2
+		if backoff > rc.config.MaxBackoff {
+			backoff = rc.config.MaxBackoff
+		}
+		return true
+	}
+	for {
+		rc.setState(ReconnectConnecting, nil)
+		client, err := rc.config.Dial(rc.ctx)
+		if err != nil {
+			if rc.ctx.Err() != nil {
+				return
+			}
+			rc.setState(ReconnectDisconnected, err)
+			if !wait() {
+				return
+			}
+			continue
+		}
+		connected := time.Now()
+
+		rc.mu.Lock()
+		// Close cancels the context before looking for the client to
+		// close.
+		if rc.ctx.Err() != nil {
+			rc.mu.Unlock()
+			client.Close()
+			return
+		}
+		rc.client = client
+		close(rc.ready)
+		var listeners []*reconnectListener
+		for l := range rc.listeners {
+			listeners = append(listeners, l)
+		}
+		rc.mu.Unlock()
+		for _, l := range listeners {
+			l.attach(client)
+		}
+		rc.setState(ReconnectConnected, nil)
+
+		err = client.Wait()
+
+		rc.mu.Lock()
+		rc.client = nil
+		rc.ready = make(chan struct{})
+		rc.mu.Unlock()
+		if rc.ctx.Err() != nil {
+			return
+		}
+		rc.setState(ReconnectDisconnected, err)
+		if time.Since(connected) >= rc.config.MinBackoff {
+			backoff = rc.config.MinBackoff
+		} else if !wait() {
+			return
+		}
+	}
+}
+
+func (rc *ReconnectingClient) setState(state ReconnectState, err error) {
+	rc.mu.Lock()
+	rc.state = state
+	rc.mu.Unlock()
+	if rc.config.StateCallback != nil {
+		rc.config.StateCallback(state, err)
+	}
+}
+
+// State returns the current state of the client.
+func (rc *ReconnectingClient) State() ReconnectState {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return rc.state
+}
+
+// errReconnectClosed is returned by the methods of a closed
+// ReconnectingClient.
+var errReconnectClosed = errors.New("ssh: reconnecting client is closed")
+
+// Client returns the current connection, waiting until one is established
+// or ctx is done. The returned Client must not be closed by the caller; it
+// fails once the connection is lost, after which Client returns the next
+// one.
+func (rc *ReconnectingClient) Client(ctx context.Context) (*Client, error) {
+	for {
+		rc.mu.Lock()
+		client, ready := rc.client, rc.ready
+		rc.mu.Unlock()
+		if client != nil {
+			return client, nil
+		}
+		select {
+		case <-ready:
+		case <-rc.done:
+			return nil, errReconnectClosed
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Listen is like Client.Listen, but the returned listener is re-established
+// on every new connection, so that Accept keeps returning the connections
+// forwarded by the server across reconnections. addr should have a fixed
+// port, as the server may allocate a different one each time otherwise. If
+// the client is connected, an error to establish the listener is returned;
+// later errors are ignored, and the listener tried again on the next
+// connection.
+func (rc *ReconnectingClient) Listen(network, addr string) (net.Listener, error) {
+	l := &reconnectListener{
+		rc:      rc,
+		network: network,
+		addr:    addr,
+		conns:   make(chan net.Conn),
+		closed:  make(chan struct{}),
+	}
+	rc.mu.Lock()
+	select {
+	case <-rc.done:
+		rc.mu.Unlock()
+		return nil, errReconnectClosed
+	default:
+	}
+	rc.listeners[l] = true
+	client := rc.client
+	rc.mu.Unlock()
+	if client != nil {
+		if err := l.attach(client); err != nil {
+			l.Close()
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// Close closes the current connection and the listeners, and stops
+// reconnecting.
+func (rc *ReconnectingClient) Close() error {
+	rc.closeOnce.Do(func() {
+		rc.cancel()
+		rc.mu.Lock()
+		client := rc.client
+		rc.mu.Unlock()
+		if client != nil {
+			client.Close()
+		}
+		<-rc.done
+
+		rc.mu.Lock()
+		var listeners []*reconnectListener
+		for l := range rc.listeners {
+			listeners = append(listeners, l)
+		}
+		rc.mu.Unlock()
+		for _, l := range listeners {
+			l.Close()
+		}
+		rc.setState(ReconnectClosed, nil)
+	})
+	return nil
+}
+
+// reconnectListener is a listener of a ReconnectingClient, which accepts
+// connections from the listener of the current connection.
+type reconnectListener struct {
+	rc            *ReconnectingClient
+	network, addr string
+	conns         chan net.Conn
+	closed        chan struct{}
+	closeOnce     sync.Once
+
+	mu    sync.Mutex
+	inner net.Listener
+}
+
+// attach establishes the listener on client, and forwards the connections
+// it accepts until it fails.
+func (l *reconnectListener) attach(client *Client) error {
+	inner, err := client.Listen(l.network, l.addr)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	select {
+	case <-l.closed:
+		l.mu.Unlock()
+		inner.Close()
+		return nil
+	default:
+	}
+	l.inner = inner
+	l.mu.Unlock()
+
+	go func() {
+		for {
+			conn, err := inner.Accept()
+			if err != nil {
+				return
+			}
+			select {
+			case l.conns <- conn:
+			case <-l.closed:
+				conn.Close()
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+func (l *reconnectListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *reconnectListener) Close() error {
+	l.closeOnce.Do(func() {
+		close(l.closed)
+		l.rc.mu.Lock()
+		delete(l.rc.listeners, l)
+		l.rc.mu.Unlock()
+		l.mu.Lock()
+		if l.inner != nil {
+			l.inner.Close()
+		}
+		l.mu.Unlock()
+	})
+	return nil
+}
+
+// Addr returns the address of the listener of the current or last
+// connection, or the requested one before the first.
+func (l *reconnectListener) Addr() net.Addr {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.inner != nil {
+		return l.inner.Addr()
+	}
+	return listenAddr{l.network, l.addr}
+}
+
+// listenAddr is the net.Addr of a listener that is not established yet.
+type listenAddr struct {
+	network, addr string
+}
+
+func (a listenAddr) Network() string { return a.network }
+func (a listenAddr) String() string  { return a.addr }
@@ -0,0 +1,232 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// listenForwardingSSH serves SSH on a local TCP listener, accepting
+// tcpip-forward requests. The server side of each connection is sent on the
+// returned channel once it has accepted a forward.
+func listenForwardingSSH(t *testing.T) (net.Listener, <-chan *ServerConn) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	config := &ServerConfig{NoClientAuth: true}
+	config.AddHostKey(testSigners["rsa"])
+	forwarded := make(chan *ServerConn, 10)
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				conn, chans, reqs, err := NewServerConn(c, config)
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				go func() {
+					for newCh := range chans {
+						newCh.Reject(Prohibited, "")
+					}
+				}()
+				for r := range reqs {
+					r.Reply(r.Type == "tcpip-forward", nil)
+					if r.Type == "tcpip-forward" {
+						forwarded <- conn
+					}
+				}
+			}()
+		}
+	}()
+	return l, forwarded
+}
+
+// checkForward opens a forwarded-tcpip channel for 127.0.0.1:2222 from
+// conn and checks that it is accepted from l.
+func checkForward(t *testing.T, conn *ServerConn, l net.Listener) {
+	t.Helper()
+	payload := Marshal(&forwardedTCPPayload{
+		Addr: "127.0.0.1", Port: 2222, OriginAddr: "10.0.0.1", OriginPort: 4321,
+	})
+	// The client registers the forward only once it received the reply to
+	// its request.
+	var ch Channel
+	var reqs <-chan *Request
+	var err error
+	for i := 0; i < 100; i++ {
+		if ch, reqs, err = conn.OpenChannel("forwarded-tcpip", payload); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("OpenChannel: %v", err)
+	}
+	go DiscardRequests(reqs)
+	defer ch.Close()
+	io.WriteString(ch, "hello")
+	ch.CloseWrite()
+
+	local, err := l.Accept()
+	if err != nil {
+		t.Fatalf("Accept: %v", err)
+	}
+	defer local.Close()
+	got, err := io.ReadAll(local)
+	if err != nil || string(got) != "hello" {
+		t.Errorf("got %q, %v; want %q", got, err, "hello")
+	}
+}
+
+func TestReconnectingClient(t *testing.T) {
+	server, forwarded := listenForwardingSSH(t)
+
+	states := make(chan ReconnectState, 20)
+	rc := NewReconnectingClient(&ReconnectConfig{
+		Dial: func(ctx context.Context) (*Client, error) {
+			return DialContext(ctx, "tcp", server.Addr().String(), &ClientConfig{
+				User:            "user",
+				HostKeyCallback: InsecureIgnoreHostKey(),
+			})
+		},
+		MinBackoff: 10 * time.Millisecond,
+		StateCallback: func(state ReconnectState, err error) {
+			states <- state
+		},
+	})
+	defer rc.Close()
+
+	l, err := rc.Listen("tcp", "127.0.0.1:2222")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+
+	first := <-forwarded
+	checkForward(t, first, l)
+
+	// Lose the connection; the listener is re-established on the next
+	// one.
+	first.Close()
+	second := <-forwarded
+	checkForward(t, second, l)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := rc.Client(ctx)
+	if err != nil {
+		t.Fatalf("Client: %v", err)
+	}
+	if !bytes.Equal(client.SessionID(), second.SessionID()) {
+		t.Error("Client did not return the current connection")
+	}
+
+	rc.Close()
+	if _, err := l.Accept(); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("Accept after Close: got %v, want %v", err, net.ErrClosed)
+	}
+	if _, err := rc.Client(ctx); err == nil {
+		t.Error("Client after Close succeeded")
+	}
+
+	want := []ReconnectState{
+		ReconnectConnecting, ReconnectConnected, ReconnectDisconnected,
+		ReconnectConnecting, ReconnectConnected, ReconnectClosed,
+	}
+	for i, w := range want {
+		if got := <-states; got != w {
+			t.Fatalf("state %d: got %v, want %v", i, got, w)
+		}
+	}
+}
+
+func TestReconnectingClientBackoff(t *testing.T) {
+	errDial := errors.New("dial failed")
+	attempts := make(chan time.Time, 10)
+	rc := NewReconnectingClient(&ReconnectConfig{
+		Dial: func(ctx context.Context) (*Client, error) {
+			select {
+			case attempts <- time.Now():
+			default:
+			}
+			return nil, errDial
+		},
+		MinBackoff: 20 * time.Millisecond,
+		MaxBackoff: 40 * time.Millisecond,
+	})
+	defer rc.Close()
+
+	prev := <-attempts
+	for i, want := range []time.Duration{20, 40, 40} {
+		next := <-attempts
+		if d := next.Sub(prev); d < want*time.Millisecond {
+			t.Errorf("attempt %d after %v, want at least %v", i+2, d, want*time.Millisecond)
+		}
+		prev = next
+	}
+	if s := rc.State(); s != ReconnectConnecting && s != ReconnectDisconnected {
+		t.Errorf("got state %v", s)
+	}
+}
+
+func TestReconnectingClientBackoffAfterHandshake(t *testing.T) {
+	serverConf := &ServerConfig{NoClientAuth: true}
+	serverConf.AddHostKey(testSigners["ecdsa"])
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			// Hang up right after the handshake.
+			if conn, _, _, err := NewServerConn(c, serverConf); err == nil {
+				conn.Close()
+			}
+			c.Close()
+		}
+	}()
+
+	attempts := make(chan time.Time, 10)
+	rc := NewReconnectingClient(&ReconnectConfig{
+		Dial: func(ctx context.Context) (*Client, error) {
+			select {
+			case attempts <- time.Now():
+			default:
+			}
+			return DialContext(ctx, "tcp", l.Addr().String(), &ClientConfig{
+				User:            "user",
+				HostKeyCallback: InsecureIgnoreHostKey(),
+			})
+		},
+		MinBackoff: 50 * time.Millisecond,
+		MaxBackoff: 100 * time.Millisecond,
+	})
+	defer rc.Close()
+
+	prev := <-attempts
+	for i, want := range []time.Duration{50, 100, 100} {
+		next := <-attempts
+		if d := next.Sub(prev); d < want*time.Millisecond {
+			t.Errorf("attempt %d after %v, want at least %v", i+2, d, want*time.Millisecond)
+		}
+		prev = next
+	}
+}
@@ -0,0 +1,250 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// A SessionRecorder receives the data of a session channel wrapped with
+// RecordSession, with the time at which it was seen. Its methods may be
+// called concurrently. An error from Input or Output is returned to the
+// caller of the channel method that produced the event, so that a server
+// can end a session that it cannot record; errors from Resize are ignored.
+// The data must not be retained.
+type SessionRecorder interface {
+	// Input is called with the data read from the channel, which is
+	// what the client typed into a pseudo-terminal.
+	Input(t time.Time, data []byte) error
+
+	// Output is called with the data written to the channel and to its
+	// stderr stream.
+	Output(t time.Time, data []byte) error
+
+	// Resize is called with the terminal size of pty-req and
+	// window-change requests.
+	Resize(t time.Time, columns, rows uint32) error
+}
+
+// RecordSession returns a session channel and its requests backed by ch and
+// reqs, which pass all input, output and terminal size changes to rec.
+// Server code handles the returned values instead of ch and reqs. The
+// requests are passed on unchanged, after those that carry a terminal size
+// are recorded. Like RateLimitChannel, the returned channel supports
+// deadlines and CloseRead if ch does.
+func RecordSession(ch Channel, reqs <-chan *Request, rec SessionRecorder) (Channel, <-chan *Request) {
+	out := make(chan *Request, chanSize)
+	go func() {
+		defer close(out)
+		for req := range reqs {
+			switch req.Type {
+			case "pty-req":
+				if pty, err := ParsePtyRequest(req); err == nil {
+					rec.Resize(time.Now(), pty.Columns, pty.Rows)
+				}
+			case "window-change":
+				if wc, err := ParseWindowChangeRequest(req); err == nil {
+					rec.Resize(time.Now(), wc.Columns, wc.Rows)
+				}
+			}
+			out <- req
+		}
+	}()
+	return &recordingChannel{wrappedChannel: wrappedChannel{ch}, rec: rec}, out
+}
+
+type recordingChannel struct {
+	wrappedChannel
+	rec SessionRecorder
+}
+
+func (c *recordingChannel) Read(data []byte) (int, error) {
+	n, err := c.Channel.Read(data)
+	if n > 0 {
+		if rerr := c.rec.Input(time.Now(), data[:n]); rerr != nil && err == nil {
+			err = rerr
+		}
+	}
+	return n, err
+}
+
+func (c *recordingChannel) Write(data []byte) (int, error) {
+	return writeRecorded(c.Channel, c.rec, data)
+}
+
+func (c *recordingChannel) Stderr() io.ReadWriter {
+	return &recordingStderr{c.Channel.Stderr(), c.rec}
+}
+
+// writeRecorded records data as output before writing it to w, so that
+// nothing reaches the client unrecorded.
+func writeRecorded(w io.Writer, rec SessionRecorder, data []byte) (int, error) {
+	if len(data) > 0 {
+		if err := rec.Output(time.Now(), data); err != nil {
+			return 0, err
+		}
+	}
+	return w.Write(data)
+}
+
+// recordingStderr records the data written to the stderr stream of a
+// session. Data read from it is not recorded, since clients do not send
+// any.
+type recordingStderr struct {
+	io.ReadWriter
+	rec SessionRecorder
+}
+
+func (s *recordingStderr) Write(data []byte) (int, error) {
+	return writeRecorded(s.ReadWriter, s.rec, data)
+}
+
+// AsciicastWriter is a SessionRecorder that writes the session in the
+// asciicast v2 format of asciinema, https://docs.asciinema.org/manual/asciicast/v2/.
+// The header is written with the first event; a terminal size received
+// before it is stored in the header, and later ones are written as resize
+// events.
+type AsciicastWriter struct {
+	// Width and Height are the terminal size written in the header if no
+	// size was received before the first event. They default to 80 and
+	// 24.
+	Width, Height uint32
+
+	// Title and Env are written in the header if they are set. Env
+	// usually holds TERM and SHELL.
+	Title string
+	Env   map[string]string
+
+	// RecordInput makes the writer record input events, which include
+	// anything typed without echo, such as passwords. Players ignore
+	// them.
+	RecordInput bool
+
+	mu      sync.Mutex
+	w       io.Writer
+	started bool
+	start   time.Time
+	err     error
+
+	// partial holds the bytes of an incomplete UTF-8 sequence at the end
+	// of the last input and output, which the asciicast format cannot
+	// represent.
+	partial [2][]byte
+}
+
+// NewAsciicastWriter returns an AsciicastWriter that writes to w.
+func NewAsciicastWriter(w io.Writer) *AsciicastWriter {
+	return &AsciicastWriter{w: w}
+}
+
+type asciicastHeader struct {
+	Version   int               `json:"version"`
+	Width     uint32            `json:"width"`
+	Height    uint32            `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// Input implements SessionRecorder.
+func (a *AsciicastWriter) Input(t time.Time, data []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.RecordInput {
+		return nil
+	}
+	return a.writeData(t, 0, "i", data)
+}
+
+// Output implements SessionRecorder.
+func (a *AsciicastWriter) Output(t time.Time, data []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.writeData(t, 1, "o", data)
+}
+
+// Resize implements SessionRecorder.
+func (a *AsciicastWriter) Resize(t time.Time, columns, rows uint32) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.started {
+		a.Width, a.Height = columns, rows
+		return nil
+	}
+	return a.writeEvent(t, "r", fmt.Sprintf("%dx%d", columns, rows))
+}
+
+func (a *AsciicastWriter) writeData(t time.Time, stream int, code string, data []byte) error {
+	data = append(a.partial[stream], data...)
+	data, a.partial[stream] = splitUTF8(data)
+	if len(data) == 0 {
+		return a.err
+	}
+	return a.writeEvent(t, code, string(data))
+}
+
+// writeEvent writes the header if needed and then the event. Once a write
+// fails, it keeps returning the error.
+func (a *AsciicastWriter) writeEvent(t time.Time, code, data string) error {
+	if a.err != nil {
+		return a.err
+	}
+	if !a.started {
+		a.started = true
+		a.start = t
+		h := asciicastHeader{
+			Version:   2,
+			Width:     a.Width,
+			Height:    a.Height,
+			Timestamp: t.Unix(),
+			Title:     a.Title,
+			Env:       a.Env,
+		}
+		if h.Width == 0 {
+			h.Width = 80
+		}
+		if h.Height == 0 {
+			h.Height = 24
+		}
+		if a.err = a.writeLine(h); a.err != nil {
+			return a.err
+		}
+	}
+	// Events are timestamped before the lock is taken, so they may
+	// arrive slightly out of order.
+	d := t.Sub(a.start)
+	if d < 0 {
+		d = 0
+	}
+	a.err = a.writeLine([]interface{}{d.Seconds(), code, data})
+	return a.err
+}
+
+func (a *AsciicastWriter) writeLine(v interface{}) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = a.w.Write(append(line, '\n'))
+	return err
+}
+
+// splitUTF8 splits b before an incomplete UTF-8 sequence at its end.
+func splitUTF8(b []byte) (complete, rest []byte) {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				return b[:i], append([]byte(nil), b[i:]...)
+			}
+			break
+		}
+	}
+	return b, nil
+}
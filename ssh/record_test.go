@@ -0,0 +1,81 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecordSession(t *testing.T) {
+	client, server, mux := channelPair(t)
+	defer server.Close()
+	defer client.Close()
+	defer mux.Close()
+
+	var buf bytes.Buffer
+	rec := NewAsciicastWriter(&buf)
+	rec.RecordInput = true
+	rec.Env = map[string]string{"TERM": "xterm"}
+
+	reqs := make(chan *Request, 2)
+	reqs <- &Request{Type: "pty-req", Payload: Marshal(ptyRequestMsg{Term: "xterm", Columns: 100, Rows: 30})}
+	reqs <- &Request{Type: "window-change", Payload: Marshal(WindowChangeRequest{Columns: 120, Rows: 40})}
+	close(reqs)
+	recorded, recordedReqs := RecordSession(server, reqs, rec)
+	var types []string
+	for req := range recordedReqs {
+		types = append(types, req.Type)
+	}
+	if got := strings.Join(types, ","); got != "pty-req,window-change" {
+		t.Errorf("got requests %s, want pty-req,window-change", got)
+	}
+
+	go func() {
+		client.Write([]byte("ls\r"))
+		client.CloseWrite()
+	}()
+	in, err := io.ReadAll(recorded)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if string(in) != "ls\r" {
+		t.Errorf("read %q, want %q", in, "ls\r")
+	}
+	// An incomplete UTF-8 sequence is held back until it is complete.
+	euro := []byte("€")
+	recorded.Write([]byte("a"))
+	recorded.Write(euro[:1])
+	recorded.Stderr().Write(euro[1:])
+
+	// Once the header is written, size changes are events.
+	rec.Resize(time.Now(), 90, 20)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("got %d lines, want 5:\n%s", len(lines), buf.String())
+	}
+	var header asciicastHeader
+	if err := json.Unmarshal([]byte(lines[0]), &header); err != nil {
+		t.Fatalf("header: %v", err)
+	}
+	// The last size received before the first event goes in the header.
+	if header.Version != 2 || header.Width != 120 || header.Height != 40 || header.Env["TERM"] != "xterm" {
+		t.Errorf("got header %+v", header)
+	}
+	for i, want := range [][2]string{{"i", "ls\r"}, {"o", "a"}, {"o", "€"}, {"r", "90x20"}} {
+		var ev []interface{}
+		if err := json.Unmarshal([]byte(lines[i+1]), &ev); err != nil {
+			t.Fatalf("event %d: %v", i, err)
+		}
+		if len(ev) != 3 || ev[1] != want[0] || ev[2] != want[1] {
+			t.Errorf("event %d: got %v, want %v", i, ev, want)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"crypto"
+	"errors"
+	"hash"
+	"io"
+)
+
+// PacketCipher encrypts and authenticates the binary packets of one
+// direction of a connection, see RFC 4253, section 6. A new PacketCipher is
+// created for every key exchange by the function passed to RegisterCipher.
+type PacketCipher interface {
+	// WritePacket encrypts packet, the payload of a single SSH packet, and
+	// writes the resulting binary packet to w. seqNum is the packet
+	// sequence number. The contents of packet may be overwritten.
+	WritePacket(seqNum uint32, w io.Writer, rand io.Reader, packet []byte) error
+
+	// ReadPacket reads a binary packet from r, decrypts and verifies it,
+	// and returns its payload. The returned slice may be overwritten by
+	// later calls.
+	ReadPacket(seqNum uint32, r io.Reader) ([]byte, error)
+}
+
+// KexConn is the connection over which a KeyExchange sends and receives its
+// messages.
+type KexConn interface {
+	// WritePacket sends a packet to the peer. The contents of packet may be
+	// overwritten.
+	WritePacket(packet []byte) error
+
+	// ReadPacket reads the next packet from the peer.
+	ReadPacket() ([]byte, error)
+}
+
+// KexMagics holds the data that is hashed into every exchange hash, see
+// RFC 4253, section 8.
+type KexMagics struct {
+	ClientVersion, ServerVersion []byte
+	ClientKexInit, ServerKexInit []byte
+}
+
+// Write writes the magics to w in the order in which they are hashed into
+// the exchange hash, each encoded as an SSH string.
+func (m *KexMagics) Write(w io.Writer) {
+	writeString(w, m.ClientVersion)
+	writeString(w, m.ServerVersion)
+	writeString(w, m.ClientKexInit)
+	writeString(w, m.ServerKexInit)
+}
+
+// KexResult is the outcome of a key exchange.
+type KexResult struct {
+	// H is the exchange hash.
+	H []byte
+
+	// K is the shared secret, encoded as it was hashed into H.
+	K []byte
+
+	// HostKey is the server's host key, in wire format, as hashed into H.
+	HostKey []byte
+
+	// Signature is the server's signature of H, in wire format.
+	Signature []byte
+
+	// Hash is the hash function used to compute H, which is also used to
+	// derive the keys from K and H.
+	Hash crypto.Hash
+}
+
+// KeyExchange implements a key exchange method, see RFC 4253, section 7.
+type KeyExchange interface {
+	// Client runs the client side of the key exchange. The caller verifies
+	// the host key and its signature of the exchange hash.
+	Client(c KexConn, rand io.Reader, magics *KexMagics) (*KexResult, error)
+
+	// Server runs the server side of the key exchange, signing the exchange
+	// hash with hostKey, which uses the negotiated host key algorithm.
+	Server(c KexConn, rand io.Reader, magics *KexMagics, hostKey Signer) (*KexResult, error)
+}
+
+// RegisterCipher makes a cipher available, under the given name, for use in
+// Config.Ciphers. keySize and ivSize are the lengths of the key and IV
+// passed to create. The cipher is responsible for the integrity of the
+// packets, so no MAC is negotiated alongside it.
+//
+// RegisterCipher must be called before any connection is established,
+// typically from an init function. It panics if name is already registered.
+func RegisterCipher(name string, keySize, ivSize int, create func(key, iv []byte) (PacketCipher, error)) {
+	if name == "" || cipherModes[name] != nil {
+		panic("ssh: RegisterCipher called twice for cipher " + name)
+	}
+	cipherModes[name] = &cipherMode{keySize, ivSize, func(key, iv []byte, macKey []byte, algs directionAlgorithms) (packetCipher, error) {
+		c, err := create(key, iv)
+		if err != nil {
+			return nil, err
+		}
+		return registeredCipher{c}, nil
+	}}
+	aeadCiphers[name] = true
+	supportedCiphers = append(supportedCiphers, name)
+}
+
+// RegisterMAC makes a MAC available, under the given name, for use in
+// Config.MACs. keySize is the length of the key passed to newMAC. If etm is
+// true, the MAC is computed over the encrypted packet, as for the
+// "-etm@openssh.com" MACs.
+//
+// RegisterMAC must be called before any connection is established,
+// typically from an init function. It panics if name is already registered.
+func RegisterMAC(name string, keySize int, etm bool, newMAC func(key []byte) hash.Hash) {
+	if name == "" || macModes[name] != nil {
+		panic("ssh: RegisterMAC called twice for MAC " + name)
+	}
+	macModes[name] = &macMode{keySize, etm, newMAC}
+	supportedMACs = append(supportedMACs, name)
+}
+
+// RegisterKex makes a key exchange method available, under the given name,
+// for use in Config.KeyExchanges.
+//
+// RegisterKex must be called before any connection is established,
+// typically from an init function. It panics if name is already registered.
+func RegisterKex(name string, kex KeyExchange) {
+	if name == "" || kexAlgoMap[name] != nil {
+		panic("ssh: RegisterKex called twice for key exchange " + name)
+	}
+	kexAlgoMap[name] = registeredKex{kex}
+	supportedKexAlgos = append(supportedKexAlgos, name)
+}
+
+// registeredCipher adapts a PacketCipher to the packetCipher interface.
+type registeredCipher struct {
+	PacketCipher
+}
+
+func (c registeredCipher) writeCipherPacket(seqnum uint32, w io.Writer, rand io.Reader, packet []byte) error {
+	return c.WritePacket(seqnum, w, rand, packet)
+}
+
+func (c registeredCipher) readCipherPacket(seqnum uint32, r io.Reader) ([]byte, error) {
+	return c.ReadPacket(seqnum, r)
+}
+
+// registeredKex adapts a KeyExchange to the kexAlgorithm interface.
+type registeredKex struct {
+	kex KeyExchange
+}
+
+// kexConn adapts a packetConn to the KexConn interface.
+type kexConn struct {
+	c packetConn
+}
+
+func (k kexConn) WritePacket(packet []byte) error {
+	return k.c.writePacket(packet)
+}
+
+func (k kexConn) ReadPacket() ([]byte, error) {
+	return k.c.readPacket()
+}
+
+// kexHostKey is a Signer that signs with the negotiated host key algorithm.
+type kexHostKey struct {
+	AlgorithmSigner
+	algo string
+}
+
+func (k kexHostKey) Sign(rand io.Reader, data []byte) (*Signature, error) {
+	return k.SignWithAlgorithm(rand, data, underlyingAlgo(k.algo))
+}
+
+func exportMagics(magics *handshakeMagics) *KexMagics {
+	return &KexMagics{
+		ClientVersion: magics.clientVersion,
+		ServerVersion: magics.serverVersion,
+		ClientKexInit: magics.clientKexInit,
+		ServerKexInit: magics.serverKexInit,
+	}
+}
+
+func importKexResult(r *KexResult, err error) (*kexResult, error) {
+	if err != nil {
+		return nil, err
+	}
+	if r == nil {
+		return nil, errors.New("ssh: registered key exchange returned no result")
+	}
+	return &kexResult{
+		H:         r.H,
+		K:         r.K,
+		HostKey:   r.HostKey,
+		Signature: r.Signature,
+		Hash:      r.Hash,
+	}, nil
+}
+
+func (r registeredKex) Client(p packetConn, rand io.Reader, magics *handshakeMagics) (*kexResult, error) {
+	return importKexResult(r.kex.Client(kexConn{p}, rand, exportMagics(magics)))
+}
+
+func (r registeredKex) Server(p packetConn, rand io.Reader, magics *handshakeMagics, s AlgorithmSigner, algo string) (*kexResult, error) {
+	return importKexResult(r.kex.Server(kexConn{p}, rand, exportMagics(magics), kexHostKey{s, algo}))
+}
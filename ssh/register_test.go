@@ -0,0 +1,148 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"hash"
+	"io"
+	"sync"
+	"testing"
+)
+
+const (
+	testRegisteredCipher = "test-gcm@example.com"
+	testRegisteredMAC    = "test-hmac@example.com"
+	testRegisteredKex    = "test-curve25519@example.com"
+)
+
+var registerTestAlgorithms sync.Once
+
+// wrappedCipher exposes a built-in cipher through the PacketCipher
+// interface.
+type wrappedCipher struct {
+	packetCipher
+}
+
+func (c wrappedCipher) WritePacket(seqNum uint32, w io.Writer, rand io.Reader, packet []byte) error {
+	return c.writeCipherPacket(seqNum, w, rand, packet)
+}
+
+func (c wrappedCipher) ReadPacket(seqNum uint32, r io.Reader) ([]byte, error) {
+	return c.readCipherPacket(seqNum, r)
+}
+
+// wrappedKex exposes a built-in key exchange through the KeyExchange
+// interface.
+type wrappedKex struct {
+	kex kexAlgorithm
+}
+
+// wrappedKexConn adapts a KexConn back to the packetConn interface.
+type wrappedKexConn struct {
+	KexConn
+}
+
+func (c wrappedKexConn) writePacket(packet []byte) error { return c.WritePacket(packet) }
+func (c wrappedKexConn) readPacket() ([]byte, error)     { return c.ReadPacket() }
+func (c wrappedKexConn) Close() error                    { return nil }
+
+func importMagics(m *KexMagics) *handshakeMagics {
+	return &handshakeMagics{m.ClientVersion, m.ServerVersion, m.ClientKexInit, m.ServerKexInit}
+}
+
+func exportKexResult(r *kexResult, err error) (*KexResult, error) {
+	if err != nil {
+		return nil, err
+	}
+	return &KexResult{H: r.H, K: r.K, HostKey: r.HostKey, Signature: r.Signature, Hash: r.Hash}, nil
+}
+
+func (k wrappedKex) Client(c KexConn, rand io.Reader, magics *KexMagics) (*KexResult, error) {
+	return exportKexResult(k.kex.Client(wrappedKexConn{c}, rand, importMagics(magics)))
+}
+
+func (k wrappedKex) Server(c KexConn, rand io.Reader, magics *KexMagics, hostKey Signer) (*KexResult, error) {
+	return exportKexResult(k.kex.Server(wrappedKexConn{c}, rand, importMagics(magics), algorithmSignerWrapper{hostKey}, hostKey.PublicKey().Type()))
+}
+
+func registerTestAlgos() {
+	registerTestAlgorithms.Do(func() {
+		RegisterCipher(testRegisteredCipher, 16, 12, func(key, iv []byte) (PacketCipher, error) {
+			c, err := cipherModes[gcm128CipherID].create(key, iv, nil, directionAlgorithms{})
+			if err != nil {
+				return nil, err
+			}
+			return wrappedCipher{c}, nil
+		})
+		RegisterMAC(testRegisteredMAC, 32, true, func(key []byte) hash.Hash {
+			return hmac.New(sha256.New, key)
+		})
+		RegisterKex(testRegisteredKex, wrappedKex{kexAlgoMap[kexAlgoCurve25519SHA256]})
+	})
+}
+
+func TestRegisteredAlgorithms(t *testing.T) {
+	registerTestAlgos()
+
+	for _, conf := range []Config{
+		{KeyExchanges: []string{testRegisteredKex}, Ciphers: []string{testRegisteredCipher}},
+		{Ciphers: []string{"aes128-ctr"}, MACs: []string{testRegisteredMAC}},
+	} {
+		c1, c2, err := netPipe()
+		if err != nil {
+			t.Fatalf("netPipe: %v", err)
+		}
+		defer c1.Close()
+		defer c2.Close()
+
+		serverConf := &ServerConfig{Config: conf, NoClientAuth: true}
+		serverConf.AddHostKey(testSigners["ecdsa"])
+		go func() {
+			conn, _, _, err := NewServerConn(c2, serverConf)
+			if err == nil {
+				conn.Close()
+			}
+		}()
+
+		clientConf := &ClientConfig{
+			Config:          conf,
+			User:            "user",
+			HostKeyCallback: InsecureIgnoreHostKey(),
+		}
+		conn, _, _, err := NewClientConn(c1, "", clientConf)
+		if err != nil {
+			t.Fatalf("NewClientConn with %v: %v", conf, err)
+		}
+		algs := conn.(*connection).transport.algorithms
+		conn.Close()
+
+		if conf.KeyExchanges != nil && algs.kex != testRegisteredKex {
+			t.Errorf("got kex %q, want %q", algs.kex, testRegisteredKex)
+		}
+		if algs.w.Cipher != conf.Ciphers[0] || algs.r.Cipher != conf.Ciphers[0] {
+			t.Errorf("got ciphers %q and %q, want %q", algs.w.Cipher, algs.r.Cipher, conf.Ciphers[0])
+		}
+		if conf.MACs != nil && (algs.w.MAC != testRegisteredMAC || algs.r.MAC != testRegisteredMAC) {
+			t.Errorf("got MACs %q and %q, want %q", algs.w.MAC, algs.r.MAC, testRegisteredMAC)
+		}
+	}
+}
+
+func TestRegisterDuplicatePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("RegisterKex of a built-in key exchange did not panic")
+		}
+	}()
+	RegisterKex(kexAlgoCurve25519SHA256, wrappedKex{kexAlgoMap[kexAlgoCurve25519SHA256]})
+}
+
+func TestImportNilKexResult(t *testing.T) {
+	if r, err := importKexResult(nil, nil); err == nil || r != nil {
+		t.Errorf("importKexResult(nil, nil) = %v, %v, want an error", r, err)
+	}
+}
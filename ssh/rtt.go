@@ -0,0 +1,104 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// RTTStats summarizes the round-trip times measured on a connection by
+// ping@openssh.com messages, keepalive requests (see
+// Config.KeepaliveInterval) and MeasureRTT calls.
+//
+// Smoothed and Jitter are the smoothed round-trip time and its mean
+// deviation, computed like TCP's SRTT and RTTVAR (RFC 6298, section 2).
+//
+// The measurements are only reported; they do not change how the
+// connection behaves. In particular, flow-control windows are not sized
+// from them: each channel advertises Config.ChannelBufferSize, which
+// limits its throughput to about ChannelBufferSize/Smoothed bytes per
+// second.
+type RTTStats struct {
+	// Samples is the number of round trips measured. The other fields
+	// are zero if it is zero.
+	Samples int
+
+	// Latest is the most recent round-trip time, and Min the lowest.
+	Latest time.Duration
+	Min    time.Duration
+
+	Smoothed time.Duration
+	Jitter   time.Duration
+}
+
+// rttEstimator accumulates round-trip time samples into RTTStats.
+type rttEstimator struct {
+	mu    sync.Mutex
+	stats RTTStats
+}
+
+func (e *rttEstimator) add(d time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	s := &e.stats
+	s.Samples++
+	s.Latest = d
+	if s.Samples == 1 {
+		s.Min = d
+		s.Smoothed = d
+		s.Jitter = d / 2
+		return
+	}
+	if d < s.Min {
+		s.Min = d
+	}
+	delta := s.Smoothed - d
+	if delta < 0 {
+		delta = -delta
+	}
+	// RTTVAR = 3/4 RTTVAR + 1/4 |SRTT - R|, then SRTT = 7/8 SRTT + 1/8 R.
+	s.Jitter += (delta - s.Jitter) / 4
+	s.Smoothed += (d - s.Smoothed) / 8
+}
+
+func (e *rttEstimator) get() RTTStats {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.stats
+}
+
+// rttConn is implemented by the Conn of this package, which measures
+// round-trip times.
+type rttConn interface {
+	RTT() RTTStats
+	MeasureRTT(ctx context.Context) (time.Duration, error)
+}
+
+var errNoRTT = errors.New("ssh: connection does not measure round-trip times")
+
+// RTT returns the round-trip times measured on the connection so far.
+func (m *mux) RTT() RTTStats {
+	return m.rtt.get()
+}
+
+// MeasureRTT measures one round trip to the peer. It sends a
+// ping@openssh.com message if the peer supports it, and a keepalive global
+// request otherwise, which the peer may answer more slowly since it goes
+// through its request handling.
+func (c *connection) MeasureRTT(ctx context.Context) (time.Duration, error) {
+	if _, ok := c.PeerExtInfo()[pingExtension]; ok {
+		return c.ping(ctx)
+	}
+	var sent time.Time
+	if _, _, err := c.mux.sendRequest(ctx, keepaliveRequest, true, nil, &sent); err != nil {
+		return 0, err
+	}
+	d := time.Since(sent)
+	c.rtt.add(d)
+	return d, nil
+}
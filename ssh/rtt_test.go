@@ -0,0 +1,146 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRTTEstimator(t *testing.T) {
+	var e rttEstimator
+	if got := e.get(); got != (RTTStats{}) {
+		t.Errorf("stats before any sample: got %+v, want zero", got)
+	}
+	e.add(100 * time.Millisecond)
+	want := RTTStats{
+		Samples:  1,
+		Latest:   100 * time.Millisecond,
+		Min:      100 * time.Millisecond,
+		Smoothed: 100 * time.Millisecond,
+		Jitter:   50 * time.Millisecond,
+	}
+	if got := e.get(); got != want {
+		t.Errorf("after one sample: got %+v, want %+v", got, want)
+	}
+	e.add(20 * time.Millisecond)
+	want = RTTStats{
+		Samples:  2,
+		Latest:   20 * time.Millisecond,
+		Min:      20 * time.Millisecond,
+		Smoothed: 90 * time.Millisecond,
+		Jitter:   57500 * time.Microsecond,
+	}
+	if got := e.get(); got != want {
+		t.Errorf("after two samples: got %+v, want %+v", got, want)
+	}
+}
+
+func TestClientMeasureRTT(t *testing.T) {
+	c1, c2, err := netPipe()
+	if err != nil {
+		t.Fatalf("netPipe: %v", err)
+	}
+	defer c1.Close()
+	defer c2.Close()
+
+	serverConf := &ServerConfig{NoClientAuth: true}
+	serverConf.AddHostKey(testSigners["ecdsa"])
+	go func() {
+		conn, chans, reqs, err := NewServerConn(c2, serverConf)
+		if err != nil {
+			return
+		}
+		go DiscardRequests(reqs)
+		for range chans {
+		}
+		conn.Close()
+	}()
+
+	clientConf := &ClientConfig{
+		User:            "user",
+		HostKeyCallback: InsecureIgnoreHostKey(),
+	}
+	conn, chans, reqs, err := NewClientConn(c1, "", clientConf)
+	if err != nil {
+		t.Fatalf("NewClientConn: %v", err)
+	}
+	client := NewClient(conn, chans, reqs)
+	defer client.Close()
+
+	if _, err := client.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+	if got := client.RTT().Samples; got != 1 {
+		t.Errorf("after Ping: got %d samples, want 1", got)
+	}
+
+	// Without ping@openssh.com, MeasureRTT uses a keepalive request.
+	delete(conn.(*connection).peerExtInfo, pingExtension)
+	d, err := client.MeasureRTT(context.Background())
+	if err != nil || d <= 0 {
+		t.Fatalf("MeasureRTT: %v, %v", d, err)
+	}
+	stats := client.RTT()
+	if stats.Samples != 2 || stats.Latest != d || stats.Min <= 0 || stats.Smoothed <= 0 {
+		t.Errorf("after MeasureRTT: got %+v", stats)
+	}
+}
+
+func TestClientMeasureRTTQueued(t *testing.T) {
+	c1, c2, err := netPipe()
+	if err != nil {
+		t.Fatalf("netPipe: %v", err)
+	}
+	defer c1.Close()
+	defer c2.Close()
+
+	const delay = 300 * time.Millisecond
+	received := make(chan struct{})
+	serverConf := &ServerConfig{NoClientAuth: true}
+	serverConf.AddHostKey(testSigners["ecdsa"])
+	go func() {
+		conn, chans, reqs, err := NewServerConn(c2, serverConf)
+		if err != nil {
+			return
+		}
+		go func() {
+			for req := range reqs {
+				if req.Type == "slow" {
+					close(received)
+					time.Sleep(delay)
+				}
+				req.Reply(false, nil)
+			}
+		}()
+		for range chans {
+		}
+		conn.Close()
+	}()
+
+	conn, chans, reqs, err := NewClientConn(c1, "", &ClientConfig{
+		User:            "user",
+		HostKeyCallback: InsecureIgnoreHostKey(),
+	})
+	if err != nil {
+		t.Fatalf("NewClientConn: %v", err)
+	}
+	client := NewClient(conn, chans, reqs)
+	defer client.Close()
+	delete(conn.(*connection).peerExtInfo, pingExtension)
+
+	go client.SendRequest("slow", true, nil)
+	<-received
+	// The keepalive waits for the reply to the slow request before it is
+	// sent, which must not count towards the round-trip time.
+	d, err := client.MeasureRTT(context.Background())
+	if err != nil {
+		t.Fatalf("MeasureRTT: %v", err)
+	}
+	if d >= delay/2 {
+		t.Errorf("MeasureRTT = %v, including the wait for an earlier request", d)
+	}
+}
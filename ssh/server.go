@@ -6,6 +6,7 @@ package ssh
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -71,7 +72,15 @@ type ServerConfig struct {
 	// If unspecified then a default set of algorithms is used.
 	PublicKeyAuthAlgorithms []string
 
-	hostKeys []Signer
+	// AnnounceHostKeys makes the server announce all its host keys,
+	// including those added with AddSecondaryHostKey, with the
+	// hostkeys-00@openssh.com extension once the user is authenticated, and
+	// answer hostkeys-prove-00@openssh.com requests for them, as OpenSSH
+	// servers do. This lets clients learn new host keys before they are used.
+	AnnounceHostKeys bool
+
+	hostKeys          []Signer
+	secondaryHostKeys []Signer
 
 	// NoClientAuth is true if clients are allowed to connect without
 	// authenticating.
@@ -95,6 +104,14 @@ type ServerConfig struct {
 	// attempts to authenticate using a password.
 	PasswordCallback func(conn ConnMetadata, password []byte) (*Permissions, error)
 
+	// PasswordChangeCallback, if non-nil, is called when a user sends a
+	// new password in response to a PasswordChangeRequiredError returned
+	// by PasswordCallback, or by PasswordChangeCallback itself if the new
+	// password is not acceptable. Without it, PasswordChangeRequiredError
+	// is treated like any other error. Each request for a new password
+	// counts towards MaxAuthTries.
+	PasswordChangeCallback func(conn ConnMetadata, oldPassword, newPassword []byte) (*Permissions, error)
+
 	// PublicKeyCallback, if non-nil, is called when a client
 	// offers a public key for authentication. It must return a nil error
 	// if the given public key can be used to authenticate the
@@ -105,6 +122,17 @@ type ServerConfig struct {
 	// Permissions.Extensions entry.
 	PublicKeyCallback func(conn ConnMetadata, key PublicKey) (*Permissions, error)
 
+	// CheckSecurityKeyFlags makes the server apply the rules of OpenSSH to
+	// the flags of signatures made by security keys, such as
+	// sk-ssh-ed25519@openssh.com keys: they must show that the user
+	// touched the key, unless the Permissions returned by
+	// PublicKeyCallback have the "no-touch-required" extension, and that
+	// the key verified the user if they have the "verify-required"
+	// critical option. Certificates carry these in the same places, so
+	// the Permissions of CertChecker.Authenticate apply them too. If not
+	// set, the flags are only available with ParseSKSignature.
+	CheckSecurityKeyFlags bool
+
 	// KeyboardInteractiveCallback, if non-nil, is called when
 	// keyboard-interactive authentication is selected (RFC
 	// 4256). The client object's Challenge function should be
@@ -114,6 +142,18 @@ type ServerConfig struct {
 	// unknown.
 	KeyboardInteractiveCallback func(conn ConnMetadata, client KeyboardInteractiveChallenge) (*Permissions, error)
 
+	// HostBasedCallback, if non-nil, is called when a client attempts
+	// "hostbased" authentication (RFC 4252, section 9), after the request
+	// has been verified to be signed by key. key is the host key of the
+	// client host, which claims to be named clientHost, and clientUser is
+	// the name of the user on that host. It must return a nil error if the
+	// client host is trusted to vouch for clientUser logging in as the
+	// user of conn, typically after checking key against the known host
+	// keys of clientHost, like the shosts.equiv files of OpenSSH. The
+	// client host name is as sent by the client, which usually includes a
+	// trailing dot.
+	HostBasedCallback func(conn ConnMetadata, key PublicKey, clientHost, clientUser string) (*Permissions, error)
+
 	// AuthLogCallback, if non-nil, is called to log all authentication
 	// attempts.
 	AuthLogCallback func(conn ConnMetadata, method string, err error)
@@ -129,14 +169,37 @@ type ServerConfig struct {
 	// the client after key exchange completed but before authentication.
 	BannerCallback func(conn ConnMetadata) string
 
+	// StreamLocalForwardCallback, if non-nil, makes the server handle
+	// streamlocal-forward@openssh.com and
+	// cancel-streamlocal-forward@openssh.com requests itself instead of
+	// passing them on to the application, as used by Client.ListenUnix. It
+	// is called with the path of the Unix socket a client asks to listen
+	// on, and returns whether to allow it. For allowed requests, the server
+	// listens on the socket and forwards each connection to it to the
+	// client as a forwarded-streamlocal@openssh.com channel, until the
+	// request is cancelled or the connection closes. The callback may
+	// block, without holding up the connection, but later global requests
+	// are only answered once it returns, since replies are sent in order.
+	StreamLocalForwardCallback func(conn ConnMetadata, socketPath string) bool
+
 	// GSSAPIWithMICConfig includes gssapi server and callback, which if both non-nil, is used
 	// when gssapi-with-mic authentication is selected (RFC 4462 section 3).
 	GSSAPIWithMICConfig *GSSAPIWithMICConfig
+
+	// GSSAPIKeyExchange, if non-nil, enables the GSSAPI key exchange
+	// methods gss-nistp256-sha256-* and gss-group14-sha256-* for the
+	// Kerberos V5 mechanism (RFC 4462, section 2 and RFC 8732), which are
+	// preferred over the methods of Config.KeyExchanges unless some of
+	// them are listed there. A host key, if any, is still sent to the
+	// client, which does not need to verify it. The null host key
+	// algorithm of RFC 4462, section 5, is offered, so the server may have
+	// no host keys. The client is not authenticated by the key exchange.
+	GSSAPIKeyExchange GSSAPIKexServer
 }
 
 // AddHostKey adds a private key as a host key. If an existing host
 // key exists with the same public key format, it is replaced. Each server
-// config must have at least one host key.
+// config must have at least one host key, unless GSSAPIKeyExchange is set.
 func (s *ServerConfig) AddHostKey(key Signer) {
 	for i, k := range s.hostKeys {
 		if k.PublicKey().Type() == key.PublicKey().Type() {
@@ -148,6 +211,14 @@ func (s *ServerConfig) AddHostKey(key Signer) {
 	s.hostKeys = append(s.hostKeys, key)
 }
 
+// AddSecondaryHostKey adds a private key that is announced to clients and
+// whose possession is proven to them, but which is not used in key
+// exchanges, typically because it is to replace a host key in the future.
+// It has no effect unless AnnounceHostKeys is set.
+func (s *ServerConfig) AddSecondaryHostKey(key Signer) {
+	s.secondaryHostKeys = append(s.secondaryHostKeys, key)
+}
+
 // cachedPubKey contains the results of querying whether a public key is
 // acceptable for a user.
 type cachedPubKey struct {
@@ -194,6 +265,67 @@ type ServerConn struct {
 	Permissions *Permissions
 }
 
+// ForceRekey starts a new key exchange, unless one is already in progress.
+// It does not wait for the key exchange to complete.
+func (c *ServerConn) ForceRekey() error {
+	if r, ok := c.Conn.(rekeyer); ok {
+		return r.ForceRekey()
+	}
+	return errNoRekey
+}
+
+// SendIgnore sends a SSH_MSG_IGNORE message with data to the client, which
+// discards it.
+func (c *ServerConn) SendIgnore(data []byte) error {
+	if d, ok := c.Conn.(diagnosticSender); ok {
+		return d.SendIgnore(data)
+	}
+	return errNoDiagnostics
+}
+
+// SendDebug sends a SSH_MSG_DEBUG message to the client, which may log or
+// display it. See RFC 4253, section 11.3.
+func (c *ServerConn) SendDebug(alwaysDisplay bool, message, language string) error {
+	if d, ok := c.Conn.(diagnosticSender); ok {
+		return d.SendDebug(alwaysDisplay, message, language)
+	}
+	return errNoDiagnostics
+}
+
+// SendRequestContext is like SendRequest, but stops waiting if ctx is done
+// before the reply arrives, returning ErrReplyTimeout if its deadline passed
+// and ctx.Err() otherwise. If the underlying Conn does not support
+// abandoning requests, ctx is only checked before sending.
+func (c *ServerConn) SendRequestContext(ctx context.Context, name string, wantReply bool, payload []byte) (bool, []byte, error) {
+	return sendRequestContext(ctx, c.Conn, name, wantReply, payload)
+}
+
+// OpenChannelContext is like OpenChannel, but returns ctx.Err() if ctx is
+// done before the peer accepts or rejects the channel, in which case a
+// channel accepted later is closed. If the underlying Conn does not support
+// abandoning channel opens, ctx is only checked before sending.
+func (c *ServerConn) OpenChannelContext(ctx context.Context, name string, data []byte) (Channel, <-chan *Request, error) {
+	return openChannelContext(ctx, c.Conn, name, data)
+}
+
+// OpenChannelAsync sends the request to open a channel like OpenChannel,
+// but returns without waiting for the peer to accept or reject it, so that
+// many channels can be opened concurrently without waiting for each
+// confirmation in turn. ctx bounds the wait, as with OpenChannelContext.
+func (c *ServerConn) OpenChannelAsync(ctx context.Context, name string, data []byte) *PendingChannel {
+	return openChannelAsync(ctx, c.Conn, name, data)
+}
+
+// Algorithms returns the algorithms negotiated in the most recent key
+// exchange. It returns the zero value if the underlying Conn does not
+// implement AlgorithmsConnMetadata.
+func (c *ServerConn) Algorithms() NegotiatedAlgorithms {
+	if a, ok := c.Conn.(AlgorithmsConnMetadata); ok {
+		return a.Algorithms()
+	}
+	return NegotiatedAlgorithms{}
+}
+
 // NewServerConn starts a new SSH server with c as the underlying
 // transport.  It starts with a handshake and, if the handshake is
 // unsuccessful, it closes the connection and returns an error.  The
@@ -205,11 +337,14 @@ type ServerConn struct {
 func NewServerConn(c net.Conn, config *ServerConfig) (*ServerConn, <-chan NewChannel, <-chan *Request, error) {
 	fullConf := *config
 	fullConf.SetDefaults()
+	if fullConf.GSSAPIKeyExchange != nil {
+		fullConf.KeyExchanges = withGSSKexAlgos(fullConf.KeyExchanges)
+	}
 	if fullConf.MaxAuthTries == 0 {
 		fullConf.MaxAuthTries = 6
 	}
 	if len(fullConf.PublicKeyAuthAlgorithms) == 0 {
-		fullConf.PublicKeyAuthAlgorithms = supportedPubKeyAuthAlgos
+		fullConf.PublicKeyAuthAlgorithms = preferredPubKeyAuthAlgos
 	} else {
 		for _, algo := range fullConf.PublicKeyAuthAlgorithms {
 			if !contains(supportedPubKeyAuthAlgos, algo) {
@@ -251,12 +386,12 @@ func signAndMarshal(k AlgorithmSigner, rand io.Reader, data []byte, algo string)
 
 // handshake performs key exchange and user authentication.
 func (s *connection) serverHandshake(config *ServerConfig) (*Permissions, error) {
-	if len(config.hostKeys) == 0 {
+	if len(config.hostKeys) == 0 && config.GSSAPIKeyExchange == nil {
 		return nil, errors.New("ssh: server has no host keys")
 	}
 
 	if !config.NoClientAuth && config.PasswordCallback == nil && config.PublicKeyCallback == nil &&
-		config.KeyboardInteractiveCallback == nil && (config.GSSAPIWithMICConfig == nil ||
+		config.KeyboardInteractiveCallback == nil && config.HostBasedCallback == nil && (config.GSSAPIWithMICConfig == nil ||
 		config.GSSAPIWithMICConfig.AllowLogin == nil || config.GSSAPIWithMICConfig.Server == nil) {
 		return nil, errors.New("ssh: no authentication methods configured but NoClientAuth is also false")
 	}
@@ -269,10 +404,13 @@ func (s *connection) serverHandshake(config *ServerConfig) (*Permissions, error)
 	var err error
 	s.clientVersion, err = exchangeVersions(s.sshConn.conn, s.serverVersion)
 	if err != nil {
+		config.Logger.Info("ssh: version exchange failed", "error", err)
 		return nil, err
 	}
+	config.Logger.Info("ssh: version exchanged", "local", string(s.serverVersion), "remote", string(s.clientVersion))
 
 	tr := newTransport(s.sshConn.conn, config.Rand, false /* not client */)
+	tr.debugCallback = config.DebugCallback
 	s.transport = newServerTransport(tr, s.clientVersion, s.serverVersion, config)
 
 	if err := s.transport.waitSession(); err != nil {
@@ -281,12 +419,25 @@ func (s *connection) serverHandshake(config *ServerConfig) (*Permissions, error)
 
 	// We just did the key change, so the session ID is established.
 	s.sessionID = s.transport.getSessionID()
+	s.peerExtInfo = s.transport.peerExtInfo
 
 	var packet []byte
 	if packet, err = s.transport.readPacket(); err != nil {
 		return nil, err
 	}
 
+	// The client may send a SSH_MSG_EXT_INFO right after its first
+	// SSH_MSG_NEWKEYS, if we advertised willingness to receive one, which we
+	// always do. See RFC 8308, Section 2.4.
+	if len(packet) > 0 && packet[0] == msgExtInfo {
+		if err := s.transport.recordExtInfo(packet); err != nil {
+			return nil, err
+		}
+		if packet, err = s.transport.readPacket(); err != nil {
+			return nil, err
+		}
+	}
+
 	var serviceRequest serviceRequestMsg
 	if err = Unmarshal(packet, &serviceRequest); err != nil {
 		return nil, err
@@ -305,7 +456,23 @@ func (s *connection) serverHandshake(config *ServerConfig) (*Permissions, error)
 	if err != nil {
 		return nil, err
 	}
-	s.mux = newMux(s.transport)
+	s.mux = newMuxState(s.transport, &config.Config)
+	s.mux.enforceNoMoreSessions = true
+	if config.StreamLocalForwardCallback != nil {
+		s.handleStreamLocalForwards(config.StreamLocalForwardCallback)
+	}
+	if config.AnnounceHostKeys {
+		if err := s.announceHostKeys(config); err != nil {
+			return nil, err
+		}
+	}
+	go s.mux.loop()
+	if config.KeepaliveInterval > 0 {
+		go s.mux.keepalive(config.KeepaliveInterval, config.KeepaliveMaxMissed)
+	}
+	if config.IdleTimeout > 0 {
+		go s.mux.idleTimeout(config.IdleTimeout)
+	}
 	return perms, err
 }
 
@@ -339,6 +506,29 @@ func checkSourceAddress(addr net.Addr, sourceAddrs string) error {
 	return fmt.Errorf("ssh: remote address %v is not allowed because of source-address restriction", addr)
 }
 
+// checkSKSignature applies the requirements of OpenSSH to the flags of a
+// verified signature made by a security key: the user must have touched
+// the key, unless perms has the no-touch-required extension, and must have
+// been verified by it if perms has the verify-required critical option.
+func checkSKSignature(sig *Signature, perms *Permissions) error {
+	skf, err := ParseSKSignature(sig)
+	if err != nil {
+		// Not made by a security key.
+		return nil
+	}
+	var p Permissions
+	if perms != nil {
+		p = *perms
+	}
+	if _, ok := p.Extensions[noTouchRequiredExtension]; !ok && !skf.UserPresent() {
+		return errors.New("ssh: security key signature without user presence")
+	}
+	if _, ok := p.CriticalOptions[verifyRequiredCriticalOption]; ok && !skf.UserVerified() {
+		return errors.New("ssh: security key signature without user verification")
+	}
+	return nil
+}
+
 func gssExchangeToken(gssapiConfig *GSSAPIWithMICConfig, token []byte, s *connection,
 	sessionID []byte, userAuthReq userAuthRequestMsg) (authErr error, perms *Permissions, err error) {
 	gssAPIServer := gssapiConfig.Server
@@ -431,12 +621,18 @@ type ServerAuthCallbacks struct {
 	// PasswordCallback behaves like [ServerConfig.PasswordCallback].
 	PasswordCallback func(conn ConnMetadata, password []byte) (*Permissions, error)
 
+	// PasswordChangeCallback behaves like [ServerConfig.PasswordChangeCallback].
+	PasswordChangeCallback func(conn ConnMetadata, oldPassword, newPassword []byte) (*Permissions, error)
+
 	// PublicKeyCallback behaves like [ServerConfig.PublicKeyCallback].
 	PublicKeyCallback func(conn ConnMetadata, key PublicKey) (*Permissions, error)
 
 	// KeyboardInteractiveCallback behaves like [ServerConfig.KeyboardInteractiveCallback].
 	KeyboardInteractiveCallback func(conn ConnMetadata, client KeyboardInteractiveChallenge) (*Permissions, error)
 
+	// HostBasedCallback behaves like [ServerConfig.HostBasedCallback].
+	HostBasedCallback func(conn ConnMetadata, key PublicKey, clientHost, clientUser string) (*Permissions, error)
+
 	// GSSAPIWithMICConfig behaves like [ServerConfig.GSSAPIWithMICConfig].
 	GSSAPIWithMICConfig *GSSAPIWithMICConfig
 }
@@ -455,6 +651,21 @@ func (p *PartialSuccessError) Error() string {
 	return "ssh: authenticated with partial success"
 }
 
+// PasswordChangeRequiredError can be returned by
+// [ServerConfig.PasswordCallback] and [ServerConfig.PasswordChangeCallback]
+// to ask the client to change the password of the user, for example because
+// it has expired. The client is sent Prompt, in the language tagged
+// Language, and is expected to answer with the old and the new password,
+// which are passed to PasswordChangeCallback. See RFC 4252, section 8.
+type PasswordChangeRequiredError struct {
+	Prompt   string
+	Language string
+}
+
+func (p *PasswordChangeRequiredError) Error() string {
+	return "ssh: password change required"
+}
+
 // ErrNoAuth is the error value returned if no
 // authentication method has been passed yet. This happens as a normal
 // part of the authentication loop, since the client first tries
@@ -494,8 +705,10 @@ func (s *connection) serverAuthenticate(config *ServerConfig) (*Permissions, err
 	// changed if a PartialSuccessError is returned.
 	authConfig := ServerAuthCallbacks{
 		PasswordCallback:            config.PasswordCallback,
+		PasswordChangeCallback:      config.PasswordChangeCallback,
 		PublicKeyCallback:           config.PublicKeyCallback,
 		KeyboardInteractiveCallback: config.KeyboardInteractiveCallback,
+		HostBasedCallback:           config.HostBasedCallback,
 		GSSAPIWithMICConfig:         config.GSSAPIWithMICConfig,
 	}
 
@@ -569,16 +782,31 @@ userAuthLoop:
 				break
 			}
 			payload := userAuthReq.Payload
-			if len(payload) < 1 || payload[0] != 0 {
+			if len(payload) < 1 {
 				return nil, parseError(msgUserAuthRequest)
 			}
+			isChange := payload[0] != 0
 			payload = payload[1:]
 			password, payload, ok := parseString(payload)
+			if !ok {
+				return nil, parseError(msgUserAuthRequest)
+			}
+			if !isChange {
+				if len(payload) > 0 {
+					return nil, parseError(msgUserAuthRequest)
+				}
+				perms, authErr = authConfig.PasswordCallback(s, password)
+				break
+			}
+			newPassword, payload, ok := parseString(payload)
 			if !ok || len(payload) > 0 {
 				return nil, parseError(msgUserAuthRequest)
 			}
-
-			perms, authErr = authConfig.PasswordCallback(s, password)
+			if authConfig.PasswordChangeCallback == nil {
+				authErr = errors.New("ssh: password change not configured")
+				break
+			}
+			perms, authErr = authConfig.PasswordChangeCallback(s, password, newPassword)
 		case "keyboard-interactive":
 			if authConfig.KeyboardInteractiveCallback == nil {
 				authErr = errors.New("ssh: keyboard-interactive auth not configured")
@@ -694,7 +922,52 @@ userAuthLoop:
 
 				authErr = candidate.result
 				perms = candidate.perms
+				if _, isPartialSuccessError := authErr.(*PartialSuccessError); config.CheckSecurityKeyFlags && (authErr == nil || isPartialSuccessError) {
+					if err := checkSKSignature(sig, perms); err != nil {
+						authErr = err
+						perms = nil
+					}
+				}
+			}
+		case "hostbased":
+			if authConfig.HostBasedCallback == nil {
+				authErr = errors.New("ssh: hostbased auth not configured")
+				break
+			}
+			var req hostBasedAuthPayload
+			if err := Unmarshal(userAuthReq.Payload, &req); err != nil {
+				return nil, parseError(msgUserAuthRequest)
 			}
+			sig, rest, ok := parseSignature(req.Sig)
+			if !ok || len(rest) > 0 {
+				return nil, parseError(msgUserAuthRequest)
+			}
+			pubKey, err := ParsePublicKey(req.PubKey)
+			if err != nil {
+				return nil, err
+			}
+			if !contains(config.PublicKeyAuthAlgorithms, underlyingAlgo(req.Algoname)) ||
+				!contains(config.PublicKeyAuthAlgorithms, sig.Format) {
+				authErr = fmt.Errorf("ssh: algorithm %q not accepted", req.Algoname)
+				break
+			}
+			if !contains(algorithmsForKeyFormat(pubKey.Type()), req.Algoname) || !isAlgoCompatible(req.Algoname, sig.Format) {
+				authErr = fmt.Errorf("ssh: signature %q not compatible with host key algorithm %q", sig.Format, req.Algoname)
+				break
+			}
+			signedData := hostBasedSignedData(sessionID, hostBasedAuthMsg{
+				User:       userAuthReq.User,
+				Service:    userAuthReq.Service,
+				Method:     userAuthReq.Method,
+				Algoname:   req.Algoname,
+				PubKey:     req.PubKey,
+				ClientHost: req.ClientHost,
+				ClientUser: req.ClientUser,
+			})
+			if err := pubKey.Verify(signedData, sig); err != nil {
+				return nil, err
+			}
+			perms, authErr = authConfig.HostBasedCallback(s, pubKey, req.ClientHost, req.ClientUser)
 		case "gssapi-with-mic":
 			if authConfig.GSSAPIWithMICConfig == nil {
 				authErr = errors.New("ssh: gssapi-with-mic auth not configured")
@@ -751,6 +1024,10 @@ userAuthLoop:
 		if config.AuthLogCallback != nil {
 			config.AuthLogCallback(s, userAuthReq.Method, authErr)
 		}
+		config.Logger.Info("ssh: authentication attempt", "user", s.user, "method", userAuthReq.Method, "error", authErr)
+		if authErr != nil && userAuthReq.Method != "none" {
+			config.Metrics.AuthFailure(userAuthReq.Method)
+		}
 
 		var bannerErr *BannerError
 		if errors.As(authErr, &bannerErr) {
@@ -768,6 +1045,25 @@ userAuthLoop:
 			break userAuthLoop
 		}
 
+		var changeErr *PasswordChangeRequiredError
+		if userAuthReq.Method == "password" && authConfig.PasswordChangeCallback != nil && errors.As(authErr, &changeErr) {
+			// Ask for a new password instead of sending a failure. Each
+			// round counts as an attempt, so that clients can't guess
+			// passwords without limit through password changes.
+			authFailures++
+			if config.MaxAuthTries > 0 && authFailures >= config.MaxAuthTries {
+				continue
+			}
+			changeReq := &userAuthPasswdChangeReqMsg{
+				Prompt:   changeErr.Prompt,
+				Language: changeErr.Language,
+			}
+			if err := s.transport.writePacket(Marshal(changeReq)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
 		var failureMsg userAuthFailureMsg
 
 		if partialSuccess, ok := authErr.(*PartialSuccessError); ok {
@@ -825,6 +1121,9 @@ userAuthLoop:
 		if authConfig.KeyboardInteractiveCallback != nil {
 			failureMsg.Methods = append(failureMsg.Methods, "keyboard-interactive")
 		}
+		if authConfig.HostBasedCallback != nil {
+			failureMsg.Methods = append(failureMsg.Methods, "hostbased")
+		}
 		if authConfig.GSSAPIWithMICConfig != nil && authConfig.GSSAPIWithMICConfig.Server != nil &&
 			authConfig.GSSAPIWithMICConfig.AllowLogin != nil {
 			failureMsg.Methods = append(failureMsg.Methods, "gssapi-with-mic")
@@ -845,6 +1144,16 @@ userAuthLoop:
 	return perms, nil
 }
 
+// hostBasedAuthPayload is the method specific part of a "hostbased" user
+// authentication request, see hostBasedAuthMsg.
+type hostBasedAuthPayload struct {
+	Algoname   string
+	PubKey     []byte
+	ClientHost string
+	ClientUser string
+	Sig        []byte `ssh:"rest"`
+}
+
 // sshClientKeyboardInteractive implements a ClientKeyboardInteractive by
 // asking the client on the other side of a ServerConn.
 type sshClientKeyboardInteractive struct {
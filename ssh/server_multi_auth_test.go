@@ -410,3 +410,70 @@ func TestDynamicAuthCallbacks(t *testing.T) {
 		t.Fatal("server not returned partial success")
 	}
 }
+
+func TestAuthSequence(t *testing.T) {
+	errPwdAuthFailed := errors.New("password auth failed")
+	serverConfig := &ServerConfig{
+		PublicKeyCallback: func(conn ConnMetadata, key PublicKey) (*Permissions, error) {
+			if !bytes.Equal(key.Marshal(), testPublicKeys["rsa"].Marshal()) {
+				return nil, fmt.Errorf("pubkey for %q not acceptable", conn.User())
+			}
+			return nil, &PartialSuccessError{
+				Next: ServerAuthCallbacks{
+					PasswordCallback: func(conn ConnMetadata, password []byte) (*Permissions, error) {
+						if string(password) == clientPassword {
+							return nil, nil
+						}
+						return nil, errPwdAuthFailed
+					},
+				},
+			}
+		},
+	}
+
+	for _, tt := range []struct {
+		name          string
+		steps         []AuthMethod
+		wantErr       bool
+		wantSucceeded []string
+	}{
+		{
+			name:          "success",
+			steps:         []AuthMethod{PublicKeys(testSigners["rsa"]), Password(clientPassword)},
+			wantSucceeded: []string{"publickey", "password"},
+		},
+		{
+			name:          "wrong password",
+			steps:         []AuthMethod{PublicKeys(testSigners["rsa"]), Password("wrong")},
+			wantErr:       true,
+			wantSucceeded: []string{"publickey"},
+		},
+		{
+			name:          "not offered",
+			steps:         []AuthMethod{PublicKeys(testSigners["rsa"]), KeyboardInteractive(nil)},
+			wantErr:       true,
+			wantSucceeded: []string{"publickey"},
+		},
+		{
+			name:    "wrong key",
+			steps:   []AuthMethod{PublicKeys(testSigners["ecdsa"]), Password(clientPassword)},
+			wantErr: true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			seq := NewAuthSequence(tt.steps...)
+			clientConfig := &ClientConfig{
+				User:            "testuser",
+				Auth:            []AuthMethod{seq},
+				HostKeyCallback: InsecureIgnoreHostKey(),
+			}
+			_, err := doClientServerAuth(t, serverConfig, clientConfig)
+			if gotErr := err != nil; gotErr != tt.wantErr {
+				t.Fatalf("client login: %v, want error: %t", err, tt.wantErr)
+			}
+			if got := seq.Succeeded(); strings.Join(got, ",") != strings.Join(tt.wantSucceeded, ",") {
+				t.Errorf("Succeeded() = %q, want %q", got, tt.wantSucceeded)
+			}
+		})
+	}
+}
@@ -5,6 +5,10 @@
 package ssh
 
 import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"io"
@@ -136,7 +140,7 @@ func TestMaxAuthTriesNoneMethod(t *testing.T) {
 	}
 	for i := 0; i <= serverConfig.MaxAuthTries; i++ {
 		auth := new(noneAuth)
-		_, _, err := auth.auth(c.sessionID, clientConfig.User, c.transport, clientConfig.Rand, nil)
+		_, _, err := auth.auth(context.Background(), c.sessionID, clientConfig.User, c.transport, clientConfig.Rand, nil)
 		if i < serverConfig.MaxAuthTries {
 			if err != nil {
 				t.Fatal(err)
@@ -341,3 +345,88 @@ func (*markerConn) RemoteAddr() net.Addr { return nil }
 func (*markerConn) SetDeadline(t time.Time) error      { return nil }
 func (*markerConn) SetReadDeadline(t time.Time) error  { return nil }
 func (*markerConn) SetWriteDeadline(t time.Time) error { return nil }
+
+// testSKSigner signs like an sk-ssh-ed25519@openssh.com security key that
+// sets the given flags.
+type testSKSigner struct {
+	priv  ed25519.PrivateKey
+	flags byte
+}
+
+func (s *testSKSigner) PublicKey() PublicKey {
+	return &skEd25519PublicKey{application: "ssh:", PublicKey: s.priv.Public().(ed25519.PublicKey)}
+}
+
+func (s *testSKSigner) Sign(rand io.Reader, data []byte) (*Signature, error) {
+	appDigest := sha256.Sum256([]byte("ssh:"))
+	dataDigest := sha256.Sum256(data)
+	skf := SKSignature{Flags: s.flags, Counter: 1}
+	blob := Marshal(struct {
+		ApplicationDigest []byte `ssh:"rest"`
+		Flags             byte
+		Counter           uint32
+		MessageDigest     []byte `ssh:"rest"`
+	}{appDigest[:], skf.Flags, skf.Counter, dataDigest[:]})
+	return &Signature{
+		Format: KeyAlgoSKED25519,
+		Blob:   ed25519.Sign(s.priv, blob),
+		Rest:   Marshal(skf),
+	}, nil
+}
+
+func TestSKSignatureFlags(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name      string
+		flags     byte
+		perms     *Permissions
+		check     bool
+		wantError bool
+	}{
+		{"touched", SKFlagUserPresent, nil, true, false},
+		{"not touched", 0, nil, true, true},
+		{"not checked", 0, nil, false, false},
+		{"no-touch-required", 0, &Permissions{Extensions: map[string]string{"no-touch-required": ""}}, true, false},
+		{"verify-required", SKFlagUserPresent, &Permissions{CriticalOptions: map[string]string{"verify-required": ""}}, true, true},
+		{"verified", SKFlagUserPresent | SKFlagUserVerified, &Permissions{CriticalOptions: map[string]string{"verify-required": ""}}, true, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			c1, c2, err := netPipe()
+			if err != nil {
+				t.Fatalf("netPipe: %v", err)
+			}
+			defer c1.Close()
+			defer c2.Close()
+			serverConf := &ServerConfig{
+				PublicKeyCallback: func(conn ConnMetadata, key PublicKey) (*Permissions, error) {
+					return tt.perms, nil
+				},
+				CheckSecurityKeyFlags: tt.check,
+			}
+			serverConf.AddHostKey(testSigners["ecdsap256"])
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				NewServerConn(c1, serverConf)
+			}()
+
+			clientConf := ClientConfig{
+				User:            "user",
+				Auth:            []AuthMethod{PublicKeys(&testSKSigner{priv, tt.flags})},
+				HostKeyCallback: InsecureIgnoreHostKey(),
+			}
+			_, _, _, err = NewClientConn(c2, "", &clientConf)
+			if err != nil && !tt.wantError {
+				t.Errorf("got unexpected error %q", err)
+			} else if err == nil && tt.wantError {
+				t.Error("succeeded, but want error")
+			}
+			c2.Close()
+			<-done
+		})
+	}
+}
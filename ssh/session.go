@@ -9,11 +9,14 @@ package ssh
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"sync"
+	"time"
 )
 
 type Signal string
@@ -49,6 +52,9 @@ var signals = map[Signal]int{
 	SIGTERM: 15,
 }
 
+// TerminalModes are the modes of a pseudo-terminal requested with
+// RequestPty, mapping the opcodes below to their values. Boolean modes are
+// 1 when set and 0 when cleared.
 type TerminalModes map[uint8]uint32
 
 // POSIX terminal mode flags as listed in RFC 4254 Section 8.
@@ -130,7 +136,15 @@ type Session struct {
 	Stdout io.Writer
 	Stderr io.Writer
 
+	// KillDelay is the time to wait after sending SIGTERM to a command
+	// started with StartContext, RunContext, OutputContext or
+	// CombinedOutputContext whose context is done, before sending SIGKILL
+	// and closing the session. If KillDelay is zero, SIGKILL is sent
+	// immediately after SIGTERM.
+	KillDelay time.Duration
+
 	ch        Channel // the channel backing this session
+	client    *Client // the client that opened the session, if any
 	started   bool    // true once Start, Run or Shell is invoked.
 	copyFuncs []func() error
 	errors    chan error // one send per copyFunc
@@ -144,6 +158,15 @@ type Session struct {
 	stdinPipeWriter io.WriteCloser
 
 	exitStatus chan error
+	exited     chan struct{} // closed once the remote command has exited
+
+	closeOnce sync.Once
+	closed    chan struct{} // closed by Close
+
+	// ctxErr is non-nil if the session was started with a context. It
+	// receives the context error if the command was interrupted by the
+	// context, or nil otherwise.
+	ctxErr chan error
 }
 
 // SendRequest sends an out-of-band channel request on the SSH channel
@@ -153,6 +176,7 @@ func (s *Session) SendRequest(name string, wantReply bool, payload []byte) (bool
 }
 
 func (s *Session) Close() error {
+	s.closeOnce.Do(func() { close(s.closed) })
 	return s.ch.Close()
 }
 
@@ -187,17 +211,12 @@ type ptyRequestMsg struct {
 }
 
 // RequestPty requests the association of a pty with the session on the remote host.
+// It returns an error if termmodes does not pass TerminalModes.Validate.
 func (s *Session) RequestPty(term string, h, w int, termmodes TerminalModes) error {
-	var tm []byte
-	for k, v := range termmodes {
-		kv := struct {
-			Key byte
-			Val uint32
-		}{k, v}
-
-		tm = append(tm, Marshal(&kv)...)
+	if err := termmodes.Validate(); err != nil {
+		return err
 	}
-	tm = append(tm, tty_OP_END)
+	tm := termmodes.marshal()
 	req := ptyRequestMsg{
 		Term:     term,
 		Columns:  uint32(w),
@@ -267,6 +286,30 @@ func (s *Session) Signal(sig Signal) error {
 	return err
 }
 
+// RFC 4335 Section 3.
+type breakMsg struct {
+	Length uint32
+}
+
+// Break asks the server to send a BREAK of the given duration, rounded to
+// milliseconds, on the serial line or terminal of the session, as used by
+// console servers. A duration of zero asks for the default BREAK of the
+// remote side. It returns an error if the server does not support or
+// perform BREAK.
+func (s *Session) Break(d time.Duration) error {
+	if d < 0 || d/time.Millisecond > math.MaxUint32 {
+		return fmt.Errorf("ssh: invalid break duration %v", d)
+	}
+	msg := breakMsg{
+		Length: uint32(d / time.Millisecond),
+	}
+	ok, err := s.ch.SendRequest("break", true, Marshal(&msg))
+	if err == nil && !ok {
+		err = errors.New("ssh: break failed")
+	}
+	return err
+}
+
 // RFC 4254 Section 6.5.
 type execMsg struct {
 	Command string
@@ -293,6 +336,59 @@ func (s *Session) Start(cmd string) error {
 	return s.start()
 }
 
+// StartContext is like Start, but the remote command is interrupted if ctx
+// is done before it exits: the command is sent SIGTERM and, after
+// Session.KillDelay, SIGKILL, and then the session is closed. In that case
+// Wait returns the context's error.
+func (s *Session) StartContext(ctx context.Context, cmd string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := s.Start(cmd); err != nil {
+		return err
+	}
+	s.watchContext(ctx)
+	return nil
+}
+
+// watchContext interrupts the remote command when ctx is done.
+func (s *Session) watchContext(ctx context.Context) {
+	s.ctxErr = make(chan error, 1)
+	go func() {
+		select {
+		case <-s.exited:
+			s.ctxErr <- nil
+			return
+		case <-ctx.Done():
+		}
+		select {
+		case <-s.exited:
+			// The command exited anyway, don't report the context
+			// error.
+			s.ctxErr <- nil
+			return
+		default:
+		}
+
+		s.Signal(SIGTERM)
+		if s.KillDelay > 0 {
+			t := time.NewTimer(s.KillDelay)
+			select {
+			case <-s.exited:
+			case <-t.C:
+			}
+			t.Stop()
+		}
+		select {
+		case <-s.exited:
+		default:
+			s.Signal(SIGKILL)
+			s.Close()
+		}
+		s.ctxErr <- ctx.Err()
+	}()
+}
+
 // Run runs cmd on the remote host. Typically, the remote
 // server passes cmd to the shell for interpretation.
 // A Session only accepts one call to Run, Start, Shell, Output,
@@ -314,6 +410,16 @@ func (s *Session) Run(cmd string) error {
 	return s.Wait()
 }
 
+// RunContext is like Run, but the remote command is interrupted if ctx is
+// done before it exits. See StartContext.
+func (s *Session) RunContext(ctx context.Context, cmd string) error {
+	err := s.StartContext(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	return s.Wait()
+}
+
 // Output runs cmd on the remote host and returns its standard output.
 func (s *Session) Output(cmd string) ([]byte, error) {
 	if s.Stdout != nil {
@@ -325,6 +431,18 @@ func (s *Session) Output(cmd string) ([]byte, error) {
 	return b.Bytes(), err
 }
 
+// OutputContext is like Output, but the remote command is interrupted if ctx
+// is done before it exits. See StartContext.
+func (s *Session) OutputContext(ctx context.Context, cmd string) ([]byte, error) {
+	if s.Stdout != nil {
+		return nil, errors.New("ssh: Stdout already set")
+	}
+	var b bytes.Buffer
+	s.Stdout = &b
+	err := s.RunContext(ctx, cmd)
+	return b.Bytes(), err
+}
+
 type singleWriter struct {
 	b  bytes.Buffer
 	mu sync.Mutex
@@ -352,6 +470,22 @@ func (s *Session) CombinedOutput(cmd string) ([]byte, error) {
 	return b.b.Bytes(), err
 }
 
+// CombinedOutputContext is like CombinedOutput, but the remote command is
+// interrupted if ctx is done before it exits. See StartContext.
+func (s *Session) CombinedOutputContext(ctx context.Context, cmd string) ([]byte, error) {
+	if s.Stdout != nil {
+		return nil, errors.New("ssh: Stdout already set")
+	}
+	if s.Stderr != nil {
+		return nil, errors.New("ssh: Stderr already set")
+	}
+	var b singleWriter
+	s.Stdout = &b
+	s.Stderr = &b
+	err := s.RunContext(ctx, cmd)
+	return b.b.Bytes(), err
+}
+
 // Shell starts a login shell on the remote host. A Session only
 // accepts one call to Run, Start, Shell, Output, or CombinedOutput.
 func (s *Session) Shell() error {
@@ -411,6 +545,11 @@ func (s *Session) Wait() error {
 			copyError = err
 		}
 	}
+	if s.ctxErr != nil {
+		if err := <-s.ctxErr; err != nil {
+			return err
+		}
+	}
 	if waitErr != nil {
 		return waitErr
 	}
@@ -437,6 +576,7 @@ func (s *Session) wait(reqs <-chan *Request) error {
 
 			// Must sanitize strings?
 			wm.signal = sigval.Signal
+			wm.coreDumped = sigval.CoreDumped
 			wm.msg = sigval.Error
 			wm.lang = sigval.Lang
 		default:
@@ -589,8 +729,12 @@ func newSession(ch Channel, reqs <-chan *Request) (*Session, error) {
 		ch: ch,
 	}
 	s.exitStatus = make(chan error, 1)
+	s.exited = make(chan struct{})
+	s.closed = make(chan struct{})
 	go func() {
-		s.exitStatus <- s.wait(reqs)
+		err := s.wait(reqs)
+		close(s.exited)
+		s.exitStatus <- err
 	}()
 
 	return s, nil
@@ -605,13 +749,49 @@ func (e *ExitError) Error() string {
 	return e.Waitmsg.String()
 }
 
+// Unwrap returns the *ExitSignal describing the signal that terminated the
+// remote command, if any, so that it can be matched with errors.As.
+func (e *ExitError) Unwrap() error {
+	if sig := e.ExitSignal(); sig != nil {
+		return sig
+	}
+	return nil
+}
+
+// ExitSignal describes the signal that terminated a remote command, as
+// reported by the server with an exit-signal request. See RFC 4254, section
+// 6.10.
+type ExitSignal struct {
+	// Signal is the name of the signal without the "SIG" prefix, such as
+	// SIGTERM.
+	Signal Signal
+	// CoreDumped reports whether the remote command dumped core.
+	CoreDumped bool
+	// Message is the error message given by the server, and Lang its
+	// language tag. See RFC 3066.
+	Message string
+	Lang    string
+}
+
+func (e *ExitSignal) Error() string {
+	str := fmt.Sprintf("remote command terminated by signal %v", e.Signal)
+	if e.CoreDumped {
+		str += " (core dumped)"
+	}
+	if e.Message != "" {
+		str += ": " + e.Message
+	}
+	return str
+}
+
 // Waitmsg stores the information about an exited remote command
 // as reported by Wait.
 type Waitmsg struct {
-	status int
-	signal string
-	msg    string
-	lang   string
+	status     int
+	signal     string
+	coreDumped bool
+	msg        string
+	lang       string
 }
 
 // ExitStatus returns the exit status of the remote command.
@@ -625,6 +805,26 @@ func (w Waitmsg) Signal() string {
 	return w.signal
 }
 
+// CoreDumped reports whether the remote command dumped core when it was
+// terminated by a signal.
+func (w Waitmsg) CoreDumped() bool {
+	return w.coreDumped
+}
+
+// ExitSignal returns the details of the exit signal of the remote command,
+// or nil if it was not terminated by a signal.
+func (w Waitmsg) ExitSignal() *ExitSignal {
+	if w.signal == "" {
+		return nil
+	}
+	return &ExitSignal{
+		Signal:     Signal(w.signal),
+		CoreDumped: w.coreDumped,
+		Message:    w.msg,
+		Lang:       w.lang,
+	}
+}
+
 // Msg returns the exit message given by the remote command
 func (w Waitmsg) Msg() string {
 	return w.msg
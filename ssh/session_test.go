@@ -8,6 +8,7 @@ package ssh
 
 import (
 	"bytes"
+	"context"
 	crypto_rand "crypto/rand"
 	"errors"
 	"io"
@@ -15,6 +16,7 @@ import (
 	"net"
 	"sync"
 	"testing"
+	"time"
 
 	"golang.org/x/crypto/ssh/terminal"
 )
@@ -218,6 +220,10 @@ func TestExitStatusNonZero(t *testing.T) {
 	if e.ExitStatus() != 15 {
 		t.Fatalf("expected command to exit with 15 but got %v", e.ExitStatus())
 	}
+	var sig *ExitSignal
+	if errors.As(err, &sig) {
+		t.Errorf("got exit signal %+v for a command that exited normally", sig)
+	}
 }
 
 // Test 0 exit status is returned correctly.
@@ -267,6 +273,71 @@ func TestChannelWithDeadlinesImplementation(t *testing.T) {
 	}
 }
 
+func TestSessionRunContextCancel(t *testing.T) {
+	signals := make(chan string, 2)
+	handler := func(ch Channel, in <-chan *Request, t *testing.T) {
+		defer ch.Close()
+		for req := range in {
+			switch req.Type {
+			case "exec":
+				req.Reply(true, nil)
+			case "signal":
+				var msg signalMsg
+				if err := Unmarshal(req.Payload, &msg); err != nil {
+					t.Errorf("Unmarshal: %v", err)
+					return
+				}
+				signals <- msg.Signal
+				if msg.Signal == string(SIGKILL) {
+					return
+				}
+			default:
+				req.Reply(false, nil)
+			}
+		}
+	}
+	conn := dial(handler, t)
+	defer conn.Close()
+	session, err := conn.NewSession()
+	if err != nil {
+		t.Fatalf("Unable to request new session: %v", err)
+	}
+	defer session.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := session.RunContext(ctx, "sleep 100"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("RunContext: got %v, want %v", err, context.DeadlineExceeded)
+	}
+	for _, want := range []Signal{SIGTERM, SIGKILL} {
+		if got := <-signals; got != string(want) {
+			t.Errorf("got signal %q, want %q", got, want)
+		}
+	}
+}
+
+func TestSessionOutputContext(t *testing.T) {
+	conn := dial(fixedOutputHandler, t)
+	defer conn.Close()
+	session, err := conn.NewSession()
+	if err != nil {
+		t.Fatalf("Unable to request new session: %v", err)
+	}
+	defer session.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	buf, err := session.OutputContext(ctx, "") // cmd is ignored by fixedOutputHandler
+	if err != nil {
+		t.Error("Remote command did not exit cleanly:", err)
+	}
+	if w, g := "this-is-stdout.", string(buf); g != w {
+		t.Error("Remote command did not return expected string:")
+		t.Logf("want %q", w)
+		t.Logf("got  %q", g)
+	}
+}
+
 // Test exit signal and status are both returned correctly.
 func TestExitSignalAndStatus(t *testing.T) {
 	conn := dial(exitSignalAndStatusHandler, t)
@@ -315,6 +386,15 @@ func TestKnownExitSignalOnly(t *testing.T) {
 	if e.Signal() != "TERM" || e.ExitStatus() != 143 {
 		t.Fatalf("expected command to exit with signal TERM and status 143 but got signal %s and status %v", e.Signal(), e.ExitStatus())
 	}
+
+	var sig *ExitSignal
+	if !errors.As(err, &sig) {
+		t.Fatalf("errors.As(%v, *ExitSignal) failed", err)
+	}
+	want := ExitSignal{Signal: SIGTERM, Message: "Process terminated", Lang: "en-GB-oed"}
+	if *sig != want {
+		t.Errorf("got exit signal %+v, want %+v", *sig, want)
+	}
 }
 
 // Test exit signal and status are both returned correctly.
@@ -918,3 +998,103 @@ func TestServerClientAuthCallback(t *testing.T) {
 		t.Errorf("username = %q; want %q", got, someUsername)
 	}
 }
+
+func TestNoMoreSessions(t *testing.T) {
+	c1, c2, err := netPipe()
+	if err != nil {
+		t.Fatalf("netPipe: %v", err)
+	}
+	defer c1.Close()
+	defer c2.Close()
+
+	serverConf := &ServerConfig{NoClientAuth: true}
+	serverConf.AddHostKey(testSigners["ecdsa"])
+	go func() {
+		conn, chans, reqs, err := NewServerConn(c2, serverConf)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		go DiscardRequests(reqs)
+		for newCh := range chans {
+			ch, reqs, err := newCh.Accept()
+			if err != nil {
+				return
+			}
+			go DiscardRequests(reqs)
+			defer ch.Close()
+		}
+	}()
+
+	conn, chans, reqs, err := NewClientConn(c1, "", &ClientConfig{
+		User:            "user",
+		HostKeyCallback: InsecureIgnoreHostKey(),
+	})
+	if err != nil {
+		t.Fatalf("NewClientConn: %v", err)
+	}
+	client := NewClient(conn, chans, reqs)
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		t.Fatalf("NewSession: %v", err)
+	}
+	defer session.Close()
+	if err := client.NoMoreSessions(); err != nil {
+		t.Fatalf("NoMoreSessions: %v", err)
+	}
+	if _, err := client.NewSession(); err == nil {
+		t.Error("NewSession succeeded after NoMoreSessions")
+	}
+
+	// The server must enforce the request too, for clients bypassing the
+	// check in NewSession.
+	_, _, err = client.OpenChannel("session", nil)
+	var openErr *OpenChannelError
+	if !errors.As(err, &openErr) || openErr.Reason != Prohibited {
+		t.Errorf("OpenChannel: got %v, want %v rejection", err, Prohibited)
+	}
+	ch, _, err := client.OpenChannel("direct-tcpip-test", nil)
+	if err != nil {
+		t.Errorf("OpenChannel of another type: %v", err)
+	} else {
+		ch.Close()
+	}
+}
+
+func TestSessionBreak(t *testing.T) {
+	lengths := make(chan uint32, 1)
+	conn := dial(func(ch Channel, in <-chan *Request, t *testing.T) {
+		defer ch.Close()
+		for req := range in {
+			var msg breakMsg
+			if req.Type != "break" || Unmarshal(req.Payload, &msg) != nil {
+				req.Reply(false, nil)
+				continue
+			}
+			lengths <- msg.Length
+			req.Reply(msg.Length <= 1000, nil)
+		}
+	}, t)
+	defer conn.Close()
+	session, err := conn.NewSession()
+	if err != nil {
+		t.Fatalf("Unable to request new session: %v", err)
+	}
+	defer session.Close()
+
+	if err := session.Break(500 * time.Millisecond); err != nil {
+		t.Errorf("Break: %v", err)
+	}
+	if got := <-lengths; got != 500 {
+		t.Errorf("server got break length %d, want 500", got)
+	}
+	if err := session.Break(2 * time.Second); err == nil {
+		t.Error("Break succeeded although the server refused it")
+	}
+	<-lengths
+	if err := session.Break(-time.Second); err == nil {
+		t.Error("Break accepted a negative duration")
+	}
+}
@@ -0,0 +1,30 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+// SessionBindCallback is the function type used to bind a connection to the
+// server it was made to, before the client authenticates. It is passed the
+// host key that the server authenticated the first key exchange with, the
+// session ID, and the signature of the session ID made with the host key,
+// in the wire format. Together, they prove to a third party, such as an
+// agent, which server the session ID belongs to, as with
+// agent.SessionBindCallback. If it returns an error, the handshake fails.
+//
+// It is not called if the server was authenticated otherwise, as by GSSAPI
+// key exchange.
+type SessionBindCallback func(hostKey PublicKey, sessionID, signature []byte) error
+
+// SessionHostKey returns the host key that the server authenticated the
+// first key exchange with, and its signature of the session ID, as passed
+// to ClientConfig.SessionBindCallback. It returns nil if the server was
+// authenticated otherwise, or if the underlying Conn is not one created by
+// this package.
+func (c *Client) SessionHostKey() (hostKey PublicKey, signature []byte) {
+	conn, ok := c.Conn.(*connection)
+	if !ok || conn.sessionHostKey == nil {
+		return nil, nil
+	}
+	return conn.sessionHostKey, dup(conn.sessionSignature)
+}
@@ -0,0 +1,93 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestSessionBindCallback(t *testing.T) {
+	serverConf := &ServerConfig{NoClientAuth: true}
+	serverConf.AddHostKey(testSigners["ecdsa"])
+
+	var gotKey PublicKey
+	var gotID, gotSig []byte
+	clientConf := &ClientConfig{
+		User:            "user",
+		HostKeyCallback: InsecureIgnoreHostKey(),
+		SessionBindCallback: func(hostKey PublicKey, sessionID, signature []byte) error {
+			gotKey, gotID, gotSig = hostKey, sessionID, signature
+			return nil
+		},
+	}
+	client, err := dialSessionBind(t, serverConf, clientConf)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer client.Close()
+
+	if gotKey == nil || !bytes.Equal(gotKey.Marshal(), testPublicKeys["ecdsa"].Marshal()) {
+		t.Fatalf("SessionBindCallback got host key %v", gotKey)
+	}
+	if !bytes.Equal(gotID, client.SessionID()) {
+		t.Errorf("SessionBindCallback got session ID %x, want %x", gotID, client.SessionID())
+	}
+	sig := new(Signature)
+	if err := Unmarshal(gotSig, sig); err != nil {
+		t.Fatalf("Unmarshal signature: %v", err)
+	}
+	if err := gotKey.Verify(gotID, sig); err != nil {
+		t.Errorf("signature of the session ID does not verify: %v", err)
+	}
+
+	key, signature := client.SessionHostKey()
+	if key == nil || !bytes.Equal(key.Marshal(), gotKey.Marshal()) || !bytes.Equal(signature, gotSig) {
+		t.Errorf("SessionHostKey = %v, %x, want the values passed to SessionBindCallback", key, signature)
+	}
+}
+
+func TestSessionBindCallbackError(t *testing.T) {
+	serverConf := &ServerConfig{NoClientAuth: true}
+	serverConf.AddHostKey(testSigners["ed25519"])
+	errBind := errors.New("binding failed")
+	clientConf := &ClientConfig{
+		User:                "user",
+		HostKeyCallback:     InsecureIgnoreHostKey(),
+		SessionBindCallback: func(PublicKey, []byte, []byte) error { return errBind },
+	}
+	if _, err := dialSessionBind(t, serverConf, clientConf); !errors.Is(err, errBind) {
+		t.Errorf("dial = %v, want %v", err, errBind)
+	}
+}
+
+func dialSessionBind(t *testing.T, serverConf *ServerConfig, clientConf *ClientConfig) (*Client, error) {
+	c1, c2, err := netPipe()
+	if err != nil {
+		t.Fatalf("netPipe: %v", err)
+	}
+	t.Cleanup(func() {
+		c1.Close()
+		c2.Close()
+	})
+	go func() {
+		conn, chans, reqs, err := NewServerConn(c2, serverConf)
+		if err != nil {
+			return
+		}
+		go DiscardRequests(reqs)
+		go func() {
+			for range chans {
+			}
+		}()
+		conn.Wait()
+	}()
+	conn, chans, reqs, err := NewClientConn(c1, "server:22", clientConf)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(conn, chans, reqs), nil
+}
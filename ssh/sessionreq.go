@@ -0,0 +1,114 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import "fmt"
+
+// This file provides typed parsing of the session requests of RFC 4254,
+// section 6, for servers.
+
+// parseSessionRequest checks that req is of type want and unmarshals its
+// payload into out.
+func parseSessionRequest(req *Request, want string, out interface{}) error {
+	if req.Type != want {
+		return fmt.Errorf("ssh: request type %q is not %s", req.Type, want)
+	}
+	return Unmarshal(req.Payload, out)
+}
+
+// PtyRequest is the decoded payload of a pty-req request, with which a
+// client asks for a pseudo-terminal. See RFC 4254, section 6.2.
+type PtyRequest struct {
+	// Term is the value of the TERM environment variable, such as
+	// "xterm".
+	Term string
+	// Columns and Rows are the size of the terminal in characters, and
+	// Width and Height in pixels. Either may be zero.
+	Columns, Rows uint32
+	Width, Height uint32
+	Modes         TerminalModes
+}
+
+// ParsePtyRequest decodes the payload of a pty-req request.
+func ParsePtyRequest(req *Request) (*PtyRequest, error) {
+	var msg ptyRequestMsg
+	if err := parseSessionRequest(req, "pty-req", &msg); err != nil {
+		return nil, err
+	}
+	modes, err := ParseTerminalModes([]byte(msg.Modelist))
+	if err != nil {
+		return nil, err
+	}
+	return &PtyRequest{
+		Term:    msg.Term,
+		Columns: msg.Columns,
+		Rows:    msg.Rows,
+		Width:   msg.Width,
+		Height:  msg.Height,
+		Modes:   modes,
+	}, nil
+}
+
+// ExecRequest is the payload of an exec request, with which a client asks
+// to run a command. See RFC 4254, section 6.5.
+type ExecRequest struct {
+	Command string
+}
+
+// ParseExecRequest decodes the payload of an exec request.
+func ParseExecRequest(req *Request) (*ExecRequest, error) {
+	var msg ExecRequest
+	if err := parseSessionRequest(req, "exec", &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// EnvRequest is the payload of an env request, with which a client asks
+// to set an environment variable. See RFC 4254, section 6.4.
+type EnvRequest struct {
+	Name  string
+	Value string
+}
+
+// ParseEnvRequest decodes the payload of an env request.
+func ParseEnvRequest(req *Request) (*EnvRequest, error) {
+	var msg EnvRequest
+	if err := parseSessionRequest(req, "env", &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// SubsystemRequest is the payload of a subsystem request, with which a
+// client asks to run a subsystem such as "sftp". See RFC 4254, section 6.5.
+type SubsystemRequest struct {
+	Subsystem string
+}
+
+// ParseSubsystemRequest decodes the payload of a subsystem request.
+func ParseSubsystemRequest(req *Request) (*SubsystemRequest, error) {
+	var msg SubsystemRequest
+	if err := parseSessionRequest(req, "subsystem", &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// WindowChangeRequest is the payload of a window-change request, with which
+// a client reports the new size of its terminal. See RFC 4254, section 6.7.
+type WindowChangeRequest struct {
+	Columns, Rows uint32
+	Width, Height uint32
+}
+
+// ParseWindowChangeRequest decodes the payload of a window-change request.
+func ParseWindowChangeRequest(req *Request) (*WindowChangeRequest, error) {
+	var msg WindowChangeRequest
+	if err := parseSessionRequest(req, "window-change", &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
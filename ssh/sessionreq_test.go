@@ -0,0 +1,93 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSessionRequests(t *testing.T) {
+	parsed := make(chan interface{}, 5)
+	conn := dial(func(ch Channel, in <-chan *Request, t *testing.T) {
+		defer ch.Close()
+		for req := range in {
+			var msg interface{}
+			var err error
+			switch req.Type {
+			case "pty-req":
+				msg, err = ParsePtyRequest(req)
+			case "env":
+				msg, err = ParseEnvRequest(req)
+			case "window-change":
+				msg, err = ParseWindowChangeRequest(req)
+			case "subsystem":
+				msg, err = ParseSubsystemRequest(req)
+			case "exec":
+				msg, err = ParseExecRequest(req)
+			}
+			if err != nil {
+				t.Errorf("parsing %s request: %v", req.Type, err)
+			}
+			parsed <- msg
+			req.Reply(req.Type != "exec", nil)
+		}
+	}, t)
+	defer conn.Close()
+
+	// Exec and subsystem requests start the session, so they are sent on
+	// sessions of their own.
+	newSession := func() *Session {
+		session, err := conn.NewSession()
+		if err != nil {
+			t.Fatalf("Unable to request new session: %v", err)
+		}
+		t.Cleanup(func() { session.Close() })
+		return session
+	}
+
+	session := newSession()
+	if err := session.RequestPty("xterm", 24, 80, TerminalModes{ECHO: 0}); err != nil {
+		t.Fatalf("RequestPty: %v", err)
+	}
+	want := interface{}(&PtyRequest{Term: "xterm", Columns: 80, Rows: 24, Width: 640, Height: 192, Modes: TerminalModes{ECHO: 0}})
+	if got := <-parsed; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	if err := session.Setenv("LANG", "C"); err != nil {
+		t.Fatalf("Setenv: %v", err)
+	}
+	want = &EnvRequest{Name: "LANG", Value: "C"}
+	if got := <-parsed; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	if err := session.WindowChange(50, 132); err != nil {
+		t.Fatalf("WindowChange: %v", err)
+	}
+	want = &WindowChangeRequest{Columns: 132, Rows: 50, Width: 1056, Height: 400}
+	if got := <-parsed; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	if err := newSession().RequestSubsystem("sftp"); err != nil {
+		t.Fatalf("RequestSubsystem: %v", err)
+	}
+	want = &SubsystemRequest{Subsystem: "sftp"}
+	if got := <-parsed; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	newSession().Start("ls -l")
+	want = &ExecRequest{Command: "ls -l"}
+	if got := <-parsed; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	if _, err := ParseExecRequest(&Request{Type: "env", Payload: Marshal(&EnvRequest{"A", "B"})}); err == nil {
+		t.Error("ParseExecRequest accepted an env request")
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// This file implements signing with U2F/FIDO2 security keys, in the manner
+// of the security key middleware of OpenSSH, see openssh/PROTOCOL.u2f.
+
+// An SKProvider talks to security keys, playing the part of the middleware
+// that OpenSSH loads with its SecurityKeyProvider option. The package
+// golang.org/x/crypto/ssh/fido2 implements it for FIDO2 devices.
+type SKProvider interface {
+	// Sign asks a security key to sign req. It is responsible for
+	// waiting for the user to touch the key, and for verifying the user
+	// if req asks for it.
+	Sign(req *SKSignRequest) (*SKSignResponse, error)
+}
+
+// SKSignRequest is a request to sign with a security key.
+type SKSignRequest struct {
+	// Algorithm is the key type, KeyAlgoSKECDSA256 or KeyAlgoSKED25519.
+	Algorithm string
+
+	// Application is the FIDO relying party ID of the key, usually
+	// "ssh:".
+	Application string
+
+	// KeyHandle is the FIDO credential ID of the key.
+	KeyHandle []byte
+
+	// Flags are the requirements of the key: SKFlagUserPresent if the
+	// user must touch the security key, and SKFlagUserVerified if the
+	// security key must also verify the user.
+	Flags byte
+
+	// Digest is the SHA-256 hash of the data, which the security key
+	// signs as the FIDO client data hash.
+	Digest []byte
+}
+
+// SKSignResponse is the result of an SKSignRequest.
+type SKSignResponse struct {
+	// Flags and Counter are the values reported by the security key,
+	// see SKSignature.
+	Flags   byte
+	Counter uint32
+
+	// Signature is the ASN.1 DER encoded signature for ECDSA keys, and
+	// the 64-byte signature for Ed25519 keys.
+	Signature []byte
+}
+
+type skSigner struct {
+	pub       PublicKey
+	keyHandle []byte
+	flags     byte
+	provider  SKProvider
+}
+
+// NewSKSigner returns a Signer for the security key public key pub, which
+// must be an sk-ecdsa-sha2-nistp256@openssh.com or sk-ssh-ed25519@openssh.com
+// key, whose signatures are made by provider. keyHandle and flags are the
+// credential ID and requirements of the key, as stored in the private key
+// file; see ParseSKPrivateKey.
+func NewSKSigner(pub PublicKey, keyHandle []byte, flags byte, provider SKProvider) (Signer, error) {
+	switch pub.(type) {
+	case *skECDSAPublicKey, *skEd25519PublicKey:
+	default:
+		return nil, fmt.Errorf("ssh: %s is not a security key", pub.Type())
+	}
+	return &skSigner{pub: pub, keyHandle: keyHandle, flags: flags, provider: provider}, nil
+}
+
+func (s *skSigner) PublicKey() PublicKey {
+	return s.pub
+}
+
+func (s *skSigner) application() string {
+	switch k := s.pub.(type) {
+	case *skECDSAPublicKey:
+		return k.application
+	case *skEd25519PublicKey:
+		return k.application
+	}
+	return ""
+}
+
+func (s *skSigner) Sign(rand io.Reader, data []byte) (*Signature, error) {
+	digest := sha256.Sum256(data)
+	resp, err := s.provider.Sign(&SKSignRequest{
+		Algorithm:   s.pub.Type(),
+		Application: s.application(),
+		KeyHandle:   s.keyHandle,
+		Flags:       s.flags,
+		Digest:      digest[:],
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sig := &Signature{
+		Format: s.pub.Type(),
+		Rest:   Marshal(SKSignature{Flags: resp.Flags, Counter: resp.Counter}),
+	}
+	if _, ok := s.pub.(*skECDSAPublicKey); ok {
+		var ecSig struct {
+			R, S *big.Int
+		}
+		if rest, err := asn1.Unmarshal(resp.Signature, &ecSig); err != nil || len(rest) > 0 {
+			return nil, errors.New("ssh: security key returned an invalid ECDSA signature")
+		}
+		sig.Blob = Marshal(ecSig)
+	} else {
+		sig.Blob = resp.Signature
+	}
+
+	// A signature by another credential, for example one that the
+	// provider picked from several security keys, would only fail later
+	// on the server.
+	if err := s.pub.Verify(data, sig); err != nil {
+		return nil, fmt.Errorf("ssh: security key signature did not verify: %w", err)
+	}
+	return sig, nil
+}
+
+type openSSHSKPrivateKey struct {
+	Flags     byte
+	KeyHandle []byte
+	Reserved  []byte
+	Comment   string
+	Pad       []byte `ssh:"rest"`
+}
+
+// ParseSKPrivateKey returns a Signer from a PEM encoded OpenSSH private key
+// file for a security key, as written by ssh-keygen -t ecdsa-sk or
+// ed25519-sk. Such files only hold a reference to the key, which stays on
+// the security key; the signatures are made by provider. If the file is
+// encrypted, it returns a PassphraseMissingError.
+func ParseSKPrivateKey(pemBytes []byte, provider SKProvider) (Signer, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, errors.New("ssh: no key found")
+	}
+	if block.Type != "OPENSSH PRIVATE KEY" {
+		return nil, fmt.Errorf("ssh: unsupported key type %q", block.Type)
+	}
+	pk1, err := decodeOpenSSHPrivateKey(block.Bytes, unencryptedOpenSSHKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var pub PublicKey
+	var rest []byte
+	switch pk1.Keytype {
+	case KeyAlgoSKECDSA256:
+		pub, rest, err = parseSKECDSA(pk1.Rest)
+	case KeyAlgoSKED25519:
+		pub, rest, err = parseSKEd25519(pk1.Rest)
+	default:
+		return nil, fmt.Errorf("ssh: %s is not a security key", pk1.Keytype)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The public key, which ends with the application, is followed by
+	// the fields of the private key.
+	var key openSSHSKPrivateKey
+	if err := Unmarshal(rest, &key); err != nil {
+		return nil, err
+	}
+	if err := checkOpenSSHKeyPadding(key.Pad); err != nil {
+		return nil, err
+	}
+	return NewSKSigner(pub, key.KeyHandle, key.Flags, provider)
+}
@@ -0,0 +1,145 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/ssh/testdata"
+)
+
+// fakeSKProvider signs like a security key holding a single credential.
+type fakeSKProvider struct {
+	key       interface{}
+	keyHandle []byte
+	reqs      []*SKSignRequest
+}
+
+func (p *fakeSKProvider) Sign(req *SKSignRequest) (*SKSignResponse, error) {
+	p.reqs = append(p.reqs, req)
+	if !bytes.Equal(req.KeyHandle, p.keyHandle) {
+		return nil, errors.New("unknown credential")
+	}
+	resp := &SKSignResponse{Flags: req.Flags, Counter: 42}
+	appDigest := sha256.Sum256([]byte(req.Application))
+	signed := append(appDigest[:], resp.Flags, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(signed[33:], resp.Counter)
+	signed = append(signed, req.Digest...)
+
+	var err error
+	switch k := p.key.(type) {
+	case ed25519.PrivateKey:
+		resp.Signature = ed25519.Sign(k, signed)
+	case *ecdsa.PrivateKey:
+		h := sha256.Sum256(signed)
+		resp.Signature, err = ecdsa.SignASN1(rand.Reader, k, h[:])
+	}
+	return resp, err
+}
+
+// marshalSKPrivateKey writes an unencrypted OpenSSH private key file for the
+// security key pub.
+func marshalSKPrivateKey(pub PublicKey, keyHandle []byte, flags byte) []byte {
+	pk1 := openSSHPrivateKey{Check1: 1, Check2: 1, Keytype: pub.Type()}
+	var w struct {
+		Name string
+		Rest []byte `ssh:"rest"`
+	}
+	if err := Unmarshal(pub.Marshal(), &w); err != nil {
+		panic(err)
+	}
+	pk1.Rest = append(w.Rest, Marshal(openSSHSKPrivateKey{Flags: flags, KeyHandle: keyHandle, Comment: "user@host"})...)
+	block := Marshal(pk1)
+	for i := 1; len(block)%8 != 0; i++ {
+		block = append(block, byte(i))
+	}
+	key := append([]byte(privateKeyAuthMagic), Marshal(openSSHEncryptedPrivateKey{
+		CipherName:   "none",
+		KdfName:      "none",
+		NumKeys:      1,
+		PubKey:       pub.Marshal(),
+		PrivKeyBlock: block,
+	})...)
+	return pem.EncodeToMemory(&pem.Block{Type: "OPENSSH PRIVATE KEY", Bytes: key})
+}
+
+func TestParseSKPrivateKey(t *testing.T) {
+	edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecPriv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		pub  PublicKey
+		priv interface{}
+	}{
+		{&skEd25519PublicKey{application: "ssh:", PublicKey: edPub}, edPriv},
+		{&skECDSAPublicKey{application: "ssh:test", PublicKey: ecPriv.PublicKey}, ecPriv},
+	} {
+		keyHandle := []byte("key handle")
+		provider := &fakeSKProvider{key: tt.priv, keyHandle: keyHandle}
+		pemBytes := marshalSKPrivateKey(tt.pub, keyHandle, SKFlagUserPresent|SKFlagUserVerified)
+		signer, err := ParseSKPrivateKey(pemBytes, provider)
+		if err != nil {
+			t.Fatalf("%s: ParseSKPrivateKey: %v", tt.pub.Type(), err)
+		}
+		if !bytes.Equal(signer.PublicKey().Marshal(), tt.pub.Marshal()) {
+			t.Errorf("%s: parsed key has the wrong public key", tt.pub.Type())
+		}
+
+		data := []byte("session data")
+		sig, err := signer.Sign(rand.Reader, data)
+		if err != nil {
+			t.Fatalf("%s: Sign: %v", tt.pub.Type(), err)
+		}
+		if err := tt.pub.Verify(data, sig); err != nil {
+			t.Errorf("%s: Verify: %v", tt.pub.Type(), err)
+		}
+		sk, err := ParseSKSignature(sig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !sk.UserPresent() || !sk.UserVerified() || sk.Counter != 42 {
+			t.Errorf("%s: got %+v, want user presence, verification and counter 42", tt.pub.Type(), sk)
+		}
+
+		req := provider.reqs[0]
+		digest := sha256.Sum256(data)
+		if req.Algorithm != tt.pub.Type() || req.Flags != SKFlagUserPresent|SKFlagUserVerified || !bytes.Equal(req.Digest, digest[:]) {
+			t.Errorf("%s: unexpected request %+v", tt.pub.Type(), req)
+		}
+
+		// A signature by another credential is caught before it is
+		// sent to a server.
+		other, _ := NewSKSigner(tt.pub, keyHandle, SKFlagUserPresent, &fakeSKProvider{key: otherPriv, keyHandle: keyHandle})
+		if _, err := other.Sign(rand.Reader, data); err == nil {
+			t.Errorf("%s: signing with the wrong key succeeded", tt.pub.Type())
+		}
+	}
+
+	if _, err := NewSKSigner(testSigners["ed25519"].PublicKey(), nil, 0, &fakeSKProvider{}); err == nil {
+		t.Error("NewSKSigner accepted a key that is not a security key")
+	}
+	if _, err := ParseSKPrivateKey(testdata.PEMBytes["ed25519"], &fakeSKProvider{}); err == nil {
+		t.Error("ParseSKPrivateKey accepted a key that is not a security key")
+	}
+}
@@ -0,0 +1,258 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// SOCKS5 constants, see RFC 1928.
+const (
+	socks5Version = 5
+
+	socks5NoAuth       = 0
+	socks5NoAcceptable = 0xff
+
+	socks5Connect = 1
+
+	socks5AddrIPv4   = 1
+	socks5AddrDomain = 3
+	socks5AddrIPv6   = 4
+
+	socks5Succeeded           = 0
+	socks5GeneralFailure      = 1
+	socks5NotAllowed          = 2
+	socks5HostUnreachable     = 4
+	socks5CommandUnsupported  = 7
+	socks5AddrTypeUnsupported = 8
+)
+
+// socks5HandshakeTimeout is how long SOCKS clients have to send their
+// request.
+var socks5HandshakeTimeout = 30 * time.Second
+
+// socksDialer connects a SOCKS CONNECT request from conn to host and port.
+type socksDialer func(conn net.Conn, host string, port int) (net.Conn, error)
+
+// ListenSOCKS5 listens on the local TCP address addr and serves a SOCKS5
+// proxy on it in the background, connecting each client through the server
+// with a direct-tcpip channel, like the -D option of OpenSSH. Host names are
+// resolved by the server. Only the CONNECT command without authentication
+// is supported. Closing the returned listener stops the proxy.
+func (c *Client) ListenSOCKS5(addr string) (net.Listener, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	go c.ServeSOCKS5(l)
+	return l, nil
+}
+
+// ServeSOCKS5 is like ListenSOCKS5, but serves the proxy on connections
+// accepted from l, until l.Accept fails. It returns the error of Accept.
+func (c *Client) ServeSOCKS5(l net.Listener) error {
+	return serveSOCKS5(l, func(conn net.Conn, host string, port int) (net.Conn, error) {
+		originAddr, originPort := net.IPv4zero.String(), 0
+		if a, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+			originAddr, originPort = a.IP.String(), a.Port
+		}
+		ch, err := c.dial(originAddr, originPort, host, port)
+		if err != nil {
+			return nil, err
+		}
+		return NewChannelConn(ch, nil, nil), nil
+	})
+}
+
+// ListenRemoteSOCKS5 asks the server to listen on the remote TCP address
+// addr and serves a SOCKS5 proxy on the connections that it forwards,
+// connecting them from the local host, like the -R option of OpenSSH given
+// only a port. The proxy runs in the background until the returned listener
+// is closed. Only the CONNECT command without authentication is supported.
+func (c *Client) ListenRemoteSOCKS5(addr string) (net.Listener, error) {
+	l, err := c.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	go serveSOCKS5(l, func(conn net.Conn, host string, port int) (net.Conn, error) {
+		return net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	})
+	return l, nil
+}
+
+func serveSOCKS5(l net.Listener, dial socksDialer) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			target, err := socks5Handshake(conn, dial)
+			if err != nil {
+				return
+			}
+			defer target.Close()
+			proxyHalfClose(conn, target)
+		}()
+	}
+}
+
+// socks5Handshake reads a SOCKS5 request from conn, connects it with dial
+// and sends the reply. The request must arrive within
+// socks5HandshakeTimeout.
+func socks5Handshake(conn net.Conn, dial socksDialer) (net.Conn, error) {
+	if err := conn.SetDeadline(time.Now().Add(socks5HandshakeTimeout)); err != nil {
+		return nil, err
+	}
+	var hdr [2]byte
+	if _, err := io.ReadFull(conn, hdr[:]); err != nil {
+		return nil, err
+	}
+	if hdr[0] != socks5Version {
+		return nil, fmt.Errorf("ssh: unsupported SOCKS version %d", hdr[0])
+	}
+	methods := make([]byte, hdr[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return nil, err
+	}
+	method := byte(socks5NoAcceptable)
+	for _, m := range methods {
+		if m == socks5NoAuth {
+			method = socks5NoAuth
+		}
+	}
+	if _, err := conn.Write([]byte{socks5Version, method}); err != nil {
+		return nil, err
+	}
+	if method == socks5NoAcceptable {
+		return nil, errors.New("ssh: no acceptable SOCKS authentication method")
+	}
+
+	var req [4]byte
+	if _, err := io.ReadFull(conn, req[:]); err != nil {
+		return nil, err
+	}
+	if req[0] != socks5Version {
+		return nil, fmt.Errorf("ssh: unsupported SOCKS version %d", req[0])
+	}
+	var host string
+	switch req[3] {
+	case socks5AddrIPv4, socks5AddrIPv6:
+		ip := make(net.IP, net.IPv4len)
+		if req[3] == socks5AddrIPv6 {
+			ip = make(net.IP, net.IPv6len)
+		}
+		if _, err := io.ReadFull(conn, ip); err != nil {
+			return nil, err
+		}
+		host = ip.String()
+	case socks5AddrDomain:
+		var n [1]byte
+		if _, err := io.ReadFull(conn, n[:]); err != nil {
+			return nil, err
+		}
+		name := make([]byte, n[0])
+		if _, err := io.ReadFull(conn, name); err != nil {
+			return nil, err
+		}
+		host = string(name)
+	default:
+		socks5Reply(conn, socks5AddrTypeUnsupported, nil)
+		return nil, fmt.Errorf("ssh: unsupported SOCKS address type %d", req[3])
+	}
+	var port [2]byte
+	if _, err := io.ReadFull(conn, port[:]); err != nil {
+		return nil, err
+	}
+	if req[1] != socks5Connect {
+		socks5Reply(conn, socks5CommandUnsupported, nil)
+		return nil, fmt.Errorf("ssh: unsupported SOCKS command %d", req[1])
+	}
+
+	// The dial may take longer, and the connection is proxied without a
+	// deadline.
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		return nil, err
+	}
+	target, err := dial(conn, host, int(binary.BigEndian.Uint16(port[:])))
+	if err != nil {
+		socks5Reply(conn, socks5ErrorCode(err), nil)
+		return nil, err
+	}
+	if err := socks5Reply(conn, socks5Succeeded, target.LocalAddr()); err != nil {
+		target.Close()
+		return nil, err
+	}
+	return target, nil
+}
+
+// socks5Reply sends a reply with the given code and bound address, which is
+// reported as 0.0.0.0:0 unless it is a TCP address.
+func socks5Reply(conn net.Conn, code byte, bound net.Addr) error {
+	ip, port := net.IPv4zero.To4(), 0
+	if a, ok := bound.(*net.TCPAddr); ok {
+		if ip = a.IP.To4(); ip == nil {
+			ip = a.IP.To16()
+		}
+		port = a.Port
+	}
+	if ip == nil {
+		ip = net.IPv4zero.To4()
+	}
+	atyp := byte(socks5AddrIPv4)
+	if len(ip) == net.IPv6len {
+		atyp = socks5AddrIPv6
+	}
+	reply := append([]byte{socks5Version, code, 0, atyp}, ip...)
+	reply = binary.BigEndian.AppendUint16(reply, uint16(port))
+	_, err := conn.Write(reply)
+	return err
+}
+
+// socks5ErrorCode returns the SOCKS5 reply code for a failed connection.
+func socks5ErrorCode(err error) byte {
+	var openErr *OpenChannelError
+	if errors.As(err, &openErr) && openErr.Reason == Prohibited {
+		return socks5NotAllowed
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return socks5HostUnreachable
+	}
+	return socks5GeneralFailure
+}
+
+// proxyHalfClose copies data between a and b in both directions until both
+// directions are done, forwarding the end of each direction as a
+// half-close if possible.
+func proxyHalfClose(a, b net.Conn) {
+	done := make(chan struct{})
+	go func() {
+		copyAndCloseWrite(a, b)
+		close(done)
+	}()
+	copyAndCloseWrite(b, a)
+	<-done
+}
+
+type closeWriter interface {
+	CloseWrite() error
+}
+
+func copyAndCloseWrite(dst, src net.Conn) {
+	io.Copy(dst, src)
+	if cw, ok := dst.(closeWriter); ok {
+		cw.CloseWrite()
+	} else {
+		dst.Close()
+	}
+}
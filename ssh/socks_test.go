@@ -0,0 +1,282 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// socks5Dial connects to the SOCKS5 proxy at proxy and asks it to connect
+// to host and port, returning the reply code.
+func socks5Dial(t *testing.T, proxy, host string, port int) (net.Conn, byte) {
+	t.Helper()
+	conn, err := net.Dial("tcp", proxy)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	code, err := socks5Request(conn, host, port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn, code
+}
+
+// socks5Request sends a SOCKS5 CONNECT request for host and port on conn,
+// returning the reply code.
+func socks5Request(conn io.ReadWriter, host string, port int) (byte, error) {
+	req := []byte{socks5Version, 1, socks5NoAuth, socks5Version, socks5Connect, 0, socks5AddrDomain, byte(len(host))}
+	req = append(req, host...)
+	req = binary.BigEndian.AppendUint16(req, uint16(port))
+	if _, err := conn.Write(req); err != nil {
+		return 0, err
+	}
+	var reply [2 + 4 + 4 + 2]byte
+	if _, err := io.ReadFull(conn, reply[:2]); err != nil {
+		return 0, fmt.Errorf("reading method: %v", err)
+	}
+	if reply[1] != socks5NoAuth {
+		return 0, fmt.Errorf("got method %d, want %d", reply[1], socks5NoAuth)
+	}
+	if _, err := io.ReadFull(conn, reply[2:]); err != nil {
+		return 0, fmt.Errorf("reading reply: %v", err)
+	}
+	if reply[2] != socks5Version || reply[5] != socks5AddrIPv4 {
+		return 0, fmt.Errorf("malformed reply %x", reply[2:])
+	}
+	return reply[3], nil
+}
+
+func TestListenSOCKS5(t *testing.T) {
+	c1, c2, err := netPipe()
+	if err != nil {
+		t.Fatalf("netPipe: %v", err)
+	}
+	defer c1.Close()
+	defer c2.Close()
+
+	serverConf := &ServerConfig{NoClientAuth: true}
+	serverConf.AddHostKey(testSigners["ecdsa"])
+	go func() {
+		conn, chans, reqs, err := NewServerConn(c2, serverConf)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		go DiscardRequests(reqs)
+		for newCh := range chans {
+			var msg forwardedTCPPayload
+			if newCh.ChannelType() != "direct-tcpip" || Unmarshal(newCh.ExtraData(), &msg) != nil {
+				newCh.Reject(UnknownChannelType, "unexpected channel")
+				continue
+			}
+			if msg.Addr == "forbidden.example" {
+				newCh.Reject(Prohibited, "forbidden")
+				continue
+			}
+			ch, reqs, err := newCh.Accept()
+			if err != nil {
+				return
+			}
+			go DiscardRequests(reqs)
+			go func() {
+				fmt.Fprintf(ch, "connected to %s:%d\n", msg.Addr, msg.Port)
+				io.Copy(ch, ch)
+				ch.CloseWrite()
+			}()
+		}
+	}()
+
+	conn, chans, reqs, err := NewClientConn(c1, "", &ClientConfig{
+		User:            "user",
+		HostKeyCallback: InsecureIgnoreHostKey(),
+	})
+	if err != nil {
+		t.Fatalf("NewClientConn: %v", err)
+	}
+	client := NewClient(conn, chans, reqs)
+	defer client.Close()
+
+	l, err := client.ListenSOCKS5("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenSOCKS5: %v", err)
+	}
+	defer l.Close()
+
+	proxied, code := socks5Dial(t, l.Addr().String(), "target.example", 8080)
+	defer proxied.Close()
+	if code != socks5Succeeded {
+		t.Fatalf("got reply code %d, want %d", code, socks5Succeeded)
+	}
+	if _, err := io.WriteString(proxied, "ping"); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	proxied.(*net.TCPConn).CloseWrite()
+	got, err := io.ReadAll(proxied)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if want := "connected to target.example:8080\nping"; string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	refused, code := socks5Dial(t, l.Addr().String(), "forbidden.example", 80)
+	defer refused.Close()
+	if code != socks5NotAllowed {
+		t.Errorf("got reply code %d for a prohibited channel, want %d", code, socks5NotAllowed)
+	}
+}
+
+func TestListenRemoteSOCKS5(t *testing.T) {
+	target, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer target.Close()
+	go func() {
+		for {
+			conn, err := target.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				io.Copy(conn, conn)
+				conn.Close()
+			}()
+		}
+	}()
+	targetHost, targetPort, _ := net.SplitHostPort(target.Addr().String())
+	port, _ := strconv.Atoi(targetPort)
+
+	c1, c2, err := netPipe()
+	if err != nil {
+		t.Fatalf("netPipe: %v", err)
+	}
+	defer c1.Close()
+	defer c2.Close()
+
+	serverConf := &ServerConfig{NoClientAuth: true}
+	serverConf.AddHostKey(testSigners["ecdsa"])
+	result := make(chan error, 1)
+	listening := make(chan struct{})
+	go func() {
+		conn, chans, reqs, err := NewServerConn(c2, serverConf)
+		if err != nil {
+			result <- err
+			return
+		}
+		defer conn.Close()
+		go func() {
+			for newCh := range chans {
+				newCh.Reject(Prohibited, "no channels")
+			}
+		}()
+		var fwd struct {
+			Addr string
+			Port uint32
+		}
+		for r := range reqs {
+			if r.Type == "tcpip-forward" && Unmarshal(r.Payload, &fwd) == nil {
+				r.Reply(true, nil)
+				break
+			}
+			r.Reply(false, nil)
+		}
+		go DiscardRequests(reqs)
+		<-listening
+
+		// Act as a SOCKS client connecting through the reverse forward.
+		ch, chReqs, err := conn.OpenChannel("forwarded-tcpip", Marshal(&forwardedTCPPayload{
+			Addr: fwd.Addr, Port: fwd.Port, OriginAddr: "10.0.0.1", OriginPort: 4321,
+		}))
+		if err != nil {
+			result <- err
+			return
+		}
+		go DiscardRequests(chReqs)
+		defer ch.Close()
+		if code, err := socks5Request(ch, targetHost, port); err != nil || code != socks5Succeeded {
+			result <- fmt.Errorf("got reply code %d, %v; want %d", code, err, socks5Succeeded)
+			return
+		}
+		io.WriteString(ch, "ping")
+		ch.CloseWrite()
+		got, err := io.ReadAll(ch)
+		if err == nil && string(got) != "ping" {
+			err = fmt.Errorf("got %q through the proxy, want %q", got, "ping")
+		}
+		result <- err
+	}()
+
+	conn, chans, reqs, err := NewClientConn(c1, "", &ClientConfig{
+		User:            "user",
+		HostKeyCallback: InsecureIgnoreHostKey(),
+	})
+	if err != nil {
+		t.Fatalf("NewClientConn: %v", err)
+	}
+	client := NewClient(conn, chans, reqs)
+	defer client.Close()
+
+	l, err := client.ListenRemoteSOCKS5("127.0.0.1:1080")
+	if err != nil {
+		t.Fatalf("ListenRemoteSOCKS5: %v", err)
+	}
+	defer l.Close()
+	close(listening)
+	if err := <-result; err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSOCKS5HandshakeTimeout(t *testing.T) {
+	defer func(d time.Duration) { socks5HandshakeTimeout = d }(socks5HandshakeTimeout)
+	socks5HandshakeTimeout = 100 * time.Millisecond
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer l.Close()
+	go serveSOCKS5(l, func(conn net.Conn, host string, port int) (net.Conn, error) {
+		// Dial for longer than the handshake timeout.
+		time.Sleep(2 * socks5HandshakeTimeout)
+		a, b := net.Pipe()
+		go func() {
+			io.Copy(b, b)
+			b.Close()
+		}()
+		return a, nil
+	})
+
+	idle, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer idle.Close()
+	idle.SetReadDeadline(time.Now().Add(10 * time.Second))
+	if _, err := idle.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("Read from an idle SOCKS client = %v, want EOF", err)
+	}
+
+	proxied, code := socks5Dial(t, l.Addr().String(), "target.example", 80)
+	defer proxied.Close()
+	if code != socks5Succeeded {
+		t.Fatalf("got reply code %d, want %d", code, socks5Succeeded)
+	}
+	time.Sleep(2 * socks5HandshakeTimeout)
+	if _, err := io.WriteString(proxied, "ping"); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(proxied, buf); err != nil || string(buf) != "ping" {
+		t.Errorf("read %q, %v after the handshake timeout, want ping", buf, err)
+	}
+}
@@ -0,0 +1,582 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sshconfig
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// defaultIdentities are the keys ssh tries if no IdentityFile is given.
+var defaultIdentities = []string{
+	"~/.ssh/id_rsa",
+	"~/.ssh/id_ecdsa",
+	"~/.ssh/id_ecdsa_sk",
+	"~/.ssh/id_ed25519",
+	"~/.ssh/id_ed25519_sk",
+	"~/.ssh/id_dsa",
+}
+
+// defaultGlobalKnownHosts are the files of GlobalKnownHostsFile by default.
+var defaultGlobalKnownHosts = []string{"/etc/ssh/ssh_known_hosts", "/etc/ssh/ssh_known_hosts2"}
+
+// ClientOptions supply what a configuration file cannot: the agent and the
+// interaction with the user.
+type ClientOptions struct {
+	// Agent, if not nil, supplies keys for public key authentication. Dial
+	// connects to the agent of IdentityAgent or SSH_AUTH_SOCK if nil.
+	Agent agent.Agent
+
+	// Passphrase, if not nil, is called for the passphrase of encrypted
+	// identity files. If nil, such files are only used through the agent.
+	Passphrase func(file string) ([]byte, error)
+
+	// Password and KeyboardInteractive, if not nil, enable password and
+	// keyboard-interactive authentication.
+	Password            func() (string, error)
+	KeyboardInteractive ssh.KeyboardInteractiveChallenge
+
+	// HostKeyPrompt, if not nil, is asked whether to trust the keys of
+	// unknown hosts when StrictHostKeyChecking is ask, the default.
+	// Accepted keys are added to the first UserKnownHostsFile. If nil, such
+	// keys are rejected.
+	HostKeyPrompt knownhosts.PromptFunc
+}
+
+// Address returns the host:port address to connect to.
+func (h *Host) Address() (string, error) {
+	hostname, err := h.Hostname()
+	if err != nil {
+		return "", err
+	}
+	port, err := h.Port()
+	if err != nil {
+		return "", err
+	}
+	return net.JoinHostPort(hostname, strconv.Itoa(port)), nil
+}
+
+// ClientConfig returns a client configuration for connecting to the host
+// like ssh would. It uses User, IdentityFile, CertificateFile,
+// IdentitiesOnly, PreferredAuthentications, the *Authentication yes/no
+// options, KexAlgorithms, Ciphers, MACs, HostKeyAlgorithms,
+// UserKnownHostsFile, GlobalKnownHostsFile, StrictHostKeyChecking,
+// HashKnownHosts, HostKeyAlias, ConnectTimeout, ServerAliveInterval and
+// ServerAliveCountMax. ProxyJump is handled by Config.Dial, and ProxyCommand
+// is not supported.
+func (h *Host) ClientConfig(opts *ClientOptions) (*ssh.ClientConfig, error) {
+	if opts == nil {
+		opts = &ClientOptions{}
+	}
+	config := &ssh.ClientConfig{User: h.User()}
+
+	var err error
+	if config.Auth, err = h.authMethods(opts); err != nil {
+		return nil, err
+	}
+	if config.HostKeyCallback, err = h.hostKeyCallback(opts); err != nil {
+		return nil, err
+	}
+	h.setAlgorithms(config)
+	if config.Timeout, err = h.Duration("connecttimeout"); err != nil {
+		return nil, err
+	}
+	if config.KeepaliveInterval, err = h.Duration("serveraliveinterval"); err != nil {
+		return nil, err
+	}
+	if v := h.Get("serveralivecountmax"); v != "" {
+		if config.KeepaliveMaxMissed, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("sshconfig: invalid ServerAliveCountMax %q", v)
+		}
+	}
+	return config, nil
+}
+
+func (h *Host) authMethods(opts *ClientOptions) ([]ssh.AuthMethod, error) {
+	methods := map[string]ssh.AuthMethod{}
+	if ok, err := h.Bool("pubkeyauthentication", true); err != nil {
+		return nil, err
+	} else if ok {
+		methods["publickey"] = ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+			return h.signers(opts)
+		})
+	}
+	if ok, err := h.Bool("kbdinteractiveauthentication", true); err != nil {
+		return nil, err
+	} else if ok && opts.KeyboardInteractive != nil {
+		methods["keyboard-interactive"] = ssh.KeyboardInteractive(opts.KeyboardInteractive)
+	}
+	if ok, err := h.Bool("passwordauthentication", true); err != nil {
+		return nil, err
+	} else if ok && opts.Password != nil {
+		methods["password"] = ssh.PasswordCallback(opts.Password)
+	}
+
+	order := []string{"publickey", "keyboard-interactive", "password"}
+	if v := h.Get("preferredauthentications"); v != "" {
+		order = strings.Split(v, ",")
+	}
+	var auth []ssh.AuthMethod
+	for _, name := range order {
+		if m, ok := methods[name]; ok {
+			auth = append(auth, m)
+			delete(methods, name)
+		}
+	}
+	return auth, nil
+}
+
+// signers returns the keys for public key authentication: those of the
+// agent, unless IdentitiesOnly restricts them to the identity files, then
+// those of the identity files, with their certificates.
+func (h *Host) signers(opts *ClientOptions) ([]ssh.Signer, error) {
+	files, err := h.IdentityFiles()
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		if files, err = h.expandPaths(defaultIdentities); err != nil {
+			return nil, err
+		}
+	}
+	certFiles, err := h.CertificateFiles()
+	if err != nil {
+		return nil, err
+	}
+	var certs []*ssh.Certificate
+	for _, fn := range certFiles {
+		if cert, err := readCertificate(fn); err == nil {
+			certs = append(certs, cert)
+		}
+	}
+
+	var fileSigners []ssh.Signer
+	var identities []ssh.PublicKey
+	for _, fn := range files {
+		if cert, err := readCertificate(fn + "-cert.pub"); err == nil {
+			certs = append(certs, cert)
+		}
+		signer, pub, err := readIdentity(fn, opts.Passphrase)
+		if err != nil {
+			return nil, err
+		}
+		if pub != nil {
+			identities = append(identities, pub)
+		}
+		if signer != nil {
+			fileSigners = append(fileSigners, signer)
+		}
+	}
+
+	var signers []ssh.Signer
+	if opts.Agent != nil {
+		agentSigners, err := opts.Agent.Signers()
+		if err != nil {
+			return nil, err
+		}
+		identitiesOnly, err := h.Bool("identitiesonly", false)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range agentSigners {
+			if !identitiesOnly || containsKey(identities, s.PublicKey()) {
+				signers = append(signers, s)
+			}
+		}
+	}
+	for _, s := range fileSigners {
+		if !containsSigner(signers, s.PublicKey()) {
+			signers = append(signers, s)
+		}
+	}
+
+	// Offer certificates before the plain keys they certify.
+	var withCerts []ssh.Signer
+	for _, s := range signers {
+		for _, cert := range certs {
+			if _, isCert := s.PublicKey().(*ssh.Certificate); !isCert && keyEqual(cert.Key, s.PublicKey()) {
+				if cs, err := ssh.NewCertSigner(cert, s); err == nil {
+					withCerts = append(withCerts, cs)
+				}
+			}
+		}
+		withCerts = append(withCerts, s)
+	}
+	return withCerts, nil
+}
+
+// readIdentity reads a private key file. If the key is encrypted and there
+// is no passphrase, it returns only the public key, from the file or from
+// the .pub file next to it. Missing files are skipped, as in ssh.
+func readIdentity(fn string, passphrase func(string) ([]byte, error)) (ssh.Signer, ssh.PublicKey, error) {
+	data, err := os.ReadFile(fn)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	signer, err := ssh.ParsePrivateKey(data)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		if passphrase == nil {
+			pub := missing.PublicKey
+			if pub == nil {
+				pub, _ = readPublicKey(fn + ".pub")
+			}
+			return nil, pub, nil
+		}
+		pass, err := passphrase(fn)
+		if err != nil {
+			return nil, nil, err
+		}
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(data, pass)
+		if err != nil {
+			return nil, nil, fmt.Errorf("sshconfig: %s: %w", fn, err)
+		}
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("sshconfig: %s: %w", fn, err)
+	}
+	return signer, signer.PublicKey(), nil
+}
+
+func readPublicKey(fn string) (ssh.PublicKey, error) {
+	data, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(data)
+	return pub, err
+}
+
+func readCertificate(fn string) (*ssh.Certificate, error) {
+	pub, err := readPublicKey(fn)
+	if err != nil {
+		return nil, err
+	}
+	cert, ok := pub.(*ssh.Certificate)
+	if !ok {
+		return nil, fmt.Errorf("sshconfig: %s is not a certificate", fn)
+	}
+	return cert, nil
+}
+
+func keyEqual(a, b ssh.PublicKey) bool {
+	return bytes.Equal(a.Marshal(), b.Marshal())
+}
+
+func containsKey(keys []ssh.PublicKey, key ssh.PublicKey) bool {
+	for _, k := range keys {
+		if keyEqual(k, key) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsSigner(signers []ssh.Signer, key ssh.PublicKey) bool {
+	for _, s := range signers {
+		if keyEqual(s.PublicKey(), key) {
+			return true
+		}
+	}
+	return false
+}
+
+// hostKeyCallback checks host keys against the known_hosts files, following
+// StrictHostKeyChecking. Changed keys are always rejected.
+func (h *Host) hostKeyCallback(opts *ClientOptions) (ssh.HostKeyCallback, error) {
+	userFiles, err := h.UserKnownHostsFiles()
+	if err != nil {
+		return nil, err
+	}
+	globalFiles := defaultGlobalKnownHosts
+	if args := h.Args("globalknownhostsfile"); len(args) > 0 {
+		globalFiles = args
+		if len(args) == 1 && strings.EqualFold(args[0], "none") {
+			globalFiles = nil
+		}
+	}
+	if globalFiles, err = h.expandPaths(globalFiles); err != nil {
+		return nil, err
+	}
+
+	db := &knownhosts.DB{}
+	for _, fn := range append(append([]string(nil), userFiles...), globalFiles...) {
+		f, err := os.Open(fn)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		err = db.Read(f, fn)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	hash, err := h.Bool("hashknownhosts", false)
+	if err != nil {
+		return nil, err
+	}
+	var writer *knownhosts.Writer
+	if len(userFiles) > 0 {
+		writer = knownhosts.NewWriter(userFiles[0])
+		writer.Hash = hash
+	}
+	db.Hash = hash
+
+	strict := strings.ToLower(h.Get("stricthostkeychecking"))
+	if strict == "" {
+		strict = "ask"
+	}
+	switch strict {
+	case "yes", "ask", "accept-new", "no", "off":
+	default:
+		return nil, fmt.Errorf("sshconfig: invalid StrictHostKeyChecking %q", strict)
+	}
+
+	check := db.HostKeyCallback()
+	alias := h.Get("hostkeyalias")
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		if alias != "" {
+			// Like ssh, look up the alias without the port.
+			hostname = net.JoinHostPort(alias, "22")
+		}
+		err := check(hostname, remote, key)
+		if err == nil || strict == "yes" {
+			return err
+		}
+		var keyErr *knownhosts.KeyError
+		if !errors.As(err, &keyErr) {
+			return err
+		}
+		if len(keyErr.Want) > 0 {
+			// ssh lets changed keys through with StrictHostKeyChecking=no,
+			// but disables password and keyboard-interactive
+			// authentication, which the callback can't do, so that the
+			// server can't learn the password. Reject them instead.
+			return err
+		}
+		if strict == "ask" {
+			if opts.HostKeyPrompt == nil {
+				return err
+			}
+			ok, perr := opts.HostKeyPrompt(hostname, remote, key)
+			if perr != nil {
+				return perr
+			}
+			if !ok {
+				return err
+			}
+		}
+		if err := db.Add([]string{hostname}, key); err != nil {
+			return err
+		}
+		if writer != nil {
+			return writer.Add([]string{hostname}, key)
+		}
+		return nil
+	}, nil
+}
+
+// setAlgorithms sets the algorithms of config from KexAlgorithms, Ciphers,
+// MACs and HostKeyAlgorithms.
+func (h *Host) setAlgorithms(config *ssh.ClientConfig) {
+	defaults := ssh.DefaultAlgorithmPolicy()
+	for _, a := range []struct {
+		keyword  string
+		dst      *[]string
+		defaults []string
+	}{
+		{"kexalgorithms", &config.KeyExchanges, defaults.KeyExchanges},
+		{"ciphers", &config.Ciphers, defaults.Ciphers},
+		{"macs", &config.MACs, defaults.MACs},
+		{"hostkeyalgorithms", &config.HostKeyAlgorithms, defaults.HostKeyAlgorithms},
+	} {
+		if v := h.Get(a.keyword); v != "" {
+			*a.dst = algorithmList(v, a.defaults)
+		}
+	}
+}
+
+// algorithmList returns the algorithms of an ssh_config algorithm list,
+// which replaces the defaults, or, when it starts with '+', '-' or '^',
+// appends algorithms to them, removes algorithms matching patterns from
+// them, or puts algorithms first. Algorithms this package doesn't support
+// are left in, to be ignored when connecting.
+func algorithmList(v string, defaults []string) []string {
+	op := v[0]
+	if op == '+' || op == '-' || op == '^' {
+		v = v[1:]
+	}
+	list := strings.Split(v, ",")
+	var algos []string
+	switch op {
+	case '+':
+		algos = append(algos, defaults...)
+		for _, a := range list {
+			if !contains(algos, a) {
+				algos = append(algos, a)
+			}
+		}
+	case '-':
+		for _, a := range defaults {
+			if !ssh.MatchPrincipalPattern(a, list) {
+				algos = append(algos, a)
+			}
+		}
+	case '^':
+		algos = append(algos, list...)
+		for _, a := range defaults {
+			if !contains(algos, a) {
+				algos = append(algos, a)
+			}
+		}
+	default:
+		algos = list
+	}
+	return algos
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Dial connects to the host alias like ssh would, using ClientConfig. Jump
+// hosts of ProxyJump are looked up in c too and connected to in turn,
+// ignoring their own ProxyJump; they are closed when the returned client
+// is. If opts.Agent is nil, Dial uses the agent of IdentityAgent or
+// SSH_AUTH_SOCK, if any, during authentication.
+func (c *Config) Dial(alias string, opts *ClientOptions) (*ssh.Client, error) {
+	h, err := c.Lookup(alias)
+	if err != nil {
+		return nil, err
+	}
+	if h.Get("proxycommand") != "" && !strings.EqualFold(h.Get("proxycommand"), "none") {
+		return nil, errors.New("sshconfig: ProxyCommand is not supported")
+	}
+	if opts == nil {
+		opts = &ClientOptions{}
+	}
+	if opts.Agent == nil {
+		// Like ssh, go on without the agent if it can't be reached.
+		if conn, err := h.dialAgent(); err == nil && conn != nil {
+			defer conn.Close()
+			o := *opts
+			o.Agent = agent.NewClient(conn)
+			opts = &o
+		}
+	}
+
+	var jumps []*ssh.Client
+	closeJumps := func() {
+		for i := len(jumps) - 1; i >= 0; i-- {
+			jumps[i].Close()
+		}
+	}
+	var proxy ssh.ProxyDialer
+	if jump := h.ProxyJump(); jump != "" {
+		for _, spec := range strings.Split(jump, ",") {
+			client, err := c.dialJump(spec, proxy, opts)
+			if err != nil {
+				closeJumps()
+				return nil, err
+			}
+			jumps = append(jumps, client)
+			proxy = client
+		}
+	}
+
+	client, err := h.dial(proxy, opts)
+	if err != nil {
+		closeJumps()
+		return nil, err
+	}
+	if len(jumps) > 0 {
+		go func() {
+			client.Wait()
+			closeJumps()
+		}()
+	}
+	return client, nil
+}
+
+// dialJump connects to the jump host of a ProxyJump entry, [user@]host[:port].
+func (c *Config) dialJump(spec string, proxy ssh.ProxyDialer, opts *ClientOptions) (*ssh.Client, error) {
+	user, hostport, hasUser := strings.Cut(spec, "@")
+	if !hasUser {
+		user, hostport = "", spec
+	}
+	host, port := hostport, ""
+	if h, p, err := net.SplitHostPort(hostport); err == nil {
+		host, port = h, p
+	}
+	h, err := c.Lookup(host)
+	if err != nil {
+		return nil, err
+	}
+	if user != "" {
+		h.values["user"] = [][]string{{user}}
+	}
+	if port != "" {
+		h.values["port"] = [][]string{{port}}
+	}
+	return h.dial(proxy, opts)
+}
+
+func (h *Host) dial(proxy ssh.ProxyDialer, opts *ClientOptions) (*ssh.Client, error) {
+	config, err := h.ClientConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	config.ProxyDialer = proxy
+	addr, err := h.Address()
+	if err != nil {
+		return nil, err
+	}
+	return ssh.Dial("tcp", addr, config)
+}
+
+// dialAgent connects to the agent of IdentityAgent or SSH_AUTH_SOCK. It
+// returns nil if there is none.
+func (h *Host) dialAgent() (net.Conn, error) {
+	sock := h.Get("identityagent")
+	switch {
+	case strings.EqualFold(sock, "none"):
+		return nil, nil
+	case sock == "" || sock == "SSH_AUTH_SOCK":
+		sock = os.Getenv("SSH_AUTH_SOCK")
+	case strings.HasPrefix(sock, "$"):
+		sock = os.Getenv(sock[1:])
+	default:
+		paths, err := h.expandPaths([]string{sock})
+		if err != nil {
+			return nil, err
+		}
+		sock = paths[0]
+	}
+	if sock == "" {
+		return nil, nil
+	}
+	return net.Dial("unix", sock)
+}
@@ -0,0 +1,232 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sshconfig
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/testdata"
+)
+
+func testSigner(t *testing.T, name string) ssh.Signer {
+	t.Helper()
+	s, err := ssh.ParsePrivateKey(testdata.PEMBytes[name])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// startServer starts a server that accepts user alice with userKey, and
+// forwards direct-tcpip channels. It returns the port of the server.
+func startServer(t *testing.T, hostKey ssh.Signer, userKey ssh.PublicKey) int {
+	t.Helper()
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if conn.User() == "alice" && bytes.Equal(key.Marshal(), userKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("unknown key")
+		},
+	}
+	config.AddHostKey(hostKey)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serveConn(c, config)
+		}
+	}()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func serveConn(c net.Conn, config *ssh.ServerConfig) {
+	conn, chans, reqs, err := ssh.NewServerConn(c, config)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	go ssh.DiscardRequests(reqs)
+	for newCh := range chans {
+		if newCh.ChannelType() != "direct-tcpip" {
+			newCh.Reject(ssh.UnknownChannelType, "")
+			continue
+		}
+		var target struct {
+			Host       string
+			Port       uint32
+			OriginHost string
+			OriginPort uint32
+		}
+		if err := ssh.Unmarshal(newCh.ExtraData(), &target); err != nil {
+			newCh.Reject(ssh.ConnectionFailed, "")
+			continue
+		}
+		out, err := net.Dial("tcp", net.JoinHostPort(target.Host, strconv.Itoa(int(target.Port))))
+		if err != nil {
+			newCh.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		ch, reqs, err := newCh.Accept()
+		if err != nil {
+			out.Close()
+			continue
+		}
+		go ssh.DiscardRequests(reqs)
+		go func() {
+			io.Copy(ch, out)
+			ch.CloseWrite()
+		}()
+		go func() {
+			io.Copy(out, ch)
+			out.Close()
+		}()
+	}
+}
+
+func TestDial(t *testing.T) {
+	home := t.TempDir()
+	if err := os.Mkdir(filepath.Join(home, ".ssh"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".ssh", "id_test"), testdata.PEMBytes["ed25519"], 0600); err != nil {
+		t.Fatal(err)
+	}
+	userKey := testSigner(t, "ed25519").PublicKey()
+	jumpPort := startServer(t, testSigner(t, "rsa"), userKey)
+	targetPort := startServer(t, testSigner(t, "ecdsa"), userKey)
+
+	config := fmt.Sprintf(`
+Host target
+	HostName 127.0.0.1
+	Port %d
+	HostKeyAlias target-alias
+
+Host jumped
+	HostName 127.0.0.1
+	Port %d
+	ProxyJump jump
+
+Host jump
+	HostName 127.0.0.1
+	Port %d
+
+Host *
+	User alice
+	IdentityFile ~/.ssh/id_test
+	IdentityAgent none
+	UserKnownHostsFile ~/.ssh/known_hosts
+	GlobalKnownHostsFile none
+	StrictHostKeyChecking accept-new
+`, targetPort, targetPort, jumpPort)
+	c, err := Parse(strings.NewReader(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.HomeDir = home
+
+	client, err := c.Dial("target", nil)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	client.Close()
+	data, err := os.ReadFile(filepath.Join(home, ".ssh", "known_hosts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "target-alias ecdsa-sha2-nistp256 ") {
+		t.Errorf("known_hosts = %q, want an entry for the HostKeyAlias", data)
+	}
+
+	client, err = c.Dial("jumped", nil)
+	if err != nil {
+		t.Fatalf("Dial through ProxyJump: %v", err)
+	}
+	client.Close()
+
+	// Both hosts are known now.
+	c.entries = append([]entry{{keyword: "stricthostkeychecking", args: []string{"yes"}}}, c.entries...)
+	for _, alias := range []string{"target", "jumped"} {
+		client, err := c.Dial(alias, nil)
+		if err != nil {
+			t.Fatalf("Dial(%s) with known host: %v", alias, err)
+		}
+		client.Close()
+	}
+
+	if err := os.Remove(filepath.Join(home, ".ssh", "known_hosts")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Dial("target", nil); err == nil {
+		t.Error("unknown host accepted with StrictHostKeyChecking yes")
+	}
+
+	// Changed keys are rejected even with StrictHostKeyChecking no.
+	known := "target-alias " + string(ssh.MarshalAuthorizedKey(testSigner(t, "rsa").PublicKey()))
+	if err := os.WriteFile(filepath.Join(home, ".ssh", "known_hosts"), []byte(known), 0600); err != nil {
+		t.Fatal(err)
+	}
+	c.entries[0].args = []string{"no"}
+	if _, err := c.Dial("target", nil); err == nil {
+		t.Error("changed host key accepted with StrictHostKeyChecking no")
+	}
+}
+
+func TestClientConfigAlgorithms(t *testing.T) {
+	c := parse(t, `
+Host *
+	Ciphers aes128-ctr,aes256-ctr
+	MACs -hmac-sha1*,*-etm@openssh.com
+	KexAlgorithms ^curve25519-sha256
+	HostKeyAlgorithms +legacy@example.com
+	ConnectTimeout 5
+	ServerAliveInterval 15
+	ServerAliveCountMax 4
+	GlobalKnownHostsFile none
+	UserKnownHostsFile none
+`)
+	config, err := lookup(t, c, "h").ClientConfig(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defaults := ssh.DefaultAlgorithmPolicy()
+	if want := []string{"aes128-ctr", "aes256-ctr"}; !reflect.DeepEqual(config.Ciphers, want) {
+		t.Errorf("Ciphers = %q, want %q", config.Ciphers, want)
+	}
+	for _, m := range config.MACs {
+		if strings.HasPrefix(m, "hmac-sha1") || strings.HasSuffix(m, "-etm@openssh.com") {
+			t.Errorf("MACs include removed %s", m)
+		}
+	}
+	if len(config.MACs) == 0 {
+		t.Error("all MACs removed")
+	}
+	if config.KeyExchanges[0] != "curve25519-sha256" || len(config.KeyExchanges) != len(defaults.KeyExchanges) {
+		t.Errorf("KeyExchanges = %q", config.KeyExchanges)
+	}
+	if n := len(config.HostKeyAlgorithms); n != len(defaults.HostKeyAlgorithms)+1 || config.HostKeyAlgorithms[n-1] != "legacy@example.com" {
+		t.Errorf("HostKeyAlgorithms = %q", config.HostKeyAlgorithms)
+	}
+	if config.Timeout.Seconds() != 5 || config.KeepaliveInterval.Seconds() != 15 || config.KeepaliveMaxMissed != 4 {
+		t.Errorf("got Timeout %v, KeepaliveInterval %v, KeepaliveMaxMissed %d", config.Timeout, config.KeepaliveInterval, config.KeepaliveMaxMissed)
+	}
+}
@@ -0,0 +1,312 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package sshconfig parses OpenSSH client configuration files, such as
+// ~/.ssh/config, as described in ssh_config(5), and looks up the settings
+// that apply to a destination host.
+//
+// As in ssh, the first value obtained for a keyword wins, except for
+// keywords such as IdentityFile that may be given several times. Host and
+// Match blocks, Include directives, and the tokens of ssh_config(5), such
+// as %h and %p, are supported. Keywords are not validated, so that files
+// written for any version of OpenSSH can be read. Host names are not
+// canonicalized, so Match canonical never matches.
+package sshconfig
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maxIncludeDepth is how deeply Include directives may nest, as in OpenSSH.
+const maxIncludeDepth = 16
+
+// A Config is a parsed ssh_config file.
+type Config struct {
+	// LocalUser and HomeDir are the local user name and home directory used
+	// for tokens, Match localuser and paths starting with ~. If empty, those
+	// of the current user are used.
+	LocalUser string
+	HomeDir   string
+
+	// Exec, if not nil, runs the command of a Match exec criterion, after
+	// token expansion, and reports whether it succeeded. If nil, looking
+	// up a host for which a Match exec criterion must be evaluated fails.
+	Exec func(command string) (bool, error)
+
+	entries []entry
+}
+
+// An entry is a keyword line, which applies if all of its blocks match.
+type entry struct {
+	blocks  []*block
+	keyword string
+	args    []string
+}
+
+// A block is a Host or a Match line.
+type block struct {
+	// patterns are the patterns of a Host line.
+	patterns []string
+
+	// criteria are the criteria of a Match line.
+	criteria []criterion
+
+	isMatch bool
+	pos     string
+}
+
+type criterion struct {
+	name   string
+	arg    string
+	negate bool
+}
+
+// matchCriteria are the criteria of Match, and whether they take an
+// argument.
+var matchCriteria = map[string]bool{
+	"all":          false,
+	"canonical":    false,
+	"final":        false,
+	"exec":         true,
+	"localnetwork": true,
+	"host":         true,
+	"originalhost": true,
+	"tagged":       true,
+	"user":         true,
+	"localuser":    true,
+}
+
+// multiValued are the keywords for which every value obtained is used,
+// rather than the first one.
+var multiValued = map[string]bool{
+	"certificatefile": true,
+	"dynamicforward":  true,
+	"identityfile":    true,
+	"localforward":    true,
+	"remoteforward":   true,
+	"sendenv":         true,
+	"setenv":          true,
+}
+
+// Parse parses a configuration file read from r. Relative paths of Include
+// directives are resolved in ~/.ssh, as for the user configuration file.
+func Parse(r io.Reader) (*Config, error) {
+	c := &Config{}
+	home, err := c.homeDir()
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{c: c, dir: filepath.Join(home, ".ssh")}
+	if err := p.parse(r, "config", nil, 0); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// ParseFile parses the configuration file at path. Relative paths of
+// Include directives are resolved in the directory of the file, which is
+// ~/.ssh for the user configuration file and /etc/ssh for the system one.
+func ParseFile(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	c := &Config{}
+	p := &parser{c: c, dir: filepath.Dir(path)}
+	if err := p.parse(f, path, nil, 0); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+type parser struct {
+	c   *Config
+	dir string
+}
+
+// parse parses a file whose lines apply within outer.
+func (p *parser) parse(r io.Reader, filename string, outer []*block, depth int) error {
+	blocks := outer
+	s := bufio.NewScanner(r)
+	lineNum := 0
+	for s.Scan() {
+		lineNum++
+		pos := fmt.Sprintf("%s line %d", filename, lineNum)
+		keyword, args, err := splitLine(s.Text())
+		if err != nil {
+			return fmt.Errorf("sshconfig: %s: %v", pos, err)
+		}
+		if keyword == "" {
+			continue
+		}
+		if len(args) == 0 {
+			return fmt.Errorf("sshconfig: %s: missing argument for %s", pos, keyword)
+		}
+
+		switch keyword {
+		case "host":
+			b := &block{patterns: args, pos: pos}
+			blocks = append(outer[:len(outer):len(outer)], b)
+		case "match":
+			criteria, err := parseCriteria(args)
+			if err != nil {
+				return fmt.Errorf("sshconfig: %s: %v", pos, err)
+			}
+			b := &block{criteria: criteria, isMatch: true, pos: pos}
+			blocks = append(outer[:len(outer):len(outer)], b)
+		case "include":
+			if depth >= maxIncludeDepth {
+				return fmt.Errorf("sshconfig: %s: too many nested includes", pos)
+			}
+			for _, pattern := range args {
+				if err := p.include(pattern, blocks, depth); err != nil {
+					return fmt.Errorf("sshconfig: %s: %w", pos, err)
+				}
+			}
+		default:
+			p.c.entries = append(p.c.entries, entry{blocks: blocks, keyword: keyword, args: args})
+		}
+	}
+	return s.Err()
+}
+
+// include parses the files matching pattern, in lexical order.
+func (p *parser) include(pattern string, blocks []*block, depth int) error {
+	if strings.HasPrefix(pattern, "~/") {
+		home, err := p.c.homeDir()
+		if err != nil {
+			return err
+		}
+		pattern = filepath.Join(home, pattern[2:])
+	} else if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(p.dir, pattern)
+	}
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+	for _, fn := range files {
+		f, err := os.Open(fn)
+		if err != nil {
+			return err
+		}
+		err = p.parse(f, fn, blocks, depth+1)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseCriteria(args []string) ([]criterion, error) {
+	var criteria []criterion
+	for i := 0; i < len(args); i++ {
+		c := criterion{name: strings.ToLower(args[i])}
+		if strings.HasPrefix(c.name, "!") {
+			c.name, c.negate = c.name[1:], true
+		}
+		hasArg, ok := matchCriteria[c.name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported Match criterion %q", args[i])
+		}
+		if hasArg {
+			if i+1 == len(args) {
+				return nil, fmt.Errorf("missing argument for Match %s", c.name)
+			}
+			i++
+			c.arg = args[i]
+		}
+		criteria = append(criteria, c)
+	}
+	all, others := false, false
+	for _, c := range criteria {
+		switch c.name {
+		case "all":
+			all = true
+		case "canonical", "final":
+		default:
+			others = true
+		}
+	}
+	if all && others {
+		return nil, errors.New("Match all cannot be combined with other criteria")
+	}
+	return criteria, nil
+}
+
+// splitLine returns the lower case keyword and the arguments of a line,
+// which may separate them with whitespace or an equals sign. It returns an
+// empty keyword for blank lines and comments.
+func splitLine(line string) (keyword string, args []string, err error) {
+	line = strings.TrimSpace(line)
+	if line == "" || line[0] == '#' {
+		return "", nil, nil
+	}
+	end := strings.IndexAny(line, " \t=")
+	if end < 0 {
+		return strings.ToLower(line), nil, nil
+	}
+	keyword, rest := strings.ToLower(line[:end]), strings.TrimLeft(line[end:], " \t")
+	if strings.HasPrefix(rest, "=") {
+		rest = strings.TrimLeft(rest[1:], " \t")
+	}
+	args, err = splitArgs(rest)
+	return keyword, args, err
+}
+
+// splitArgs splits s into whitespace separated arguments, which may be
+// quoted with double or single quotes, stopping at a comment. Backslashes
+// escape quotes, backslashes and spaces.
+func splitArgs(s string) ([]string, error) {
+	var args []string
+	var arg strings.Builder
+	inArg := false
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case ch == '\\' && i+1 < len(s) && strings.IndexByte(`\"' `, s[i+1]) >= 0:
+			i++
+			arg.WriteByte(s[i])
+			inArg = true
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			} else {
+				arg.WriteByte(ch)
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+			inArg = true
+		case ch == ' ' || ch == '\t':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		case ch == '#' && !inArg:
+			return args, nil
+		default:
+			arg.WriteByte(ch)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New("unterminated quoted argument")
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,241 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sshconfig
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func parse(t *testing.T, s string) *Config {
+	t.Helper()
+	c, err := Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.LocalUser = "local"
+	c.HomeDir = "/home/local"
+	return c
+}
+
+func lookup(t *testing.T, c *Config, alias string) *Host {
+	t.Helper()
+	h, err := c.Lookup(alias)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func TestFirstValueWins(t *testing.T) {
+	c := parse(t, `
+# Global settings.
+User default
+
+Host web* !web-test
+	HostName %h.example.com
+	Port=2222
+	IdentityFile ~/.ssh/web
+
+Host *
+	User other
+	Port 22
+	IdentityFile ~/.ssh/id_%r
+	LocalForward 8080 localhost:80
+	ServerAliveInterval 1m30s
+`)
+	h := lookup(t, c, "web1")
+	if got, _ := h.Hostname(); got != "web1.example.com" {
+		t.Errorf("Hostname = %q", got)
+	}
+	if got, _ := h.Port(); got != 2222 {
+		t.Errorf("Port = %d", got)
+	}
+	if got := h.User(); got != "default" {
+		t.Errorf("User = %q", got)
+	}
+	files, err := h.IdentityFiles()
+	if want := []string{"/home/local/.ssh/web", "/home/local/.ssh/id_default"}; err != nil || !reflect.DeepEqual(files, want) {
+		t.Errorf("IdentityFiles = %q, %v, want %q", files, err, want)
+	}
+	if got := h.GetAll("localforward"); !reflect.DeepEqual(got, []string{"8080 localhost:80"}) {
+		t.Errorf("LocalForward = %q", got)
+	}
+	if d, err := h.Duration("ServerAliveInterval"); err != nil || d != 90*time.Second {
+		t.Errorf("ServerAliveInterval = %v, %v", d, err)
+	}
+
+	h = lookup(t, c, "web-test")
+	if got, _ := h.Hostname(); got != "web-test" {
+		t.Errorf("negated host got HostName %q", got)
+	}
+	if got, _ := h.Port(); got != 22 {
+		t.Errorf("negated host got Port %d", got)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	c := parse(t, `
+Host db
+	HostName db.internal
+	User admin
+
+Match host *.internal user admin
+	ProxyJump bastion
+
+Match originalhost db !localuser root
+	ForwardAgent yes
+
+Match exec "test %h = db.internal"
+	Compression yes
+
+Match tagged prod
+	LogLevel DEBUG
+
+Match final host db.internal
+	ConnectTimeout 10
+`)
+	var commands []string
+	c.Exec = func(cmd string) (bool, error) {
+		commands = append(commands, cmd)
+		return true, nil
+	}
+	h := lookup(t, c, "db")
+	if got := h.ProxyJump(); got != "bastion" {
+		t.Errorf("ProxyJump = %q", got)
+	}
+	if ok, err := h.Bool("forwardagent", false); err != nil || !ok {
+		t.Errorf("ForwardAgent = %v, %v", ok, err)
+	}
+	if ok, _ := h.Bool("Compression", false); !ok {
+		t.Error("Match exec did not apply")
+	}
+	if h.Get("loglevel") != "" {
+		t.Error("Match tagged applied without a tag")
+	}
+	if d, _ := h.Duration("connecttimeout"); d != 10*time.Second {
+		t.Errorf("ConnectTimeout = %v, want the value of Match final", d)
+	}
+	if len(commands) == 0 || commands[0] != "test db.internal = db.internal" {
+		t.Errorf("ran %q", commands)
+	}
+
+	h = lookup(t, c, "other")
+	if h.ProxyJump() != "" || h.Get("forwardagent") != "" {
+		t.Errorf("Match blocks applied to another host")
+	}
+}
+
+func TestMatchUnsupported(t *testing.T) {
+	c := parse(t, "Match exec true\n\tUser x\n")
+	if _, err := c.Lookup("h"); err == nil {
+		t.Error("Match exec without Config.Exec succeeded")
+	}
+	c = parse(t, "Match localnetwork 10.0.0.0/8\n\tUser x\n")
+	if _, err := c.Lookup("h"); err == nil {
+		t.Error("Match localnetwork succeeded")
+	}
+	// Criteria that can't match stop exec from running.
+	c = parse(t, "Match host nothing exec true\n\tUser x\n")
+	if _, err := c.Lookup("h"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestInclude(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, s string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(s), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("config", "Host a\n\tInclude conf.d/*.conf\nHost *\n\tUser fallback\n")
+	write("conf.d/1.conf", "User first\nHost b\n\tUser b\n")
+	write("conf.d/2.conf", "Port 2200\n")
+	c, err := ParseFile(filepath.Join(dir, "config"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.LocalUser = "local"
+
+	h := lookup(t, c, "a")
+	if got := h.User(); got != "first" {
+		t.Errorf("User of a = %q", got)
+	}
+	if got, _ := h.Port(); got != 2200 {
+		t.Errorf("Port of a = %d", got)
+	}
+	h = lookup(t, c, "b")
+	if got := h.User(); got != "fallback" {
+		t.Errorf("included file applied outside its Host block: User of b = %q", got)
+	}
+
+	write("loop", "Include loop\n")
+	if _, err := ParseFile(filepath.Join(dir, "loop")); err == nil {
+		t.Error("recursive include succeeded")
+	}
+}
+
+func TestSplitLine(t *testing.T) {
+	for line, want := range map[string][]string{
+		`ProxyCommand ssh -W %h:%p bastion # comment`: {"proxycommand", "ssh", "-W", "%h:%p", "bastion"},
+		`IdentityFile "~/My Keys/id"`:                 {"identityfile", "~/My Keys/id"},
+		`SendEnv=LANG LC_*`:                           {"sendenv", "LANG", "LC_*"},
+		`User = 'a b'`:                                {"user", "a b"},
+		`RemoteCommand echo \"hi\"`:                   {"remotecommand", "echo", `"hi"`},
+		"  # only a comment":                          {""},
+	} {
+		keyword, args, err := splitLine(line)
+		if err != nil {
+			t.Errorf("splitLine(%q): %v", line, err)
+			continue
+		}
+		if got := append([]string{keyword}, args...); !reflect.DeepEqual(got, want) {
+			t.Errorf("splitLine(%q) = %q, want %q", line, got, want)
+		}
+	}
+	if _, _, err := splitLine(`User "unterminated`); err == nil {
+		t.Error("unterminated quote accepted")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, s := range []string{
+		"User\n",
+		"Match bogus x\n",
+		"Match host\n",
+		"Match all host x\n",
+	} {
+		if _, err := Parse(strings.NewReader(s)); err == nil {
+			t.Errorf("Parse(%q) succeeded", s)
+		}
+	}
+}
+
+func TestExpand(t *testing.T) {
+	c := parse(t, "Host h\n\tHostName real.example.com\n\tPort 2022\n\tUser u\n\tProxyCommand nc %h %p # %r\n")
+	h := lookup(t, c, "h")
+	got, err := h.Expand("%d/%n-%h-%p-%r-%u-%%")
+	if want := "/home/local/h-real.example.com-2022-u-local-%"; err != nil || got != want {
+		t.Errorf("Expand = %q, %v, want %q", got, err, want)
+	}
+	if cmd, _ := h.ProxyCommand(); cmd != "nc real.example.com 2022" {
+		t.Errorf("ProxyCommand = %q", cmd)
+	}
+	if _, err := h.Expand("%Z"); err == nil {
+		t.Error("unknown token accepted")
+	}
+	if files, _ := h.UserKnownHostsFiles(); len(files) != 2 || files[0] != "/home/local/.ssh/known_hosts" {
+		t.Errorf("UserKnownHostsFiles = %q", files)
+	}
+}
@@ -0,0 +1,442 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sshconfig
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Host holds the settings that apply to a destination host, as returned by
+// Config.Lookup. Keywords are case-insensitive. Values are returned as
+// written, without token expansion, except by the methods documented to
+// expand them.
+type Host struct {
+	// Alias is the host name passed to Lookup.
+	Alias string
+
+	c         *Config
+	values    map[string][][]string
+	localUser string
+	homeDir   string
+}
+
+func (c *Config) localUser() (string, error) {
+	if c.LocalUser != "" {
+		return c.LocalUser, nil
+	}
+	u, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+	return u.Username, nil
+}
+
+func (c *Config) homeDir() (string, error) {
+	if c.HomeDir != "" {
+		return c.HomeDir, nil
+	}
+	return os.UserHomeDir()
+}
+
+// Lookup returns the settings for the host alias, the name given to ssh on
+// the command line. Like ssh, it reads the file in a second pass, for Match
+// final, if the file uses it.
+func (c *Config) Lookup(alias string) (*Host, error) {
+	h := &Host{Alias: alias, c: c, values: make(map[string][][]string)}
+	var err error
+	if h.localUser, err = c.localUser(); err != nil {
+		return nil, err
+	}
+	if h.homeDir, err = c.homeDir(); err != nil {
+		return nil, err
+	}
+
+	final, err := h.apply(false)
+	if err != nil {
+		return nil, err
+	}
+	if final {
+		if _, err := h.apply(true); err != nil {
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+// apply adds the values of the entries that match, and reports whether a
+// Match final criterion was seen.
+func (h *Host) apply(finalPass bool) (usesFinal bool, err error) {
+	matched := make(map[*block]bool)
+	for _, e := range h.c.entries {
+		active := true
+		for _, b := range e.blocks {
+			m, ok := matched[b]
+			if !ok {
+				var final bool
+				m, final, err = h.match(b, finalPass)
+				if err != nil {
+					return false, fmt.Errorf("sshconfig: %s: %v", b.pos, err)
+				}
+				usesFinal = usesFinal || final
+				matched[b] = m
+			}
+			if !m {
+				active = false
+				break
+			}
+		}
+		if active {
+			h.add(e.keyword, e.args)
+		}
+	}
+	return usesFinal, nil
+}
+
+func (h *Host) add(keyword string, args []string) {
+	old := h.values[keyword]
+	if len(old) > 0 && !multiValued[keyword] {
+		return
+	}
+	for _, o := range old {
+		if strings.Join(o, " ") == strings.Join(args, " ") {
+			return
+		}
+	}
+	h.values[keyword] = append(old, args)
+}
+
+// match reports whether b matches, and whether it has a final criterion.
+func (h *Host) match(b *block, finalPass bool) (matched, final bool, err error) {
+	if !b.isMatch {
+		return ssh.MatchPrincipalPattern(strings.ToLower(h.Alias), lowerAll(b.patterns)), false, nil
+	}
+	matched = true
+	for _, c := range b.criteria {
+		var m bool
+		switch c.name {
+		case "all":
+			m = true
+		case "canonical":
+			m = false
+		case "final":
+			final = true
+			m = finalPass
+		case "host":
+			host, err := h.matchHost(finalPass)
+			if err != nil {
+				return false, false, err
+			}
+			m = ssh.MatchPrincipalPattern(strings.ToLower(host), []string{strings.ToLower(c.arg)})
+		case "originalhost":
+			m = ssh.MatchPrincipalPattern(strings.ToLower(h.Alias), []string{strings.ToLower(c.arg)})
+		case "user":
+			m = ssh.MatchPrincipalPattern(h.User(), []string{c.arg})
+		case "localuser":
+			m = ssh.MatchPrincipalPattern(h.localUser, []string{c.arg})
+		case "tagged":
+			m = ssh.MatchPrincipalPattern(h.Get("tag"), []string{c.arg})
+		case "exec":
+			if !matched {
+				// Like ssh, don't run commands needlessly.
+				continue
+			}
+			if h.c.Exec == nil {
+				return false, false, errors.New("Match exec is not supported without Config.Exec")
+			}
+			cmd, err := h.Expand(c.arg)
+			if err != nil {
+				return false, false, err
+			}
+			if m, err = h.c.Exec(cmd); err != nil {
+				return false, false, err
+			}
+		case "localnetwork":
+			return false, false, errors.New("Match localnetwork is not supported")
+		}
+		if m == c.negate {
+			// Keep going, to find out if there is a final criterion.
+			matched = false
+		}
+	}
+	return matched, final, nil
+}
+
+// matchHost returns the host name matched by Match host: the HostName so
+// far, or the alias.
+func (h *Host) matchHost(finalPass bool) (string, error) {
+	if finalPass || h.Get("hostname") != "" {
+		return h.Hostname()
+	}
+	return h.Alias, nil
+}
+
+func lowerAll(s []string) []string {
+	l := make([]string, len(s))
+	for i, v := range s {
+		l[i] = strings.ToLower(v)
+	}
+	return l
+}
+
+// Get returns the value of keyword, with its arguments separated by
+// spaces, or "" if it is not set.
+func (h *Host) Get(keyword string) string {
+	return strings.Join(h.Args(keyword), " ")
+}
+
+// Args returns the arguments of the first value of keyword.
+func (h *Host) Args(keyword string) []string {
+	values := h.values[strings.ToLower(keyword)]
+	if len(values) == 0 {
+		return nil
+	}
+	return values[0]
+}
+
+// GetAll returns all the values of a keyword that may be given several
+// times, such as IdentityFile or LocalForward, with their arguments
+// separated by spaces.
+func (h *Host) GetAll(keyword string) []string {
+	var all []string
+	for _, args := range h.values[strings.ToLower(keyword)] {
+		all = append(all, strings.Join(args, " "))
+	}
+	return all
+}
+
+// Hostname returns the host name to connect to: HostName, in which %h is
+// replaced with the alias, or the alias.
+func (h *Host) Hostname() (string, error) {
+	hostname := h.Get("hostname")
+	if hostname == "" {
+		return h.Alias, nil
+	}
+	return expandTokens(hostname, map[byte]string{'h': h.Alias})
+}
+
+// Port returns the port to connect to, 22 by default.
+func (h *Host) Port() (int, error) {
+	v := h.Get("port")
+	if v == "" {
+		return 22, nil
+	}
+	port, err := strconv.Atoi(v)
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("sshconfig: invalid port %q", v)
+	}
+	return port, nil
+}
+
+// User returns the user to log in as, the local user by default.
+func (h *Host) User() string {
+	if u := h.Get("user"); u != "" {
+		return u
+	}
+	return h.localUser
+}
+
+// Bool returns the value of a yes/no keyword, or def if it is not set.
+func (h *Host) Bool(keyword string, def bool) (bool, error) {
+	switch v := strings.ToLower(h.Get(keyword)); v {
+	case "":
+		return def, nil
+	case "yes", "true":
+		return true, nil
+	case "no", "false":
+		return false, nil
+	default:
+		return false, fmt.Errorf("sshconfig: invalid %s value %q", keyword, v)
+	}
+}
+
+// Duration returns the value of a keyword that is a time interval, such as
+// ConnectTimeout or ServerAliveInterval, or 0 if it is not set. Intervals
+// are in seconds, or in the format of sshd_config(5), as in 1m30s.
+func (h *Host) Duration(keyword string) (time.Duration, error) {
+	v := h.Get(keyword)
+	if v == "" || strings.EqualFold(v, "none") {
+		return 0, nil
+	}
+	d, err := parseInterval(v)
+	if err != nil {
+		return 0, fmt.Errorf("sshconfig: invalid %s value %q", keyword, v)
+	}
+	return d, nil
+}
+
+// parseInterval parses a time interval: numbers of seconds, or of units
+// given by a suffix of s, m, h, d or w, which may be combined.
+func parseInterval(v string) (time.Duration, error) {
+	units := map[byte]time.Duration{
+		's': time.Second, 'S': time.Second,
+		'm': time.Minute, 'M': time.Minute,
+		'h': time.Hour, 'H': time.Hour,
+		'd': 24 * time.Hour, 'D': 24 * time.Hour,
+		'w': 7 * 24 * time.Hour, 'W': 7 * 24 * time.Hour,
+	}
+	var total time.Duration
+	for v != "" {
+		i := 0
+		for i < len(v) && v[i] >= '0' && v[i] <= '9' {
+			i++
+		}
+		if i == 0 {
+			return 0, errors.New("invalid interval")
+		}
+		n, err := strconv.Atoi(v[:i])
+		if err != nil {
+			return 0, err
+		}
+		unit := time.Second
+		if i < len(v) {
+			u, ok := units[v[i]]
+			if !ok {
+				return 0, errors.New("invalid interval unit")
+			}
+			unit = u
+			i++
+		}
+		total += time.Duration(n) * unit
+		v = v[i:]
+	}
+	return total, nil
+}
+
+// IdentityFiles returns the IdentityFile values, with tokens and ~
+// expanded. It returns nil if there are none, in which case ssh tries its
+// default keys.
+func (h *Host) IdentityFiles() ([]string, error) {
+	return h.expandPaths(h.GetAll("identityfile"))
+}
+
+// CertificateFiles returns the CertificateFile values, with tokens and ~
+// expanded.
+func (h *Host) CertificateFiles() ([]string, error) {
+	return h.expandPaths(h.GetAll("certificatefile"))
+}
+
+// UserKnownHostsFiles returns the files of UserKnownHostsFile, with tokens
+// and ~ expanded, by default ~/.ssh/known_hosts and ~/.ssh/known_hosts2.
+func (h *Host) UserKnownHostsFiles() ([]string, error) {
+	files := h.Args("userknownhostsfile")
+	if len(files) == 0 {
+		files = []string{"~/.ssh/known_hosts", "~/.ssh/known_hosts2"}
+	} else if len(files) == 1 && strings.EqualFold(files[0], "none") {
+		return nil, nil
+	}
+	return h.expandPaths(files)
+}
+
+func (h *Host) expandPaths(paths []string) ([]string, error) {
+	var expanded []string
+	for _, p := range paths {
+		p, err := h.Expand(p)
+		if err != nil {
+			return nil, err
+		}
+		if p == "~" || strings.HasPrefix(p, "~/") {
+			p = filepath.Join(h.homeDir, p[1:])
+		}
+		expanded = append(expanded, p)
+	}
+	return expanded, nil
+}
+
+// ProxyJump returns the value of ProxyJump, or "" if it is not set or is
+// none.
+func (h *Host) ProxyJump() string {
+	if v := h.Get("proxyjump"); !strings.EqualFold(v, "none") {
+		return v
+	}
+	return ""
+}
+
+// ProxyCommand returns the value of ProxyCommand with tokens expanded, or
+// "" if it is not set or is none.
+func (h *Host) ProxyCommand() (string, error) {
+	v := h.Get("proxycommand")
+	if strings.EqualFold(v, "none") {
+		return "", nil
+	}
+	return h.Expand(v)
+}
+
+// Expand replaces the tokens of ssh_config(5) in s, such as %h for the host
+// name and %p for the port, with their values for h. Unknown tokens are
+// errors.
+func (h *Host) Expand(s string) (string, error) {
+	if !strings.Contains(s, "%") {
+		return s, nil
+	}
+	hostname, err := h.Hostname()
+	if err != nil {
+		return "", err
+	}
+	port, err := h.Port()
+	if err != nil {
+		return "", err
+	}
+	local, err := os.Hostname()
+	if err != nil {
+		return "", err
+	}
+	shortLocal, _, _ := strings.Cut(local, ".")
+	hostKeyAlias := h.Get("hostkeyalias")
+	if hostKeyAlias == "" {
+		hostKeyAlias = h.Alias
+	}
+	tokens := map[byte]string{
+		'd': h.homeDir,
+		'h': hostname,
+		'i': strconv.Itoa(os.Getuid()),
+		'j': h.ProxyJump(),
+		'k': hostKeyAlias,
+		'L': shortLocal,
+		'l': local,
+		'n': h.Alias,
+		'p': strconv.Itoa(port),
+		'r': h.User(),
+		'u': h.localUser,
+	}
+	sum := sha1.Sum([]byte(local + hostname + tokens['p'] + tokens['r'] + tokens['j']))
+	tokens['C'] = hex.EncodeToString(sum[:])
+	return expandTokens(s, tokens)
+}
+
+// expandTokens replaces %x in s with tokens[x], and %% with %.
+func expandTokens(s string, tokens map[byte]string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+1 == len(s) {
+			return "", fmt.Errorf("sshconfig: %q ends with %%", s)
+		}
+		i++
+		if s[i] == '%' {
+			b.WriteByte('%')
+			continue
+		}
+		v, ok := tokens[s[i]]
+		if !ok {
+			return "", fmt.Errorf("sshconfig: unknown token %%%c in %q", s[i], s)
+		}
+		b.WriteString(v)
+	}
+	return b.String(), nil
+}
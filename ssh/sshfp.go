@@ -0,0 +1,170 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// Algorithm numbers of SSHFP records, see RFC 4255, RFC 6594 and RFC 7479.
+const (
+	SSHFPAlgorithmRSA     = 1
+	SSHFPAlgorithmDSA     = 2
+	SSHFPAlgorithmECDSA   = 3
+	SSHFPAlgorithmEd25519 = 4
+)
+
+// Fingerprint types of SSHFP records.
+const (
+	SSHFPTypeSHA1   = 1
+	SSHFPTypeSHA256 = 2
+)
+
+// An SSHFPRecord is an SSHFP DNS record, which publishes the fingerprint of
+// a host key.
+type SSHFPRecord struct {
+	Algorithm   uint8
+	Type        uint8
+	Fingerprint []byte
+}
+
+// sshfpAlgorithm returns the SSHFP algorithm number of pub, or 0 if there
+// is none.
+func sshfpAlgorithm(pub PublicKey) uint8 {
+	switch pub.Type() {
+	case KeyAlgoRSA:
+		return SSHFPAlgorithmRSA
+	case KeyAlgoDSA:
+		return SSHFPAlgorithmDSA
+	case KeyAlgoECDSA256, KeyAlgoECDSA384, KeyAlgoECDSA521:
+		return SSHFPAlgorithmECDSA
+	case KeyAlgoED25519:
+		return SSHFPAlgorithmEd25519
+	}
+	return 0
+}
+
+// SSHFPRecords returns the SSHFP records of pub, with SHA-1 and SHA-256
+// fingerprints, like ssh-keygen -r. It returns nil for keys that have no
+// SSHFP algorithm number, such as security keys.
+func SSHFPRecords(pub PublicKey) []SSHFPRecord {
+	alg := sshfpAlgorithm(pub)
+	if alg == 0 {
+		return nil
+	}
+	sum1 := sha1.Sum(pub.Marshal())
+	sum256 := sha256.Sum256(pub.Marshal())
+	return []SSHFPRecord{
+		{Algorithm: alg, Type: SSHFPTypeSHA1, Fingerprint: sum1[:]},
+		{Algorithm: alg, Type: SSHFPTypeSHA256, Fingerprint: sum256[:]},
+	}
+}
+
+// Matches reports whether r is a record of pub. Records with unknown
+// fingerprint types never match.
+func (r SSHFPRecord) Matches(pub PublicKey) bool {
+	if r.Algorithm == 0 || r.Algorithm != sshfpAlgorithm(pub) {
+		return false
+	}
+	var sum []byte
+	switch r.Type {
+	case SSHFPTypeSHA1:
+		s := sha1.Sum(pub.Marshal())
+		sum = s[:]
+	case SSHFPTypeSHA256:
+		s := sha256.Sum256(pub.Marshal())
+		sum = s[:]
+	default:
+		return false
+	}
+	return bytes.Equal(sum, r.Fingerprint)
+}
+
+// An SSHFPResolver looks up the SSHFP records of a host name. authenticated
+// reports whether the answer was validated with DNSSEC, for example by the
+// AD flag set by a trusted validating resolver.
+type SSHFPResolver interface {
+	LookupSSHFP(ctx context.Context, name string) (records []SSHFPRecord, authenticated bool, err error)
+}
+
+// SSHFPChecker verifies host keys against SSHFP DNS records, like the
+// VerifyHostKeyDNS option of ssh. Its CheckHostKey method can be used as
+// ClientConfig.HostKeyCallback.
+type SSHFPChecker struct {
+	// Resolver looks up the records. It must be set, as the standard
+	// library can't look up SSHFP records.
+	Resolver SSHFPResolver
+
+	// AllowUnauthenticated makes CheckHostKey accept keys whose records
+	// were not validated with DNSSEC, which it otherwise rejects. Without
+	// DNSSEC, SSHFP records are only as trustworthy as the path to the DNS
+	// server.
+	AllowUnauthenticated bool
+
+	// Timeout limits the lookup, if not zero.
+	Timeout time.Duration
+}
+
+// CheckHostKey checks the host key of the server dialed at addr against the
+// SSHFP records of its host name. Certificates are checked by the key
+// they certify, as ssh does. If there are no records for the algorithm of
+// the key, or addr is an IP address, the error matches ErrUnknownHostKey,
+// so that another callback can be tried with AnyHostKeyCallback.
+func (c *SSHFPChecker) CheckHostKey(addr string, remote net.Addr, key PublicKey) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	if net.ParseIP(strings.Trim(host, "[]")) != nil {
+		return fmt.Errorf("ssh: no SSHFP records for IP address %s: %w", host, ErrUnknownHostKey)
+	}
+	if cert, ok := key.(*Certificate); ok {
+		key = cert.Key
+	}
+	alg := sshfpAlgorithm(key)
+	if alg == 0 {
+		return fmt.Errorf("ssh: no SSHFP algorithm for %s keys: %w", key.Type(), ErrUnknownHostKey)
+	}
+
+	if c.Resolver == nil {
+		return errors.New("ssh: SSHFPChecker has no Resolver")
+	}
+	ctx := context.Background()
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	records, authenticated, err := c.Resolver.LookupSSHFP(ctx, host)
+	if err != nil {
+		return fmt.Errorf("ssh: looking up SSHFP records of %s: %w", host, err)
+	}
+
+	found := false
+	for _, r := range records {
+		if r.Algorithm != alg {
+			continue
+		}
+		found = true
+		if r.Matches(key) {
+			if !authenticated && !c.AllowUnauthenticated {
+				return errors.New("ssh: SSHFP records of " + host + " are not DNSSEC-authenticated")
+			}
+			return nil
+		}
+	}
+	if !found {
+		return fmt.Errorf("ssh: no SSHFP records for %s keys of %s: %w", key.Type(), host, ErrUnknownHostKey)
+	}
+	return fmt.Errorf("ssh: host key %s does not match the SSHFP records of %s", FingerprintSHA256(key), host)
+}
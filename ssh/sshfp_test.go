@@ -0,0 +1,97 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+type testSSHFPResolver struct {
+	records       map[string][]SSHFPRecord
+	authenticated bool
+}
+
+func (r *testSSHFPResolver) LookupSSHFP(ctx context.Context, name string) ([]SSHFPRecord, bool, error) {
+	records, ok := r.records[name]
+	if !ok {
+		return nil, false, errors.New("no such host")
+	}
+	return records, r.authenticated, nil
+}
+
+func TestSSHFPRecords(t *testing.T) {
+	// From ssh-keygen -r.
+	want := []string{
+		"4 1 3c4f13ff0722469c12b3bff45842a057b06dd6bd",
+		"4 2 995d663d7e12e9313ea1dc9f5835c6ac2e5fbd06cb87ec3ca3634adead8c998c",
+	}
+	records := SSHFPRecords(testPublicKeys["ed25519"])
+	if len(records) != len(want) {
+		t.Fatalf("got %d records, want %d", len(records), len(want))
+	}
+	for i, r := range records {
+		got := fmt.Sprintf("%d %d %x", r.Algorithm, r.Type, r.Fingerprint)
+		if got != want[i] {
+			t.Errorf("record %d = %s, want %s", i, got, want[i])
+		}
+		if !r.Matches(testPublicKeys["ed25519"]) || r.Matches(testPublicKeys["ecdsa"]) {
+			t.Errorf("record %d matches the wrong keys", i)
+		}
+	}
+}
+
+func TestSSHFPChecker(t *testing.T) {
+	ed, rsa := testPublicKeys["ed25519"], testPublicKeys["rsa"]
+	resolver := &testSSHFPResolver{records: map[string][]SSHFPRecord{
+		"host.example":  SSHFPRecords(ed),
+		"empty.example": nil,
+	}}
+	c := &SSHFPChecker{}
+	if err := c.CheckHostKey("host.example:22", nil, ed); err == nil {
+		t.Error("accepted a key without a Resolver")
+	}
+	c.Resolver = resolver
+
+	if err := c.CheckHostKey("host.example:22", nil, ed); err == nil {
+		t.Error("accepted unauthenticated records")
+	}
+	c.AllowUnauthenticated = true
+	if err := c.CheckHostKey("host.example:22", nil, ed); err != nil {
+		t.Errorf("unauthenticated records with AllowUnauthenticated: %v", err)
+	}
+	c.AllowUnauthenticated = false
+	resolver.authenticated = true
+	if err := c.CheckHostKey("host.example:22", nil, ed); err != nil {
+		t.Errorf("matching key: %v", err)
+	}
+
+	cert := &Certificate{Key: ed, CertType: HostCert}
+	if err := c.CheckHostKey("host.example:22", nil, cert); err != nil {
+		t.Errorf("certificate of a matching key: %v", err)
+	}
+
+	other := SSHFPRecords(ed)
+	other[1].Fingerprint = make([]byte, 32)
+	resolver.records["host.example"] = other[1:]
+	if err := c.CheckHostKey("host.example:22", nil, ed); err == nil || errors.Is(err, ErrUnknownHostKey) {
+		t.Errorf("mismatched key: got %v, want a mismatch", err)
+	}
+
+	for _, addr := range []string{"host.example:22", "empty.example:22", "127.0.0.1:22", "[::1]:22"} {
+		key := rsa
+		if addr != "host.example:22" {
+			key = ed
+		}
+		if err := c.CheckHostKey(addr, nil, key); !errors.Is(err, ErrUnknownHostKey) {
+			t.Errorf("%s: got %v, want an unknown key", addr, err)
+		}
+	}
+	if err := c.CheckHostKey("missing.example:22", nil, ed); err == nil || errors.Is(err, ErrUnknownHostKey) {
+		t.Errorf("lookup error: got %v", err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"io"
 	"net"
+	"sync"
 )
 
 // streamLocalChannelOpenDirectMsg is a struct used for SSH_MSG_CHANNEL_OPEN message
@@ -114,3 +115,111 @@ func (l *unixListener) Addr() net.Addr {
 		Net:  "unix",
 	}
 }
+
+// streamLocalForwarder serves the streamlocal-forward@openssh.com requests
+// of a client on the server side.
+type streamLocalForwarder struct {
+	conn  *connection
+	allow func(conn ConnMetadata, socketPath string) bool
+
+	mu        sync.Mutex
+	listeners map[string]net.Listener
+}
+
+// handleStreamLocalForwards sets up the mux to handle Unix socket
+// forwarding requests. It must be called before the read loop starts.
+func (s *connection) handleStreamLocalForwards(allow func(conn ConnMetadata, socketPath string) bool) {
+	f := &streamLocalForwarder{
+		conn:      s,
+		allow:     allow,
+		listeners: make(map[string]net.Listener),
+	}
+	s.mux.handleRequests("streamlocal-forward@openssh.com", f.forward)
+	s.mux.handleRequests("cancel-streamlocal-forward@openssh.com", f.cancel)
+	go func() {
+		s.mux.Wait()
+		f.closeAll()
+	}()
+}
+
+func parseStreamLocalForward(payload []byte) (string, bool) {
+	var msg struct {
+		SocketPath string
+	}
+	if err := Unmarshal(payload, &msg); err != nil {
+		return "", false
+	}
+	return msg.SocketPath, true
+}
+
+func (f *streamLocalForwarder) forward(payload []byte) (bool, []byte) {
+	socketPath, ok := parseStreamLocalForward(payload)
+	if !ok || !f.allow(f.conn, socketPath) {
+		return false, nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.listeners == nil {
+		// The connection is closed.
+		return false, nil
+	}
+	if _, ok := f.listeners[socketPath]; ok {
+		return false, nil
+	}
+	l, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return false, nil
+	}
+	f.listeners[socketPath] = l
+	go f.serve(l, socketPath)
+	return true, nil
+}
+
+func (f *streamLocalForwarder) cancel(payload []byte) (bool, []byte) {
+	socketPath, ok := parseStreamLocalForward(payload)
+	if !ok {
+		return false, nil
+	}
+	f.mu.Lock()
+	l, ok := f.listeners[socketPath]
+	delete(f.listeners, socketPath)
+	f.mu.Unlock()
+	if !ok {
+		return false, nil
+	}
+	l.Close()
+	return true, nil
+}
+
+func (f *streamLocalForwarder) closeAll() {
+	f.mu.Lock()
+	listeners := f.listeners
+	f.listeners = nil
+	f.mu.Unlock()
+	for _, l := range listeners {
+		l.Close()
+	}
+}
+
+// serve forwards the connections accepted on l to the client.
+func (f *streamLocalForwarder) serve(l net.Listener, socketPath string) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			ch, in, err := f.conn.OpenChannel("forwarded-streamlocal@openssh.com", Marshal(&forwardedStreamLocalPayload{
+				SocketPath: socketPath,
+			}))
+			if err != nil {
+				return
+			}
+			go DiscardRequests(in)
+			chConn := NewChannelConn(ch, &net.UnixAddr{Name: "@", Net: "unix"}, &net.UnixAddr{Name: socketPath, Net: "unix"})
+			defer chConn.Close()
+			proxyHalfClose(conn, chConn)
+		}()
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+)
+
+func TestServerStreamLocalForward(t *testing.T) {
+	dir := t.TempDir()
+	allowed := filepath.Join(dir, "allowed.sock")
+	if l, err := net.Listen("unix", filepath.Join(dir, "probe.sock")); err != nil {
+		t.Skipf("Unix sockets are not supported: %v", err)
+	} else {
+		l.Close()
+	}
+
+	c1, c2, err := netPipe()
+	if err != nil {
+		t.Fatalf("netPipe: %v", err)
+	}
+	defer c1.Close()
+	defer c2.Close()
+
+	serverConf := &ServerConfig{
+		NoClientAuth: true,
+		StreamLocalForwardCallback: func(conn ConnMetadata, socketPath string) bool {
+			return socketPath == allowed
+		},
+	}
+	serverConf.AddHostKey(testSigners["ecdsa"])
+	go func() {
+		conn, chans, reqs, err := NewServerConn(c2, serverConf)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		go DiscardRequests(reqs)
+		for newCh := range chans {
+			newCh.Reject(Prohibited, "no channels")
+		}
+	}()
+
+	conn, chans, reqs, err := NewClientConn(c1, "", &ClientConfig{
+		User:            "user",
+		HostKeyCallback: InsecureIgnoreHostKey(),
+	})
+	if err != nil {
+		t.Fatalf("NewClientConn: %v", err)
+	}
+	client := NewClient(conn, chans, reqs)
+	defer client.Close()
+
+	if _, err := client.ListenUnix(filepath.Join(dir, "denied.sock")); err == nil {
+		t.Error("ListenUnix succeeded for a path the server denies")
+	}
+
+	l, err := client.ListenUnix(allowed)
+	if err != nil {
+		t.Fatalf("ListenUnix: %v", err)
+	}
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				io.Copy(c, c)
+				c.Close()
+			}()
+		}
+	}()
+
+	local, err := net.Dial("unix", allowed)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer local.Close()
+	if _, err := io.WriteString(local, "hello"); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	local.(*net.UnixConn).CloseWrite()
+	got, err := io.ReadAll(local)
+	if err != nil || string(got) != "hello" {
+		t.Errorf("got %q, %v through the forward; want %q", got, err, "hello")
+	}
+
+	if err := l.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if c, err := net.Dial("unix", allowed); err == nil {
+		c.Close()
+		t.Error("socket still accepts connections after the forward was cancelled")
+	}
+}
+
+func TestServerStreamLocalForwardBlocking(t *testing.T) {
+	dir := t.TempDir()
+	if l, err := net.Listen("unix", filepath.Join(dir, "probe.sock")); err != nil {
+		t.Skipf("Unix sockets are not supported: %v", err)
+	} else {
+		l.Close()
+	}
+
+	c1, c2, err := netPipe()
+	if err != nil {
+		t.Fatalf("netPipe: %v", err)
+	}
+	defer c1.Close()
+	defer c2.Close()
+
+	entered, release := make(chan struct{}), make(chan struct{})
+	serverConf := &ServerConfig{
+		NoClientAuth: true,
+		StreamLocalForwardCallback: func(conn ConnMetadata, socketPath string) bool {
+			close(entered)
+			<-release
+			return true
+		},
+	}
+	serverConf.AddHostKey(testSigners["ecdsa"])
+	go func() {
+		conn, chans, reqs, err := NewServerConn(c2, serverConf)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		go DiscardRequests(reqs)
+		for newCh := range chans {
+			newCh.Reject(Prohibited, "no channels")
+		}
+	}()
+
+	conn, chans, reqs, err := NewClientConn(c1, "", &ClientConfig{
+		User:            "user",
+		HostKeyCallback: InsecureIgnoreHostKey(),
+	})
+	if err != nil {
+		t.Fatalf("NewClientConn: %v", err)
+	}
+	client := NewClient(conn, chans, reqs)
+	defer client.Close()
+
+	listened := make(chan error, 1)
+	go func() {
+		l, err := client.ListenUnix(filepath.Join(dir, "agent.sock"))
+		if err == nil {
+			l.Close()
+		}
+		listened <- err
+	}()
+
+	// The connection keeps working while the server decides.
+	<-entered
+	if _, _, err := client.OpenChannel("session", nil); err == nil {
+		t.Error("OpenChannel succeeded, want a rejection")
+	}
+	close(release)
+	if err := <-listened; err != nil {
+		t.Errorf("ListenUnix: %v", err)
+	}
+}
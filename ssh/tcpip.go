@@ -418,6 +418,110 @@ func (c *Client) Dial(n, addr string) (net.Conn, error) {
 	}
 }
 
+// ForwardStdio connects to addr from the remote host and copies data from r
+// to the connection and from the connection to w, like the -W option of
+// OpenSSH, typically with the standard input and output of a program used
+// as a ProxyCommand. The end of r is forwarded as a half-close. It returns
+// once the remote side has closed the connection and all its data has been
+// written to w.
+func (c *Client) ForwardStdio(addr string, r io.Reader, w io.Writer) error {
+	conn, err := c.Dial("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	go func() {
+		if _, err := io.Copy(conn, r); err != nil {
+			conn.Close()
+			return
+		}
+		conn.(closeWriter).CloseWrite()
+	}()
+	_, err = io.Copy(w, conn)
+	return err
+}
+
+// DialSSH starts a client connection to the SSH server at addr, reached
+// through a direct-tcpip channel of c, so that c acts as a jump host like
+// with the -J option of OpenSSH. The new connection is independent of c,
+// but fails once c is closed.
+func (c *Client) DialSSH(n, addr string, config *ClientConfig) (*Client, error) {
+	conn, err := c.Dial(n, addr)
+	if err != nil {
+		return nil, err
+	}
+	sshConn, chans, reqs, err := NewClientConn(conn, addr, config)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(sshConn, chans, reqs), nil
+}
+
+// JumpHost is a host of a chain of SSH connections dialed with DialJump.
+type JumpHost struct {
+	// Addr is the host and port of the SSH server. It is resolved by the
+	// previous host of the chain, if any.
+	Addr string
+
+	// Config is used to connect to the host, and its HostKeyCallback to
+	// verify it.
+	Config *ClientConfig
+}
+
+// DialJump starts a client connection to the last of hosts through the
+// others, like the -J option of OpenSSH given several jump hosts. The first
+// host is dialed on network like with DialContext, and each of the others
+// through a direct-tcpip channel of the previous one. ctx bounds the whole
+// chain. Closing the returned Client, or losing its connection, closes the
+// connections to the jump hosts.
+func DialJump(ctx context.Context, network string, hosts ...JumpHost) (*Client, error) {
+	if len(hosts) == 0 {
+		return nil, errors.New("ssh: no host to dial")
+	}
+	var chain []*Client
+	closeChain := func() {
+		for i := len(chain) - 1; i >= 0; i-- {
+			chain[i].Close()
+		}
+	}
+	for i, h := range hosts {
+		var client *Client
+		var err error
+		if i == 0 {
+			client, err = DialContext(ctx, network, h.Addr, h.Config)
+		} else {
+			client, err = chain[i-1].dialSSHContext(ctx, h.Addr, h.Config)
+		}
+		if err != nil {
+			closeChain()
+			return nil, fmt.Errorf("ssh: connecting to %s: %w", h.Addr, err)
+		}
+		chain = append(chain, client)
+	}
+	client := chain[len(chain)-1]
+	chain = chain[:len(chain)-1]
+	if len(chain) > 0 {
+		go func() {
+			client.Wait()
+			closeChain()
+		}()
+	}
+	return client, nil
+}
+
+// dialSSHContext is like DialSSH, but ctx bounds the connection.
+func (c *Client) dialSSHContext(ctx context.Context, addr string, config *ClientConfig) (*Client, error) {
+	conn, err := c.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	sshConn, chans, reqs, err := NewClientConnContext(ctx, conn, addr, config)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(sshConn, chans, reqs), nil
+}
+
 // DialTCP connects to the remote address raddr on the network net,
 // which must be "tcp", "tcp4", or "tcp6".  If laddr is not nil, it is used
 // as the local address for the connection.
@@ -473,6 +577,39 @@ type chanConn struct {
 	laddr, raddr net.Addr
 }
 
+// NewChannelConn returns a net.Conn backed by the given Channel. LocalAddr and
+// RemoteAddr of the returned connection report laddr and raddr respectively;
+// a nil address is reported as the zero TCP address, as for connections
+// returned by Client.Dial. The deadline methods of the returned connection
+// are effective if ch implements ChannelWithDeadlines, which is the case for
+// all channels created by this package, and return an error otherwise.
+func NewChannelConn(ch Channel, laddr, raddr net.Addr) net.Conn {
+	zeroAddr := &net.TCPAddr{
+		IP:   net.IPv4zero,
+		Port: 0,
+	}
+	if laddr == nil {
+		laddr = zeroAddr
+	}
+	if raddr == nil {
+		raddr = zeroAddr
+	}
+	return &chanConn{
+		Channel: ch,
+		laddr:   laddr,
+		raddr:   raddr,
+	}
+}
+
+// CloseRead shuts down the reading side of the connection, if the backing
+// channel implements ChannelWithCloseRead.
+func (t *chanConn) CloseRead() error {
+	if ch, ok := t.Channel.(ChannelWithCloseRead); ok {
+		return ch.CloseRead()
+	}
+	return errors.New("ssh: tcpChan: CloseRead not supported")
+}
+
 // LocalAddr returns the local network address.
 func (t *chanConn) LocalAddr() net.Addr {
 	return t.laddr
@@ -505,8 +642,10 @@ func (t *chanConn) SetReadDeadline(deadline time.Time) error {
 	return errors.New("ssh: tcpChan: deadline not supported")
 }
 
-// SetWriteDeadline exists to satisfy the net.Conn interface
-// but is not implemented by this type.  It always returns an error.
+// SetWriteDeadline sets the write deadline.
+// A zero value for t means Write will not time out.
+// After the deadline, the error from Write will implement net.Error
+// with Timeout() == true.
 func (t *chanConn) SetWriteDeadline(deadline time.Time) error {
 	if ch, ok := t.Channel.(ChannelWithDeadlines); ok {
 		return ch.SetWriteDeadline(deadline)
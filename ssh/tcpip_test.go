@@ -5,8 +5,14 @@
 package ssh
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"net"
+	"os"
+	"strings"
 	"testing"
 	"time"
 )
@@ -51,3 +57,256 @@ func TestClientDialContextWithDeadline(t *testing.T) {
 		t.Errorf("DialContext: got nil error, expected %v", context.DeadlineExceeded)
 	}
 }
+
+func TestNewChannelConnDeadlines(t *testing.T) {
+	writer, reader, mux := channelPair(t)
+	defer writer.Close()
+	defer reader.Close()
+	defer mux.Close()
+
+	conn := NewChannelConn(reader, nil, &net.UnixAddr{Name: "remote", Net: "unix"})
+	if got := conn.LocalAddr().String(); got != "0.0.0.0:0" {
+		t.Errorf("LocalAddr: got %q, want %q", got, "0.0.0.0:0")
+	}
+	if got := conn.RemoteAddr().String(); got != "remote" {
+		t.Errorf("RemoteAddr: got %q, want %q", got, "remote")
+	}
+
+	if err := conn.SetReadDeadline(time.Now().Add(10 * time.Millisecond)); err != nil {
+		t.Fatalf("SetReadDeadline: %v", err)
+	}
+	var buf [1]byte
+	_, err := conn.Read(buf[:])
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("Read: got %v, want %v", err, os.ErrDeadlineExceeded)
+	}
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Errorf("Read: error %v is not a net.Error with Timeout() == true", err)
+	}
+
+	// Clearing the deadline makes the connection usable again.
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		t.Fatalf("SetDeadline: %v", err)
+	}
+	go writer.Write([]byte("x"))
+	if _, err := conn.Read(buf[:]); err != nil {
+		t.Fatalf("Read after clearing deadline: %v", err)
+	}
+}
+
+// newJumpServer returns a client connected to a server that serves
+// direct-tcpip channels to "echo:7" by echoing data, and those to "ssh:22"
+// with a second SSH server.
+func newJumpServer(t *testing.T) *Client {
+	c1, c2, err := netPipe()
+	if err != nil {
+		t.Fatalf("netPipe: %v", err)
+	}
+	t.Cleanup(func() {
+		c1.Close()
+		c2.Close()
+	})
+
+	serverConf := &ServerConfig{NoClientAuth: true}
+	serverConf.AddHostKey(testSigners["ecdsa"])
+	serve := func(conn net.Conn) {
+		sconn, chans, reqs, err := NewServerConn(conn, serverConf)
+		if err != nil {
+			return
+		}
+		defer sconn.Close()
+		go DiscardRequests(reqs)
+		for newCh := range chans {
+			var msg forwardedTCPPayload
+			if newCh.ChannelType() != "direct-tcpip" || Unmarshal(newCh.ExtraData(), &msg) != nil {
+				newCh.Reject(UnknownChannelType, "unexpected channel")
+				continue
+			}
+			ch, reqs, err := newCh.Accept()
+			if err != nil {
+				return
+			}
+			go DiscardRequests(reqs)
+			switch net.JoinHostPort(msg.Addr, fmt.Sprint(msg.Port)) {
+			case "echo:7":
+				go func() {
+					io.Copy(ch, ch)
+					ch.CloseWrite()
+				}()
+			case "ssh:22":
+				go func() {
+					nested, chans, reqs, err := NewServerConn(NewChannelConn(ch, nil, nil), serverConf)
+					if err != nil {
+						return
+					}
+					go DiscardRequests(reqs)
+					for newCh := range chans {
+						newCh.Reject(Prohibited, "nested")
+					}
+					nested.Close()
+				}()
+			default:
+				ch.Close()
+			}
+		}
+	}
+	go serve(c2)
+
+	conn, chans, reqs, err := NewClientConn(c1, "", &ClientConfig{
+		User:            "user",
+		HostKeyCallback: InsecureIgnoreHostKey(),
+	})
+	if err != nil {
+		t.Fatalf("NewClientConn: %v", err)
+	}
+	client := NewClient(conn, chans, reqs)
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestForwardStdio(t *testing.T) {
+	client := newJumpServer(t)
+	var out bytes.Buffer
+	if err := client.ForwardStdio("echo:7", strings.NewReader("hello"), &out); err != nil {
+		t.Fatalf("ForwardStdio: %v", err)
+	}
+	if out.String() != "hello" {
+		t.Errorf("got %q, want %q", out.String(), "hello")
+	}
+}
+
+func TestDialSSH(t *testing.T) {
+	client := newJumpServer(t)
+	nested, err := client.DialSSH("tcp", "ssh:22", &ClientConfig{
+		User:            "user",
+		HostKeyCallback: InsecureIgnoreHostKey(),
+	})
+	if err != nil {
+		t.Fatalf("DialSSH: %v", err)
+	}
+	defer nested.Close()
+	if string(nested.ServerVersion()) != string(client.ServerVersion()) {
+		t.Errorf("got server version %q, want %q", nested.ServerVersion(), client.ServerVersion())
+	}
+	_, _, err = nested.OpenChannel("session", nil)
+	var openErr *OpenChannelError
+	if !errors.As(err, &openErr) || openErr.Message != "nested" {
+		t.Errorf("OpenChannel on the nested connection: got %v, want rejection by the nested server", err)
+	}
+}
+
+// listenJumpServers serves SSH on a local TCP listener, with each
+// connection accepting direct-tcpip channels to "ssh:22" on which it serves
+// SSH again. A value is sent on the returned channel whenever a connection
+// ends.
+func listenJumpServers(t *testing.T) (net.Listener, <-chan struct{}) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	closed := make(chan struct{}, 10)
+	serverConf := &ServerConfig{NoClientAuth: true}
+	serverConf.AddHostKey(testSigners["ecdsa"])
+	var serve func(conn net.Conn)
+	serve = func(conn net.Conn) {
+		defer func() { closed <- struct{}{} }()
+		sconn, chans, reqs, err := NewServerConn(conn, serverConf)
+		if err != nil {
+			return
+		}
+		defer sconn.Close()
+		go DiscardRequests(reqs)
+		for newCh := range chans {
+			var msg forwardedTCPPayload
+			if newCh.ChannelType() != "direct-tcpip" || Unmarshal(newCh.ExtraData(), &msg) != nil || msg.Addr != "ssh" {
+				newCh.Reject(Prohibited, "end")
+				continue
+			}
+			ch, reqs, err := newCh.Accept()
+			if err != nil {
+				return
+			}
+			go DiscardRequests(reqs)
+			go serve(NewChannelConn(ch, nil, nil))
+		}
+	}
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serve(c)
+		}
+	}()
+	return l, closed
+}
+
+func TestDialJump(t *testing.T) {
+	l, closed := listenJumpServers(t)
+
+	var verified []string
+	config := &ClientConfig{
+		User: "user",
+		HostKeyCallback: func(hostname string, remote net.Addr, key PublicKey) error {
+			verified = append(verified, hostname)
+			return nil
+		},
+	}
+	hosts := []JumpHost{
+		{Addr: l.Addr().String(), Config: config},
+		{Addr: "ssh:22", Config: config},
+		{Addr: "ssh:22", Config: config},
+	}
+	client, err := DialJump(context.Background(), "tcp", hosts...)
+	if err != nil {
+		t.Fatalf("DialJump: %v", err)
+	}
+	if len(verified) != 3 || verified[0] != l.Addr().String() || verified[2] != "ssh:22" {
+		t.Errorf("got verified hosts %q", verified)
+	}
+	_, _, err = client.OpenChannel("session", nil)
+	var openErr *OpenChannelError
+	if !errors.As(err, &openErr) || openErr.Message != "end" {
+		t.Errorf("OpenChannel on the last host: got %v, want rejection by the last server", err)
+	}
+
+	client.Close()
+	for i := range hosts {
+		select {
+		case <-closed:
+		case <-time.After(10 * time.Second):
+			t.Fatalf("only %d of %d connections closed", i, len(hosts))
+		}
+	}
+}
+
+func TestDialJumpError(t *testing.T) {
+	l, closed := listenJumpServers(t)
+
+	errRejected := errors.New("rejected")
+	_, err := DialJump(context.Background(), "tcp",
+		JumpHost{Addr: l.Addr().String(), Config: &ClientConfig{
+			User:            "user",
+			HostKeyCallback: InsecureIgnoreHostKey(),
+		}},
+		JumpHost{Addr: "ssh:22", Config: &ClientConfig{
+			User: "user",
+			HostKeyCallback: func(hostname string, remote net.Addr, key PublicKey) error {
+				return errRejected
+			},
+		}},
+	)
+	if !errors.Is(err, errRejected) {
+		t.Fatalf("DialJump: got %v, want %v", err, errRejected)
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-closed:
+		case <-time.After(10 * time.Second):
+			t.Fatalf("only %d of 2 connections closed", i)
+		}
+	}
+}
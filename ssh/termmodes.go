@@ -0,0 +1,138 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ttyOpMaxArg is the last opcode of the encoded terminal modes that takes a
+// uint32 argument. Opcodes 160 to 255 are not defined, and stop the parsing
+// of the modes. See RFC 4254, section 8.
+const ttyOpMaxArg = 159
+
+// ttyCharDisabled is the value of control characters that are disabled.
+const ttyCharDisabled = 255
+
+func isTerminalFlag(op uint8) bool {
+	return (op >= IGNPAR && op <= IUTF8) || (op >= ISIG && op <= PENDIN) ||
+		(op >= OPOST && op <= ONLRET) || (op >= CS7 && op <= PARODD)
+}
+
+func isTerminalChar(op uint8) bool {
+	return op >= VINTR && op <= VDISCARD
+}
+
+// SetFlag sets the boolean mode op, such as ECHO or ICRNL, and returns m.
+func (m TerminalModes) SetFlag(op uint8, on bool) TerminalModes {
+	m[op] = 0
+	if on {
+		m[op] = 1
+	}
+	return m
+}
+
+// SetChar sets the control character op, such as VINTR, to c and returns
+// m. A value of 255 disables the character.
+func (m TerminalModes) SetChar(op uint8, c byte) TerminalModes {
+	m[op] = uint32(c)
+	return m
+}
+
+// SetSpeed sets the input and output baud rates, and returns m.
+func (m TerminalModes) SetSpeed(in, out uint32) TerminalModes {
+	m[TTY_OP_ISPEED] = in
+	m[TTY_OP_OSPEED] = out
+	return m
+}
+
+// Echo sets the ECHO mode, which echoes input characters, and returns m.
+func (m TerminalModes) Echo(on bool) TerminalModes { return m.SetFlag(ECHO, on) }
+
+// ICanon sets the ICANON mode, which enables line editing, and returns m.
+func (m TerminalModes) ICanon(on bool) TerminalModes { return m.SetFlag(ICANON, on) }
+
+// ISig sets the ISIG mode, which enables the INTR, QUIT and SUSP characters,
+// and returns m.
+func (m TerminalModes) ISig(on bool) TerminalModes { return m.SetFlag(ISIG, on) }
+
+// ICRNL sets the ICRNL mode, which maps CR to NL on input, and returns m.
+func (m TerminalModes) ICRNL(on bool) TerminalModes { return m.SetFlag(ICRNL, on) }
+
+// ONLCR sets the ONLCR mode, which maps NL to CR-NL on output, and returns
+// m.
+func (m TerminalModes) ONLCR(on bool) TerminalModes { return m.SetFlag(ONLCR, on) }
+
+// OPost sets the OPOST mode, which enables output processing, and returns
+// m.
+func (m TerminalModes) OPost(on bool) TerminalModes { return m.SetFlag(OPOST, on) }
+
+// IUTF8 sets the IUTF8 mode, which tells the terminal that input is UTF-8
+// encoded, and returns m.
+func (m TerminalModes) IUTF8(on bool) TerminalModes { return m.SetFlag(IUTF8, on) }
+
+// Validate reports whether m can be sent in a pty-req request: opcodes must
+// be between 1 and 159, boolean modes must be 0 or 1, and control characters
+// must fit in a byte.
+func (m TerminalModes) Validate() error {
+	for _, op := range m.opcodes() {
+		v := m[op]
+		switch {
+		case op == tty_OP_END || op > ttyOpMaxArg:
+			return fmt.Errorf("ssh: invalid terminal mode opcode %d", op)
+		case isTerminalFlag(op) && v > 1:
+			return fmt.Errorf("ssh: terminal mode %d is a flag, but has value %d", op, v)
+		case isTerminalChar(op) && v > ttyCharDisabled:
+			return fmt.Errorf("ssh: terminal mode %d is a character, but has value %d", op, v)
+		}
+	}
+	return nil
+}
+
+// opcodes returns the opcodes of m in increasing order.
+func (m TerminalModes) opcodes() []uint8 {
+	ops := make([]uint8, 0, len(m))
+	for op := range m {
+		ops = append(ops, op)
+	}
+	sort.Slice(ops, func(i, j int) bool { return ops[i] < ops[j] })
+	return ops
+}
+
+// marshal returns the encoding of m, sorted by opcode.
+func (m TerminalModes) marshal() []byte {
+	var tm []byte
+	for _, op := range m.opcodes() {
+		kv := struct {
+			Key byte
+			Val uint32
+		}{op, m[op]}
+
+		tm = append(tm, Marshal(&kv)...)
+	}
+	return append(tm, tty_OP_END)
+}
+
+// ParseTerminalModes decodes the encoded terminal modes of a pty-req
+// request. As required by RFC 4254, parsing stops at an opcode between 160
+// and 255, whose argument is not known.
+func ParseTerminalModes(modelist []byte) (TerminalModes, error) {
+	m := make(TerminalModes)
+	for len(modelist) > 0 {
+		op := modelist[0]
+		if op == tty_OP_END || op > ttyOpMaxArg {
+			return m, nil
+		}
+		if len(modelist) < 5 {
+			return nil, errors.New("ssh: truncated terminal modes")
+		}
+		m[op] = binary.BigEndian.Uint32(modelist[1:5])
+		modelist = modelist[5:]
+	}
+	return m, nil
+}
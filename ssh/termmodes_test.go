@@ -0,0 +1,68 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTerminalModesRoundTrip(t *testing.T) {
+	modes := TerminalModes{}.Echo(false).ICRNL(true).IUTF8(true).SetChar(VINTR, 3).SetSpeed(38400, 38400)
+	if err := modes.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	want := TerminalModes{ECHO: 0, ICRNL: 1, IUTF8: 1, VINTR: 3, TTY_OP_ISPEED: 38400, TTY_OP_OSPEED: 38400}
+	if !reflect.DeepEqual(modes, want) {
+		t.Fatalf("got modes %v, want %v", modes, want)
+	}
+
+	req := &Request{Type: "pty-req", Payload: Marshal(&ptyRequestMsg{
+		Term:     "xterm",
+		Columns:  80,
+		Rows:     24,
+		Modelist: string(modes.marshal()),
+	})}
+	pty, err := ParsePtyRequest(req)
+	if err != nil {
+		t.Fatalf("ParsePtyRequest: %v", err)
+	}
+	if pty.Term != "xterm" || pty.Columns != 80 || pty.Rows != 24 || !reflect.DeepEqual(pty.Modes, want) {
+		t.Errorf("got %+v, want xterm 80x24 with modes %v", pty, want)
+	}
+}
+
+func TestTerminalModesValidate(t *testing.T) {
+	for _, m := range []TerminalModes{
+		{tty_OP_END: 0},
+		{160: 1},
+		{ECHO: 2},
+		{VINTR: 256},
+	} {
+		if err := m.Validate(); err == nil {
+			t.Errorf("Validate(%v) succeeded", m)
+		}
+	}
+}
+
+func TestParseTerminalModes(t *testing.T) {
+	for _, tt := range []struct {
+		modelist string
+		want     TerminalModes
+	}{
+		{"", TerminalModes{}},
+		{"\x35\x00\x00\x00\x01\x00\x24\x00\x00\x00\x01", TerminalModes{ECHO: 1}},
+		// Parsing stops at opcodes with an unknown argument.
+		{"\x35\x00\x00\x00\x01\xa0\x24\x00\x00\x00\x01", TerminalModes{ECHO: 1}},
+	} {
+		got, err := ParseTerminalModes([]byte(tt.modelist))
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseTerminalModes(%q) = %v, %v; want %v", tt.modelist, got, err, tt.want)
+		}
+	}
+	if _, err := ParseTerminalModes([]byte("\x35\x00\x00")); err == nil {
+		t.Error("ParseTerminalModes accepted truncated modes")
+	}
+}
@@ -98,3 +98,80 @@ func TestSSHCLIAuth(t *testing.T) {
 		t.Fatalf("user certificate authentication failed, error: %v, command output %q", err, string(out))
 	}
 }
+
+func TestSSHCLIKeyExchanges(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skipf("always fails on Windows, see #64403")
+	}
+	sshCLI := sshClient(t)
+	for _, kex := range []string{"sntrup761x25519-sha512@openssh.com", "sntrup761x25519-sha512"} {
+		t.Run(kex, func(t *testing.T) {
+			out, err := testenv.Command(t, sshCLI, "-Q", "kex").Output()
+			if err != nil {
+				t.Fatalf("ssh -Q kex: %v", err)
+			}
+			if !bytes.Contains(append([]byte("\n"), out...), []byte("\n"+kex+"\n")) {
+				t.Skipf("ssh(1) does not support %s", kex)
+			}
+
+			config := &ssh.ServerConfig{
+				Config:       ssh.Config{KeyExchanges: []string{kex}},
+				NoClientAuth: true,
+			}
+			config.AddHostKey(testSigners["ed25519"])
+			server, err := newTestServer(config)
+			if err != nil {
+				t.Fatalf("unable to start test server: %v", err)
+			}
+			defer server.Close()
+			port, err := server.port()
+			if err != nil {
+				t.Fatalf("unable to get server port: %v", err)
+			}
+
+			cmd := testenv.Command(t, sshCLI, "-vvv", "-F", "none", "-o", "BatchMode=yes",
+				"-o", "StrictHostKeyChecking=no", "-o", "UserKnownHostsFile=/dev/null",
+				"-o", "KexAlgorithms="+kex, "-p", port, "test@127.0.0.1", "true")
+			out, err = cmd.CombinedOutput()
+			if err != nil {
+				t.Fatalf("key exchange failed, error: %v, command output %q", err, string(out))
+			}
+			if !bytes.Contains(out, []byte("kex: algorithm: "+kex)) {
+				t.Errorf("ssh(1) did not use %s, command output %q", kex, string(out))
+			}
+		})
+	}
+}
+
+func TestSSHCLICiphers(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skipf("always fails on Windows, see #64403")
+	}
+	sshCLI := sshClient(t)
+	const cipher = "aes128-gcm@openssh.com"
+	config := &ssh.ServerConfig{
+		Config:       ssh.Config{Ciphers: []string{cipher}},
+		NoClientAuth: true,
+	}
+	config.AddHostKey(testSigners["ed25519"])
+	server, err := newTestServer(config)
+	if err != nil {
+		t.Fatalf("unable to start test server: %v", err)
+	}
+	defer server.Close()
+	port, err := server.port()
+	if err != nil {
+		t.Fatalf("unable to get server port: %v", err)
+	}
+
+	cmd := testenv.Command(t, sshCLI, "-vvv", "-F", "none", "-o", "BatchMode=yes",
+		"-o", "StrictHostKeyChecking=no", "-o", "UserKnownHostsFile=/dev/null",
+		"-c", cipher, "-p", port, "test@127.0.0.1", "true")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("connection failed, error: %v, command output %q", err, string(out))
+	}
+	if !bytes.Contains(out, []byte("cipher: "+cipher)) {
+		t.Errorf("ssh(1) did not use %s, command output %q", cipher, string(out))
+	}
+}
@@ -0,0 +1,418 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package tpm2 implements SSH signers for keys held by a TPM 2.0, so that
+// host and user keys can't be exported from the machine.
+//
+// Keys are referenced by the handle of a loaded or persistent object, such
+// as one made with tpm2_evictcontrol, and must be unrestricted signing keys.
+// RSA keys with the RSASSA or null scheme and ECDSA keys on the NIST curves
+// are supported. The TPM is reached through a Transport; on Linux, the
+// resource manager device /dev/tpmrm0 can be used with NewTransport.
+package tpm2
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sync"
+
+	"golang.org/x/crypto/cryptobyte"
+	"golang.org/x/crypto/ssh"
+)
+
+// TPM 2.0 constants, see TPM 2.0 Library Part 2: Structures.
+const (
+	tagNoSessions = 0x8001
+	tagSessions   = 0x8002
+	tagHashcheck  = 0x8024
+
+	ccSign          = 0x0000015d
+	ccReadPublic    = 0x00000173
+	ccGetCapability = 0x0000017a
+
+	rsPW   = 0x40000009
+	rhNull = 0x40000007
+
+	capAlgs = 0x00000000
+
+	algRSA    = 0x0001
+	algSHA1   = 0x0004
+	algSHA256 = 0x000b
+	algSHA384 = 0x000c
+	algSHA512 = 0x000d
+	algNull   = 0x0010
+	algRSASSA = 0x0014
+	algECDSA  = 0x0018
+	algECC    = 0x0023
+
+	eccNISTP256 = 0x0003
+	eccNISTP384 = 0x0004
+	eccNISTP521 = 0x0005
+
+	// Object attributes.
+	attrRestricted = 1 << 16
+	attrSign       = 1 << 18
+)
+
+var tpmHashes = map[crypto.Hash]uint16{
+	crypto.SHA1:   algSHA1,
+	crypto.SHA256: algSHA256,
+	crypto.SHA384: algSHA384,
+	crypto.SHA512: algSHA512,
+}
+
+// Error is a TPM response code other than TPM_RC_SUCCESS.
+type Error uint32
+
+func (e Error) Error() string {
+	return fmt.Sprintf("tpm2: TPM returned response code %#x", uint32(e))
+}
+
+// A Transport sends a TPM 2.0 command, in its wire encoding, and returns
+// the response.
+type Transport interface {
+	Send(command []byte) ([]byte, error)
+}
+
+// maxResponseSize is the size of the response buffer of TPMs.
+const maxResponseSize = 4096
+
+type rwTransport struct {
+	mu sync.Mutex
+	rw io.ReadWriter
+}
+
+// NewTransport returns a Transport for a TPM character device such as
+// /dev/tpmrm0, which reads back a whole response after each command.
+func NewTransport(rw io.ReadWriter) Transport {
+	return &rwTransport{rw: rw}
+}
+
+func (t *rwTransport) Send(command []byte) ([]byte, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, err := t.rw.Write(command); err != nil {
+		return nil, err
+	}
+	resp := make([]byte, maxResponseSize)
+	n, err := t.rw.Read(resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp[:n], nil
+}
+
+// run sends a command and returns the response parameters. The
+// authorization area, if any, holds the password session for auth.
+func run(t Transport, code uint32, handles []uint32, auth []byte, withAuth bool, params []byte) ([]byte, error) {
+	var b cryptobyte.Builder
+	tag := uint16(tagNoSessions)
+	if withAuth {
+		tag = tagSessions
+	}
+	b.AddUint16(tag)
+	b.AddUint32(0) // size, patched below
+	b.AddUint32(code)
+	for _, h := range handles {
+		b.AddUint32(h)
+	}
+	if withAuth {
+		b.AddUint32LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint32(rsPW)
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {}) // nonce
+			b.AddUint8(0)                                             // session attributes
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(auth) })
+		})
+	}
+	b.AddBytes(params)
+	cmd, err := b.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	binary.BigEndian.PutUint32(cmd[2:], uint32(len(cmd)))
+
+	resp, err := t.Send(cmd)
+	if err != nil {
+		return nil, err
+	}
+	s := cryptobyte.String(resp)
+	var respTag uint16
+	var size, rc uint32
+	if !s.ReadUint16(&respTag) || !s.ReadUint32(&size) || !s.ReadUint32(&rc) || int(size) != len(resp) {
+		return nil, errors.New("tpm2: malformed response")
+	}
+	if rc != 0 {
+		return nil, Error(rc)
+	}
+	if respTag == tagSessions {
+		// The parameters are followed by the authorization area.
+		var n uint32
+		var out []byte
+		if !s.ReadUint32(&n) || !s.ReadBytes(&out, int(n)) {
+			return nil, errors.New("tpm2: malformed response")
+		}
+		return out, nil
+	}
+	return s, nil
+}
+
+// readPublic returns the public area of handle.
+func readPublic(t Transport, handle uint32) (*publicArea, error) {
+	resp, err := run(t, ccReadPublic, []uint32{handle}, nil, false, nil)
+	if err != nil {
+		return nil, err
+	}
+	s := cryptobyte.String(resp)
+	var area cryptobyte.String
+	if !s.ReadUint16LengthPrefixed(&area) {
+		return nil, errors.New("tpm2: malformed TPM2_ReadPublic response")
+	}
+	return parsePublicArea(area)
+}
+
+// publicArea is the relevant part of a TPMT_PUBLIC.
+type publicArea struct {
+	attributes uint32
+	scheme     uint16
+	schemeHash uint16
+	pub        crypto.PublicKey
+}
+
+func readScheme(s *cryptobyte.String, scheme, hash *uint16) bool {
+	if !s.ReadUint16(scheme) {
+		return false
+	}
+	if *scheme == algNull {
+		return true
+	}
+	return s.ReadUint16(hash)
+}
+
+func parsePublicArea(s cryptobyte.String) (*publicArea, error) {
+	var p publicArea
+	var typ, nameAlg, symmetric uint16
+	var authPolicy cryptobyte.String
+	if !s.ReadUint16(&typ) || !s.ReadUint16(&nameAlg) || !s.ReadUint32(&p.attributes) ||
+		!s.ReadUint16LengthPrefixed(&authPolicy) || !s.ReadUint16(&symmetric) {
+		return nil, errors.New("tpm2: malformed public area")
+	}
+	if symmetric != algNull {
+		return nil, errors.New("tpm2: key is a storage key, not a signing key")
+	}
+
+	switch typ {
+	case algRSA:
+		var keyBits uint16
+		var exponent uint32
+		var modulus cryptobyte.String
+		if !readScheme(&s, &p.scheme, &p.schemeHash) || !s.ReadUint16(&keyBits) ||
+			!s.ReadUint32(&exponent) || !s.ReadUint16LengthPrefixed(&modulus) {
+			return nil, errors.New("tpm2: malformed RSA public area")
+		}
+		if p.scheme != algNull && p.scheme != algRSASSA {
+			return nil, fmt.Errorf("tpm2: unsupported RSA scheme %#x", p.scheme)
+		}
+		if exponent == 0 {
+			exponent = 65537
+		}
+		if exponent > 1<<31-1 {
+			return nil, errors.New("tpm2: unsupported RSA exponent")
+		}
+		p.pub = &rsa.PublicKey{N: new(big.Int).SetBytes(modulus), E: int(exponent)}
+	case algECC:
+		var curveID, kdf, kdfHash uint16
+		var x, y cryptobyte.String
+		if !readScheme(&s, &p.scheme, &p.schemeHash) || !s.ReadUint16(&curveID) ||
+			!readScheme(&s, &kdf, &kdfHash) || !s.ReadUint16LengthPrefixed(&x) || !s.ReadUint16LengthPrefixed(&y) {
+			return nil, errors.New("tpm2: malformed ECC public area")
+		}
+		if p.scheme != algNull && p.scheme != algECDSA {
+			return nil, fmt.Errorf("tpm2: unsupported ECC scheme %#x", p.scheme)
+		}
+		var curve elliptic.Curve
+		switch curveID {
+		case eccNISTP256:
+			curve = elliptic.P256()
+		case eccNISTP384:
+			curve = elliptic.P384()
+		case eccNISTP521:
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("tpm2: unsupported curve %#x", curveID)
+		}
+		pub := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, errors.New("tpm2: invalid curve point")
+		}
+		p.pub = pub
+	default:
+		return nil, fmt.Errorf("tpm2: unsupported key type %#x", typ)
+	}
+
+	if p.attributes&attrSign == 0 {
+		return nil, errors.New("tpm2: key is not a signing key")
+	}
+	if p.attributes&attrRestricted != 0 {
+		return nil, errors.New("tpm2: restricted keys can only sign data hashed by the TPM")
+	}
+	return &p, nil
+}
+
+// supportedHashes returns the hash algorithms that the TPM implements.
+func supportedHashes(t Transport) (map[uint16]bool, error) {
+	var params cryptobyte.Builder
+	params.AddUint32(capAlgs)
+	params.AddUint32(algSHA1) // first algorithm
+	params.AddUint32(64)      // count
+	resp, err := run(t, ccGetCapability, nil, nil, false, params.BytesOrPanic())
+	if err != nil {
+		return nil, err
+	}
+	s := cryptobyte.String(resp)
+	var moreData uint8
+	var capability, count uint32
+	if !s.ReadUint8(&moreData) || !s.ReadUint32(&capability) || !s.ReadUint32(&count) || capability != capAlgs {
+		return nil, errors.New("tpm2: malformed TPM2_GetCapability response")
+	}
+	algs := make(map[uint16]bool)
+	for i := uint32(0); i < count; i++ {
+		var alg uint16
+		var attrs uint32
+		if !s.ReadUint16(&alg) || !s.ReadUint32(&attrs) {
+			return nil, errors.New("tpm2: malformed TPM2_GetCapability response")
+		}
+		algs[alg] = true
+	}
+	return algs, nil
+}
+
+// A Key is a signing key held by a TPM. It implements crypto.Signer.
+type Key struct {
+	t      Transport
+	handle uint32
+	auth   []byte
+	area   *publicArea
+}
+
+// NewKey returns the key at handle, whose authorization value is auth,
+// reading its public part from the TPM.
+func NewKey(t Transport, handle uint32, auth []byte) (*Key, error) {
+	area, err := readPublic(t, handle)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{t: t, handle: handle, auth: auth, area: area}, nil
+}
+
+// Public returns the public key of k.
+func (k *Key) Public() crypto.PublicKey {
+	return k.area.pub
+}
+
+// Sign signs digest with the TPM, returning a PKCS #1 v1.5 signature for
+// RSA keys and an ASN.1 encoded signature for ECDSA keys.
+func (k *Key) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if _, ok := opts.(*rsa.PSSOptions); ok {
+		return nil, errors.New("tpm2: RSA-PSS signatures are not supported")
+	}
+	hash, ok := tpmHashes[opts.HashFunc()]
+	if !ok || len(digest) != opts.HashFunc().Size() {
+		return nil, fmt.Errorf("tpm2: unsupported hash function %v", opts.HashFunc())
+	}
+	scheme := uint16(algRSASSA)
+	if _, ok := k.area.pub.(*ecdsa.PublicKey); ok {
+		scheme = algECDSA
+	}
+	if k.area.scheme != algNull && k.area.schemeHash != hash {
+		return nil, fmt.Errorf("tpm2: key is bound to another hash function than %v", opts.HashFunc())
+	}
+
+	var params cryptobyte.Builder
+	params.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(digest) })
+	params.AddUint16(scheme)
+	params.AddUint16(hash)
+	// A null ticket, which unrestricted keys accept.
+	params.AddUint16(tagHashcheck)
+	params.AddUint32(rhNull)
+	params.AddUint16(0)
+	resp, err := run(k.t, ccSign, []uint32{k.handle}, k.auth, true, params.BytesOrPanic())
+	if err != nil {
+		return nil, err
+	}
+
+	s := cryptobyte.String(resp)
+	var sigAlg, sigHash uint16
+	if !s.ReadUint16(&sigAlg) || !s.ReadUint16(&sigHash) || sigAlg != scheme || sigHash != hash {
+		return nil, errors.New("tpm2: malformed TPM2_Sign response")
+	}
+	if scheme == algRSASSA {
+		var sig cryptobyte.String
+		if !s.ReadUint16LengthPrefixed(&sig) {
+			return nil, errors.New("tpm2: malformed TPM2_Sign response")
+		}
+		return sig, nil
+	}
+	var r, rs cryptobyte.String
+	if !s.ReadUint16LengthPrefixed(&r) || !s.ReadUint16LengthPrefixed(&rs) {
+		return nil, errors.New("tpm2: malformed TPM2_Sign response")
+	}
+	return asn1.Marshal(struct{ R, S *big.Int }{new(big.Int).SetBytes(r), new(big.Int).SetBytes(rs)})
+}
+
+// Signer returns an SSH signer for k. RSA keys offer the rsa-sha2-256,
+// rsa-sha2-512 and ssh-rsa algorithms that the key's scheme and the TPM's
+// hash functions allow.
+func (k *Key) Signer() (ssh.MultiAlgorithmSigner, error) {
+	s, err := ssh.NewSignerFromSigner(k)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := k.area.pub.(*rsa.PublicKey); !ok {
+		return s.(ssh.MultiAlgorithmSigner), nil
+	}
+
+	hashes, err := supportedHashes(k.t)
+	if err != nil {
+		return nil, err
+	}
+	var algorithms []string
+	for _, a := range []struct {
+		name string
+		hash uint16
+	}{
+		{ssh.KeyAlgoRSASHA256, algSHA256},
+		{ssh.KeyAlgoRSASHA512, algSHA512},
+		{ssh.KeyAlgoRSA, algSHA1},
+	} {
+		if hashes[a.hash] && (k.area.scheme == algNull || k.area.schemeHash == a.hash) {
+			algorithms = append(algorithms, a.name)
+		}
+	}
+	if len(algorithms) == 0 {
+		return nil, errors.New("tpm2: the key's hash function can't be used with SSH")
+	}
+	return ssh.NewSignerWithAlgorithms(s.(ssh.AlgorithmSigner), algorithms)
+}
+
+// CertSigner returns an SSH signer that presents cert, which must be a
+// certificate for k, such as a host certificate issued for a TPM host key.
+func (k *Key) CertSigner(cert *ssh.Certificate) (ssh.MultiAlgorithmSigner, error) {
+	s, err := k.Signer()
+	if err != nil {
+		return nil, err
+	}
+	cs, err := ssh.NewCertSigner(cert, s)
+	if err != nil {
+		return nil, err
+	}
+	return cs.(ssh.MultiAlgorithmSigner), nil
+}
@@ -0,0 +1,299 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tpm2
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"golang.org/x/crypto/cryptobyte"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/testdata"
+)
+
+type fakeObject struct {
+	key                crypto.Signer
+	auth               []byte
+	scheme, schemeHash uint16
+	attributes         uint32
+}
+
+// fakeTPM implements the commands used by this package.
+type fakeTPM struct {
+	t       *testing.T
+	objects map[uint32]fakeObject
+	hashes  []uint16
+}
+
+const rcAuthFail = 0x98e
+
+var algHashes = map[uint16]crypto.Hash{
+	algSHA1:   crypto.SHA1,
+	algSHA256: crypto.SHA256,
+	algSHA384: crypto.SHA384,
+	algSHA512: crypto.SHA512,
+}
+
+func response(tag uint16, rc uint32, params []byte) []byte {
+	var b cryptobyte.Builder
+	b.AddUint16(tag)
+	b.AddUint32(0)
+	b.AddUint32(rc)
+	if rc == 0 {
+		if tag == tagSessions {
+			b.AddUint32(uint32(len(params)))
+			b.AddBytes(params)
+			// The response authorization of the password session.
+			b.AddBytes([]byte{0, 0, 0, 0, 0})
+		} else {
+			b.AddBytes(params)
+		}
+	}
+	resp := b.BytesOrPanic()
+	binary.BigEndian.PutUint32(resp[2:], uint32(len(resp)))
+	return resp
+}
+
+func (f *fakeTPM) Send(cmd []byte) ([]byte, error) {
+	s := cryptobyte.String(cmd)
+	var tag uint16
+	var size, code uint32
+	if !s.ReadUint16(&tag) || !s.ReadUint32(&size) || !s.ReadUint32(&code) || int(size) != len(cmd) {
+		f.t.Fatalf("malformed command %x", cmd)
+	}
+	var b cryptobyte.Builder
+	switch code {
+	case ccGetCapability:
+		var capability, first, count uint32
+		if !s.ReadUint32(&capability) || !s.ReadUint32(&first) || !s.ReadUint32(&count) || capability != capAlgs {
+			f.t.Fatalf("malformed TPM2_GetCapability %x", cmd)
+		}
+		b.AddUint8(0)
+		b.AddUint32(capAlgs)
+		b.AddUint32(uint32(len(f.hashes)))
+		for _, h := range f.hashes {
+			b.AddUint16(h)
+			b.AddUint32(0)
+		}
+	case ccReadPublic:
+		var handle uint32
+		if !s.ReadUint32(&handle) {
+			f.t.Fatalf("malformed TPM2_ReadPublic %x", cmd)
+		}
+		obj, ok := f.objects[handle]
+		if !ok {
+			return response(tagNoSessions, 0x18b, nil), nil // TPM_RC_HANDLE
+		}
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { f.publicArea(b, obj) })
+		b.AddUint16(0) // name
+		b.AddUint16(0) // qualified name
+	case ccSign:
+		var handle, authSize, session uint32
+		var nonce, hmac, digest cryptobyte.String
+		var attrs uint8
+		var scheme, hash, ticketTag, ticketDigest uint16
+		var hierarchy uint32
+		if tag != tagSessions || !s.ReadUint32(&handle) || !s.ReadUint32(&authSize) || !s.ReadUint32(&session) ||
+			!s.ReadUint16LengthPrefixed(&nonce) || !s.ReadUint8(&attrs) || !s.ReadUint16LengthPrefixed(&hmac) ||
+			!s.ReadUint16LengthPrefixed(&digest) || !s.ReadUint16(&scheme) || !s.ReadUint16(&hash) ||
+			!s.ReadUint16(&ticketTag) || !s.ReadUint32(&hierarchy) || !s.ReadUint16(&ticketDigest) || !s.Empty() {
+			f.t.Fatalf("malformed TPM2_Sign %x", cmd)
+		}
+		if session != rsPW || ticketTag != tagHashcheck || hierarchy != rhNull {
+			f.t.Fatalf("unexpected TPM2_Sign %x", cmd)
+		}
+		obj := f.objects[handle]
+		if !bytes.Equal(hmac, obj.auth) {
+			return response(tagSessions, rcAuthFail, nil), nil
+		}
+		if obj.scheme != algNull && (scheme != obj.scheme || hash != obj.schemeHash) {
+			return response(tagSessions, 0x2d2, nil), nil // TPM_RC_SCHEME
+		}
+		b.AddUint16(scheme)
+		b.AddUint16(hash)
+		switch k := obj.key.(type) {
+		case *rsa.PrivateKey:
+			sig, err := rsa.SignPKCS1v15(rand.Reader, k, algHashes[hash], digest)
+			if err != nil {
+				f.t.Fatal(err)
+			}
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(sig) })
+		case *ecdsa.PrivateKey:
+			r, s, err := ecdsa.Sign(rand.Reader, k, digest)
+			if err != nil {
+				f.t.Fatal(err)
+			}
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(r.Bytes()) })
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(s.Bytes()) })
+		}
+	default:
+		f.t.Fatalf("unexpected command %#x", code)
+	}
+	return response(tag, 0, b.BytesOrPanic()), nil
+}
+
+func (f *fakeTPM) publicArea(b *cryptobyte.Builder, obj fakeObject) {
+	addScheme := func() {
+		b.AddUint16(obj.scheme)
+		if obj.scheme != algNull {
+			b.AddUint16(obj.schemeHash)
+		}
+	}
+	switch k := obj.key.(type) {
+	case *rsa.PrivateKey:
+		b.AddUint16(algRSA)
+		b.AddUint16(algSHA256)
+		b.AddUint32(obj.attributes)
+		b.AddUint16(0) // auth policy
+		b.AddUint16(algNull)
+		addScheme()
+		b.AddUint16(uint16(k.N.BitLen()))
+		b.AddUint32(0) // default exponent
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(k.N.Bytes()) })
+	case *ecdsa.PrivateKey:
+		b.AddUint16(algECC)
+		b.AddUint16(algSHA256)
+		b.AddUint32(obj.attributes)
+		b.AddUint16(0)
+		b.AddUint16(algNull)
+		addScheme()
+		b.AddUint16(eccNISTP256)
+		b.AddUint16(algNull) // kdf
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(k.X.Bytes()) })
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(k.Y.Bytes()) })
+	}
+}
+
+func newFakeTPM(t *testing.T) *fakeTPM {
+	rsaKey, err := ssh.ParseRawPrivateKey(testdata.PEMBytes["rsa"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &fakeTPM{
+		t:      t,
+		hashes: []uint16{algSHA1, algSHA256, algSHA384},
+		objects: map[uint32]fakeObject{
+			0x81000001: {key: rsaKey.(*rsa.PrivateKey), scheme: algNull, attributes: attrSign},
+			0x81000002: {key: ecKey, auth: []byte("secret"), scheme: algECDSA, schemeHash: algSHA256, attributes: attrSign},
+			0x81000003: {key: rsaKey.(*rsa.PrivateKey), scheme: algRSASSA, schemeHash: algSHA256, attributes: attrSign},
+			0x81000004: {key: ecKey, scheme: algNull, attributes: attrSign | attrRestricted},
+			0x81000005: {key: ecKey, scheme: algNull},
+		},
+	}
+}
+
+func TestSigner(t *testing.T) {
+	tpm := newFakeTPM(t)
+	for _, tt := range []struct {
+		handle     uint32
+		auth       string
+		algorithms []string
+	}{
+		// The fake TPM doesn't implement SHA-512.
+		{0x81000001, "", []string{ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA}},
+		{0x81000002, "secret", []string{ssh.KeyAlgoECDSA256}},
+		{0x81000003, "", []string{ssh.KeyAlgoRSASHA256}},
+	} {
+		key, err := NewKey(tpm, tt.handle, []byte(tt.auth))
+		if err != nil {
+			t.Fatalf("NewKey(%#x): %v", tt.handle, err)
+		}
+		signer, err := key.Signer()
+		if err != nil {
+			t.Fatalf("%#x: Signer: %v", tt.handle, err)
+		}
+		if got := signer.Algorithms(); !reflect.DeepEqual(got, tt.algorithms) {
+			t.Errorf("%#x: got algorithms %q, want %q", tt.handle, got, tt.algorithms)
+		}
+		data := []byte("session data")
+		for _, algo := range tt.algorithms {
+			sig, err := signer.SignWithAlgorithm(rand.Reader, data, algo)
+			if err != nil {
+				t.Errorf("%#x: SignWithAlgorithm(%s): %v", tt.handle, algo, err)
+				continue
+			}
+			if err := signer.PublicKey().Verify(data, sig); err != nil {
+				t.Errorf("%#x: Verify(%s): %v", tt.handle, algo, err)
+			}
+		}
+	}
+
+	key, err := NewKey(tpm, 0x81000002, []byte("wrong"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := key.Signer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := signer.Sign(rand.Reader, []byte("data")); err != Error(rcAuthFail) {
+		t.Errorf("signing with a wrong authorization value: got %v, want %v", err, Error(rcAuthFail))
+	}
+
+	for _, handle := range []uint32{0x81000004, 0x81000005, 0x81000006} {
+		if _, err := NewKey(tpm, handle, nil); err == nil {
+			t.Errorf("NewKey(%#x) succeeded", handle)
+		}
+	}
+}
+
+func TestCertSigner(t *testing.T) {
+	key, err := NewKey(newFakeTPM(t), 0x81000001, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := ssh.NewPublicKey(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := ssh.ParsePrivateKey(testdata.PEMBytes["ed25519"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := &ssh.Certificate{
+		Key:             pub,
+		CertType:        ssh.HostCert,
+		ValidPrincipals: []string{"host.example.com"},
+		ValidBefore:     ssh.CertTimeInfinity,
+	}
+	if err := cert.SignCert(rand.Reader, ca); err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := key.CertSigner(cert)
+	if err != nil {
+		t.Fatalf("CertSigner: %v", err)
+	}
+	if !bytes.Equal(signer.PublicKey().Marshal(), cert.Marshal()) {
+		t.Error("CertSigner does not present the certificate")
+	}
+	sig, err := signer.SignWithAlgorithm(rand.Reader, []byte("data"), ssh.KeyAlgoRSASHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := signer.PublicKey().Verify([]byte("data"), sig); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+
+	other, err := ssh.ParsePrivateKey(testdata.PEMBytes["ecdsa"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert.Key = other.PublicKey()
+	if _, err := key.CertSigner(cert); err == nil {
+		t.Error("CertSigner accepted a certificate for another key")
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+// PacketDirection is the direction of a packet passed to a PacketTracer.
+type PacketDirection int
+
+const (
+	// PacketSent marks a packet sent to the peer.
+	PacketSent PacketDirection = iota
+
+	// PacketReceived marks a packet received from the peer.
+	PacketReceived
+)
+
+func (d PacketDirection) String() string {
+	if d == PacketSent {
+		return "sent"
+	}
+	return "received"
+}
+
+// PacketTracer is called for every packet of a connection, before it is
+// encrypted when sending and after it is decrypted when receiving.
+// payload holds the contents of the packet after the message type byte.
+// It is only valid during the call and must not be modified.
+//
+// PacketTracer may be called concurrently from the goroutines reading and
+// writing the connection.
+type PacketTracer func(dir PacketDirection, msgType byte, payload []byte)
+
+// isSensitiveMsg reports whether packets of the given type may carry
+// passwords or user data, and should not be traced by default.
+func isSensitiveMsg(msgType byte) bool {
+	switch msgType {
+	case msgUserAuthRequest, msgUserAuthInfoResponse, msgChannelData, msgChannelExtendedData:
+		return true
+	}
+	return false
+}
+
+// tracingTransport calls a PacketTracer for every packet passing through
+// the underlying keyingTransport.
+type tracingTransport struct {
+	keyingTransport
+	tracer    PacketTracer
+	sensitive bool
+}
+
+func newTracingTransport(conn keyingTransport, config *Config) keyingTransport {
+	if config.PacketTracer == nil {
+		return conn
+	}
+	return &tracingTransport{
+		keyingTransport: conn,
+		tracer:          config.PacketTracer,
+		sensitive:       config.TraceSensitivePayloads,
+	}
+}
+
+func (t *tracingTransport) trace(dir PacketDirection, packet []byte) {
+	if len(packet) == 0 {
+		return
+	}
+	payload := packet[1:]
+	if !t.sensitive && isSensitiveMsg(packet[0]) {
+		payload = nil
+	}
+	t.tracer(dir, packet[0], payload)
+}
+
+func (t *tracingTransport) writePacket(packet []byte) error {
+	// writePacket destroys the contents, so trace first.
+	t.trace(PacketSent, packet)
+	return t.keyingTransport.writePacket(packet)
+}
+
+func (t *tracingTransport) readPacket() ([]byte, error) {
+	packet, err := t.keyingTransport.readPacket()
+	if err == nil {
+		t.trace(PacketReceived, packet)
+	}
+	return packet, err
+}
@@ -0,0 +1,96 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+)
+
+type tracedPacket struct {
+	dir     PacketDirection
+	msgType byte
+	payload []byte
+}
+
+func tracePasswordAuth(t *testing.T, sensitive bool) []tracedPacket {
+	c1, c2, err := netPipe()
+	if err != nil {
+		t.Fatalf("netPipe: %v", err)
+	}
+	defer c1.Close()
+	defer c2.Close()
+
+	serverConf := &ServerConfig{
+		PasswordCallback: func(conn ConnMetadata, password []byte) (*Permissions, error) {
+			return nil, nil
+		},
+	}
+	serverConf.AddHostKey(testSigners["ecdsa"])
+	go func() {
+		conn, _, _, err := NewServerConn(c2, serverConf)
+		if err == nil {
+			conn.Close()
+		}
+	}()
+
+	var mu sync.Mutex
+	var packets []tracedPacket
+	clientConf := &ClientConfig{
+		Config: Config{
+			PacketTracer: func(dir PacketDirection, msgType byte, payload []byte) {
+				mu.Lock()
+				defer mu.Unlock()
+				packets = append(packets, tracedPacket{dir, msgType, append([]byte(nil), payload...)})
+			},
+			TraceSensitivePayloads: sensitive,
+		},
+		User:            "user",
+		Auth:            []AuthMethod{Password("secret-password")},
+		HostKeyCallback: InsecureIgnoreHostKey(),
+	}
+	conn, _, _, err := NewClientConn(c1, "", clientConf)
+	if err != nil {
+		t.Fatalf("NewClientConn: %v", err)
+	}
+	conn.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	return packets
+}
+
+func TestPacketTracer(t *testing.T) {
+	for _, sensitive := range []bool{false, true} {
+		packets := tracePasswordAuth(t, sensitive)
+
+		seen := make(map[PacketDirection]map[byte]bool)
+		leaked := false
+		for _, p := range packets {
+			if seen[p.dir] == nil {
+				seen[p.dir] = make(map[byte]bool)
+			}
+			seen[p.dir][p.msgType] = true
+
+			if p.msgType == msgUserAuthRequest && bytes.Contains(p.payload, []byte("secret-password")) {
+				leaked = true
+			}
+		}
+		if leaked != sensitive {
+			t.Errorf("sensitive %t: password in traced userauth requests: %t", sensitive, leaked)
+		}
+		for _, dir := range []PacketDirection{PacketSent, PacketReceived} {
+			for _, msgType := range []byte{msgKexInit, msgNewKeys} {
+				if !seen[dir][msgType] {
+					t.Errorf("sensitive %t: no %s packet of type %d traced", sensitive, dir, msgType)
+				}
+			}
+		}
+		if !seen[PacketSent][msgUserAuthRequest] || !seen[PacketReceived][msgUserAuthSuccess] {
+			t.Errorf("sensitive %t: authentication packets not traced", sensitive)
+		}
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"errors"
 	"io"
 	"log"
+	"sync/atomic"
 )
 
 // debugTransport if set, will print packet types as they go over the
@@ -52,6 +53,14 @@ type transport struct {
 
 	strictMode     bool
 	initialKEXDone bool
+
+	// userAuthDone is set once user authentication has succeeded, which
+	// enables delayed compression.
+	userAuthDone atomic.Bool
+
+	// debugCallback, if not nil, receives the SSH_MSG_DEBUG messages that
+	// are skipped.
+	debugCallback DebugCallback
 }
 
 // packetCipher represents a combination of SSH encryption/MAC
@@ -74,7 +83,32 @@ type connectionState struct {
 	packetCipher
 	seqNum           uint32
 	dir              direction
-	pendingKeyChange chan packetCipher
+	pendingKeyChange chan keyChange
+
+	// compression is the compression algorithm negotiated for this
+	// direction. The compressor or decompressor is created once
+	// compression becomes active, and discarded at each key change.
+	compression  string
+	compressor   *zlibCompressor
+	decompressor *zlibDecompressor
+}
+
+// keyChange holds the algorithms for one direction that take effect with
+// the next msgNewKeys.
+type keyChange struct {
+	cipher      packetCipher
+	compression string
+}
+
+// setKeys switches to the algorithms negotiated in the last key exchange.
+func (s *connectionState) setKeys(k keyChange, strictMode bool) {
+	s.packetCipher = k.cipher
+	s.compression = k.compression
+	s.compressor = nil
+	s.decompressor = nil
+	if strictMode {
+		s.seqNum = 0
+	}
 }
 
 func (t *transport) setStrictMode() error {
@@ -97,13 +131,13 @@ func (t *transport) prepareKeyChange(algs *algorithms, kexResult *kexResult) err
 	if err != nil {
 		return err
 	}
-	t.reader.pendingKeyChange <- ciph
+	t.reader.pendingKeyChange <- keyChange{ciph, algs.r.Compression}
 
 	ciph, err = newPacketCipher(t.writer.dir, algs.w, kexResult)
 	if err != nil {
 		return err
 	}
-	t.writer.pendingKeyChange <- ciph
+	t.writer.pendingKeyChange <- keyChange{ciph, algs.w.Compression}
 
 	return nil
 }
@@ -127,14 +161,24 @@ func (t *transport) printPacket(p []byte, write bool) {
 // Read and decrypt next packet.
 func (t *transport) readPacket() (p []byte, err error) {
 	for {
-		p, err = t.reader.readPacket(t.bufReader, t.strictMode)
+		p, err = t.reader.readPacket(t.bufReader, t.strictMode, &t.userAuthDone)
 		if err != nil {
 			break
 		}
+		if t.isClient && len(p) > 0 && p[0] == msgUserAuthSuccess {
+			// The server compresses everything after this message.
+			t.userAuthDone.Store(true)
+		}
 		// in strict mode we pass through DEBUG and IGNORE packets only during the initial KEX
 		if len(p) == 0 || (t.strictMode && !t.initialKEXDone) || (p[0] != msgIgnore && p[0] != msgDebug) {
 			break
 		}
+		if p[0] == msgDebug && t.debugCallback != nil {
+			var msg debugMsg
+			if err := Unmarshal(p, &msg); err == nil {
+				t.debugCallback(msg.AlwaysDisplay, msg.Message, msg.Language)
+			}
+		}
 	}
 	if debugTransport {
 		t.printPacket(p, false)
@@ -143,9 +187,17 @@ func (t *transport) readPacket() (p []byte, err error) {
 	return p, err
 }
 
-func (s *connectionState) readPacket(r *bufio.Reader, strictMode bool) ([]byte, error) {
+func (s *connectionState) readPacket(r *bufio.Reader, strictMode bool, authDone *atomic.Bool) ([]byte, error) {
 	packet, err := s.packetCipher.readCipherPacket(s.seqNum, r)
 	s.seqNum++
+	// authDone must be checked after the read, as it may be set while we
+	// are waiting for the packet.
+	if err == nil && s.compressionActive(authDone.Load()) {
+		if s.decompressor == nil {
+			s.decompressor = newZlibDecompressor()
+		}
+		packet, err = s.decompressor.decompress(packet)
+	}
 	if err == nil && len(packet) == 0 {
 		err = errors.New("ssh: zero length packet")
 	}
@@ -154,11 +206,8 @@ func (s *connectionState) readPacket(r *bufio.Reader, strictMode bool) ([]byte,
 		switch packet[0] {
 		case msgNewKeys:
 			select {
-			case cipher := <-s.pendingKeyChange:
-				s.packetCipher = cipher
-				if strictMode {
-					s.seqNum = 0
-				}
+			case k := <-s.pendingKeyChange:
+				s.setKeys(k, strictMode)
 			default:
 				return nil, errors.New("ssh: got bogus newkeys message")
 			}
@@ -189,12 +238,28 @@ func (t *transport) writePacket(packet []byte) error {
 	if debugTransport {
 		t.printPacket(packet, true)
 	}
-	return t.writer.writePacket(t.bufWriter, t.rand, packet, t.strictMode)
+	authDone := t.userAuthDone.Load()
+	if !t.isClient && len(packet) > 0 && packet[0] == msgUserAuthSuccess {
+		// The client compresses everything after it receives this
+		// message, which itself is sent uncompressed.
+		t.userAuthDone.Store(true)
+	}
+	return t.writer.writePacket(t.bufWriter, t.rand, packet, t.strictMode, authDone)
 }
 
-func (s *connectionState) writePacket(w *bufio.Writer, rand io.Reader, packet []byte, strictMode bool) error {
+func (s *connectionState) writePacket(w *bufio.Writer, rand io.Reader, packet []byte, strictMode, authDone bool) error {
 	changeKeys := len(packet) > 0 && packet[0] == msgNewKeys
 
+	if s.compressionActive(authDone) {
+		if s.compressor == nil {
+			s.compressor = newZlibCompressor()
+		}
+		var err error
+		if packet, err = s.compressor.compress(packet); err != nil {
+			return err
+		}
+	}
+
 	err := s.packetCipher.writeCipherPacket(s.seqNum, w, rand, packet)
 	if err != nil {
 		return err
@@ -205,11 +270,8 @@ func (s *connectionState) writePacket(w *bufio.Writer, rand io.Reader, packet []
 	s.seqNum++
 	if changeKeys {
 		select {
-		case cipher := <-s.pendingKeyChange:
-			s.packetCipher = cipher
-			if strictMode {
-				s.seqNum = 0
-			}
+		case k := <-s.pendingKeyChange:
+			s.setKeys(k, strictMode)
 		default:
 			panic("ssh: no key material for msgNewKeys")
 		}
@@ -224,11 +286,11 @@ func newTransport(rwc io.ReadWriteCloser, rand io.Reader, isClient bool) *transp
 		rand:      rand,
 		reader: connectionState{
 			packetCipher:     &streamPacketCipher{cipher: noneCipher{}},
-			pendingKeyChange: make(chan packetCipher, 1),
+			pendingKeyChange: make(chan keyChange, 1),
 		},
 		writer: connectionState{
 			packetCipher:     &streamPacketCipher{cipher: noneCipher{}},
-			pendingKeyChange: make(chan packetCipher, 1),
+			pendingKeyChange: make(chan keyChange, 1),
 		},
 		Closer: rwc,
 	}
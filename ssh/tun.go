@@ -0,0 +1,176 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// tunChannelType is the channel type of OpenSSH's layer 2 and 3 tunnels,
+// see [PROTOCOL], section 2.3.
+const tunChannelType = "tun@openssh.com"
+
+// TunMode is the kind of traffic forwarded by a tun@openssh.com tunnel.
+type TunMode uint32
+
+const (
+	// TunPointToPoint tunnels forward IPv4 and IPv6 packets, for TUN
+	// devices.
+	TunPointToPoint TunMode = 1
+
+	// TunEthernet tunnels forward Ethernet frames, for TAP devices.
+	TunEthernet TunMode = 2
+)
+
+func (m TunMode) String() string {
+	switch m {
+	case TunPointToPoint:
+		return "point-to-point"
+	case TunEthernet:
+		return "ethernet"
+	}
+	return fmt.Sprintf("unknown tunnel mode %d", uint32(m))
+}
+
+// TunUnitAny asks the server to choose the tunnel device to use.
+const TunUnitAny uint32 = 0x7fffffff
+
+// Address families prefixed to the packets of point-to-point tunnels. These
+// are the OpenBSD values, regardless of the platform.
+const (
+	tunAFInet  = 2
+	tunAFInet6 = 24
+)
+
+// tunOpenMsg is the type specific data of a tun@openssh.com channel open.
+type tunOpenMsg struct {
+	Mode uint32
+	Unit uint32
+}
+
+// TunConn is a tun@openssh.com tunnel, as set up by the -w option of
+// OpenSSH. Each Read returns a single packet, and each Write sends one, so a
+// TunConn can be copied to and from a TUN or TAP device opened without
+// packet information headers. In point-to-point mode, packets are plain
+// IPv4 or IPv6 packets; the address family header used on the wire is added
+// and removed by TunConn.
+type TunConn struct {
+	ch *channel
+
+	// Mode and Unit are the tunnel mode and the remote device number
+	// requested by the client.
+	Mode TunMode
+	Unit uint32
+}
+
+func newTunConn(ch Channel, mode TunMode, unit uint32) (*TunConn, error) {
+	c, ok := ch.(*channel)
+	if !ok {
+		ch.Close()
+		return nil, errors.New("ssh: tunnels are not supported by this connection")
+	}
+	return &TunConn{ch: c, Mode: mode, Unit: unit}, nil
+}
+
+// DialTun opens a tunnel of the given mode to the server's tunnel device
+// unit, or to a device of the server's choosing if unit is TunUnitAny.
+func (c *Client) DialTun(mode TunMode, unit uint32) (*TunConn, error) {
+	ch, in, err := c.OpenChannel(tunChannelType, Marshal(&tunOpenMsg{uint32(mode), unit}))
+	if err != nil {
+		return nil, err
+	}
+	go DiscardRequests(in)
+	return newTunConn(ch, mode, unit)
+}
+
+// ParseTun returns the tunnel mode and remote device number requested by a
+// tun@openssh.com channel open, so that servers can decide whether to
+// accept it.
+func ParseTun(newCh NewChannel) (TunMode, uint32, error) {
+	if t := newCh.ChannelType(); t != tunChannelType {
+		return 0, 0, fmt.Errorf("ssh: channel type %q is not %q", t, tunChannelType)
+	}
+	var msg tunOpenMsg
+	if err := Unmarshal(newCh.ExtraData(), &msg); err != nil {
+		return 0, 0, err
+	}
+	return TunMode(msg.Mode), msg.Unit, nil
+}
+
+// AcceptTun accepts a tun@openssh.com channel open. It rejects the channel
+// and returns an error if the request is malformed or asks for an unknown
+// mode.
+func AcceptTun(newCh NewChannel) (*TunConn, error) {
+	mode, unit, err := ParseTun(newCh)
+	if err == nil && mode != TunPointToPoint && mode != TunEthernet {
+		err = fmt.Errorf("ssh: unsupported %v", mode)
+	}
+	if err != nil {
+		newCh.Reject(ConnectionFailed, err.Error())
+		return nil, err
+	}
+	ch, in, err := newCh.Accept()
+	if err != nil {
+		return nil, err
+	}
+	go DiscardRequests(in)
+	return newTunConn(ch, mode, unit)
+}
+
+// Read reads the next packet into p. If p is too small for it, the packet
+// is discarded and io.ErrShortBuffer is returned.
+func (t *TunConn) Read(p []byte) (int, error) {
+	for {
+		data, err := t.ch.readDatagram()
+		if err != nil {
+			return 0, err
+		}
+		if t.Mode == TunPointToPoint {
+			if len(data) < 4 {
+				// OpenSSH drops such packets too.
+				continue
+			}
+			data = data[4:]
+		}
+		if len(p) < len(data) {
+			return 0, io.ErrShortBuffer
+		}
+		return copy(p, data), nil
+	}
+}
+
+// Write sends p as a single packet. In point-to-point mode, p must be an
+// IPv4 or IPv6 packet.
+func (t *TunConn) Write(p []byte) (int, error) {
+	data := p
+	if t.Mode == TunPointToPoint {
+		if len(p) == 0 {
+			return 0, errors.New("ssh: empty packet")
+		}
+		var af uint32
+		switch p[0] >> 4 {
+		case 4:
+			af = tunAFInet
+		case 6:
+			af = tunAFInet6
+		default:
+			return 0, fmt.Errorf("ssh: packet has unknown IP version %d", p[0]>>4)
+		}
+		data = binary.BigEndian.AppendUint32(make([]byte, 0, 4+len(p)), af)
+		data = append(data, p...)
+	}
+	if err := t.ch.writeDatagram(data); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close closes the tunnel.
+func (t *TunConn) Close() error {
+	return t.ch.Close()
+}
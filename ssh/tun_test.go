@@ -0,0 +1,130 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+)
+
+func TestTunConn(t *testing.T) {
+	c1, c2, err := netPipe()
+	if err != nil {
+		t.Fatalf("netPipe: %v", err)
+	}
+	defer c1.Close()
+	defer c2.Close()
+
+	serverConf := &ServerConfig{NoClientAuth: true}
+	serverConf.AddHostKey(testSigners["ecdsa"])
+	go func() {
+		conn, chans, reqs, err := NewServerConn(c2, serverConf)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		go DiscardRequests(reqs)
+		for newCh := range chans {
+			if mode, unit, err := ParseTun(newCh); err != nil || mode != TunPointToPoint || unit != 3 {
+				t.Errorf("ParseTun: got %v, %d, %v; want %v, 3", mode, unit, err, TunPointToPoint)
+			}
+			tun, err := AcceptTun(newCh)
+			if err != nil {
+				t.Errorf("AcceptTun: %v", err)
+				return
+			}
+			buf := make([]byte, 1500)
+			for {
+				n, err := tun.Read(buf)
+				if err != nil {
+					return
+				}
+				tun.Write(buf[:n])
+			}
+		}
+	}()
+
+	conn, chans, reqs, err := NewClientConn(c1, "", &ClientConfig{
+		User:            "user",
+		HostKeyCallback: InsecureIgnoreHostKey(),
+	})
+	if err != nil {
+		t.Fatalf("NewClientConn: %v", err)
+	}
+	client := NewClient(conn, chans, reqs)
+	defer client.Close()
+
+	tun, err := client.DialTun(TunPointToPoint, 3)
+	if err != nil {
+		t.Fatalf("DialTun: %v", err)
+	}
+	defer tun.Close()
+
+	// Packet boundaries must be preserved, even for packets sent back to
+	// back.
+	packets := [][]byte{
+		append([]byte{0x45}, bytes.Repeat([]byte{1}, 59)...),
+		append([]byte{0x60}, bytes.Repeat([]byte{2}, 1279)...),
+		{0x45, 3},
+	}
+	for _, p := range packets {
+		if _, err := tun.Write(p); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	buf := make([]byte, 1500)
+	for i, want := range packets {
+		n, err := tun.Read(buf)
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+		if !bytes.Equal(buf[:n], want) {
+			t.Errorf("packet %d: got %d bytes, want %d", i, n, len(want))
+		}
+	}
+
+	if _, err := tun.Write([]byte{0x10}); err == nil {
+		t.Error("Write of a packet with an unknown IP version succeeded")
+	}
+}
+
+func TestTunConnWireFormat(t *testing.T) {
+	a, b, mux := channelPair(t)
+	defer mux.Close()
+	defer a.Close()
+	defer b.Close()
+
+	tun := &TunConn{ch: a, Mode: TunPointToPoint}
+	packet := []byte{0x60, 1, 2, 3}
+	if _, err := tun.Write(packet); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	data, err := b.readDatagram()
+	if err != nil {
+		t.Fatalf("readDatagram: %v", err)
+	}
+	if len(data) != 4+len(packet) || binary.BigEndian.Uint32(data) != tunAFInet6 || !bytes.Equal(data[4:], packet) {
+		t.Errorf("got datagram %x, want address family %d followed by %x", data, tunAFInet6, packet)
+	}
+
+	// Packets that do not fit are dropped.
+	if err := b.writeDatagram(append(binary.BigEndian.AppendUint32(nil, tunAFInet), make([]byte, 100)...)); err != nil {
+		t.Fatalf("writeDatagram: %v", err)
+	}
+	if _, err := tun.Read(make([]byte, 10)); err != io.ErrShortBuffer {
+		t.Errorf("Read into short buffer: got %v, want io.ErrShortBuffer", err)
+	}
+
+	ethernet := &TunConn{ch: b, Mode: TunEthernet}
+	frame := []byte("not an IP packet")
+	if _, err := ethernet.Write(frame); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if data, err := a.readDatagram(); err != nil || !bytes.Equal(data, frame) {
+		t.Errorf("readDatagram: got %q, %v; want %q", data, err, frame)
+	}
+}
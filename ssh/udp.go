@@ -0,0 +1,184 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+)
+
+// udpTunnelChannelType is the channel type of UDP tunnels. The type
+// specific data is the same as for direct-tcpip channels, and the channel
+// data is a sequence of datagrams, each prefixed with its length as a
+// uint32.
+const udpTunnelChannelType = "direct-udp@golang.org"
+
+// maxUDPDatagram is the largest datagram a UDP tunnel carries.
+const maxUDPDatagram = 65535
+
+// DialUDPTunnel asks the server to forward UDP datagrams to and from addr,
+// a host and port resolved by the server. The server handles the request
+// with HandleUDPTunnel. Each Write on the returned connection sends a
+// single datagram, and each Read returns one, truncated to the size of the
+// buffer like for UDP sockets.
+func (c *Client) DialUDPTunnel(addr string) (net.Conn, error) {
+	host, portString, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.ParseUint(portString, 10, 16)
+	if err != nil {
+		return nil, err
+	}
+	msg := channelOpenDirectMsg{
+		raddr: host,
+		rport: uint32(port),
+		laddr: net.IPv4zero.String(),
+		lport: 0,
+	}
+	ch, in, err := c.OpenChannel(udpTunnelChannelType, Marshal(&msg))
+	if err != nil {
+		return nil, err
+	}
+	go DiscardRequests(in)
+
+	raddr := &net.UDPAddr{IP: net.IPv4zero, Port: int(port)}
+	if ip := net.ParseIP(host); ip != nil {
+		raddr.IP = ip
+	}
+	return &udpTunnelConn{
+		chanConn: &chanConn{
+			Channel: ch,
+			laddr:   &net.UDPAddr{IP: net.IPv4zero},
+			raddr:   raddr,
+		},
+	}, nil
+}
+
+// ParseUDPTunnel returns the host and port that a UDP tunnel channel open
+// asks to forward datagrams to, so that servers can decide whether to
+// accept it.
+func ParseUDPTunnel(newCh NewChannel) (string, error) {
+	if t := newCh.ChannelType(); t != udpTunnelChannelType {
+		return "", fmt.Errorf("ssh: channel type %q is not %q", t, udpTunnelChannelType)
+	}
+	var msg forwardedTCPPayload
+	if err := Unmarshal(newCh.ExtraData(), &msg); err != nil {
+		return "", err
+	}
+	if msg.Port == 0 || msg.Port > 65535 {
+		return "", fmt.Errorf("ssh: port number out of range: %d", msg.Port)
+	}
+	return net.JoinHostPort(msg.Addr, strconv.Itoa(int(msg.Port))), nil
+}
+
+// HandleUDPTunnel serves a UDP tunnel opened with DialUDPTunnel: it
+// connects a local UDP socket to the requested address, accepts the channel
+// and forwards datagrams in both directions until the channel is closed or
+// the socket fails. The channel is rejected if the socket cannot be
+// created.
+func HandleUDPTunnel(newCh NewChannel) error {
+	addr, err := ParseUDPTunnel(newCh)
+	if err != nil {
+		newCh.Reject(ConnectionFailed, err.Error())
+		return err
+	}
+	udp, err := net.Dial("udp", addr)
+	if err != nil {
+		newCh.Reject(ConnectionFailed, err.Error())
+		return err
+	}
+	defer udp.Close()
+	ch, in, err := newCh.Accept()
+	if err != nil {
+		return err
+	}
+	go DiscardRequests(in)
+	defer ch.Close()
+
+	tunnel := &udpTunnelConn{chanConn: &chanConn{Channel: ch}}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- copyDatagrams(tunnel, udp, false)
+	}()
+	// Errors sending to the UDP socket, such as those caused by ICMP port
+	// unreachable messages, are not fatal.
+	err = copyDatagrams(udp, tunnel, true)
+	// Unblock the other direction.
+	udp.Close()
+	ch.Close()
+	if err2 := <-errc; err == nil {
+		err = err2
+	}
+	if err == io.EOF || errors.Is(err, net.ErrClosed) {
+		err = nil
+	}
+	return err
+}
+
+// copyDatagrams copies datagrams from src to dst until src fails, or dst
+// fails unless ignoreWriteErrors is set.
+func copyDatagrams(dst, src net.Conn, ignoreWriteErrors bool) error {
+	buf := make([]byte, maxUDPDatagram)
+	for {
+		n, err := src.Read(buf)
+		if err != nil {
+			return err
+		}
+		if _, err := dst.Write(buf[:n]); err != nil && !ignoreWriteErrors {
+			return err
+		}
+	}
+}
+
+// udpTunnelConn frames datagrams on a channel of a UDP tunnel.
+type udpTunnelConn struct {
+	*chanConn
+
+	readMu  sync.Mutex
+	writeMu sync.Mutex
+	header  [4]byte
+}
+
+func (c *udpTunnelConn) Read(p []byte) (int, error) {
+	c.readMu.Lock()
+	defer c.readMu.Unlock()
+
+	if _, err := io.ReadFull(c.Channel, c.header[:]); err != nil {
+		return 0, err
+	}
+	length := binary.BigEndian.Uint32(c.header[:])
+	if length > maxUDPDatagram {
+		return 0, fmt.Errorf("ssh: UDP datagram of %d bytes is too large", length)
+	}
+	n := min(length, len(p))
+	if _, err := io.ReadFull(c.Channel, p[:n]); err != nil {
+		return 0, err
+	}
+	if _, err := io.CopyN(io.Discard, c.Channel, int64(length-n)); err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
+
+func (c *udpTunnelConn) Write(p []byte) (int, error) {
+	if len(p) > maxUDPDatagram {
+		return 0, fmt.Errorf("ssh: UDP datagram of %d bytes is too large", len(p))
+	}
+	packet := binary.BigEndian.AppendUint32(make([]byte, 0, 4+len(p)), uint32(len(p)))
+	packet = append(packet, p...)
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if _, err := c.Channel.Write(packet); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,105 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"bytes"
+	"net"
+	"testing"
+)
+
+func TestUDPTunnel(t *testing.T) {
+	echo, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket: %v", err)
+	}
+	defer echo.Close()
+	go func() {
+		buf := make([]byte, maxUDPDatagram)
+		for {
+			n, addr, err := echo.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			echo.WriteTo(buf[:n], addr)
+		}
+	}()
+
+	c1, c2, err := netPipe()
+	if err != nil {
+		t.Fatalf("netPipe: %v", err)
+	}
+	defer c1.Close()
+	defer c2.Close()
+
+	serverConf := &ServerConfig{NoClientAuth: true}
+	serverConf.AddHostKey(testSigners["ecdsa"])
+	handled := make(chan error, 1)
+	go func() {
+		conn, chans, reqs, err := NewServerConn(c2, serverConf)
+		if err != nil {
+			handled <- err
+			return
+		}
+		defer conn.Close()
+		go DiscardRequests(reqs)
+		newCh := <-chans
+		if addr, err := ParseUDPTunnel(newCh); err != nil || addr != echo.LocalAddr().String() {
+			t.Errorf("ParseUDPTunnel: got %q, %v; want %q", addr, err, echo.LocalAddr())
+		}
+		handled <- HandleUDPTunnel(newCh)
+	}()
+
+	conn, chans, reqs, err := NewClientConn(c1, "", &ClientConfig{
+		User:            "user",
+		HostKeyCallback: InsecureIgnoreHostKey(),
+	})
+	if err != nil {
+		t.Fatalf("NewClientConn: %v", err)
+	}
+	client := NewClient(conn, chans, reqs)
+	defer client.Close()
+
+	tunnel, err := client.DialUDPTunnel(echo.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("DialUDPTunnel: %v", err)
+	}
+	if _, ok := tunnel.RemoteAddr().(*net.UDPAddr); !ok {
+		t.Errorf("RemoteAddr is a %T, want *net.UDPAddr", tunnel.RemoteAddr())
+	}
+
+	// Datagram boundaries must be preserved.
+	datagrams := [][]byte{[]byte("first"), bytes.Repeat([]byte{'x'}, 2000), []byte("third")}
+	for _, d := range datagrams {
+		if _, err := tunnel.Write(d); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	buf := make([]byte, maxUDPDatagram)
+	for i, want := range datagrams {
+		n, err := tunnel.Read(buf)
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+		if !bytes.Equal(buf[:n], want) {
+			t.Errorf("datagram %d: got %d bytes, want %d", i, n, len(want))
+		}
+	}
+
+	// Reads truncate datagrams to the buffer, like for UDP sockets.
+	tunnel.Write([]byte("truncated"))
+	tunnel.Write([]byte("next"))
+	if n, err := tunnel.Read(buf[:5]); err != nil || string(buf[:n]) != "trunc" {
+		t.Errorf("short Read: got %q, %v; want %q", buf[:n], err, "trunc")
+	}
+	if n, err := tunnel.Read(buf); err != nil || string(buf[:n]) != "next" {
+		t.Errorf("Read after short Read: got %q, %v; want %q", buf[:n], err, "next")
+	}
+
+	tunnel.Close()
+	if err := <-handled; err != nil {
+		t.Errorf("HandleUDPTunnel: %v", err)
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"sync"
+
+	"golang.org/x/term"
+)
+
+// PropagateWindowSize sends the size of the local terminal fd to the server
+// with a window-change request, and sends it again whenever the terminal is
+// resized, so that full-screen programs run by the session follow the local
+// window. Resizes are detected with SIGWINCH on Unix systems, and by polling
+// elsewhere. It is typically called after RequestPty and before Shell.
+//
+// Propagation stops when the returned function is called, or when a
+// window-change request fails because the session is closed.
+func (s *Session) PropagateWindowSize(fd int) (stop func(), err error) {
+	width, height, err := term.GetSize(fd)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.WindowChange(height, width); err != nil {
+		return nil, err
+	}
+
+	resized, stopWatching := watchWindowSize()
+	done := make(chan struct{})
+	go func() {
+		defer stopWatching()
+		for {
+			select {
+			case <-done:
+				return
+			case <-resized:
+			}
+			w, h, err := term.GetSize(fd)
+			if err != nil || (w == width && h == height) {
+				continue
+			}
+			width, height = w, h
+			if err := s.WindowChange(height, width); err != nil {
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }, nil
+}
@@ -0,0 +1,91 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// openPty opens a pseudo-terminal and returns its controlling and terminal
+// sides.
+func openPty(t *testing.T) (*os.File, *os.File) {
+	ptmx, err := os.OpenFile("/dev/ptmx", os.O_RDWR, 0)
+	if err != nil {
+		t.Skipf("cannot open a pty: %v", err)
+	}
+	t.Cleanup(func() { ptmx.Close() })
+	if err := unix.IoctlSetPointerInt(int(ptmx.Fd()), unix.TIOCSPTLCK, 0); err != nil {
+		t.Skipf("cannot unlock the pty: %v", err)
+	}
+	n, err := unix.IoctlGetInt(int(ptmx.Fd()), unix.TIOCGPTN)
+	if err != nil {
+		t.Skipf("cannot get the pty number: %v", err)
+	}
+	tty, err := os.OpenFile("/dev/pts/"+strconv.Itoa(n), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		t.Skipf("cannot open the pty: %v", err)
+	}
+	t.Cleanup(func() { tty.Close() })
+	return ptmx, tty
+}
+
+func TestPropagateWindowSize(t *testing.T) {
+	_, tty := openPty(t)
+	setSize := func(cols, rows uint16) {
+		if err := unix.IoctlSetWinsize(int(tty.Fd()), unix.TIOCSWINSZ, &unix.Winsize{Col: cols, Row: rows}); err != nil {
+			t.Fatalf("IoctlSetWinsize: %v", err)
+		}
+	}
+	setSize(80, 24)
+
+	sizes := make(chan ptyWindowChangeMsg, 2)
+	conn := dial(func(ch Channel, in <-chan *Request, t *testing.T) {
+		defer ch.Close()
+		for req := range in {
+			var msg ptyWindowChangeMsg
+			if req.Type == "window-change" && Unmarshal(req.Payload, &msg) == nil {
+				sizes <- msg
+			}
+		}
+	}, t)
+	defer conn.Close()
+	session, err := conn.NewSession()
+	if err != nil {
+		t.Fatalf("Unable to request new session: %v", err)
+	}
+	defer session.Close()
+
+	stop, err := session.PropagateWindowSize(int(tty.Fd()))
+	if err != nil {
+		t.Fatalf("PropagateWindowSize: %v", err)
+	}
+	defer stop()
+	if got := <-sizes; got.Columns != 80 || got.Rows != 24 {
+		t.Errorf("got initial size %dx%d, want 80x24", got.Columns, got.Rows)
+	}
+
+	setSize(132, 50)
+	if err := syscall.Kill(os.Getpid(), syscall.SIGWINCH); err != nil {
+		t.Fatalf("Kill: %v", err)
+	}
+	if got := <-sizes; got.Columns != 132 || got.Rows != 50 {
+		t.Errorf("got size %dx%d after resize, want 132x50", got.Columns, got.Rows)
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Pipe: %v", err)
+	}
+	defer r.Close()
+	defer w.Close()
+	if _, err := session.PropagateWindowSize(int(r.Fd())); err == nil {
+		t.Error("PropagateWindowSize succeeded on a pipe")
+	}
+}
@@ -0,0 +1,21 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris
+
+package ssh
+
+import "time"
+
+// windowSizePollInterval is how often the terminal size is checked on
+// systems without SIGWINCH, such as Windows.
+const windowSizePollInterval = 500 * time.Millisecond
+
+// watchWindowSize returns a channel that receives a value when the size of
+// the terminal should be checked again, and a function that stops the
+// notifications.
+func watchWindowSize() (<-chan time.Time, func()) {
+	t := time.NewTicker(windowSizePollInterval)
+	return t.C, t.Stop
+}
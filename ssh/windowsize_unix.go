@@ -0,0 +1,22 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+
+package ssh
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// watchWindowSize returns a channel that receives a value when the size of
+// the controlling terminal may have changed, and a function that stops the
+// notifications.
+func watchWindowSize() (<-chan os.Signal, func()) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGWINCH)
+	return c, func() { signal.Stop(c) }
+}
@@ -0,0 +1,374 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// x11DefaultAuthProtocol is the X11 authentication protocol used by
+// RequestX11 if X11Config.AuthProtocol is empty.
+const x11DefaultAuthProtocol = "MIT-MAGIC-COOKIE-1"
+
+// X11Request is the payload of an x11-req channel request, with which a
+// client asks for the X11 connections of a session to be forwarded to it.
+// See RFC 4254, section 6.3.1.
+type X11Request struct {
+	SingleConnection bool
+	// AuthProtocol and AuthCookie are the credentials that X11 clients
+	// must present, typically installed with xauth for the programs run
+	// by the session. AuthCookie is hex encoded.
+	AuthProtocol string
+	AuthCookie   string
+	ScreenNumber uint32
+}
+
+// ParseX11Request parses the payload of an x11-req channel request.
+func ParseX11Request(req *Request) (*X11Request, error) {
+	if req.Type != "x11-req" {
+		return nil, fmt.Errorf("ssh: request type %q is not x11-req", req.Type)
+	}
+	var msg X11Request
+	if err := Unmarshal(req.Payload, &msg); err != nil {
+		return nil, err
+	}
+	if _, err := hex.DecodeString(msg.AuthCookie); err != nil {
+		return nil, errors.New("ssh: x11-req cookie is not hex encoded")
+	}
+	return &msg, nil
+}
+
+// RFC 4254 Section 6.3.2.
+type x11ChannelOpenMsg struct {
+	OriginatorAddress string
+	OriginatorPort    uint32
+}
+
+// X11Config describes the local X display that RequestX11 forwards X11
+// connections to.
+type X11Config struct {
+	// Display is the local display, in the format of the DISPLAY
+	// environment variable, such as ":0", "localhost:10.0" or the path of
+	// a socket followed by the display number.
+	Display string
+
+	// AuthProtocol and AuthCookie are the credentials of the display, as
+	// listed by "xauth list". The server and the programs it runs only see
+	// a random cookie, which is replaced with AuthCookie when they connect.
+	// If AuthProtocol is empty, MIT-MAGIC-COOKIE-1 is announced to the
+	// server and no credentials are passed to the display.
+	AuthProtocol string
+	AuthCookie   []byte
+
+	// SingleConnection asks the server to forward only one X11
+	// connection.
+	SingleConnection bool
+}
+
+// RequestX11 asks the server to forward X11 connections of programs run by
+// the session to the local display, like the -X option of OpenSSH. It must
+// be called before starting the session. X11 connections are no longer
+// forwarded once the session is closed.
+func (s *Session) RequestX11(config *X11Config) error {
+	if s.client == nil {
+		return errors.New("ssh: X11 forwarding requires a session created by Client.NewSession")
+	}
+	_, _, screen, err := parseX11Display(config.Display)
+	if err != nil {
+		return err
+	}
+	fwd, err := s.client.x11Forwarder()
+	if err != nil {
+		return err
+	}
+
+	proto := config.AuthProtocol
+	if proto == "" {
+		proto = x11DefaultAuthProtocol
+	}
+	fake := make([]byte, 16)
+	if len(config.AuthCookie) > 0 {
+		fake = make([]byte, len(config.AuthCookie))
+	}
+	if _, err := io.ReadFull(rand.Reader, fake); err != nil {
+		return err
+	}
+	fwd.add(fake, config)
+
+	req := X11Request{
+		SingleConnection: config.SingleConnection,
+		AuthProtocol:     proto,
+		AuthCookie:       hex.EncodeToString(fake),
+		ScreenNumber:     screen,
+	}
+	ok, err := s.ch.SendRequest("x11-req", true, Marshal(&req))
+	if err == nil && !ok {
+		err = errors.New("ssh: x11-req failed")
+	}
+	if err != nil {
+		fwd.remove(fake)
+		return err
+	}
+	// The cookie is only accepted for the X11 connections of the session.
+	go func() {
+		select {
+		case <-s.exited:
+		case <-s.closed:
+		}
+		fwd.remove(fake)
+	}()
+	return nil
+}
+
+// parseX11Display returns the address of the socket of display, and its
+// screen number.
+func parseX11Display(display string) (network, addr string, screen uint32, err error) {
+	colon := strings.LastIndex(display, ":")
+	if colon < 0 {
+		return "", "", 0, fmt.Errorf("ssh: invalid X11 display %q", display)
+	}
+	host, number := display[:colon], display[colon+1:]
+	if dot := strings.Index(number, "."); dot >= 0 {
+		s, err := strconv.ParseUint(number[dot+1:], 10, 32)
+		if err != nil {
+			return "", "", 0, fmt.Errorf("ssh: invalid X11 display %q", display)
+		}
+		screen, number = uint32(s), number[:dot]
+	}
+	n, err := strconv.ParseUint(number, 10, 16)
+	if err != nil {
+		return "", "", 0, fmt.Errorf("ssh: invalid X11 display %q", display)
+	}
+	switch {
+	case strings.HasPrefix(host, "/"):
+		return "unix", host, screen, nil
+	case host == "" || host == "unix":
+		return "unix", "/tmp/.X11-unix/X" + number, screen, nil
+	case 6000+n > 65535:
+		return "", "", 0, fmt.Errorf("ssh: invalid X11 display %q", display)
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	return "tcp", net.JoinHostPort(host, strconv.Itoa(int(6000+n))), screen, nil
+}
+
+// x11Forwarder handles the x11 channels of a client, connecting those that
+// present a fake cookie of RequestX11 to the corresponding display.
+type x11Forwarder struct {
+	mu      sync.Mutex
+	cookies map[string]*X11Config
+}
+
+// x11Forwarder returns the X11 forwarder of the client, starting it on
+// first use.
+func (c *Client) x11Forwarder() (*x11Forwarder, error) {
+	c.x11Mu.Lock()
+	defer c.x11Mu.Unlock()
+	if c.x11 != nil {
+		return c.x11, nil
+	}
+	chans := c.HandleChannelOpen("x11")
+	if chans == nil {
+		return nil, errors.New("ssh: x11 channels are already handled")
+	}
+	c.x11 = &x11Forwarder{cookies: make(map[string]*X11Config)}
+	go func() {
+		for newCh := range chans {
+			go c.x11.handle(newCh)
+		}
+	}()
+	return c.x11, nil
+}
+
+func (f *x11Forwarder) add(fake []byte, config *X11Config) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c := *config
+	f.cookies[string(fake)] = &c
+}
+
+func (f *x11Forwarder) remove(fake []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.cookies, string(fake))
+}
+
+// lookup returns the configuration of the display that the fake cookie
+// was issued for.
+func (f *x11Forwarder) lookup(proto string, fake []byte) *X11Config {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for cookie, config := range f.cookies {
+		want := config.AuthProtocol
+		if want == "" {
+			want = x11DefaultAuthProtocol
+		}
+		if proto == want && subtle.ConstantTimeCompare([]byte(cookie), fake) == 1 {
+			if config.SingleConnection {
+				delete(f.cookies, cookie)
+			}
+			return config
+		}
+	}
+	return nil
+}
+
+func (f *x11Forwarder) handle(newCh NewChannel) {
+	ch, reqs, err := newCh.Accept()
+	if err != nil {
+		return
+	}
+	go DiscardRequests(reqs)
+	defer ch.Close()
+
+	setup, err := readX11Setup(ch)
+	if err != nil {
+		return
+	}
+	config := f.lookup(setup.authProtocol, setup.authData)
+	if config == nil {
+		return
+	}
+	network, addr, _, err := parseX11Display(config.Display)
+	if err != nil {
+		return
+	}
+	display, err := net.Dial(network, addr)
+	if err != nil {
+		return
+	}
+	defer display.Close()
+	if config.AuthProtocol == "" {
+		setup.authProtocol, setup.authData = "", nil
+	} else {
+		setup.authData = config.AuthCookie
+	}
+	if _, err := display.Write(setup.marshal()); err != nil {
+		return
+	}
+	proxyHalfClose(display, NewChannelConn(ch, nil, nil))
+}
+
+// x11Setup is the connection setup message that X11 clients start with.
+type x11Setup struct {
+	order        binary.ByteOrder
+	orderByte    byte
+	major, minor uint16
+	authProtocol string
+	authData     []byte
+}
+
+func x11Pad(n int) int {
+	return (4 - n%4) % 4
+}
+
+func readX11Setup(r io.Reader) (*x11Setup, error) {
+	var hdr [12]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, err
+	}
+	s := &x11Setup{orderByte: hdr[0]}
+	switch hdr[0] {
+	case 'B':
+		s.order = binary.BigEndian
+	case 'l':
+		s.order = binary.LittleEndian
+	default:
+		return nil, errors.New("ssh: invalid X11 connection setup")
+	}
+	s.major = s.order.Uint16(hdr[2:])
+	s.minor = s.order.Uint16(hdr[4:])
+	nameLen := int(s.order.Uint16(hdr[6:]))
+	dataLen := int(s.order.Uint16(hdr[8:]))
+	buf := make([]byte, nameLen+x11Pad(nameLen)+dataLen+x11Pad(dataLen))
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	s.authProtocol = string(buf[:nameLen])
+	data := buf[nameLen+x11Pad(nameLen):]
+	s.authData = data[:dataLen]
+	return s, nil
+}
+
+func (s *x11Setup) marshal() []byte {
+	buf := make([]byte, 12, 12+len(s.authProtocol)+len(s.authData)+6)
+	buf[0] = s.orderByte
+	s.order.PutUint16(buf[2:], s.major)
+	s.order.PutUint16(buf[4:], s.minor)
+	s.order.PutUint16(buf[6:], uint16(len(s.authProtocol)))
+	s.order.PutUint16(buf[8:], uint16(len(s.authData)))
+	buf = append(buf, s.authProtocol...)
+	buf = append(buf, make([]byte, x11Pad(len(s.authProtocol)))...)
+	buf = append(buf, s.authData...)
+	buf = append(buf, make([]byte, x11Pad(len(s.authData)))...)
+	return buf
+}
+
+// OpenX11Channel opens an x11 channel to the client of conn for an X11
+// connection from origin, typically a program run by a session for which
+// the client sent an x11-req request. The data of the channel starts with
+// the X11 connection setup.
+func OpenX11Channel(conn Conn, origin net.Addr) (Channel, error) {
+	msg := x11ChannelOpenMsg{OriginatorAddress: net.IPv4zero.String()}
+	if a, ok := origin.(*net.TCPAddr); ok {
+		msg.OriginatorAddress, msg.OriginatorPort = a.IP.String(), uint32(a.Port)
+	}
+	ch, in, err := conn.OpenChannel("x11", Marshal(&msg))
+	if err != nil {
+		return nil, err
+	}
+	go DiscardRequests(in)
+	return ch, nil
+}
+
+// ServeX11 accepts X11 connections on l, the listener of the display
+// set up for the programs of a session, and forwards those that present the
+// credentials of req to the client of conn over x11 channels. Connections
+// with other credentials are closed, as are all connections after the first
+// one if req asks for a single connection. ServeX11 returns the error of
+// l.Accept.
+func ServeX11(conn Conn, req *X11Request, l net.Listener) error {
+	cookie, err := hex.DecodeString(req.AuthCookie)
+	if err != nil {
+		return errors.New("ssh: x11-req cookie is not hex encoded")
+	}
+	var forwarded atomic.Bool
+	for {
+		local, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer local.Close()
+			setup, err := readX11Setup(local)
+			if err != nil || setup.authProtocol != req.AuthProtocol || subtle.ConstantTimeCompare(setup.authData, cookie) != 1 {
+				return
+			}
+			if !forwarded.CompareAndSwap(false, true) && req.SingleConnection {
+				return
+			}
+			ch, err := OpenX11Channel(conn, local.RemoteAddr())
+			if err != nil {
+				return
+			}
+			remote := NewChannelConn(ch, nil, nil)
+			defer remote.Close()
+			if _, err := remote.Write(setup.marshal()); err != nil {
+				return
+			}
+			proxyHalfClose(local, remote)
+		}()
+	}
+}
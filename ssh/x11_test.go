@@ -0,0 +1,280 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseX11Display(t *testing.T) {
+	for _, tt := range []struct {
+		display, network, addr string
+		screen                 uint32
+	}{
+		{":0", "unix", "/tmp/.X11-unix/X0", 0},
+		{"unix:1.2", "unix", "/tmp/.X11-unix/X1", 2},
+		{"localhost:10.0", "tcp", "localhost:6010", 0},
+		{"[::1]:3", "tcp", "[::1]:6003", 0},
+		{"/private/tmp/launchd/org.xquartz:0", "unix", "/private/tmp/launchd/org.xquartz", 0},
+	} {
+		network, addr, screen, err := parseX11Display(tt.display)
+		if err != nil || network != tt.network || addr != tt.addr || screen != tt.screen {
+			t.Errorf("parseX11Display(%q) = %q, %q, %d, %v; want %q, %q, %d", tt.display, network, addr, screen, err, tt.network, tt.addr, tt.screen)
+		}
+	}
+	for _, display := range []string{"", "localhost", ":x", ":0.x", "host:70000"} {
+		if _, _, _, err := parseX11Display(display); err == nil {
+			t.Errorf("parseX11Display(%q) succeeded", display)
+		}
+	}
+}
+
+func TestRequestX11(t *testing.T) {
+	dir := t.TempDir()
+	socket := filepath.Join(dir, "X")
+	display, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("Unix sockets are not supported: %v", err)
+	}
+	defer display.Close()
+	realCookie := bytes.Repeat([]byte{0xaa}, 16)
+
+	// The display checks the cookie and replies with the rest of the
+	// connection.
+	go func() {
+		for {
+			conn, err := display.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				setup, err := readX11Setup(conn)
+				if err != nil || setup.authProtocol != x11DefaultAuthProtocol || !bytes.Equal(setup.authData, realCookie) {
+					t.Errorf("display got setup %+v, %v", setup, err)
+					return
+				}
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	c1, c2, err := netPipe()
+	if err != nil {
+		t.Fatalf("netPipe: %v", err)
+	}
+	defer c1.Close()
+	defer c2.Close()
+
+	serverConf := &ServerConfig{NoClientAuth: true}
+	serverConf.AddHostKey(testSigners["ecdsa"])
+	fakeCookie := make(chan []byte, 1)
+	serverConn := make(chan *ServerConn, 1)
+	go func() {
+		conn, chans, reqs, err := NewServerConn(c2, serverConf)
+		if err != nil {
+			return
+		}
+		serverConn <- conn
+		go DiscardRequests(reqs)
+		for newCh := range chans {
+			ch, reqs, err := newCh.Accept()
+			if err != nil {
+				return
+			}
+			defer ch.Close()
+			go func() {
+				for req := range reqs {
+					if msg, err := ParseX11Request(req); err == nil && msg.AuthProtocol == x11DefaultAuthProtocol {
+						cookie, _ := hex.DecodeString(msg.AuthCookie)
+						fakeCookie <- cookie
+						req.Reply(true, nil)
+						continue
+					}
+					req.Reply(false, nil)
+				}
+			}()
+		}
+	}()
+
+	conn, chans, reqs, err := NewClientConn(c1, "", &ClientConfig{
+		User:            "user",
+		HostKeyCallback: InsecureIgnoreHostKey(),
+	})
+	if err != nil {
+		t.Fatalf("NewClientConn: %v", err)
+	}
+	client := NewClient(conn, chans, reqs)
+	defer client.Close()
+	session, err := client.NewSession()
+	if err != nil {
+		t.Fatalf("NewSession: %v", err)
+	}
+	defer session.Close()
+	if err := session.RequestX11(&X11Config{
+		Display:      socket + ":0",
+		AuthProtocol: x11DefaultAuthProtocol,
+		AuthCookie:   realCookie,
+	}); err != nil {
+		t.Fatalf("RequestX11: %v", err)
+	}
+	fake := <-fakeCookie
+	if len(fake) != len(realCookie) || bytes.Equal(fake, realCookie) {
+		t.Fatalf("server got cookie %x, want a random cookie of %d bytes", fake, len(realCookie))
+	}
+	server := <-serverConn
+
+	// openX11 connects like an X11 client on the server would.
+	openX11 := func(cookie []byte) Channel {
+		ch, reqs, err := server.OpenChannel("x11", Marshal(&x11ChannelOpenMsg{"127.0.0.1", 5000}))
+		if err != nil {
+			t.Fatalf("OpenChannel: %v", err)
+		}
+		go DiscardRequests(reqs)
+		setup := &x11Setup{order: binary.LittleEndian, orderByte: 'l', major: 11, authProtocol: x11DefaultAuthProtocol, authData: cookie}
+		if _, err := ch.Write(setup.marshal()); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		return ch
+	}
+
+	ch := openX11(fake)
+	io.WriteString(ch, "request")
+	ch.CloseWrite()
+	if got, err := io.ReadAll(ch); err != nil || string(got) != "request" {
+		t.Errorf("got %q, %v from the display; want %q", got, err, "request")
+	}
+	ch.Close()
+
+	// Connections with the wrong cookie do not reach the display.
+	ch = openX11(realCookie)
+	if got, _ := io.ReadAll(ch); len(got) != 0 {
+		t.Errorf("got %q from the display with a wrong cookie", got)
+	}
+	ch.Close()
+
+	// Nor do those of a closed session.
+	session.Close()
+	ch = openX11(fake)
+	io.WriteString(ch, "request")
+	ch.CloseWrite()
+	if got, _ := io.ReadAll(ch); len(got) != 0 {
+		t.Errorf("got %q from the display after the session was closed", got)
+	}
+	ch.Close()
+}
+
+func TestServeX11(t *testing.T) {
+	c1, c2, err := netPipe()
+	if err != nil {
+		t.Fatalf("netPipe: %v", err)
+	}
+	defer c1.Close()
+	defer c2.Close()
+
+	serverConf := &ServerConfig{NoClientAuth: true}
+	serverConf.AddHostKey(testSigners["ecdsa"])
+	serverConn := make(chan *ServerConn, 1)
+	go func() {
+		conn, chans, reqs, err := NewServerConn(c2, serverConf)
+		if err != nil {
+			return
+		}
+		serverConn <- conn
+		go DiscardRequests(reqs)
+		for newCh := range chans {
+			newCh.Reject(Prohibited, "no channels")
+		}
+	}()
+
+	conn, chans, reqs, err := NewClientConn(c1, "", &ClientConfig{
+		User:            "user",
+		HostKeyCallback: InsecureIgnoreHostKey(),
+	})
+	if err != nil {
+		t.Fatalf("NewClientConn: %v", err)
+	}
+	client := NewClient(conn, chans, reqs)
+	defer client.Close()
+
+	// The client echoes the data of x11 channels after the connection
+	// setup.
+	cookie := bytes.Repeat([]byte{0x55}, 16)
+	x11Chans := client.HandleChannelOpen("x11")
+	go func() {
+		for newCh := range x11Chans {
+			var msg x11ChannelOpenMsg
+			if err := Unmarshal(newCh.ExtraData(), &msg); err != nil || msg.OriginatorAddress != "127.0.0.1" {
+				t.Errorf("x11 channel from %+v, %v; want 127.0.0.1", msg, err)
+			}
+			ch, reqs, err := newCh.Accept()
+			if err != nil {
+				return
+			}
+			go DiscardRequests(reqs)
+			go func() {
+				defer ch.Close()
+				setup, err := readX11Setup(ch)
+				if err != nil || !bytes.Equal(setup.authData, cookie) {
+					t.Errorf("client got setup %+v, %v", setup, err)
+					return
+				}
+				io.Copy(ch, ch)
+				ch.CloseWrite()
+			}()
+		}
+	}()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer l.Close()
+	req := &X11Request{
+		AuthProtocol: x11DefaultAuthProtocol,
+		AuthCookie:   hex.EncodeToString(cookie),
+	}
+	go ServeX11(<-serverConn, req, l)
+
+	// dialX11 connects like an X11 client on the server would.
+	dialX11 := func(cookie []byte) net.Conn {
+		conn, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			t.Fatalf("Dial: %v", err)
+		}
+		setup := &x11Setup{order: binary.BigEndian, orderByte: 'B', major: 11, authProtocol: x11DefaultAuthProtocol, authData: cookie}
+		if _, err := conn.Write(setup.marshal()); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		return conn
+	}
+
+	for i := 0; i < 2; i++ {
+		conn := dialX11(cookie)
+		io.WriteString(conn, "request")
+		conn.(*net.TCPConn).CloseWrite()
+		if got, err := io.ReadAll(conn); err != nil || string(got) != "request" {
+			t.Errorf("got %q, %v from the client; want %q", got, err, "request")
+		}
+		conn.Close()
+	}
+
+	// Connections with the wrong cookie are closed.
+	conn2 := dialX11(bytes.Repeat([]byte{0xaa}, 16))
+	if got, _ := io.ReadAll(conn2); len(got) != 0 {
+		t.Errorf("got %q from the client with a wrong cookie", got)
+	}
+	conn2.Close()
+
+	if _, err := ParseX11Request(&Request{Type: "x11-req", Payload: Marshal(&X11Request{AuthCookie: "zz"})}); err == nil {
+		t.Error("ParseX11Request accepted a cookie that is not hex encoded")
+	}
+}